@@ -17,8 +17,13 @@
 package blockproducer
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
@@ -38,14 +43,58 @@ import (
 var (
 	metaBucket                          = [4]byte{0x0, 0x0, 0x0, 0x0}
 	metaStateKey                        = []byte("covenantsql-state")
+	metaSnapshotKey                     = []byte("covenantsql-state-snapshot")
+	metaSnapshotBlockKey                = []byte("covenantsql-state-snapshot-block")
+	metaSyncedBlockKey                  = []byte("covenantsql-synced-block")
+	metaPrunedHeightKey                 = []byte("covenantsql-pruned-height")
 	metaBlockIndexBucket                = []byte("covenantsql-block-index-bucket")
 	metaTransactionBucket               = []byte("covenantsql-tx-index-bucket")
+	metaTxHeightIndexBucket             = []byte("covenantsql-tx-height-index-bucket")
+	metaHeightTxIndexBucket             = []byte("covenantsql-height-tx-index-bucket")
 	metaTxBillingIndexBucket            = []byte("covenantsql-tx-billing-index-bucket")
 	metaLastTxBillingIndexBucket        = []byte("covenantsql-last-tx-billing-index-bucket")
 	metaAccountIndexBucket              = []byte("covenantsql-account-index-bucket")
 	metaSQLChainIndexBucket             = []byte("covenantsql-sqlchain-index-bucket")
+	metaMinerIndexBucket                = []byte("covenantsql-miner-index-bucket")
+	metaBillingIndexBucket              = []byte("covenantsql-billing-index-bucket")
+	metaMultiSigIndexBucket             = []byte("covenantsql-multisig-index-bucket")
+	metaParamsIndexBucket               = []byte("covenantsql-params-index-bucket")
+	metaVestingIndexBucket              = []byte("covenantsql-vesting-index-bucket")
+	metaAccountEventBucket              = []byte("covenantsql-account-event-bucket")
+	metaCheckpointBucket                = []byte("covenantsql-checkpoint-bucket")
+	metaPendingTxBucket                 = []byte("covenantsql-pending-tx-bucket")
 	gasprice                     uint32 = 1
 	accountAddress               proto.AccountAddress
+
+	// billingDisputePeriod defines the blocks in which a billing could be challenged, and
+	// billingChallengeWindow defines the blocks in which a challenge should be proved.
+	billingDisputePeriod   uint32 = 1024
+	billingChallengeWindow uint32 = 64
+
+	// minMinerStake defines the minimum covenant coin locked by a registered miner before it's
+	// changed by chain parameter updates,
+	// minerSlashPercent defines the part of stake slashed on misbehavior, and
+	// serviceChallengeWindow defines the blocks in which a service challenge should be answered.
+	minMinerStake          uint64 = 100
+	minerSlashPercent      uint64 = 50
+	serviceChallengeWindow uint32 = 16
+
+	// maxTransactionsPerBlock defines the max number of transactions packed in a block by fee
+	// rate, and maxAccountTransactionsPerBlock limits the transactions of each account in a block.
+	maxTransactionsPerBlock        = 1024
+	maxAccountTransactionsPerBlock = 64
+
+	// maxNonceGap defines the window of out-of-order nonces accepted from an account, a transaction
+	// with nonce in (next, next+maxNonceGap] is queued until the transactions before it arrive.
+	maxNonceGap pi.AccountNonce = 16
+
+	// stateSnapshotInterval defines the blocks between state snapshots, which are committed by
+	// the state root of block headers and synchronized by new block producers.
+	stateSnapshotInterval uint32 = 64
+
+	// minKeepBlocks defines the minimum blocks kept by a pruned node, so that the blocks after
+	// the latest state snapshot are always available to new block producers.
+	minKeepBlocks = 2 * stateSnapshotInterval
 )
 
 // Chain defines the main chain.
@@ -64,6 +113,42 @@ type Chain struct {
 	blocksFromRPC  chan *types.Block
 	pendingTxs     chan pi.Transaction
 	stopCh         chan struct{}
+
+	// minerHandlers are called with the nodes of miners deregistered by produced blocks.
+	minerHandlers []func(proto.NodeID)
+
+	// keepBlocks is the recent blocks kept in pruned mode, 0 in archival mode, and prunedHeight
+	// is the lowest height from which the block history is kept.
+	keepBlocks   uint32
+	prunedHeight uint32
+
+	// stateTreeMutex protects following stateTree field, which is the tree of state committed by
+	// the meta root of the head block, used to prove state objects to light clients.
+	stateTreeMutex sync.RWMutex
+	stateTree      *stateTree
+
+	// verifyWorkers defines the goroutines verifying transactions of a received block.
+	verifyWorkers int
+
+	// eventMutex protects following eventCh field, which is closed and renewed once account
+	// events are added to wake up the waiting subscribers.
+	eventMutex sync.Mutex
+	eventCh    chan struct{}
+
+	// checkpointMutex protects following checkpoints field, which collects the signatures of
+	// checkpoints not finalized yet by header hash.
+	checkpointMutex sync.Mutex
+	checkpoints     map[hash.Hash]*types.Checkpoint
+
+	// accountLimiter and ipLimiter limit the transaction submissions of an account and from an
+	// IP address.
+	accountLimiter *rateLimiter
+	ipLimiter      *rateLimiter
+
+	// chainID defines the chain which accepted transactions are signed for, legacy transactions
+	// without chain id are rejected from legacyTxDeadline height if it's set.
+	chainID          pi.ChainID
+	legacyTxDeadline uint32
 }
 
 // NewChain creates a new blockchain.
@@ -98,7 +183,22 @@ func NewChain(cfg *Config) (*Chain, error) {
 			return
 		}
 
-		_, err = bucket.CreateBucketIfNotExists(metaTransactionBucket)
+		txbk, err := bucket.CreateBucketIfNotExists(metaTransactionBucket)
+		if err != nil {
+			return
+		}
+		for i := pi.TransactionType(0); i < pi.TransactionTypeNumber; i++ {
+			if _, err = txbk.CreateBucketIfNotExists(i.Bytes()); err != nil {
+				return
+			}
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaTxHeightIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaHeightTxIndexBucket)
 		if err != nil {
 			return
 		}
@@ -119,6 +219,46 @@ func NewChain(cfg *Config) (*Chain, error) {
 		}
 
 		_, err = bucket.CreateBucketIfNotExists(metaSQLChainIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaMinerIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaBillingIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaMultiSigIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaParamsIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaVestingIndexBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaAccountEventBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaCheckpointBucket)
+		if err != nil {
+			return
+		}
+
+		_, err = bucket.CreateBucketIfNotExists(metaPendingTxBucket)
 		return
 	})
 	if err != nil {
@@ -138,9 +278,26 @@ func NewChain(cfg *Config) (*Chain, error) {
 		blocksFromRPC:  make(chan *types.Block),
 		pendingTxs:     make(chan pi.Transaction),
 		stopCh:         make(chan struct{}),
+		keepBlocks:     cfg.KeepBlocks,
+		verifyWorkers:  cfg.VerifyWorkers,
+		accountLimiter: newRateLimiter(cfg.AccountTxRate, cfg.AccountTxBurst),
+		ipLimiter:      newRateLimiter(cfg.IPTxRate, cfg.IPTxBurst),
+		chainID:        cfg.ChainID,
+
+		legacyTxDeadline: cfg.LegacyTxDeadline,
+	}
+	if chain.keepBlocks > 0 && chain.keepBlocks < minKeepBlocks {
+		chain.keepBlocks = minKeepBlocks
 	}
+	if chain.verifyWorkers <= 0 {
+		chain.verifyWorkers = runtime.NumCPU()
+	}
+	chain.ms.setGovernance(cfg.Authorities, cfg.AuthorityThreshold, defaultChainParams(cfg.Period))
 
 	chain.pushGenesisBlock(cfg.Genesis)
+	if err = chain.restoreMempool(cfg.MempoolTTL); err != nil {
+		return nil, err
+	}
 	log.WithFields(log.Fields{
 		"index":     chain.rt.index,
 		"bp_number": chain.rt.bpNum,
@@ -182,7 +339,21 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 		blocksFromRPC:  make(chan *types.Block),
 		pendingTxs:     make(chan pi.Transaction),
 		stopCh:         make(chan struct{}),
+		keepBlocks:     cfg.KeepBlocks,
+		verifyWorkers:  cfg.VerifyWorkers,
+		accountLimiter: newRateLimiter(cfg.AccountTxRate, cfg.AccountTxBurst),
+		ipLimiter:      newRateLimiter(cfg.IPTxRate, cfg.IPTxBurst),
+		chainID:        cfg.ChainID,
+
+		legacyTxDeadline: cfg.LegacyTxDeadline,
+	}
+	if chain.keepBlocks > 0 && chain.keepBlocks < minKeepBlocks {
+		chain.keepBlocks = minKeepBlocks
 	}
+	if chain.verifyWorkers <= 0 {
+		chain.verifyWorkers = runtime.NumCPU()
+	}
+	chain.ms.setGovernance(cfg.Authorities, cfg.AuthorityThreshold, defaultChainParams(cfg.Period))
 
 	err = chain.db.View(func(tx *bolt.Tx) (err error) {
 		meta := tx.Bucket(metaBucket[:])
@@ -194,6 +365,10 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 
 		var last *blockNode
 		var index int32
+		synced := meta.Get(metaSyncedBlockKey)
+		if v := meta.Get(metaPrunedHeightKey); len(v) == 4 {
+			chain.prunedHeight = binary.BigEndian.Uint32(v)
+		}
 		blocks := meta.Bucket(metaBlockIndexBucket)
 		nodes := make([]blockNode, blocks.Stats().KeyN)
 
@@ -208,7 +383,10 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 
 			if last == nil {
 				// TODO(lambda): check genesis block
-			} else if block.SignedHeader.ParentHash.IsEqual(&last.hash) {
+			} else if block.SignedHeader.ParentHash.IsEqual(&last.hash) ||
+				bytes.Equal(block.SignedHeader.BlockHash[:], synced) || last.height < chain.prunedHeight {
+				// the block synchronized with state snapshot follows the genesis block directly, and
+				// so does the block following pruned blocks
 				if err = block.SignedHeader.Verify(); err != nil {
 					return err
 				}
@@ -223,6 +401,8 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 			}
 
 			nodes[index].initBlockNode(block, parent)
+			// heights are not continuous with pruned or skipped blocks
+			nodes[index].height = binary.BigEndian.Uint32(k[:4])
 			last = &nodes[index]
 			index++
 			return err
@@ -273,6 +453,11 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 	if err != nil {
 		return nil, err
 	}
+	chain.ms.setHeight(chain.st.getHeight())
+	chain.applyChainParams()
+	if err = chain.restoreMempool(cfg.MempoolTTL); err != nil {
+		return nil, err
+	}
 
 	return chain, nil
 }
@@ -283,7 +468,12 @@ func (c *Chain) checkTxBilling(tb *types.TxBilling) (err error) {
 	if err != nil {
 		return err
 	}
+	return c.checkTxBillingIndex(tb)
+}
 
+// checkTxBillingIndex checks a verified TxBilling against the indexed ones: 1. existed tx
+// 2. SequenceID.
+func (c *Chain) checkTxBillingIndex(tb *types.TxBilling) (err error) {
 	if val := c.ti.getTxBilling(tb.TxHash); val == nil {
 		err = c.db.View(func(tx *bolt.Tx) error {
 			meta := tx.Bucket(metaBucket[:])
@@ -332,6 +522,65 @@ func (c *Chain) fetchTx(h hash.Hash, tx ci.Transaction) (ok bool, err error) {
 	return
 }
 
+// queryTxState returns the state of the main chain transaction with hash h, and the height of
+// block packing the transaction if it's confirmed.
+func (c *Chain) queryTxState(h hash.Hash) (state pi.TransactionState, height uint32, err error) {
+	var receipts []TxReceipt
+	if receipts, err = c.queryTxReceipts([]hash.Hash{h}); err != nil {
+		return
+	}
+	state, height = receipts[0].State, receipts[0].Height
+	return
+}
+
+// queryTxReceipts returns the receipts of the main chain transactions with hashes in order, the
+// confirmed transactions are looked up in a single db transaction.
+func (c *Chain) queryTxReceipts(hashes []hash.Hash) (receipts []TxReceipt, err error) {
+	if len(hashes) > maxTxReceiptsQuery {
+		err = ErrTooManyTxReceipts
+		return
+	}
+	receipts = make([]TxReceipt, len(hashes))
+	for i, h := range hashes {
+		receipts[i].Hash = h
+		if c.ms.isTxPending(h) {
+			receipts[i].State = pi.TransactionStatePending
+		}
+	}
+	err = c.db.View(func(tx *bolt.Tx) (err error) {
+		var (
+			meta = tx.Bucket(metaBucket[:])
+			tb   = meta.Bucket(metaTransactionBucket)
+			hb   = meta.Bucket(metaTxHeightIndexBucket)
+		)
+		for i := range receipts {
+			r := &receipts[i]
+			if r.State == pi.TransactionStatePending {
+				continue
+			}
+			for j := pi.TransactionType(0); j < pi.TransactionTypeNumber; j++ {
+				if b := tb.Bucket(j.Bytes()); b != nil && b.Get(r.Hash[:]) != nil {
+					r.State = pi.TransactionStateConfirmed
+					break
+				}
+			}
+			// transactions of pruned blocks are removed, but their heights are still indexed
+			if v := hb.Get(r.Hash[:]); len(v) == 4 {
+				r.State = pi.TransactionStateConfirmed
+				r.Height = binary.BigEndian.Uint32(v)
+			}
+			if r.State != pi.TransactionStateConfirmed {
+				if reason, ok := c.ms.failed.get(r.Hash); ok {
+					r.State = pi.TransactionStateFailed
+					r.Reason = reason
+				}
+			}
+		}
+		return
+	})
+	return
+}
+
 func (c *Chain) checkTx(tx ci.Transaction) (err error) {
 	if err = tx.Verify(); err != nil {
 		return
@@ -353,9 +602,21 @@ func (c *Chain) checkBlock(b *types.Block) (err error) {
 		return ErrParentNotMatch
 	}
 
-	// TODO(leventeliu): merge transactions checking.
+	// signatures and transactions of different accounts are verified concurrently, then the
+	// billings are checked against the index in the block order
+	if err = verifyBlockTransactions(b, c.verifyWorkers); err != nil {
+		return err
+	}
 	for i := range b.TxBillings {
-		if err = c.checkTxBilling(b.TxBillings[i]); err != nil {
+		if err = c.checkChainID(b.TxBillings[i]); err != nil {
+			return err
+		}
+		if err = c.checkTxBillingIndex(b.TxBillings[i]); err != nil {
+			return err
+		}
+	}
+	for _, t := range b.Transactions {
+		if err = c.checkChainID(t); err != nil {
 			return err
 		}
 	}
@@ -364,6 +625,9 @@ func (c *Chain) checkBlock(b *types.Block) (err error) {
 	if !b.SignedHeader.MerkleRoot.IsEqual(rootHash) {
 		return ErrInvalidMerkleTreeRoot
 	}
+	if err = c.checkStateRoots(b); err != nil {
+		return err
+	}
 
 	enc, err := b.SignedHeader.Header.MarshalHash()
 	if err != nil {
@@ -377,7 +641,60 @@ func (c *Chain) checkBlock(b *types.Block) (err error) {
 	return nil
 }
 
+// checkStateRoots checks that the meta root of block b, and the state root at snapshot heights,
+// commit the state of the parent block.
+func (c *Chain) checkStateRoots(b *types.Block) (err error) {
+	var (
+		snap = c.ms.snapshot()
+		tree *stateTree
+		root hash.Hash
+	)
+	if tree, err = newStateTree(snap); err != nil {
+		return
+	}
+	if root = tree.root(); !root.IsEqual(&b.SignedHeader.MetaRoot) {
+		log.WithFields(log.Fields{
+			"block_hash": b.SignedHeader.BlockHash.String(),
+			"meta_root":  b.SignedHeader.MetaRoot.String(),
+			"local_root": root.String(),
+		}).Warning("meta root not match")
+		return ErrInvalidMetaRoot
+	}
+	if h := c.rt.getHeightFromTime(b.Timestamp()); h == 0 || h%stateSnapshotInterval != 0 {
+		return
+	}
+	if root, err = snap.StateRoot(); err != nil {
+		return
+	}
+	if !root.IsEqual(&b.SignedHeader.StateRoot) {
+		log.WithFields(log.Fields{
+			"block_hash": b.SignedHeader.BlockHash.String(),
+			"state_root": b.SignedHeader.StateRoot.String(),
+			"local_root": root.String(),
+		}).Warning("state root not match")
+		return ErrInvalidStateRoot
+	}
+	return
+}
+
 func (c *Chain) pushBlockWithoutCheck(b *types.Block) error {
+	var (
+		deregistered []proto.NodeID
+		pruned       uint32
+	)
+	// the state before pushing b is committed by the meta root of b, which is checked by
+	// checkBlock for the received blocks
+	tree, err := newStateTree(c.ms.snapshot())
+	if err != nil {
+		return err
+	}
+	if root := tree.root(); root.IsEqual(&b.SignedHeader.MetaRoot) {
+		tree.header = &b.SignedHeader
+	} else {
+		// the genesis block commits no state
+		tree = nil
+	}
+
 	h := c.rt.getHeightFromTime(b.Timestamp())
 	node := newBlockNode(h, b, c.st.getNode())
 	state := State{
@@ -405,17 +722,235 @@ func (c *Chain) pushBlockWithoutCheck(b *types.Block) error {
 		if err != nil {
 			return err
 		}
-		// TODO(leventeliu): verify that block tx list matches tx pool.
-		err = c.ms.commitProcedure()(tx)
-		return err
+		// the transactions of block are applied in the block order to the committed state,
+		// pending transactions not packed by the block are deferred to the next blocks
+		var deferred []pi.Transaction
+		if deferred, err = c.ms.applyBlockTransactions(b.Transactions); err != nil {
+			return err
+		}
+		// index the height of block packing each committed transaction, and the transactions of
+		// each height by type for pruning
+		var (
+			hb     = tx.Bucket(metaBucket[:]).Bucket(metaTxHeightIndexBucket)
+			tb     = tx.Bucket(metaBucket[:]).Bucket(metaHeightTxIndexBucket)
+			txb    = tx.Bucket(metaBucket[:]).Bucket(metaTransactionBucket)
+			height = make([]byte, 4)
+			enc    []byte
+		)
+		binary.BigEndian.PutUint32(height, node.height)
+		for _, t := range b.Transactions {
+			h := t.GetHash()
+			if enc, err = t.Serialize(); err != nil {
+				return err
+			}
+			if err = txb.Bucket(t.GetTransactionType().Bytes()).Put(h[:], enc); err != nil {
+				return err
+			}
+			if err = hb.Put(h[:], height); err != nil {
+				return err
+			}
+			if err = tb.Put(append(height, h[:]...), t.GetTransactionType().Bytes()); err != nil {
+				return err
+			}
+			addrs, events := accountEvents(t, node.height)
+			for i := range events {
+				if err = putAccountEvent(tx, addrs[i], events[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if err = c.saveStateSnapshot(tx, node, b); err != nil {
+			return err
+		}
+		if pruned, err = c.pruneBlocks(tx, node.height); err != nil {
+			return err
+		}
+		if err = c.ms.collectFees(b.Producer()); err != nil {
+			return err
+		}
+		if err = c.ms.settleMinerChallenges(node.height); err != nil {
+			return err
+		}
+		deregistered = c.ms.pendingDeregisteredMiners()
+		if err = c.ms.settleBillings(node.height); err != nil {
+			return err
+		}
+		c.ms.settleParams(node.height)
+		if err = c.ms.commitProcedure()(tx); err != nil {
+			return err
+		}
+		return c.ms.reapplyTransactionsProcedure(deferred)(tx)
 	})
 	if err != nil {
 		return err
 	}
 	c.st = &state
 	c.bi.addBlock(node)
+	c.applyChainParams()
+	c.stateTreeMutex.Lock()
+	c.stateTree = tree
+	c.stateTreeMutex.Unlock()
+	atomic.StoreUint32(&c.prunedHeight, pruned)
+	c.notifyAccountEvents()
+	c.signCheckpoint(node.height, b)
+	for _, n := range deregistered {
+		for _, handler := range c.minerHandlers {
+			go handler(n)
+		}
+	}
 	return nil
+}
+
+// OnMinerDeregistered adds handler to be called with the node of each miner deregistered by a
+// produced block, it should be called before the chain starts.
+func (c *Chain) OnMinerDeregistered(handler func(proto.NodeID)) {
+	c.minerHandlers = append(c.minerHandlers, handler)
+}
+
+// applyChainParams updates the block producing cycles of runtime by the scheduled chain
+// parameter updates.
+func (c *Chain) applyChainParams() {
+	var changes []periodChange
+	for _, v := range c.ms.scheduledParams() {
+		changes = append(changes, periodChange{
+			height: v.ActivationHeight,
+			period: v.Params.BlockPeriod,
+		})
+	}
+	c.rt.setPeriods(changes)
+}
 
+// saveStateSnapshot saves the state committed by the parent of block b if it is at a snapshot
+// height, b is invalid if the snapshot does not match its state root.
+func (c *Chain) saveStateSnapshot(tx *bolt.Tx, node *blockNode, b *types.Block) (err error) {
+	if node.height == 0 || node.height%stateSnapshotInterval != 0 {
+		return
+	}
+	var (
+		snap = c.ms.snapshot()
+		root hash.Hash
+		enc  []byte
+	)
+	snap.Height = c.st.getHeight()
+	snap.Head = b.SignedHeader.ParentHash
+	if root, err = snap.StateRoot(); err != nil {
+		return
+	}
+	if !root.IsEqual(&b.SignedHeader.StateRoot) {
+		return ErrInvalidStateRoot
+	}
+	if enc, err = snap.Serialize(); err != nil {
+		return
+	}
+	meta := tx.Bucket(metaBucket[:])
+	if err = meta.Put(metaSnapshotKey, enc); err != nil {
+		return
+	}
+	return meta.Put(metaSnapshotBlockKey, node.indexKey())
+}
+
+// pruneBlocks removes the blocks below height-keepBlocks and their transactions in pruned mode,
+// the heights of transactions are still indexed. The genesis block and the block committing the
+// saved state snapshot are kept. It returns the lowest height from which block history is kept.
+func (c *Chain) pruneBlocks(tx *bolt.Tx, height uint32) (pruned uint32, err error) {
+	pruned = atomic.LoadUint32(&c.prunedHeight)
+	if c.keepBlocks == 0 || height < c.keepBlocks || height-c.keepBlocks <= pruned {
+		return
+	}
+	var (
+		meta     = tx.Bucket(metaBucket[:])
+		bb       = meta.Bucket(metaBlockIndexBucket)
+		hb       = meta.Bucket(metaHeightTxIndexBucket)
+		tb       = meta.Bucket(metaTransactionBucket)
+		snapshot = meta.Get(metaSnapshotBlockKey)
+		cutoff   = height - c.keepBlocks
+		blocks   [][]byte
+		txs      [][]byte
+	)
+	cur := bb.Cursor()
+	for k, _ := cur.First(); k != nil && binary.BigEndian.Uint32(k[:4]) < cutoff; k, _ = cur.Next() {
+		if binary.BigEndian.Uint32(k[:4]) != 0 && !bytes.Equal(k, snapshot) {
+			blocks = append(blocks, append([]byte{}, k...))
+		}
+	}
+	cur = hb.Cursor()
+	for k, v := cur.First(); k != nil && binary.BigEndian.Uint32(k[:4]) < cutoff; k, v = cur.Next() {
+		if err = tb.Bucket(v).Delete(k[4:]); err != nil {
+			return
+		}
+		txs = append(txs, append([]byte{}, k...))
+	}
+	for _, k := range blocks {
+		if err = bb.Delete(k); err != nil {
+			return
+		}
+	}
+	for _, k := range txs {
+		if err = hb.Delete(k); err != nil {
+			return
+		}
+	}
+	var enc = make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, cutoff)
+	if err = meta.Put(metaPrunedHeightKey, enc); err != nil {
+		return
+	}
+	pruned = cutoff
+	return
+}
+
+// chainMode returns the mode of block history kept by the chain, and the lowest height from
+// which the block history is kept.
+func (c *Chain) chainMode() (mode ChainMode, prunedHeight uint32) {
+	if c.keepBlocks > 0 {
+		mode = ChainModePruned
+	}
+	return mode, atomic.LoadUint32(&c.prunedHeight)
+}
+
+// proveAccount returns account addr committed by the meta root of the head block with its
+// inclusion proof.
+func (c *Chain) proveAccount(addr proto.AccountAddress) (
+	account types.Account, proof *types.StateProof, err error,
+) {
+	c.stateTreeMutex.RLock()
+	defer c.stateTreeMutex.RUnlock()
+	if c.stateTree == nil {
+		err = ErrStateProofUnavailable
+		return
+	}
+	return c.stateTree.proveAccount(addr)
+}
+
+// proveDatabase returns the profile of database id committed by the meta root of the head block
+// with its inclusion proof.
+func (c *Chain) proveDatabase(id proto.DatabaseID) (
+	profile types.SQLChainProfile, proof *types.StateProof, err error,
+) {
+	c.stateTreeMutex.RLock()
+	defer c.stateTreeMutex.RUnlock()
+	if c.stateTree == nil {
+		err = ErrStateProofUnavailable
+		return
+	}
+	return c.stateTree.proveDatabase(id)
+}
+
+// fetchStateSnapshot returns the latest saved state snapshot and the block committing it.
+func (c *Chain) fetchStateSnapshot() (snap *types.StateSnapshot, b *types.Block, err error) {
+	err = c.db.View(func(tx *bolt.Tx) (err error) {
+		meta := tx.Bucket(metaBucket[:])
+		enc, k := meta.Get(metaSnapshotKey), meta.Get(metaSnapshotBlockKey)
+		if enc == nil || k == nil {
+			return ErrNoSuchSnapshot
+		}
+		snap, b = &types.StateSnapshot{}, &types.Block{}
+		if err = snap.Deserialize(enc); err != nil {
+			return
+		}
+		return b.Deserialize(meta.Bucket(metaBlockIndexBucket).Get(k))
+	})
+	return
 }
 
 func (c *Chain) pushGenesisBlock(b *types.Block) error {
@@ -509,6 +1044,21 @@ func (c *Chain) produceBlock(now time.Time) error {
 			},
 		},
 		TxBillings: c.ti.fetchUnpackedTxBillings(),
+		Transactions: c.ms.selectTransactions(
+			maxTransactionsPerBlock, maxAccountTransactionsPerBlock),
+	}
+	var (
+		snap = c.ms.snapshot()
+		tree *stateTree
+	)
+	if tree, err = newStateTree(snap); err != nil {
+		return err
+	}
+	b.SignedHeader.MetaRoot = tree.root()
+	if h := c.rt.getHeightFromTime(now); h > 0 && h%stateSnapshotInterval == 0 {
+		if b.SignedHeader.StateRoot, err = snap.StateRoot(); err != nil {
+			return err
+		}
 	}
 
 	err = b.PackAndSignBlock(priv)
@@ -560,6 +1110,64 @@ func (c *Chain) produceBlock(now time.Time) error {
 	return err
 }
 
+// queryDatabaseUsage sums up the billings of database dbID, the remaining deposit is loaded from
+// the confirmed database profile.
+func (c *Chain) queryDatabaseUsage(dbID proto.DatabaseID) (usage DatabaseUsage, err error) {
+	profile, loaded := c.ms.loadConfirmedSQLChainProfile(dbID)
+	billings := c.ti.fetchDatabaseTxBillings(dbID)
+	if !loaded && len(billings) == 0 {
+		err = ErrDatabaseNotFound
+		return
+	}
+
+	usage.DatabaseID = dbID
+	usage.Deposit = profile.Deposit
+
+	var (
+		incomes = make(map[proto.AccountAddress]*MinerIncome)
+		order   []proto.AccountAddress
+	)
+	for _, tb := range billings {
+		header := &tb.TxContent.BillingRequest.Header
+		if usage.Billings == 0 || header.LowHeight < usage.LowHeight {
+			usage.LowHeight = header.LowHeight
+		}
+		if usage.Billings == 0 || header.HighHeight > usage.HighHeight {
+			usage.HighHeight = header.HighHeight
+		}
+		usage.Billings++
+		usage.ReadCount += header.ReadCount
+		usage.WriteCount += header.WriteCount
+		for _, v := range header.GasAmounts {
+			if v != nil {
+				usage.GasAmount += v.GasAmount
+			}
+		}
+		for i, v := range tb.TxContent.Receivers {
+			if v == nil || i >= len(tb.TxContent.Fees) || i >= len(tb.TxContent.Rewards) {
+				continue
+			}
+			income, ok := incomes[*v]
+			if !ok {
+				income = &MinerIncome{Address: *v}
+				incomes[*v] = income
+				order = append(order, *v)
+			}
+			income.Fee += tb.TxContent.Fees[i]
+			income.Reward += tb.TxContent.Rewards[i]
+		}
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		return bytes.Compare(order[i][:], order[j][:]) < 0
+	})
+	usage.Incomes = make([]MinerIncome, 0, len(order))
+	for _, v := range order {
+		usage.Incomes = append(usage.Incomes, *incomes[v])
+	}
+	return
+}
+
 func (c *Chain) produceTxBilling(br *types.BillingRequest) (_ *types.BillingResponse, err error) {
 	// TODO(lambda): simplify the function
 	if err = c.checkBillingRequest(br); err != nil {
@@ -579,7 +1187,7 @@ func (c *Chain) produceTxBilling(br *types.BillingRequest) (_ *types.BillingResp
 
 	for i, addrAndGas := range br.Header.GasAmounts {
 		receivers[i] = &addrAndGas.AccountAddress
-		fees[i] = addrAndGas.GasAmount * uint64(gasprice)
+		fees[i] = addrAndGas.GasAmount * c.ms.activeParams().GasPrice
 		rewards[i] = 0
 	}
 
@@ -614,6 +1222,7 @@ func (c *Chain) produceTxBilling(br *types.BillingRequest) (_ *types.BillingResp
 		tc = types.NewTxContent(uint32(nc), br, receivers, fees, rewards, resp)
 		tb = types.NewTxBilling(tc, types.TxTypeBilling, &c.rt.accountAddress)
 	)
+	tb.ChainID = c.chainID
 	if err = tb.Sign(privKey); err != nil {
 		return
 	}
@@ -688,6 +1297,9 @@ func (c *Chain) checkBillingRequest(br *types.BillingRequest) error {
 func (c *Chain) fetchBlockByHeight(h uint32) (*types.Block, error) {
 	node := c.st.getNode().ancestor(h)
 	if node == nil {
+		if h < atomic.LoadUint32(&c.prunedHeight) {
+			return nil, ErrBlockPruned
+		}
 		return nil, ErrNoSuchBlock
 	}
 
@@ -696,6 +1308,9 @@ func (c *Chain) fetchBlockByHeight(h uint32) (*types.Block, error) {
 
 	err := c.db.View(func(tx *bolt.Tx) error {
 		v := tx.Bucket(metaBucket[:]).Bucket(metaBlockIndexBucket).Get(k)
+		if v == nil {
+			return ErrBlockPruned
+		}
 		return b.Deserialize(v)
 	})
 	if err != nil {
@@ -731,6 +1346,15 @@ func (c *Chain) sync() error {
 		"peer": c.rt.getPeerInfoString(),
 	}).Debug("Synchronizing chain state")
 
+	if c.st.getHeight() == 0 && c.rt.getHeightFromTime(c.rt.now()) > stateSnapshotInterval {
+		// new block producer with genesis block only
+		if err := c.syncState(); err != nil {
+			log.WithFields(log.Fields{
+				"peer": c.rt.getPeerInfoString(),
+			}).WithError(err).Warning("Failed to sync state snapshot, fallback to replay blocks")
+		}
+	}
+
 	for {
 		now := c.rt.now()
 		height := c.rt.getHeightFromTime(now)
@@ -743,15 +1367,135 @@ func (c *Chain) sync() error {
 
 		for c.rt.getNextTurn() <= height {
 			// TODO(lambda): fetch blocks and txes.
+			if !c.syncBlock(c.rt.getNextTurn()) {
+				// TODO(lambda): remove it after implementing fetch
+				c.st.increaseHeightByOne()
+			}
 			c.rt.setNextTurn()
-			// TODO(lambda): remove it after implementing fetch
-			c.st.increaseHeightByOne()
 		}
 	}
 
 	return nil
 }
 
+// syncState fetches the latest state snapshot from the other peers, and restores the state if
+// it is committed by the state root of a block signed by any peer.
+func (c *Chain) syncState() (err error) {
+	var (
+		req = &FetchStateSnapshotReq{
+			Envelope: proto.Envelope{
+				// TODO(lambda): Add fields.
+			},
+		}
+		method = fmt.Sprintf("%s.%s", MainChainRPCName, "FetchStateSnapshot")
+		peers  = c.rt.getPeers()
+		resp   *FetchStateSnapshotResp
+	)
+	err = ErrNoSuchSnapshot
+	for _, s := range peers.Servers {
+		if s.ID.IsEqual(&c.rt.nodeID) {
+			continue
+		}
+		r := &FetchStateSnapshotResp{}
+		if err = c.cl.CallNode(s.ID, method, req, r); err != nil {
+			continue
+		}
+		if err = c.verifyStateSnapshot(r.Snapshot, r.Block); err != nil {
+			log.WithFields(log.Fields{
+				"peer":   c.rt.getPeerInfoString(),
+				"remote": s.ID,
+			}).WithError(err).Warning("Received invalid state snapshot")
+			continue
+		}
+		if resp == nil || r.Snapshot.Height > resp.Snapshot.Height {
+			resp = r
+		}
+	}
+	if resp == nil {
+		return
+	}
+
+	if err = c.db.Update(func(tx *bolt.Tx) (err error) {
+		if err = c.ms.restoreProcedure(resp.Snapshot)(tx); err != nil {
+			return
+		}
+		return tx.Bucket(metaBucket[:]).Put(metaSyncedBlockKey, resp.Block.SignedHeader.BlockHash[:])
+	}); err != nil {
+		return
+	}
+	if err = c.pushBlockWithoutCheck(resp.Block); err != nil {
+		return
+	}
+	for h := c.st.getHeight(); c.rt.getNextTurn() <= h; {
+		c.rt.setNextTurn()
+	}
+
+	log.WithFields(log.Fields{
+		"peer":       c.rt.getPeerInfoString(),
+		"height":     c.st.getHeight(),
+		"block_hash": resp.Block.SignedHeader.BlockHash.String(),
+	}).Info("Synchronized state snapshot")
+	return
+}
+
+// verifyStateSnapshot checks that b is signed by a peer and commits the state snapshot snap, and
+// that the transactions of b are signed.
+func (c *Chain) verifyStateSnapshot(snap *types.StateSnapshot, b *types.Block) (err error) {
+	if snap == nil || b == nil {
+		return ErrInvalidSnapshot
+	}
+	if err = b.Verify(); err != nil {
+		return
+	}
+	var signed bool
+	for _, s := range c.rt.getPeers().Servers {
+		if s.PubKey != nil && s.PubKey.IsEqual(b.SignedHeader.Signee) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return ErrInvalidSnapshot
+	}
+	var root hash.Hash
+	if root, err = snap.StateRoot(); err != nil {
+		return
+	}
+	if !snap.Head.IsEqual(&b.SignedHeader.ParentHash) || !root.IsEqual(&b.SignedHeader.StateRoot) {
+		return ErrInvalidSnapshot
+	}
+	// the transactions of b are applied to the snapshot once it is restored
+	return verifyBlockTransactions(b, c.verifyWorkers)
+}
+
+// syncBlock fetches the block of height h from the other peers and pushes it, which returns
+// whether the block is pushed.
+func (c *Chain) syncBlock(h uint32) bool {
+	req := &FetchBlockReq{
+		Envelope: proto.Envelope{
+			// TODO(lambda): Add fields.
+		},
+		Height: h,
+	}
+	method := fmt.Sprintf("%s.%s", MainChainRPCName, "FetchBlock")
+	for _, s := range c.rt.getPeers().Servers {
+		if s.ID.IsEqual(&c.rt.nodeID) {
+			continue
+		}
+		resp := &FetchBlockResp{}
+		if err := c.cl.CallNode(s.ID, method, req, resp); err != nil || resp.Block == nil {
+			continue
+		}
+		if c.rt.getHeightFromTime(resp.Block.Timestamp()) != h {
+			continue
+		}
+		if err := c.pushBlock(resp.Block); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // Start starts the chain by step:
 // 1. sync the chain
 // 2. goroutine for getting blocks
@@ -835,8 +1579,30 @@ func (c *Chain) processBlocks() {
 	}
 }
 
+// checkChainID checks that tx is signed for this chain. The legacy transaction signed without
+// chain id is accepted until the legacy transaction deadline.
+func (c *Chain) checkChainID(tx pi.Transaction) error {
+	id := tx.GetChainID()
+	if id == "" {
+		if c.legacyTxDeadline > 0 && c.st.getHeight() >= c.legacyTxDeadline {
+			return ErrLegacyTransaction
+		}
+		return nil
+	}
+	if id != c.chainID {
+		return ErrInvalidChainID
+	}
+	return nil
+}
+
 func (c *Chain) processTx(tx pi.Transaction) (err error) {
-	return c.db.Update(c.ms.applyTransactionProcedure(tx))
+	if err = c.checkChainID(tx); err == nil {
+		err = c.db.Update(c.ms.applyTransactionProcedure(tx))
+	}
+	if err != nil {
+		c.ms.failed.add(tx.GetHash(), err.Error())
+	}
+	return
 }
 
 func (c *Chain) processTxs() {
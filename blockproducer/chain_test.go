@@ -19,17 +19,22 @@ package blockproducer
 import (
 	"fmt"
 	"io/ioutil"
+	"path"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
 	"github.com/CovenantSQL/CovenantSQL/kayak"
 	"github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/coreos/bbolt"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -78,6 +83,10 @@ func TestChain(t *testing.T) {
 		// Hack for signle instance test
 		chain.rt.bpNum = 5
 
+		state, _, err := chain.queryTxState(hash.Hash{})
+		So(err, ShouldBeNil)
+		So(state, ShouldEqual, pi.TransactionStateNotFound)
+
 		for {
 			time.Sleep(testPeriod)
 			t.Logf("Chain state: head = %s, height = %d, turn = %d, nextturnstart = %s, ismyturn = %t",
@@ -280,3 +289,356 @@ func TestMultiNode(t *testing.T) {
 
 	return
 }
+
+func TestChainPruneBlocks(t *testing.T) {
+	db, err := bolt.Open(path.Join(testDataDir, t.Name()), 0600, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		c      = &Chain{db: db, ms: newMetaState(), keepBlocks: minKeepBlocks}
+		keys   = make([][]byte, 301)
+		hashes = make([]hash.Hash, 301)
+		ttype  = pi.TransactionTypeTransfer.Bytes()
+	)
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		var meta, txbk *bolt.Bucket
+		if meta, err = tx.CreateBucket(metaBucket[:]); err != nil {
+			return
+		}
+		for _, name := range [][]byte{
+			metaBlockIndexBucket, metaTxHeightIndexBucket, metaHeightTxIndexBucket,
+		} {
+			if _, err = meta.CreateBucket(name); err != nil {
+				return
+			}
+		}
+		if txbk, err = meta.CreateBucket(metaTransactionBucket); err != nil {
+			return
+		}
+		if txbk, err = txbk.CreateBucket(ttype); err != nil {
+			return
+		}
+		for i := range keys {
+			node := &blockNode{height: uint32(i), hash: generateRandomHash()}
+			keys[i], hashes[i] = node.indexKey(), generateRandomHash()
+			height := keys[i][:4]
+			if err = meta.Bucket(metaBlockIndexBucket).Put(keys[i], []byte("block")); err != nil {
+				return
+			}
+			if err = txbk.Put(hashes[i][:], []byte("tx")); err != nil {
+				return
+			}
+			if err = meta.Bucket(metaTxHeightIndexBucket).Put(hashes[i][:], height); err != nil {
+				return
+			}
+			if err = meta.Bucket(metaHeightTxIndexBucket).Put(
+				append(append([]byte{}, height...), hashes[i][:]...), ttype,
+			); err != nil {
+				return
+			}
+		}
+		return meta.Put(metaSnapshotBlockKey, keys[64])
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// archival node keeps everything
+	var pruned uint32
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		pruned, err = (&Chain{db: db}).pruneBlocks(tx, 300)
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned != 0 {
+		t.Fatalf("unexpected pruned height: %d", pruned)
+	}
+
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		pruned, err = c.pruneBlocks(tx, 300)
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned != 300-minKeepBlocks {
+		t.Fatalf("unexpected pruned height: %d", pruned)
+	}
+	atomic.StoreUint32(&c.prunedHeight, pruned)
+	if mode, h := c.chainMode(); mode != ChainModePruned || h != pruned {
+		t.Fatalf("unexpected chain mode: %v %d", mode, h)
+	}
+	if err = db.View(func(tx *bolt.Tx) (err error) {
+		meta := tx.Bucket(metaBucket[:])
+		for i := range keys {
+			block := meta.Bucket(metaBlockIndexBucket).Get(keys[i])
+			body := meta.Bucket(metaTransactionBucket).Bucket(ttype).Get(hashes[i][:])
+			kept := uint32(i) >= pruned
+			if (block != nil) != (kept || i == 0 || i == 64) || (body != nil) != kept {
+				t.Fatalf("unexpected pruning at height %d: %v %v", i, block, body)
+			}
+		}
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// heights of pruned transactions are still available
+	state, height, err := c.queryTxState(hashes[10])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != pi.TransactionStateConfirmed || height != 10 {
+		t.Fatalf("unexpected transaction state: %v %d", state, height)
+	}
+	if state, _, err = c.queryTxState(generateRandomHash()); state != pi.TransactionStateNotFound {
+		t.Fatalf("unexpected transaction state: %v", state)
+	}
+
+	// failures are reported by batched lookups unless the transaction is confirmed
+	var (
+		unknown  = generateRandomHash()
+		failed   = generateRandomHash()
+		receipts []TxReceipt
+	)
+	c.ms.failed.add(failed, ErrInvalidAccountNonce.Error())
+	c.ms.failed.add(hashes[300], ErrInvalidAccountNonce.Error())
+	if receipts, err = c.queryTxReceipts([]hash.Hash{hashes[300], failed, unknown}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(receipts) != 3 ||
+		receipts[0].State != pi.TransactionStateConfirmed || receipts[0].Height != 300 ||
+		receipts[1].State != pi.TransactionStateFailed ||
+		receipts[1].Reason != ErrInvalidAccountNonce.Error() || receipts[1].Hash != failed ||
+		receipts[2].State != pi.TransactionStateNotFound {
+		t.Fatalf("unexpected transaction receipts: %v", receipts)
+	}
+	if _, err = c.queryTxReceipts(make([]hash.Hash, maxTxReceiptsQuery+1)); err != ErrTooManyTxReceipts {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// newTestProducer returns a standalone chain producing blocks with the local key, and a funded
+// account to submit transactions.
+func newTestProducer(t *testing.T) (
+	c *Chain, cfg *Config, priv *asymmetric.PrivateKey, sender proto.AccountAddress, cleanup func(),
+) {
+	cleanup, _, _, server, err := initNode(
+		"../test/mainchain/node_standalone/config.yaml",
+		"../test/mainchain/node_standalone/private.key",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	genesis, err := generateRandomBlock(genesisHash, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	local, err := kms.GetLocalPrivateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, peers, err := createTestPeersWithPrivKeys(local, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg = NewConfig(genesis, path.Join(testDataDir, t.Name()), server, peers, peers.Servers[0].ID,
+		testPeriod, testTick)
+	if c, err = NewChain(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var pub *asymmetric.PublicKey
+	if priv, pub, err = asymmetric.GenSecp256k1KeyPair(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sender = proto.AccountAddress(hash.THashH(enc))
+	c.ms.readonly.accounts[sender] = &accountObject{Account: types.Account{
+		Address: sender, StableCoinBalance: 1000, CovenantCoinBalance: 1000,
+	}}
+	return
+}
+
+// submitTestTransfer applies a transfer of sender to the memory pool of c.
+func submitTestTransfer(
+	t *testing.T, c *Chain, priv *asymmetric.PrivateKey, sender, receiver proto.AccountAddress,
+	nonce pi.AccountNonce,
+) *types.Transfer {
+	tx := &types.Transfer{TransferHeader: types.TransferHeader{
+		Sender:    sender,
+		Receiver:  receiver,
+		Nonce:     nonce,
+		Amount:    10,
+		TokenType: types.StableCoin,
+		Fee:       1,
+	}}
+	if err := tx.Sign(priv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.db.Update(c.ms.applyTransactionProcedure(tx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tx
+}
+
+func TestProduceBlock(t *testing.T) {
+	c, cfg, priv, sender, cleanup := newTestProducer(t)
+	defer cleanup()
+	defer c.db.Close()
+
+	var (
+		receiver = proto.AccountAddress{0x2}
+		txs      = []*types.Transfer{
+			submitTestTransfer(t, c, priv, sender, receiver, 0),
+			submitTestTransfer(t, c, priv, sender, receiver, 1),
+		}
+		now = cfg.Genesis.Timestamp().Add(3 * testPeriod)
+	)
+	if err := c.produceBlock(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	height := c.st.getHeight()
+	if height != c.rt.getHeightFromTime(now) || height == 0 {
+		t.Fatalf("unexpected height: %d", height)
+	}
+
+	// the pending transactions are packed in the produced block
+	b, err := c.fetchBlockByHeight(height)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b.Transactions) != len(txs) {
+		t.Fatalf("unexpected block transactions: %v", b.Transactions)
+	}
+	for i, tx := range txs {
+		if b.Transactions[i].GetHash() != tx.GetHash() {
+			t.Fatalf("unexpected block transaction #%d: %v", i, b.Transactions[i])
+		}
+	}
+
+	// and confirmed at the height of block
+	receipts, err := c.queryTxReceipts([]hash.Hash{txs[0].GetHash(), txs[1].GetHash()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range receipts {
+		if r.State != pi.TransactionStateConfirmed || r.Height != height {
+			t.Fatalf("unexpected transaction receipt: %v", r)
+		}
+	}
+	if stable, _, loaded := c.ms.loadAccountBalance(receiver); !loaded || stable != 20 {
+		t.Fatalf("unexpected receiver balance: %d %v", stable, loaded)
+	}
+}
+
+func TestReplaySnapshotBlock(t *testing.T) {
+	c, cfg, priv, sender, cleanup := newTestProducer(t)
+	defer cleanup()
+	defer c.db.Close()
+
+	receiver := proto.AccountAddress{0x2}
+	for i := 0; i < 2; i++ {
+		submitTestTransfer(t, c, priv, sender, receiver, pi.AccountNonce(i))
+	}
+	snap := c.ms.snapshot()
+	snap.Head = *c.st.getHeader()
+	now := cfg.Genesis.Timestamp().Add(time.Duration(stateSnapshotInterval) * testPeriod)
+	if err := c.produceBlock(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := c.fetchBlockByHeight(c.st.getHeight())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fcfg := *cfg
+	fcfg.DataFile = cfg.DataFile + ".synced"
+	f, err := NewChain(&fcfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.db.Close()
+	if err = f.verifyStateSnapshot(snap, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tampered := *b
+	tampered.Transactions = append([]pi.Transaction{}, b.Transactions...)
+	tx := *b.Transactions[0].(*types.Transfer)
+	tx.Amount++
+	tampered.Transactions[0] = &tx
+	if err = f.verifyStateSnapshot(snap, &tampered); err != types.ErrSignVerification {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the block committing the snapshot is replayed on the restored state
+	if err = f.db.Update(f.ms.restoreProcedure(snap)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = f.pushBlockWithoutCheck(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, addr := range []proto.AccountAddress{sender, receiver, b.Producer()} {
+		stable, covenant, _ := c.ms.loadAccountBalance(addr)
+		fstable, fcovenant, _ := f.ms.loadAccountBalance(addr)
+		if stable != fstable || covenant != fcovenant {
+			t.Fatalf("unexpected synced balance of %v: %d %d, expected %d %d",
+				addr, fstable, fcovenant, stable, covenant)
+		}
+	}
+	if stable, _, _ := f.ms.loadAccountBalance(receiver); stable != 20 {
+		t.Fatalf("unexpected receiver balance: %d", stable)
+	}
+}
+
+func TestProducedBlockPruning(t *testing.T) {
+	c, cfg, priv, sender, cleanup := newTestProducer(t)
+	defer cleanup()
+	defer c.db.Close()
+	c.keepBlocks = minKeepBlocks
+
+	tx := submitTestTransfer(t, c, priv, sender, proto.AccountAddress{0x2}, 0)
+	if err := c.produceBlock(cfg.Genesis.Timestamp().Add(testPeriod)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	txHeight := c.st.getHeight()
+	loadTx := func() (body []byte) {
+		if err := c.db.View(func(btx *bolt.Tx) error {
+			h := tx.GetHash()
+			body = btx.Bucket(metaBucket[:]).Bucket(metaTransactionBucket).Bucket(
+				tx.GetTransactionType().Bytes()).Get(h[:])
+			return nil
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if loadTx() == nil {
+		t.Fatal("unexpected missing transaction")
+	}
+
+	// the transaction of the block out of the kept range is pruned
+	now := cfg.Genesis.Timestamp().Add(time.Duration(txHeight+minKeepBlocks+1) * testPeriod)
+	if err := c.produceBlock(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned := atomic.LoadUint32(&c.prunedHeight); pruned <= txHeight {
+		t.Fatalf("unexpected pruned height: %d", pruned)
+	}
+	if loadTx() != nil {
+		t.Fatal("unexpected transaction kept after pruning")
+	}
+	// the height of pruned transaction is still available
+	state, height, err := c.queryTxState(tx.GetHash())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != pi.TransactionStateConfirmed || height != txHeight {
+		t.Fatalf("unexpected transaction state: %v %d", state, height)
+	}
+}
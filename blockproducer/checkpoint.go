@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/coreos/bbolt"
+)
+
+// checkpointInterval defines the blocks between checkpoints, the block at each multiple of the
+// interval is co-signed by the block producers as a finality marker.
+const checkpointInterval uint32 = 16
+
+// signCheckpoint signs the checkpoint of block b at height h if it's a checkpoint block, and
+// advises the signature to the other peers.
+func (c *Chain) signCheckpoint(h uint32, b *types.Block) {
+	if h == 0 || h%checkpointInterval != 0 {
+		return
+	}
+	header := &types.CheckpointHeader{
+		Height:    h,
+		BlockHash: b.SignedHeader.BlockHash,
+		MetaRoot:  b.SignedHeader.MetaRoot,
+	}
+	cp, err := types.NewCheckpoint(header)
+	if err != nil {
+		log.WithError(err).Warning("create checkpoint failed")
+		return
+	}
+	priv, err := kms.GetLocalPrivateKey()
+	if err != nil {
+		log.WithError(err).Warning("get local private key failed")
+		return
+	}
+	sig, err := cp.Sign(priv)
+	if err != nil {
+		log.WithError(err).Warning("sign checkpoint failed")
+		return
+	}
+	if err = c.addCheckpointSignature(header, sig); err != nil {
+		log.WithError(err).Warning("add checkpoint signature failed")
+		return
+	}
+
+	req := &AdviseCheckpointReq{Header: *header, Signature: sig}
+	method := fmt.Sprintf("%s.%s", MainChainRPCName, "AdviseCheckpoint")
+	for _, s := range c.rt.getPeers().Servers {
+		if !s.ID.IsEqual(&c.rt.nodeID) {
+			go func(id proto.NodeID) {
+				if err := c.cl.CallNode(id, method, req, &AdviseCheckpointResp{}); err != nil {
+					log.WithFields(log.Fields{
+						"peer":   c.rt.getPeerInfoString(),
+						"height": h,
+						"remote": id,
+					}).WithError(err).Debug("Failed to advise checkpoint signature")
+				}
+			}(s.ID)
+		}
+	}
+}
+
+// addCheckpointSignature adds the signature of a peer to the checkpoint of header, the
+// checkpoint is saved once it's signed by a quorum of peers. Signatures of different headers at
+// the same height are collected separately, so at most one of them could be finalized.
+func (c *Chain) addCheckpointSignature(header *types.CheckpointHeader, sig types.CheckpointSignature) (err error) {
+	var (
+		peers  = c.rt.getPeers()
+		signed bool
+	)
+	for _, s := range peers.Servers {
+		if s.PubKey != nil && s.PubKey.IsEqual(sig.Signee) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return ErrUnknownCheckpointSignee
+	}
+	if _, err = c.loadCheckpoint(header.Height); err == nil {
+		// already finalized
+		return
+	} else if err != ErrCheckpointNotFound {
+		return
+	}
+
+	cp, err := types.NewCheckpoint(header)
+	if err != nil {
+		return
+	}
+	c.checkpointMutex.Lock()
+	defer c.checkpointMutex.Unlock()
+	if c.checkpoints == nil {
+		c.checkpoints = make(map[hash.Hash]*types.Checkpoint)
+	}
+	if pending, ok := c.checkpoints[cp.HeaderHash]; ok {
+		cp = pending
+	} else {
+		c.checkpoints[cp.HeaderHash] = cp
+	}
+	if _, err = cp.AddSignature(sig); err != nil {
+		return
+	}
+	if len(cp.Signatures) < types.CheckpointQuorum(len(peers.Servers)) {
+		return
+	}
+
+	enc, err := utils.EncodeMsgPack(cp)
+	if err != nil {
+		return
+	}
+	if err = c.db.Update(func(tx *bolt.Tx) (err error) {
+		cb, err := tx.Bucket(metaBucket[:]).CreateBucketIfNotExists(metaCheckpointBucket)
+		if err != nil {
+			return
+		}
+		return cb.Put(checkpointKey(cp.Height), enc.Bytes())
+	}); err != nil {
+		return
+	}
+	for k, v := range c.checkpoints {
+		if v.Height <= cp.Height {
+			delete(c.checkpoints, k)
+		}
+	}
+	log.WithFields(log.Fields{
+		"peer":       c.rt.getPeerInfoString(),
+		"height":     cp.Height,
+		"block_hash": cp.BlockHash.String(),
+	}).Info("Finalized checkpoint")
+	return
+}
+
+func checkpointKey(h uint32) (key []byte) {
+	key = make([]byte, 4)
+	binary.BigEndian.PutUint32(key, h)
+	return
+}
+
+// loadCheckpoint returns the finalized checkpoint at height h, or the latest one if h is 0.
+func (c *Chain) loadCheckpoint(h uint32) (cp *types.Checkpoint, err error) {
+	err = c.db.View(func(tx *bolt.Tx) (err error) {
+		var v []byte
+		if cb := tx.Bucket(metaBucket[:]).Bucket(metaCheckpointBucket); cb != nil {
+			if h == 0 {
+				_, v = cb.Cursor().Last()
+			} else {
+				v = cb.Get(checkpointKey(h))
+			}
+		}
+		if v == nil {
+			return ErrCheckpointNotFound
+		}
+		return utils.DecodeMsgPack(v, &cp)
+	})
+	return
+}
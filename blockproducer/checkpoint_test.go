@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"path"
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/kayak"
+	"github.com/coreos/bbolt"
+)
+
+func TestCheckpointSignatures(t *testing.T) {
+	db, err := bolt.Open(path.Join(testDataDir, t.Name()), 0600, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		_, err = tx.CreateBucketIfNotExists(metaBucket[:])
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var (
+		privs   = make([]*asymmetric.PrivateKey, 4)
+		signees = make([]*asymmetric.PublicKey, 4)
+		peers   = &kayak.Peers{}
+	)
+	for i := range privs {
+		if privs[i], signees[i], err = asymmetric.GenSecp256k1KeyPair(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		peers.Servers = append(peers.Servers, &kayak.Server{PubKey: signees[i]})
+	}
+	var (
+		c      = &Chain{db: db, rt: &rt{peers: peers}}
+		header = &types.CheckpointHeader{Height: checkpointInterval, BlockHash: hash.Hash{0x1}}
+		forked = &types.CheckpointHeader{Height: checkpointInterval, BlockHash: hash.Hash{0x2}}
+	)
+	sign := func(header *types.CheckpointHeader, priv *asymmetric.PrivateKey) types.CheckpointSignature {
+		cp, err := types.NewCheckpoint(header)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sig, err := cp.Sign(priv)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return sig
+	}
+
+	other, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.addCheckpointSignature(header, sign(header, other)); err != ErrUnknownCheckpointSignee {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = c.addCheckpointSignature(header, sign(header, privs[i])); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// a signature of another header doesn't count
+	if err = c.addCheckpointSignature(header, sign(forked, privs[2])); err != types.ErrSignVerification {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.addCheckpointSignature(forked, sign(forked, privs[2])); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = c.loadCheckpoint(0); err != ErrCheckpointNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = c.addCheckpointSignature(header, sign(header, privs[3])); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, h := range []uint32{0, checkpointInterval} {
+		cp, err := c.loadCheckpoint(h)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cp.BlockHash != header.BlockHash || len(cp.Signatures) != 3 {
+			t.Fatalf("unexpected checkpoint: %v", cp)
+		}
+		if err = cp.Verify(signees, types.CheckpointQuorum(len(signees))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(c.checkpoints) != 0 {
+		t.Fatalf("unexpected pending checkpoints: %d", len(c.checkpoints))
+	}
+
+	// the finalized checkpoint is never replaced
+	if err = c.addCheckpointSignature(forked, sign(forked, privs[3])); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.checkpoints) != 0 {
+		t.Fatalf("unexpected pending checkpoints: %d", len(c.checkpoints))
+	}
+	if _, err = c.loadCheckpoint(2 * checkpointInterval); err != ErrCheckpointNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -19,6 +19,7 @@ package blockproducer
 import (
 	"time"
 
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
 	"github.com/CovenantSQL/CovenantSQL/kayak"
 	"github.com/CovenantSQL/CovenantSQL/proto"
@@ -42,6 +43,59 @@ type Config struct {
 
 	Period time.Duration
 	Tick   time.Duration
+
+	// Authorities defines the accounts voting for chain parameter updates, an update is
+	// scheduled by AuthorityThreshold votes, or by the majority of authorities if it's not set.
+	Authorities        []proto.AccountAddress
+	AuthorityThreshold int
+
+	// KeepBlocks defines the recent blocks kept by a pruned node, or 0 for an archival node
+	// keeping the full history.
+	KeepBlocks uint32
+
+	// VerifyWorkers defines the goroutines verifying transactions of received blocks, the number
+	// of CPUs is used if it's not set.
+	VerifyWorkers int
+
+	// MempoolTTL defines how long a pending transaction is kept in the persisted memory pool, the
+	// older ones are expired on startup. 24 hours is used if it's not set.
+	MempoolTTL time.Duration
+
+	// AccountTxRate and IPTxRate define the transactions submitted per second by an account and
+	// from an IP address, AccountTxBurst and IPTxBurst define the transactions allowed in a burst.
+	// Submissions are not limited by a rate which is not set.
+	AccountTxRate  float64
+	AccountTxBurst int
+	IPTxRate       float64
+	IPTxBurst      int
+
+	// ChainID identifies the network, transactions signed for other chains are rejected to prevent
+	// replaying. The legacy transactions signed without chain id are accepted for migration, until
+	// the LegacyTxDeadline height if it's set.
+	ChainID          pi.ChainID
+	LegacyTxDeadline uint32
+}
+
+// ChainMode defines the block history kept by a block producer.
+type ChainMode int
+
+const (
+	// ChainModeArchival keeps the full block history.
+	ChainModeArchival ChainMode = iota
+	// ChainModePruned keeps the recent blocks, and the full account and database state.
+	ChainModePruned
+)
+
+// String implements fmt.Stringer.
+func (m ChainMode) String() string {
+	switch m {
+	case ChainModeArchival:
+		return "Archival"
+	case ChainModePruned:
+		return "Pruned"
+	default:
+		return "Unknown"
+	}
 }
 
 // NewConfig creates new config.
@@ -59,3 +113,12 @@ func NewConfig(genesis *types.Block, dataFile string,
 	}
 	return &config
 }
+
+// defaultChainParams returns the chain parameters before any update takes effect.
+func defaultChainParams(period time.Duration) types.ChainParams {
+	return types.ChainParams{
+		BlockPeriod:   period,
+		GasPrice:      uint64(gasprice),
+		MinMinerStake: minMinerStake,
+	}
+}
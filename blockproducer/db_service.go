@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
 	"github.com/CovenantSQL/CovenantSQL/consistent"
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
@@ -52,17 +53,16 @@ var (
 	}
 )
 
-type allocatedNode struct {
-	NodeID       proto.NodeID
-	MemoryMetric uint64
-}
-
 // DBService defines block producer database service rpc endpoint.
 type DBService struct {
 	AllocationRounds int
 	ServiceMap       *DBServiceMap
 	Consistent       *consistent.Consistent
 	NodeMetrics      *metric.NodeMetricMap
+	// Chain defines the main chain to lookup database users and deposits, optional.
+	Chain *Chain
+	// Matcher defines the policy of matching databases to miners, optional.
+	Matcher Matcher
 
 	// include block producer nodes for database allocation, for test case injection
 	includeBPNodesForAllocation bool
@@ -78,6 +78,24 @@ func (s *DBService) CreateDatabase(req *CreateDatabaseRequest, resp *CreateDatab
 	// TODO(xq262144): verify identity
 	// verify identity
 
+	// verify resource requirements
+	params := s.chainParams()
+	if err = verifyResourceMeta(&req.Header.ResourceMeta, params.GasPrice); err != nil {
+		return
+	}
+	meta := req.Header.ResourceMeta
+	if meta.Space == 0 {
+		meta.Space = params.DefaultSpace
+	}
+	if meta.Memory == 0 {
+		meta.Memory = params.DefaultMemory
+	}
+
+	var owner proto.AccountAddress
+	if owner, err = pubKeyToAccountAddress(req.Header.Signee); err != nil {
+		return
+	}
+
 	// create random DatabaseID
 	var dbID proto.DatabaseID
 	if dbID, err = s.generateDatabaseID(req.GetNodeID()); err != nil {
@@ -86,13 +104,13 @@ func (s *DBService) CreateDatabase(req *CreateDatabaseRequest, resp *CreateDatab
 
 	// allocate nodes
 	var peers *kayak.Peers
-	if peers, err = s.allocateNodes(0, dbID, req.Header.ResourceMeta); err != nil {
+	if peers, err = s.allocateNodes(0, dbID, meta); err != nil {
 		return
 	}
 
 	// TODO(lambda): call accounting features, top up deposit
 	var genesisBlock *ct.Block
-	if genesisBlock, err = s.generateGenesisBlock(dbID, req.Header.ResourceMeta); err != nil {
+	if genesisBlock, err = s.generateGenesisBlock(dbID, meta); err != nil {
 		return
 	}
 
@@ -118,6 +136,7 @@ func (s *DBService) CreateDatabase(req *CreateDatabaseRequest, resp *CreateDatab
 	initSvcReq.Header.Instance = wt.ServiceInstance{
 		DatabaseID:   dbID,
 		Peers:        peers,
+		ResourceMeta: meta,
 		GenesisBlock: genesisBlock,
 	}
 	initSvcReq.Header.Signee = pubKey
@@ -143,8 +162,9 @@ func (s *DBService) CreateDatabase(req *CreateDatabaseRequest, resp *CreateDatab
 	instanceMeta := wt.ServiceInstance{
 		DatabaseID:   dbID,
 		Peers:        peers,
-		ResourceMeta: req.Header.ResourceMeta,
+		ResourceMeta: meta,
 		GenesisBlock: genesisBlock,
+		Owner:        owner,
 	}
 
 	log.Debugf("generated instance meta: %v", instanceMeta)
@@ -155,6 +175,12 @@ func (s *DBService) CreateDatabase(req *CreateDatabaseRequest, resp *CreateDatab
 		return err
 	}
 
+	if s.Chain != nil {
+		if err := s.Chain.addDatabaseEvent(owner, dbID); err != nil {
+			log.WithError(err).Warningf("add database created event of %s failed", dbID)
+		}
+	}
+
 	// send response to client
 	resp.Header.InstanceMeta = instanceMeta
 	resp.Header.Signee = pubKey
@@ -275,6 +301,80 @@ func (s *DBService) GetNodeDatabases(req *wt.InitService, resp *wt.InitServiceRe
 	return
 }
 
+// ListDatabases defines block producer list databases logic, databases created by the request
+// signee account or shared with it by main chain permissions are listed.
+func (s *DBService) ListDatabases(req *ListDatabasesRequest, resp *ListDatabasesResponse) (err error) {
+	// verify signature
+	if err = req.Verify(); err != nil {
+		return
+	}
+
+	var addr proto.AccountAddress
+	if addr, err = pubKeyToAccountAddress(req.Header.Signee); err != nil {
+		return
+	}
+
+	// fetch owned databases from meta
+	var instances []wt.ServiceInstance
+	if instances, err = s.ServiceMap.GetOwnerDatabases(addr); err != nil {
+		return
+	}
+
+	var (
+		owned     = make(map[proto.DatabaseID]bool)
+		databases = make([]DatabaseProfile, 0, len(instances))
+	)
+	for _, instance := range instances {
+		owned[instance.DatabaseID] = true
+		profile := DatabaseProfile{
+			InstanceMeta: instance,
+		}
+		if s.Chain != nil {
+			if p, ok := s.Chain.ms.loadConfirmedSQLChainProfile(instance.DatabaseID); ok {
+				profile.Deposit = p.Deposit
+			}
+		}
+		databases = append(databases, profile)
+	}
+
+	// fetch shared databases from main chain
+	if s.Chain != nil {
+		for _, p := range s.Chain.ms.loadConfirmedUserSQLChainProfiles(addr) {
+			if owned[p.ID] {
+				continue
+			}
+			var instance wt.ServiceInstance
+			if instance, err = s.ServiceMap.Get(p.ID); err != nil {
+				// database is not served by database service
+				err = nil
+				continue
+			}
+			databases = append(databases, DatabaseProfile{
+				InstanceMeta: instance,
+				Shared:       true,
+				Deposit:      p.Deposit,
+			})
+		}
+	}
+
+	sort.Slice(databases, func(i, j int) bool {
+		return databases[i].InstanceMeta.DatabaseID < databases[j].InstanceMeta.DatabaseID
+	})
+
+	// send response to client
+	resp.Header.Databases = databases
+	if resp.Header.Signee, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	err = resp.Sign(privateKey)
+
+	return
+}
+
 func (s *DBService) generateDatabaseID(reqNodeID *proto.RawNodeID) (dbID proto.DatabaseID, err error) {
 	var startNonce cpuminer.Uint256
 
@@ -307,10 +407,33 @@ func (s *DBService) generateDatabaseID(reqNodeID *proto.RawNodeID) (dbID proto.D
 	}
 }
 
+// chainParams returns the chain parameters in effect, or the defaults if main chain is not set.
+func (s *DBService) chainParams() pt.ChainParams {
+	if s.Chain == nil {
+		return defaultChainParams(0)
+	}
+	return s.Chain.ms.activeParams()
+}
+
+func verifyResourceMeta(meta *wt.ResourceMeta, gasPrice uint64) (err error) {
+	if meta.ConsistencyLevel < 0 || meta.ConsistencyLevel > 1 {
+		return ErrInvalidResourceMeta
+	}
+	if meta.GasPrice != 0 && meta.GasPrice < gasPrice {
+		return ErrGasPriceTooLow
+	}
+	if max := meta.Constraints.MaxGasPrice; max != 0 {
+		if meta.GasPrice > max || (meta.GasPrice == 0 && gasPrice > max) {
+			return ErrGasPriceTooHigh
+		}
+	}
+	return
+}
+
 func (s *DBService) allocateNodes(lastTerm uint64, dbID proto.DatabaseID, resourceMeta wt.ResourceMeta) (peers *kayak.Peers, err error) {
 	curRange := int(resourceMeta.Node)
 	excludeNodes := make(map[proto.NodeID]bool)
-	var allocated []allocatedNode
+	var candidates []MinerCandidate
 
 	if resourceMeta.Node <= 0 {
 		err = ErrDatabaseAllocation
@@ -329,8 +452,8 @@ func (s *DBService) allocateNodes(lastTerm uint64, dbID proto.DatabaseID, resour
 
 		var nodes []proto.Node
 
-		// clear previous allocated
-		allocated = allocated[:0]
+		// clear previous candidates
+		candidates = candidates[:0]
 		rolesFilter := []proto.ServerRole{
 			proto.Miner,
 		}
@@ -347,7 +470,7 @@ func (s *DBService) allocateNodes(lastTerm uint64, dbID proto.DatabaseID, resour
 		var nodeIDs []proto.NodeID
 
 		for _, node := range nodes {
-			if _, ok := excludeNodes[node.ID]; !ok {
+			if _, ok := excludeNodes[node.ID]; !ok && s.minerAvailable(node.ID, &resourceMeta) {
 				nodeIDs = append(nodeIDs, node.ID)
 			}
 		}
@@ -381,9 +504,10 @@ func (s *DBService) allocateNodes(lastTerm uint64, dbID proto.DatabaseID, resour
 
 			if resourceMeta.Memory < metricValue {
 				// can allocate
-				allocated = append(allocated, allocatedNode{
-					NodeID:       nodeID,
-					MemoryMetric: metricValue,
+				candidates = append(candidates, MinerCandidate{
+					NodeID:     nodeID,
+					Profile:    s.minerProfile(nodeID),
+					FreeMemory: metricValue,
 				})
 			} else {
 				log.Debugf("node %s memory metric does not meet requirements", nodeID)
@@ -391,21 +515,13 @@ func (s *DBService) allocateNodes(lastTerm uint64, dbID proto.DatabaseID, resour
 			}
 		}
 
-		if len(allocated) >= int(resourceMeta.Node) {
-			// sort allocated node by metric
-			sort.Slice(allocated, func(i, j int) bool {
-				return allocated[i].MemoryMetric > allocated[j].MemoryMetric
-			})
-
-			allocated = allocated[:int(resourceMeta.Node)]
-
-			// build plain allocated slice
-			nodeAllocated := make([]proto.NodeID, 0, len(allocated))
-
-			for _, node := range allocated {
-				nodeAllocated = append(nodeAllocated, node.NodeID)
-			}
-
+		// sort candidates to match the same way with the same metrics
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].NodeID < candidates[j].NodeID
+		})
+		if nodeAllocated := s.matchMiners(
+			&resourceMeta, candidates, int(resourceMeta.Node),
+		); len(nodeAllocated) >= int(resourceMeta.Node) {
 			// build peers
 			return s.buildPeers(lastTerm+1, nodes, nodeAllocated)
 		}
@@ -418,6 +534,192 @@ func (s *DBService) allocateNodes(lastTerm uint64, dbID proto.DatabaseID, resour
 	return
 }
 
+// minerAvailable reports whether the node could serve a database of resource requirements and
+// constraints. Once any miner is registered on main chain, only the registered miners accepting the
+// gas price and providing enough space are available, and miners with pending service challenges
+// are skipped. The constraints on miner profiles are never satisfied by unregistered miners.
+func (s *DBService) minerAvailable(nodeID proto.NodeID, meta *wt.ResourceMeta) bool {
+	constraints := &meta.Constraints
+	if constraints.Excludes(nodeID) {
+		return false
+	}
+	if s.Chain == nil {
+		return !constraints.RequireProfile()
+	}
+	miner, registered := s.Chain.ms.loadConfirmedMiner(nodeID)
+	if !registered {
+		return !constraints.RequireProfile() && !s.Chain.ms.hasConfirmedMiners()
+	}
+	price := meta.GasPrice
+	if price == 0 {
+		price = s.Chain.ms.activeParams().GasPrice
+	}
+	return len(miner.Challenges) == 0 &&
+		miner.GasPrice <= price && (miner.Space == 0 || miner.Space >= meta.Space) &&
+		constraints.Accept(miner.Region, miner.Stake, miner.GasPrice)
+}
+
+// minerProfile returns the profile of miner registered on main chain, or nil if not registered.
+func (s *DBService) minerProfile(nodeID proto.NodeID) *pt.MinerProfile {
+	if s.Chain == nil {
+		return nil
+	}
+	if miner, registered := s.Chain.ms.loadConfirmedMiner(nodeID); registered {
+		return &miner
+	}
+	return nil
+}
+
+// ReprovisionNode moves the databases served by the node to other miners, which is called when
+// the miner is deregistered from main chain.
+func (s *DBService) ReprovisionNode(nodeID proto.NodeID) {
+	instances, err := s.ServiceMap.GetDatabases(nodeID)
+	if err != nil {
+		log.WithField("node", nodeID).WithError(err).Error("get databases of node failed")
+		return
+	}
+	for _, instance := range instances {
+		if err = s.reprovisionDatabase(instance, nodeID); err != nil {
+			log.WithFields(log.Fields{
+				"database": instance.DatabaseID,
+				"node":     nodeID,
+			}).WithError(err).Error("reprovision database failed")
+		}
+	}
+}
+
+// reprovisionDatabase replaces the leaving node of database with the available miner with most
+// free memory, the other peers and the leader are kept.
+func (s *DBService) reprovisionDatabase(instance wt.ServiceInstance, leaving proto.NodeID) (err error) {
+	if instance.Peers == nil || len(instance.Peers.Servers) == 0 {
+		return ErrInvalidDBPeersConfig
+	}
+
+	// keep remaining peers with the leader first, see buildPeers
+	var (
+		remaining    []proto.Node
+		excludeNodes = map[proto.NodeID]bool{leaving: true}
+		leader       = instance.Peers.Leader
+	)
+	if leader != nil && leader.ID != leaving {
+		remaining = append(remaining, proto.Node{ID: leader.ID, PublicKey: leader.PubKey})
+	}
+	for _, server := range instance.Peers.Servers {
+		excludeNodes[server.ID] = true
+		if server.ID != leaving && (leader == nil || server.ID != leader.ID) {
+			remaining = append(remaining, proto.Node{ID: server.ID, PublicKey: server.PubKey})
+		}
+	}
+	if !s.includeBPNodesForAllocation {
+		for _, nodeID := range route.GetBPs() {
+			excludeNodes[nodeID] = true
+		}
+	}
+
+	// find a replacement
+	var nodes []proto.Node
+	if nodes, err = s.Consistent.GetNeighborsEx(string(instance.DatabaseID),
+		len(instance.Peers.Servers)+int(instance.ResourceMeta.Node),
+		proto.ServerRoles([]proto.ServerRole{proto.Miner})); err != nil {
+		return
+	}
+	var (
+		available   []proto.NodeID
+		candidates  []MinerCandidate
+		replacement *proto.NodeID
+	)
+	for _, node := range nodes {
+		if !excludeNodes[node.ID] && s.minerAvailable(node.ID, &instance.ResourceMeta) {
+			available = append(available, node.ID)
+		}
+	}
+	for nodeID, nodeMetric := range s.NodeMetrics.GetMetrics(available) {
+		var metricValue uint64
+		if metricValue, err = s.getMetric(nodeMetric, MetricKeyFreeMemory); err != nil {
+			err = nil
+			continue
+		}
+		if instance.ResourceMeta.Memory < metricValue {
+			candidates = append(candidates, MinerCandidate{
+				NodeID:     nodeID,
+				Profile:    s.minerProfile(nodeID),
+				FreeMemory: metricValue,
+			})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].NodeID < candidates[j].NodeID
+	})
+	if selected := s.matchMiners(&instance.ResourceMeta, candidates, 1); len(selected) > 0 {
+		replacement = &selected[0]
+	}
+
+	allocatedNodes := remaining
+	if replacement != nil {
+		for _, node := range nodes {
+			if node.ID == *replacement {
+				allocatedNodes = append(allocatedNodes, node)
+			}
+		}
+	} else {
+		log.WithFields(log.Fields{
+			"database": instance.DatabaseID,
+			"node":     leaving,
+		}).Warning("no replacement for leaving node, database runs with less peers")
+	}
+	if len(allocatedNodes) == 0 {
+		return ErrDatabaseAllocation
+	}
+	allocated := make([]proto.NodeID, 0, len(allocatedNodes))
+	for _, node := range allocatedNodes {
+		allocated = append(allocated, node.ID)
+	}
+
+	var peers *kayak.Peers
+	if peers, err = s.buildPeers(instance.Peers.Term+1, allocatedNodes, allocated); err != nil {
+		return
+	}
+	instance.Peers = peers
+
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	newSvcReq := func(op wt.UpdateType) (req *wt.UpdateService, err error) {
+		req = new(wt.UpdateService)
+		req.Header.Op = op
+		req.Header.Instance = instance
+		if req.Header.Signee, err = kms.GetLocalPublicKey(); err != nil {
+			return
+		}
+		err = req.Sign(privateKey)
+		return
+	}
+
+	// the replacement creates the database, and the remaining peers update peers
+	var updateReq, createReq, dropReq *wt.UpdateService
+	if updateReq, err = newSvcReq(wt.UpdateDB); err != nil {
+		return
+	}
+	if err = s.batchSendSingleSvcReq(updateReq, allocated[:len(remaining)]); err != nil {
+		return
+	}
+	if replacement != nil {
+		if createReq, err = newSvcReq(wt.CreateDB); err != nil {
+			return
+		}
+		if err = s.batchSendSingleSvcReq(createReq, []proto.NodeID{*replacement}); err != nil {
+			return
+		}
+	}
+	if dropReq, err = newSvcReq(wt.DropDB); err == nil {
+		// the leaving node may be offline already
+		s.batchSendSingleSvcReq(dropReq, []proto.NodeID{leaving})
+	}
+
+	return s.ServiceMap.Set(instance)
+}
+
 func (s *DBService) getMetric(metric metric.MetricMap, keys []string) (value uint64, err error) {
 	for _, key := range keys {
 		var rawMetric *dto.MetricFamily
@@ -570,3 +872,16 @@ func (s *DBService) peersToNodes(peers *kayak.Peers) (nodes []proto.NodeID) {
 
 	return
 }
+
+func pubKeyToAccountAddress(pubKey *asymmetric.PublicKey) (addr proto.AccountAddress, err error) {
+	if pubKey == nil {
+		err = wt.ErrSignVerification
+		return
+	}
+	var enc []byte
+	if enc, err = pubKey.MarshalHash(); err != nil {
+		return
+	}
+	addr = proto.AccountAddress(hash.THashH(enc))
+	return
+}
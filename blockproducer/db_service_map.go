@@ -172,3 +172,19 @@ func (c *DBServiceMap) GetDatabases(nodeID proto.NodeID) (dbs []wt.ServiceInstan
 
 	return
 }
+
+// GetOwnerDatabases returns databases created by the account.
+func (c *DBServiceMap) GetOwnerDatabases(owner proto.AccountAddress) (dbs []wt.ServiceInstance, err error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	dbs = make([]wt.ServiceInstance, 0)
+
+	for _, db := range c.dbMap {
+		if db.Owner == owner {
+			dbs = append(dbs, db)
+		}
+	}
+
+	return
+}
@@ -136,6 +136,20 @@ func TestService(t *testing.T) {
 			createDBRes.Header.InstanceMeta.DatabaseID,
 		})
 
+		// list databases, only the new database is owned by the account
+		listReq := new(ListDatabasesRequest)
+		listReq.Header.Signee = pubKey
+		err = listReq.Sign(privateKey)
+		So(err, ShouldBeNil)
+		listRes := new(ListDatabasesResponse)
+		err = rpc.NewCaller().CallNode(nodeID, route.BPDBListDatabases.String(), listReq, listRes)
+		So(err, ShouldBeNil)
+		So(listRes.Verify(), ShouldBeNil)
+		So(listRes.Header.Databases, ShouldHaveLength, 1)
+		So(listRes.Header.Databases[0].InstanceMeta.DatabaseID, ShouldEqual,
+			createDBRes.Header.InstanceMeta.DatabaseID)
+		So(listRes.Header.Databases[0].Shared, ShouldBeFalse)
+
 		// use the database
 		serverID := createDBRes.Header.InstanceMeta.Peers.Leader.ID
 		dbID := createDBRes.Header.InstanceMeta.DatabaseID
@@ -181,6 +195,12 @@ func TestService(t *testing.T) {
 		So(err, ShouldBeNil)
 		err = rpc.NewCaller().CallNode(nodeID, route.BPDBGetDatabase.String(), getReq, getRes)
 		So(err, ShouldNotBeNil)
+
+		// list databases again, the dropped database should not exist
+		listRes = new(ListDatabasesResponse)
+		err = rpc.NewCaller().CallNode(nodeID, route.BPDBListDatabases.String(), listReq, listRes)
+		So(err, ShouldBeNil)
+		So(listRes.Header.Databases, ShouldBeEmpty)
 	})
 }
 
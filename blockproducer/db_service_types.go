@@ -17,6 +17,9 @@
 package blockproducer
 
 import (
+	"bytes"
+	"encoding/binary"
+
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
@@ -338,6 +341,160 @@ func (r *GetDatabaseResponse) Sign(signer *asymmetric.PrivateKey) (err error) {
 	return r.Header.Sign(signer)
 }
 
+// ListDatabasesRequestHeader defines client list databases rpc request header entity, the
+// databases of the signee account are listed.
+type ListDatabasesRequestHeader struct{}
+
+// Serialize structure to bytes.
+func (h *ListDatabasesRequestHeader) Serialize() []byte {
+	if h == nil {
+		return []byte{'\000'}
+	}
+
+	return []byte{}
+}
+
+// SignedListDatabasesRequestHeader defines signed client list databases rpc request header entity.
+type SignedListDatabasesRequestHeader struct {
+	ListDatabasesRequestHeader
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Verify checks hash and signature in request header.
+func (sh *SignedListDatabasesRequestHeader) Verify() (err error) {
+	// verify hash
+	if err = verifyHash(&sh.ListDatabasesRequestHeader, &sh.HeaderHash); err != nil {
+		return
+	}
+	// verify sign
+	if sh.Signee == nil || sh.Signature == nil || !sh.Signature.Verify(sh.HeaderHash[:], sh.Signee) {
+		return wt.ErrSignVerification
+	}
+	return
+}
+
+// Sign the request.
+func (sh *SignedListDatabasesRequestHeader) Sign(signer *asymmetric.PrivateKey) (err error) {
+	// build hash
+	buildHash(&sh.ListDatabasesRequestHeader, &sh.HeaderHash)
+
+	// sign
+	sh.Signature, err = signer.Sign(sh.HeaderHash[:])
+
+	return
+}
+
+// ListDatabasesRequest defines client list databases rpc request entity.
+type ListDatabasesRequest struct {
+	proto.Envelope
+	Header SignedListDatabasesRequestHeader
+}
+
+// Verify checks hash and signature in request header.
+func (r *ListDatabasesRequest) Verify() error {
+	return r.Header.Verify()
+}
+
+// Sign the request.
+func (r *ListDatabasesRequest) Sign(signer *asymmetric.PrivateKey) error {
+	return r.Header.Sign(signer)
+}
+
+// DatabaseProfile defines the profile of a database owned by or shared with an account.
+type DatabaseProfile struct {
+	InstanceMeta wt.ServiceInstance
+	// Shared indicates that the database is not owned by but shared with the account.
+	Shared bool
+	// Deposit defines the remaining deposit of the database recorded on main chain.
+	Deposit uint64
+}
+
+// Serialize structure to bytes.
+func (p *DatabaseProfile) Serialize() []byte {
+	if p == nil {
+		return []byte{'\000'}
+	}
+
+	buf := new(bytes.Buffer)
+
+	buf.Write(p.InstanceMeta.Serialize())
+	binary.Write(buf, binary.LittleEndian, p.Shared)
+	binary.Write(buf, binary.LittleEndian, p.Deposit)
+
+	return buf.Bytes()
+}
+
+// ListDatabasesResponseHeader defines client list databases rpc response header entity.
+type ListDatabasesResponseHeader struct {
+	Databases []DatabaseProfile
+}
+
+// Serialize structure to bytes.
+func (h *ListDatabasesResponseHeader) Serialize() []byte {
+	if h == nil {
+		return []byte{'\000'}
+	}
+
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.LittleEndian, uint64(len(h.Databases)))
+	for i := range h.Databases {
+		buf.Write(h.Databases[i].Serialize())
+	}
+
+	return buf.Bytes()
+}
+
+// SignedListDatabasesResponseHeader defines signed client list databases rpc response header entity.
+type SignedListDatabasesResponseHeader struct {
+	ListDatabasesResponseHeader
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Verify checks hash and signature in response header.
+func (sh *SignedListDatabasesResponseHeader) Verify() (err error) {
+	// verify hash
+	if err = verifyHash(&sh.ListDatabasesResponseHeader, &sh.HeaderHash); err != nil {
+		return
+	}
+	// verify sign
+	if sh.Signee == nil || sh.Signature == nil || !sh.Signature.Verify(sh.HeaderHash[:], sh.Signee) {
+		return wt.ErrSignVerification
+	}
+	return
+}
+
+// Sign the request.
+func (sh *SignedListDatabasesResponseHeader) Sign(signer *asymmetric.PrivateKey) (err error) {
+	// build hash
+	buildHash(&sh.ListDatabasesResponseHeader, &sh.HeaderHash)
+
+	// sign
+	sh.Signature, err = signer.Sign(sh.HeaderHash[:])
+
+	return
+}
+
+// ListDatabasesResponse defines client list databases rpc response entity.
+type ListDatabasesResponse struct {
+	proto.Envelope
+	Header SignedListDatabasesResponseHeader
+}
+
+// Verify checks hash and signature in response header.
+func (r *ListDatabasesResponse) Verify() (err error) {
+	return r.Header.Verify()
+}
+
+// Sign the request.
+func (r *ListDatabasesResponse) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return r.Header.Sign(signer)
+}
+
 // FIXIT(xq262144) remove duplicated interface in utils package.
 type canSerialize interface {
 	Serialize() []byte
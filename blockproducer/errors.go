@@ -27,6 +27,12 @@ var (
 	ErrDatabaseAllocation = errors.New("allocate database failed")
 	// ErrMetricNotCollected defines errors collected.
 	ErrMetricNotCollected = errors.New("metric not collected")
+	// ErrInvalidResourceMeta defines invalid database resource requirements error.
+	ErrInvalidResourceMeta = errors.New("invalid database resource requirements")
+	// ErrGasPriceTooLow defines the target gas price is lower than the current gas price error.
+	ErrGasPriceTooLow = errors.New("gas price is lower than the current gas price")
+	// ErrGasPriceTooHigh defines the gas price paid for database exceeds the price cap of owner error.
+	ErrGasPriceTooHigh = errors.New("gas price is higher than the price cap")
 
 	// Errors on main chain
 
@@ -40,10 +46,20 @@ var (
 	ErrExistedTx = errors.New("Tx existed")
 	// ErrInvalidMerkleTreeRoot defines invalid merkle tree root error.
 	ErrInvalidMerkleTreeRoot = errors.New("Block merkle tree root does not match the tx hashes")
+	// ErrInvalidMetaRoot indicates that the meta root of block does not match the committed state.
+	ErrInvalidMetaRoot = errors.New("block meta root does not match the committed state")
+	// ErrInvalidStateRoot indicates that the state root of block does not match the committed
+	// state snapshot.
+	ErrInvalidStateRoot = errors.New("block state root does not match the committed state")
 	// ErrParentNotMatch defines invalid parent hash.
 	ErrParentNotMatch = errors.New("Block's parent hash cannot match best block")
 	// ErrNoSuchBlock defines no such block error.
 	ErrNoSuchBlock = errors.New("Cannot find such block")
+	// ErrBlockPruned indicates that the block is removed by a pruned node.
+	ErrBlockPruned = errors.New("block is pruned")
+	// ErrStateProofUnavailable indicates that the state committed by the head block is not built
+	// yet, which is available after the next block is pushed.
+	ErrStateProofUnavailable = errors.New("state proof unavailable")
 	// ErrNoSuchTxBilling defines no such txbilling error.
 	ErrNoSuchTxBilling = errors.New("Cannot find such txbilling")
 	// ErrSmallerSequenceID defines that new sequence id is smaller the old one.
@@ -65,9 +81,81 @@ var (
 	ErrDatabaseExists = errors.New("database already exists")
 	// ErrDatabaseUserExists indicates that the database user already exists.
 	ErrDatabaseUserExists = errors.New("database user already exists")
+	// ErrDatabaseUserNotFound indicates that the database user is not found.
+	ErrDatabaseUserNotFound = errors.New("database user not found")
+	// ErrMinerNotFound indicates that a miner is not registered.
+	ErrMinerNotFound = errors.New("miner not found")
+	// ErrInsufficientStake indicates that a miner registration locks less stake than required.
+	ErrInsufficientStake = errors.New("insufficient miner stake")
+	// ErrMinerChallenged indicates that the miner has pending service challenges, which should be
+	// answered before its stake is reduced.
+	ErrMinerChallenged = errors.New("miner has pending challenges")
+	// ErrMinerChallengeNotFound indicates that the proved request is not challenged.
+	ErrMinerChallengeNotFound = errors.New("miner challenge not found")
+	// ErrBillingNotFound indicates that a billing is not applied or out of dispute period.
+	ErrBillingNotFound = errors.New("billing not found")
+	// ErrInvalidBillingState indicates that a billing is not in the state required by the
+	// dispute transaction.
+	ErrInvalidBillingState = errors.New("invalid billing state")
+	// ErrInsufficientBillingProof indicates that the acks of a billing proof do not cover the
+	// billed queries.
+	ErrInsufficientBillingProof = errors.New("insufficient billing proof")
+	// ErrAccountExists indicates that the account already exists.
+	ErrAccountExists = errors.New("account already exists")
+	// ErrMultiSigNotFound indicates that a multi-signature account is not found.
+	ErrMultiSigNotFound = errors.New("multi-signature account not found")
+	// ErrMultiSigProposalNotFound indicates that a multi-signature proposal is not pending.
+	ErrMultiSigProposalNotFound = errors.New("multi-signature proposal not found")
+	// ErrMultiSigAlreadyApproved indicates that the owner already approved the proposal.
+	ErrMultiSigAlreadyApproved = errors.New("multi-signature proposal already approved")
+	// ErrParamsActivationPassed indicates that a chain parameters update is voted after its
+	// activation height.
+	ErrParamsActivationPassed = errors.New("chain parameters activation height passed")
+	// ErrParamsUpdateScheduled indicates that a chain parameters update is already scheduled.
+	ErrParamsUpdateScheduled = errors.New("chain parameters update already scheduled")
+	// ErrParamsAlreadyVoted indicates that the authority already voted for the update.
+	ErrParamsAlreadyVoted = errors.New("chain parameters update already voted")
+	// ErrNoSuchSnapshot indicates that no state snapshot is saved yet.
+	ErrNoSuchSnapshot = errors.New("no such state snapshot")
+	// ErrInvalidSnapshot indicates that a state snapshot does not match the state root of block.
+	ErrInvalidSnapshot = errors.New("invalid state snapshot")
+	// ErrPermissionDenied indicates that the account has no admin permission of the database.
+	ErrPermissionDenied = errors.New("permission denied")
 	// ErrInvalidAccountNonce indicates that a transaction has a invalid account nonce.
 	ErrInvalidAccountNonce = errors.New("invalid account nonce")
 	// ErrUnknownTransactionType indicates that a transaction has a unknown type and cannot be
 	// further processed.
 	ErrUnknownTransactionType = errors.New("unknown transaction type")
+	// ErrTooManyTransactions indicates that a block packs more transactions than allowed in
+	// total or for an account.
+	ErrTooManyTransactions = errors.New("too many transactions in block")
+	// ErrCheckpointNotFound indicates that no checkpoint is finalized at the height.
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+	// ErrUnknownCheckpointSignee indicates that a checkpoint signature is not signed by a peer.
+	ErrUnknownCheckpointSignee = errors.New("unknown checkpoint signee")
+	// ErrTooManyTxReceipts indicates that a batched receipt lookup queries too many transactions.
+	ErrTooManyTxReceipts = errors.New("too many transaction receipts queried")
+	// ErrTransactionExpired indicates that a pending transaction is persisted in the memory pool
+	// longer than the TTL, and is expired on startup.
+	ErrTransactionExpired = errors.New("transaction expired in memory pool")
+	// ErrCorruptedPendingTx indicates that a transaction persisted in the memory pool cannot be
+	// decoded.
+	ErrCorruptedPendingTx = errors.New("corrupted pending transaction")
+	// ErrTxThrottled indicates that a transaction submission exceeds the rate limit of the account
+	// or the IP address, see TxThrottledError for details.
+	ErrTxThrottled = errors.New("transaction submission throttled")
+	// ErrInvalidChainID indicates that a transaction is signed for another chain.
+	ErrInvalidChainID = errors.New("transaction signed for another chain")
+	// ErrLegacyTransaction indicates that a transaction signed without chain id is no longer
+	// accepted after the migration deadline.
+	ErrLegacyTransaction = errors.New("legacy transaction without chain id")
+	// ErrVestingNotFound indicates that the vesting of a transaction does not exist or is fully
+	// released.
+	ErrVestingNotFound = errors.New("vesting not found")
+	// ErrVestingNotRevocable indicates that a vesting cannot be revoked by its creator.
+	ErrVestingNotRevocable = errors.New("vesting not revocable")
+	// ErrVestingRevoked indicates that a vesting is already revoked.
+	ErrVestingRevoked = errors.New("vesting already revoked")
+	// ErrInsufficientVested indicates that a release withdraws more tokens than vested.
+	ErrInsufficientVested = errors.New("insufficient vested tokens")
 )
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"encoding/binary"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/coreos/bbolt"
+)
+
+const (
+	// maxAccountEventsQuery defines the events returned by an account events query at most.
+	maxAccountEventsQuery = 1000
+	// maxAccountEventsWait defines the longest time an account events query waits for new events.
+	maxAccountEventsWait = 30 * time.Second
+)
+
+// AccountEventType defines the type of events touching an account.
+type AccountEventType uint32
+
+const (
+	// AccountEventTransferIn defines the event of tokens transferred to the account.
+	AccountEventTransferIn AccountEventType = iota
+	// AccountEventDatabaseCreated defines the event of a database created for the account.
+	AccountEventDatabaseCreated
+	// AccountEventPermissionChanged defines the event of the database permission of the account
+	// granted, altered or revoked.
+	AccountEventPermissionChanged
+	// AccountEventVestingCreated defines the event of tokens locked to the account by a vesting.
+	AccountEventVestingCreated
+)
+
+// String implements fmt.Stringer for AccountEventType.
+func (t AccountEventType) String() string {
+	switch t {
+	case AccountEventTransferIn:
+		return "TransferIn"
+	case AccountEventDatabaseCreated:
+		return "DatabaseCreated"
+	case AccountEventPermissionChanged:
+		return "PermissionChanged"
+	case AccountEventVestingCreated:
+		return "VestingCreated"
+	default:
+		return "Unknown"
+	}
+}
+
+// AccountEvent defines an event touching an account, the events of an account are numbered by
+// Seq in the order they happen.
+type AccountEvent struct {
+	Seq  uint64
+	Type AccountEventType
+	// Height is the height of block packing the transaction, or the chain height when the
+	// database is created.
+	Height uint32
+	// Tx is the hash of the transaction, which is empty for database creation.
+	Tx hash.Hash
+	// Counterparty is the sender of incoming transfers and vestings, and the admin changing
+	// permissions.
+	Counterparty proto.AccountAddress
+	Amount       uint64
+	TokenType    pt.TokenType
+	DatabaseID   proto.DatabaseID
+	Permission   pt.UserPermission
+	Revoked      bool
+}
+
+// accountEvents returns the events of transaction t touching accounts other than the sender.
+func accountEvents(t pi.Transaction, height uint32) (
+	addrs []proto.AccountAddress, events []*AccountEvent,
+) {
+	switch tx := t.(type) {
+	case *pt.Transfer:
+		addrs = append(addrs, tx.Receiver)
+		events = append(events, &AccountEvent{
+			Type:         AccountEventTransferIn,
+			Height:       height,
+			Tx:           tx.GetHash(),
+			Counterparty: tx.Sender,
+			Amount:       tx.Amount,
+			TokenType:    tx.TokenType,
+		})
+	case *pt.UpdatePermission:
+		addrs = append(addrs, tx.User)
+		events = append(events, &AccountEvent{
+			Type:         AccountEventPermissionChanged,
+			Height:       height,
+			Tx:           tx.GetHash(),
+			Counterparty: tx.Sender,
+			DatabaseID:   tx.DatabaseID,
+			Permission:   tx.Permission,
+			Revoked:      tx.Revoke,
+		})
+	case *pt.CreateVesting:
+		addrs = append(addrs, tx.Beneficiary)
+		events = append(events, &AccountEvent{
+			Type:         AccountEventVestingCreated,
+			Height:       height,
+			Tx:           tx.GetHash(),
+			Counterparty: tx.Sender,
+			Amount:       tx.Amount,
+			TokenType:    tx.TokenType,
+		})
+	}
+	return
+}
+
+// putAccountEvent numbers event by the sequence of account addr and stores it, the events are
+// indexed locally and kept after the blocks are pruned.
+func putAccountEvent(tx *bolt.Tx, addr proto.AccountAddress, event *AccountEvent) (err error) {
+	var eb, ab *bolt.Bucket
+	if eb, err = tx.Bucket(metaBucket[:]).CreateBucketIfNotExists(metaAccountEventBucket); err != nil {
+		return
+	}
+	if ab, err = eb.CreateBucketIfNotExists(addr[:]); err != nil {
+		return
+	}
+	if event.Seq, err = ab.NextSequence(); err != nil {
+		return
+	}
+	enc, err := utils.EncodeMsgPack(event)
+	if err != nil {
+		return
+	}
+	return ab.Put(eventSeqKey(event.Seq), enc.Bytes())
+}
+
+func eventSeqKey(seq uint64) (key []byte) {
+	key = make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return
+}
+
+// addDatabaseEvent adds the event of database id created for account owner, it's called by the
+// database service which creates databases outside of the main chain transactions.
+func (c *Chain) addDatabaseEvent(owner proto.AccountAddress, id proto.DatabaseID) (err error) {
+	if err = c.db.Update(func(tx *bolt.Tx) error {
+		return putAccountEvent(tx, owner, &AccountEvent{
+			Type:       AccountEventDatabaseCreated,
+			Height:     c.st.getHeight(),
+			DatabaseID: id,
+		})
+	}); err != nil {
+		return
+	}
+	c.notifyAccountEvents()
+	return
+}
+
+// loadAccountEvents returns at most limit events of account addr after sequence since.
+func (c *Chain) loadAccountEvents(addr proto.AccountAddress, since uint64, limit int) (
+	events []*AccountEvent, err error,
+) {
+	err = c.db.View(func(tx *bolt.Tx) (err error) {
+		eb := tx.Bucket(metaBucket[:]).Bucket(metaAccountEventBucket)
+		if eb == nil {
+			return
+		}
+		ab := eb.Bucket(addr[:])
+		if ab == nil {
+			return
+		}
+		cur := ab.Cursor()
+		for k, v := cur.Seek(eventSeqKey(since + 1)); k != nil && len(events) < limit; k, v = cur.Next() {
+			var event *AccountEvent
+			if err = utils.DecodeMsgPack(v, &event); err != nil {
+				return
+			}
+			events = append(events, event)
+		}
+		return
+	})
+	return
+}
+
+// waitAccountEvents is like loadAccountEvents but waits at most timeout for new events if
+// there is no event after sequence since.
+func (c *Chain) waitAccountEvents(
+	addr proto.AccountAddress, since uint64, limit int, timeout time.Duration) (
+	events []*AccountEvent, err error,
+) {
+	if limit <= 0 || limit > maxAccountEventsQuery {
+		limit = maxAccountEventsQuery
+	}
+	if timeout > maxAccountEventsWait {
+		timeout = maxAccountEventsWait
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// take the notification channel before loading, so that no event is missed
+		updated := c.accountEventsUpdated()
+		if events, err = c.loadAccountEvents(addr, since, limit); err != nil || len(events) > 0 {
+			return
+		}
+		select {
+		case <-updated:
+		case <-timer.C:
+			return
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// accountEventsUpdated returns the channel closed once new account events are added.
+func (c *Chain) accountEventsUpdated() <-chan struct{} {
+	c.eventMutex.Lock()
+	defer c.eventMutex.Unlock()
+	if c.eventCh == nil {
+		c.eventCh = make(chan struct{})
+	}
+	return c.eventCh
+}
+
+// notifyAccountEvents wakes up the queries waiting for account events.
+func (c *Chain) notifyAccountEvents() {
+	c.eventMutex.Lock()
+	defer c.eventMutex.Unlock()
+	if c.eventCh != nil {
+		close(c.eventCh)
+		c.eventCh = nil
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/coreos/bbolt"
+)
+
+func TestAccountEvents(t *testing.T) {
+	db, err := bolt.Open(path.Join(testDataDir, t.Name()), 0600, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		_, err = tx.CreateBucketIfNotExists(metaBucket[:])
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var (
+		c        = &Chain{db: db, st: &State{Height: 7}}
+		sender   = proto.AccountAddress{0x1}
+		receiver = proto.AccountAddress{0x2}
+		txs      = []pi.Transaction{
+			&pt.Transfer{TransferHeader: pt.TransferHeader{
+				Sender: sender, Receiver: receiver, Amount: 10,
+			}},
+			&pt.UpdatePermission{UpdatePermissionHeader: pt.UpdatePermissionHeader{
+				Sender: sender, DatabaseID: "db#1", User: receiver, Permission: pt.Read,
+			}},
+			&pt.RegisterMiner{},
+		}
+		events []*AccountEvent
+	)
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		for i, t := range txs {
+			addrs, events := accountEvents(t, uint32(i+1))
+			for j := range events {
+				if err = putAccountEvent(tx, addrs[j], events[j]); err != nil {
+					return
+				}
+			}
+		}
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if events, err = c.loadAccountEvents(receiver, 0, maxAccountEventsQuery); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 ||
+		events[0].Seq != 1 || events[0].Type != AccountEventTransferIn ||
+		events[0].Counterparty != sender || events[0].Amount != 10 || events[0].Height != 1 ||
+		events[1].Seq != 2 || events[1].Type != AccountEventPermissionChanged ||
+		events[1].DatabaseID != "db#1" || events[1].Permission != pt.Read {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if events, err = c.loadAccountEvents(receiver, 1, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].Seq != 2 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if events, err = c.loadAccountEvents(sender, 0, maxAccountEventsQuery); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	// waiting times out without new events
+	if events, err = c.waitAccountEvents(sender, 0, 0, 10*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	// waiting is woken up by new events
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.addDatabaseEvent(sender, "db#2")
+	}()
+	if events, err = c.waitAccountEvents(sender, 0, 0, 10*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != AccountEventDatabaseCreated ||
+		events[0].DatabaseID != "db#2" || events[0].Height != 7 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
+
+func TestProducedBlockAccountEvents(t *testing.T) {
+	c, cfg, priv, sender, cleanup := newTestProducer(t)
+	defer cleanup()
+	defer c.db.Close()
+
+	var (
+		receiver = proto.AccountAddress{0x2}
+		result   = make(chan []*AccountEvent)
+		failure  = make(chan error, 1)
+	)
+	// subscribe before the block is produced
+	go func() {
+		events, err := c.waitAccountEvents(receiver, 0, 0, 10*time.Second)
+		if err != nil {
+			failure <- err
+			return
+		}
+		result <- events
+	}()
+	tx := submitTestTransfer(t, c, priv, sender, receiver, 0)
+	if err := c.produceBlock(cfg.Genesis.Timestamp().Add(2 * testPeriod)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case events := <-result:
+		if len(events) != 1 ||
+			events[0].Type != AccountEventTransferIn || events[0].Tx != tx.GetHash() ||
+			events[0].Counterparty != sender || events[0].Amount != tx.Amount ||
+			events[0].Height != c.st.getHeight() {
+			t.Fatalf("unexpected events: %v", events)
+		}
+	case err := <-failure:
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the sender gets no event of its own transaction
+	events, err := c.loadAccountEvents(sender, 0, maxAccountEventsQuery)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
@@ -29,6 +29,11 @@ import (
 // AccountNonce defines the an account nonce.
 type AccountNonce uint32
 
+// ChainID identifies the network which a transaction is signed for, so that the transaction
+// cannot be replayed on other networks. A transaction with empty ChainID is a legacy one signed
+// before chain ids are introduced.
+type ChainID string
+
 // TransactionType defines an transaction type.
 type TransactionType uint32
 
@@ -59,10 +64,70 @@ const (
 	TransactionTypeAlterDatabaseUser
 	// TransactionTypeDeleteDatabaseUser defines database user deletion transaction type.
 	TransactionTypeDeleteDatabaseUser
+	// TransactionTypeRegisterMiner defines miner registration transaction type.
+	TransactionTypeRegisterMiner
+	// TransactionTypeDeregisterMiner defines miner deregistration transaction type.
+	TransactionTypeDeregisterMiner
+	// TransactionTypeBillingChallenge defines billing challenge transaction type.
+	TransactionTypeBillingChallenge
+	// TransactionTypeBillingProof defines billing proof transaction type.
+	TransactionTypeBillingProof
+	// TransactionTypeCreateMultiSig defines multi-signature account creation transaction type.
+	TransactionTypeCreateMultiSig
+	// TransactionTypeProposeMultiSig defines multi-signature proposal transaction type.
+	TransactionTypeProposeMultiSig
+	// TransactionTypeApproveMultiSig defines multi-signature approval transaction type.
+	TransactionTypeApproveMultiSig
+	// TransactionTypeMinerEvidence defines miner misbehavior evidence transaction type.
+	TransactionTypeMinerEvidence
+	// TransactionTypeServiceChallenge defines miner service challenge transaction type.
+	TransactionTypeServiceChallenge
+	// TransactionTypeServiceProof defines miner service proof transaction type.
+	TransactionTypeServiceProof
+	// TransactionTypeUpdateParams defines chain parameters update transaction type.
+	TransactionTypeUpdateParams
+	// TransactionTypeCreateVesting defines token vesting creation transaction type.
+	TransactionTypeCreateVesting
+	// TransactionTypeReleaseVesting defines token vesting release transaction type.
+	TransactionTypeReleaseVesting
+	// TransactionTypeRevokeVesting defines token vesting revocation transaction type.
+	TransactionTypeRevokeVesting
 	// TransactionTypeNumber defines transaction types number.
 	TransactionTypeNumber
 )
 
+// TransactionState defines the state of a transaction on main chain.
+type TransactionState uint32
+
+const (
+	// TransactionStateNotFound defines the state of an unknown or rejected transaction.
+	TransactionStateNotFound TransactionState = iota
+	// TransactionStatePending defines the state of an applied transaction which is not packed
+	// in any block yet.
+	TransactionStatePending
+	// TransactionStateConfirmed defines the state of a transaction committed by a produced block.
+	TransactionStateConfirmed
+	// TransactionStateFailed defines the state of a recently submitted transaction which failed
+	// to apply, the reason is reported along with the state.
+	TransactionStateFailed
+)
+
+// String implements fmt.Stringer for TransactionState.
+func (s TransactionState) String() string {
+	switch s {
+	case TransactionStateNotFound:
+		return "NotFound"
+	case TransactionStatePending:
+		return "Pending"
+	case TransactionStateConfirmed:
+		return "Confirmed"
+	case TransactionStateFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
 // Serializer is the interface implemented by an object that can serialize itself into binary form.
 type Serializer interface {
 	Serialize() ([]byte, error)
@@ -81,7 +146,12 @@ type Transaction interface {
 	Deserializer
 	GetAccountAddress() proto.AccountAddress
 	GetAccountNonce() AccountNonce
+	// GetFee returns the fee paid by the account, which prioritizes the transaction when
+	// block producers pack pending transactions.
+	GetFee() uint64
 	GetHash() hash.Hash
+	// GetChainID returns the id of the chain which the transaction is signed for.
+	GetChainID() ChainID
 	GetTransactionType() TransactionType
 	Sign(signer *asymmetric.PrivateKey) error
 	Verify() error
@@ -20,6 +20,20 @@ func (z AccountNonce) Msgsize() (s int) {
 	return
 }
 
+// MarshalHash marshals for hash
+func (z ChainID) MarshalHash() (o []byte, err error) {
+	var b []byte
+	o = hsp.Require(b, z.Msgsize())
+	o = hsp.AppendString(o, string(z))
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z ChainID) Msgsize() (s int) {
+	s = hsp.StringPrefixSize + len(string(z))
+	return
+}
+
 // MarshalHash marshals for hash
 func (z TransactionType) MarshalHash() (o []byte, err error) {
 	var b []byte
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"sort"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+// MinerCandidate defines a miner which satisfies the resource requirements and constraints of
+// a database.
+type MinerCandidate struct {
+	NodeID proto.NodeID
+	// Profile is the miner profile registered on main chain, nil if the miner is not registered.
+	Profile    *pt.MinerProfile
+	FreeMemory uint64
+}
+
+// Matcher defines the policy of matching databases to miners, which is set to DBService to
+// replace the default policy.
+type Matcher interface {
+	// Match selects at most count miners from candidates to serve a database of meta, the
+	// candidates are checked against the database constraints already.
+	Match(meta *wt.ResourceMeta, candidates []MinerCandidate, count int) []proto.NodeID
+}
+
+// defaultMatcher prefers the miners in the target region of database, and then the miners with
+// more free memory.
+type defaultMatcher struct{}
+
+func (defaultMatcher) Match(meta *wt.ResourceMeta, candidates []MinerCandidate, count int) (
+	selected []proto.NodeID,
+) {
+	inRegion := func(c *MinerCandidate) bool {
+		return meta.TargetRegion != "" && c.Profile != nil && c.Profile.Region == meta.TargetRegion
+	}
+	sorted := append([]MinerCandidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ri, rj := inRegion(&sorted[i]), inRegion(&sorted[j]); ri != rj {
+			return ri
+		}
+		return sorted[i].FreeMemory > sorted[j].FreeMemory
+	})
+	for i := 0; i < len(sorted) && i < count; i++ {
+		selected = append(selected, sorted[i].NodeID)
+	}
+	return
+}
+
+// matchMiners selects count miners from candidates by the matcher of s, the miners selected out
+// of candidates are ignored, so the constraints are always honored.
+func (s *DBService) matchMiners(
+	meta *wt.ResourceMeta, candidates []MinerCandidate, count int) (selected []proto.NodeID,
+) {
+	var matcher Matcher = defaultMatcher{}
+	if s.Matcher != nil {
+		matcher = s.Matcher
+	}
+	var (
+		valid = make(map[proto.NodeID]bool, len(candidates))
+		seen  = make(map[proto.NodeID]bool, count)
+	)
+	for _, c := range candidates {
+		valid[c.NodeID] = true
+	}
+	for _, nodeID := range matcher.Match(meta, candidates, count) {
+		if len(selected) < count && valid[nodeID] && !seen[nodeID] {
+			seen[nodeID] = true
+			selected = append(selected, nodeID)
+		}
+	}
+	return
+}
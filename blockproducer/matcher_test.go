@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"reflect"
+	"testing"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+type reverseMatcher struct{}
+
+func (reverseMatcher) Match(meta *wt.ResourceMeta, candidates []MinerCandidate, count int) (
+	selected []proto.NodeID,
+) {
+	selected = []proto.NodeID{"unknown"}
+	for i := len(candidates) - 1; i >= 0; i-- {
+		selected = append(selected, candidates[i].NodeID, candidates[i].NodeID)
+	}
+	return
+}
+
+func TestMatchMiners(t *testing.T) {
+	var (
+		s          = &DBService{}
+		meta       = &wt.ResourceMeta{TargetRegion: "eu"}
+		candidates = []MinerCandidate{
+			{NodeID: "a", FreeMemory: 10},
+			{NodeID: "b", FreeMemory: 30, Profile: &pt.MinerProfile{Region: "us"}},
+			{NodeID: "c", FreeMemory: 20, Profile: &pt.MinerProfile{Region: "eu"}},
+		}
+	)
+	if selected := s.matchMiners(meta, candidates, 2); !reflect.DeepEqual(
+		selected, []proto.NodeID{"c", "b"}) {
+		t.Fatalf("unexpected selected miners: %v", selected)
+	}
+
+	// miners out of candidates or selected twice are ignored
+	s.Matcher = reverseMatcher{}
+	if selected := s.matchMiners(meta, candidates, 2); !reflect.DeepEqual(
+		selected, []proto.NodeID{"c", "b"}) {
+		t.Fatalf("unexpected selected miners: %v", selected)
+	}
+	if selected := s.matchMiners(meta, candidates, 5); len(selected) != 3 {
+		t.Fatalf("unexpected selected miners: %v", selected)
+	}
+}
+
+func TestMinerAvailable(t *testing.T) {
+	var (
+		s    = &DBService{}
+		meta = &wt.ResourceMeta{}
+	)
+	if !s.minerAvailable("a", meta) {
+		t.Fatal("unexpected unavailable miner")
+	}
+	meta.Constraints.ExcludeMiners = []proto.NodeID{"a"}
+	if s.minerAvailable("a", meta) {
+		t.Fatal("unexpected available miner")
+	}
+	// unregistered miners never satisfy the profile constraints
+	meta.Constraints = wt.MatchConstraints{Regions: []string{"eu"}}
+	if s.minerAvailable("b", meta) {
+		t.Fatal("unexpected available miner")
+	}
+
+	s.Chain = &Chain{ms: newMetaState()}
+	s.Chain.ms.setGovernance(nil, 0, defaultChainParams(0))
+	for _, v := range []pt.MinerProfile{
+		{NodeID: "eu", GasPrice: 1, Region: "eu", Stake: 100},
+		{NodeID: "us", GasPrice: 1, Region: "us", Stake: 100},
+		{NodeID: "eu-low-stake", GasPrice: 1, Region: "eu", Stake: 10},
+		{NodeID: "eu-expensive", GasPrice: 5, Region: "eu", Stake: 100},
+	} {
+		s.Chain.ms.readonly.miners[v.NodeID] = &minerObject{MinerProfile: v}
+	}
+	meta.GasPrice = 10
+	meta.Constraints = wt.MatchConstraints{Regions: []string{"eu"}, MinStake: 50, MaxGasPrice: 3}
+	for _, v := range []struct {
+		node      proto.NodeID
+		available bool
+	}{
+		{"eu", true},
+		{"us", false},
+		{"eu-low-stake", false},
+		{"eu-expensive", false},
+		{"unregistered", false},
+	} {
+		if available := s.minerAvailable(v.node, meta); available != v.available {
+			t.Fatalf("unexpected availability of %s: %v", v.node, available)
+		}
+	}
+}
+
+func TestVerifyResourceMetaPriceCap(t *testing.T) {
+	meta := &wt.ResourceMeta{Constraints: wt.MatchConstraints{MaxGasPrice: 5}}
+	if err := verifyResourceMeta(meta, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := verifyResourceMeta(meta, 10); err != ErrGasPriceTooHigh {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta.GasPrice = 6
+	if err := verifyResourceMeta(meta, 3); err != ErrGasPriceTooHigh {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/coreos/bbolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultMempoolTTL defines how long a pending transaction is kept in the persisted memory pool
+// if Config.MempoolTTL is not set.
+const defaultMempoolTTL = 24 * time.Hour
+
+var (
+	mempoolRestoredTxs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "covenantsql_bp_mempool_restored_total",
+		Help: "Pending transactions restored from the persisted memory pool on startup.",
+	})
+	mempoolDroppedTxs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "covenantsql_bp_mempool_dropped_total",
+		Help: "Pending transactions dropped from the memory pool as they no longer apply.",
+	})
+	mempoolExpiredTxs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "covenantsql_bp_mempool_expired_total",
+		Help: "Pending transactions expired in the persisted memory pool on startup.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(mempoolRestoredTxs, mempoolDroppedTxs, mempoolExpiredTxs)
+}
+
+// pendingTx defines a transaction persisted in the memory pool, which is written ahead of
+// applying the transaction and removed once it's committed or dropped.
+type pendingTx struct {
+	Type     pi.TransactionType
+	Received int64
+	Tx       []byte
+}
+
+// newTransaction returns an empty transaction of type t to be deserialized.
+func newTransaction(t pi.TransactionType) (tx pi.Transaction, err error) {
+	if tx, err = pt.NewTransaction(t); err != nil {
+		err = ErrUnknownTransactionType
+	}
+	return
+}
+
+// decode returns the transaction persisted as h.
+func (p *pendingTx) decode(h hash.Hash) (t pi.Transaction, err error) {
+	if t, err = newTransaction(p.Type); err != nil {
+		return
+	}
+	if err = t.Deserialize(p.Tx); err != nil {
+		return
+	}
+	if t.GetHash() != h {
+		err = ErrCorruptedPendingTx
+	}
+	return
+}
+
+// putPendingTx persists t to the memory pool with the time it's received, a transaction already
+// persisted keeps its original time.
+func putPendingTx(tx *bolt.Tx, t pi.Transaction, enc []byte, received time.Time) (err error) {
+	var (
+		pb  *bolt.Bucket
+		buf *bytes.Buffer
+		h   = t.GetHash()
+	)
+	if pb, err = tx.Bucket(metaBucket[:]).CreateBucketIfNotExists(metaPendingTxBucket); err != nil {
+		return
+	}
+	if pb.Get(h[:]) != nil {
+		return
+	}
+	if buf, err = utils.EncodeMsgPack(&pendingTx{
+		Type:     t.GetTransactionType(),
+		Received: received.UnixNano(),
+		Tx:       enc,
+	}); err != nil {
+		return
+	}
+	return pb.Put(h[:], buf.Bytes())
+}
+
+// deletePendingTxs removes the committed or dropped transactions from the persisted memory pool.
+func deletePendingTxs(tx *bolt.Tx, hashes ...hash.Hash) (err error) {
+	pb := tx.Bucket(metaBucket[:]).Bucket(metaPendingTxBucket)
+	if pb == nil {
+		return
+	}
+	for _, h := range hashes {
+		if err = pb.Delete(h[:]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// restoreMempool reloads the persisted memory pool on startup. The transactions are re-applied
+// in the order they are received, the ones older than ttl are expired and the ones which no
+// longer apply are dropped.
+func (c *Chain) restoreMempool(ttl time.Duration) (err error) {
+	if ttl <= 0 {
+		ttl = defaultMempoolTTL
+	}
+	type entry struct {
+		pendingTx
+		hash hash.Hash
+		err  error
+	}
+	var entries []*entry
+	if err = c.db.View(func(tx *bolt.Tx) error {
+		pb := tx.Bucket(metaBucket[:]).Bucket(metaPendingTxBucket)
+		if pb == nil {
+			return nil
+		}
+		return pb.ForEach(func(k, v []byte) error {
+			e := &entry{}
+			copy(e.hash[:], k)
+			if e.err = utils.DecodeMsgPack(v, &e.pendingTx); e.err != nil {
+				// corrupted entry is dropped
+				e.Type = pi.TransactionTypeNumber
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	}); err != nil || len(entries) == 0 {
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Received < entries[j].Received
+	})
+
+	var (
+		deadline         = time.Now().Add(-ttl).UnixNano()
+		expired, dropped []*entry
+		restored         int
+	)
+	for _, e := range entries {
+		if e.err == nil && e.Received < deadline {
+			c.ms.failed.add(e.hash, ErrTransactionExpired.Error())
+			expired = append(expired, e)
+			continue
+		}
+		var t pi.Transaction
+		if e.err == nil {
+			t, e.err = e.decode(e.hash)
+		}
+		if e.err == nil {
+			e.err = c.checkChainID(t)
+		}
+		if e.err == nil {
+			e.err = c.db.Update(c.ms.applyTransactionProcedure(t))
+		}
+		if e.err != nil {
+			log.WithFields(log.Fields{
+				"transaction": e.hash.String(),
+			}).WithError(e.err).Warning("drop persisted transaction")
+			c.ms.failed.add(e.hash, e.err.Error())
+			dropped = append(dropped, e)
+			continue
+		}
+		restored++
+	}
+
+	// the transactions out of pool are also removed from the transaction index, as they are never
+	// committed as long as they are persisted in the pool
+	if err = c.db.Update(func(tx *bolt.Tx) (err error) {
+		tb := tx.Bucket(metaBucket[:]).Bucket(metaTransactionBucket)
+		for _, e := range append(expired, dropped...) {
+			if bk := tb.Bucket(e.Type.Bytes()); bk != nil {
+				if err = bk.Delete(e.hash[:]); err != nil {
+					return
+				}
+			}
+			if err = deletePendingTxs(tx, e.hash); err != nil {
+				return
+			}
+		}
+		return
+	}); err != nil {
+		return
+	}
+
+	mempoolRestoredTxs.Add(float64(restored))
+	mempoolExpiredTxs.Add(float64(len(expired)))
+	mempoolDroppedTxs.Add(float64(len(dropped)))
+	log.WithFields(log.Fields{
+		"restored": restored,
+		"expired":  len(expired),
+		"dropped":  len(dropped),
+	}).Info("restored persisted memory pool")
+	return
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/coreos/bbolt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestRestoreMempool(t *testing.T) {
+	db, err := bolt.Open(path.Join(testDataDir, t.Name()), 0600, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		var meta, txbk *bolt.Bucket
+		if meta, err = tx.CreateBucketIfNotExists(metaBucket[:]); err != nil {
+			return
+		}
+		for _, b := range [][]byte{
+			metaAccountIndexBucket, metaSQLChainIndexBucket, metaMinerIndexBucket,
+			metaBillingIndexBucket, metaMultiSigIndexBucket, metaParamsIndexBucket,
+			metaVestingIndexBucket,
+		} {
+			if _, err = meta.CreateBucketIfNotExists(b); err != nil {
+				return
+			}
+		}
+		if txbk, err = meta.CreateBucketIfNotExists(metaTransactionBucket); err != nil {
+			return
+		}
+		for i := pi.TransactionType(0); i < pi.TransactionTypeNumber; i++ {
+			if _, err = txbk.CreateBucketIfNotExists(i.Bytes()); err != nil {
+				return
+			}
+		}
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	enc, err := testPubKey.MarshalHash()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var (
+		c      = &Chain{db: db, ms: newMetaState()}
+		sender = proto.AccountAddress(hash.THashH(enc))
+		newTx  = func(nonce pi.AccountNonce, amount uint64) (tx *pt.Transfer) {
+			tx = &pt.Transfer{TransferHeader: pt.TransferHeader{
+				Sender:   sender,
+				Receiver: proto.AccountAddress{0x1},
+				Nonce:    nonce,
+				Amount:   amount,
+			}}
+			if err := tx.Sign(testPrivKey); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+		tx0, tx1, tx3 = newTx(0, 10), newTx(1, 10), newTx(3, 10)
+		stale, forged = newTx(4, 10), newTx(5, 10)
+	)
+	c.ms.loadOrStoreAccountObject(sender, &accountObject{Account: pt.Account{
+		Address: sender, StableCoinBalance: 100,
+	}})
+	if err = db.Update(c.ms.commitProcedure()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.processTx(tx0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.processTx(tx3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.processTx(newTx(0, 1000)); err != ErrInvalidAccountNonce {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// persist a transaction received long ago and a forged one which doesn't verify
+	forged.Amount = 20
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		for v, received := range map[*pt.Transfer]time.Time{
+			stale:  time.Now().Add(-2 * time.Hour),
+			forged: time.Now(),
+		} {
+			var b []byte
+			if b, err = v.Serialize(); err != nil {
+				return
+			}
+			if err = putPendingTx(tx, v, b, received); err != nil {
+				return
+			}
+		}
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// restart with the committed state
+	var (
+		restored = counterValue(t, mempoolRestoredTxs)
+		expired  = counterValue(t, mempoolExpiredTxs)
+		dropped  = counterValue(t, mempoolDroppedTxs)
+	)
+	c = &Chain{db: db, ms: newMetaState()}
+	if err = db.View(c.ms.reloadProcedure()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.restoreMempool(time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.ms.isTxPending(tx0.GetHash()) || !c.ms.isTxPending(tx3.GetHash()) {
+		t.Fatal("pending transactions should be restored")
+	}
+	if nonces := c.ms.queuedNonces(sender); len(nonces) != 1 || nonces[0] != 3 {
+		t.Fatalf("unexpected queued nonces: %v", nonces)
+	}
+	if reason, ok := c.ms.failed.get(stale.GetHash()); !ok || reason != ErrTransactionExpired.Error() {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+	if _, ok := c.ms.failed.get(forged.GetHash()); !ok {
+		t.Fatal("forged transaction should be dropped")
+	}
+	if v := counterValue(t, mempoolRestoredTxs) - restored; v != 2 {
+		t.Fatalf("unexpected restored transactions: %v", v)
+	}
+	if v := counterValue(t, mempoolExpiredTxs) - expired; v != 1 {
+		t.Fatalf("unexpected expired transactions: %v", v)
+	}
+	if v := counterValue(t, mempoolDroppedTxs) - dropped; v != 1 {
+		t.Fatalf("unexpected dropped transactions: %v", v)
+	}
+
+	// committed transactions are removed, and the queued one is kept
+	if err = c.processTx(tx1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = db.Update(c.ms.commitProcedure()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var keys int
+	if err = db.View(func(tx *bolt.Tx) error {
+		keys = tx.Bucket(metaBucket[:]).Bucket(metaPendingTxBucket).Stats().KeyN
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys != 1 {
+		t.Fatalf("unexpected persisted transactions: %d", keys)
+	}
+}
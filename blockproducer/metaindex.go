@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils"
 	"github.com/coreos/bbolt"
@@ -54,16 +55,51 @@ type sqlchainObject struct {
 	pt.SQLChainProfile
 }
 
+type minerObject struct {
+	sync.RWMutex
+	pt.MinerProfile
+}
+
+type billingObject struct {
+	sync.RWMutex
+	pt.BillingProfile
+}
+
+type multisigObject struct {
+	sync.RWMutex
+	pt.MultiSigProfile
+}
+
+type vestingObject struct {
+	sync.RWMutex
+	pt.VestingProfile
+}
+
+type paramsObject struct {
+	sync.RWMutex
+	pt.ParamsUpdate
+}
+
 type metaIndex struct {
 	sync.RWMutex
 	accounts  map[proto.AccountAddress]*accountObject
 	databases map[proto.DatabaseID]*sqlchainObject
+	miners    map[proto.NodeID]*minerObject
+	billings  map[hash.Hash]*billingObject
+	multisigs map[proto.AccountAddress]*multisigObject
+	params    map[hash.Hash]*paramsObject
+	vestings  map[hash.Hash]*vestingObject
 }
 
 func newMetaIndex() *metaIndex {
 	return &metaIndex{
 		accounts:  make(map[proto.AccountAddress]*accountObject),
 		databases: make(map[proto.DatabaseID]*sqlchainObject),
+		miners:    make(map[proto.NodeID]*minerObject),
+		billings:  make(map[hash.Hash]*billingObject),
+		multisigs: make(map[proto.AccountAddress]*multisigObject),
+		params:    make(map[hash.Hash]*paramsObject),
+		vestings:  make(map[hash.Hash]*vestingObject),
 	}
 }
 
@@ -18,12 +18,17 @@ package blockproducer
 
 import (
 	"bytes"
+	"sort"
 	"sync"
+	"time"
 
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 	"github.com/coreos/bbolt"
 	"github.com/ulule/deepcopier"
 )
@@ -34,6 +39,17 @@ type metaState struct {
 	sync.RWMutex
 	dirty, readonly *metaIndex
 	pool            *txPool
+	// failed remembers the recent transactions failing to apply for receipt queries.
+	failed *failedTxs
+
+	// authorities defines the accounts voting for chain parameter updates, and threshold is the
+	// number of votes scheduling an update.
+	authorities []proto.AccountAddress
+	threshold   int
+	// params defines the chain parameters before any update takes effect.
+	params pt.ChainParams
+	// height is the height of the last settled block.
+	height uint32
 }
 
 func newMetaState() *metaState {
@@ -41,6 +57,8 @@ func newMetaState() *metaState {
 		dirty:    newMetaIndex(),
 		readonly: newMetaIndex(),
 		pool:     newTxPool(),
+		failed:   newFailedTxs(),
+		params:   defaultChainParams(0),
 	}
 }
 
@@ -124,9 +142,40 @@ func (s *metaState) commitProcedure() (_ func(*bolt.Tx) error) {
 			enc *bytes.Buffer
 			ab  = tx.Bucket(metaBucket[:]).Bucket(metaAccountIndexBucket)
 			cb  = tx.Bucket(metaBucket[:]).Bucket(metaSQLChainIndexBucket)
+			mb  = tx.Bucket(metaBucket[:]).Bucket(metaMinerIndexBucket)
+			bb  = tx.Bucket(metaBucket[:]).Bucket(metaBillingIndexBucket)
+			sb  = tx.Bucket(metaBucket[:]).Bucket(metaMultiSigIndexBucket)
+			pb  = tx.Bucket(metaBucket[:]).Bucket(metaParamsIndexBucket)
+			vb  = tx.Bucket(metaBucket[:]).Bucket(metaVestingIndexBucket)
 		)
 		s.Lock()
 		defer s.Unlock()
+		// Advance the account nonces over the committed transactions
+		for k, e := range s.pool.entries {
+			o, ok := s.dirty.accounts[k]
+			if !ok {
+				o = s.readonly.accounts[k]
+			}
+			if o == nil {
+				continue
+			}
+			n := &accountObject{Account: o.Account}
+			n.NextNonce = e.nextNonce()
+			s.dirty.accounts[k] = n
+			for _, t := range s.pool.dropQueuedTxs(k, n.NextNonce) {
+				s.failed.add(t.GetHash(), ErrInvalidAccountNonce.Error())
+				mempoolDroppedTxs.Inc()
+				if err = deletePendingTxs(tx, t.GetHash()); err != nil {
+					return
+				}
+			}
+		}
+		// Committed transactions are removed from the persisted memory pool
+		for _, t := range s.pool.txs {
+			if err = deletePendingTxs(tx, t.GetHash()); err != nil {
+				return
+			}
+		}
 		for k, v := range s.dirty.accounts {
 			if v != nil {
 				// New/update object
@@ -163,9 +212,99 @@ func (s *metaState) commitProcedure() (_ func(*bolt.Tx) error) {
 				}
 			}
 		}
-		// Clean dirty map and tx pool
+		for k, v := range s.dirty.miners {
+			if v != nil {
+				// New/update object
+				s.readonly.miners[k] = v
+				if enc, err = utils.EncodeMsgPack(v.MinerProfile); err != nil {
+					return
+				}
+				if err = mb.Put([]byte(k), enc.Bytes()); err != nil {
+					return
+				}
+			} else {
+				// Delete object
+				delete(s.readonly.miners, k)
+				if err = mb.Delete([]byte(k)); err != nil {
+					return
+				}
+			}
+		}
+		for k, v := range s.dirty.billings {
+			if v != nil {
+				// New/update object
+				s.readonly.billings[k] = v
+				if enc, err = utils.EncodeMsgPack(v.BillingProfile); err != nil {
+					return
+				}
+				if err = bb.Put(k[:], enc.Bytes()); err != nil {
+					return
+				}
+			} else {
+				// Delete object
+				delete(s.readonly.billings, k)
+				if err = bb.Delete(k[:]); err != nil {
+					return
+				}
+			}
+		}
+		for k, v := range s.dirty.multisigs {
+			if v != nil {
+				// New/update object
+				s.readonly.multisigs[k] = v
+				if enc, err = utils.EncodeMsgPack(v.MultiSigProfile); err != nil {
+					return
+				}
+				if err = sb.Put(k[:], enc.Bytes()); err != nil {
+					return
+				}
+			} else {
+				// Delete object
+				delete(s.readonly.multisigs, k)
+				if err = sb.Delete(k[:]); err != nil {
+					return
+				}
+			}
+		}
+		for k, v := range s.dirty.params {
+			if v != nil {
+				// New/update object
+				s.readonly.params[k] = v
+				if enc, err = utils.EncodeMsgPack(v.ParamsUpdate); err != nil {
+					return
+				}
+				if err = pb.Put(k[:], enc.Bytes()); err != nil {
+					return
+				}
+			} else {
+				// Delete object
+				delete(s.readonly.params, k)
+				if err = pb.Delete(k[:]); err != nil {
+					return
+				}
+			}
+		}
+		for k, v := range s.dirty.vestings {
+			if v != nil {
+				// New/update object
+				s.readonly.vestings[k] = v
+				if enc, err = utils.EncodeMsgPack(v.VestingProfile); err != nil {
+					return
+				}
+				if err = vb.Put(k[:], enc.Bytes()); err != nil {
+					return
+				}
+			} else {
+				// Delete object
+				delete(s.readonly.vestings, k)
+				if err = vb.Delete(k[:]); err != nil {
+					return
+				}
+			}
+		}
+		// Clean dirty map and tx pool, queued transactions are kept for the next blocks
 		s.dirty = newMetaIndex()
-		s.pool = newTxPool()
+		s.pool = s.pool.reset()
 		return
 	}
 }
@@ -181,6 +320,11 @@ func (s *metaState) reloadProcedure() (_ func(*bolt.Tx) error) {
 		var (
 			ab = tx.Bucket(metaBucket[:]).Bucket(metaAccountIndexBucket)
 			cb = tx.Bucket(metaBucket[:]).Bucket(metaSQLChainIndexBucket)
+			mb = tx.Bucket(metaBucket[:]).Bucket(metaMinerIndexBucket)
+			bb = tx.Bucket(metaBucket[:]).Bucket(metaBillingIndexBucket)
+			sb = tx.Bucket(metaBucket[:]).Bucket(metaMultiSigIndexBucket)
+			pb = tx.Bucket(metaBucket[:]).Bucket(metaParamsIndexBucket)
+			vb = tx.Bucket(metaBucket[:]).Bucket(metaVestingIndexBucket)
 		)
 		if err = ab.ForEach(func(k, v []byte) (err error) {
 			ao := &accountObject{}
@@ -202,6 +346,215 @@ func (s *metaState) reloadProcedure() (_ func(*bolt.Tx) error) {
 		}); err != nil {
 			return
 		}
+		if err = mb.ForEach(func(k, v []byte) (err error) {
+			mo := &minerObject{}
+			if err = utils.DecodeMsgPack(v, &mo.MinerProfile); err != nil {
+				return
+			}
+			s.readonly.miners[mo.MinerProfile.NodeID] = mo
+			return
+		}); err != nil {
+			return
+		}
+		if err = bb.ForEach(func(k, v []byte) (err error) {
+			bo := &billingObject{}
+			if err = utils.DecodeMsgPack(v, &bo.BillingProfile); err != nil {
+				return
+			}
+			s.readonly.billings[bo.BillingProfile.RequestHash] = bo
+			return
+		}); err != nil {
+			return
+		}
+		if err = sb.ForEach(func(k, v []byte) (err error) {
+			so := &multisigObject{}
+			if err = utils.DecodeMsgPack(v, &so.MultiSigProfile); err != nil {
+				return
+			}
+			s.readonly.multisigs[so.MultiSigProfile.Address] = so
+			return
+		}); err != nil {
+			return
+		}
+		if err = pb.ForEach(func(k, v []byte) (err error) {
+			po := &paramsObject{}
+			if err = utils.DecodeMsgPack(v, &po.ParamsUpdate); err != nil {
+				return
+			}
+			s.readonly.params[po.ParamsUpdate.Hash] = po
+			return
+		}); err != nil {
+			return
+		}
+		if err = vb.ForEach(func(k, v []byte) (err error) {
+			vo := &vestingObject{}
+			if err = utils.DecodeMsgPack(v, &vo.VestingProfile); err != nil {
+				return
+			}
+			s.readonly.vestings[vo.VestingProfile.ID] = vo
+			return
+		}); err != nil {
+			return
+		}
+		return
+	}
+}
+
+// snapshot returns the state committed by produced blocks, the objects are sorted by their keys.
+func (s *metaState) snapshot() (snap *pt.StateSnapshot) {
+	s.RLock()
+	defer s.RUnlock()
+	snap = &pt.StateSnapshot{
+		Accounts:  make([]pt.Account, 0, len(s.readonly.accounts)),
+		Databases: make([]pt.SQLChainProfile, 0, len(s.readonly.databases)),
+		Miners:    make([]pt.MinerProfile, 0, len(s.readonly.miners)),
+		Billings:  make([]pt.BillingProfile, 0, len(s.readonly.billings)),
+		MultiSigs: make([]pt.MultiSigProfile, 0, len(s.readonly.multisigs)),
+		Params:    make([]pt.ParamsUpdate, 0, len(s.readonly.params)),
+		Vestings:  make([]pt.VestingProfile, 0, len(s.readonly.vestings)),
+	}
+	for _, o := range s.readonly.accounts {
+		snap.Accounts = append(snap.Accounts, o.Account)
+	}
+	sort.Slice(snap.Accounts, func(i, j int) bool {
+		return bytes.Compare(snap.Accounts[i].Address[:], snap.Accounts[j].Address[:]) < 0
+	})
+	for _, o := range s.readonly.databases {
+		snap.Databases = append(snap.Databases, copySQLChainProfile(&o.SQLChainProfile))
+	}
+	sort.Slice(snap.Databases, func(i, j int) bool {
+		return snap.Databases[i].ID < snap.Databases[j].ID
+	})
+	for _, o := range s.readonly.miners {
+		snap.Miners = append(snap.Miners, o.MinerProfile)
+	}
+	sort.Slice(snap.Miners, func(i, j int) bool {
+		return snap.Miners[i].NodeID < snap.Miners[j].NodeID
+	})
+	for _, o := range s.readonly.billings {
+		snap.Billings = append(snap.Billings, o.BillingProfile)
+	}
+	sort.Slice(snap.Billings, func(i, j int) bool {
+		return bytes.Compare(snap.Billings[i].RequestHash[:], snap.Billings[j].RequestHash[:]) < 0
+	})
+	for _, o := range s.readonly.multisigs {
+		snap.MultiSigs = append(snap.MultiSigs, copyMultiSigProfile(&o.MultiSigProfile))
+	}
+	sort.Slice(snap.MultiSigs, func(i, j int) bool {
+		return bytes.Compare(snap.MultiSigs[i].Address[:], snap.MultiSigs[j].Address[:]) < 0
+	})
+	for _, o := range s.readonly.params {
+		snap.Params = append(snap.Params, copyParamsUpdate(&o.ParamsUpdate))
+	}
+	sort.Slice(snap.Params, func(i, j int) bool {
+		return bytes.Compare(snap.Params[i].Hash[:], snap.Params[j].Hash[:]) < 0
+	})
+	for _, o := range s.readonly.vestings {
+		snap.Vestings = append(snap.Vestings, o.VestingProfile)
+	}
+	sort.Slice(snap.Vestings, func(i, j int) bool {
+		return bytes.Compare(snap.Vestings[i].ID[:], snap.Vestings[j].ID[:]) < 0
+	})
+	return
+}
+
+// restoreProcedure replaces the whole state with snap and write persistence within a boltdb
+// transaction, pending transactions are dropped.
+func (s *metaState) restoreProcedure(snap *pt.StateSnapshot) (_ func(*bolt.Tx) error) {
+	return func(tx *bolt.Tx) (err error) {
+		var (
+			meta = tx.Bucket(metaBucket[:])
+			enc  *bytes.Buffer
+			bks  = make(map[string]*bolt.Bucket)
+			ri   = newMetaIndex()
+		)
+		for _, name := range [][]byte{
+			metaAccountIndexBucket, metaSQLChainIndexBucket, metaMinerIndexBucket, metaBillingIndexBucket,
+			metaMultiSigIndexBucket, metaParamsIndexBucket, metaVestingIndexBucket,
+		} {
+			if err = meta.DeleteBucket(name); err != nil && err != bolt.ErrBucketNotFound {
+				return
+			}
+			if bks[string(name)], err = meta.CreateBucket(name); err != nil {
+				return
+			}
+		}
+		for i := range snap.Accounts {
+			o := &accountObject{Account: snap.Accounts[i]}
+			if enc, err = utils.EncodeMsgPack(o.Account); err != nil {
+				return
+			}
+			if err = bks[string(metaAccountIndexBucket)].Put(o.Address[:], enc.Bytes()); err != nil {
+				return
+			}
+			ri.accounts[o.Address] = o
+		}
+		for i := range snap.Databases {
+			o := &sqlchainObject{SQLChainProfile: snap.Databases[i]}
+			if enc, err = utils.EncodeMsgPack(o.SQLChainProfile); err != nil {
+				return
+			}
+			if err = bks[string(metaSQLChainIndexBucket)].Put([]byte(o.ID), enc.Bytes()); err != nil {
+				return
+			}
+			ri.databases[o.ID] = o
+		}
+		for i := range snap.Miners {
+			o := &minerObject{MinerProfile: snap.Miners[i]}
+			if enc, err = utils.EncodeMsgPack(o.MinerProfile); err != nil {
+				return
+			}
+			if err = bks[string(metaMinerIndexBucket)].Put([]byte(o.NodeID), enc.Bytes()); err != nil {
+				return
+			}
+			ri.miners[o.NodeID] = o
+		}
+		for i := range snap.Billings {
+			o := &billingObject{BillingProfile: snap.Billings[i]}
+			if enc, err = utils.EncodeMsgPack(o.BillingProfile); err != nil {
+				return
+			}
+			if err = bks[string(metaBillingIndexBucket)].Put(o.RequestHash[:], enc.Bytes()); err != nil {
+				return
+			}
+			ri.billings[o.RequestHash] = o
+		}
+		for i := range snap.MultiSigs {
+			o := &multisigObject{MultiSigProfile: snap.MultiSigs[i]}
+			if enc, err = utils.EncodeMsgPack(o.MultiSigProfile); err != nil {
+				return
+			}
+			if err = bks[string(metaMultiSigIndexBucket)].Put(o.Address[:], enc.Bytes()); err != nil {
+				return
+			}
+			ri.multisigs[o.Address] = o
+		}
+		for i := range snap.Params {
+			o := &paramsObject{ParamsUpdate: snap.Params[i]}
+			if enc, err = utils.EncodeMsgPack(o.ParamsUpdate); err != nil {
+				return
+			}
+			if err = bks[string(metaParamsIndexBucket)].Put(o.Hash[:], enc.Bytes()); err != nil {
+				return
+			}
+			ri.params[o.Hash] = o
+		}
+		for i := range snap.Vestings {
+			o := &vestingObject{VestingProfile: snap.Vestings[i]}
+			if enc, err = utils.EncodeMsgPack(o.VestingProfile); err != nil {
+				return
+			}
+			if err = bks[string(metaVestingIndexBucket)].Put(o.ID[:], enc.Bytes()); err != nil {
+				return
+			}
+			ri.vestings[o.ID] = o
+		}
+		s.Lock()
+		defer s.Unlock()
+		s.readonly = ri
+		s.dirty = newMetaIndex()
+		s.pool = newTxPool()
+		s.height = snap.Height
 		return
 	}
 }
@@ -251,6 +604,28 @@ func (s *metaState) decreaseAccountStableBalance(k proto.AccountAddress, amount
 func (s *metaState) transferAccountStableBalance(
 	sender, receiver proto.AccountAddress, amount uint64) (err error,
 ) {
+	return s.transferAccountBalance(sender, receiver, amount, pt.StableCoin)
+}
+
+// tokenBalance returns the balance field of token type in account.
+func tokenBalance(o *accountObject, token pt.TokenType) (balance *uint64, err error) {
+	switch token {
+	case pt.StableCoin:
+		balance = &o.StableCoinBalance
+	case pt.CovenantCoin:
+		balance = &o.CovenantCoinBalance
+	default:
+		err = pt.ErrInvalidTokenType
+	}
+	return
+}
+
+func (s *metaState) transferAccountBalance(
+	sender, receiver proto.AccountAddress, amount uint64, token pt.TokenType) (err error,
+) {
+	if token < pt.StableCoin || token >= pt.NumberOfTokenType {
+		return pt.ErrInvalidTokenType
+	}
 	if sender == receiver {
 		return
 	}
@@ -289,9 +664,16 @@ func (s *metaState) transferAccountStableBalance(
 	}
 
 	// Try transfer
+	var sbp, rbp *uint64
+	if sbp, err = tokenBalance(so, token); err != nil {
+		return
+	}
+	if rbp, err = tokenBalance(ro, token); err != nil {
+		return
+	}
 	var (
-		sb = so.StableCoinBalance
-		rb = ro.StableCoinBalance
+		sb = *sbp
+		rb = *rbp
 	)
 	if err = safeSub(&sb, &amount); err != nil {
 		return
@@ -313,8 +695,9 @@ func (s *metaState) transferAccountStableBalance(
 		ro = cpy
 		s.dirty.accounts[receiver] = cpy
 	}
-	so.StableCoinBalance = sb
-	ro.StableCoinBalance = rb
+	sbp, _ = tokenBalance(so, token)
+	rbp, _ = tokenBalance(ro, token)
+	*sbp, *rbp = sb, rb
 	return
 }
 
@@ -463,73 +846,1229 @@ func (s *metaState) alterSQLChainUser(
 	return
 }
 
-func (s *metaState) nextNonce(addr proto.AccountAddress) (nonce pi.AccountNonce, err error) {
-	s.Lock()
-	defer s.Unlock()
-	if e, ok := s.pool.getTxEntries(addr); ok {
-		nonce = e.nextNonce()
-		return
+// updatePermission grants permission to or revokes permission from a database user on behalf
+// of the database admin.
+func (s *metaState) updatePermission(tx *pt.UpdatePermission) (err error) {
+	o, loaded := s.loadSQLChainObject(tx.DatabaseID)
+	if !loaded {
+		return ErrDatabaseNotFound
 	}
+
 	var (
-		o      *accountObject
-		loaded bool
+		isAdmin, exists bool
 	)
-	if o, loaded = s.dirty.accounts[addr]; !loaded {
-		if o, loaded = s.readonly.accounts[addr]; !loaded {
-			err = ErrAccountNotFound
-			return
+	s.RLock()
+	for _, v := range o.Users {
+		if v.Address == tx.Sender && v.Permission == pt.Admin {
+			isAdmin = true
+		}
+		if v.Address == tx.User {
+			exists = true
 		}
 	}
-	nonce = o.Account.NextNonce
-	return
+	s.RUnlock()
+
+	if !isAdmin {
+		return ErrPermissionDenied
+	}
+	if tx.Revoke {
+		if !exists {
+			return ErrDatabaseUserNotFound
+		}
+		return s.deleteSQLChainUser(tx.DatabaseID, tx.User)
+	}
+	if exists {
+		return s.alterSQLChainUser(tx.DatabaseID, tx.User, tx.Permission)
+	}
+	return s.addSQLChainUser(tx.DatabaseID, tx.User, tx.Permission)
 }
 
-func (s *metaState) applyBilling(tx *pt.TxBilling) (err error) {
-	for i, v := range tx.TxContent.Receivers {
-		if err = s.increaseAccountCovenantBalance(*v, tx.TxContent.Fees[i]); err != nil {
+// registerMiner registers or deregisters the miner node by the sender account, the profile of a
+// registered miner is replaced by a new registration. A registration must be signed by the node
+// key over the sender account, and only the registering account can update or deregister it.
+// The stake is locked from the covenant coin balance of the miner account, and unlocked by
+// deregistration.
+func (s *metaState) registerMiner(tx *pt.RegisterMiner) (err error) {
+	if !tx.Deregister {
+		if err = tx.VerifyNode(); err != nil {
 			return
 		}
-		if err = s.increaseAccountStableBalance(*v, tx.TxContent.Rewards[i]); err != nil {
+	}
+	current, registered := s.loadMinerProfile(tx.NodeID)
+	if registered && current.Address != tx.Sender {
+		return ErrPermissionDenied
+	}
+	if tx.Deregister {
+		if !registered {
+			return ErrMinerNotFound
+		}
+		if len(current.Challenges) > 0 {
+			return ErrMinerChallenged
+		}
+		if err = s.increaseAccountCovenantBalance(tx.Sender, current.Stake); err != nil {
 			return
 		}
+		s.deleteMinerObject(tx.NodeID)
+		return
+	}
+	if tx.Stake < s.activeParams().MinMinerStake {
+		return ErrInsufficientStake
+	}
+	switch {
+	case tx.Stake > current.Stake:
+		err = s.decreaseAccountCovenantBalance(tx.Sender, tx.Stake-current.Stake)
+	case tx.Stake < current.Stake:
+		if len(current.Challenges) > 0 {
+			return ErrMinerChallenged
+		}
+		err = s.increaseAccountCovenantBalance(tx.Sender, current.Stake-tx.Stake)
+	}
+	if err != nil {
+		return
 	}
+	profile := tx.Profile()
+	profile.Challenges = current.Challenges
+	s.storeMinerProfile(profile)
 	return
 }
 
-func (s *metaState) applyTransaction(tx pi.Transaction) (err error) {
-	switch t := tx.(type) {
-	case *pt.Transfer:
-		err = s.transferAccountStableBalance(t.Sender, t.Receiver, t.Amount)
-	case *pt.TxBilling:
-		err = s.applyBilling(t)
-	default:
-		err = ErrUnknownTransactionType
+// loadMinerProfile returns a copy of the miner profile of node id from the dirty map or the
+// readonly map.
+func (s *metaState) loadMinerProfile(id proto.NodeID) (profile pt.MinerProfile, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *minerObject
+	if o, loaded = s.dirty.miners[id]; !loaded {
+		o, loaded = s.readonly.miners[id]
+	}
+	if !loaded || o == nil {
+		loaded = false
+		return
 	}
+	profile = copyMinerProfile(&o.MinerProfile)
 	return
 }
 
-// applyTransaction tries to apply t to the metaState and push t to the memory pool if and
-// only if it can be applied correctly.
-func (s *metaState) applyTransactionProcedure(t pi.Transaction) (_ func(*bolt.Tx) error) {
-	var (
-		err     error
-		errPass = func(*bolt.Tx) error {
-			return err
-		}
-	)
-	// Static checks, which have no relation with metaState
-	if err = t.Verify(); err != nil {
-		return errPass
-	}
+// storeMinerProfile stores a copy of miner profile to the dirty map.
+func (s *metaState) storeMinerProfile(profile *pt.MinerProfile) {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty.miners[profile.NodeID] = &minerObject{MinerProfile: copyMinerProfile(profile)}
+}
 
-	var (
-		enc   []byte
-		hash  = t.GetHash()
-		addr  = t.GetAccountAddress()
-		nonce = t.GetAccountNonce()
-		ttype = t.GetTransactionType()
-	)
-	if enc, err = t.Serialize(); err != nil {
+func (s *metaState) deleteMinerObject(id proto.NodeID) {
+	s.Lock()
+	defer s.Unlock()
+	// Use a nil pointer to mark a deletion, which will be later used by commit procedure.
+	s.dirty.miners[id] = nil
+}
+
+func copyMinerProfile(src *pt.MinerProfile) (dst pt.MinerProfile) {
+	dst = *src
+	dst.Challenges = append([]pt.MinerChallenge(nil), src.Challenges...)
+	return
+}
+
+// applyMinerEvidence slashes the miner proved to sign conflicting responses, the slashed stake is
+// rewarded to the reporter.
+func (s *metaState) applyMinerEvidence(tx *pt.MinerEvidence) (err error) {
+	profile, loaded := s.loadMinerProfile(tx.NodeID)
+	if !loaded {
+		return ErrMinerNotFound
+	}
+	var addr proto.AccountAddress
+	if addr, err = tx.MinerAddress(); err != nil {
+		return
+	}
+	if addr != profile.Address {
+		return pt.ErrInvalidMinerEvidence
+	}
+	return s.slashMiner(&profile, tx.Sender)
+}
+
+// challengeService adds a service challenge to the miner on behalf of the request signer, the
+// deadline is set by settleMinerChallenges.
+func (s *metaState) challengeService(tx *pt.ServiceChallenge) (err error) {
+	profile, loaded := s.loadMinerProfile(tx.NodeID)
+	if !loaded {
+		return ErrMinerNotFound
+	}
+	for _, v := range profile.Challenges {
+		if v.Request == tx.Request.HeaderHash {
+			return ErrExistedTx
+		}
+	}
+	profile.Challenges = append(profile.Challenges, pt.MinerChallenge{
+		Request:    tx.Request.HeaderHash,
+		Challenger: tx.Sender,
+	})
+	s.storeMinerProfile(&profile)
+	return
+}
+
+// proveService removes the service challenge answered by the response of the miner.
+func (s *metaState) proveService(tx *pt.ServiceProof) (err error) {
+	profile, loaded := s.loadMinerProfile(tx.NodeID)
+	if !loaded {
+		return ErrMinerNotFound
+	}
+	if profile.Address != tx.Sender {
+		return ErrPermissionDenied
+	}
+	for i, v := range profile.Challenges {
+		if v.Request == tx.Response.Request.HeaderHash {
+			profile.Challenges = append(profile.Challenges[:i], profile.Challenges[i+1:]...)
+			s.storeMinerProfile(&profile)
+			return
+		}
+	}
+	return ErrMinerChallengeNotFound
+}
+
+// settleMinerChallenges settles the service challenges with the main chain height of block being
+// pushed, which should be called before the commit procedure: new challenges are stamped with
+// the deadline, and miners with challenges not answered until the deadline are slashed.
+func (s *metaState) settleMinerChallenges(height uint32) (err error) {
+	var (
+		expired     []pt.MinerProfile
+		challengers []proto.AccountAddress
+		check       = func(profile *pt.MinerProfile) {
+			for _, v := range profile.Challenges {
+				if v.Deadline != 0 && height > v.Deadline {
+					expired = append(expired, copyMinerProfile(profile))
+					challengers = append(challengers, v.Challenger)
+					return
+				}
+			}
+		}
+	)
+	s.Lock()
+	for k, v := range s.dirty.miners {
+		if v == nil {
+			continue
+		}
+		profile := copyMinerProfile(&v.MinerProfile)
+		for i := range profile.Challenges {
+			if profile.Challenges[i].Deadline == 0 {
+				profile.Challenges[i].Deadline = height + serviceChallengeWindow
+			}
+		}
+		s.dirty.miners[k] = &minerObject{MinerProfile: profile}
+		check(&profile)
+	}
+	for k, v := range s.readonly.miners {
+		if _, ok := s.dirty.miners[k]; !ok {
+			check(&v.MinerProfile)
+		}
+	}
+	s.Unlock()
+
+	var index = make([]int, len(expired))
+	for i := range index {
+		index[i] = i
+	}
+	sort.Slice(index, func(i, j int) bool {
+		return expired[index[i]].NodeID < expired[index[j]].NodeID
+	})
+	for _, i := range index {
+		if err = s.slashMiner(&expired[i], challengers[i]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// slashMiner removes the miner from main chain, minerSlashPercent of its stake is rewarded to the
+// reporter and the rest is unlocked.
+func (s *metaState) slashMiner(profile *pt.MinerProfile, reporter proto.AccountAddress) (err error) {
+	var (
+		slashed = profile.Stake/100*minerSlashPercent + profile.Stake%100*minerSlashPercent/100
+		rest    = profile.Stake - slashed
+	)
+	if slashed > 0 {
+		if err = s.increaseAccountCovenantBalance(reporter, slashed); err != nil {
+			return
+		}
+	}
+	if rest > 0 {
+		if err = s.increaseAccountCovenantBalance(profile.Address, rest); err != nil {
+			return
+		}
+	}
+	s.deleteMinerObject(profile.NodeID)
+	log.WithFields(log.Fields{
+		"node":     profile.NodeID,
+		"reporter": hash.Hash(reporter).String(),
+		"slashed":  slashed,
+	}).Warning("miner slashed for misbehavior")
+	return
+}
+
+// loadConfirmedMiners returns the miners registered by produced blocks.
+func (s *metaState) loadConfirmedMiners() (miners []pt.MinerProfile) {
+	s.RLock()
+	defer s.RUnlock()
+	miners = make([]pt.MinerProfile, 0, len(s.readonly.miners))
+	for _, o := range s.readonly.miners {
+		miners = append(miners, o.MinerProfile)
+	}
+	sort.Slice(miners, func(i, j int) bool {
+		return miners[i].NodeID < miners[j].NodeID
+	})
+	return
+}
+
+// hasConfirmedMiners returns whether any miner is registered by produced blocks.
+func (s *metaState) hasConfirmedMiners() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.readonly.miners) > 0
+}
+
+// loadConfirmedMiner returns the profile of miner node registered by produced blocks.
+func (s *metaState) loadConfirmedMiner(id proto.NodeID) (miner pt.MinerProfile, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *minerObject
+	if o, loaded = s.readonly.miners[id]; loaded {
+		miner = o.MinerProfile
+	}
+	return
+}
+
+// pendingDeregisteredMiners returns the registered miners which are deregistered by pending
+// transactions.
+func (s *metaState) pendingDeregisteredMiners() (nodes []proto.NodeID) {
+	s.RLock()
+	defer s.RUnlock()
+	for k, v := range s.dirty.miners {
+		if _, ok := s.readonly.miners[k]; ok && v == nil {
+			nodes = append(nodes, k)
+		}
+	}
+	return
+}
+
+// loadConfirmedSQLChainProfile returns the profile of database committed by produced blocks.
+func (s *metaState) loadConfirmedSQLChainProfile(k proto.DatabaseID) (profile pt.SQLChainProfile, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *sqlchainObject
+	if o, loaded = s.readonly.databases[k]; !loaded {
+		return
+	}
+	profile = copySQLChainProfile(&o.SQLChainProfile)
+	return
+}
+
+// loadConfirmedUserSQLChainProfiles returns the profiles of databases which have addr as a user,
+// the profiles are committed by produced blocks.
+func (s *metaState) loadConfirmedUserSQLChainProfiles(
+	addr proto.AccountAddress) (profiles []pt.SQLChainProfile,
+) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, o := range s.readonly.databases {
+		for _, v := range o.Users {
+			if v.Address == addr {
+				profiles = append(profiles, copySQLChainProfile(&o.SQLChainProfile))
+				break
+			}
+		}
+	}
+	return
+}
+
+func copySQLChainProfile(src *pt.SQLChainProfile) (dst pt.SQLChainProfile) {
+	dst = pt.SQLChainProfile{
+		ID:      src.ID,
+		Deposit: src.Deposit,
+		Owner:   src.Owner,
+		Miners:  append([]proto.AccountAddress(nil), src.Miners...),
+		Users:   make([]*pt.SQLChainUser, 0, len(src.Users)),
+	}
+	for _, v := range src.Users {
+		var user = *v
+		dst.Users = append(dst.Users, &user)
+	}
+	return
+}
+
+// loadAccountBalance returns the balances of account including the changes of pending transactions.
+func (s *metaState) loadAccountBalance(
+	addr proto.AccountAddress) (stable, covenant uint64, loaded bool,
+) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *accountObject
+	if o, loaded = s.dirty.accounts[addr]; !loaded {
+		o, loaded = s.readonly.accounts[addr]
+	}
+	if !loaded || o == nil {
+		loaded = false
+		return
+	}
+	stable, covenant = o.StableCoinBalance, o.CovenantCoinBalance
+	return
+}
+
+// isTxPending returns whether the transaction is applied but not committed yet.
+func (s *metaState) isTxPending(h hash.Hash) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.pool.hasTx(h)
+}
+
+func (s *metaState) nextNonce(addr proto.AccountAddress) (nonce pi.AccountNonce, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if e, ok := s.pool.getTxEntries(addr); ok {
+		nonce = e.nextNonce()
+		return
+	}
+	var (
+		o      *accountObject
+		loaded bool
+	)
+	if o, loaded = s.dirty.accounts[addr]; !loaded {
+		if o, loaded = s.readonly.accounts[addr]; !loaded {
+			err = ErrAccountNotFound
+			return
+		}
+	}
+	nonce = o.Account.NextNonce
+	return
+}
+
+// queuedNonces returns the nonces of transactions of account addr which are queued for the
+// nonce gap in ascending order.
+func (s *metaState) queuedNonces(addr proto.AccountAddress) []pi.AccountNonce {
+	s.RLock()
+	defer s.RUnlock()
+	return s.pool.queuedNonces(addr)
+}
+
+func (s *metaState) applyBilling(tx *pt.TxBilling) (err error) {
+	var (
+		br      = &tx.TxContent.BillingRequest
+		profile = pt.BillingProfile{
+			RequestHash: br.RequestHash,
+			DatabaseID:  br.Header.DatabaseID,
+			Fees:        tx.TxContent.Fees,
+			Rewards:     tx.TxContent.Rewards,
+			ReadCount:   br.Header.ReadCount,
+			WriteCount:  br.Header.WriteCount,
+		}
+	)
+	for i, v := range tx.TxContent.Receivers {
+		if err = s.increaseAccountCovenantBalance(*v, tx.TxContent.Fees[i]); err != nil {
+			return
+		}
+		if err = s.increaseAccountStableBalance(*v, tx.TxContent.Rewards[i]); err != nil {
+			return
+		}
+		profile.Receivers = append(profile.Receivers, *v)
+	}
+	// Keep the billing for dispute, see settleBillings
+	s.Lock()
+	defer s.Unlock()
+	s.dirty.billings[br.RequestHash] = &billingObject{BillingProfile: profile}
+	return
+}
+
+// loadBillingObject returns the billing object of request hash h from the dirty map or the
+// readonly map.
+func (s *metaState) loadBillingObject(h hash.Hash) (o *billingObject, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if o, loaded = s.dirty.billings[h]; loaded {
+		loaded = o != nil
+		return
+	}
+	o, loaded = s.readonly.billings[h]
+	return
+}
+
+// storeBillingProfile stores a copy of billing profile to the dirty map.
+func (s *metaState) storeBillingProfile(profile *pt.BillingProfile) {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty.billings[profile.RequestHash] = &billingObject{BillingProfile: *profile}
+}
+
+// challengeBilling challenges a settled billing on behalf of the database owner, the billed
+// miners should prove the billing in billingChallengeWindow blocks.
+func (s *metaState) challengeBilling(tx *pt.BillingChallenge) (err error) {
+	o, loaded := s.loadBillingObject(tx.RequestHash)
+	if !loaded {
+		return ErrBillingNotFound
+	}
+	db, loaded := s.loadSQLChainObject(o.DatabaseID)
+	if !loaded {
+		return ErrDatabaseNotFound
+	}
+	s.RLock()
+	owner := db.Owner
+	profile := o.BillingProfile
+	s.RUnlock()
+	if owner != tx.Sender {
+		return ErrPermissionDenied
+	}
+	if profile.State != pt.BillingStateSettled {
+		return ErrInvalidBillingState
+	}
+	profile.State = pt.BillingStateChallenged
+	profile.Challenger = tx.Sender
+	// The deadline is stamped by the block confirming the challenge
+	profile.Deadline = 0
+	s.storeBillingProfile(&profile)
+	return
+}
+
+// proveBilling proves a challenged billing on behalf of a billed miner, the acks should be
+// signed by the database users and cover the billed read and write queries.
+func (s *metaState) proveBilling(tx *pt.BillingProof) (err error) {
+	o, loaded := s.loadBillingObject(tx.RequestHash)
+	if !loaded {
+		return ErrBillingNotFound
+	}
+	db, loaded := s.loadSQLChainObject(o.DatabaseID)
+	if !loaded {
+		return ErrDatabaseNotFound
+	}
+	var (
+		users         = make(map[proto.AccountAddress]bool)
+		billed        bool
+		profile       pt.BillingProfile
+		reads, writes uint64
+		seen          = make(map[hash.Hash]bool)
+	)
+	s.RLock()
+	for _, v := range db.Users {
+		users[v.Address] = true
+	}
+	profile = o.BillingProfile
+	s.RUnlock()
+	for _, v := range profile.Receivers {
+		if v == tx.Sender {
+			billed = true
+		}
+	}
+	if !billed {
+		return ErrPermissionDenied
+	}
+	if profile.State != pt.BillingStateChallenged {
+		return ErrInvalidBillingState
+	}
+	for _, v := range tx.Acks {
+		if seen[v.HeaderHash] || v.Response.Request.DatabaseID != profile.DatabaseID {
+			continue
+		}
+		var addr proto.AccountAddress
+		if addr, err = utils.PubKeyHash(v.Signee); err != nil {
+			return
+		}
+		if !users[addr] {
+			continue
+		}
+		seen[v.HeaderHash] = true
+		if v.Response.Request.QueryType == wt.WriteQuery {
+			writes += v.Response.Request.BatchCount
+		} else {
+			reads += v.Response.Request.BatchCount
+		}
+	}
+	if reads < profile.ReadCount || writes < profile.WriteCount {
+		return ErrInsufficientBillingProof
+	}
+	profile.State = pt.BillingStateProved
+	s.storeBillingProfile(&profile)
+	return
+}
+
+// settleBillings settles the billings with the main chain height of block being pushed, which
+// should be called before the commit procedure: new billings and challenges are stamped with the
+// height, challenges not proved until the deadline are rejected, and billings out of dispute
+// period are removed.
+func (s *metaState) settleBillings(height uint32) (err error) {
+	var rejected []pt.BillingProfile
+	s.Lock()
+	for k, v := range s.dirty.billings {
+		if v == nil {
+			continue
+		}
+		profile := v.BillingProfile
+		if profile.Height == 0 {
+			profile.Height = height
+		}
+		if profile.State == pt.BillingStateChallenged && profile.Deadline == 0 {
+			profile.Deadline = height + billingChallengeWindow
+		}
+		s.dirty.billings[k] = &billingObject{BillingProfile: profile}
+	}
+	for k, v := range s.readonly.billings {
+		if _, ok := s.dirty.billings[k]; ok {
+			continue
+		}
+		switch {
+		case v.State == pt.BillingStateChallenged:
+			if height > v.Deadline {
+				rejected = append(rejected, v.BillingProfile)
+			}
+		case height >= v.Height+billingDisputePeriod:
+			// Use a nil pointer to mark a deletion, which will be later used by commit procedure.
+			s.dirty.billings[k] = nil
+		}
+	}
+	s.Unlock()
+
+	sort.Slice(rejected, func(i, j int) bool {
+		return bytes.Compare(rejected[i].RequestHash[:], rejected[j].RequestHash[:]) < 0
+	})
+	for i := range rejected {
+		if err = s.rejectBilling(&rejected[i]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// rejectBilling takes back the billed incomes from the miners, and transfers the fees as much
+// as the miners have to the challenger as penalty.
+func (s *metaState) rejectBilling(profile *pt.BillingProfile) (err error) {
+	var penalty uint64
+	for i, v := range profile.Receivers {
+		var fee, reward, drained uint64
+		if i < len(profile.Fees) {
+			fee = profile.Fees[i]
+		}
+		if i < len(profile.Rewards) {
+			reward = profile.Rewards[i]
+		}
+		if _, err = s.drainAccountBalance(v, fee, pt.CovenantCoin); err != nil {
+			return
+		}
+		if _, err = s.drainAccountBalance(v, reward, pt.StableCoin); err != nil {
+			return
+		}
+		if drained, err = s.drainAccountBalance(v, fee, pt.CovenantCoin); err != nil {
+			return
+		}
+		if err = safeAdd(&penalty, &drained); err != nil {
+			return
+		}
+	}
+	if penalty > 0 {
+		if err = s.increaseAccountCovenantBalance(profile.Challenger, penalty); err != nil {
+			return
+		}
+	}
+	log.WithFields(log.Fields{
+		"request":  profile.RequestHash.String(),
+		"database": profile.DatabaseID,
+		"penalty":  penalty,
+	}).Warning("billing rejected for challenge not proved in time")
+	profile.State = pt.BillingStateRejected
+	s.storeBillingProfile(profile)
+	return
+}
+
+// drainAccountBalance decreases the token balance of account k by amount at most, and returns
+// the amount actually decreased.
+func (s *metaState) drainAccountBalance(
+	k proto.AccountAddress, amount uint64, token pt.TokenType) (drained uint64, err error,
+) {
+	s.Lock()
+	defer s.Unlock()
+	var (
+		src, dst *accountObject
+		ok       bool
+		balance  *uint64
+	)
+	if dst, ok = s.dirty.accounts[k]; !ok {
+		if src, ok = s.readonly.accounts[k]; !ok {
+			return
+		}
+		dst = &accountObject{}
+		deepcopier.Copy(&src.Account).To(&dst.Account)
+		s.dirty.accounts[k] = dst
+	} else if dst == nil {
+		return
+	}
+	if balance, err = tokenBalance(dst, token); err != nil {
+		return
+	}
+	drained = amount
+	if *balance < drained {
+		drained = *balance
+	}
+	*balance -= drained
+	return
+}
+
+// loadConfirmedBilling returns the billing of request hash h confirmed by produced blocks.
+func (s *metaState) loadConfirmedBilling(h hash.Hash) (profile pt.BillingProfile, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *billingObject
+	if o, loaded = s.readonly.billings[h]; loaded {
+		profile = o.BillingProfile
+	}
+	return
+}
+
+// loadMultiSigObject returns the multi-signature account object of addr from the dirty map or
+// the readonly map.
+func (s *metaState) loadMultiSigObject(addr proto.AccountAddress) (o *multisigObject, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if o, loaded = s.dirty.multisigs[addr]; loaded {
+		if o == nil {
+			loaded = false
+		}
+		return
+	}
+	o, loaded = s.readonly.multisigs[addr]
+	return
+}
+
+// storeMultiSigProfile stores a copy of multi-signature account profile to the dirty map.
+func (s *metaState) storeMultiSigProfile(profile *pt.MultiSigProfile) {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty.multisigs[profile.Address] = &multisigObject{MultiSigProfile: copyMultiSigProfile(profile)}
+}
+
+func copyMultiSigProfile(src *pt.MultiSigProfile) (dst pt.MultiSigProfile) {
+	dst = pt.MultiSigProfile{
+		Address:   src.Address,
+		Threshold: src.Threshold,
+		Owners:    append([]proto.AccountAddress(nil), src.Owners...),
+		Proposals: make([]pt.MultiSigProposal, 0, len(src.Proposals)),
+	}
+	for _, v := range src.Proposals {
+		v.Approvals = append([]proto.AccountAddress(nil), v.Approvals...)
+		dst.Proposals = append(dst.Proposals, v)
+	}
+	return
+}
+
+// createMultiSig creates a multi-signature account with an empty balance, which can only spend
+// through proposals approved by its owners.
+func (s *metaState) createMultiSig(tx *pt.CreateMultiSig) (err error) {
+	var profile = tx.Profile()
+	if _, loaded := s.loadOrStoreAccountObject(profile.Address, &accountObject{
+		Account: pt.Account{
+			Address: profile.Address,
+		},
+	}); loaded {
+		return ErrAccountExists
+	}
+	s.storeMultiSigProfile(profile)
+	return
+}
+
+// proposeMultiSig adds a proposal of the multi-signature account on behalf of an owner, the
+// proposal is executed at once if the threshold is 1.
+func (s *metaState) proposeMultiSig(tx *pt.ProposeMultiSig) (err error) {
+	o, loaded := s.loadMultiSigObject(tx.Account)
+	if !loaded {
+		return ErrMultiSigNotFound
+	}
+	s.RLock()
+	profile := copyMultiSigProfile(&o.MultiSigProfile)
+	s.RUnlock()
+	if !profile.IsOwner(tx.Sender) {
+		return ErrPermissionDenied
+	}
+	for _, v := range profile.Proposals {
+		if v.Hash == tx.HeaderHash {
+			return ErrExistedTx
+		}
+	}
+	profile.Proposals = append(profile.Proposals, *tx.Proposal())
+	return s.executeMultiSigProposal(&profile, len(profile.Proposals)-1)
+}
+
+// approveMultiSig approves a pending proposal of the multi-signature account on behalf of an
+// owner, the proposal is executed once it's approved by enough owners.
+func (s *metaState) approveMultiSig(tx *pt.ApproveMultiSig) (err error) {
+	o, loaded := s.loadMultiSigObject(tx.Account)
+	if !loaded {
+		return ErrMultiSigNotFound
+	}
+	s.RLock()
+	profile := copyMultiSigProfile(&o.MultiSigProfile)
+	s.RUnlock()
+	if !profile.IsOwner(tx.Sender) {
+		return ErrPermissionDenied
+	}
+	var index = -1
+	for i, v := range profile.Proposals {
+		if v.Hash == tx.Proposal {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return ErrMultiSigProposalNotFound
+	}
+	var proposal = &profile.Proposals[index]
+	for _, v := range proposal.Approvals {
+		if v == tx.Sender {
+			return ErrMultiSigAlreadyApproved
+		}
+	}
+	proposal.Approvals = append(proposal.Approvals, tx.Sender)
+	return s.executeMultiSigProposal(&profile, index)
+}
+
+// executeMultiSigProposal executes the proposal at index if it reaches the threshold, and stores
+// the updated profile. An error of the execution fails the transaction, so the proposal is kept
+// pending and can be approved again by another owner.
+func (s *metaState) executeMultiSigProposal(profile *pt.MultiSigProfile, index int) (err error) {
+	var proposal = &profile.Proposals[index]
+	if uint32(len(proposal.Approvals)) >= profile.Threshold {
+		switch action := &proposal.Action; {
+		case action.Transfer != nil:
+			err = s.transferAccountBalance(
+				profile.Address, action.Transfer.Receiver, action.Transfer.Amount, action.Transfer.TokenType)
+		case action.Permission != nil:
+			err = s.updatePermission(&pt.UpdatePermission{UpdatePermissionHeader: *action.Permission})
+		default:
+			err = pt.ErrInvalidMultiSigAction
+		}
+		if err != nil {
+			return
+		}
+		profile.Proposals = append(profile.Proposals[:index], profile.Proposals[index+1:]...)
+	}
+	s.storeMultiSigProfile(profile)
+	return
+}
+
+// loadConfirmedMultiSig returns the multi-signature account profile of addr confirmed by
+// produced blocks.
+func (s *metaState) loadConfirmedMultiSig(addr proto.AccountAddress) (profile pt.MultiSigProfile, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *multisigObject
+	if o, loaded = s.readonly.multisigs[addr]; loaded {
+		profile = copyMultiSigProfile(&o.MultiSigProfile)
+	}
+	return
+}
+
+// loadVestingObject returns the vesting object of h from the dirty map or the readonly map.
+func (s *metaState) loadVestingObject(h hash.Hash) (o *vestingObject, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if o, loaded = s.dirty.vestings[h]; loaded {
+		if o == nil {
+			loaded = false
+		}
+		return
+	}
+	o, loaded = s.readonly.vestings[h]
+	return
+}
+
+// storeVestingProfile stores a copy of vesting profile to the dirty map.
+func (s *metaState) storeVestingProfile(profile *pt.VestingProfile) {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty.vestings[profile.ID] = &vestingObject{VestingProfile: *profile}
+}
+
+func (s *metaState) deleteVestingObject(h hash.Hash) {
+	s.Lock()
+	defer s.Unlock()
+	// Use a nil pointer to mark a deletion, which will be later used by commit procedure.
+	s.dirty.vestings[h] = nil
+}
+
+func (s *metaState) increaseAccountBalance(
+	k proto.AccountAddress, amount uint64, token pt.TokenType) (err error,
+) {
+	switch token {
+	case pt.StableCoin:
+		return s.increaseAccountStableBalance(k, amount)
+	case pt.CovenantCoin:
+		return s.increaseAccountCovenantBalance(k, amount)
+	default:
+		return pt.ErrInvalidTokenType
+	}
+}
+
+func (s *metaState) decreaseAccountBalance(
+	k proto.AccountAddress, amount uint64, token pt.TokenType) (err error,
+) {
+	switch token {
+	case pt.StableCoin:
+		return s.decreaseAccountStableBalance(k, amount)
+	case pt.CovenantCoin:
+		return s.decreaseAccountCovenantBalance(k, amount)
+	default:
+		return pt.ErrInvalidTokenType
+	}
+}
+
+// createVesting locks the tokens of the sender in a vesting, which are released to the beneficiary
+// by the schedule.
+func (s *metaState) createVesting(tx *pt.CreateVesting) (err error) {
+	if _, loaded := s.loadVestingObject(tx.HeaderHash); loaded {
+		return ErrExistedTx
+	}
+	if err = s.decreaseAccountBalance(tx.Sender, tx.Amount, tx.TokenType); err != nil {
+		return
+	}
+	s.storeVestingProfile(tx.Profile())
+	return
+}
+
+// releaseVesting transfers the vested tokens to the beneficiary, the vesting is removed once all
+// the tokens are settled. The tokens are vested by the height of the last settled block.
+func (s *metaState) releaseVesting(tx *pt.ReleaseVesting) (err error) {
+	o, loaded := s.loadVestingObject(tx.Vesting)
+	if !loaded {
+		return ErrVestingNotFound
+	}
+	s.RLock()
+	profile, height := o.VestingProfile, s.height
+	s.RUnlock()
+	if profile.Beneficiary != tx.Sender {
+		return ErrPermissionDenied
+	}
+	if tx.Amount > profile.Releasable(height) {
+		return ErrInsufficientVested
+	}
+	s.loadOrStoreAccountObject(profile.Beneficiary, &accountObject{
+		Account: pt.Account{
+			Address: profile.Beneficiary,
+		},
+	})
+	if err = s.increaseAccountBalance(profile.Beneficiary, tx.Amount, profile.TokenType); err != nil {
+		return
+	}
+	profile.Released += tx.Amount
+	if profile.Settled() {
+		s.deleteVestingObject(profile.ID)
+		return
+	}
+	s.storeVestingProfile(&profile)
+	return
+}
+
+// revokeVesting refunds the tokens not vested yet to the creator of a revocable vesting, the
+// vested ones are kept for the beneficiary to release.
+func (s *metaState) revokeVesting(tx *pt.RevokeVesting) (err error) {
+	o, loaded := s.loadVestingObject(tx.Vesting)
+	if !loaded {
+		return ErrVestingNotFound
+	}
+	s.RLock()
+	profile, height := o.VestingProfile, s.height
+	s.RUnlock()
+	if profile.Creator != tx.Sender {
+		return ErrPermissionDenied
+	}
+	if !profile.Revocable {
+		return ErrVestingNotRevocable
+	}
+	if profile.Revoked {
+		return ErrVestingRevoked
+	}
+	if unvested := profile.Unvested(height); unvested > 0 {
+		s.loadOrStoreAccountObject(profile.Creator, &accountObject{
+			Account: pt.Account{
+				Address: profile.Creator,
+			},
+		})
+		if err = s.increaseAccountBalance(profile.Creator, unvested, profile.TokenType); err != nil {
+			return
+		}
+	}
+	profile.Revoked, profile.RevokedHeight = true, height
+	if profile.Settled() {
+		s.deleteVestingObject(profile.ID)
+		return
+	}
+	s.storeVestingProfile(&profile)
+	return
+}
+
+// loadConfirmedVesting returns the vesting of h confirmed by produced blocks.
+func (s *metaState) loadConfirmedVesting(h hash.Hash) (profile pt.VestingProfile, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var o *vestingObject
+	if o, loaded = s.readonly.vestings[h]; loaded {
+		profile = o.VestingProfile
+	}
+	return
+}
+
+// setGovernance sets the authorities voting for chain parameter updates and the parameters
+// before any update takes effect, the threshold defaults to the majority of the authorities.
+func (s *metaState) setGovernance(
+	authorities []proto.AccountAddress, threshold int, params pt.ChainParams,
+) {
+	s.Lock()
+	defer s.Unlock()
+	if threshold <= 0 {
+		threshold = len(authorities)/2 + 1
+	}
+	s.authorities = append([]proto.AccountAddress{}, authorities...)
+	s.threshold = threshold
+	s.params = params
+}
+
+// setHeight sets the height of the last settled block, which is called after reloading.
+func (s *metaState) setHeight(height uint32) {
+	s.Lock()
+	defer s.Unlock()
+	s.height = height
+}
+
+func (s *metaState) isAuthority(addr proto.AccountAddress) bool {
+	s.RLock()
+	defer s.RUnlock()
+	for _, v := range s.authorities {
+		if v == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// loadParamsObject returns the chain parameters update of k from the dirty map or the readonly
+// map.
+func (s *metaState) loadParamsObject(k hash.Hash) (o *paramsObject, loaded bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if o, loaded = s.dirty.params[k]; loaded {
+		if o == nil {
+			loaded = false
+		}
+		return
+	}
+	o, loaded = s.readonly.params[k]
+	return
+}
+
+// storeParamsUpdate stores a copy of chain parameters update to the dirty map.
+func (s *metaState) storeParamsUpdate(update *pt.ParamsUpdate) {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty.params[update.Hash] = &paramsObject{ParamsUpdate: copyParamsUpdate(update)}
+}
+
+func copyParamsUpdate(src *pt.ParamsUpdate) (dst pt.ParamsUpdate) {
+	dst = *src
+	dst.Votes = append([]proto.AccountAddress{}, src.Votes...)
+	return
+}
+
+// updateParams counts the vote of an authority for a chain parameters update, the update is
+// scheduled by settleParams once the votes reach the threshold.
+func (s *metaState) updateParams(tx *pt.UpdateParams) (err error) {
+	if !s.isAuthority(tx.Sender) {
+		return ErrPermissionDenied
+	}
+	s.RLock()
+	height := s.height
+	s.RUnlock()
+	if tx.ActivationHeight <= height {
+		return ErrParamsActivationPassed
+	}
+	var update *pt.ParamsUpdate
+	if update, err = tx.Update(); err != nil {
+		return
+	}
+	if o, loaded := s.loadParamsObject(update.Hash); loaded {
+		if o.Scheduled() {
+			return ErrParamsUpdateScheduled
+		}
+		for _, v := range o.Votes {
+			if v == tx.Sender {
+				return ErrParamsAlreadyVoted
+			}
+		}
+		voted := copyParamsUpdate(&o.ParamsUpdate)
+		voted.Votes = append(voted.Votes, tx.Sender)
+		update = &voted
+	}
+	s.storeParamsUpdate(update)
+	return
+}
+
+// settleParams schedules the chain parameter updates reaching the threshold by the block of
+// height, and drops the updates which can not be scheduled before activation any more.
+func (s *metaState) settleParams(height uint32) {
+	s.Lock()
+	defer s.Unlock()
+	s.height = height
+	var updates = make(map[hash.Hash]*paramsObject)
+	for k, v := range s.readonly.params {
+		updates[k] = v
+	}
+	for k, v := range s.dirty.params {
+		updates[k] = v
+	}
+	for k, v := range updates {
+		switch {
+		case v == nil || v.Scheduled():
+		case len(v.Votes) >= s.threshold && v.ActivationHeight >= height:
+			update := copyParamsUpdate(&v.ParamsUpdate)
+			update.Height = height
+			s.dirty.params[k] = &paramsObject{ParamsUpdate: update}
+		case v.ActivationHeight <= height:
+			// Use a nil pointer to mark a deletion, which will be later used by commit procedure.
+			s.dirty.params[k] = nil
+		}
+	}
+}
+
+// scheduledParams returns the committed scheduled updates sorted by activation height, only the
+// latest scheduled one is returned for each activation height.
+func (s *metaState) scheduledParams() (updates []pt.ParamsUpdate) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, o := range s.readonly.params {
+		if o.Scheduled() {
+			updates = append(updates, copyParamsUpdate(&o.ParamsUpdate))
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].ActivationHeight != updates[j].ActivationHeight {
+			return updates[i].ActivationHeight < updates[j].ActivationHeight
+		}
+		if updates[i].Height != updates[j].Height {
+			return updates[i].Height < updates[j].Height
+		}
+		return bytes.Compare(updates[i].Hash[:], updates[j].Hash[:]) < 0
+	})
+	var n int
+	for i := range updates {
+		if i+1 < len(updates) && updates[i+1].ActivationHeight == updates[i].ActivationHeight {
+			continue
+		}
+		updates[n] = updates[i]
+		n++
+	}
+	updates = updates[:n]
+	return
+}
+
+// activeParams returns the chain parameters in effect at the height of the last settled block.
+func (s *metaState) activeParams() (params pt.ChainParams) {
+	s.RLock()
+	height := s.height
+	params = s.params
+	s.RUnlock()
+	for _, v := range s.scheduledParams() {
+		if v.ActivationHeight > height {
+			break
+		}
+		params = v.Params
+	}
+	return
+}
+
+// loadConfirmedParams returns the committed chain parameter updates sorted by activation height,
+// including the ones waiting for votes.
+func (s *metaState) loadConfirmedParams() (updates []pt.ParamsUpdate) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, o := range s.readonly.params {
+		updates = append(updates, copyParamsUpdate(&o.ParamsUpdate))
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].ActivationHeight != updates[j].ActivationHeight {
+			return updates[i].ActivationHeight < updates[j].ActivationHeight
+		}
+		return bytes.Compare(updates[i].Hash[:], updates[j].Hash[:]) < 0
+	})
+	return
+}
+
+func (s *metaState) applyTransaction(tx pi.Transaction) (err error) {
+	if tx == nil {
+		return ErrUnknownTransactionType
+	}
+	// Charge fee before any state change, and refund it if the transaction doesn't apply
+	if fee := tx.GetFee(); fee > 0 {
+		addr := tx.GetAccountAddress()
+		if err = s.decreaseAccountCovenantBalance(addr, fee); err != nil {
+			return
+		}
+		defer func() {
+			if err != nil {
+				s.increaseAccountCovenantBalance(addr, fee)
+			}
+		}()
+	}
+	switch t := tx.(type) {
+	case *pt.Transfer:
+		err = s.transferAccountBalance(t.Sender, t.Receiver, t.Amount, t.TokenType)
+	case *pt.TxBilling:
+		err = s.applyBilling(t)
+	case *pt.UpdatePermission:
+		err = s.updatePermission(t)
+	case *pt.RegisterMiner:
+		err = s.registerMiner(t)
+	case *pt.BillingChallenge:
+		err = s.challengeBilling(t)
+	case *pt.BillingProof:
+		err = s.proveBilling(t)
+	case *pt.CreateMultiSig:
+		err = s.createMultiSig(t)
+	case *pt.ProposeMultiSig:
+		err = s.proposeMultiSig(t)
+	case *pt.ApproveMultiSig:
+		err = s.approveMultiSig(t)
+	case *pt.UpdateParams:
+		err = s.updateParams(t)
+	case *pt.MinerEvidence:
+		err = s.applyMinerEvidence(t)
+	case *pt.ServiceChallenge:
+		err = s.challengeService(t)
+	case *pt.ServiceProof:
+		err = s.proveService(t)
+	case *pt.CreateVesting:
+		err = s.createVesting(t)
+	case *pt.ReleaseVesting:
+		err = s.releaseVesting(t)
+	case *pt.RevokeVesting:
+		err = s.revokeVesting(t)
+	default:
+		err = ErrUnknownTransactionType
+	}
+	return
+}
+
+// applyTransaction tries to apply t to the metaState and push t to the memory pool if and
+// only if it can be applied correctly.
+func (s *metaState) applyTransactionProcedure(t pi.Transaction) (_ func(*bolt.Tx) error) {
+	var (
+		err     error
+		errPass = func(*bolt.Tx) error {
+			return err
+		}
+	)
+	// Static checks, which have no relation with metaState
+	if err = t.Verify(); err != nil {
+		return errPass
+	}
+
+	var (
+		enc   []byte
+		hash  = t.GetHash()
+		addr  = t.GetAccountAddress()
+		nonce = t.GetAccountNonce()
+		ttype = t.GetTransactionType()
+	)
+	if enc, err = t.Serialize(); err != nil {
 		return errPass
 	}
 
@@ -540,10 +2079,24 @@ func (s *metaState) applyTransactionProcedure(t pi.Transaction) (_ func(*bolt.Tx
 		if nextNonce, err = s.nextNonce(addr); err != nil {
 			return
 		}
+		if nonce > nextNonce && nonce-nextNonce <= maxNonceGap {
+			// Hold the transaction until the gap is filled
+			s.Lock()
+			defer s.Unlock()
+			if !s.pool.queueTx(t) {
+				err = ErrInvalidAccountNonce
+				return
+			}
+			return putPendingTx(tx, t, enc, time.Now())
+		}
 		if nextNonce != nonce {
 			err = ErrInvalidAccountNonce
 			return
 		}
+		// Write ahead to the persisted memory pool, so that the transaction survives restarts
+		if err = putPendingTx(tx, t, enc, time.Now()); err != nil {
+			return
+		}
 		// Try to put transaction before any state change, will be rolled back later
 		// if transaction doesn't apply
 		tb := tx.Bucket(metaBucket[:]).Bucket(metaTransactionBucket).Bucket(ttype.Bytes())
@@ -555,7 +2108,356 @@ func (s *metaState) applyTransactionProcedure(t pi.Transaction) (_ func(*bolt.Tx
 			return
 		}
 		// Push to pool
+		s.Lock()
 		s.pool.addTx(t, nextNonce)
+		s.Unlock()
+		return s.promoteQueuedTransactions(tx, addr, nonce+1)
+	}
+}
+
+// promoteQueuedTransactions applies the queued transactions of account addr following nonce
+// in order and pushes them to the memory pool. A queued transaction which doesn't apply is
+// dropped, and the later ones are kept queued for the gap.
+func (s *metaState) promoteQueuedTransactions(
+	tx *bolt.Tx, addr proto.AccountAddress, nonce pi.AccountNonce) (err error,
+) {
+	tb := tx.Bucket(metaBucket[:]).Bucket(metaTransactionBucket)
+	for ; ; nonce++ {
+		s.Lock()
+		t, ok := s.pool.popQueuedTx(addr, nonce)
+		s.Unlock()
+		if !ok {
+			return
+		}
+		var (
+			enc []byte
+			h   = t.GetHash()
+			bk  = tb.Bucket(t.GetTransactionType().Bytes())
+		)
+		if enc, err = t.Serialize(); err != nil {
+			return
+		}
+		if err = bk.Put(h[:], enc); err != nil {
+			return
+		}
+		if ierr := s.applyTransaction(t); ierr != nil {
+			log.WithFields(log.Fields{
+				"account":     hash.Hash(addr).String(),
+				"transaction": h.String(),
+			}).WithError(ierr).Warning("drop queued transaction")
+			s.failed.add(h, ierr.Error())
+			mempoolDroppedTxs.Inc()
+			if err = deletePendingTxs(tx, h); err != nil {
+				return
+			}
+			return bk.Delete(h[:])
+		}
+		s.Lock()
+		s.pool.addTx(t, nonce)
+		s.Unlock()
+	}
+}
+
+// fork returns a scratch copy of the metaState. The objects are shared with the origin state
+// and copied on write, so any change applied to the fork never affects the origin state.
+func (s *metaState) fork() (f *metaState) {
+	s.RLock()
+	defer s.RUnlock()
+	f = s.forkReadonly()
+	for k, v := range s.dirty.accounts {
+		if v != nil {
+			f.readonly.accounts[k] = v
+		} else {
+			delete(f.readonly.accounts, k)
+		}
+	}
+	for k, v := range s.dirty.databases {
+		if v != nil {
+			f.readonly.databases[k] = v
+		} else {
+			delete(f.readonly.databases, k)
+		}
+	}
+	for k, v := range s.dirty.miners {
+		if v != nil {
+			f.readonly.miners[k] = v
+		} else {
+			delete(f.readonly.miners, k)
+		}
+	}
+	for k, v := range s.dirty.billings {
+		if v != nil {
+			f.readonly.billings[k] = v
+		} else {
+			delete(f.readonly.billings, k)
+		}
+	}
+	for k, v := range s.dirty.multisigs {
+		if v != nil {
+			f.readonly.multisigs[k] = v
+		} else {
+			delete(f.readonly.multisigs, k)
+		}
+	}
+	for k, v := range s.dirty.params {
+		if v != nil {
+			f.readonly.params[k] = v
+		} else {
+			delete(f.readonly.params, k)
+		}
+	}
+	for k, v := range s.dirty.vestings {
+		if v != nil {
+			f.readonly.vestings[k] = v
+		} else {
+			delete(f.readonly.vestings, k)
+		}
+	}
+	for k, v := range s.pool.entries {
+		e := newAccountTxEntries(v.account, v.baseNonce)
+		e.transacions = append(e.transacions, v.transacions...)
+		f.pool.entries[k] = e
+	}
+	f.pool.txs = append(f.pool.txs, s.pool.txs...)
+	return
+}
+
+// forkConfirmed returns a scratch copy of the state committed by produced blocks, the changes
+// of the pending transactions in memory pool are excluded.
+func (s *metaState) forkConfirmed() (f *metaState) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.forkReadonly()
+}
+
+// forkReadonly copies the committed objects to a new metaState, the caller should hold the lock.
+func (s *metaState) forkReadonly() (f *metaState) {
+	f = newMetaState()
+	f.authorities, f.threshold, f.params, f.height = s.authorities, s.threshold, s.params, s.height
+	for k, v := range s.readonly.accounts {
+		f.readonly.accounts[k] = v
+	}
+	for k, v := range s.readonly.databases {
+		f.readonly.databases[k] = v
+	}
+	for k, v := range s.readonly.miners {
+		f.readonly.miners[k] = v
+	}
+	for k, v := range s.readonly.billings {
+		f.readonly.billings[k] = v
+	}
+	for k, v := range s.readonly.multisigs {
+		f.readonly.multisigs[k] = v
+	}
+	for k, v := range s.readonly.params {
+		f.readonly.params[k] = v
+	}
+	for k, v := range s.readonly.vestings {
+		f.readonly.vestings[k] = v
+	}
+	return
+}
+
+// applyNextTransaction applies t if it follows the next nonce of its account and pushes it to
+// the memory pool.
+func (s *metaState) applyNextTransaction(t pi.Transaction) (err error) {
+	var nextNonce pi.AccountNonce
+	if nextNonce, err = s.nextNonce(t.GetAccountAddress()); err != nil {
+		return
+	}
+	if t.GetAccountNonce() != nextNonce {
+		return ErrInvalidAccountNonce
+	}
+	if err = s.applyTransaction(t); err != nil {
+		return
+	}
+	s.Lock()
+	s.pool.addTx(t, nextNonce)
+	s.Unlock()
+	return
+}
+
+// selectTransactions returns the pending transactions to be packed by the next block in the
+// order they are applied. The transactions selected by fee rate are applied to a fork of the
+// committed state, and the ones which no longer apply without the others are left out with the
+// later transactions of their accounts.
+func (s *metaState) selectTransactions(limit, accountLimit int) (selected []pi.Transaction) {
+	s.RLock()
+	var (
+		packed, _ = s.pool.packTxs(limit, accountLimit)
+		pending   = append([]pi.Transaction(nil), s.pool.txs...)
+		f         = s.forkReadonly()
+		set       = make(map[hash.Hash]bool)
+		broken    = make(map[proto.AccountAddress]bool)
+	)
+	s.RUnlock()
+	for _, t := range packed {
+		set[t.GetHash()] = true
+	}
+	for _, t := range pending {
+		addr := t.GetAccountAddress()
+		if !set[t.GetHash()] || broken[addr] {
+			continue
+		}
+		if err := f.applyNextTransaction(t); err != nil {
+			broken[addr] = true
+			continue
+		}
+		selected = append(selected, t)
+	}
+	return
+}
+
+// applyBlockTransactions applies the transactions of a block in the block order to a fork of
+// the committed state, any transaction failing to apply or breaking the account nonces rejects
+// the whole block and leaves the metaState untouched. Otherwise the pending state is replaced by
+// the fork, and the pending transactions not packed by the block are returned as deferred
+// transactions, which should be re-applied by reapplyTransactionsProcedure after commit.
+func (s *metaState) applyBlockTransactions(txs []pi.Transaction) (deferred []pi.Transaction, err error) {
+	var (
+		f      = s.forkConfirmed()
+		packed = make(map[hash.Hash]bool, len(txs))
+	)
+	for _, t := range txs {
+		if err = f.applyNextTransaction(t); err != nil {
+			return
+		}
+		packed[t.GetHash()] = true
+	}
+	s.Lock()
+	defer s.Unlock()
+	for _, t := range s.pool.txs {
+		if !packed[t.GetHash()] {
+			deferred = append(deferred, t)
+		}
+	}
+	f.pool.queued = s.pool.queued
+	s.dirty, s.pool = f.dirty, f.pool
+	// the packed transactions queued locally for the nonce gap are no longer pending
+	for _, t := range txs {
+		addr, nonce := t.GetAccountAddress(), t.GetAccountNonce()
+		if q, ok := s.pool.queued[addr][nonce]; ok && q.GetHash() == t.GetHash() {
+			s.pool.popQueuedTx(addr, nonce)
+		}
+	}
+	return
+}
+
+// reapplyTransactionsProcedure re-applies the deferred transactions returned by
+// applyBlockTransactions to the metaState and push them to the memory pool, transactions which
+// no longer apply are dropped and recorded as failed.
+func (s *metaState) reapplyTransactionsProcedure(txs []pi.Transaction) (_ func(*bolt.Tx) error) {
+	return func(tx *bolt.Tx) (err error) {
+		var (
+			tb      = tx.Bucket(metaBucket[:]).Bucket(metaTransactionBucket)
+			dropped = make(map[proto.AccountAddress]bool)
+		)
+		for _, t := range txs {
+			var (
+				addr = t.GetAccountAddress()
+				ierr = ErrInvalidAccountNonce
+			)
+			if !dropped[addr] {
+				if ierr = s.applyNextTransaction(t); ierr == nil {
+					continue
+				}
+			}
+			// Later transactions of the account are dropped for the nonce gap
+			log.WithFields(log.Fields{
+				"account":     hash.Hash(addr).String(),
+				"transaction": t.GetHash().String(),
+			}).WithError(ierr).Warning("drop deferred transaction")
+			dropped[addr] = true
+			mempoolDroppedTxs.Inc()
+			h := t.GetHash()
+			s.failed.add(h, ierr.Error())
+			if err = tb.Bucket(t.GetTransactionType().Bytes()).Delete(h[:]); err != nil {
+				return
+			}
+			if err = deletePendingTxs(tx, h); err != nil {
+				return
+			}
+		}
+		return
+	}
+}
+
+// collectFees credits the fees of pending transactions to the account of block producer, the
+// account is created if not exists.
+func (s *metaState) collectFees(producer proto.AccountAddress) (err error) {
+	var fees uint64
+	s.RLock()
+	for _, t := range s.pool.txs {
+		fee := t.GetFee()
+		if err = safeAdd(&fees, &fee); err != nil {
+			s.RUnlock()
+			return
+		}
+	}
+	s.RUnlock()
+	if fees == 0 {
+		return
+	}
+	s.loadOrStoreAccountObject(producer, &accountObject{
+		Account: pt.Account{
+			Address: producer,
+		},
+	})
+	return s.increaseAccountCovenantBalance(producer, fees)
+}
+
+// estimateFee estimates the fee rates by packing pending transactions as the next block.
+func (s *metaState) estimateFee(limit, accountLimit int) (estimate FeeEstimate) {
+	s.RLock()
+	defer s.RUnlock()
+	_, rates := s.pool.packTxs(limit, accountLimit)
+	estimate.Pending = s.pool.size()
+	estimate.Capacity = limit
+	if len(rates) == 0 {
+		return
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i] < rates[j] })
+	if len(rates) >= limit {
+		estimate.MinFeeRate = rates[0] + 1
+	}
+	estimate.MedianFeeRate = rates[len(rates)/2]
+	return
+}
+
+// simulateTransaction dry-runs t against a fork of the metaState and returns the would-be
+// states of the accounts touched by t, the origin metaState is left untouched.
+func (s *metaState) simulateTransaction(t pi.Transaction) (accounts []pt.Account, err error) {
+	if t == nil {
+		err = ErrUnknownTransactionType
+		return
+	}
+	if err = t.Verify(); err != nil {
+		return
+	}
+
+	var (
+		f         = s.fork()
+		nextNonce pi.AccountNonce
+	)
+	if nextNonce, err = f.nextNonce(t.GetAccountAddress()); err != nil {
+		return
+	}
+	if nextNonce != t.GetAccountNonce() {
+		err = ErrInvalidAccountNonce
+		return
+	}
+	if err = f.applyTransaction(t); err != nil {
 		return
 	}
+
+	accounts = make([]pt.Account, 0, len(f.dirty.accounts))
+	for _, v := range f.dirty.accounts {
+		if v != nil {
+			accounts = append(accounts, v.Account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].Address[:], accounts[j].Address[:]) < 0
+	})
+	return
 }
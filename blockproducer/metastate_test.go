@@ -21,10 +21,15 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 	"github.com/coreos/bbolt"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -64,6 +69,21 @@ func TestMetaState(t *testing.T) {
 			if _, err = meta.CreateBucket(metaSQLChainIndexBucket); err != nil {
 				return
 			}
+			if _, err = meta.CreateBucket(metaMinerIndexBucket); err != nil {
+				return
+			}
+			if _, err = meta.CreateBucket(metaBillingIndexBucket); err != nil {
+				return
+			}
+			if _, err = meta.CreateBucket(metaMultiSigIndexBucket); err != nil {
+				return
+			}
+			if _, err = meta.CreateBucket(metaParamsIndexBucket); err != nil {
+				return
+			}
+			if _, err = meta.CreateBucket(metaVestingIndexBucket); err != nil {
+				return
+			}
 			if txbk, err = meta.CreateBucket(metaTransactionBucket); err != nil {
 				return
 			}
@@ -413,6 +433,43 @@ func TestMetaState(t *testing.T) {
 					So(&oc1.SQLChainProfile, ShouldResemble, &rc1.SQLChainProfile)
 					So(&oc2.SQLChainProfile, ShouldResemble, &rc2.SQLChainProfile)
 				})
+				Convey("The metaState should be restorable from its snapshot", func() {
+					var (
+						snap         = ms.snapshot()
+						root1, root2 hash.Hash
+						sms          = newMetaState()
+						rms          = newMetaState()
+					)
+					So(len(snap.Accounts), ShouldEqual, 2)
+					So(len(snap.Databases), ShouldEqual, 2)
+					So(snap.Accounts[0].Address, ShouldEqual, addr1)
+					So(snap.Databases[0].ID, ShouldEqual, dbid1)
+					root1, err = snap.StateRoot()
+					So(err, ShouldBeNil)
+					// Stale objects should be dropped by restore
+					_, loaded = sms.loadOrStoreAccountObject(addr3, &accountObject{
+						Account: pt.Account{
+							Address: addr3,
+						},
+					})
+					So(loaded, ShouldBeFalse)
+					err = db.Update(sms.commitProcedure())
+					So(err, ShouldBeNil)
+					err = db.Update(sms.restoreProcedure(snap))
+					So(err, ShouldBeNil)
+					_, loaded = sms.loadAccountObject(addr3)
+					So(loaded, ShouldBeFalse)
+					root2, err = sms.snapshot().StateRoot()
+					So(err, ShouldBeNil)
+					So(root2, ShouldEqual, root1)
+					err = db.View(rms.reloadProcedure())
+					So(err, ShouldBeNil)
+					_, loaded = rms.loadAccountObject(addr3)
+					So(loaded, ShouldBeFalse)
+					root2, err = rms.snapshot().StateRoot()
+					So(err, ShouldBeNil)
+					So(root2, ShouldEqual, root1)
+				})
 				Convey("When the some accountObject is corrupted", func() {
 					err = db.Update(func(tx *bolt.Tx) (err error) {
 						return tx.Bucket(metaBucket[:]).Bucket(metaAccountIndexBucket).Put(
@@ -564,10 +621,1031 @@ func TestMetaState(t *testing.T) {
 					So(err, ShouldBeNil)
 					So(n, ShouldEqual, 1)
 				})
+				Convey("The metaState should report transaction state and account balance", func() {
+					var stable, covenant uint64
+					So(ms.isTxPending(tx.GetHash()), ShouldBeTrue)
+					So(ms.isTxPending(hash.Hash{}), ShouldBeFalse)
+					err = ms.increaseAccountStableBalance(addr1, 100)
+					So(err, ShouldBeNil)
+					err = ms.increaseAccountCovenantBalance(addr1, 10)
+					So(err, ShouldBeNil)
+					stable, covenant, loaded = ms.loadAccountBalance(addr1)
+					So(loaded, ShouldBeTrue)
+					So(stable, ShouldEqual, 100)
+					So(covenant, ShouldEqual, 10)
+					_, _, loaded = ms.loadAccountBalance(addr3)
+					So(loaded, ShouldBeFalse)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					So(ms.isTxPending(tx.GetHash()), ShouldBeFalse)
+					stable, _, loaded = ms.loadAccountBalance(addr1)
+					So(loaded, ShouldBeTrue)
+					So(stable, ShouldEqual, 100)
+				})
 				Convey("The metaState should report error on unknown transaction type", func() {
 					err = ms.applyTransaction(nil)
 					So(err, ShouldEqual, ErrUnknownTransactionType)
 				})
+				Convey("The metaState should simulate transactions without changing state", func() {
+					var accounts []pt.Account
+					_, err = ms.simulateTransaction(tx)
+					So(err, ShouldEqual, ErrInvalidAccountNonce)
+					_, err = ms.simulateTransaction(nil)
+					So(err, ShouldEqual, ErrUnknownTransactionType)
+					tx.Nonce = 1
+					tx.Amount = 10
+					err = tx.Sign(testPrivKey)
+					So(err, ShouldBeNil)
+					_, err = ms.simulateTransaction(tx)
+					So(err, ShouldEqual, ErrInsufficientBalance)
+					err = ms.increaseAccountStableBalance(addr1, 100)
+					So(err, ShouldBeNil)
+					accounts, err = ms.simulateTransaction(tx)
+					So(err, ShouldBeNil)
+					So(len(accounts), ShouldEqual, 2)
+					So(accounts[0].Address, ShouldEqual, addr1)
+					So(accounts[0].StableCoinBalance, ShouldEqual, 90)
+					So(accounts[1].Address, ShouldEqual, addr2)
+					So(accounts[1].StableCoinBalance, ShouldEqual, 10)
+					ao, loaded = ms.loadAccountObject(addr1)
+					So(loaded, ShouldBeTrue)
+					So(ao.StableCoinBalance, ShouldEqual, 100)
+					n, err = ms.nextNonce(addr1)
+					So(err, ShouldBeNil)
+					So(n, ShouldEqual, 1)
+				})
+			})
+			Convey("When database permissions are updated by transactions", func() {
+				var (
+					enc     []byte
+					admin   proto.AccountAddress
+					profile pt.SQLChainProfile
+					newTx   = func(
+						id proto.DatabaseID, user proto.AccountAddress, perm pt.UserPermission, revoke bool,
+					) (tx *pt.UpdatePermission) {
+						tx = &pt.UpdatePermission{
+							UpdatePermissionHeader: pt.UpdatePermissionHeader{
+								Sender:     admin,
+								DatabaseID: id,
+								User:       user,
+								Permission: perm,
+								Revoke:     revoke,
+							},
+						}
+						tx.Nonce, err = ms.nextNonce(admin)
+						So(err, ShouldBeNil)
+						err = tx.Sign(testPrivKey)
+						So(err, ShouldBeNil)
+						return
+					}
+				)
+				enc, err = testPubKey.MarshalHash()
+				So(err, ShouldBeNil)
+				admin = proto.AccountAddress(hash.THashH(enc))
+				ao, loaded = ms.loadOrStoreAccountObject(admin, &accountObject{
+					Account: pt.Account{
+						Address: admin,
+					},
+				})
+				So(loaded, ShouldBeFalse)
+				err = ms.createSQLChain(admin, dbid3)
+				So(err, ShouldBeNil)
+				err = db.Update(ms.applyTransactionProcedure(newTx(dbid3, addr2, pt.Read, false)))
+				So(err, ShouldBeNil)
+				_, loaded = ms.loadConfirmedSQLChainProfile(dbid3)
+				So(loaded, ShouldBeFalse)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				profile, loaded = ms.loadConfirmedSQLChainProfile(dbid3)
+				So(loaded, ShouldBeTrue)
+				So(len(profile.Users), ShouldEqual, 2)
+				So(profile.Users[1].Address, ShouldEqual, addr2)
+				So(profile.Users[1].Permission, ShouldEqual, pt.Read)
+				Convey("The metaState should alter permission of existing user", func() {
+					err = db.Update(ms.applyTransactionProcedure(newTx(dbid3, addr2, pt.ReadWrite, false)))
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					profile, loaded = ms.loadConfirmedSQLChainProfile(dbid3)
+					So(loaded, ShouldBeTrue)
+					So(len(profile.Users), ShouldEqual, 2)
+					So(profile.Users[1].Permission, ShouldEqual, pt.ReadWrite)
+				})
+				Convey("The metaState should revoke permission of existing user", func() {
+					err = db.Update(ms.applyTransactionProcedure(newTx(dbid3, addr2, pt.Admin, true)))
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					profile, loaded = ms.loadConfirmedSQLChainProfile(dbid3)
+					So(loaded, ShouldBeTrue)
+					So(len(profile.Users), ShouldEqual, 1)
+					So(profile.Users[0].Address, ShouldEqual, admin)
+					err = db.Update(ms.applyTransactionProcedure(newTx(dbid3, addr2, pt.Admin, true)))
+					So(err, ShouldEqual, ErrDatabaseUserNotFound)
+				})
+				Convey("The metaState should report error on invalid permission updates", func() {
+					err = db.Update(ms.applyTransactionProcedure(newTx(dbid1, addr2, pt.Read, false)))
+					So(err, ShouldEqual, ErrPermissionDenied)
+					err = db.Update(ms.applyTransactionProcedure(
+						newTx(proto.DatabaseID("db#4"), addr2, pt.Read, false)))
+					So(err, ShouldEqual, ErrDatabaseNotFound)
+					err = db.Update(ms.applyTransactionProcedure(
+						newTx(dbid3, addr2, pt.NumberOfUserPermission, false)))
+					So(err, ShouldEqual, pt.ErrInvalidPermission)
+				})
+			})
+			Convey("When miners are registered by transactions", func() {
+				var (
+					enc      []byte
+					sender   proto.AccountAddress
+					nodePriv *asymmetric.PrivateKey
+					nodePub  *asymmetric.PublicKey
+					nonce    = cpuminer.Uint256{D: 1}
+					nodeID   proto.NodeID
+					miners   []pt.MinerProfile
+					miner    pt.MinerProfile
+					covenant uint64
+					newTx    = func(space, stake uint64, deregister bool) (tx *pt.RegisterMiner) {
+						tx = &pt.RegisterMiner{
+							RegisterMinerHeader: pt.RegisterMinerHeader{
+								Sender:     sender,
+								NodeID:     nodeID,
+								NodeNonce:  nonce,
+								Space:      space,
+								GasPrice:   1,
+								Region:     "us-west",
+								Stake:      stake,
+								Deregister: deregister,
+							},
+						}
+						tx.Nonce, err = ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						err = tx.SignNode(nodePriv)
+						So(err, ShouldBeNil)
+						err = tx.Sign(testPrivKey)
+						So(err, ShouldBeNil)
+						return
+					}
+				)
+				// the miner node is registered by an account other than the node key
+				nodePriv, nodePub, err = asymmetric.GenSecp256k1KeyPair()
+				So(err, ShouldBeNil)
+				nodeID = proto.NodeID(cpuminer.HashBlock(nodePub.Serialize(), nonce).String())
+				enc, err = testPubKey.MarshalHash()
+				So(err, ShouldBeNil)
+				sender = proto.AccountAddress(hash.THashH(enc))
+				ao, loaded = ms.loadOrStoreAccountObject(sender, &accountObject{
+					Account: pt.Account{
+						Address:             sender,
+						CovenantCoinBalance: 1000,
+					},
+				})
+				So(loaded, ShouldBeFalse)
+				err = db.Update(ms.applyTransactionProcedure(newTx(0, 0, true)))
+				So(err, ShouldEqual, ErrMinerNotFound)
+				// registration must be signed by the node key over the sender
+				tx := newTx(100, minMinerStake, false)
+				tx.NodeSignature = nil
+				err = tx.Sign(testPrivKey)
+				So(err, ShouldBeNil)
+				err = db.Update(ms.applyTransactionProcedure(tx))
+				So(err, ShouldEqual, pt.ErrInvalidMinerNode)
+				tx = newTx(100, minMinerStake, false)
+				err = tx.SignNode(testPrivKey)
+				So(err, ShouldBeNil)
+				err = tx.Sign(testPrivKey)
+				So(err, ShouldBeNil)
+				err = db.Update(ms.applyTransactionProcedure(tx))
+				So(err, ShouldEqual, pt.ErrInvalidMinerNode)
+				err = db.Update(ms.applyTransactionProcedure(newTx(100, minMinerStake-1, false)))
+				So(err, ShouldEqual, ErrInsufficientStake)
+				err = db.Update(ms.applyTransactionProcedure(newTx(100, 10000, false)))
+				So(err, ShouldEqual, ErrInsufficientBalance)
+				err = db.Update(ms.applyTransactionProcedure(newTx(100, minMinerStake, false)))
+				So(err, ShouldBeNil)
+				So(ms.loadConfirmedMiners(), ShouldBeEmpty)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				miners = ms.loadConfirmedMiners()
+				So(len(miners), ShouldEqual, 1)
+				So(miners[0].Address, ShouldEqual, sender)
+				So(miners[0].NodeID, ShouldEqual, nodeID)
+				So(miners[0].Space, ShouldEqual, 100)
+				So(miners[0].Region, ShouldEqual, "us-west")
+				So(miners[0].Stake, ShouldEqual, minMinerStake)
+				_, covenant, _ = ms.loadAccountBalance(sender)
+				So(covenant, ShouldEqual, 1000-minMinerStake)
+				Convey("The metaState should update profile of registered miner", func() {
+					err = db.Update(ms.applyTransactionProcedure(newTx(200, 2*minMinerStake, false)))
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					miner, loaded = ms.loadConfirmedMiner(nodeID)
+					So(loaded, ShouldBeTrue)
+					So(miner.Space, ShouldEqual, 200)
+					So(miner.Stake, ShouldEqual, 2*minMinerStake)
+					_, covenant, _ = ms.loadAccountBalance(sender)
+					So(covenant, ShouldEqual, 1000-2*minMinerStake)
+				})
+				Convey("The metaState should deregister miner", func() {
+					err = db.Update(ms.applyTransactionProcedure(newTx(0, 0, true)))
+					So(err, ShouldBeNil)
+					So(ms.pendingDeregisteredMiners(), ShouldResemble, []proto.NodeID{nodeID})
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					So(ms.pendingDeregisteredMiners(), ShouldBeEmpty)
+					_, loaded = ms.loadConfirmedMiner(nodeID)
+					So(loaded, ShouldBeFalse)
+					_, covenant, _ = ms.loadAccountBalance(sender)
+					So(covenant, ShouldEqual, 1000)
+				})
+				Convey("The metaState should be reproducible from the persistence db", func() {
+					var recovered = newMetaState()
+					err = db.View(recovered.reloadProcedure())
+					So(err, ShouldBeNil)
+					So(recovered.loadConfirmedMiners(), ShouldResemble, miners)
+				})
+				Convey("The metaState should reject miner registered by other account", func() {
+					ms.Lock()
+					ms.readonly.miners[nodeID].Address = addr1
+					ms.Unlock()
+					err = db.Update(ms.applyTransactionProcedure(newTx(0, 0, true)))
+					So(err, ShouldEqual, ErrPermissionDenied)
+				})
+				Convey("When misbehavior of the miner is reported", func() {
+					var (
+						reporter     proto.AccountAddress
+						reporterPriv *asymmetric.PrivateKey
+						reporterPub  *asymmetric.PublicKey
+						base         *wt.SignedAckHeader
+						newAck       = func(offset uint64) (ack *wt.SignedAckHeader) {
+							ack = &wt.SignedAckHeader{
+								AckHeader: wt.AckHeader{
+									Response: wt.SignedResponseHeader{
+										ResponseHeader: base.Response.ResponseHeader,
+										Signee:         testPubKey,
+									},
+									Timestamp: base.Timestamp,
+								},
+								Signee: reporterPub,
+							}
+							ack.Response.NodeID = nodeID
+							ack.Response.LogOffset = offset
+							err = ack.Response.Sign(testPrivKey)
+							So(err, ShouldBeNil)
+							err = ack.Sign(reporterPriv)
+							So(err, ShouldBeNil)
+							return
+						}
+						newEvidenceTx = func(acks ...*wt.SignedAckHeader) (tx *pt.MinerEvidence) {
+							tx = &pt.MinerEvidence{
+								MinerEvidenceHeader: pt.MinerEvidenceHeader{
+									Sender: reporter,
+									NodeID: nodeID,
+									Acks:   acks,
+								},
+							}
+							tx.Nonce, err = ms.nextNonce(reporter)
+							So(err, ShouldBeNil)
+							err = tx.Sign(reporterPriv)
+							So(err, ShouldBeNil)
+							return
+						}
+					)
+					reporterPriv, reporterPub, err = asymmetric.GenSecp256k1KeyPair()
+					So(err, ShouldBeNil)
+					enc, err = reporterPub.MarshalHash()
+					So(err, ShouldBeNil)
+					reporter = proto.AccountAddress(hash.THashH(enc))
+					ao, loaded = ms.loadOrStoreAccountObject(reporter, &accountObject{
+						Account: pt.Account{
+							Address: reporter,
+						},
+					})
+					So(loaded, ShouldBeFalse)
+					base, err = generateSignedAck(dbid1, wt.WriteQuery, 1, reporterPriv)
+					So(err, ShouldBeNil)
+					Convey("The metaState should slash the miner signing conflicting acks", func() {
+						err = db.Update(ms.applyTransactionProcedure(newEvidenceTx(newAck(1), newAck(1))))
+						So(err, ShouldEqual, pt.ErrInvalidMinerEvidence)
+						err = db.Update(ms.applyTransactionProcedure(newEvidenceTx(newAck(1), newAck(2))))
+						So(err, ShouldBeNil)
+						So(ms.pendingDeregisteredMiners(), ShouldResemble, []proto.NodeID{nodeID})
+						err = db.Update(ms.commitProcedure())
+						So(err, ShouldBeNil)
+						_, loaded = ms.loadConfirmedMiner(nodeID)
+						So(loaded, ShouldBeFalse)
+						_, covenant, _ = ms.loadAccountBalance(reporter)
+						So(covenant, ShouldEqual, minMinerStake*minerSlashPercent/100)
+						_, covenant, _ = ms.loadAccountBalance(sender)
+						So(covenant, ShouldEqual, 1000-minMinerStake*minerSlashPercent/100)
+					})
+					Convey("The metaState should slash the miner not answering challenge in time", func() {
+						var challenge = &pt.ServiceChallenge{
+							ServiceChallengeHeader: pt.ServiceChallengeHeader{
+								Sender:  reporter,
+								NodeID:  nodeID,
+								Request: base.Response.Request,
+							},
+						}
+						challenge.Nonce, err = ms.nextNonce(reporter)
+						So(err, ShouldBeNil)
+						err = challenge.Sign(reporterPriv)
+						So(err, ShouldBeNil)
+						err = db.Update(ms.applyTransactionProcedure(challenge))
+						So(err, ShouldBeNil)
+						err = ms.settleMinerChallenges(10)
+						So(err, ShouldBeNil)
+						err = db.Update(ms.commitProcedure())
+						So(err, ShouldBeNil)
+						miner, loaded = ms.loadConfirmedMiner(nodeID)
+						So(loaded, ShouldBeTrue)
+						So(miner.Challenges, ShouldResemble, []pt.MinerChallenge{{
+							Request:    base.Response.Request.HeaderHash,
+							Challenger: reporter,
+							Deadline:   10 + serviceChallengeWindow,
+						}})
+						err = db.Update(ms.applyTransactionProcedure(newTx(0, 0, true)))
+						So(err, ShouldEqual, ErrMinerChallenged)
+						err = ms.settleMinerChallenges(10 + serviceChallengeWindow)
+						So(err, ShouldBeNil)
+						So(ms.pendingDeregisteredMiners(), ShouldBeEmpty)
+						Convey("The challenge should be removed by the proof", func() {
+							var proof = &pt.ServiceProof{
+								ServiceProofHeader: pt.ServiceProofHeader{
+									Sender: sender,
+									NodeID: nodeID,
+									Response: wt.SignedResponseHeader{
+										ResponseHeader: wt.ResponseHeader{
+											Request: base.Response.Request,
+											NodeID:  nodeID,
+										},
+										Signee: testPubKey,
+									},
+								},
+							}
+							err = proof.Response.Sign(testPrivKey)
+							So(err, ShouldBeNil)
+							proof.Nonce, err = ms.nextNonce(sender)
+							So(err, ShouldBeNil)
+							err = proof.Sign(testPrivKey)
+							So(err, ShouldBeNil)
+							err = db.Update(ms.applyTransactionProcedure(proof))
+							So(err, ShouldBeNil)
+							err = db.Update(ms.applyTransactionProcedure(proof))
+							So(err, ShouldNotBeNil)
+							err = ms.settleMinerChallenges(11 + serviceChallengeWindow)
+							So(err, ShouldBeNil)
+							So(ms.pendingDeregisteredMiners(), ShouldBeEmpty)
+						})
+						Convey("The miner should be slashed after the deadline", func() {
+							err = ms.settleMinerChallenges(11 + serviceChallengeWindow)
+							So(err, ShouldBeNil)
+							So(ms.pendingDeregisteredMiners(), ShouldResemble, []proto.NodeID{nodeID})
+							_, covenant, _ = ms.loadAccountBalance(reporter)
+							So(covenant, ShouldEqual, minMinerStake*minerSlashPercent/100)
+						})
+					})
+				})
+			})
+			Convey("When transactions with fees are applied", func() {
+				var (
+					enc              []byte
+					sender           proto.AccountAddress
+					producer         = proto.AccountAddress{0x0, 0x0, 0x0, 0x4}
+					stable, covenant uint64
+					nonce            pi.AccountNonce
+					deferred         []pi.Transaction
+					newTx            = func(nonce pi.AccountNonce, amount, fee uint64) (tx *pt.Transfer) {
+						tx = &pt.Transfer{
+							TransferHeader: pt.TransferHeader{
+								Sender:   sender,
+								Receiver: addr2,
+								Nonce:    nonce,
+								Amount:   amount,
+								Fee:      fee,
+							},
+						}
+						err = tx.Sign(testPrivKey)
+						So(err, ShouldBeNil)
+						return
+					}
+				)
+				enc, err = testPubKey.MarshalHash()
+				So(err, ShouldBeNil)
+				sender = proto.AccountAddress(hash.THashH(enc))
+				_, loaded = ms.loadOrStoreAccountObject(sender, &accountObject{
+					Account: pt.Account{
+						Address:             sender,
+						StableCoinBalance:   100,
+						CovenantCoinBalance: 10,
+					},
+				})
+				So(loaded, ShouldBeFalse)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				tx1, tx2 := newTx(0, 10, 3), newTx(1, 20, 0)
+				err = db.Update(ms.applyTransactionProcedure(tx1))
+				So(err, ShouldBeNil)
+				_, covenant, _ = ms.loadAccountBalance(sender)
+				So(covenant, ShouldEqual, 7)
+				Convey("The metaState should refund fee of transaction failed to apply", func() {
+					err = db.Update(ms.applyTransactionProcedure(newTx(1, 10, 100)))
+					So(err, ShouldEqual, ErrInsufficientBalance)
+					err = db.Update(ms.applyTransactionProcedure(newTx(1, 1000, 1)))
+					So(err, ShouldEqual, ErrInsufficientBalance)
+					stable, covenant, _ = ms.loadAccountBalance(sender)
+					So(stable, ShouldEqual, 90)
+					So(covenant, ShouldEqual, 7)
+				})
+				Convey("The metaState should defer transactions out of block capacity", func() {
+					err = db.Update(ms.applyTransactionProcedure(tx2))
+					So(err, ShouldBeNil)
+					So(ms.estimateFee(1, 1).MinFeeRate, ShouldEqual, txFeeRate(tx1)+1)
+					So(ms.estimateFee(2, 2).MinFeeRate, ShouldEqual, 1)
+					So(ms.estimateFee(3, 3).MinFeeRate, ShouldEqual, 0)
+					So(ms.selectTransactions(1, 1), ShouldResemble, []pi.Transaction{tx1})
+					deferred, err = ms.applyBlockTransactions([]pi.Transaction{tx1})
+					So(err, ShouldBeNil)
+					So(deferred, ShouldResemble, []pi.Transaction{tx2})
+					err = ms.collectFees(producer)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					err = db.Update(ms.reapplyTransactionsProcedure(deferred))
+					So(err, ShouldBeNil)
+					So(ms.isTxPending(tx1.GetHash()), ShouldBeFalse)
+					So(ms.isTxPending(tx2.GetHash()), ShouldBeTrue)
+					nonce, err = ms.nextNonce(sender)
+					So(err, ShouldBeNil)
+					So(nonce, ShouldEqual, 2)
+					stable, covenant, _ = ms.loadAccountBalance(sender)
+					So(stable, ShouldEqual, 70)
+					So(covenant, ShouldEqual, 7)
+					_, covenant, loaded = ms.loadAccountBalance(producer)
+					So(loaded, ShouldBeTrue)
+					So(covenant, ShouldEqual, 3)
+				})
+				Convey("The metaState should apply block transactions in place of the memory pool", func() {
+					err = db.Update(ms.applyTransactionProcedure(tx2))
+					So(err, ShouldBeNil)
+					// nonce gap and failed transaction reject the whole block
+					_, err = ms.applyBlockTransactions([]pi.Transaction{tx2})
+					So(err, ShouldEqual, ErrInvalidAccountNonce)
+					_, err = ms.applyBlockTransactions([]pi.Transaction{tx1, newTx(1, 1000, 0)})
+					So(err, ShouldEqual, ErrInsufficientBalance)
+					So(ms.isTxPending(tx1.GetHash()), ShouldBeTrue)
+					So(ms.isTxPending(tx2.GetHash()), ShouldBeTrue)
+					stable, covenant, _ = ms.loadAccountBalance(sender)
+					So(stable, ShouldEqual, 70)
+					So(covenant, ShouldEqual, 7)
+					// the block packs another transaction of the same nonce
+					other := newTx(0, 30, 1)
+					deferred, err = ms.applyBlockTransactions([]pi.Transaction{other})
+					So(err, ShouldBeNil)
+					So(deferred, ShouldResemble, []pi.Transaction{tx1, tx2})
+					err = ms.collectFees(producer)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					err = db.Update(ms.reapplyTransactionsProcedure(deferred))
+					So(err, ShouldBeNil)
+					So(ms.isTxPending(tx1.GetHash()), ShouldBeFalse)
+					So(ms.isTxPending(tx2.GetHash()), ShouldBeFalse)
+					for _, tx := range deferred {
+						reason, ok := ms.failed.get(tx.GetHash())
+						So(ok, ShouldBeTrue)
+						So(reason, ShouldEqual, ErrInvalidAccountNonce.Error())
+					}
+					nonce, err = ms.nextNonce(sender)
+					So(err, ShouldBeNil)
+					So(nonce, ShouldEqual, 1)
+					stable, covenant, _ = ms.loadAccountBalance(sender)
+					So(stable, ShouldEqual, 70)
+					So(covenant, ShouldEqual, 9)
+					_, covenant, _ = ms.loadAccountBalance(producer)
+					So(covenant, ShouldEqual, 1)
+				})
+				Convey("The metaState should queue transactions ahead of the next nonce", func() {
+					tx3, tx4 := newTx(2, 5, 0), newTx(3, 5, 0)
+					err = db.Update(ms.applyTransactionProcedure(tx4))
+					So(err, ShouldBeNil)
+					err = db.Update(ms.applyTransactionProcedure(tx4))
+					So(err, ShouldEqual, ErrInvalidAccountNonce)
+					err = db.Update(ms.applyTransactionProcedure(newTx(2+maxNonceGap, 5, 0)))
+					So(err, ShouldEqual, ErrInvalidAccountNonce)
+					err = db.Update(ms.applyTransactionProcedure(tx3))
+					So(err, ShouldBeNil)
+					So(ms.isTxPending(tx3.GetHash()), ShouldBeTrue)
+					So(ms.isTxPending(tx4.GetHash()), ShouldBeTrue)
+					So(ms.queuedNonces(sender), ShouldResemble, []pi.AccountNonce{2, 3})
+					nonce, err = ms.nextNonce(sender)
+					So(err, ShouldBeNil)
+					So(nonce, ShouldEqual, 1)
+					stable, _, _ = ms.loadAccountBalance(sender)
+					So(stable, ShouldEqual, 90)
+					Convey("The queued transactions should be applied once the gap is filled", func() {
+						err = db.Update(ms.applyTransactionProcedure(tx2))
+						So(err, ShouldBeNil)
+						So(ms.queuedNonces(sender), ShouldBeEmpty)
+						nonce, err = ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						So(nonce, ShouldEqual, 4)
+						err = db.Update(ms.commitProcedure())
+						So(err, ShouldBeNil)
+						nonce, err = ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						So(nonce, ShouldEqual, 4)
+						stable, _, _ = ms.loadAccountBalance(sender)
+						So(stable, ShouldEqual, 60)
+					})
+				})
+			})
+			Convey("When a multi-signature account is created by transaction", func() {
+				var (
+					enc           []byte
+					sender, owner proto.AccountAddress
+					ownerPriv     *asymmetric.PrivateKey
+					ownerPub      *asymmetric.PublicKey
+					create        *pt.CreateMultiSig
+					propose       *pt.ProposeMultiSig
+					account       proto.AccountAddress
+					profile       pt.MultiSigProfile
+					stable        uint64
+					newApproveTx  = func(priv *asymmetric.PrivateKey, addr proto.AccountAddress) (tx *pt.ApproveMultiSig) {
+						tx = &pt.ApproveMultiSig{
+							ApproveMultiSigHeader: pt.ApproveMultiSigHeader{
+								Sender:   addr,
+								Account:  account,
+								Proposal: propose.HeaderHash,
+							},
+						}
+						tx.Nonce, err = ms.nextNonce(addr)
+						So(err, ShouldBeNil)
+						err = tx.Sign(priv)
+						So(err, ShouldBeNil)
+						return
+					}
+				)
+				enc, err = testPubKey.MarshalHash()
+				So(err, ShouldBeNil)
+				sender = proto.AccountAddress(hash.THashH(enc))
+				ownerPriv, ownerPub, err = asymmetric.GenSecp256k1KeyPair()
+				So(err, ShouldBeNil)
+				enc, err = ownerPub.MarshalHash()
+				So(err, ShouldBeNil)
+				owner = proto.AccountAddress(hash.THashH(enc))
+				for _, addr := range []proto.AccountAddress{sender, owner} {
+					_, loaded = ms.loadOrStoreAccountObject(addr, &accountObject{
+						Account: pt.Account{
+							Address: addr,
+						},
+					})
+					So(loaded, ShouldBeFalse)
+				}
+				create = &pt.CreateMultiSig{
+					CreateMultiSigHeader: pt.CreateMultiSigHeader{
+						Sender:    sender,
+						Owners:    []proto.AccountAddress{sender, owner},
+						Threshold: 2,
+					},
+				}
+				err = create.Sign(testPrivKey)
+				So(err, ShouldBeNil)
+				account = create.MultiSigAddress()
+				err = db.Update(ms.applyTransactionProcedure(create))
+				So(err, ShouldBeNil)
+				err = ms.increaseAccountStableBalance(account, 100)
+				So(err, ShouldBeNil)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				profile, loaded = ms.loadConfirmedMultiSig(account)
+				So(loaded, ShouldBeTrue)
+				So(profile.Threshold, ShouldEqual, 2)
+				So(profile.Owners, ShouldResemble, []proto.AccountAddress{sender, owner})
+
+				propose = &pt.ProposeMultiSig{
+					ProposeMultiSigHeader: pt.ProposeMultiSigHeader{
+						Sender:  sender,
+						Account: account,
+						Action: pt.MultiSigAction{
+							Transfer: &pt.TransferHeader{
+								Sender:    account,
+								Receiver:  addr2,
+								Amount:    10,
+								TokenType: pt.StableCoin,
+							},
+						},
+					},
+				}
+				propose.Nonce, err = ms.nextNonce(sender)
+				So(err, ShouldBeNil)
+				err = propose.Sign(testPrivKey)
+				So(err, ShouldBeNil)
+				err = db.Update(ms.applyTransactionProcedure(propose))
+				So(err, ShouldBeNil)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				profile, loaded = ms.loadConfirmedMultiSig(account)
+				So(loaded, ShouldBeTrue)
+				So(len(profile.Proposals), ShouldEqual, 1)
+				So(profile.Proposals[0].Approvals, ShouldResemble, []proto.AccountAddress{sender})
+				stable, _, _ = ms.loadAccountBalance(account)
+				So(stable, ShouldEqual, 100)
+				Convey("The metaState should reject duplicated multi-signature account", func() {
+					create.Threshold = 1
+					err = create.Sign(testPrivKey)
+					So(err, ShouldBeNil)
+					err = ms.createMultiSig(create)
+					So(err, ShouldBeNil)
+					err = ms.createMultiSig(create)
+					So(err, ShouldEqual, ErrAccountExists)
+				})
+				Convey("The metaState should reject invalid approvals", func() {
+					err = db.Update(ms.applyTransactionProcedure(newApproveTx(testPrivKey, sender)))
+					So(err, ShouldEqual, ErrMultiSigAlreadyApproved)
+					err = ms.approveMultiSig(&pt.ApproveMultiSig{
+						ApproveMultiSigHeader: pt.ApproveMultiSigHeader{
+							Sender:   addr1,
+							Account:  account,
+							Proposal: propose.HeaderHash,
+						},
+					})
+					So(err, ShouldEqual, ErrPermissionDenied)
+					err = ms.approveMultiSig(&pt.ApproveMultiSig{
+						ApproveMultiSigHeader: pt.ApproveMultiSigHeader{
+							Sender:  owner,
+							Account: account,
+						},
+					})
+					So(err, ShouldEqual, ErrMultiSigProposalNotFound)
+					err = ms.approveMultiSig(&pt.ApproveMultiSig{
+						ApproveMultiSigHeader: pt.ApproveMultiSigHeader{
+							Sender:   owner,
+							Account:  addr1,
+							Proposal: propose.HeaderHash,
+						},
+					})
+					So(err, ShouldEqual, ErrMultiSigNotFound)
+				})
+				Convey("The metaState should execute the proposal approved by enough owners", func() {
+					err = db.Update(ms.applyTransactionProcedure(newApproveTx(ownerPriv, owner)))
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					profile, loaded = ms.loadConfirmedMultiSig(account)
+					So(loaded, ShouldBeTrue)
+					So(profile.Proposals, ShouldBeEmpty)
+					stable, _, _ = ms.loadAccountBalance(account)
+					So(stable, ShouldEqual, 90)
+					stable, _, _ = ms.loadAccountBalance(addr2)
+					So(stable, ShouldEqual, 10)
+				})
+				Convey("The metaState should keep the proposal failed to execute", func() {
+					err = ms.decreaseAccountStableBalance(account, 95)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.applyTransactionProcedure(newApproveTx(ownerPriv, owner)))
+					So(err, ShouldEqual, ErrInsufficientBalance)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					profile, loaded = ms.loadConfirmedMultiSig(account)
+					So(loaded, ShouldBeTrue)
+					So(len(profile.Proposals), ShouldEqual, 1)
+				})
+				Convey("The metaState should be reproducible from the persistence db", func() {
+					var recovered = newMetaState()
+					err = db.View(recovered.reloadProcedure())
+					So(err, ShouldBeNil)
+					var recoveredProfile pt.MultiSigProfile
+					recoveredProfile, loaded = recovered.loadConfirmedMultiSig(account)
+					So(loaded, ShouldBeTrue)
+					So(recoveredProfile, ShouldResemble, profile)
+				})
+			})
+			Convey("When chain parameters are voted by the authorities", func() {
+				var (
+					enc           []byte
+					sender, owner proto.AccountAddress
+					ownerPriv     *asymmetric.PrivateKey
+					ownerPub      *asymmetric.PublicKey
+					vote          *pt.UpdateParams
+					updates       []pt.ParamsUpdate
+					params        = pt.ChainParams{
+						BlockPeriod:   2 * time.Second,
+						GasPrice:      3,
+						MinMinerStake: 200,
+						DefaultSpace:  1 << 30,
+					}
+					newUpdateTx = func(
+						priv *asymmetric.PrivateKey, addr proto.AccountAddress, p pt.ChainParams,
+					) (tx *pt.UpdateParams) {
+						tx = &pt.UpdateParams{
+							UpdateParamsHeader: pt.UpdateParamsHeader{
+								Sender:           addr,
+								Params:           p,
+								ActivationHeight: 20,
+							},
+						}
+						tx.Nonce, err = ms.nextNonce(addr)
+						So(err, ShouldBeNil)
+						err = tx.Sign(priv)
+						So(err, ShouldBeNil)
+						return
+					}
+				)
+				enc, err = testPubKey.MarshalHash()
+				So(err, ShouldBeNil)
+				sender = proto.AccountAddress(hash.THashH(enc))
+				ownerPriv, ownerPub, err = asymmetric.GenSecp256k1KeyPair()
+				So(err, ShouldBeNil)
+				enc, err = ownerPub.MarshalHash()
+				So(err, ShouldBeNil)
+				owner = proto.AccountAddress(hash.THashH(enc))
+				for _, addr := range []proto.AccountAddress{sender, owner} {
+					_, loaded = ms.loadOrStoreAccountObject(addr, &accountObject{
+						Account: pt.Account{
+							Address: addr,
+						},
+					})
+					So(loaded, ShouldBeFalse)
+				}
+				ms.setGovernance(
+					[]proto.AccountAddress{sender, owner, addr1}, 0, defaultChainParams(time.Second))
+				ms.settleParams(10)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+
+				vote = newUpdateTx(testPrivKey, sender, params)
+				err = db.Update(ms.applyTransactionProcedure(vote))
+				So(err, ShouldBeNil)
+				ms.settleParams(11)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				updates = ms.loadConfirmedParams()
+				So(len(updates), ShouldEqual, 1)
+				So(updates[0].Scheduled(), ShouldBeFalse)
+				So(updates[0].Votes, ShouldResemble, []proto.AccountAddress{sender})
+				So(ms.activeParams(), ShouldResemble, defaultChainParams(time.Second))
+				Convey("The metaState should reject invalid votes", func() {
+					err = db.Update(ms.applyTransactionProcedure(newUpdateTx(testPrivKey, sender, params)))
+					So(err, ShouldEqual, ErrParamsAlreadyVoted)
+					err = ms.updateParams(&pt.UpdateParams{
+						UpdateParamsHeader: pt.UpdateParamsHeader{
+							Sender:           addr2,
+							Params:           params,
+							ActivationHeight: 20,
+						},
+					})
+					So(err, ShouldEqual, ErrPermissionDenied)
+					err = ms.updateParams(&pt.UpdateParams{
+						UpdateParamsHeader: pt.UpdateParamsHeader{
+							Sender:           addr1,
+							Params:           params,
+							ActivationHeight: 11,
+						},
+					})
+					So(err, ShouldEqual, ErrParamsActivationPassed)
+				})
+				Convey("The metaState should drop the update not scheduled before activation", func() {
+					ms.settleParams(20)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					So(ms.loadConfirmedParams(), ShouldBeEmpty)
+					So(ms.activeParams(), ShouldResemble, defaultChainParams(time.Second))
+				})
+				Convey("The metaState should activate the update voted by enough authorities", func() {
+					err = db.Update(ms.applyTransactionProcedure(newUpdateTx(ownerPriv, owner, params)))
+					So(err, ShouldBeNil)
+					ms.settleParams(12)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					updates = ms.scheduledParams()
+					So(len(updates), ShouldEqual, 1)
+					So(updates[0].Height, ShouldEqual, 12)
+					So(updates[0].Votes, ShouldResemble, []proto.AccountAddress{sender, owner})
+					So(ms.activeParams(), ShouldResemble, defaultChainParams(time.Second))
+					err = ms.updateParams(&pt.UpdateParams{
+						UpdateParamsHeader: pt.UpdateParamsHeader{
+							Sender:           addr1,
+							Params:           params,
+							ActivationHeight: 20,
+						},
+					})
+					So(err, ShouldEqual, ErrParamsUpdateScheduled)
+					ms.settleParams(20)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					So(ms.activeParams(), ShouldResemble, params)
+					Convey("The metaState should be reproducible from the persistence db", func() {
+						var recovered = newMetaState()
+						recovered.setGovernance(
+							[]proto.AccountAddress{sender, owner, addr1}, 0, defaultChainParams(time.Second))
+						err = db.View(recovered.reloadProcedure())
+						So(err, ShouldBeNil)
+						recovered.setHeight(20)
+						So(recovered.activeParams(), ShouldResemble, params)
+						So(recovered.loadConfirmedParams(), ShouldResemble, ms.loadConfirmedParams())
+					})
+				})
+				Convey("The later scheduled update should win at the same activation height", func() {
+					err = db.Update(ms.applyTransactionProcedure(newUpdateTx(ownerPriv, owner, params)))
+					So(err, ShouldBeNil)
+					ms.settleParams(12)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					other := params
+					other.GasPrice = 5
+					for _, v := range []struct {
+						priv *asymmetric.PrivateKey
+						addr proto.AccountAddress
+					}{{testPrivKey, sender}, {ownerPriv, owner}} {
+						err = db.Update(ms.applyTransactionProcedure(newUpdateTx(v.priv, v.addr, other)))
+						So(err, ShouldBeNil)
+					}
+					ms.settleParams(13)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					So(len(ms.loadConfirmedParams()), ShouldEqual, 2)
+					updates = ms.scheduledParams()
+					So(len(updates), ShouldEqual, 1)
+					So(updates[0].Params, ShouldResemble, other)
+					ms.settleParams(20)
+					So(ms.activeParams(), ShouldResemble, other)
+				})
+			})
+			Convey("When a billing is applied", func() {
+				var (
+					enc            []byte
+					owner, miner   proto.AccountAddress
+					minerPriv      *asymmetric.PrivateKey
+					minerPub       *asymmetric.PublicKey
+					billing        pt.BillingProfile
+					requestHash    = generateRandomHash()
+					newChallengeTx = func() (tx *pt.BillingChallenge) {
+						tx = &pt.BillingChallenge{
+							BillingChallengeHeader: pt.BillingChallengeHeader{
+								Sender:      owner,
+								RequestHash: requestHash,
+							},
+						}
+						tx.Nonce, err = ms.nextNonce(owner)
+						So(err, ShouldBeNil)
+						err = tx.Sign(testPrivKey)
+						So(err, ShouldBeNil)
+						return
+					}
+					newProofTx = func(reads, writes uint64) (tx *pt.BillingProof) {
+						tx = &pt.BillingProof{
+							BillingProofHeader: pt.BillingProofHeader{
+								Sender:      miner,
+								RequestHash: requestHash,
+							},
+						}
+						for _, v := range []struct {
+							qt    wt.QueryType
+							count uint64
+						}{{wt.ReadQuery, reads}, {wt.WriteQuery, writes}} {
+							if v.count == 0 {
+								continue
+							}
+							var ack *wt.SignedAckHeader
+							ack, err = generateSignedAck(dbid3, v.qt, v.count, testPrivKey)
+							So(err, ShouldBeNil)
+							tx.Acks = append(tx.Acks, ack)
+						}
+						tx.Nonce, err = ms.nextNonce(miner)
+						So(err, ShouldBeNil)
+						err = tx.Sign(minerPriv)
+						So(err, ShouldBeNil)
+						return
+					}
+				)
+				enc, err = testPubKey.MarshalHash()
+				So(err, ShouldBeNil)
+				owner = proto.AccountAddress(hash.THashH(enc))
+				minerPriv, minerPub, err = asymmetric.GenSecp256k1KeyPair()
+				So(err, ShouldBeNil)
+				enc, err = minerPub.MarshalHash()
+				So(err, ShouldBeNil)
+				miner = proto.AccountAddress(hash.THashH(enc))
+				ao, loaded = ms.loadOrStoreAccountObject(owner, &accountObject{
+					Account: pt.Account{
+						Address: owner,
+					},
+				})
+				So(loaded, ShouldBeFalse)
+				ao, loaded = ms.loadOrStoreAccountObject(miner, &accountObject{
+					Account: pt.Account{
+						Address:             miner,
+						CovenantCoinBalance: 100,
+					},
+				})
+				So(loaded, ShouldBeFalse)
+				err = ms.createSQLChain(owner, dbid3)
+				So(err, ShouldBeNil)
+				err = ms.applyBilling(&pt.TxBilling{
+					TxContent: pt.TxContent{
+						BillingRequest: pt.BillingRequest{
+							Header: pt.BillingRequestHeader{
+								DatabaseID: dbid3,
+								ReadCount:  1,
+								WriteCount: 2,
+							},
+							RequestHash: requestHash,
+						},
+						Receivers: []*proto.AccountAddress{&miner},
+						Fees:      []uint64{10},
+						Rewards:   []uint64{0},
+					},
+				})
+				So(err, ShouldBeNil)
+				err = ms.settleBillings(1)
+				So(err, ShouldBeNil)
+				err = db.Update(ms.commitProcedure())
+				So(err, ShouldBeNil)
+				billing, loaded = ms.loadConfirmedBilling(requestHash)
+				So(loaded, ShouldBeTrue)
+				So(billing.State, ShouldEqual, pt.BillingStateSettled)
+				So(billing.Height, ShouldEqual, 1)
+				So(billing.Receivers, ShouldResemble, []proto.AccountAddress{miner})
+				Convey("The metaState should reject the challenge not proved in time", func() {
+					err = db.Update(ms.applyTransactionProcedure(newChallengeTx()))
+					So(err, ShouldBeNil)
+					err = db.Update(ms.applyTransactionProcedure(newChallengeTx()))
+					So(err, ShouldEqual, ErrInvalidBillingState)
+					err = ms.settleBillings(2)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					billing, loaded = ms.loadConfirmedBilling(requestHash)
+					So(loaded, ShouldBeTrue)
+					So(billing.State, ShouldEqual, pt.BillingStateChallenged)
+					So(billing.Challenger, ShouldEqual, owner)
+					So(billing.Deadline, ShouldEqual, 2+billingChallengeWindow)
+					err = ms.settleBillings(2 + billingChallengeWindow)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					billing, loaded = ms.loadConfirmedBilling(requestHash)
+					So(loaded, ShouldBeTrue)
+					So(billing.State, ShouldEqual, pt.BillingStateChallenged)
+					err = ms.settleBillings(3 + billingChallengeWindow)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					billing, loaded = ms.loadConfirmedBilling(requestHash)
+					So(loaded, ShouldBeTrue)
+					So(billing.State, ShouldEqual, pt.BillingStateRejected)
+					ao, loaded = ms.loadAccountObject(miner)
+					So(loaded, ShouldBeTrue)
+					So(ao.CovenantCoinBalance, ShouldEqual, 90)
+					ao, loaded = ms.loadAccountObject(owner)
+					So(loaded, ShouldBeTrue)
+					So(ao.CovenantCoinBalance, ShouldEqual, 10)
+					err = db.Update(ms.applyTransactionProcedure(newProofTx(1, 2)))
+					So(err, ShouldEqual, ErrInvalidBillingState)
+				})
+				Convey("The metaState should accept the proof covering billed queries", func() {
+					err = db.Update(ms.applyTransactionProcedure(newProofTx(1, 2)))
+					So(err, ShouldEqual, ErrInvalidBillingState)
+					err = db.Update(ms.applyTransactionProcedure(newChallengeTx()))
+					So(err, ShouldBeNil)
+					err = ms.settleBillings(2)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					err = db.Update(ms.applyTransactionProcedure(newProofTx(1, 1)))
+					So(err, ShouldEqual, ErrInsufficientBillingProof)
+					err = db.Update(ms.applyTransactionProcedure(newProofTx(1, 2)))
+					So(err, ShouldBeNil)
+					err = ms.settleBillings(3 + billingChallengeWindow)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					billing, loaded = ms.loadConfirmedBilling(requestHash)
+					So(loaded, ShouldBeTrue)
+					So(billing.State, ShouldEqual, pt.BillingStateProved)
+					ao, loaded = ms.loadAccountObject(miner)
+					So(loaded, ShouldBeTrue)
+					So(ao.CovenantCoinBalance, ShouldEqual, 110)
+				})
+				Convey("The metaState should remove the billing out of dispute period", func() {
+					err = ms.settleBillings(1 + billingDisputePeriod)
+					So(err, ShouldBeNil)
+					err = db.Update(ms.commitProcedure())
+					So(err, ShouldBeNil)
+					_, loaded = ms.loadConfirmedBilling(requestHash)
+					So(loaded, ShouldBeFalse)
+					err = db.Update(ms.applyTransactionProcedure(newChallengeTx()))
+					So(err, ShouldEqual, ErrBillingNotFound)
+				})
+				Convey("The metaState should be reproducible from the persistence db", func() {
+					var recovered = newMetaState()
+					err = db.View(recovered.reloadProcedure())
+					So(err, ShouldBeNil)
+					_, loaded = recovered.loadConfirmedBilling(requestHash)
+					So(loaded, ShouldBeTrue)
+				})
 			})
 		})
 	})
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	// NodeGasPerHour defines the gas consumed by each database node per hour.
+	NodeGasPerHour uint64 = 100
+	// SpaceGasPerGBHour defines the gas consumed by each GB of reserved space on a node per hour.
+	SpaceGasPerGBHour uint64 = 10
+	// MemoryGasPerGBHour defines the gas consumed by each GB of reserved memory on a node per hour.
+	MemoryGasPerGBHour uint64 = 20
+
+	// HoursPerMonth defines the hours of a billing month.
+	HoursPerMonth uint64 = 30 * 24
+
+	gb = 1 << 30
+)
+
+// PriceEstimate defines the estimated cost of a database resource profile.
+type PriceEstimate struct {
+	// CurrentGasPrice defines the gas price of block producer, GasPrice defines the gas price
+	// applied to the resource profile which is not less than the current one.
+	CurrentGasPrice uint64
+	GasPrice        uint64
+	GasPerHour      uint64
+	CostPerHour     uint64
+	CostPerMonth    uint64
+}
+
+// EstimatePriceRequest defines client estimate database price rpc request entity.
+type EstimatePriceRequest struct {
+	proto.Envelope
+	ResourceMeta wt.ResourceMeta
+}
+
+// EstimatePriceResponse defines client estimate database price rpc response entity.
+type EstimatePriceResponse struct {
+	proto.Envelope
+	Estimate PriceEstimate
+}
+
+// estimatePrice calculates the cost of resource profile meta with the current gas price.
+func estimatePrice(meta *wt.ResourceMeta, gasPrice uint64) (est PriceEstimate, err error) {
+	if err = verifyResourceMeta(meta, gasPrice); err != nil {
+		return
+	}
+	if meta.Node == 0 {
+		err = ErrInvalidResourceMeta
+		return
+	}
+
+	est.CurrentGasPrice = gasPrice
+	est.GasPrice = est.CurrentGasPrice
+	if meta.GasPrice > est.GasPrice {
+		est.GasPrice = meta.GasPrice
+	}
+	perNode := NodeGasPerHour +
+		(meta.Space+gb-1)/gb*SpaceGasPerGBHour +
+		(meta.Memory+gb-1)/gb*MemoryGasPerGBHour
+	est.GasPerHour = perNode * uint64(meta.Node)
+	est.CostPerHour = est.GasPerHour * est.GasPrice
+	est.CostPerMonth = est.CostPerHour * HoursPerMonth
+	return
+}
+
+// EstimatePrice defines block producer estimate database price logic.
+func (s *DBService) EstimatePrice(req *EstimatePriceRequest, resp *EstimatePriceResponse) (err error) {
+	resp.Estimate, err = estimatePrice(&req.ResourceMeta, s.chainParams().GasPrice)
+	return
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"testing"
+
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+func TestEstimatePrice(t *testing.T) {
+	meta := &wt.ResourceMeta{
+		Node:   2,
+		Space:  gb + 1,
+		Memory: gb,
+	}
+	est, err := estimatePrice(meta, uint64(gasprice))
+	if err != nil {
+		t.Fatalf("unexpect error: %v", err)
+	}
+	gasPerHour := 2 * (NodeGasPerHour + 2*SpaceGasPerGBHour + MemoryGasPerGBHour)
+	if est.GasPerHour != gasPerHour {
+		t.Fatalf("unexpected gas per hour: %d", est.GasPerHour)
+	}
+	if est.GasPrice != uint64(gasprice) || est.CostPerMonth != gasPerHour*uint64(gasprice)*HoursPerMonth {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+
+	meta.GasPrice = uint64(gasprice) * 3
+	if est, err = estimatePrice(meta, uint64(gasprice)); err != nil {
+		t.Fatalf("unexpect error: %v", err)
+	}
+	if est.CostPerHour != gasPerHour*meta.GasPrice {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+
+	meta.Node = 0
+	if _, err = estimatePrice(meta, uint64(gasprice)); err != ErrInvalidResourceMeta {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
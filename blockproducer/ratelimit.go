@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+// maxRateLimiterKeys defines the keys tracked by a rate limiter before the idle ones are swept.
+const maxRateLimiterKeys = 10000
+
+// ThrottleScope defines what a transaction submission is throttled by.
+type ThrottleScope string
+
+const (
+	// ThrottleScopeAccount throttles the submissions of an account.
+	ThrottleScopeAccount ThrottleScope = "account"
+	// ThrottleScopeIP throttles the submissions from an IP address.
+	ThrottleScopeIP ThrottleScope = "ip"
+)
+
+// TxThrottledError indicates that a transaction submission exceeds the rate limit, the caller
+// should retry after RetryAfter.
+type TxThrottledError struct {
+	Scope      ThrottleScope
+	Key        string
+	RetryAfter time.Duration
+}
+
+// Error implements error.Error.
+func (e *TxThrottledError) Error() string {
+	return fmt.Sprintf("%v: %s %s, retry after %v", ErrTxThrottled, e.Scope, e.Key, e.RetryAfter)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the events of each key by a token bucket, which is refilled at rate per
+// second and holds burst tokens at most. A nil rateLimiter limits nothing.
+type rateLimiter struct {
+	sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter returns a rate limiter, or nil if rate is not set. The burst is the rate rounded
+// up if it's not set.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token of key at now, or returns the time to wait for the next token if the
+// bucket is empty.
+func (l *rateLimiter) allow(key string, now time.Time) (ok bool, retryAfter time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	b, exists := l.buckets[key]
+	if !exists {
+		if len(l.buckets) >= maxRateLimiterKeys {
+			l.sweep(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+	if b.tokens < 1 {
+		retryAfter = time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, retryAfter
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *rateLimiter) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+}
+
+// sweep removes the buckets refilled to full, which behave the same as new ones.
+func (l *rateLimiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+		if l.refill(b, now); b.tokens >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// throttleTx checks the transaction submission against the rate limits of the sender account
+// and the IP address of caller, remoteAddr is ignored if it's empty.
+func (c *Chain) throttleTx(tx pi.Transaction, remoteAddr string) (err error) {
+	var now = time.Now()
+	if remoteAddr != "" {
+		ip := remoteAddr
+		if host, _, serr := net.SplitHostPort(remoteAddr); serr == nil {
+			ip = host
+		}
+		if ok, retryAfter := c.ipLimiter.allow(ip, now); !ok {
+			return &TxThrottledError{Scope: ThrottleScopeIP, Key: ip, RetryAfter: retryAfter}
+		}
+	}
+	addr := hash.Hash(tx.GetAccountAddress()).String()
+	if ok, retryAfter := c.accountLimiter.allow(addr, now); !ok {
+		return &TxThrottledError{Scope: ThrottleScopeAccount, Key: addr, RetryAfter: retryAfter}
+	}
+	return
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var (
+		l   = newRateLimiter(2, 3)
+		now = time.Now()
+	)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("event %d should be allowed in burst", i)
+		}
+	}
+	ok, retryAfter := l.allow("a", now)
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("unexpected result: %v %v", ok, retryAfter)
+	}
+	if ok, _ = l.allow("b", now); !ok {
+		t.Fatal("other keys should not be limited")
+	}
+	if ok, _ = l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Fatal("bucket should be refilled")
+	}
+	if ok, _ = l.allow("a", now.Add(500*time.Millisecond)); ok {
+		t.Fatal("bucket should be empty")
+	}
+
+	// full buckets are swept
+	l.sweep(now.Add(time.Hour))
+	if len(l.buckets) != 0 {
+		t.Fatalf("unexpected buckets: %d", len(l.buckets))
+	}
+
+	if l = newRateLimiter(0, 10); l != nil {
+		t.Fatal("rate limiter should not be created without rate")
+	}
+	if ok, _ = l.allow("a", now); !ok {
+		t.Fatal("nil rate limiter should limit nothing")
+	}
+	if l = newRateLimiter(0.5, 0); l.burst != 1 {
+		t.Fatalf("unexpected burst: %v", l.burst)
+	}
+}
+
+func TestThrottleTx(t *testing.T) {
+	var (
+		c = &Chain{
+			accountLimiter: newRateLimiter(1, 2),
+			ipLimiter:      newRateLimiter(1, 3),
+		}
+		newTx = func(sender byte) *pt.Transfer {
+			return &pt.Transfer{TransferHeader: pt.TransferHeader{
+				Sender: proto.AccountAddress{sender},
+			}}
+		}
+		err error
+	)
+	for i := 0; i < 2; i++ {
+		if err = c.throttleTx(newTx(0x1), "127.0.0.1:1000"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err = c.throttleTx(newTx(0x1), "127.0.0.1:1001")
+	if e, ok := err.(*TxThrottledError); !ok || e.Scope != ThrottleScopeAccount || e.RetryAfter <= 0 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), ErrTxThrottled.Error()) {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+	// the ip address is limited regardless of port
+	err = c.throttleTx(newTx(0x2), "127.0.0.1:1002")
+	if e, ok := err.(*TxThrottledError); !ok || e.Scope != ThrottleScopeIP || e.Key != "127.0.0.1" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// local submissions are only limited by account
+	if err = c.throttleTx(newTx(0x2), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"sync"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+const (
+	// maxFailedTxs defines the failed transactions remembered for receipt queries, the oldest
+	// ones are forgotten first.
+	maxFailedTxs = 4096
+	// maxTxReceiptsQuery defines the transactions queried by a batched receipt lookup at most.
+	maxTxReceiptsQuery = 1000
+)
+
+// failedTxs remembers the recent transactions failing to apply and the reasons, which are lost
+// on restart.
+type failedTxs struct {
+	sync.Mutex
+	reasons map[hash.Hash]string
+	order   []hash.Hash
+}
+
+func newFailedTxs() *failedTxs {
+	return &failedTxs{
+		reasons: make(map[hash.Hash]string),
+	}
+}
+
+func (f *failedTxs) add(h hash.Hash, reason string) {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.reasons[h]; !ok {
+		if len(f.order) >= maxFailedTxs {
+			delete(f.reasons, f.order[0])
+			f.order = f.order[1:]
+		}
+		f.order = append(f.order, h)
+	}
+	f.reasons[h] = reason
+}
+
+func (f *failedTxs) get(h hash.Hash) (reason string, ok bool) {
+	f.Lock()
+	defer f.Unlock()
+	reason, ok = f.reasons[h]
+	return
+}
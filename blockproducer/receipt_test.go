@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+func TestFailedTxs(t *testing.T) {
+	var (
+		f      = newFailedTxs()
+		hashes = make([]hash.Hash, maxFailedTxs+1)
+	)
+	for i := range hashes {
+		hashes[i] = generateRandomHash()
+		f.add(hashes[i], "failed")
+	}
+	// the oldest failure is forgotten
+	if _, ok := f.get(hashes[0]); ok {
+		t.Fatal("unexpected failure of the oldest transaction")
+	}
+	if reason, ok := f.get(hashes[1]); !ok || reason != "failed" {
+		t.Fatalf("unexpected failure: %v %s", ok, reason)
+	}
+
+	// updating the reason keeps the order
+	f.add(hashes[1], "failed again")
+	f.add(generateRandomHash(), "failed")
+	if _, ok := f.get(hashes[1]); ok {
+		t.Fatal("unexpected failure of the oldest transaction")
+	}
+	if len(f.order) != maxFailedTxs || len(f.reasons) != maxFailedTxs {
+		t.Fatalf("unexpected failures: %d %d", len(f.order), len(f.reasons))
+	}
+}
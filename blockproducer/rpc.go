@@ -17,11 +17,14 @@
 package blockproducer
 
 import (
+	"time"
+
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
 	ci "github.com/CovenantSQL/CovenantSQL/chain/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/ugorji/go/codec"
 )
 
 const (
@@ -81,6 +84,18 @@ type FetchBlockResp struct {
 	Block  *types.Block
 }
 
+// FetchStateSnapshotReq defines a request of the FetchStateSnapshot RPC method.
+type FetchStateSnapshotReq struct {
+	proto.Envelope
+}
+
+// FetchStateSnapshotResp defines a response of the FetchStateSnapshot RPC method.
+type FetchStateSnapshotResp struct {
+	proto.Envelope
+	Snapshot *types.StateSnapshot
+	Block    *types.Block
+}
+
 // FetchTxBillingReq defines a request of the FetchTxBilling RPC method.
 type FetchTxBillingReq struct {
 	proto.Envelope
@@ -115,6 +130,9 @@ type NextAccountNonceResp struct {
 	proto.Envelope
 	Addr  proto.AccountAddress
 	Nonce pi.AccountNonce
+	// Queued lists the nonces of transactions accepted ahead of Nonce, which are applied once
+	// the transactions before them arrive.
+	Queued []pi.AccountNonce
 }
 
 // AddTxReq defines a request of the AddTx RPC method.
@@ -128,6 +146,500 @@ type AddTxResp struct {
 	proto.Envelope
 }
 
+// SimulateTxReq defines a request of the SimulateTx RPC method.
+type SimulateTxReq struct {
+	proto.Envelope
+	Tx pi.Transaction
+}
+
+// encodedSimulateTxReq defines the wire format of SimulateTxReq, the transaction is encoded with
+// its type so that it's decoded as the concrete transaction.
+type encodedSimulateTxReq struct {
+	proto.Envelope
+	Type pi.TransactionType
+	Tx   []byte
+}
+
+// CodecEncodeSelf implements codec.Selfer.CodecEncodeSelf, the panics are recovered as errors
+// by the codec.
+func (r *SimulateTxReq) CodecEncodeSelf(e *codec.Encoder) {
+	var enc = &encodedSimulateTxReq{Envelope: r.Envelope}
+	if r.Tx != nil {
+		buf, err := r.Tx.Serialize()
+		if err != nil {
+			panic(err)
+		}
+		enc.Type, enc.Tx = r.Tx.GetTransactionType(), buf
+	}
+	e.MustEncode(enc)
+}
+
+// CodecDecodeSelf implements codec.Selfer.CodecDecodeSelf.
+func (r *SimulateTxReq) CodecDecodeSelf(d *codec.Decoder) {
+	var dec = &encodedSimulateTxReq{}
+	d.MustDecode(dec)
+	r.Envelope, r.Tx = dec.Envelope, nil
+	if dec.Tx == nil {
+		return
+	}
+	t, err := types.NewTransaction(dec.Type)
+	if err != nil {
+		panic(err)
+	}
+	if err = t.Deserialize(dec.Tx); err != nil {
+		panic(err)
+	}
+	r.Tx = t
+}
+
+// SimulateTxResp defines a response of the SimulateTx RPC method.
+type SimulateTxResp struct {
+	proto.Envelope
+	Ok    bool
+	Error string
+	// Fee is the fee in covenant coins charged to the sender once the transaction is applied.
+	Fee      uint64
+	Accounts []types.Account
+}
+
+// UpdatePermissionReq defines a request of the UpdatePermission RPC method.
+type UpdatePermissionReq struct {
+	proto.Envelope
+	Tx *types.UpdatePermission
+}
+
+// UpdatePermissionResp defines a response of the UpdatePermission RPC method.
+type UpdatePermissionResp struct {
+	proto.Envelope
+}
+
+// QuerySQLChainProfileReq defines a request of the QuerySQLChainProfile RPC method.
+type QuerySQLChainProfileReq struct {
+	proto.Envelope
+	DBID proto.DatabaseID
+}
+
+// QuerySQLChainProfileResp defines a response of the QuerySQLChainProfile RPC method.
+type QuerySQLChainProfileResp struct {
+	proto.Envelope
+	Profile types.SQLChainProfile
+}
+
+// QueryAccountBalanceReq defines a request of the QueryAccountBalance RPC method.
+type QueryAccountBalanceReq struct {
+	proto.Envelope
+	Addr proto.AccountAddress
+}
+
+// QueryAccountBalanceResp defines a response of the QueryAccountBalance RPC method.
+type QueryAccountBalanceResp struct {
+	proto.Envelope
+	Addr                proto.AccountAddress
+	StableCoinBalance   uint64
+	CovenantCoinBalance uint64
+}
+
+// TransferReq defines a request of the Transfer RPC method.
+type TransferReq struct {
+	proto.Envelope
+	Tx *types.Transfer
+}
+
+// TransferResp defines a response of the Transfer RPC method.
+type TransferResp struct {
+	proto.Envelope
+}
+
+// QueryTxStateReq defines a request of the QueryTxState RPC method.
+type QueryTxStateReq struct {
+	proto.Envelope
+	Hash hash.Hash
+}
+
+// QueryTxStateResp defines a response of the QueryTxState RPC method.
+type QueryTxStateResp struct {
+	proto.Envelope
+	Hash  hash.Hash
+	State pi.TransactionState
+	// Height is the height of block packing the transaction if it's confirmed.
+	Height uint32
+	// Reason is the error of the transaction if it failed to apply.
+	Reason string
+}
+
+// QueryTxReceiptsReq defines a request of the QueryTxReceipts RPC method.
+type QueryTxReceiptsReq struct {
+	proto.Envelope
+	Hashes []hash.Hash
+}
+
+// QueryTxReceiptsResp defines a response of the QueryTxReceipts RPC method, the receipts are in
+// the order of the queried hashes.
+type QueryTxReceiptsResp struct {
+	proto.Envelope
+	Receipts []TxReceipt
+}
+
+// RegisterMinerReq defines a request of the RegisterMiner RPC method.
+type RegisterMinerReq struct {
+	proto.Envelope
+	Tx *types.RegisterMiner
+}
+
+// RegisterMinerResp defines a response of the RegisterMiner RPC method.
+type RegisterMinerResp struct {
+	proto.Envelope
+}
+
+// QueryMinersReq defines a request of the QueryMiners RPC method.
+type QueryMinersReq struct {
+	proto.Envelope
+}
+
+// QueryMinersResp defines a response of the QueryMiners RPC method.
+type QueryMinersResp struct {
+	proto.Envelope
+	Miners []types.MinerProfile
+}
+
+// ChallengeBillingReq defines a request of the ChallengeBilling RPC method.
+type ChallengeBillingReq struct {
+	proto.Envelope
+	Tx *types.BillingChallenge
+}
+
+// ChallengeBillingResp defines a response of the ChallengeBilling RPC method.
+type ChallengeBillingResp struct {
+	proto.Envelope
+}
+
+// ProveBillingReq defines a request of the ProveBilling RPC method.
+type ProveBillingReq struct {
+	proto.Envelope
+	Tx *types.BillingProof
+}
+
+// ProveBillingResp defines a response of the ProveBilling RPC method.
+type ProveBillingResp struct {
+	proto.Envelope
+}
+
+// QueryBillingReq defines a request of the QueryBilling RPC method.
+type QueryBillingReq struct {
+	proto.Envelope
+	RequestHash hash.Hash
+}
+
+// QueryBillingResp defines a response of the QueryBilling RPC method.
+type QueryBillingResp struct {
+	proto.Envelope
+	Billing types.BillingProfile
+}
+
+// CreateMultiSigReq defines a request of the CreateMultiSig RPC method.
+type CreateMultiSigReq struct {
+	proto.Envelope
+	Tx *types.CreateMultiSig
+}
+
+// CreateMultiSigResp defines a response of the CreateMultiSig RPC method.
+type CreateMultiSigResp struct {
+	proto.Envelope
+}
+
+// ProposeMultiSigReq defines a request of the ProposeMultiSig RPC method.
+type ProposeMultiSigReq struct {
+	proto.Envelope
+	Tx *types.ProposeMultiSig
+}
+
+// ProposeMultiSigResp defines a response of the ProposeMultiSig RPC method.
+type ProposeMultiSigResp struct {
+	proto.Envelope
+}
+
+// ApproveMultiSigReq defines a request of the ApproveMultiSig RPC method.
+type ApproveMultiSigReq struct {
+	proto.Envelope
+	Tx *types.ApproveMultiSig
+}
+
+// ApproveMultiSigResp defines a response of the ApproveMultiSig RPC method.
+type ApproveMultiSigResp struct {
+	proto.Envelope
+}
+
+// QueryMultiSigReq defines a request of the QueryMultiSig RPC method.
+type QueryMultiSigReq struct {
+	proto.Envelope
+	Addr proto.AccountAddress
+}
+
+// QueryMultiSigResp defines a response of the QueryMultiSig RPC method.
+type QueryMultiSigResp struct {
+	proto.Envelope
+	Profile types.MultiSigProfile
+}
+
+// SubmitMinerEvidenceReq defines a request of the SubmitMinerEvidence RPC method.
+type SubmitMinerEvidenceReq struct {
+	proto.Envelope
+	Tx *types.MinerEvidence
+}
+
+// SubmitMinerEvidenceResp defines a response of the SubmitMinerEvidence RPC method.
+type SubmitMinerEvidenceResp struct {
+	proto.Envelope
+}
+
+// ChallengeServiceReq defines a request of the ChallengeService RPC method.
+type ChallengeServiceReq struct {
+	proto.Envelope
+	Tx *types.ServiceChallenge
+}
+
+// ChallengeServiceResp defines a response of the ChallengeService RPC method.
+type ChallengeServiceResp struct {
+	proto.Envelope
+}
+
+// ProveServiceReq defines a request of the ProveService RPC method.
+type ProveServiceReq struct {
+	proto.Envelope
+	Tx *types.ServiceProof
+}
+
+// ProveServiceResp defines a response of the ProveService RPC method.
+type ProveServiceResp struct {
+	proto.Envelope
+}
+
+// UpdateParamsReq defines a request of the UpdateParams RPC method.
+type UpdateParamsReq struct {
+	proto.Envelope
+	Tx *types.UpdateParams
+}
+
+// UpdateParamsResp defines a response of the UpdateParams RPC method.
+type UpdateParamsResp struct {
+	proto.Envelope
+}
+
+// QueryParamsReq defines a request of the QueryParams RPC method.
+type QueryParamsReq struct {
+	proto.Envelope
+}
+
+// QueryParamsResp defines a response of the QueryParams RPC method.
+type QueryParamsResp struct {
+	proto.Envelope
+	Params  types.ChainParams
+	Updates []types.ParamsUpdate
+}
+
+// QueryChainModeReq defines a request of the QueryChainMode RPC method.
+type QueryChainModeReq struct {
+	proto.Envelope
+}
+
+// QueryChainModeResp defines a response of the QueryChainMode RPC method, blocks below
+// PrunedHeight are not available except the genesis block.
+type QueryChainModeResp struct {
+	proto.Envelope
+	Mode         ChainMode
+	KeepBlocks   uint32
+	Height       uint32
+	PrunedHeight uint32
+}
+
+// QueryAccountProofReq defines a request of the QueryAccountProof RPC method.
+type QueryAccountProofReq struct {
+	proto.Envelope
+	Addr proto.AccountAddress
+}
+
+// QueryAccountProofResp defines a response of the QueryAccountProof RPC method, the account is
+// proved by the meta root of the block header in Proof.
+type QueryAccountProofResp struct {
+	proto.Envelope
+	Account types.Account
+	Proof   *types.StateProof
+}
+
+// QueryDatabaseProofReq defines a request of the QueryDatabaseProof RPC method.
+type QueryDatabaseProofReq struct {
+	proto.Envelope
+	DBID proto.DatabaseID
+}
+
+// QueryDatabaseProofResp defines a response of the QueryDatabaseProof RPC method, the profile is
+// proved by the meta root of the block header in Proof.
+type QueryDatabaseProofResp struct {
+	proto.Envelope
+	Profile types.SQLChainProfile
+	Proof   *types.StateProof
+}
+
+// QueryAccountEventsReq defines a request of the QueryAccountEvents RPC method, the events
+// after sequence Since are queried and Wait is the longest time to wait for new events.
+type QueryAccountEventsReq struct {
+	proto.Envelope
+	Addr  proto.AccountAddress
+	Since uint64
+	Limit int
+	Wait  time.Duration
+}
+
+// QueryAccountEventsResp defines a response of the QueryAccountEvents RPC method.
+type QueryAccountEventsResp struct {
+	proto.Envelope
+	Events []*AccountEvent
+}
+
+// AdviseCheckpointReq defines a request of the AdviseCheckpoint RPC method.
+type AdviseCheckpointReq struct {
+	proto.Envelope
+	Header    types.CheckpointHeader
+	Signature types.CheckpointSignature
+}
+
+// AdviseCheckpointResp defines a response of the AdviseCheckpoint RPC method.
+type AdviseCheckpointResp struct {
+	proto.Envelope
+}
+
+// QueryCheckpointReq defines a request of the QueryCheckpoint RPC method, the latest checkpoint
+// is queried if Height is 0.
+type QueryCheckpointReq struct {
+	proto.Envelope
+	Height uint32
+}
+
+// QueryCheckpointResp defines a response of the QueryCheckpoint RPC method.
+type QueryCheckpointResp struct {
+	proto.Envelope
+	Checkpoint *types.Checkpoint
+}
+
+// CreateVestingReq defines a request of the CreateVesting RPC method.
+type CreateVestingReq struct {
+	proto.Envelope
+	Tx *types.CreateVesting
+}
+
+// CreateVestingResp defines a response of the CreateVesting RPC method.
+type CreateVestingResp struct {
+	proto.Envelope
+}
+
+// ReleaseVestingReq defines a request of the ReleaseVesting RPC method.
+type ReleaseVestingReq struct {
+	proto.Envelope
+	Tx *types.ReleaseVesting
+}
+
+// ReleaseVestingResp defines a response of the ReleaseVesting RPC method.
+type ReleaseVestingResp struct {
+	proto.Envelope
+}
+
+// RevokeVestingReq defines a request of the RevokeVesting RPC method.
+type RevokeVestingReq struct {
+	proto.Envelope
+	Tx *types.RevokeVesting
+}
+
+// RevokeVestingResp defines a response of the RevokeVesting RPC method.
+type RevokeVestingResp struct {
+	proto.Envelope
+}
+
+// QueryVestingReq defines a request of the QueryVesting RPC method, the vesting is identified by
+// the hash of its creating transaction.
+type QueryVestingReq struct {
+	proto.Envelope
+	Vesting hash.Hash
+}
+
+// QueryVestingResp defines a response of the QueryVesting RPC method, Height is the height of
+// the last settled block, at which Releasable is vested but not released yet.
+type QueryVestingResp struct {
+	proto.Envelope
+	Profile    types.VestingProfile
+	Height     uint32
+	Releasable uint64
+}
+
+// TxReceipt defines the state of a main chain transaction.
+type TxReceipt struct {
+	Hash  hash.Hash
+	State pi.TransactionState
+	// Height is the height of block packing the transaction if it's confirmed.
+	Height uint32
+	// Reason is the error of the transaction if it failed to apply, failures are kept in memory
+	// for the recent transactions only.
+	Reason string
+}
+
+// MinerIncome defines the tokens distributed to a miner by billings of a database.
+type MinerIncome struct {
+	Address proto.AccountAddress
+	Fee     uint64 // paid by covenant coin
+	Reward  uint64 // paid by stable coin
+}
+
+// DatabaseUsage defines the accumulated usage of a database billed by block producer.
+type DatabaseUsage struct {
+	DatabaseID proto.DatabaseID
+	// Billings defines the count of billing periods, LowHeight and HighHeight define the sqlchain
+	// heights covered by the billings.
+	Billings   uint32
+	LowHeight  int32
+	HighHeight int32
+	ReadCount  uint64
+	WriteCount uint64
+	GasAmount  uint64
+	Incomes    []MinerIncome
+	// Deposit defines the remaining advance payment of the database.
+	Deposit uint64
+}
+
+// FeeEstimate defines the fee rates of pending transactions, a fee rate is the fee per kilobyte
+// of serialized transaction.
+type FeeEstimate struct {
+	// Pending is the number of pending transactions and Capacity is the max number of
+	// transactions packed in a block.
+	Pending, Capacity int
+	// MinFeeRate is the minimum fee rate required to be packed in the next block, which is 0 if
+	// all the pending transactions fit in a block.
+	MinFeeRate uint64
+	// MedianFeeRate is the median fee rate of transactions to be packed in the next block.
+	MedianFeeRate uint64
+}
+
+// QueryFeeEstimateReq defines a request of the QueryFeeEstimate RPC method.
+type QueryFeeEstimateReq struct {
+	proto.Envelope
+}
+
+// QueryFeeEstimateResp defines a response of the QueryFeeEstimate RPC method.
+type QueryFeeEstimateResp struct {
+	proto.Envelope
+	Estimate FeeEstimate
+}
+
+// QueryDatabaseUsageReq defines a request of the QueryDatabaseUsage RPC method.
+type QueryDatabaseUsageReq struct {
+	proto.Envelope
+	DBID proto.DatabaseID
+}
+
+// QueryDatabaseUsageResp defines a response of the QueryDatabaseUsage RPC method.
+type QueryDatabaseUsageResp struct {
+	proto.Envelope
+	Usage DatabaseUsage
+}
+
 // AdviseNewBlock is the RPC method to advise a new block to target server.
 func (s *ChainRPCService) AdviseNewBlock(req *AdviseNewBlockReq, resp *AdviseNewBlockResp) error {
 	s.chain.blocksFromRPC <- req.Block
@@ -157,6 +669,15 @@ func (s *ChainRPCService) FetchBlock(req *FetchBlockReq, resp *FetchBlockResp) e
 	return err
 }
 
+// FetchStateSnapshot is the RPC method to fetch the latest state snapshot and the block
+// committing it from the target server.
+func (s *ChainRPCService) FetchStateSnapshot(
+	req *FetchStateSnapshotReq, resp *FetchStateSnapshotResp) (err error,
+) {
+	resp.Snapshot, resp.Block, err = s.chain.fetchStateSnapshot()
+	return
+}
+
 // FetchTxBilling is the RPC method to fetch a known billing tx form the target server.
 func (s *ChainRPCService) FetchTxBilling(req *FetchTxBillingReq, resp *FetchTxBillingResp) error {
 	return nil
@@ -176,11 +697,329 @@ func (s *ChainRPCService) NextAccountNonce(
 		return
 	}
 	resp.Addr = req.Addr
+	resp.Queued = s.chain.ms.queuedNonces(req.Addr)
 	return
 }
 
 // AddTx is the RPC method to add a transaction.
 func (s *ChainRPCService) AddTx(req *AddTxReq, resp *AddTxResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	if err = s.chain.throttleTx(req.Tx, req.GetRemoteAddr()); err != nil {
+		return
+	}
 	s.chain.pendingTxs <- req.Tx
 	return
 }
+
+// submitTx applies the transaction submitted by caller synchronously if it's not throttled.
+func (s *ChainRPCService) submitTx(req proto.EnvelopeAPI, tx pi.Transaction) (err error) {
+	if err = s.chain.throttleTx(tx, req.GetRemoteAddr()); err != nil {
+		return
+	}
+	return s.chain.processTx(tx)
+}
+
+// SimulateTx is the RPC method to dry-run a transaction against the current state without
+// committing it, validation failures are reported in the response instead of the RPC error.
+func (s *ChainRPCService) SimulateTx(req *SimulateTxReq, resp *SimulateTxResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	var accounts []types.Account
+	if err = s.chain.checkChainID(req.Tx); err == nil {
+		accounts, err = s.chain.ms.simulateTransaction(req.Tx)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		err = nil
+		return
+	}
+	resp.Ok = true
+	resp.Fee = req.Tx.GetFee()
+	resp.Accounts = accounts
+	return
+}
+
+// UpdatePermission is the RPC method to grant or revoke database permission, the transaction
+// is applied synchronously so that an invalid request is reported to the caller.
+func (s *ChainRPCService) UpdatePermission(
+	req *UpdatePermissionReq, resp *UpdatePermissionResp) (err error,
+) {
+	if req.Tx == nil {
+		return types.ErrInvalidPermission
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QuerySQLChainProfile is the RPC method to query the confirmed profile of a database.
+func (s *ChainRPCService) QuerySQLChainProfile(
+	req *QuerySQLChainProfileReq, resp *QuerySQLChainProfileResp) (err error,
+) {
+	var loaded bool
+	if resp.Profile, loaded = s.chain.ms.loadConfirmedSQLChainProfile(req.DBID); !loaded {
+		err = ErrDatabaseNotFound
+	}
+	return
+}
+
+// QueryAccountBalance is the RPC method to query the balances of an account, including the changes
+// of pending transactions.
+func (s *ChainRPCService) QueryAccountBalance(
+	req *QueryAccountBalanceReq, resp *QueryAccountBalanceResp) (err error,
+) {
+	var loaded bool
+	if resp.StableCoinBalance, resp.CovenantCoinBalance, loaded = s.chain.ms.loadAccountBalance(
+		req.Addr); !loaded {
+		err = ErrAccountNotFound
+		return
+	}
+	resp.Addr = req.Addr
+	return
+}
+
+// Transfer is the RPC method to transfer stable coins between accounts, the transaction is applied
+// synchronously so that an invalid request is reported to the caller.
+func (s *ChainRPCService) Transfer(req *TransferReq, resp *TransferResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QueryTxState is the RPC method to query the state of a main chain transaction.
+func (s *ChainRPCService) QueryTxState(req *QueryTxStateReq, resp *QueryTxStateResp) (err error) {
+	var receipts []TxReceipt
+	if receipts, err = s.chain.queryTxReceipts([]hash.Hash{req.Hash}); err != nil {
+		return
+	}
+	resp.Hash = req.Hash
+	resp.State = receipts[0].State
+	resp.Height = receipts[0].Height
+	resp.Reason = receipts[0].Reason
+	return
+}
+
+// QueryTxReceipts is the RPC method to query the states of main chain transactions in batch.
+func (s *ChainRPCService) QueryTxReceipts(req *QueryTxReceiptsReq, resp *QueryTxReceiptsResp) (err error) {
+	resp.Receipts, err = s.chain.queryTxReceipts(req.Hashes)
+	return
+}
+
+// QueryDatabaseUsage is the RPC method to query the accumulated billing usage of a database.
+func (s *ChainRPCService) QueryDatabaseUsage(
+	req *QueryDatabaseUsageReq, resp *QueryDatabaseUsageResp) (err error,
+) {
+	resp.Usage, err = s.chain.queryDatabaseUsage(req.DBID)
+	return
+}
+
+// RegisterMiner is the RPC method to register or deregister a miner, the transaction is applied
+// synchronously so that an invalid request is reported to the caller.
+func (s *ChainRPCService) RegisterMiner(req *RegisterMinerReq, resp *RegisterMinerResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QueryMiners is the RPC method to query the miners registered by produced blocks.
+func (s *ChainRPCService) QueryMiners(req *QueryMinersReq, resp *QueryMinersResp) (err error) {
+	resp.Miners = s.chain.ms.loadConfirmedMiners()
+	return
+}
+
+// ChallengeBilling is the RPC method to challenge a billing on behalf of the database owner.
+func (s *ChainRPCService) ChallengeBilling(req *ChallengeBillingReq, resp *ChallengeBillingResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// ProveBilling is the RPC method to answer a billing challenge with the acks of billed queries.
+func (s *ChainRPCService) ProveBilling(req *ProveBillingReq, resp *ProveBillingResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QueryBilling is the RPC method to query the dispute state of a billing, which is polled by
+// miners to find the challenges to prove.
+func (s *ChainRPCService) QueryBilling(req *QueryBillingReq, resp *QueryBillingResp) (err error) {
+	var loaded bool
+	if resp.Billing, loaded = s.chain.ms.loadConfirmedBilling(req.RequestHash); !loaded {
+		err = ErrBillingNotFound
+	}
+	return
+}
+
+// QueryFeeEstimate is the RPC method to estimate the fee rates required by pending transactions.
+func (s *ChainRPCService) QueryFeeEstimate(req *QueryFeeEstimateReq, resp *QueryFeeEstimateResp) error {
+	resp.Estimate = s.chain.ms.estimateFee(maxTransactionsPerBlock, maxAccountTransactionsPerBlock)
+	return nil
+}
+
+// CreateMultiSig is the RPC method to create a multi-signature account.
+func (s *ChainRPCService) CreateMultiSig(req *CreateMultiSigReq, resp *CreateMultiSigResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// ProposeMultiSig is the RPC method to propose an action of a multi-signature account on behalf
+// of an owner.
+func (s *ChainRPCService) ProposeMultiSig(req *ProposeMultiSigReq, resp *ProposeMultiSigResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// ApproveMultiSig is the RPC method to approve a pending action of a multi-signature account on
+// behalf of an owner.
+func (s *ChainRPCService) ApproveMultiSig(req *ApproveMultiSigReq, resp *ApproveMultiSigResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QueryMultiSig is the RPC method to query the owners and pending proposals of a multi-signature
+// account.
+func (s *ChainRPCService) QueryMultiSig(req *QueryMultiSigReq, resp *QueryMultiSigResp) (err error) {
+	var loaded bool
+	if resp.Profile, loaded = s.chain.ms.loadConfirmedMultiSig(req.Addr); !loaded {
+		err = ErrMultiSigNotFound
+	}
+	return
+}
+
+// SubmitMinerEvidence is the RPC method to report the conflicting responses signed by a miner.
+func (s *ChainRPCService) SubmitMinerEvidence(req *SubmitMinerEvidenceReq, resp *SubmitMinerEvidenceResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// ChallengeService is the RPC method to challenge a miner refusing to serve a request.
+func (s *ChainRPCService) ChallengeService(req *ChallengeServiceReq, resp *ChallengeServiceResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// ProveService is the RPC method to answer a service challenge with the response of the miner.
+func (s *ChainRPCService) ProveService(req *ProveServiceReq, resp *ProveServiceResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// UpdateParams is the RPC method to vote for a chain parameters update on behalf of an authority.
+func (s *ChainRPCService) UpdateParams(req *UpdateParamsReq, resp *UpdateParamsResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QueryParams is the RPC method to query the chain parameters in effect and the updates
+// scheduled or waiting for votes.
+func (s *ChainRPCService) QueryParams(req *QueryParamsReq, resp *QueryParamsResp) (err error) {
+	resp.Params = s.chain.ms.activeParams()
+	resp.Updates = s.chain.ms.loadConfirmedParams()
+	return
+}
+
+// QueryChainMode is the RPC method to query the block history kept by the node, so that the
+// historical queries could be routed to archival nodes.
+func (s *ChainRPCService) QueryChainMode(req *QueryChainModeReq, resp *QueryChainModeResp) (err error) {
+	resp.Mode, resp.PrunedHeight = s.chain.chainMode()
+	resp.KeepBlocks = s.chain.keepBlocks
+	resp.Height = s.chain.st.getHeight()
+	return
+}
+
+// QueryAccountProof is the RPC method to query an account with the proof of its inclusion in the
+// state committed by the head block, so that light clients could verify the account balances.
+func (s *ChainRPCService) QueryAccountProof(
+	req *QueryAccountProofReq, resp *QueryAccountProofResp) (err error,
+) {
+	resp.Account, resp.Proof, err = s.chain.proveAccount(req.Addr)
+	return
+}
+
+// QueryDatabaseProof is the RPC method to query a database profile with the proof of its
+// inclusion in the state committed by the head block, so that light clients could verify the
+// database owner.
+func (s *ChainRPCService) QueryDatabaseProof(
+	req *QueryDatabaseProofReq, resp *QueryDatabaseProofResp) (err error,
+) {
+	resp.Profile, resp.Proof, err = s.chain.proveDatabase(req.DBID)
+	return
+}
+
+// QueryAccountEvents is the RPC method to query the events touching an account, the request is
+// held until new events arrive or the wait times out, so it could be used as a long-poll
+// subscription.
+func (s *ChainRPCService) QueryAccountEvents(
+	req *QueryAccountEventsReq, resp *QueryAccountEventsResp) (err error,
+) {
+	resp.Events, err = s.chain.waitAccountEvents(req.Addr, req.Since, req.Limit, req.Wait)
+	return
+}
+
+// AdviseCheckpoint is the RPC method to advise the signature of a peer on a checkpoint.
+func (s *ChainRPCService) AdviseCheckpoint(req *AdviseCheckpointReq, resp *AdviseCheckpointResp) error {
+	return s.chain.addCheckpointSignature(&req.Header, req.Signature)
+}
+
+// QueryCheckpoint is the RPC method to query a checkpoint finalized by a quorum of block
+// producers, the checkpointed block and its ancestors are never reverted.
+func (s *ChainRPCService) QueryCheckpoint(req *QueryCheckpointReq, resp *QueryCheckpointResp) (err error) {
+	resp.Checkpoint, err = s.chain.loadCheckpoint(req.Height)
+	return
+}
+
+// CreateVesting is the RPC method to lock tokens to a beneficiary with a release schedule.
+func (s *ChainRPCService) CreateVesting(req *CreateVestingReq, resp *CreateVestingResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// ReleaseVesting is the RPC method to withdraw vested tokens on behalf of the beneficiary.
+func (s *ChainRPCService) ReleaseVesting(req *ReleaseVestingReq, resp *ReleaseVestingResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// RevokeVesting is the RPC method to refund the unvested tokens of a revocable vesting on behalf
+// of the creator.
+func (s *ChainRPCService) RevokeVesting(req *RevokeVestingReq, resp *RevokeVestingResp) (err error) {
+	if req.Tx == nil {
+		return ErrUnknownTransactionType
+	}
+	return s.submitTx(&req.Envelope, req.Tx)
+}
+
+// QueryVesting is the RPC method to query the schedule and released tokens of a vesting.
+func (s *ChainRPCService) QueryVesting(req *QueryVestingReq, resp *QueryVestingResp) (err error) {
+	var loaded bool
+	if resp.Profile, loaded = s.chain.ms.loadConfirmedVesting(req.Vesting); !loaded {
+		return ErrVestingNotFound
+	}
+	resp.Height = s.chain.st.getHeight()
+	resp.Releasable = resp.Profile.Releasable(resp.Height)
+	return
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"reflect"
+	"testing"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestSimulateTxReqCodec(t *testing.T) {
+	priv, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx := &pt.Transfer{
+		TransferHeader: pt.TransferHeader{
+			Sender:    proto.AccountAddress{0x1},
+			Receiver:  proto.AccountAddress{0x2},
+			Nonce:     1,
+			Amount:    100,
+			TokenType: pt.CovenantCoin,
+			Fee:       10,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the transaction is decoded as the concrete transaction
+	req := &SimulateTxReq{Tx: tx}
+	req.SetTTL(1)
+	enc, err := utils.EncodeMsgPack(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dec := &SimulateTxReq{}
+	if err = utils.DecodeMsgPack(enc.Bytes(), dec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decTx, ok := dec.Tx.(*pt.Transfer)
+	if !ok {
+		t.Fatalf("unexpected transaction type: %T", dec.Tx)
+	}
+	if err = decTx.Verify(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decTx.TransferHeader, tx.TransferHeader) {
+		t.Fatalf("unexpected transfer header: %v", decTx.TransferHeader)
+	}
+	if dec.GetTTL() != 1 || decTx.GetFee() != 10 {
+		t.Fatalf("unexpected request: %v", dec)
+	}
+
+	// request without transaction
+	if enc, err = utils.EncodeMsgPack(&SimulateTxReq{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = utils.DecodeMsgPack(enc.Bytes(), dec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dec.Tx != nil {
+		t.Fatalf("unexpected transaction: %v", dec.Tx)
+	}
+}
@@ -26,6 +26,14 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/rpc"
 )
 
+// periodChange defines the block producing cycle from height on, start is the time of the
+// block at height.
+type periodChange struct {
+	height uint32
+	start  time.Time
+	period time.Duration
+}
+
 // copy from /sqlchain/runtime.go
 // rt define the runtime of main chain.
 type rt struct {
@@ -42,8 +50,12 @@ type rt struct {
 	// index is the index of the current server in the peer list.
 	index uint32
 
-	// period is the block producing cycle.
+	// period is the block producing cycle before it's changed by chain parameter updates.
 	period time.Duration
+	// periodsMutex protects following periods field.
+	periodsMutex sync.RWMutex
+	// periods lists the block producing cycles sorted by height, starting from the genesis block.
+	periods []periodChange
 	// tick defines the maximum duration between each cycle.
 	tick time.Duration
 
@@ -88,6 +100,7 @@ func newRuntime(cfg *Config, accountAddress proto.AccountAddress) *rt {
 		bpNum:          uint32(len(cfg.Peers.Servers)),
 		index:          index,
 		period:         cfg.Period,
+		periods:        []periodChange{{start: cfg.Genesis.SignedHeader.Timestamp, period: cfg.Period}},
 		tick:           cfg.Tick,
 		peers:          cfg.Peers,
 		nodeID:         cfg.NodeID,
@@ -105,7 +118,7 @@ func (r *rt) startService(chain *Chain) {
 // caused by concurrently time synchronization.
 func (r *rt) nextTick() (t time.Time, d time.Duration) {
 	t = r.now()
-	d = r.chainInitTime.Add(time.Duration(r.nextTurn) * r.period).Sub(t)
+	d = r.getTimeFromHeight(r.nextTurn).Sub(t)
 
 	if d > r.tick {
 		d = r.tick
@@ -138,7 +151,48 @@ func (r *rt) getPeerInfoString() string {
 
 // getHeightFromTime calculates the height with this sql-chain config of a given time reading.
 func (r *rt) getHeightFromTime(t time.Time) uint32 {
-	return uint32(t.Sub(r.chainInitTime) / r.period)
+	r.periodsMutex.RLock()
+	defer r.periodsMutex.RUnlock()
+	p := r.periods[0]
+	for _, v := range r.periods[1:] {
+		if v.start.After(t) {
+			break
+		}
+		p = v
+	}
+	return p.height + uint32(t.Sub(p.start)/p.period)
+}
+
+// getTimeFromHeight calculates the producing time of the block at height h.
+func (r *rt) getTimeFromHeight(h uint32) time.Time {
+	r.periodsMutex.RLock()
+	defer r.periodsMutex.RUnlock()
+	p := r.periods[0]
+	for _, v := range r.periods[1:] {
+		if v.height > h {
+			break
+		}
+		p = v
+	}
+	return p.start.Add(time.Duration(h-p.height) * p.period)
+}
+
+// setPeriods replaces the block producing cycles changed by chain parameter updates, changes
+// should be sorted by height and the start time of each change is calculated here.
+func (r *rt) setPeriods(changes []periodChange) {
+	periods := []periodChange{{start: r.chainInitTime, period: r.period}}
+	for _, v := range changes {
+		last := &periods[len(periods)-1]
+		if v.height == last.height {
+			last.period = v.period
+			continue
+		}
+		v.start = last.start.Add(time.Duration(v.height-last.height) * last.period)
+		periods = append(periods, v)
+	}
+	r.periodsMutex.Lock()
+	defer r.periodsMutex.Unlock()
+	r.periods = periods
 }
 
 func (r *rt) getNextTurn() uint32 {
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuntimePeriods(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &rt{
+		chainInitTime: start,
+		period:        time.Second,
+		periods:       []periodChange{{start: start, period: time.Second}},
+	}
+	if h := r.getHeightFromTime(start.Add(15 * time.Second)); h != 15 {
+		t.Fatalf("unexpected height: %d", h)
+	}
+
+	// 1s until height 10, 2s until height 20, and 500ms from then on
+	r.setPeriods([]periodChange{
+		{height: 10, period: 2 * time.Second},
+		{height: 20, period: 500 * time.Millisecond},
+	})
+	for _, v := range []struct {
+		height  uint32
+		elapsed time.Duration
+	}{
+		{0, 0},
+		{5, 5 * time.Second},
+		{10, 10 * time.Second},
+		{15, 20 * time.Second},
+		{20, 30 * time.Second},
+		{24, 32 * time.Second},
+	} {
+		if tm := r.getTimeFromHeight(v.height); !tm.Equal(start.Add(v.elapsed)) {
+			t.Fatalf("unexpected time of height %d: %v", v.height, tm)
+		}
+		if h := r.getHeightFromTime(start.Add(v.elapsed)); h != v.height {
+			t.Fatalf("unexpected height at %v: %d", v.elapsed, h)
+		}
+	}
+	if h := r.getHeightFromTime(start.Add(21 * time.Second)); h != 15 {
+		t.Fatalf("unexpected height: %d", h)
+	}
+
+	// changes are dropped with the updates
+	r.setPeriods(nil)
+	if h := r.getHeightFromTime(start.Add(30 * time.Second)); h != 30 {
+		t.Fatalf("unexpected height: %d", h)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"bytes"
+	"sort"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// stateTree defines the merkle tree of the committed accounts and databases, the leaves are the
+// accounts sorted by address followed by the databases sorted by id. The root is committed by
+// the MetaRoot of the next block header, which is set to header once the block is pushed.
+type stateTree struct {
+	header    *pt.SignedHeader
+	tree      *merkle.Merkle
+	accounts  []pt.Account
+	databases []pt.SQLChainProfile
+}
+
+// newStateTree builds the state tree of the objects of snapshot snap.
+func newStateTree(snap *pt.StateSnapshot) (t *stateTree, err error) {
+	var (
+		leaves = make([]*hash.Hash, 0, len(snap.Accounts)+len(snap.Databases))
+		leaf   hash.Hash
+	)
+	for i := range snap.Accounts {
+		if leaf, err = pt.AccountLeaf(&snap.Accounts[i]); err != nil {
+			return
+		}
+		h := leaf
+		leaves = append(leaves, &h)
+	}
+	for i := range snap.Databases {
+		if leaf, err = pt.DatabaseLeaf(&snap.Databases[i]); err != nil {
+			return
+		}
+		h := leaf
+		leaves = append(leaves, &h)
+	}
+	t = &stateTree{
+		tree:      merkle.NewMerkle(leaves),
+		accounts:  snap.Accounts,
+		databases: snap.Databases,
+	}
+	return
+}
+
+func (t *stateTree) root() hash.Hash {
+	return *t.tree.GetRoot()
+}
+
+func (t *stateTree) proof(index uint64) (proof *pt.StateProof, err error) {
+	path, ok := t.tree.GetProof(index)
+	if !ok || t.header == nil {
+		err = ErrStateProofUnavailable
+		return
+	}
+	proof = &pt.StateProof{
+		Header: *t.header,
+		Index:  index,
+		Path:   make([]hash.Hash, len(path)),
+	}
+	for i, h := range path {
+		proof.Path[i] = *h
+	}
+	return
+}
+
+// proveAccount returns account addr with its inclusion proof.
+func (t *stateTree) proveAccount(addr proto.AccountAddress) (
+	account pt.Account, proof *pt.StateProof, err error,
+) {
+	i := sort.Search(len(t.accounts), func(i int) bool {
+		return bytes.Compare(t.accounts[i].Address[:], addr[:]) >= 0
+	})
+	if i >= len(t.accounts) || t.accounts[i].Address != addr {
+		err = ErrAccountNotFound
+		return
+	}
+	if proof, err = t.proof(uint64(i)); err != nil {
+		return
+	}
+	account = t.accounts[i]
+	return
+}
+
+// proveDatabase returns the profile of database id with its inclusion proof.
+func (t *stateTree) proveDatabase(id proto.DatabaseID) (
+	profile pt.SQLChainProfile, proof *pt.StateProof, err error,
+) {
+	i := sort.Search(len(t.databases), func(i int) bool {
+		return t.databases[i].ID >= id
+	})
+	if i >= len(t.databases) || t.databases[i].ID != id {
+		err = ErrDatabaseNotFound
+		return
+	}
+	if proof, err = t.proof(uint64(len(t.accounts) + i)); err != nil {
+		return
+	}
+	profile = t.databases[i]
+	return
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"testing"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestStateTree(t *testing.T) {
+	priv, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var (
+		ms    = newMetaState()
+		addr1 = proto.AccountAddress{0x1}
+		addr2 = proto.AccountAddress{0x2}
+		dbid  = proto.DatabaseID("db#1")
+	)
+	for _, addr := range []proto.AccountAddress{addr2, addr1} {
+		ms.readonly.accounts[addr] = &accountObject{
+			Account: pt.Account{Address: addr, StableCoinBalance: 100},
+		}
+	}
+	ms.readonly.databases[dbid] = &sqlchainObject{
+		SQLChainProfile: pt.SQLChainProfile{ID: dbid, Owner: addr1},
+	}
+
+	tree, err := newStateTree(ms.snapshot())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err = tree.proveAccount(addr1); err != ErrStateProofUnavailable {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := &pt.Block{SignedHeader: pt.SignedHeader{Header: pt.Header{MetaRoot: tree.root()}}}
+	if err = b.PackAndSignBlock(priv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tree.header = &b.SignedHeader
+
+	for _, addr := range []proto.AccountAddress{addr1, addr2} {
+		account, proof, err := tree.proveAccount(addr)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if account.Address != addr || account.StableCoinBalance != 100 {
+			t.Fatalf("unexpected account: %v", account)
+		}
+		if err = proof.VerifyAccount(&account); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	profile, proof, err := tree.proveDatabase(dbid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = proof.VerifyDatabase(&profile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Owner != addr1 {
+		t.Fatalf("unexpected owner: %v", profile.Owner)
+	}
+
+	if _, _, err = tree.proveAccount(proto.AccountAddress{0x3}); err != ErrAccountNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err = tree.proveDatabase("db#2"); err != ErrDatabaseNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -102,6 +102,21 @@ func (ti *txIndex) getTxBilling(h *hash.Hash) *types.TxBilling {
 	return val
 }
 
+// fetchDatabaseTxBillings fetch all txbillings of specific databaseID in index.
+func (ti *txIndex) fetchDatabaseTxBillings(databaseID proto.DatabaseID) []*types.TxBilling {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	txes := make([]*types.TxBilling, 0)
+
+	for _, t := range ti.billingHashIndex {
+		if t != nil && t.TxContent.BillingRequest.Header.DatabaseID == databaseID {
+			txes = append(txes, t)
+		}
+	}
+	return txes
+}
+
 // lastSequenceID look up the last sequenceID of specific databaseID.
 func (ti *txIndex) lastSequenceID(databaseID *proto.DatabaseID) (uint32, error) {
 	if seqID, ok := ti.lastBillingIndex[databaseID]; ok {
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
 )
 
 func Test_AddAndHasTxBilling(t *testing.T) {
@@ -64,3 +65,31 @@ func Test_AddAndHasTxBilling(t *testing.T) {
 		}
 	}
 }
+
+func Test_FetchDatabaseTxBillings(t *testing.T) {
+	ti := newTxIndex()
+	tbs := make([]*types.TxBilling, 10)
+	for i := range tbs {
+		tb, err := generateRandomTxBilling()
+		if err != nil {
+			t.Fatalf("unexpect error: %v", err)
+		}
+		if i%2 == 0 {
+			tb.TxContent.BillingRequest.Header.DatabaseID = proto.DatabaseID("db")
+		}
+		tbs[i] = tb
+		if err = ti.addTxBilling(tb); err != nil {
+			t.Fatalf("unexpect error: %v", err)
+		}
+	}
+
+	fetchedTbs := ti.fetchDatabaseTxBillings(proto.DatabaseID("db"))
+	if len(fetchedTbs) != len(tbs)/2 {
+		t.Fatalf("unexpected billing count: %d", len(fetchedTbs))
+	}
+	for _, tb := range fetchedTbs {
+		if tb.TxContent.BillingRequest.Header.DatabaseID != proto.DatabaseID("db") {
+			t.Fatalf("unexpected billing of database %s", tb.TxContent.BillingRequest.Header.DatabaseID)
+		}
+	}
+}
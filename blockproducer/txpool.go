@@ -17,7 +17,12 @@
 package blockproducer
 
 import (
+	"bytes"
+	"math"
+	"sort"
+
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 )
 
@@ -47,14 +52,26 @@ func (e *accountTxEntries) addTx(tx pi.Transaction) {
 
 type txPool struct {
 	entries map[proto.AccountAddress]*accountTxEntries
+	// txs keeps all the transactions in the order they are applied.
+	txs []pi.Transaction
+	// queued keeps the transactions arrived ahead of their nonces, which are not applied yet.
+	queued map[proto.AccountAddress]map[pi.AccountNonce]pi.Transaction
 }
 
 func newTxPool() *txPool {
 	return &txPool{
 		entries: make(map[proto.AccountAddress]*accountTxEntries),
+		queued:  make(map[proto.AccountAddress]map[pi.AccountNonce]pi.Transaction),
 	}
 }
 
+// reset returns an empty pool which keeps the queued transactions of p.
+func (p *txPool) reset() *txPool {
+	n := newTxPool()
+	n.queued = p.queued
+	return n
+}
+
 func (p *txPool) addTx(tx pi.Transaction, baseNonce pi.AccountNonce) {
 	addr := tx.GetAccountAddress()
 	e, ok := p.entries[addr]
@@ -63,9 +80,139 @@ func (p *txPool) addTx(tx pi.Transaction, baseNonce pi.AccountNonce) {
 		p.entries[addr] = e
 	}
 	e.addTx(tx)
+	p.txs = append(p.txs, tx)
+}
+
+func (p *txPool) hasTx(h hash.Hash) bool {
+	for _, e := range p.entries {
+		for _, tx := range e.transacions {
+			if tx.GetHash() == h {
+				return true
+			}
+		}
+	}
+	for _, q := range p.queued {
+		for _, tx := range q {
+			if tx.GetHash() == h {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queueTx holds tx until the transactions before its nonce are applied, it returns false if
+// another transaction with the same nonce is already queued.
+func (p *txPool) queueTx(tx pi.Transaction) bool {
+	var (
+		addr  = tx.GetAccountAddress()
+		nonce = tx.GetAccountNonce()
+	)
+	q, ok := p.queued[addr]
+	if !ok {
+		q = make(map[pi.AccountNonce]pi.Transaction)
+		p.queued[addr] = q
+	}
+	if _, ok = q[nonce]; ok {
+		return false
+	}
+	q[nonce] = tx
+	return true
+}
+
+// popQueuedTx removes and returns the queued transaction of account addr with nonce.
+func (p *txPool) popQueuedTx(addr proto.AccountAddress, nonce pi.AccountNonce) (tx pi.Transaction, ok bool) {
+	q := p.queued[addr]
+	if tx, ok = q[nonce]; ok {
+		delete(q, nonce)
+		if len(q) == 0 {
+			delete(p.queued, addr)
+		}
+	}
+	return
+}
+
+// dropQueuedTxs removes and returns the queued transactions of account addr with nonces before
+// next, which could never be applied.
+func (p *txPool) dropQueuedTxs(addr proto.AccountAddress, next pi.AccountNonce) (dropped []pi.Transaction) {
+	q := p.queued[addr]
+	for nonce, tx := range q {
+		if nonce < next {
+			delete(q, nonce)
+			dropped = append(dropped, tx)
+		}
+	}
+	if len(q) == 0 {
+		delete(p.queued, addr)
+	}
+	return
+}
+
+// queuedNonces returns the nonces of the queued transactions of account addr in ascending order.
+func (p *txPool) queuedNonces(addr proto.AccountAddress) (nonces []pi.AccountNonce) {
+	for nonce := range p.queued[addr] {
+		nonces = append(nonces, nonce)
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	return
 }
 
 func (p *txPool) getTxEntries(addr proto.AccountAddress) (e *accountTxEntries, ok bool) {
 	e, ok = p.entries[addr]
 	return
 }
+
+func (p *txPool) size() int {
+	return len(p.txs)
+}
+
+// txFeeRate returns the fee of tx per kilobyte of its serialized form.
+func txFeeRate(tx pi.Transaction) (rate uint64) {
+	enc, err := tx.Serialize()
+	if err != nil || len(enc) == 0 {
+		return
+	}
+	if fee := tx.GetFee(); fee > math.MaxUint64>>10 {
+		rate = fee / uint64(len(enc)) << 10
+	} else {
+		rate = fee << 10 / uint64(len(enc))
+	}
+	return
+}
+
+// packTxs selects at most limit transactions in descending order of fee rate, and at most
+// accountLimit transactions of each account to keep the packing fair. The transactions of an
+// account are always selected in nonce order, so a transaction with higher fee rate may wait for
+// the earlier ones of the same account. The selected transactions are returned with their fee
+// rates in the order of selection.
+func (p *txPool) packTxs(limit, accountLimit int) (packed []pi.Transaction, rates []uint64) {
+	type head struct {
+		e     *accountTxEntries
+		index int
+		rate  uint64
+	}
+	var heads []*head
+	for _, e := range p.entries {
+		if len(e.transacions) > 0 {
+			heads = append(heads, &head{e: e, rate: txFeeRate(e.transacions[0])})
+		}
+	}
+	for len(packed) < limit && len(heads) > 0 {
+		// ties are broken by account address, so every block producer packs the same way
+		sort.Slice(heads, func(i, j int) bool {
+			if heads[i].rate != heads[j].rate {
+				return heads[i].rate > heads[j].rate
+			}
+			return bytes.Compare(heads[i].e.account[:], heads[j].e.account[:]) < 0
+		})
+		h := heads[0]
+		packed = append(packed, h.e.transacions[h.index])
+		rates = append(rates, h.rate)
+		if h.index++; h.index < len(h.e.transacions) && h.index < accountLimit {
+			h.rate = txFeeRate(h.e.transacions[h.index])
+		} else {
+			heads = heads[1:]
+		}
+	}
+	return
+}
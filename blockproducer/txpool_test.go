@@ -15,3 +15,58 @@
  */
 
 package blockproducer
+
+import (
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTxPool(t *testing.T) {
+	Convey("Given a tx pool with transactions of different fees", t, func() {
+		var (
+			addr1 = proto.AccountAddress{0x0, 0x0, 0x0, 0x1}
+			addr2 = proto.AccountAddress{0x0, 0x0, 0x0, 0x2}
+			addr3 = proto.AccountAddress{0x0, 0x0, 0x0, 0x3}
+			pool  = newTxPool()
+			newTx = func(sender proto.AccountAddress, nonce pi.AccountNonce, fee uint64) (tx *pt.Transfer) {
+				tx = &pt.Transfer{
+					TransferHeader: pt.TransferHeader{
+						Sender:   sender,
+						Receiver: addr1,
+						Nonce:    nonce,
+						Amount:   1,
+						Fee:      fee,
+					},
+				}
+				So(tx.Sign(testPrivKey), ShouldBeNil)
+				return
+			}
+			a0, a1, a2 = newTx(addr1, 0, 0), newTx(addr1, 1, 0), newTx(addr1, 2, 0)
+			b0, b1     = newTx(addr2, 0, 100), newTx(addr2, 1, 1000)
+			c0         = newTx(addr3, 0, 50)
+		)
+		for _, tx := range []pi.Transaction{a0, b0, a1, c0, a2, b1} {
+			pool.addTx(tx, 0)
+		}
+		So(pool.size(), ShouldEqual, 6)
+		So(txFeeRate(a0), ShouldEqual, 0)
+		So(txFeeRate(b0), ShouldBeGreaterThan, txFeeRate(c0))
+		Convey("The pool should pack transactions by fee rate in nonce order", func() {
+			packed, rates := pool.packTxs(3, 10)
+			So(packed, ShouldResemble, []pi.Transaction{b0, b1, c0})
+			So(rates, ShouldResemble, []uint64{txFeeRate(b0), txFeeRate(b1), txFeeRate(c0)})
+			packed, _ = pool.packTxs(10, 10)
+			So(packed, ShouldResemble, []pi.Transaction{b0, b1, c0, a0, a1, a2})
+		})
+		Convey("The pool should limit transactions of each account", func() {
+			packed, _ := pool.packTxs(10, 1)
+			So(packed, ShouldResemble, []pi.Transaction{b0, c0, a0})
+			packed, _ = pool.packTxs(10, 2)
+			So(packed, ShouldResemble, []pi.Transaction{b0, b1, c0, a0, a1})
+		})
+	})
+}
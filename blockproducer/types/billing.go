@@ -34,6 +34,9 @@ type BillingRequestHeader struct {
 	HighBlock  hash.Hash
 	HighHeight int32
 	GasAmounts []*proto.AddrAndGas
+	// ReadCount and WriteCount define the acked queries within the height range
+	ReadCount  uint64
+	WriteCount uint64
 }
 
 //
@@ -78,8 +78,8 @@ func (z *BillingRequest) Msgsize() (s int) {
 func (z *BillingRequestHeader) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 6
-	o = append(o, 0x86, 0x86)
+	// map header, size 8
+	o = append(o, 0x88, 0x88)
 	o = hsp.AppendArrayHeader(o, uint32(len(z.GasAmounts)))
 	for za0001 := range z.GasAmounts {
 		if z.GasAmounts[za0001] == nil {
@@ -92,23 +92,27 @@ func (z *BillingRequestHeader) MarshalHash() (o []byte, err error) {
 			}
 		}
 	}
-	o = append(o, 0x86)
+	o = append(o, 0x88)
 	if oTemp, err := z.LowBlock.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x86)
+	o = append(o, 0x88)
 	if oTemp, err := z.HighBlock.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x86)
+	o = append(o, 0x88)
 	o = hsp.AppendInt32(o, z.LowHeight)
-	o = append(o, 0x86)
+	o = append(o, 0x88)
 	o = hsp.AppendInt32(o, z.HighHeight)
-	o = append(o, 0x86)
+	o = append(o, 0x88)
+	o = hsp.AppendUint64(o, z.ReadCount)
+	o = append(o, 0x88)
+	o = hsp.AppendUint64(o, z.WriteCount)
+	o = append(o, 0x88)
 	if oTemp, err := z.DatabaseID.MarshalHash(); err != nil {
 		return nil, err
 	} else {
@@ -127,7 +131,7 @@ func (z *BillingRequestHeader) Msgsize() (s int) {
 			s += z.GasAmounts[za0001].Msgsize()
 		}
 	}
-	s += 9 + z.LowBlock.Msgsize() + 10 + z.HighBlock.Msgsize() + 10 + hsp.Int32Size + 11 + hsp.Int32Size + 11 + z.DatabaseID.Msgsize()
+	s += 9 + z.LowBlock.Msgsize() + 10 + z.HighBlock.Msgsize() + 10 + hsp.Int32Size + 11 + hsp.Int32Size + 10 + hsp.Uint64Size + 11 + hsp.Uint64Size + 11 + z.DatabaseID.Msgsize()
 	return
 }
 
@@ -35,7 +35,13 @@ type Header struct {
 	Producer   proto.AccountAddress
 	MerkleRoot hash.Hash
 	ParentHash hash.Hash
-	Timestamp  time.Time
+	// StateRoot defines the hash of state snapshot after the parent block, which is only set
+	// for blocks at snapshot heights, see StateSnapshot.
+	StateRoot hash.Hash
+	// MetaRoot defines the merkle root of the accounts and databases committed by the parent
+	// block, which is used to prove the state objects to light clients, see StateProof.
+	MetaRoot  hash.Hash
+	Timestamp time.Time
 }
 
 // SignedHeader defines the main chain header with the signature.
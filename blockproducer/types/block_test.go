@@ -23,6 +23,7 @@ import (
 
 	"bytes"
 
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/utils"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -115,6 +116,41 @@ func TestBlock_MarshalUnmarshalBinary(t *testing.T) {
 	}
 }
 
+func TestBlock_MarshalTransactions(t *testing.T) {
+	block, err := generateRandomBlock(genesisHash, false)
+	if err != nil {
+		t.Fatalf("Failed to generate block: %v", err)
+	}
+	block.Transactions = []pi.Transaction{
+		&Transfer{TransferHeader: TransferHeader{Nonce: 1, Amount: 10, TokenType: CovenantCoin}},
+		&CreateVesting{CreateVestingHeader: CreateVestingHeader{Nonce: 2, Amount: 20}},
+	}
+
+	enc, err := block.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to mashal binary: %v", err)
+	}
+	dec := &Block{}
+	if err = dec.Deserialize(enc); err != nil {
+		t.Fatalf("Failed to unmashal binary: %v", err)
+	}
+	if !reflect.DeepEqual(block, dec) {
+		t.Fatalf("Value not math:\n\tv1 = %+v\n\tv2 = %+v", block, dec)
+	}
+
+	// unknown transaction type
+	buf, err := utils.EncodeMsgPack(&encodedBlock{
+		SignedHeader: block.SignedHeader,
+		Transactions: []packedTx{{Type: pi.TransactionTypeNumber}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to mashal binary: %v", err)
+	}
+	if err = dec.Deserialize(buf.Bytes()); err == nil {
+		t.Fatal("Unexpeted decoded block with unknown transaction type")
+	}
+}
+
 func TestBlock_PackAndSignBlock(t *testing.T) {
 	block, err := generateRandomBlock(genesisHash, false)
 	if err != nil {
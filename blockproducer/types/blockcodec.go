@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/ugorji/go/codec"
+)
+
+// NewTransaction returns an empty transaction of type t to be deserialized.
+func NewTransaction(t pi.TransactionType) (tx pi.Transaction, err error) {
+	switch t {
+	case pi.TransactionTypeBilling:
+		tx = &TxBilling{}
+	case pi.TransactionTypeTransfer:
+		tx = &Transfer{}
+	case pi.TransactionTypeAlterDatabaseUser, pi.TransactionTypeDeleteDatabaseUser:
+		tx = &UpdatePermission{}
+	case pi.TransactionTypeRegisterMiner, pi.TransactionTypeDeregisterMiner:
+		tx = &RegisterMiner{}
+	case pi.TransactionTypeBillingChallenge:
+		tx = &BillingChallenge{}
+	case pi.TransactionTypeBillingProof:
+		tx = &BillingProof{}
+	case pi.TransactionTypeCreateMultiSig:
+		tx = &CreateMultiSig{}
+	case pi.TransactionTypeProposeMultiSig:
+		tx = &ProposeMultiSig{}
+	case pi.TransactionTypeApproveMultiSig:
+		tx = &ApproveMultiSig{}
+	case pi.TransactionTypeMinerEvidence:
+		tx = &MinerEvidence{}
+	case pi.TransactionTypeServiceChallenge:
+		tx = &ServiceChallenge{}
+	case pi.TransactionTypeServiceProof:
+		tx = &ServiceProof{}
+	case pi.TransactionTypeUpdateParams:
+		tx = &UpdateParams{}
+	case pi.TransactionTypeCreateVesting:
+		tx = &CreateVesting{}
+	case pi.TransactionTypeReleaseVesting:
+		tx = &ReleaseVesting{}
+	case pi.TransactionTypeRevokeVesting:
+		tx = &RevokeVesting{}
+	default:
+		err = ErrUnknownTransactionType
+	}
+	return
+}
+
+// packedTx defines a transaction packed in block, which is encoded with its type so that it's
+// decoded as the concrete transaction.
+type packedTx struct {
+	Type pi.TransactionType
+	Tx   []byte
+}
+
+// encodedBlock defines the wire format of Block.
+type encodedBlock struct {
+	SignedHeader SignedHeader
+	TxBillings   []*TxBilling
+	Transactions []packedTx
+}
+
+// CodecEncodeSelf implements codec.Selfer.CodecEncodeSelf, the panics are recovered as errors
+// by the codec.
+func (b *Block) CodecEncodeSelf(e *codec.Encoder) {
+	var enc = &encodedBlock{
+		SignedHeader: b.SignedHeader,
+		TxBillings:   b.TxBillings,
+	}
+	if len(b.Transactions) > 0 {
+		enc.Transactions = make([]packedTx, len(b.Transactions))
+	}
+	for i, t := range b.Transactions {
+		buf, err := t.Serialize()
+		if err != nil {
+			panic(err)
+		}
+		enc.Transactions[i] = packedTx{Type: t.GetTransactionType(), Tx: buf}
+	}
+	e.MustEncode(enc)
+}
+
+// CodecDecodeSelf implements codec.Selfer.CodecDecodeSelf.
+func (b *Block) CodecDecodeSelf(d *codec.Decoder) {
+	var dec = &encodedBlock{}
+	d.MustDecode(dec)
+	b.SignedHeader, b.TxBillings, b.Transactions = dec.SignedHeader, dec.TxBillings, nil
+	for _, v := range dec.Transactions {
+		t, err := NewTransaction(v.Type)
+		if err != nil {
+			panic(err)
+		}
+		if err = t.Deserialize(v.Tx); err != nil {
+			panic(err)
+		}
+		b.Transactions = append(b.Transactions, t)
+	}
+}
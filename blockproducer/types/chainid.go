@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+// signedHash returns the hash signed by a transaction of chainID with the header encoded as enc.
+// The chain id is prepended to the header so that the signature is only valid on that chain, the
+// hash of a legacy transaction without chain id is the plain header hash as before.
+func signedHash(chainID pi.ChainID, enc []byte) hash.Hash {
+	if chainID == "" {
+		return hash.THashH(enc)
+	}
+	buf := make([]byte, 0, len(chainID)+1+len(enc))
+	buf = append(buf, chainID...)
+	// separates the chain id from header
+	buf = append(buf, 0)
+	return hash.THashH(append(buf, enc...))
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestTransfer_ChainID(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	var (
+		header = TransferHeader{
+			Sender:   proto.AccountAddress(hash.THashH(enc)),
+			Receiver: proto.AccountAddress{0x1},
+			Nonce:    1,
+			Amount:   10,
+		}
+		legacy  = &Transfer{TransferHeader: header}
+		testnet = &Transfer{TransferHeader: header, ChainID: "testnet"}
+	)
+	if err = legacy.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = testnet.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// legacy transaction is hashed as before
+	if enc, err = header.MarshalHash(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if h := hash.THashH(enc); legacy.GetHash() != h {
+		t.Fatalf("Unexpeted hash: %v", legacy.GetHash())
+	}
+	if legacy.GetHash() == testnet.GetHash() {
+		t.Fatal("chain id should change transaction hash")
+	}
+	if err = legacy.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = testnet.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// encode and decode
+	b, err := testnet.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	decoded := &Transfer{}
+	if err = decoded.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if decoded.GetChainID() != "testnet" {
+		t.Fatalf("Unexpeted chain id: %v", decoded.GetChainID())
+	}
+
+	// replay on other chain
+	testnet.ChainID = "mainnet"
+	if err = testnet.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	testnet.ChainID = ""
+	if err = testnet.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
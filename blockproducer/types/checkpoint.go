@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// CheckpointQuorum returns the signatures required to finalize a checkpoint by n block
+// producers, which is more than two thirds of them.
+func CheckpointQuorum(n int) int {
+	return n*2/3 + 1
+}
+
+// CheckpointHeader defines the block finalized by a checkpoint.
+type CheckpointHeader struct {
+	Height    uint32
+	BlockHash hash.Hash
+	MetaRoot  hash.Hash
+}
+
+// MarshalHash marshals for hash.
+func (h *CheckpointHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// CheckpointSignature defines the signature of a block producer on a checkpoint.
+type CheckpointSignature struct {
+	Signee    *asymmetric.PublicKey
+	Signature *asymmetric.Signature
+}
+
+// Checkpoint defines a block co-signed by block producers, the block and its ancestors are final
+// once the checkpoint is signed by a quorum of block producers.
+type Checkpoint struct {
+	CheckpointHeader
+	HeaderHash hash.Hash
+	Signatures []CheckpointSignature
+}
+
+// NewCheckpoint returns an unsigned checkpoint of header.
+func NewCheckpoint(header *CheckpointHeader) (c *Checkpoint, err error) {
+	var enc []byte
+	if enc, err = header.MarshalHash(); err != nil {
+		return
+	}
+	c = &Checkpoint{
+		CheckpointHeader: *header,
+		HeaderHash:       hash.THashH(enc),
+	}
+	return
+}
+
+// Sign signs the checkpoint and adds the signature.
+func (c *Checkpoint) Sign(signer *asymmetric.PrivateKey) (sig CheckpointSignature, err error) {
+	if sig.Signature, err = signer.Sign(c.HeaderHash[:]); err != nil {
+		return
+	}
+	sig.Signee = signer.PubKey()
+	_, err = c.AddSignature(sig)
+	return
+}
+
+// AddSignature verifies and adds sig to the checkpoint, it returns false if the signee has
+// already signed.
+func (c *Checkpoint) AddSignature(sig CheckpointSignature) (added bool, err error) {
+	if sig.Signee == nil || sig.Signature == nil || !sig.Signature.Verify(c.HeaderHash[:], sig.Signee) {
+		err = ErrSignVerification
+		return
+	}
+	for _, s := range c.Signatures {
+		if s.Signee.IsEqual(sig.Signee) {
+			return
+		}
+	}
+	c.Signatures = append(c.Signatures, sig)
+	added = true
+	return
+}
+
+// Verify checks that the checkpoint is signed by at least quorum distinct signees.
+func (c *Checkpoint) Verify(signees []*asymmetric.PublicKey, quorum int) (err error) {
+	var enc []byte
+	if enc, err = c.CheckpointHeader.MarshalHash(); err != nil {
+		return
+	} else if h := hash.THashH(enc); !c.HeaderHash.IsEqual(&h) {
+		return ErrHashVerification
+	}
+	var signed = make(map[int]bool)
+	for _, s := range c.Signatures {
+		if s.Signee == nil || s.Signature == nil || !s.Signature.Verify(c.HeaderHash[:], s.Signee) {
+			return ErrSignVerification
+		}
+		for i, k := range signees {
+			if k != nil && k.IsEqual(s.Signee) {
+				signed[i] = true
+				break
+			}
+		}
+	}
+	if len(signed) < quorum {
+		return ErrCheckpointQuorum
+	}
+	return
+}
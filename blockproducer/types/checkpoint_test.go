@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+func TestCheckpoint_Verify(t *testing.T) {
+	var (
+		privs   = make([]*asymmetric.PrivateKey, 4)
+		signees = make([]*asymmetric.PublicKey, 4)
+		err     error
+	)
+	for i := range privs {
+		if privs[i], signees[i], err = asymmetric.GenSecp256k1KeyPair(); err != nil {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+	}
+	quorum := CheckpointQuorum(len(signees))
+	if quorum != 3 {
+		t.Fatalf("Unexpeted quorum: %d", quorum)
+	}
+
+	c, err := NewCheckpoint(&CheckpointHeader{Height: 16, BlockHash: hash.Hash{0x1}})
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = c.Sign(privs[i]); err != nil {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+	}
+	// signing twice doesn't count
+	sig, err := c.Sign(privs[0])
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if len(c.Signatures) != 2 {
+		t.Fatalf("Unexpeted signatures: %d", len(c.Signatures))
+	}
+	if err = c.Verify(signees, quorum); err != ErrCheckpointQuorum {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// signatures of unknown signees don't count
+	other, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if _, err = c.Sign(other); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = c.Verify(signees, quorum); err != ErrCheckpointQuorum {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if _, err = c.Sign(privs[3]); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = c.Verify(signees, quorum); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// signatures of another checkpoint are rejected
+	d, err := NewCheckpoint(&CheckpointHeader{Height: 16, BlockHash: hash.Hash{0x2}})
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if _, err = d.AddSignature(sig); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	c.BlockHash = hash.Hash{0x2}
+	if err = c.Verify(signees, quorum); err != ErrHashVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
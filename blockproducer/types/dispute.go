@@ -0,0 +1,304 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+// BillingState defines the dispute state of a billing on main chain.
+type BillingState int32
+
+const (
+	// BillingStateSettled defines the state of a billing which is not challenged.
+	BillingStateSettled BillingState = iota
+	// BillingStateChallenged defines the state of a billing challenged by the database owner,
+	// which waits for the proof from miners.
+	BillingStateChallenged
+	// BillingStateProved defines the state of a challenged billing proved by miners.
+	BillingStateProved
+	// BillingStateRejected defines the state of a challenged billing not proved in time, the
+	// billed incomes are taken back and the miners are penalized.
+	BillingStateRejected
+)
+
+// String implements fmt.Stringer for BillingState.
+func (s BillingState) String() string {
+	switch s {
+	case BillingStateSettled:
+		return "Settled"
+	case BillingStateChallenged:
+		return "Challenged"
+	case BillingStateProved:
+		return "Proved"
+	case BillingStateRejected:
+		return "Rejected"
+	default:
+		return "Unknown"
+	}
+}
+
+// BillingProfile defines a billing applied on main chain and its dispute state.
+type BillingProfile struct {
+	RequestHash hash.Hash
+	DatabaseID  proto.DatabaseID
+	Receivers   []proto.AccountAddress
+	Fees        []uint64
+	Rewards     []uint64
+	ReadCount   uint64
+	WriteCount  uint64
+	Height      uint32 // main chain height confirming the billing
+	State       BillingState
+	Challenger  proto.AccountAddress
+	Deadline    uint32 // main chain height until which the challenge could be proved
+}
+
+// BillingChallengeHeader defines the billing challenge transaction header.
+type BillingChallengeHeader struct {
+	Sender      proto.AccountAddress // database owner
+	Nonce       pi.AccountNonce
+	RequestHash hash.Hash // hash of the challenged billing request
+	Fee         uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *BillingChallengeHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// BillingChallenge defines the billing challenge transaction, which is submitted by the database
+// owner against a billing believed to be inflated.
+type BillingChallenge struct {
+	BillingChallengeHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes BillingChallenge using msgpack.
+func (t *BillingChallenge) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes BillingChallenge using msgpack.
+func (t *BillingChallenge) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *BillingChallenge) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *BillingChallenge) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *BillingChallenge) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *BillingChallenge) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *BillingChallenge) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *BillingChallenge) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeBillingChallenge
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *BillingChallenge) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.BillingChallengeHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *BillingChallenge) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.BillingChallengeHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
+
+// BillingProofHeader defines the billing proof transaction header.
+type BillingProofHeader struct {
+	Sender      proto.AccountAddress // billed miner
+	Nonce       pi.AccountNonce
+	RequestHash hash.Hash // hash of the challenged billing request
+	// Acks are the query acks signed by database users, which should cover the billed read
+	// and write counts.
+	Acks []*wt.SignedAckHeader
+	Fee  uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *BillingProofHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// BillingProof defines the billing proof transaction, which is submitted by a billed miner to
+// answer the challenge of billing.
+type BillingProof struct {
+	BillingProofHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes BillingProof using msgpack.
+func (t *BillingProof) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes BillingProof using msgpack.
+func (t *BillingProof) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *BillingProof) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *BillingProof) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *BillingProof) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *BillingProof) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *BillingProof) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *BillingProof) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeBillingProof
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *BillingProof) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.BillingProofHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify, the acks are verified as well.
+func (t *BillingProof) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.BillingProofHeader.MarshalHash(); err != nil {
+		return
+	}
+	if err = verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature); err != nil {
+		return
+	}
+	for _, v := range t.Acks {
+		if v == nil {
+			return ErrInvalidBillingProof
+		}
+		if err = v.Verify(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// verifySender verifies that the header hash h is signed by the key of sender.
+func verifySender(
+	sender proto.AccountAddress, h hash.Hash, headerHash *hash.Hash,
+	signee *asymmetric.PublicKey, signature *asymmetric.Signature,
+) (err error) {
+	if !headerHash.IsEqual(&h) {
+		return ErrSignVerification
+	}
+	if signee == nil || signature == nil || !signature.Verify(h[:], signee) {
+		return ErrSignVerification
+	}
+	var enc []byte
+	if enc, err = signee.MarshalHash(); err != nil {
+		return
+	}
+	if proto.AccountAddress(hash.THashH(enc)) != sender {
+		return ErrSignVerification
+	}
+	return
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+func TestBillingChallenge_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tx := &BillingChallenge{
+		BillingChallengeHeader: BillingChallengeHeader{
+			Sender:      proto.AccountAddress(hash.THashH(enc)),
+			Nonce:       1,
+			RequestHash: generateRandomHash(),
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeBillingChallenge {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &BillingChallenge{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if dec.GetHash() != tx.GetHash() {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", dec.GetHash(), tx.GetHash())
+	}
+
+	// tampered header
+	dec.RequestHash = generateRandomHash()
+	if err = dec.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// sender is not the signer
+	tx.Sender = generateRandomAccountAddresses(1)[0]
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
+
+func TestBillingProof_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	ack, err := generateSignedAck(priv)
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tx := &BillingProof{
+		BillingProofHeader: BillingProofHeader{
+			Sender:      proto.AccountAddress(hash.THashH(enc)),
+			Nonce:       1,
+			RequestHash: generateRandomHash(),
+			Acks:        []*wt.SignedAckHeader{ack},
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeBillingProof {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &BillingProof{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if dec.GetHash() != tx.GetHash() {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", dec.GetHash(), tx.GetHash())
+	}
+
+	// tampered ack
+	ack.Response.Request.BatchCount = 100
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err == nil {
+		t.Fatal("Unexpeted result: tampered ack should not be verified")
+	}
+
+	// empty ack
+	tx.Acks = []*wt.SignedAckHeader{nil}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrInvalidBillingProof {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
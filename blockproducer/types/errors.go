@@ -33,4 +33,35 @@ var (
 	// ErrNodePublicKeyNotMatch indicates that the public key given with a node does not match the
 	// one in the key store.
 	ErrNodePublicKeyNotMatch = errors.New("node publick key doesn't match")
+
+	// ErrInvalidPermission indicates that a user permission is out of the defined range.
+	ErrInvalidPermission = errors.New("invalid user permission")
+	// ErrInvalidTokenType indicates that a token type is out of the defined range.
+	ErrInvalidTokenType = errors.New("invalid token type")
+	// ErrInvalidMinerNode indicates that a miner node id is not derived from the node key, or the
+	// node key does not sign the registering account.
+	ErrInvalidMinerNode = errors.New("invalid miner node")
+	// ErrInvalidBillingProof indicates that a billing proof contains an empty ack.
+	ErrInvalidBillingProof = errors.New("invalid billing proof")
+	// ErrInvalidMinerEvidence indicates that a miner evidence does not prove conflicting
+	// responses of the miner.
+	ErrInvalidMinerEvidence = errors.New("invalid miner evidence")
+	// ErrInvalidMultiSigOwners indicates that the owners or threshold of a multi-signature
+	// account is invalid.
+	ErrInvalidMultiSigOwners = errors.New("invalid multi-signature owners")
+	// ErrInvalidMultiSigAction indicates that a multi-signature action is not set properly or not
+	// sent by the multi-signature account.
+	ErrInvalidMultiSigAction = errors.New("invalid multi-signature action")
+	// ErrInvalidChainParams indicates that the chain parameters of an update are not usable.
+	ErrInvalidChainParams = errors.New("invalid chain parameters")
+	// ErrStateProofVerification indicates that a state object is not proved by the meta root of
+	// a block header.
+	ErrStateProofVerification = errors.New("state proof verification failed")
+	// ErrCheckpointQuorum indicates that a checkpoint is not signed by enough block producers.
+	ErrCheckpointQuorum = errors.New("checkpoint not signed by quorum")
+	// ErrUnknownTransactionType indicates that a packed transaction has a unknown type and cannot
+	// be decoded.
+	ErrUnknownTransactionType = errors.New("unknown transaction type")
+	// ErrInvalidVesting indicates that the amount or schedule of a vesting is invalid.
+	ErrInvalidVesting = errors.New("invalid vesting")
 )
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// ChainParams defines the protocol parameters changed by governance transactions instead of
+// the config files of all block producers.
+type ChainParams struct {
+	BlockPeriod   time.Duration // block producing cycle
+	GasPrice      uint64        // base gas price of databases and billings
+	MinMinerStake uint64        // minimum covenant coin locked by a registered miner
+	DefaultSpace  uint64        // space quota of databases created without one, 0 for unlimited
+	DefaultMemory uint64        // memory quota of databases created without one, 0 for unlimited
+}
+
+// Verify checks that the parameters are usable by the chain.
+func (p *ChainParams) Verify() error {
+	if p.BlockPeriod <= 0 || p.GasPrice == 0 {
+		return ErrInvalidChainParams
+	}
+	return nil
+}
+
+// ParamsUpdate defines a chain parameters update voted by the authorities, it's scheduled by
+// the block in which the votes reach the threshold, and takes effect from ActivationHeight on.
+type ParamsUpdate struct {
+	Hash             hash.Hash // hash of the parameters and activation height
+	Params           ChainParams
+	ActivationHeight uint32
+	Votes            []proto.AccountAddress
+	Height           uint32 // height of the block scheduling the update, 0 if not scheduled yet
+}
+
+// Scheduled returns whether the update is scheduled.
+func (u *ParamsUpdate) Scheduled() bool {
+	return u.Height != 0
+}
+
+// UpdateParamsHeader defines the chain parameters update transaction header.
+type UpdateParamsHeader struct {
+	Sender           proto.AccountAddress // an authority
+	Nonce            pi.AccountNonce
+	Params           ChainParams
+	ActivationHeight uint32
+	Fee              uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *UpdateParamsHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// UpdateParams defines the chain parameters update transaction, which is a vote of an authority
+// for the parameters to take effect at the activation height. The votes of different
+// authorities are counted together if they have the same update hash.
+type UpdateParams struct {
+	UpdateParamsHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes UpdateParams using msgpack.
+func (t *UpdateParams) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes UpdateParams using msgpack.
+func (t *UpdateParams) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *UpdateParams) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *UpdateParams) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *UpdateParams) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *UpdateParams) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *UpdateParams) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *UpdateParams) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeUpdateParams
+}
+
+// UpdateHash returns the hash identifying the voted update.
+func (t *UpdateParams) UpdateHash() (h hash.Hash, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(&ParamsUpdate{
+		Params:           t.Params,
+		ActivationHeight: t.ActivationHeight,
+	}); err != nil {
+		return
+	}
+	h = hash.THashH(enc.Bytes())
+	return
+}
+
+// Update returns the update voted by the sender.
+func (t *UpdateParams) Update() (u *ParamsUpdate, err error) {
+	u = &ParamsUpdate{
+		Params:           t.Params,
+		ActivationHeight: t.ActivationHeight,
+		Votes:            []proto.AccountAddress{t.Sender},
+	}
+	u.Hash, err = t.UpdateHash()
+	return
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *UpdateParams) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.UpdateParamsHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *UpdateParams) Verify() (err error) {
+	if err = t.Params.Verify(); err != nil {
+		return
+	}
+	var enc []byte
+	if enc, err = t.UpdateParamsHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestUpdateParams_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	sender := proto.AccountAddress(hash.THashH(enc))
+
+	tx := &UpdateParams{
+		UpdateParamsHeader: UpdateParamsHeader{
+			Sender: sender,
+			Nonce:  1,
+			Params: ChainParams{
+				BlockPeriod:   3 * time.Second,
+				GasPrice:      2,
+				MinMinerStake: 100,
+			},
+			ActivationHeight: 10,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeUpdateParams {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &UpdateParams{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// votes of different senders and nonces share the update hash
+	update, err := tx.Update()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	other := &UpdateParams{UpdateParamsHeader: tx.UpdateParamsHeader}
+	other.Sender = generateRandomAccountAddresses(1)[0]
+	other.Nonce = 5
+	h, err := other.UpdateHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if !h.IsEqual(&update.Hash) {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", h, update.Hash)
+	}
+	if update.Scheduled() || len(update.Votes) != 1 || update.Votes[0] != sender {
+		t.Fatalf("Unexpeted update: %v", update)
+	}
+	other.ActivationHeight++
+	if h, err = other.UpdateHash(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if h.IsEqual(&update.Hash) {
+		t.Fatalf("Unexpeted hash: %v", h)
+	}
+
+	// invalid params
+	for _, v := range []ChainParams{
+		{GasPrice: 1},
+		{BlockPeriod: time.Second},
+	} {
+		tx.Params = v
+		if err = tx.Sign(priv); err != nil {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+		if err = tx.Verify(); err != ErrInvalidChainParams {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+	}
+}
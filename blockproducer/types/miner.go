@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// MinerProfile defines a miner registered on main chain, which provides database service.
+type MinerProfile struct {
+	Address  proto.AccountAddress
+	NodeID   proto.NodeID
+	Space    uint64 // provided storage space in bytes
+	Memory   uint64 // provided memory in bytes
+	GasPrice uint64 // the minimum gas price accepted
+	Region   string
+	// Stake is the covenant coin locked by the miner, which is slashed on misbehavior.
+	Stake      uint64
+	Challenges []MinerChallenge
+}
+
+// RegisterMinerHeader defines the miner registration transaction header.
+type RegisterMinerHeader struct {
+	Sender proto.AccountAddress // account registering the miner, which stakes and earns incomes
+	Nonce  pi.AccountNonce
+	NodeID proto.NodeID
+	// NodeKey and NodeNonce prove that NodeID is derived from the node key, see cmd/idminer.
+	NodeKey   *asymmetric.PublicKey
+	NodeNonce cpuminer.Uint256
+	// NodeSignature is signed by the node key over Sender, which proves that the node consents to
+	// be registered by the account.
+	NodeSignature *asymmetric.Signature
+	Space         uint64
+	Memory        uint64
+	GasPrice      uint64
+	Region        string
+	Stake         uint64 // covenant coin locked, the difference to the current stake is settled
+	Deregister    bool   // removes the miner from main chain if set, resources are ignored
+	Fee           uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *RegisterMinerHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// RegisterMiner defines the miner registration transaction, which registers a miner node with
+// its resources and price, or gracefully deregisters it.
+type RegisterMiner struct {
+	RegisterMinerHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Profile returns the miner profile registered by the transaction.
+func (t *RegisterMiner) Profile() *MinerProfile {
+	return &MinerProfile{
+		Address:  t.Sender,
+		NodeID:   t.NodeID,
+		Space:    t.Space,
+		Memory:   t.Memory,
+		GasPrice: t.GasPrice,
+		Region:   t.Region,
+		Stake:    t.Stake,
+	}
+}
+
+// Serialize serializes RegisterMiner using msgpack.
+func (t *RegisterMiner) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes RegisterMiner using msgpack.
+func (t *RegisterMiner) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *RegisterMiner) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *RegisterMiner) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *RegisterMiner) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *RegisterMiner) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *RegisterMiner) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *RegisterMiner) GetTransactionType() pi.TransactionType {
+	if t.Deregister {
+		return pi.TransactionTypeDeregisterMiner
+	}
+	return pi.TransactionTypeRegisterMiner
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *RegisterMiner) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.RegisterMinerHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// nodeHash returns the hash of Sender signed by the node key.
+func (t *RegisterMiner) nodeHash() hash.Hash {
+	return signedHash(t.ChainID, t.Sender[:])
+}
+
+// SignNode signs Sender with the node key, it should be called before Sign since the node
+// signature is a part of the header.
+func (t *RegisterMiner) SignNode(nodeKey *asymmetric.PrivateKey) (err error) {
+	var h = t.nodeHash()
+	if t.NodeSignature, err = nodeKey.Sign(h[:]); err != nil {
+		return
+	}
+	t.NodeKey = nodeKey.PubKey()
+	return
+}
+
+// VerifyNode verifies that NodeID is derived from the node key and the node consents to be
+// registered by Sender.
+func (t *RegisterMiner) VerifyNode() (err error) {
+	if t.NodeKey == nil || !kms.IsIDPubNonceValid(t.NodeID.ToRawNodeID(), &t.NodeNonce, t.NodeKey) {
+		return ErrInvalidMinerNode
+	}
+	var h = t.nodeHash()
+	if t.NodeSignature == nil || !t.NodeSignature.Verify(h[:], t.NodeKey) {
+		return ErrInvalidMinerNode
+	}
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *RegisterMiner) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.RegisterMinerHeader.MarshalHash(); err != nil {
+		return
+	} else if h := signedHash(t.ChainID, enc); !t.HeaderHash.IsEqual(&h) {
+		err = ErrSignVerification
+		return
+	} else if t.Signee == nil || t.Signature == nil || !t.Signature.Verify(h[:], t.Signee) {
+		err = ErrSignVerification
+		return
+	}
+	if enc, err = t.Signee.MarshalHash(); err != nil {
+		return
+	} else if proto.AccountAddress(hash.THashH(enc)) != t.Sender {
+		err = ErrSignVerification
+		return
+	}
+	return
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestRegisterMiner_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	nodePriv, nodePub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	nonce := cpuminer.Uint256{A: 1}
+
+	tx := &RegisterMiner{
+		RegisterMinerHeader: RegisterMinerHeader{
+			Sender:    proto.AccountAddress(hash.THashH(enc)),
+			Nonce:     1,
+			NodeID:    proto.NodeID(cpuminer.HashBlock(nodePub.Serialize(), nonce).String()),
+			NodeNonce: nonce,
+			Space:     1 << 30,
+			Memory:    1 << 20,
+			GasPrice:  1,
+			Region:    "us-west",
+		},
+	}
+	if err = tx.VerifyNode(); err != ErrInvalidMinerNode {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.SignNode(nodePriv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.VerifyNode(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeRegisterMiner {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+	if p := tx.Profile(); p.Address != tx.Sender || p.NodeID != tx.NodeID || p.Region != tx.Region {
+		t.Fatalf("Unexpeted profile: %v", p)
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &RegisterMiner{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.VerifyNode(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if dec.GetHash() != tx.GetHash() {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", dec.GetHash(), tx.GetHash())
+	}
+
+	// tampered header
+	dec.GasPrice = 2
+	if err = dec.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// deregistration
+	tx.Deregister = true
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeDeregisterMiner {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// node is not derived from the node key
+	tx.NodeNonce = cpuminer.Uint256{A: 2}
+	if err = tx.VerifyNode(); err != ErrInvalidMinerNode {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// node key does not sign the sender
+	tx.NodeNonce = nonce
+	tx.Sender = generateRandomAccountAddresses(1)[0]
+	if err = tx.VerifyNode(); err != ErrInvalidMinerNode {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.SignNode(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.VerifyNode(); err != ErrInvalidMinerNode {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// sender is not the signer
+	if err = tx.SignNode(nodePriv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.VerifyNode(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
@@ -0,0 +1,432 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// MaxMultiSigOwners defines the max number of owners of a multi-signature account.
+const MaxMultiSigOwners = 32
+
+// MultiSigAction defines the transaction executed on behalf of a multi-signature account once
+// it's approved by enough owners, exactly one of the fields should be set. The Sender of the
+// action header should be the multi-signature account, and its Nonce and Fee are ignored.
+type MultiSigAction struct {
+	Transfer   *TransferHeader
+	Permission *UpdatePermissionHeader
+}
+
+// Sender returns the account executing the action.
+func (a *MultiSigAction) Sender() (sender proto.AccountAddress) {
+	if a.Transfer != nil {
+		return a.Transfer.Sender
+	}
+	if a.Permission != nil {
+		return a.Permission.Sender
+	}
+	return
+}
+
+// Verify checks that exactly one action is set with valid arguments.
+func (a *MultiSigAction) Verify() error {
+	switch {
+	case a.Transfer != nil && a.Permission == nil:
+		if a.Transfer.TokenType < StableCoin || a.Transfer.TokenType >= NumberOfTokenType {
+			return ErrInvalidTokenType
+		}
+	case a.Permission != nil && a.Transfer == nil:
+		if !a.Permission.Revoke && (a.Permission.Permission < Admin ||
+			a.Permission.Permission >= NumberOfUserPermission) {
+			return ErrInvalidPermission
+		}
+	default:
+		return ErrInvalidMultiSigAction
+	}
+	return nil
+}
+
+// MultiSigProposal defines an action proposed by an owner of multi-signature account, which is
+// pending until approved by enough owners.
+type MultiSigProposal struct {
+	Hash      hash.Hash // hash of the proposing transaction
+	Proposer  proto.AccountAddress
+	Action    MultiSigAction
+	Approvals []proto.AccountAddress // owners approved the action, including the proposer
+}
+
+// MultiSigProfile defines a multi-signature account and its pending proposals.
+type MultiSigProfile struct {
+	Address   proto.AccountAddress
+	Threshold uint32 // number of approvals required to execute a proposal
+	Owners    []proto.AccountAddress
+	Proposals []MultiSigProposal
+}
+
+// IsOwner returns whether addr is an owner of the multi-signature account.
+func (p *MultiSigProfile) IsOwner(addr proto.AccountAddress) bool {
+	for _, v := range p.Owners {
+		if v == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateMultiSigHeader defines the multi-signature account creation transaction header.
+type CreateMultiSigHeader struct {
+	Sender    proto.AccountAddress
+	Nonce     pi.AccountNonce
+	Owners    []proto.AccountAddress
+	Threshold uint32
+	Fee       uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *CreateMultiSigHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// CreateMultiSig defines the multi-signature account creation transaction, the created account
+// requires Threshold of the Owners to approve each transfer or database administration.
+type CreateMultiSig struct {
+	CreateMultiSigHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes CreateMultiSig using msgpack.
+func (t *CreateMultiSig) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes CreateMultiSig using msgpack.
+func (t *CreateMultiSig) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *CreateMultiSig) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *CreateMultiSig) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *CreateMultiSig) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *CreateMultiSig) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *CreateMultiSig) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *CreateMultiSig) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeCreateMultiSig
+}
+
+// MultiSigAddress returns the address of the created account, which is derived from the
+// transaction hash, so no key could sign for it directly.
+func (t *CreateMultiSig) MultiSigAddress() proto.AccountAddress {
+	return proto.AccountAddress(hash.THashH(append([]byte("multisig"), t.HeaderHash[:]...)))
+}
+
+// Profile returns the profile of the created account.
+func (t *CreateMultiSig) Profile() *MultiSigProfile {
+	return &MultiSigProfile{
+		Address:   t.MultiSigAddress(),
+		Threshold: t.Threshold,
+		Owners:    append([]proto.AccountAddress{}, t.Owners...),
+	}
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *CreateMultiSig) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.CreateMultiSigHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify, the owners should be distinct and the
+// threshold should be in range [1, len(Owners)].
+func (t *CreateMultiSig) Verify() (err error) {
+	if len(t.Owners) == 0 || len(t.Owners) > MaxMultiSigOwners ||
+		t.Threshold == 0 || int(t.Threshold) > len(t.Owners) {
+		return ErrInvalidMultiSigOwners
+	}
+	var owners = make(map[proto.AccountAddress]bool)
+	for _, v := range t.Owners {
+		if owners[v] {
+			return ErrInvalidMultiSigOwners
+		}
+		owners[v] = true
+	}
+	var enc []byte
+	if enc, err = t.CreateMultiSigHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
+
+// ProposeMultiSigHeader defines the multi-signature proposal transaction header.
+type ProposeMultiSigHeader struct {
+	Sender  proto.AccountAddress // owner of the multi-signature account
+	Nonce   pi.AccountNonce
+	Account proto.AccountAddress // the multi-signature account
+	Action  MultiSigAction
+	Fee     uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *ProposeMultiSigHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// ProposeMultiSig defines the multi-signature proposal transaction, which is submitted by an
+// owner to propose an action of the multi-signature account and approves it at the same time.
+type ProposeMultiSig struct {
+	ProposeMultiSigHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes ProposeMultiSig using msgpack.
+func (t *ProposeMultiSig) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes ProposeMultiSig using msgpack.
+func (t *ProposeMultiSig) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *ProposeMultiSig) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *ProposeMultiSig) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *ProposeMultiSig) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *ProposeMultiSig) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *ProposeMultiSig) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *ProposeMultiSig) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeProposeMultiSig
+}
+
+// Proposal returns the proposal approved by the proposer.
+func (t *ProposeMultiSig) Proposal() *MultiSigProposal {
+	return &MultiSigProposal{
+		Hash:      t.HeaderHash,
+		Proposer:  t.Sender,
+		Action:    t.Action,
+		Approvals: []proto.AccountAddress{t.Sender},
+	}
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *ProposeMultiSig) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.ProposeMultiSigHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *ProposeMultiSig) Verify() (err error) {
+	if err = t.Action.Verify(); err != nil {
+		return
+	}
+	if t.Action.Sender() != t.Account {
+		return ErrInvalidMultiSigAction
+	}
+	var enc []byte
+	if enc, err = t.ProposeMultiSigHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
+
+// ApproveMultiSigHeader defines the multi-signature approval transaction header.
+type ApproveMultiSigHeader struct {
+	Sender   proto.AccountAddress // owner of the multi-signature account
+	Nonce    pi.AccountNonce
+	Account  proto.AccountAddress // the multi-signature account
+	Proposal hash.Hash            // hash of the proposing transaction
+	Fee      uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *ApproveMultiSigHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// ApproveMultiSig defines the multi-signature approval transaction, which is submitted by an
+// owner to co-sign a pending proposal. The proposed action is executed by the approval reaching
+// the threshold.
+type ApproveMultiSig struct {
+	ApproveMultiSigHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes ApproveMultiSig using msgpack.
+func (t *ApproveMultiSig) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes ApproveMultiSig using msgpack.
+func (t *ApproveMultiSig) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *ApproveMultiSig) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *ApproveMultiSig) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *ApproveMultiSig) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *ApproveMultiSig) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *ApproveMultiSig) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *ApproveMultiSig) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeApproveMultiSig
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *ApproveMultiSig) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.ApproveMultiSigHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *ApproveMultiSig) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.ApproveMultiSigHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
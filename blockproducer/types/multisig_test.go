@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestCreateMultiSig_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	sender := proto.AccountAddress(hash.THashH(enc))
+
+	tx := &CreateMultiSig{
+		CreateMultiSigHeader: CreateMultiSigHeader{
+			Sender:    sender,
+			Nonce:     1,
+			Owners:    append([]proto.AccountAddress{sender}, generateRandomAccountAddresses(2)...),
+			Threshold: 2,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeCreateMultiSig {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+	if addr := tx.MultiSigAddress(); addr == sender || addr == proto.AccountAddress(tx.HeaderHash) {
+		t.Fatalf("Unexpeted multi-signature address: %v", addr)
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &CreateMultiSig{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if dec.MultiSigAddress() != tx.MultiSigAddress() {
+		t.Fatalf("Address not match: \n\tv1=%v,\n\tv2=%v", dec.MultiSigAddress(), tx.MultiSigAddress())
+	}
+
+	// invalid threshold and owners
+	for _, v := range []struct {
+		owners    []proto.AccountAddress
+		threshold uint32
+	}{
+		{owners: tx.Owners, threshold: 0},
+		{owners: tx.Owners, threshold: 4},
+		{owners: nil, threshold: 1},
+		{owners: []proto.AccountAddress{sender, sender}, threshold: 1},
+		{owners: generateRandomAccountAddresses(MaxMultiSigOwners + 1), threshold: 1},
+	} {
+		dec.Owners, dec.Threshold = v.owners, v.threshold
+		if err = dec.Sign(priv); err != nil {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+		if err = dec.Verify(); err != ErrInvalidMultiSigOwners {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+	}
+}
+
+func TestProposeMultiSig_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	addrs := generateRandomAccountAddresses(2)
+
+	tx := &ProposeMultiSig{
+		ProposeMultiSigHeader: ProposeMultiSigHeader{
+			Sender:  proto.AccountAddress(hash.THashH(enc)),
+			Nonce:   1,
+			Account: addrs[0],
+			Action: MultiSigAction{
+				Transfer: &TransferHeader{
+					Sender:    addrs[0],
+					Receiver:  addrs[1],
+					Amount:    100,
+					TokenType: StableCoin,
+				},
+			},
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeProposeMultiSig {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+	if p := tx.Proposal(); p.Hash != tx.HeaderHash || len(p.Approvals) != 1 || p.Approvals[0] != tx.Sender {
+		t.Fatalf("Unexpeted proposal: %v", p)
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &ProposeMultiSig{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if dec.GetHash() != tx.GetHash() {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", dec.GetHash(), tx.GetHash())
+	}
+
+	// tampered action
+	dec.Action.Transfer.Amount = 1000
+	if err = dec.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// action not sent by the multi-signature account
+	tx.Action.Transfer.Sender = addrs[1]
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrInvalidMultiSigAction {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// more than one action
+	tx.Action.Transfer.Sender = addrs[0]
+	tx.Action.Permission = &UpdatePermissionHeader{
+		Sender:     addrs[0],
+		User:       addrs[1],
+		Permission: Read,
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrInvalidMultiSigAction {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
+
+func TestApproveMultiSig_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tx := &ApproveMultiSig{
+		ApproveMultiSigHeader: ApproveMultiSigHeader{
+			Sender:   proto.AccountAddress(hash.THashH(enc)),
+			Nonce:    1,
+			Account:  generateRandomAccountAddresses(1)[0],
+			Proposal: generateRandomHash(),
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeApproveMultiSig {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &ApproveMultiSig{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// tampered header
+	dec.Proposal = generateRandomHash()
+	if err = dec.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// UpdatePermissionHeader defines the database user permission update transaction header.
+type UpdatePermissionHeader struct {
+	Sender     proto.AccountAddress // admin of the database
+	Nonce      pi.AccountNonce
+	DatabaseID proto.DatabaseID
+	User       proto.AccountAddress
+	Permission UserPermission
+	Revoke     bool // removes the user from database if set, Permission is ignored
+	Fee        uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *UpdatePermissionHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// UpdatePermission defines the database user permission update transaction, which grants
+// permission to or revokes permission from a database user.
+type UpdatePermission struct {
+	UpdatePermissionHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes UpdatePermission using msgpack.
+func (t *UpdatePermission) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes UpdatePermission using msgpack.
+func (t *UpdatePermission) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *UpdatePermission) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *UpdatePermission) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *UpdatePermission) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *UpdatePermission) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *UpdatePermission) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *UpdatePermission) GetTransactionType() pi.TransactionType {
+	if t.Revoke {
+		return pi.TransactionTypeDeleteDatabaseUser
+	}
+	return pi.TransactionTypeAlterDatabaseUser
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *UpdatePermission) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.UpdatePermissionHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *UpdatePermission) Verify() (err error) {
+	if !t.Revoke && (t.Permission < Admin || t.Permission >= NumberOfUserPermission) {
+		err = ErrInvalidPermission
+		return
+	}
+	var enc []byte
+	if enc, err = t.UpdatePermissionHeader.MarshalHash(); err != nil {
+		return
+	} else if h := signedHash(t.ChainID, enc); !t.HeaderHash.IsEqual(&h) {
+		err = ErrSignVerification
+		return
+	} else if t.Signee == nil || t.Signature == nil || !t.Signature.Verify(h[:], t.Signee) {
+		err = ErrSignVerification
+		return
+	}
+	// the permission is updated on behalf of the signer
+	if enc, err = t.Signee.MarshalHash(); err != nil {
+		return
+	} else if proto.AccountAddress(hash.THashH(enc)) != t.Sender {
+		err = ErrSignVerification
+		return
+	}
+	return
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
+)
+
+// kinds of state objects prefixed to the leaves of the meta state tree, so that an account
+// could never be proved as a database and vice versa.
+const (
+	stateLeafAccount byte = iota
+	stateLeafDatabase
+)
+
+func stateLeaf(kind byte, enc []byte) hash.Hash {
+	return hash.THashH(append([]byte{kind}, enc...))
+}
+
+// AccountLeaf returns the leaf hash of account a in the meta state tree.
+func AccountLeaf(a *Account) (h hash.Hash, err error) {
+	var enc []byte
+	if enc, err = a.MarshalHash(); err != nil {
+		return
+	}
+	h = stateLeaf(stateLeafAccount, enc)
+	return
+}
+
+// DatabaseLeaf returns the leaf hash of database profile p in the meta state tree.
+func DatabaseLeaf(p *SQLChainProfile) (h hash.Hash, err error) {
+	var enc []byte
+	if enc, err = p.MarshalHash(); err != nil {
+		return
+	}
+	h = stateLeaf(stateLeafDatabase, enc)
+	return
+}
+
+// StateProof defines the inclusion proof of a state object in the MetaRoot of a block header.
+// The header is signed by its producer, so a light client could check the proved object against
+// the headers served by other block producers instead of trusting a single one.
+type StateProof struct {
+	Header SignedHeader
+	Index  uint64
+	Path   []hash.Hash
+}
+
+// Verify verifies the block header and that leaf is at Index of the MetaRoot of the header.
+func (p *StateProof) Verify(leaf hash.Hash) (err error) {
+	var enc []byte
+	if enc, err = p.Header.Header.MarshalHash(); err != nil {
+		return
+	}
+	if h := hash.THashH(enc); !h.IsEqual(&p.Header.BlockHash) {
+		return ErrHashVerification
+	}
+	if p.Header.Signee == nil || p.Header.Signature == nil {
+		return ErrSignVerification
+	}
+	if err = p.Header.Verify(); err != nil {
+		return
+	}
+	path := make([]*hash.Hash, len(p.Path))
+	for i := range p.Path {
+		path[i] = &p.Path[i]
+	}
+	if !merkle.VerifyProof(&leaf, p.Index, path, &p.Header.MetaRoot) {
+		return ErrStateProofVerification
+	}
+	return
+}
+
+// VerifyAccount verifies that account a is proved by p.
+func (p *StateProof) VerifyAccount(a *Account) (err error) {
+	var leaf hash.Hash
+	if leaf, err = AccountLeaf(a); err != nil {
+		return
+	}
+	return p.Verify(leaf)
+}
+
+// VerifyDatabase verifies that database profile profile is proved by p.
+func (p *StateProof) VerifyDatabase(profile *SQLChainProfile) (err error) {
+	var leaf hash.Hash
+	if leaf, err = DatabaseLeaf(profile); err != nil {
+		return
+	}
+	return p.Verify(leaf)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestStateProof_Verify(t *testing.T) {
+	priv, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	var (
+		account = &Account{Address: proto.AccountAddress{0x1}, StableCoinBalance: 100}
+		profile = &SQLChainProfile{ID: "db", Owner: proto.AccountAddress{0x1}}
+		leaf0   hash.Hash
+		leaf1   hash.Hash
+	)
+	if leaf0, err = AccountLeaf(account); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if leaf1, err = DatabaseLeaf(profile); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tree := merkle.NewMerkle([]*hash.Hash{&leaf0, &leaf1})
+	b := &Block{SignedHeader: SignedHeader{Header: Header{MetaRoot: *tree.GetRoot()}}}
+	if err = b.PackAndSignBlock(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	path, _ := tree.GetProof(1)
+	proof := &StateProof{Header: b.SignedHeader, Index: 1, Path: []hash.Hash{*path[0]}}
+	if err = proof.VerifyDatabase(profile); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// the account leaf is not at index 1
+	if err = proof.VerifyAccount(account); err != ErrStateProofVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	profile.Owner = proto.AccountAddress{0x2}
+	if err = proof.VerifyDatabase(profile); err != ErrStateProofVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	profile.Owner = proto.AccountAddress{0x1}
+	proof.Header.MetaRoot = hash.Hash{}
+	if err = proof.VerifyDatabase(profile); err != ErrHashVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// StateSnapshot defines the main chain state after block Head of Height, which is synchronized
+// by new block producers instead of replaying the whole chain. The objects are sorted by their
+// keys, so that the snapshot hash is committed by the StateRoot of the next block header.
+type StateSnapshot struct {
+	Height    uint32
+	Head      hash.Hash
+	Accounts  []Account
+	Databases []SQLChainProfile
+	Miners    []MinerProfile
+	Billings  []BillingProfile
+	MultiSigs []MultiSigProfile
+	Params    []ParamsUpdate
+	Vestings  []VestingProfile
+}
+
+// stateObjects defines the objects covered by the snapshot hash.
+type stateObjects struct {
+	Accounts  []Account
+	Databases []SQLChainProfile
+	Miners    []MinerProfile
+	Billings  []BillingProfile
+	MultiSigs []MultiSigProfile
+	Params    []ParamsUpdate
+	Vestings  []VestingProfile
+}
+
+// StateRoot returns the hash of state objects of the snapshot, the block position is excluded.
+func (s *StateSnapshot) StateRoot() (h hash.Hash, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(&stateObjects{
+		Accounts:  s.Accounts,
+		Databases: s.Databases,
+		Miners:    s.Miners,
+		Billings:  s.Billings,
+		MultiSigs: s.MultiSigs,
+		Params:    s.Params,
+		Vestings:  s.Vestings,
+	}); err != nil {
+		return
+	}
+	h = hash.THashH(enc.Bytes())
+	return
+}
+
+// Serialize serializes StateSnapshot using msgpack.
+func (s *StateSnapshot) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(s); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes StateSnapshot using msgpack.
+func (s *StateSnapshot) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, s)
+}
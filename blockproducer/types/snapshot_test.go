@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+)
+
+func TestStateSnapshot_StateRoot(t *testing.T) {
+	snap := &StateSnapshot{
+		Height:    10,
+		Head:      generateRandomHash(),
+		Accounts:  []Account{*generateRandomAccount(), *generateRandomAccount()},
+		Databases: []SQLChainProfile{*generateRandomProfile()},
+		Miners: []MinerProfile{{
+			Address: generateRandomAccountAddresses(1)[0],
+			Space:   1 << 30,
+		}},
+	}
+	root, err := snap.StateRoot()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// encode and decode
+	b, err := snap.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &StateSnapshot{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	decRoot, err := dec.StateRoot()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if decRoot != root {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", decRoot, root)
+	}
+
+	// block position is excluded
+	dec.Height++
+	dec.Head = generateRandomHash()
+	if decRoot, err = dec.StateRoot(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if decRoot != root {
+		t.Fatalf("Hash not match: \n\tv1=%v,\n\tv2=%v", decRoot, root)
+	}
+
+	// tampered state
+	dec.Accounts[0].StableCoinBalance++
+	if decRoot, err = dec.StateRoot(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if decRoot == root {
+		t.Fatal("Unexpeted result: tampered state should change the state root")
+	}
+}
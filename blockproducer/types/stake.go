@@ -0,0 +1,406 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+// MinerChallenge defines a pending service challenge of a miner, the miner is slashed if the
+// challenged request is not answered until the deadline.
+type MinerChallenge struct {
+	Request    hash.Hash // header hash of the challenged request
+	Challenger proto.AccountAddress
+	Deadline   uint32 // main chain height, set by the block including the challenge
+}
+
+// MinerEvidenceHeader defines the miner misbehavior evidence transaction header.
+type MinerEvidenceHeader struct {
+	Sender proto.AccountAddress // the reporter, who is rewarded with the slashed stake
+	Nonce  pi.AccountNonce
+	NodeID proto.NodeID
+	// Acks are two acks of the same write request with conflicting responses signed by the
+	// miner, a write request should be logged only once with a deterministic result.
+	Acks []*wt.SignedAckHeader
+	Fee  uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *MinerEvidenceHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// MinerEvidence defines the miner misbehavior evidence transaction, which slashes the stake of
+// the miner and removes it from main chain.
+type MinerEvidence struct {
+	MinerEvidenceHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes MinerEvidence using msgpack.
+func (t *MinerEvidence) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes MinerEvidence using msgpack.
+func (t *MinerEvidence) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *MinerEvidence) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *MinerEvidence) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *MinerEvidence) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *MinerEvidence) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *MinerEvidence) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *MinerEvidence) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeMinerEvidence
+}
+
+// MinerAddress returns the account of the key signing the conflicting responses, which should
+// be the account of the registered miner.
+func (t *MinerEvidence) MinerAddress() (addr proto.AccountAddress, err error) {
+	if len(t.Acks) == 0 || t.Acks[0] == nil {
+		err = ErrInvalidMinerEvidence
+		return
+	}
+	return accountOf(t.Acks[0].Response.Signee)
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *MinerEvidence) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.MinerEvidenceHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify, the acks should be signed for the same write
+// request and responded by the miner node with different log offsets or results.
+func (t *MinerEvidence) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.MinerEvidenceHeader.MarshalHash(); err != nil {
+		return
+	}
+	if err = verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature); err != nil {
+		return
+	}
+	if len(t.Acks) != 2 || t.Acks[0] == nil || t.Acks[1] == nil {
+		return ErrInvalidMinerEvidence
+	}
+	for _, v := range t.Acks {
+		if err = v.Verify(); err != nil {
+			return
+		}
+		if v.Response.NodeID != t.NodeID ||
+			v.SignedRequestHeader().QueryType != wt.WriteQuery ||
+			v.Response.Signee == nil || !v.Response.Signee.IsEqual(t.Acks[0].Response.Signee) {
+			return ErrInvalidMinerEvidence
+		}
+	}
+	var r0, r1 = &t.Acks[0].Response, &t.Acks[1].Response
+	if r0.Request.HeaderHash != r1.Request.HeaderHash ||
+		(r0.LogOffset == r1.LogOffset && r0.DataHash == r1.DataHash) {
+		return ErrInvalidMinerEvidence
+	}
+	return
+}
+
+// ServiceChallengeHeader defines the service challenge transaction header.
+type ServiceChallengeHeader struct {
+	Sender  proto.AccountAddress // the challenger, who should be the request signer
+	Nonce   pi.AccountNonce
+	NodeID  proto.NodeID
+	Request wt.SignedRequestHeader
+	Fee     uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *ServiceChallengeHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// ServiceChallenge defines the service challenge transaction, which is submitted by a client
+// whose request is refused by the miner. The miner should answer the challenge by a ServiceProof
+// in time, or its stake is slashed.
+type ServiceChallenge struct {
+	ServiceChallengeHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes ServiceChallenge using msgpack.
+func (t *ServiceChallenge) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes ServiceChallenge using msgpack.
+func (t *ServiceChallenge) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *ServiceChallenge) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *ServiceChallenge) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *ServiceChallenge) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *ServiceChallenge) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *ServiceChallenge) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *ServiceChallenge) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeServiceChallenge
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *ServiceChallenge) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.ServiceChallengeHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *ServiceChallenge) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.ServiceChallengeHeader.MarshalHash(); err != nil {
+		return
+	}
+	if err = verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature); err != nil {
+		return
+	}
+	if err = t.Request.Verify(); err != nil {
+		return
+	}
+	var signer proto.AccountAddress
+	if signer, err = accountOf(t.Request.Signee); err != nil {
+		return
+	}
+	if signer != t.Sender {
+		return ErrSignVerification
+	}
+	return
+}
+
+// ServiceProofHeader defines the service proof transaction header.
+type ServiceProofHeader struct {
+	Sender   proto.AccountAddress // account of the miner node key
+	Nonce    pi.AccountNonce
+	NodeID   proto.NodeID
+	Response wt.SignedResponseHeader
+	Fee      uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *ServiceProofHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// ServiceProof defines the service proof transaction, which answers a service challenge with
+// the response of the challenged request signed by the miner.
+type ServiceProof struct {
+	ServiceProofHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes ServiceProof using msgpack.
+func (t *ServiceProof) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes ServiceProof using msgpack.
+func (t *ServiceProof) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *ServiceProof) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *ServiceProof) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *ServiceProof) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *ServiceProof) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *ServiceProof) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *ServiceProof) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeServiceProof
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *ServiceProof) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.ServiceProofHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify, the response should be signed by the miner
+// node key.
+func (t *ServiceProof) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.ServiceProofHeader.MarshalHash(); err != nil {
+		return
+	}
+	if err = verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature); err != nil {
+		return
+	}
+	if err = t.Response.Verify(); err != nil {
+		return
+	}
+	var signer proto.AccountAddress
+	if signer, err = accountOf(t.Response.Signee); err != nil {
+		return
+	}
+	if signer != t.Sender || t.Response.NodeID != t.NodeID {
+		return ErrSignVerification
+	}
+	return
+}
+
+// accountOf returns the account address of public key.
+func accountOf(pub *asymmetric.PublicKey) (addr proto.AccountAddress, err error) {
+	if pub == nil {
+		err = ErrSignVerification
+		return
+	}
+	var enc []byte
+	if enc, err = pub.MarshalHash(); err != nil {
+		return
+	}
+	addr = proto.AccountAddress(hash.THashH(enc))
+	return
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+func generateConflictingAck(
+	priv *asymmetric.PrivateKey, ack *wt.SignedAckHeader, offset uint64,
+) (conflict *wt.SignedAckHeader, err error) {
+	conflict = &wt.SignedAckHeader{
+		AckHeader: wt.AckHeader{
+			Response: wt.SignedResponseHeader{
+				ResponseHeader: ack.Response.ResponseHeader,
+				Signee:         priv.PubKey(),
+			},
+			Timestamp: ack.Timestamp,
+		},
+		Signee: priv.PubKey(),
+	}
+	conflict.Response.LogOffset = offset
+	if err = conflict.Response.Sign(priv); err != nil {
+		return
+	}
+	err = conflict.Sign(priv)
+	return
+}
+
+func TestMinerEvidence_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	ack, err := generateSignedAck(priv)
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	conflict, err := generateConflictingAck(priv, ack, 1)
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tx := &MinerEvidence{
+		MinerEvidenceHeader: MinerEvidenceHeader{
+			Sender: proto.AccountAddress(hash.THashH(enc)),
+			Nonce:  1,
+			NodeID: ack.Response.NodeID,
+			Acks:   []*wt.SignedAckHeader{ack, conflict},
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeMinerEvidence {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+	if addr, err := tx.MinerAddress(); err != nil || addr != tx.Sender {
+		t.Fatalf("Unexpeted miner address: %v, %v", addr, err)
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &MinerEvidence{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// responses not conflicting
+	if conflict, err = generateConflictingAck(priv, ack, 0); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	tx.Acks = []*wt.SignedAckHeader{ack, conflict}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrInvalidMinerEvidence {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// responses of different requests
+	if conflict, err = generateSignedAck(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	tx.Acks = []*wt.SignedAckHeader{ack, conflict}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrInvalidMinerEvidence {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// single ack
+	tx.Acks = []*wt.SignedAckHeader{ack}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrInvalidMinerEvidence {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
+
+func TestServiceChallenge_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	ack, err := generateSignedAck(priv)
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tx := &ServiceChallenge{
+		ServiceChallengeHeader: ServiceChallengeHeader{
+			Sender:  proto.AccountAddress(hash.THashH(enc)),
+			Nonce:   1,
+			Request: ack.Response.Request,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeServiceChallenge {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &ServiceChallenge{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// request signed by others
+	other, otherPub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if enc, err = otherPub.MarshalHash(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	tx.Sender = proto.AccountAddress(hash.THashH(enc))
+	if err = tx.Sign(other); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
+
+func TestServiceProof_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	ack, err := generateSignedAck(priv)
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	tx := &ServiceProof{
+		ServiceProofHeader: ServiceProofHeader{
+			Sender:   proto.AccountAddress(hash.THashH(enc)),
+			Nonce:    1,
+			NodeID:   ack.Response.NodeID,
+			Response: ack.Response,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeServiceProof {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &ServiceProof{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// response of other node
+	tx.NodeID = proto.NodeID("0000000000000000000000000000000000000000000000000000000000000001")
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
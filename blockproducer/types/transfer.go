@@ -28,8 +28,33 @@ import (
 
 //go:generate hsp
 
-// TransferHeader defines the transfer transaction header.
-type TransferHeader struct {
+// TokenType defines the type of tokens held by accounts.
+type TokenType int32
+
+const (
+	// StableCoin defines the stable coin paid for database usage.
+	StableCoin TokenType = iota
+	// CovenantCoin defines the covenant coin paid as fees.
+	CovenantCoin
+	// NumberOfTokenType defines the token type number.
+	NumberOfTokenType
+)
+
+// String implements fmt.Stringer.String.
+func (t TokenType) String() string {
+	switch t {
+	case StableCoin:
+		return "StableCoin"
+	case CovenantCoin:
+		return "CovenantCoin"
+	default:
+		return "Unknown"
+	}
+}
+
+// legacyTransferHeader defines the transfer transaction header signed before token types and
+// fees were introduced, see TransferHeader.MarshalHash.
+type legacyTransferHeader struct {
 	Sender, Receiver proto.AccountAddress
 	Nonce            pi.AccountNonce
 	Amount           uint64
@@ -38,6 +63,7 @@ type TransferHeader struct {
 // Transfer defines the transfer transaction.
 type Transfer struct {
 	TransferHeader
+	ChainID    pi.ChainID
 	HeaderHash hash.Hash
 	Signee     *asymmetric.PublicKey
 	Signature  *asymmetric.Signature
@@ -68,11 +94,21 @@ func (t *Transfer) GetAccountNonce() pi.AccountNonce {
 	return t.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *Transfer) GetFee() uint64 {
+	return t.Fee
+}
+
 // GetHash implements interfaces/Transaction.GetHash.
 func (t *Transfer) GetHash() hash.Hash {
 	return t.HeaderHash
 }
 
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *Transfer) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
 // GetTransactionType implements interfaces/Transaction.GetTransactionType.
 func (t *Transfer) GetTransactionType() pi.TransactionType {
 	return pi.TransactionTypeTransfer
@@ -84,7 +120,7 @@ func (t *Transfer) Sign(signer *asymmetric.PrivateKey) (err error) {
 	if enc, err = t.TransferHeader.MarshalHash(); err != nil {
 		return
 	}
-	var h = hash.THashH(enc)
+	var h = signedHash(t.ChainID, enc)
 	if t.Signature, err = signer.Sign(h[:]); err != nil {
 		return
 	}
@@ -95,10 +131,14 @@ func (t *Transfer) Sign(signer *asymmetric.PrivateKey) (err error) {
 
 // Verify implements interfaces/Transaction.Verify.
 func (t *Transfer) Verify() (err error) {
+	if t.TokenType < StableCoin || t.TokenType >= NumberOfTokenType {
+		err = ErrInvalidTokenType
+		return
+	}
 	var enc []byte
 	if enc, err = t.TransferHeader.MarshalHash(); err != nil {
 		return
-	} else if h := hash.THashH(enc); !t.HeaderHash.IsEqual(&h) {
+	} else if h := signedHash(t.ChainID, enc); !t.HeaderHash.IsEqual(&h) {
 		err = ErrSignVerification
 		return
 	} else if !t.Signature.Verify(h[:], t.Signee) {
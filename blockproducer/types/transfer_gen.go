@@ -10,8 +10,8 @@ import (
 func (z *Transfer) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 4
-	o = append(o, 0x84, 0x84)
+	// map header, size 5
+	o = append(o, 0x85, 0x85)
 	if z.Signee == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -21,7 +21,7 @@ func (z *Transfer) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x84)
+	o = append(o, 0x85)
 	if z.Signature == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -31,18 +31,24 @@ func (z *Transfer) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x84)
+	o = append(o, 0x85)
 	if oTemp, err := z.TransferHeader.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x84)
+	o = append(o, 0x85)
 	if oTemp, err := z.HeaderHash.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
+	o = append(o, 0x85)
+	if oTemp, err := z.ChainID.MarshalHash(); err != nil {
+		return nil, err
+	} else {
+		o = hsp.AppendBytes(o, oTemp)
+	}
 	return
 }
 
@@ -60,12 +66,12 @@ func (z *Transfer) Msgsize() (s int) {
 	} else {
 		s += z.Signature.Msgsize()
 	}
-	s += 15 + z.TransferHeader.Msgsize() + 11 + z.HeaderHash.Msgsize()
+	s += 15 + z.TransferHeader.Msgsize() + 11 + z.HeaderHash.Msgsize() + 8 + z.ChainID.Msgsize()
 	return
 }
 
 // MarshalHash marshals for hash
-func (z *TransferHeader) MarshalHash() (o []byte, err error) {
+func (z *legacyTransferHeader) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
 	// map header, size 4
@@ -93,7 +99,7 @@ func (z *TransferHeader) MarshalHash() (o []byte, err error) {
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z *TransferHeader) Msgsize() (s int) {
+func (z *legacyTransferHeader) Msgsize() (s int) {
 	s = 1 + 6 + z.Nonce.Msgsize() + 7 + z.Sender.Msgsize() + 9 + z.Receiver.Msgsize() + 7 + hsp.Uint64Size
 	return
 }
@@ -46,8 +46,8 @@ func BenchmarkAppendMsgTransfer(b *testing.B) {
 	}
 }
 
-func TestMarshalHashTransferHeader(t *testing.T) {
-	v := TransferHeader{}
+func TestMarshalHashlegacyTransferHeader(t *testing.T) {
+	v := legacyTransferHeader{}
 	binary.Read(rand.Reader, binary.BigEndian, &v)
 	bts1, err := v.MarshalHash()
 	if err != nil {
@@ -62,8 +62,8 @@ func TestMarshalHashTransferHeader(t *testing.T) {
 	}
 }
 
-func BenchmarkMarshalHashTransferHeader(b *testing.B) {
-	v := TransferHeader{}
+func BenchmarkMarshalHashlegacyTransferHeader(b *testing.B) {
+	v := legacyTransferHeader{}
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -71,8 +71,8 @@ func BenchmarkMarshalHashTransferHeader(b *testing.B) {
 	}
 }
 
-func BenchmarkAppendMsgTransferHeader(b *testing.B) {
-	v := TransferHeader{}
+func BenchmarkAppendMsglegacyTransferHeader(b *testing.B) {
+	v := legacyTransferHeader{}
 	bts := make([]byte, 0, v.Msgsize())
 	bts, _ = v.MarshalHash()
 	b.SetBytes(int64(len(bts)))
@@ -15,3 +15,88 @@
  */
 
 package types
+
+import (
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestTransferHeader_MarshalHash(t *testing.T) {
+	var (
+		header = TransferHeader{
+			Sender:   proto.AccountAddress{0x1},
+			Receiver: proto.AccountAddress{0x2},
+			Nonce:    1,
+			Amount:   10,
+		}
+		legacy = legacyTransferHeader{
+			Sender:   proto.AccountAddress{0x1},
+			Receiver: proto.AccountAddress{0x2},
+			Nonce:    1,
+			Amount:   10,
+		}
+	)
+	enc, err := header.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	legacyEnc, err := legacy.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	// stable coin transfer without fee is hashed in the legacy layout
+	if hash.THashH(enc) != hash.THashH(legacyEnc) {
+		t.Fatal("stable coin transfer should keep the legacy hash")
+	}
+
+	covenant := header
+	covenant.TokenType = CovenantCoin
+	if enc, err = covenant.MarshalHash(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if hash.THashH(enc) == hash.THashH(legacyEnc) {
+		t.Fatal("token type should change transfer hash")
+	}
+	withFee := header
+	withFee.Fee = 1
+	if enc, err = withFee.MarshalHash(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if hash.THashH(enc) == hash.THashH(legacyEnc) {
+		t.Fatal("fee should change transfer hash")
+	}
+}
+
+func TestTransfer_TokenType(t *testing.T) {
+	priv, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	tx := &Transfer{
+		TransferHeader: TransferHeader{
+			Receiver:  proto.AccountAddress{0x2},
+			Nonce:     1,
+			Amount:    10,
+			TokenType: CovenantCoin,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// token type is covered by signature
+	tx.TokenType = StableCoin
+	if err = tx.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	tx.TokenType = NumberOfTokenType
+	if err = tx.Verify(); err != ErrInvalidTokenType {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	hsp "github.com/CovenantSQL/HashStablePack/marshalhash"
+)
+
+// TransferHeader defines the transfer transaction header.
+type TransferHeader struct {
+	Sender, Receiver proto.AccountAddress
+	Nonce            pi.AccountNonce
+	Amount           uint64
+	TokenType        TokenType
+	Fee              uint64 // paid in covenant coins to the block producer packing the transaction
+}
+
+func (h *TransferHeader) legacy() *legacyTransferHeader {
+	return &legacyTransferHeader{
+		Sender:   h.Sender,
+		Receiver: h.Receiver,
+		Nonce:    h.Nonce,
+		Amount:   h.Amount,
+	}
+}
+
+// isLegacy returns whether the header can be expressed by the legacy header layout, i.e. it
+// transfers stable coins without fee.
+func (h *TransferHeader) isLegacy() bool {
+	return h.TokenType == StableCoin && h.Fee == 0
+}
+
+// MarshalHash marshals for hash. A legacy header keeps the layout hashed before token types and
+// fees were introduced, so the transfers already on chain keep their hashes and signatures.
+func (h *TransferHeader) MarshalHash() (o []byte, err error) {
+	if h.isLegacy() {
+		return h.legacy().MarshalHash()
+	}
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized
+// message.
+func (h *TransferHeader) Msgsize() (s int) {
+	s = h.legacy().Msgsize()
+	if !h.isLegacy() {
+		s += 10 + hsp.Int32Size + 4 + hsp.Uint64Size
+	}
+	return
+}
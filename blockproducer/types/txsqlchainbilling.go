@@ -75,6 +75,7 @@ type TxBilling struct {
 	TxContent      TxContent
 	TxType         byte
 	AccountAddress *proto.AccountAddress
+	ChainID        pi.ChainID
 	TxHash         *hash.Hash
 	Signee         *asymmetric.PublicKey
 	Signature      *asymmetric.Signature
@@ -115,11 +116,21 @@ func (tb *TxBilling) GetAccountNonce() pi.AccountNonce {
 	return pi.AccountNonce(tb.TxContent.SequenceID)
 }
 
+// GetFee implements interfaces/Transaction.GetFee, billing transactions are free.
+func (tb *TxBilling) GetFee() uint64 {
+	return 0
+}
+
 // GetHash implements interfaces/Transaction.GetHash.
 func (tb *TxBilling) GetHash() hash.Hash {
 	return *tb.TxHash
 }
 
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (tb *TxBilling) GetChainID() pi.ChainID {
+	return tb.ChainID
+}
+
 // GetTransactionType implements interfaces/Transaction.GetTransactionType.
 func (tb *TxBilling) GetTransactionType() pi.TransactionType {
 	return pi.TransactionTypeBilling
@@ -131,7 +142,7 @@ func (tb *TxBilling) Sign(signer *asymmetric.PrivateKey) error {
 	if err != nil {
 		return err
 	}
-	h := hash.THashH(enc)
+	h := signedHash(tb.ChainID, enc)
 	tb.TxHash = &h
 
 	pub := asymmetric.PublicKey(signer.PublicKey)
@@ -151,7 +162,7 @@ func (tb *TxBilling) Verify() (err error) {
 	var enc []byte
 	if enc, err = tb.TxContent.MarshalHash(); err != nil {
 		return
-	} else if h := hash.THashH(enc); !tb.TxHash.IsEqual(&h) {
+	} else if h := signedHash(tb.ChainID, enc); !tb.TxHash.IsEqual(&h) {
 		err = ErrSignVerification
 		return
 	} else if !tb.Signature.Verify(h[:], tb.Signee) {
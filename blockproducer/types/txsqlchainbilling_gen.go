@@ -10,8 +10,8 @@ import (
 func (z *TxBilling) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 7
-	o = append(o, 0x87, 0x87)
+	// map header, size 8
+	o = append(o, 0x88, 0x88)
 	if z.Signee == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -21,7 +21,7 @@ func (z *TxBilling) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x87)
+	o = append(o, 0x88)
 	if z.Signature == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -31,7 +31,7 @@ func (z *TxBilling) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x87)
+	o = append(o, 0x88)
 	if z.SignedBlock == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -41,7 +41,7 @@ func (z *TxBilling) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x87)
+	o = append(o, 0x88)
 	if z.TxHash == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -51,7 +51,7 @@ func (z *TxBilling) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x87)
+	o = append(o, 0x88)
 	if z.AccountAddress == nil {
 		o = hsp.AppendNil(o)
 	} else {
@@ -61,13 +61,19 @@ func (z *TxBilling) MarshalHash() (o []byte, err error) {
 			o = hsp.AppendBytes(o, oTemp)
 		}
 	}
-	o = append(o, 0x87)
+	o = append(o, 0x88)
 	if oTemp, err := z.TxContent.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x87)
+	o = append(o, 0x88)
+	if oTemp, err := z.ChainID.MarshalHash(); err != nil {
+		return nil, err
+	} else {
+		o = hsp.AppendBytes(o, oTemp)
+	}
+	o = append(o, 0x88)
 	o = hsp.AppendByte(o, z.TxType)
 	return
 }
@@ -104,7 +110,7 @@ func (z *TxBilling) Msgsize() (s int) {
 	} else {
 		s += z.AccountAddress.Msgsize()
 	}
-	s += 10 + z.TxContent.Msgsize() + 7 + hsp.ByteSize
+	s += 10 + z.TxContent.Msgsize() + 8 + z.ChainID.Msgsize() + 7 + hsp.ByteSize
 	return
 }
 
@@ -0,0 +1,426 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+// VestingSchedule defines the main chain heights releasing the tokens of a vesting. Nothing is
+// released before CliffHeight, the tokens are released linearly from StartHeight to EndHeight,
+// and the tokens released before the cliff become available at once at CliffHeight. A time
+// locked transfer is a schedule with all the heights equal.
+type VestingSchedule struct {
+	StartHeight uint32
+	CliffHeight uint32
+	EndHeight   uint32
+}
+
+// Verify checks that StartHeight <= CliffHeight <= EndHeight.
+func (s *VestingSchedule) Verify() error {
+	if s.StartHeight > s.CliffHeight || s.CliffHeight > s.EndHeight {
+		return ErrInvalidVesting
+	}
+	return nil
+}
+
+// Vested returns the tokens of amount released by the schedule at height.
+func (s *VestingSchedule) Vested(amount uint64, height uint32) uint64 {
+	switch {
+	case height < s.CliffHeight:
+		return 0
+	case height >= s.EndHeight:
+		return amount
+	}
+	// StartHeight <= CliffHeight <= height < EndHeight here, the product may overflow uint64
+	vested := new(big.Int).SetUint64(amount)
+	vested.Mul(vested, big.NewInt(int64(height-s.StartHeight)))
+	vested.Div(vested, big.NewInt(int64(s.EndHeight-s.StartHeight)))
+	return vested.Uint64()
+}
+
+// VestingProfile defines the tokens locked by a vesting, which are released to the beneficiary
+// by the schedule. A revocable vesting can be revoked by its creator to refund the tokens not
+// vested yet, which also makes a refundable deposit.
+type VestingProfile struct {
+	ID            hash.Hash // hash of the creating transaction
+	Creator       proto.AccountAddress
+	Beneficiary   proto.AccountAddress
+	TokenType     TokenType
+	Amount        uint64 // tokens locked in total
+	Released      uint64 // tokens already released to the beneficiary
+	Schedule      VestingSchedule
+	Revocable     bool
+	Revoked       bool
+	RevokedHeight uint32 // height of the last settled block when the vesting is revoked
+}
+
+// Vested returns the tokens vested at height, which stop vesting once the vesting is revoked.
+func (p *VestingProfile) Vested(height uint32) uint64 {
+	if p.Revoked && p.RevokedHeight < height {
+		height = p.RevokedHeight
+	}
+	return p.Schedule.Vested(p.Amount, height)
+}
+
+// Releasable returns the tokens vested at height but not released yet.
+func (p *VestingProfile) Releasable(height uint32) uint64 {
+	return p.Vested(height) - p.Released
+}
+
+// Unvested returns the tokens not vested at height, which are refunded by revoking.
+func (p *VestingProfile) Unvested(height uint32) uint64 {
+	return p.Amount - p.Vested(height)
+}
+
+// Settled returns whether all the tokens of the vesting are released or refunded.
+func (p *VestingProfile) Settled() bool {
+	if p.Revoked {
+		return p.Released == p.Schedule.Vested(p.Amount, p.RevokedHeight)
+	}
+	return p.Released == p.Amount
+}
+
+// CreateVestingHeader defines the vesting creation transaction header.
+type CreateVestingHeader struct {
+	Sender      proto.AccountAddress
+	Nonce       pi.AccountNonce
+	Beneficiary proto.AccountAddress
+	Amount      uint64
+	TokenType   TokenType
+	Schedule    VestingSchedule
+	Revocable   bool
+	Fee         uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *CreateVestingHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// CreateVesting defines the vesting creation transaction, which locks Amount tokens of the sender
+// to the beneficiary until they are released by the schedule.
+type CreateVesting struct {
+	CreateVestingHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes CreateVesting using msgpack.
+func (t *CreateVesting) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes CreateVesting using msgpack.
+func (t *CreateVesting) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *CreateVesting) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *CreateVesting) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *CreateVesting) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *CreateVesting) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *CreateVesting) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *CreateVesting) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeCreateVesting
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *CreateVesting) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.CreateVestingHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Profile returns the profile of the created vesting.
+func (t *CreateVesting) Profile() *VestingProfile {
+	return &VestingProfile{
+		ID:          t.HeaderHash,
+		Creator:     t.Sender,
+		Beneficiary: t.Beneficiary,
+		TokenType:   t.TokenType,
+		Amount:      t.Amount,
+		Schedule:    t.Schedule,
+		Revocable:   t.Revocable,
+	}
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *CreateVesting) Verify() (err error) {
+	if t.TokenType < StableCoin || t.TokenType >= NumberOfTokenType {
+		return ErrInvalidTokenType
+	}
+	if t.Amount == 0 {
+		return ErrInvalidVesting
+	}
+	if err = t.Schedule.Verify(); err != nil {
+		return
+	}
+	var enc []byte
+	if enc, err = t.CreateVestingHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
+
+// ReleaseVestingHeader defines the vesting release transaction header.
+type ReleaseVestingHeader struct {
+	Sender  proto.AccountAddress // beneficiary of the vesting
+	Nonce   pi.AccountNonce
+	Vesting hash.Hash // hash of the creating transaction
+	Amount  uint64
+	Fee     uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *ReleaseVestingHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// ReleaseVesting defines the vesting release transaction, which is submitted by the beneficiary
+// to withdraw Amount of the vested tokens. The amount is given explicitly so the result doesn't
+// depend on the height of block packing the transaction.
+type ReleaseVesting struct {
+	ReleaseVestingHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes ReleaseVesting using msgpack.
+func (t *ReleaseVesting) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes ReleaseVesting using msgpack.
+func (t *ReleaseVesting) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *ReleaseVesting) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *ReleaseVesting) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *ReleaseVesting) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *ReleaseVesting) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *ReleaseVesting) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *ReleaseVesting) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeReleaseVesting
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *ReleaseVesting) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.ReleaseVestingHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *ReleaseVesting) Verify() (err error) {
+	if t.Amount == 0 {
+		return ErrInvalidVesting
+	}
+	var enc []byte
+	if enc, err = t.ReleaseVestingHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
+
+// RevokeVestingHeader defines the vesting revocation transaction header.
+type RevokeVestingHeader struct {
+	Sender  proto.AccountAddress // creator of the vesting
+	Nonce   pi.AccountNonce
+	Vesting hash.Hash // hash of the creating transaction
+	Fee     uint64
+}
+
+// MarshalHash marshals for hash.
+func (h *RevokeVestingHeader) MarshalHash() (o []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(h); err != nil {
+		return
+	}
+	o = enc.Bytes()
+	return
+}
+
+// RevokeVesting defines the vesting revocation transaction, which is submitted by the creator of
+// a revocable vesting to refund the tokens not vested yet, the vested ones are still released to
+// the beneficiary.
+type RevokeVesting struct {
+	RevokeVestingHeader
+	ChainID    pi.ChainID
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// Serialize serializes RevokeVesting using msgpack.
+func (t *RevokeVesting) Serialize() (b []byte, err error) {
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(t); err != nil {
+		return
+	}
+	b = enc.Bytes()
+	return
+}
+
+// Deserialize desrializes RevokeVesting using msgpack.
+func (t *RevokeVesting) Deserialize(enc []byte) error {
+	return utils.DecodeMsgPack(enc, t)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *RevokeVesting) GetAccountAddress() proto.AccountAddress {
+	return t.Sender
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *RevokeVesting) GetAccountNonce() pi.AccountNonce {
+	return t.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *RevokeVesting) GetFee() uint64 {
+	return t.Fee
+}
+
+// GetHash implements interfaces/Transaction.GetHash.
+func (t *RevokeVesting) GetHash() hash.Hash {
+	return t.HeaderHash
+}
+
+// GetChainID implements interfaces/Transaction.GetChainID.
+func (t *RevokeVesting) GetChainID() pi.ChainID {
+	return t.ChainID
+}
+
+// GetTransactionType implements interfaces/Transaction.GetTransactionType.
+func (t *RevokeVesting) GetTransactionType() pi.TransactionType {
+	return pi.TransactionTypeRevokeVesting
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *RevokeVesting) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var enc []byte
+	if enc, err = t.RevokeVestingHeader.MarshalHash(); err != nil {
+		return
+	}
+	var h = signedHash(t.ChainID, enc)
+	if t.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	t.HeaderHash = h
+	t.Signee = signer.PubKey()
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *RevokeVesting) Verify() (err error) {
+	var enc []byte
+	if enc, err = t.RevokeVestingHeader.MarshalHash(); err != nil {
+		return
+	}
+	return verifySender(t.Sender, signedHash(t.ChainID, enc), &t.HeaderHash, t.Signee, t.Signature)
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"math"
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestVestingSchedule_Vested(t *testing.T) {
+	schedule := &VestingSchedule{StartHeight: 100, CliffHeight: 150, EndHeight: 200}
+	if err := schedule.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	for _, v := range []struct {
+		amount uint64
+		height uint32
+		vested uint64
+	}{
+		{amount: 1000, height: 0, vested: 0},
+		{amount: 1000, height: 149, vested: 0},
+		{amount: 1000, height: 150, vested: 500},
+		{amount: 1000, height: 175, vested: 750},
+		{amount: 1000, height: 200, vested: 1000},
+		{amount: 1000, height: 300, vested: 1000},
+		{amount: 3, height: 160, vested: 1},
+		{amount: math.MaxUint64, height: 150, vested: math.MaxUint64 / 2},
+	} {
+		if vested := schedule.Vested(v.amount, v.height); vested != v.vested {
+			t.Fatalf("Unexpeted vested tokens at %d: %d, expected %d", v.height, vested, v.vested)
+		}
+	}
+
+	// time locked transfer
+	schedule = &VestingSchedule{StartHeight: 100, CliffHeight: 100, EndHeight: 100}
+	if err := schedule.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if vested := schedule.Vested(1000, 99); vested != 0 {
+		t.Fatalf("Unexpeted vested tokens: %d", vested)
+	}
+	if vested := schedule.Vested(1000, 100); vested != 1000 {
+		t.Fatalf("Unexpeted vested tokens: %d", vested)
+	}
+
+	for _, v := range []VestingSchedule{
+		{StartHeight: 100, CliffHeight: 99, EndHeight: 200},
+		{StartHeight: 100, CliffHeight: 201, EndHeight: 200},
+		{StartHeight: 201, CliffHeight: 201, EndHeight: 200},
+	} {
+		if err := v.Verify(); err != ErrInvalidVesting {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+	}
+}
+
+func TestVestingProfile_Revoked(t *testing.T) {
+	profile := &VestingProfile{
+		Amount:    1000,
+		Released:  200,
+		Schedule:  VestingSchedule{StartHeight: 100, CliffHeight: 100, EndHeight: 200},
+		Revocable: true,
+	}
+	if releasable := profile.Releasable(150); releasable != 300 {
+		t.Fatalf("Unexpeted releasable tokens: %d", releasable)
+	}
+	if unvested := profile.Unvested(150); unvested != 500 {
+		t.Fatalf("Unexpeted unvested tokens: %d", unvested)
+	}
+	if profile.Settled() {
+		t.Fatal("Unexpeted settled vesting")
+	}
+
+	// vesting stops at the revoked height
+	profile.Revoked, profile.RevokedHeight = true, 150
+	if releasable := profile.Releasable(300); releasable != 300 {
+		t.Fatalf("Unexpeted releasable tokens: %d", releasable)
+	}
+	if unvested := profile.Unvested(300); unvested != 500 {
+		t.Fatalf("Unexpeted unvested tokens: %d", unvested)
+	}
+	profile.Released += 300
+	if !profile.Settled() {
+		t.Fatal("Unexpeted unsettled vesting")
+	}
+}
+
+func TestCreateVesting_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	sender := proto.AccountAddress(hash.THashH(enc))
+
+	tx := &CreateVesting{
+		CreateVestingHeader: CreateVestingHeader{
+			Sender:      sender,
+			Nonce:       1,
+			Beneficiary: generateRandomAccountAddresses(1)[0],
+			Amount:      1000,
+			TokenType:   CovenantCoin,
+			Schedule:    VestingSchedule{StartHeight: 100, CliffHeight: 150, EndHeight: 200},
+			Revocable:   true,
+		},
+	}
+	if err = tx.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = tx.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if tx.GetTransactionType() != pi.TransactionTypeCreateVesting {
+		t.Fatalf("Unexpeted transaction type: %v", tx.GetTransactionType())
+	}
+	if profile := tx.Profile(); profile.ID != tx.HeaderHash || profile.Creator != sender {
+		t.Fatalf("Unexpeted vesting profile: %v", profile)
+	}
+
+	// encode and decode
+	b, err := tx.Serialize()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	dec := &CreateVesting{}
+	if err = dec.Deserialize(b); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = dec.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// tampered header
+	dec.Amount++
+	if err = dec.Verify(); err != ErrSignVerification {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	// invalid amount, schedule and token type
+	for _, v := range []struct {
+		amount   uint64
+		schedule VestingSchedule
+		token    TokenType
+		err      error
+	}{
+		{amount: 0, schedule: tx.Schedule, token: CovenantCoin, err: ErrInvalidVesting},
+		{amount: 1000, schedule: VestingSchedule{StartHeight: 200, EndHeight: 100}, token: CovenantCoin,
+			err: ErrInvalidVesting},
+		{amount: 1000, schedule: tx.Schedule, token: NumberOfTokenType, err: ErrInvalidTokenType},
+	} {
+		dec.Amount, dec.Schedule, dec.TokenType = v.amount, v.schedule, v.token
+		if err = dec.Sign(priv); err != nil {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+		if err = dec.Verify(); err != v.err {
+			t.Fatalf("Unexpeted error: %v", err)
+		}
+	}
+}
+
+func TestReleaseAndRevokeVesting_SignAndVerify(t *testing.T) {
+	priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	enc, err := pub.MarshalHash()
+	if err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	sender := proto.AccountAddress(hash.THashH(enc))
+	vesting := hash.THashH([]byte("vesting"))
+
+	release := &ReleaseVesting{
+		ReleaseVestingHeader: ReleaseVestingHeader{
+			Sender:  sender,
+			Nonce:   1,
+			Vesting: vesting,
+			Amount:  100,
+		},
+	}
+	if err = release.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = release.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if release.GetTransactionType() != pi.TransactionTypeReleaseVesting {
+		t.Fatalf("Unexpeted transaction type: %v", release.GetTransactionType())
+	}
+	release.Amount = 0
+	if err = release.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = release.Verify(); err != ErrInvalidVesting {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+
+	revoke := &RevokeVesting{
+		RevokeVestingHeader: RevokeVestingHeader{
+			Sender:  sender,
+			Nonce:   2,
+			Vesting: vesting,
+		},
+	}
+	if err = revoke.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = revoke.Verify(); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if revoke.GetTransactionType() != pi.TransactionTypeRevokeVesting {
+		t.Fatalf("Unexpeted transaction type: %v", revoke.GetTransactionType())
+	}
+	revoke.Sender = generateRandomAccountAddresses(1)[0]
+	if err = revoke.Sign(priv); err != nil {
+		t.Fatalf("Unexpeted error: %v", err)
+	}
+	if err = revoke.Verify(); err == nil {
+		t.Fatal("Unexpeted verified transaction signed by another account")
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 )
 
 var (
@@ -289,6 +290,44 @@ func generateRandomGasAmount(n uint32) []*proto.AddrAndGas {
 	return gasAmount
 }
 
+func generateSignedAck(priv *asymmetric.PrivateKey) (ack *wt.SignedAckHeader, err error) {
+	var (
+		pub = priv.PubKey()
+		now = time.Now().UTC()
+		req = wt.SignedRequestHeader{
+			RequestHeader: wt.RequestHeader{
+				QueryType:  wt.WriteQuery,
+				DatabaseID: *generateRandomDatabaseID(),
+				Timestamp:  now,
+				BatchCount: 1,
+			},
+			Signee: pub,
+		}
+	)
+	if err = req.Sign(priv); err != nil {
+		return
+	}
+	resp := wt.SignedResponseHeader{
+		ResponseHeader: wt.ResponseHeader{
+			Request:   req,
+			Timestamp: now,
+		},
+		Signee: pub,
+	}
+	if err = resp.Sign(priv); err != nil {
+		return
+	}
+	ack = &wt.SignedAckHeader{
+		AckHeader: wt.AckHeader{
+			Response:  resp,
+			Timestamp: now,
+		},
+		Signee: pub,
+	}
+	err = ack.Sign(priv)
+	return
+}
+
 func setup() {
 	rand.Seed(time.Now().UnixNano())
 	rand.Read(genesisHash[:])
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"sync"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// runParallel calls f with 0 to n-1 across at most workers goroutines and waits for them.
+func runParallel(n, workers int, f func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			f(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += workers {
+				f(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// verifyBlockTransactions verifies the transactions of block b across worker goroutines. The
+// signatures are checked independently, and the transactions of each account are checked to
+// have consecutive nonces in the block order, which is independent of the other accounts. The
+// error of the first invalid transaction in the block order is returned, so the result never
+// depends on scheduling.
+func verifyBlockTransactions(b *types.Block, workers int) (err error) {
+	var (
+		nb   = len(b.TxBillings)
+		errs = make([]error, nb+len(b.Transactions))
+	)
+	if len(b.Transactions) > maxTransactionsPerBlock {
+		return ErrTooManyTransactions
+	}
+	runParallel(len(errs), workers, func(i int) {
+		if i < nb {
+			errs[i] = b.TxBillings[i].Verify()
+		} else {
+			errs[i] = b.Transactions[i-nb].Verify()
+		}
+	})
+
+	// group transactions by account in the order of their first appearance
+	var (
+		accounts []proto.AccountAddress
+		groups   = make(map[proto.AccountAddress][]int)
+	)
+	for i, t := range b.Transactions {
+		addr := t.GetAccountAddress()
+		if _, ok := groups[addr]; !ok {
+			accounts = append(accounts, addr)
+		}
+		groups[addr] = append(groups[addr], i)
+	}
+	runParallel(len(accounts), workers, func(i int) {
+		var (
+			group = groups[accounts[i]]
+			last  pi.AccountNonce
+		)
+		for j, k := range group {
+			var (
+				nonce = b.Transactions[k].GetAccountNonce()
+				err   error
+			)
+			switch {
+			case j >= maxAccountTransactionsPerBlock:
+				err = ErrTooManyTransactions
+			case j > 0 && nonce != last+1:
+				err = ErrInvalidAccountNonce
+			default:
+				last = nonce
+				continue
+			}
+			// keep the signature error if any
+			if errs[nb+k] == nil {
+				errs[nb+k] = err
+			}
+			return
+		}
+	})
+
+	for _, err = range errs {
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"testing"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestVerifyBlockTransactions(t *testing.T) {
+	priv, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newTransfer := func(sender byte, nonce pi.AccountNonce) *pt.Transfer {
+		tx := &pt.Transfer{TransferHeader: pt.TransferHeader{
+			Sender:   proto.AccountAddress{sender},
+			Receiver: proto.AccountAddress{0xff},
+			Nonce:    nonce,
+			Amount:   1,
+		}}
+		if err := tx.Sign(priv); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return tx
+	}
+
+	var b = &pt.Block{}
+	for i := 0; i < 100; i++ {
+		// interleaved accounts with consecutive nonces
+		b.Transactions = append(b.Transactions, newTransfer(byte(i%3), pi.AccountNonce(10+i/3)))
+	}
+	for _, workers := range []int{1, 4, 16} {
+		if err = verifyBlockTransactions(b, workers); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the first invalid transaction in block order is reported by any number of workers
+	b.Transactions[90].(*pt.Transfer).Amount = 2
+	b.Transactions[40] = newTransfer(1, 100)
+	for _, workers := range []int{1, 4, 16} {
+		if err = verifyBlockTransactions(b, workers); err != ErrInvalidAccountNonce {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	b.Transactions[40] = newTransfer(1, 23)
+	for _, workers := range []int{1, 4, 16} {
+		if err = verifyBlockTransactions(b, workers); err != pt.ErrSignVerification {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// transactions of an account are limited
+	b.Transactions = nil
+	for i := 0; i <= maxAccountTransactionsPerBlock; i++ {
+		b.Transactions = append(b.Transactions, newTransfer(0, pi.AccountNonce(i)))
+	}
+	if err = verifyBlockTransactions(b, 4); err != ErrTooManyTransactions {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckChainID(t *testing.T) {
+	var (
+		c = &Chain{
+			st:               &State{},
+			chainID:          "testnet",
+			legacyTxDeadline: 10,
+		}
+		newTx = func(id pi.ChainID) *pt.Transfer {
+			return &pt.Transfer{ChainID: id}
+		}
+		err error
+	)
+	if err = c.checkChainID(newTx("testnet")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = c.checkChainID(newTx("mainnet")); err != ErrInvalidChainID {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// legacy transactions are accepted before deadline
+	if err = c.checkChainID(newTx("")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.st.setHeight(10)
+	if err = c.checkChainID(newTx("")); err != ErrLegacyTransaction {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.legacyTxDeadline = 0
+	if err = c.checkChainID(newTx("")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestVerifyProducedBlock(t *testing.T) {
+	c, cfg, priv, sender, cleanup := newTestProducer(t)
+	defer cleanup()
+	defer c.db.Close()
+	local, err := kms.GetLocalPrivateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a follower starting from the same genesis state
+	fcfg := *cfg
+	fcfg.DataFile = cfg.DataFile + ".follower"
+	f, err := NewChain(&fcfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.db.Close()
+	f.ms.readonly.accounts[sender] = c.ms.readonly.accounts[sender]
+
+	for i := 0; i < 3; i++ {
+		submitTestTransfer(t, c, priv, sender, proto.AccountAddress{0x2}, pi.AccountNonce(i))
+	}
+	// the follower receives another transaction of the same nonce in its memory pool
+	conflict := submitTestTransfer(t, f, priv, sender, proto.AccountAddress{0x3}, 0)
+	if err = c.produceBlock(cfg.Genesis.Timestamp().Add(testPeriod)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	produced, err := c.fetchBlockByHeight(c.st.getHeight())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	enc, err := produced.Serialize()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decode := func() *pt.Block {
+		b := &pt.Block{}
+		if err := b.Deserialize(enc); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return b
+	}
+	resign := func(b *pt.Block) *pt.Block {
+		if err := b.PackAndSignBlock(local); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return b
+	}
+
+	// tampered transactions are rejected by the follower
+	b := decode()
+	b.Transactions[1].(*pt.Transfer).Amount++
+	if err = f.pushBlock(b); err != pt.ErrSignVerification {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b = decode()
+	b.Transactions[0], b.Transactions[1] = b.Transactions[1], b.Transactions[0]
+	if err = f.pushBlock(b); err != ErrInvalidAccountNonce {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the first nonce of account is checked against the committed state
+	b = decode()
+	b.Transactions = b.Transactions[1:]
+	if err = f.pushBlock(resign(b)); err != ErrInvalidAccountNonce {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// transactions failing to apply reject the block signed by producer
+	b = decode()
+	overspent := &pt.Transfer{TransferHeader: pt.TransferHeader{
+		Sender:    sender,
+		Receiver:  proto.AccountAddress{0x2},
+		Amount:    10000,
+		TokenType: pt.StableCoin,
+	}}
+	if err = overspent.Sign(priv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b.Transactions = []pi.Transaction{overspent}
+	if err = f.pushBlock(resign(b)); err != ErrInsufficientBalance {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the meta root committing another state is rejected
+	b = decode()
+	b.SignedHeader.MetaRoot[0]++
+	if err = f.pushBlock(resign(b)); err != ErrInvalidMetaRoot {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.st.getHeight() != 0 {
+		t.Fatalf("unexpected follower height: %d", f.st.getHeight())
+	}
+	if !f.ms.isTxPending(conflict.GetHash()) {
+		t.Fatal("unexpected follower memory pool")
+	}
+
+	// the produced block is accepted
+	b = decode()
+	if len(b.Transactions) != 3 {
+		t.Fatalf("unexpected block transactions: %v", b.Transactions)
+	}
+	if err = f.pushBlock(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if head := f.st.getHeader(); !head.IsEqual(&produced.SignedHeader.BlockHash) {
+		t.Fatalf("unexpected follower head: %v", head)
+	}
+
+	// the follower commits the block transactions instead of its memory pool
+	for _, addr := range []proto.AccountAddress{
+		sender, {0x2}, {0x3}, produced.Producer(),
+	} {
+		stable, covenant, loaded := c.ms.loadAccountBalance(addr)
+		fstable, fcovenant, floaded := f.ms.loadAccountBalance(addr)
+		if stable != fstable || covenant != fcovenant || loaded != floaded {
+			t.Fatalf("unexpected follower balance of %v: %d %d %v, expected %d %d %v",
+				addr, fstable, fcovenant, floaded, stable, covenant, loaded)
+		}
+	}
+	if stable, covenant, _ := f.ms.loadAccountBalance(sender); stable != 970 || covenant != 997 {
+		t.Fatalf("unexpected sender balance: %d %d", stable, covenant)
+	}
+	if stable, _, _ := f.ms.loadAccountBalance(proto.AccountAddress{0x2}); stable != 30 {
+		t.Fatalf("unexpected receiver balance: %d", stable)
+	}
+	if _, _, loaded := f.ms.loadAccountBalance(proto.AccountAddress{0x3}); loaded {
+		t.Fatal("unexpected account created by conflicting transaction")
+	}
+
+	// receipts are indexed by the block transactions
+	var hashes []hash.Hash
+	for _, tx := range b.Transactions {
+		hashes = append(hashes, tx.GetHash())
+	}
+	hashes = append(hashes, conflict.GetHash())
+	receipts, err := f.queryTxReceipts(hashes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, r := range receipts[:3] {
+		if r.State != pi.TransactionStateConfirmed || r.Height != f.st.getHeight() {
+			t.Fatalf("unexpected receipt %d: %v", i, r)
+		}
+	}
+	if r := receipts[3]; r.State != pi.TransactionStateFailed || r.Reason != ErrInvalidAccountNonce.Error() {
+		t.Fatalf("unexpected receipt of conflicting transaction: %v", r)
+	}
+}
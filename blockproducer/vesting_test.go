@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"path"
+	"testing"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/coreos/bbolt"
+)
+
+func TestMetaState_Vesting(t *testing.T) {
+	db, err := bolt.Open(path.Join(testDataDir, t.Name()), 0600, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	if err = db.Update(func(tx *bolt.Tx) (err error) {
+		var meta *bolt.Bucket
+		if meta, err = tx.CreateBucketIfNotExists(metaBucket[:]); err != nil {
+			return
+		}
+		for _, b := range [][]byte{
+			metaAccountIndexBucket, metaSQLChainIndexBucket, metaMinerIndexBucket,
+			metaBillingIndexBucket, metaMultiSigIndexBucket, metaParamsIndexBucket,
+			metaVestingIndexBucket,
+		} {
+			if _, err = meta.CreateBucketIfNotExists(b); err != nil {
+				return
+			}
+		}
+		return
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var (
+		ms          = newMetaState()
+		creator     = proto.AccountAddress{0x1}
+		beneficiary = proto.AccountAddress{0x2}
+		create      = &pt.CreateVesting{CreateVestingHeader: pt.CreateVestingHeader{
+			Sender:      creator,
+			Beneficiary: beneficiary,
+			Amount:      1000,
+			TokenType:   pt.StableCoin,
+			Schedule:    pt.VestingSchedule{StartHeight: 100, CliffHeight: 150, EndHeight: 200},
+			Revocable:   true,
+		}}
+		release = func(amount uint64) *pt.ReleaseVesting {
+			return &pt.ReleaseVesting{ReleaseVestingHeader: pt.ReleaseVestingHeader{
+				Sender: beneficiary, Vesting: create.HeaderHash, Amount: amount,
+			}}
+		}
+		revoke = &pt.RevokeVesting{RevokeVestingHeader: pt.RevokeVestingHeader{
+			Sender: creator, Vesting: create.HeaderHash,
+		}}
+		balance = func(addr proto.AccountAddress) uint64 {
+			o, loaded := ms.loadAccountObject(addr)
+			if !loaded {
+				return 0
+			}
+			return o.StableCoinBalance
+		}
+	)
+	create.HeaderHash[0] = 0x1
+	revoke.Vesting = create.HeaderHash
+	ms.readonly.accounts[creator] = &accountObject{Account: pt.Account{
+		Address: creator, StableCoinBalance: 1500,
+	}}
+
+	// lock tokens
+	if err = ms.applyTransaction(create); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(create); err != ErrExistedTx {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := balance(creator); b != 500 {
+		t.Fatalf("unexpected creator balance: %d", b)
+	}
+
+	// nothing is released before cliff
+	ms.setHeight(149)
+	if err = ms.applyTransaction(release(1)); err != ErrInsufficientVested {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// release half of the vested tokens after cliff
+	ms.setHeight(150)
+	if err = ms.applyTransaction(release(501)); err != ErrInsufficientVested {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(&pt.ReleaseVesting{ReleaseVestingHeader: pt.ReleaseVestingHeader{
+		Sender: creator, Vesting: create.HeaderHash, Amount: 1,
+	}}); err != ErrPermissionDenied {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(release(250)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := balance(beneficiary); b != 250 {
+		t.Fatalf("unexpected beneficiary balance: %d", b)
+	}
+
+	// commit and reload
+	if err = db.Update(ms.commitProcedure()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = db.View(ms.reloadProcedure()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	profile, loaded := ms.loadConfirmedVesting(create.HeaderHash)
+	if !loaded || profile.Released != 250 || profile.Beneficiary != beneficiary {
+		t.Fatalf("unexpected vesting: %v", profile)
+	}
+	if snap := ms.snapshot(); len(snap.Vestings) != 1 || snap.Vestings[0].ID != create.HeaderHash {
+		t.Fatalf("unexpected snapshot vestings: %v", snap.Vestings)
+	}
+
+	// revoke refunds the unvested tokens
+	ms.setHeight(175)
+	if err = ms.applyTransaction(&pt.RevokeVesting{RevokeVestingHeader: pt.RevokeVestingHeader{
+		Sender: beneficiary, Vesting: create.HeaderHash,
+	}}); err != ErrPermissionDenied {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(revoke); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(revoke); err != ErrVestingRevoked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := balance(creator); b != 750 {
+		t.Fatalf("unexpected creator balance: %d", b)
+	}
+
+	// the vested tokens are still released after revocation, and the vesting is removed once
+	// settled
+	ms.setHeight(300)
+	if err = ms.applyTransaction(release(501)); err != ErrInsufficientVested {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(release(500)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := balance(beneficiary); b != 750 {
+		t.Fatalf("unexpected beneficiary balance: %d", b)
+	}
+	if err = ms.applyTransaction(release(1)); err != ErrVestingNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = db.Update(ms.commitProcedure()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, loaded = ms.loadConfirmedVesting(create.HeaderHash); loaded {
+		t.Fatal("unexpected settled vesting kept in state")
+	}
+
+	// irrevocable vesting
+	create.HeaderHash[0], create.Amount, create.Revocable = 0x2, 500, false
+	revoke.Vesting = create.HeaderHash
+	if err = ms.applyTransaction(create); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(revoke); err != ErrVestingNotRevocable {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ms.applyTransaction(create); err != ErrExistedTx {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
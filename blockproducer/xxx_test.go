@@ -32,6 +32,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 )
 
 var (
@@ -319,6 +320,49 @@ func generateRandomGasAmount(n uint32) []*proto.AddrAndGas {
 	return gasAmount
 }
 
+// generateSignedAck generates a query ack of database dbID, of which the request, response and
+// ack are all signed by priv.
+func generateSignedAck(
+	dbID proto.DatabaseID, qt wt.QueryType, count uint64, priv *asymmetric.PrivateKey,
+) (ack *wt.SignedAckHeader, err error) {
+	var (
+		pub = priv.PubKey()
+		now = time.Now().UTC()
+		req = wt.SignedRequestHeader{
+			RequestHeader: wt.RequestHeader{
+				QueryType:  qt,
+				DatabaseID: dbID,
+				SeqNo:      rand.Uint64(),
+				Timestamp:  now,
+				BatchCount: count,
+			},
+			Signee: pub,
+		}
+	)
+	if err = req.Sign(priv); err != nil {
+		return
+	}
+	resp := wt.SignedResponseHeader{
+		ResponseHeader: wt.ResponseHeader{
+			Request:   req,
+			Timestamp: now,
+		},
+		Signee: pub,
+	}
+	if err = resp.Sign(priv); err != nil {
+		return
+	}
+	ack = &wt.SignedAckHeader{
+		AckHeader: wt.AckHeader{
+			Response:  resp,
+			Timestamp: now,
+		},
+		Signee: pub,
+	}
+	err = ack.Sign(priv)
+	return
+}
+
 func generateRandomHash() hash.Hash {
 	h := hash.Hash{}
 	rand.Read(h[:])
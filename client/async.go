@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+const (
+	// DefaultAsyncQueueSize defines the default pending writes limit of AsyncWriter.
+	DefaultAsyncQueueSize = 4096
+	// DefaultAsyncBatchSize defines the default max writes committed in a single write request.
+	DefaultAsyncBatchSize = 256
+)
+
+// AckLevel defines when an async write is reported as completed.
+type AckLevel int
+
+const (
+	// AckNone reports completion as soon as the write is queued, failures are only logged.
+	AckNone AckLevel = iota
+	// AckLeader reports completion when the leader responds to the write request. Database
+	// peers commit writes with two-phase commit, so the leader responds after all peers committed.
+	AckLeader
+)
+
+type asyncWrite struct {
+	level AckLevel
+	query string
+	args  []interface{}
+	done  chan error
+}
+
+// AsyncWriter pipelines writes to a database without blocking the caller on consensus.
+// Queued writes are committed in batches, each batch is sent in a single write request.
+// If a batch is proved not applied, e.g. rejected by the database, its writes are retried one
+// by one so that a failing write does not fail the others. Otherwise the batch may have been
+// applied, and the error is reported to every write of the batch without retrying.
+type AsyncWriter struct {
+	db        *sql.DB
+	batchSize int
+
+	queue  chan *asyncWrite
+	lock   sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewAsyncWriter returns an async writer of database, non-positive queueSize and batchSize
+// are replaced with DefaultAsyncQueueSize and DefaultAsyncBatchSize.
+func NewAsyncWriter(db *sql.DB, queueSize int, batchSize int) (w *AsyncWriter) {
+	if queueSize <= 0 {
+		queueSize = DefaultAsyncQueueSize
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultAsyncBatchSize
+	}
+
+	w = &AsyncWriter{
+		db:        db,
+		batchSize: batchSize,
+		queue:     make(chan *asyncWrite, queueSize),
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return
+}
+
+// Exec queues a write query, it blocks only if the queue is full. The returned channel
+// receives the result of the write once according to level and is closed afterwards.
+func (w *AsyncWriter) Exec(ctx context.Context, level AckLevel, query string, args ...interface{}) (
+	done <-chan error, err error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		err = ErrAsyncWriterClosed
+		return
+	}
+
+	write := &asyncWrite{
+		level: level,
+		query: query,
+		args:  args,
+		done:  make(chan error, 1),
+	}
+
+	select {
+	case w.queue <- write:
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	}
+
+	if level == AckNone {
+		close(write.done)
+	}
+
+	done = write.done
+	return
+}
+
+// Close stops accepting writes and waits for all queued writes to complete.
+func (w *AsyncWriter) Close() {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.lock.Unlock()
+
+	w.wg.Wait()
+}
+
+func (w *AsyncWriter) run() {
+	defer w.wg.Done()
+
+	batch := make([]*asyncWrite, 0, w.batchSize)
+
+	for write := range w.queue {
+		batch = append(batch, write)
+
+		// collect queued writes without waiting
+	collect:
+		for len(batch) < w.batchSize {
+			select {
+			case write, ok := <-w.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, write)
+			default:
+				break collect
+			}
+		}
+
+		w.commit(batch)
+		batch = batch[:0]
+	}
+}
+
+func (w *AsyncWriter) commit(batch []*asyncWrite) {
+	// writes are only queued locally until commit, the batch is not applied if failed before
+	var committing bool
+	err := func() (err error) {
+		var tx *sql.Tx
+		if tx, err = w.db.Begin(); err != nil {
+			return
+		}
+		for _, write := range batch {
+			if _, err = tx.Exec(write.query, write.args...); err != nil {
+				tx.Rollback()
+				return
+			}
+		}
+		committing = true
+		return tx.Commit()
+	}()
+
+	if err == nil {
+		for _, write := range batch {
+			write.ack(nil)
+		}
+		return
+	}
+
+	if len(batch) == 1 || (committing && !isRejectedError(err)) {
+		log.Warningf("async write batch of %d queries failed: %v", len(batch), err)
+		for _, write := range batch {
+			write.ack(err)
+		}
+		return
+	}
+
+	// writes of the failed batch are not applied, retry them individually
+	log.Warningf("async write batch of %d queries failed, retrying individually: %v", len(batch), err)
+	for _, write := range batch {
+		_, err = w.db.Exec(write.query, write.args...)
+		if err != nil {
+			log.Warningf("async write failed: %v", err)
+		}
+		write.ack(err)
+	}
+}
+
+// ack reports the result of write according to its ack level.
+func (aw *asyncWrite) ack(err error) {
+	if aw.level != AckNone {
+		aw.done <- err
+		close(aw.done)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	Convey("test async writes", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+
+		w := NewAsyncWriter(db, 0, 10)
+
+		var dones []<-chan error
+		for i := 0; i < 50; i++ {
+			var done <-chan error
+			level := AckLeader
+			if i%2 == 0 {
+				level = AckNone
+			}
+			done, err = w.Exec(context.Background(), level, "insert into test values (?)", i)
+			So(err, ShouldBeNil)
+			dones = append(dones, done)
+		}
+
+		// the invalid query fails alone, other writes of its batch are retried individually
+		var badDone, nextDone <-chan error
+		badDone, err = w.Exec(context.Background(), AckLeader, "THIS IS NOT A SQL!!!")
+		So(err, ShouldBeNil)
+		nextDone, err = w.Exec(context.Background(), AckLeader, "insert into test values (?)", 50)
+		So(err, ShouldBeNil)
+
+		w.Close()
+
+		for _, done := range dones {
+			So(<-done, ShouldBeNil)
+		}
+		So(<-badDone, ShouldNotBeNil)
+		So(<-nextDone, ShouldBeNil)
+
+		var count int
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 51)
+
+		_, err = w.Exec(context.Background(), AckLeader, "insert into test values (1)")
+		So(err, ShouldEqual, ErrAsyncWriterClosed)
+	})
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	// DefaultBlobChunkSize defines the default size threshold of chunked blob values.
+	DefaultBlobChunkSize = 1 << 20
+
+	// BlobChunkTable defines the managed table storing content-addressed blob chunks.
+	BlobChunkTable = "__cql_blob_chunks"
+)
+
+var (
+	// blobRefMagic prefixes the reference value stored in place of a chunked blob,
+	// followed by uint64 blob length and hashes of chunks.
+	blobRefMagic = []byte("\x00CQLBLOB\x01")
+)
+
+// isBlobRef returns if the value is a reference to chunked blob.
+func isBlobRef(b []byte) bool {
+	return len(b) >= len(blobRefMagic)+8 &&
+		(len(b)-len(blobRefMagic)-8)%hash.HashSize == 0 &&
+		bytes.HasPrefix(b, blobRefMagic)
+}
+
+// chunkBlobArgs stores blob arguments larger than chunk size to chunk table, each chunk is
+// written in a separate request to keep replicated log entries small. The blob arguments
+// are replaced with references to chunks.
+func (c *conn) chunkBlobArgs(ctx context.Context, query *wt.Query) (err error) {
+	if c.blobChunkSize <= 0 {
+		return
+	}
+
+	for i, arg := range query.Args {
+		blob, ok := arg.Value.([]byte)
+		if !ok || len(blob) <= c.blobChunkSize {
+			continue
+		}
+
+		var ref []byte
+		if ref, err = c.storeBlob(ctx, blob); err != nil {
+			return
+		}
+		query.Args[i].Value = ref
+	}
+
+	return
+}
+
+func (c *conn) storeBlob(ctx context.Context, blob []byte) (ref []byte, err error) {
+	if !c.blobTableReady {
+		if _, _, err = c.sendQuery(ctx, wt.WriteQuery, []wt.Query{{
+			Pattern: fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" ("hash" BLOB PRIMARY KEY NOT NULL, "data" BLOB)`,
+				BlobChunkTable),
+		}}, noopSpan{}); err != nil {
+			return
+		}
+		c.blobTableReady = true
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(blobRefMagic)
+	binary.Write(buf, binary.BigEndian, uint64(len(blob)))
+
+	for start := 0; start < len(blob); start += c.blobChunkSize {
+		end := start + c.blobChunkSize
+		if end > len(blob) {
+			end = len(blob)
+		}
+
+		chunk := blob[start:end]
+		h := hash.THashH(chunk)
+
+		// identical chunks are stored once
+		if _, _, err = c.sendQuery(ctx, wt.WriteQuery, []wt.Query{{
+			Pattern: fmt.Sprintf(`INSERT OR IGNORE INTO "%s" ("hash", "data") VALUES (?, ?)`, BlobChunkTable),
+			Args:    []sql.NamedArg{sql.Named("", h[:]), sql.Named("", chunk)},
+		}}, noopSpan{}); err != nil {
+			return
+		}
+
+		buf.Write(h[:])
+	}
+
+	ref = buf.Bytes()
+	return
+}
+
+// decodeBlobs enables reassembling chunked blobs referenced in rows.
+func (c *conn) decodeBlobs(dr driver.Rows) {
+	if r, ok := dr.(*rows); ok {
+		r.blobConn = c
+	}
+}
+
+// loadBlob reassembles the chunked blob of reference.
+func (c *conn) loadBlob(ref []byte) (blob []byte, err error) {
+	length := binary.BigEndian.Uint64(ref[len(blobRefMagic):])
+	hashes := ref[len(blobRefMagic)+8:]
+
+	blob = make([]byte, 0, length)
+
+	for len(hashes) > 0 {
+		var h hash.Hash
+		copy(h[:], hashes[:hash.HashSize])
+		hashes = hashes[hash.HashSize:]
+
+		var rows driver.Rows
+		if rows, _, err = c.sendQuery(context.Background(), wt.ReadQuery, []wt.Query{{
+			Pattern: fmt.Sprintf(`SELECT "data" FROM "%s" WHERE "hash" = ? LIMIT 1`, BlobChunkTable),
+			Args:    []sql.NamedArg{sql.Named("", h[:])},
+		}}, noopSpan{}); err != nil {
+			return
+		}
+
+		dest := make([]driver.Value, 1)
+		err = rows.Next(dest)
+		rows.Close()
+		if err == io.EOF {
+			err = ErrBlobChunkNotFound
+			return
+		} else if err != nil {
+			return
+		}
+
+		chunk, _ := dest[0].([]byte)
+		if hash.THashH(chunk) != h {
+			err = ErrBlobChunkCorrupted
+			return
+		}
+
+		blob = append(blob, chunk...)
+	}
+
+	if uint64(len(blob)) != length {
+		err = ErrBlobChunkCorrupted
+	}
+
+	return
+}
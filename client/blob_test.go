@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBlobChunk(t *testing.T) {
+	Convey("test chunked blob storage", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db, dbNoChunk *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db?blob_chunk_size=1024")
+		So(err, ShouldBeNil)
+		defer db.Close()
+		dbNoChunk, err = sql.Open("covenantsql", "covenantsql://db?blob_chunk_size=0")
+		So(err, ShouldBeNil)
+		defer dbNoChunk.Close()
+
+		_, err = db.Exec("create table test (id int, data blob)")
+		So(err, ShouldBeNil)
+
+		// 4 identical chunks and a partial chunk
+		blob := append(bytes.Repeat([]byte{'a'}, 4096), []byte("tail")...)
+		small := []byte("small blob")
+
+		_, err = db.Exec("insert into test values (?, ?), (?, ?)", 1, blob, 2, small)
+		So(err, ShouldBeNil)
+
+		var chunks int
+		err = db.QueryRow(fmt.Sprintf(`select count(1) from "%s"`, BlobChunkTable)).Scan(&chunks)
+		So(err, ShouldBeNil)
+		So(chunks, ShouldEqual, 2)
+
+		for _, d := range []*sql.DB{db, dbNoChunk} {
+			var data []byte
+			err = d.QueryRow("select data from test where id = 1").Scan(&data)
+			So(err, ShouldBeNil)
+			So(data, ShouldResemble, blob)
+			err = d.QueryRow("select data from test where id = 2").Scan(&data)
+			So(err, ShouldBeNil)
+			So(data, ShouldResemble, small)
+		}
+
+		// missing chunk
+		_, err = db.Exec(fmt.Sprintf(`delete from "%s"`, BlobChunkTable))
+		So(err, ShouldBeNil)
+		var data []byte
+		err = db.QueryRow("select data from test where id = 1").Scan(&data)
+		So(err, ShouldEqual, ErrBlobChunkNotFound)
+	})
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"net"
+	netrpc "net/rpc"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	"github.com/CovenantSQL/CovenantSQL/rpc"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	// BPProbeTimeout defines the timeout of health checking each block producer endpoint.
+	BPProbeTimeout = 3 * time.Second
+
+	// bpEndpoints holds block producer endpoints configured by DSN, shared by all connections
+	// as block producer requests are not bound to a database.
+	bpEndpoints = &bpSelector{}
+
+	// probeBPEndpoint checks if the endpoint is reachable, the elapsed time is used as latency.
+	probeBPEndpoint = func(ctx context.Context, endpoint BPEndpoint) (err error) {
+		var dialer net.Dialer
+		var c net.Conn
+		if c, err = dialer.DialContext(ctx, "tcp", endpoint.Addr); err != nil {
+			return
+		}
+		return c.Close()
+	}
+)
+
+// BPEndpoint defines a block producer node with its address, formatted as nodeID@host:port in DSN.
+type BPEndpoint struct {
+	NodeID proto.NodeID
+	Addr   string
+}
+
+// String implements fmt.Stringer for BPEndpoint.
+func (e BPEndpoint) String() string {
+	return string(e.NodeID) + "@" + e.Addr
+}
+
+func formatBPEndpoints(endpoints []BPEndpoint) string {
+	s := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		s = append(s, e.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func parseBPEndpoints(s string) (endpoints []BPEndpoint, err error) {
+	for _, item := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "@", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			err = ErrInvalidParameter
+			return
+		}
+		e := BPEndpoint{NodeID: proto.NodeID(parts[0]), Addr: parts[1]}
+		if e.NodeID.ToRawNodeID() == nil {
+			err = ErrInvalidParameter
+			return
+		}
+		if _, _, err = net.SplitHostPort(e.Addr); err != nil {
+			err = ErrInvalidParameter
+			return
+		}
+		endpoints = append(endpoints, e)
+	}
+	return
+}
+
+type bpEndpointState struct {
+	BPEndpoint
+	latency time.Duration
+	healthy bool
+}
+
+// bpSelector keeps block producer endpoints ordered by health and latency, the first endpoint
+// is used as current block producer and requests fail over along the order.
+type bpSelector struct {
+	sync.Mutex
+	endpoints []*bpEndpointState
+	timeout   time.Duration
+}
+
+// add registers new endpoints and health checks all endpoints if any of them is new.
+func (s *bpSelector) add(endpoints []BPEndpoint, timeout time.Duration) (err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if timeout > 0 {
+		s.timeout = timeout
+	}
+
+	var added bool
+	for _, e := range endpoints {
+		if s.find(e.NodeID) >= 0 {
+			continue
+		}
+		if err = route.AddBPNode(e.NodeID.ToRawNodeID(), e.Addr); err != nil {
+			return
+		}
+		s.endpoints = append(s.endpoints, &bpEndpointState{BPEndpoint: e})
+		added = true
+	}
+
+	if added {
+		s.probe()
+	}
+
+	return
+}
+
+func (s *bpSelector) find(nodeID proto.NodeID) int {
+	for i, e := range s.endpoints {
+		if e.NodeID == nodeID {
+			return i
+		}
+	}
+	return -1
+}
+
+// probe health checks all endpoints concurrently and picks the fastest as current block producer.
+func (s *bpSelector) probe() {
+	var wg sync.WaitGroup
+	for _, e := range s.endpoints {
+		wg.Add(1)
+		go func(e *bpEndpointState) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), BPProbeTimeout)
+			defer cancel()
+			start := time.Now()
+			err := probeBPEndpoint(ctx, e.BPEndpoint)
+			e.latency = time.Since(start)
+			e.healthy = err == nil
+			if err != nil {
+				log.Warningf("block producer endpoint %s is unreachable: %v", e, err)
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	sort.SliceStable(s.endpoints, func(i, j int) bool {
+		if s.endpoints[i].healthy != s.endpoints[j].healthy {
+			return s.endpoints[i].healthy
+		}
+		return s.endpoints[i].latency < s.endpoints[j].latency
+	})
+
+	if len(s.endpoints) > 0 {
+		rpc.SetCurrentBP(s.endpoints[0].NodeID)
+	}
+}
+
+// candidates returns endpoints in fail over order.
+func (s *bpSelector) candidates() (endpoints []BPEndpoint, timeout time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	endpoints = make([]BPEndpoint, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		endpoints = append(endpoints, e.BPEndpoint)
+	}
+	timeout = s.timeout
+	return
+}
+
+// demote moves the failed endpoint to the end and makes the next endpoint current block producer.
+func (s *bpSelector) demote(nodeID proto.NodeID) {
+	s.Lock()
+	defer s.Unlock()
+
+	i := s.find(nodeID)
+	if i < 0 {
+		return
+	}
+	e := s.endpoints[i]
+	e.healthy = false
+	s.endpoints = append(append(s.endpoints[:i:i], s.endpoints[i+1:]...), e)
+	rpc.SetCurrentBP(s.endpoints[0].NodeID)
+}
+
+// promote marks the endpoint healthy after a successful request.
+func (s *bpSelector) promote(nodeID proto.NodeID) {
+	s.Lock()
+	defer s.Unlock()
+
+	if i := s.find(nodeID); i >= 0 {
+		s.endpoints[i].healthy = true
+	}
+}
+
+// isBPFailoverError reports if the error is caused by failing to reach the block producer rather
+// than returned by the block producer, only the former is worth failing over.
+func isBPFailoverError(err error) bool {
+	_, ok := err.(netrpc.ServerError)
+	return !ok
+}
+
+// isTxThrottledError reports if the transaction submission is rejected by the rate limits of
+// block producer.
+func isTxThrottledError(err error) bool {
+	serverErr, ok := err.(netrpc.ServerError)
+	return ok && strings.HasPrefix(string(serverErr), bp.ErrTxThrottled.Error())
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/conf"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/rpc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBPEndpoints(t *testing.T) {
+	Convey("test block producer endpoints failover", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		origProbe := probeBPEndpoint
+		defer func() {
+			probeBPEndpoint = origProbe
+			bpEndpoints = &bpSelector{}
+			rpc.SetCurrentBP(conf.GConf.BP.NodeID)
+		}()
+
+		realBP := BPEndpoint{NodeID: conf.GConf.BP.NodeID, Addr: conf.GConf.ListenAddr}
+		deadBP := BPEndpoint{
+			NodeID: proto.NodeID("00000000000000000000000000000000000000000000000000000000000000dd"),
+			Addr:   "127.0.0.1:1",
+		}
+		slowBP := BPEndpoint{
+			NodeID: proto.NodeID("00000000000000000000000000000000000000000000000000000000000000ee"),
+			Addr:   "127.0.0.1:2",
+		}
+
+		// health check orders endpoints by latency and puts unreachable ones last
+		probeBPEndpoint = func(ctx context.Context, e BPEndpoint) error {
+			switch e.NodeID {
+			case slowBP.NodeID:
+				return errors.New("unreachable")
+			case realBP.NodeID:
+				time.Sleep(50 * time.Millisecond)
+			}
+			return nil
+		}
+		err = bpEndpoints.add([]BPEndpoint{slowBP, realBP, deadBP}, time.Second)
+		So(err, ShouldBeNil)
+		endpoints, timeout := bpEndpoints.candidates()
+		So(endpoints, ShouldResemble, []BPEndpoint{deadBP, realBP, slowBP})
+		So(timeout, ShouldEqual, time.Second)
+		current, err := rpc.GetCurrentBP()
+		So(err, ShouldBeNil)
+		So(current, ShouldEqual, deadBP.NodeID)
+
+		// known endpoints are not checked again
+		err = bpEndpoints.add([]BPEndpoint{realBP}, 0)
+		So(err, ShouldBeNil)
+		endpoints, _ = bpEndpoints.candidates()
+		So(endpoints, ShouldHaveLength, 3)
+
+		// request fails over to the reachable endpoint
+		_, err = ListDatabases()
+		So(err, ShouldBeNil)
+		endpoints, _ = bpEndpoints.candidates()
+		So(endpoints, ShouldResemble, []BPEndpoint{realBP, slowBP, deadBP})
+		current, err = rpc.GetCurrentBP()
+		So(err, ShouldBeNil)
+		So(current, ShouldEqual, realBP.NodeID)
+
+		// server errors are returned without failing over
+		_, err = GetBalance()
+		So(err, ShouldNotBeNil)
+		endpoints, _ = bpEndpoints.candidates()
+		So(endpoints[0], ShouldResemble, realBP)
+	})
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+)
+
+const (
+	// MaxBulkInsertVariables defines the max bind variables count of a single bulk insert statement,
+	// which is the default SQLITE_MAX_VARIABLE_NUMBER of sqlite.
+	MaxBulkInsertVariables = 999
+)
+
+// BulkInsert inserts rows into table using multi-row insert statements. All statements are
+// committed in a single write request, which is processed by database peers in one consensus round.
+func BulkInsert(db *sql.DB, table string, columns []string, rows [][]interface{}) (err error) {
+	if !identifierRegex.MatchString(table) {
+		return ErrInvalidTableName
+	}
+	if len(columns) == 0 || len(columns) > MaxBulkInsertVariables {
+		return ErrInvalidColumnName
+	}
+	for _, c := range columns {
+		if !identifierRegex.MatchString(c) {
+			return ErrInvalidColumnName
+		}
+	}
+	for _, r := range rows {
+		if len(r) != len(columns) {
+			return ErrColumnCountInvalid
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	var tx *sql.Tx
+	if tx, err = db.Begin(); err != nil {
+		return
+	}
+
+	rowsPerStmt := MaxBulkInsertVariables / len(columns)
+
+	for start := 0; start < len(rows); start += rowsPerStmt {
+		end := start + rowsPerStmt
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		q, args := buildBulkInsert(table, columns, rows[start:end])
+		if _, err = tx.Exec(q, args...); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+func buildBulkInsert(table string, columns []string, rows [][]interface{}) (q string, args []interface{}) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, `INSERT INTO "%s" (`, table)
+	for i, c := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, `"%s"`, c)
+	}
+	buf.WriteString(") VALUES ")
+
+	args = make([]interface{}, 0, len(rows)*len(columns))
+
+	for i, r := range rows {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteByte('(')
+		for j := range r {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteByte('?')
+		}
+		buf.WriteByte(')')
+		args = append(args, r...)
+	}
+
+	q = buf.String()
+	return
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"database/sql"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuildBulkInsert(t *testing.T) {
+	Convey("test build bulk insert statement", t, func() {
+		q, args := buildBulkInsert("test", []string{"a", "b"}, [][]interface{}{{1, "x"}, {2, "y"}})
+		So(q, ShouldEqual, `INSERT INTO "test" ("a", "b") VALUES (?, ?), (?, ?)`)
+		So(args, ShouldResemble, []interface{}{1, "x", 2, "y"})
+	})
+}
+
+func TestBulkInsert(t *testing.T) {
+	Convey("test bulk insert", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (a int, b text)")
+		So(err, ShouldBeNil)
+
+		rows := make([][]interface{}, 1000)
+		for i := range rows {
+			rows[i] = []interface{}{i, "value"}
+		}
+
+		err = BulkInsert(db, "invalid table", []string{"a", "b"}, rows)
+		So(err, ShouldEqual, ErrInvalidTableName)
+		err = BulkInsert(db, "test", []string{"a", "b;"}, rows)
+		So(err, ShouldEqual, ErrInvalidColumnName)
+		err = BulkInsert(db, "test", []string{"a"}, rows)
+		So(err, ShouldEqual, ErrColumnCountInvalid)
+		err = BulkInsert(db, "test", []string{"a", "b"}, nil)
+		So(err, ShouldBeNil)
+
+		err = BulkInsert(db, "test", []string{"a", "b"}, rows)
+		So(err, ShouldBeNil)
+
+		var count int
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1000)
+
+		// failure in any statement rollbacks whole batch
+		err = BulkInsert(db, "not_exists", []string{"a", "b"}, rows)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/route"
+	"github.com/CovenantSQL/CovenantSQL/rpc"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+var (
+	// DefaultCacheRefreshInterval defines the default max age of known committed index used
+	// to validate cached results, an older index is refreshed by probing a database peer.
+	DefaultCacheRefreshInterval = time.Second
+)
+
+type cacheEntry struct {
+	key      string
+	index    uint64
+	response *wt.Response
+}
+
+// queryCache caches complete read query results in LRU order, an entry is only valid while
+// the known committed index of database is not advanced after the result is produced.
+type queryCache struct {
+	sync.Mutex
+	size      int
+	entries   map[string]*list.Element
+	lru       *list.List
+	index     uint64
+	checkedAt time.Time
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// cacheKey returns cache key of queries, both statements and arguments are included.
+func cacheKey(queries []wt.Query) (key string, err error) {
+	var buf *bytes.Buffer
+	if buf, err = utils.EncodeMsgPack(queries); err != nil {
+		return
+	}
+	key = buf.String()
+	return
+}
+
+// observe records committed index reported by database peers.
+func (qc *queryCache) observe(index uint64) {
+	qc.Lock()
+	defer qc.Unlock()
+
+	if index > qc.index {
+		qc.index = index
+	}
+	qc.checkedAt = time.Now()
+}
+
+// stale returns if the known committed index is older than maxAge.
+func (qc *queryCache) stale(maxAge time.Duration) bool {
+	qc.Lock()
+	defer qc.Unlock()
+
+	return time.Since(qc.checkedAt) >= maxAge
+}
+
+func (qc *queryCache) get(key string) *wt.Response {
+	qc.Lock()
+	defer qc.Unlock()
+
+	e, ok := qc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := e.Value.(*cacheEntry)
+	if entry.index < qc.index {
+		// database changed since the result is produced
+		qc.lru.Remove(e)
+		delete(qc.entries, key)
+		return nil
+	}
+
+	qc.lru.MoveToFront(e)
+	return entry.response
+}
+
+func (qc *queryCache) put(key string, index uint64, response *wt.Response) {
+	qc.Lock()
+	defer qc.Unlock()
+
+	if index < qc.index {
+		return
+	}
+
+	if e, ok := qc.entries[key]; ok {
+		e.Value = &cacheEntry{key: key, index: index, response: response}
+		qc.lru.MoveToFront(e)
+		return
+	}
+
+	qc.entries[key] = qc.lru.PushFront(&cacheEntry{key: key, index: index, response: response})
+
+	for qc.lru.Len() > qc.size {
+		e := qc.lru.Back()
+		qc.lru.Remove(e)
+		delete(qc.entries, e.Value.(*cacheEntry).key)
+	}
+}
+
+// cachedResponse returns cached result of queries, the committed index is refreshed first if it is stale.
+func (c *conn) cachedResponse(ctx context.Context, key string) *wt.Response {
+	if c.cache.stale(c.cacheRefresh) {
+		req := &wt.StatusReq{
+			DatabaseID: c.dbID,
+		}
+		res := new(wt.StatusResp)
+
+		callCtx, cancel := withTimeout(ctx, c.queryTimeout)
+		err := rpc.NewCaller().CallNodeWithContext(callCtx, c.pickTarget(wt.ReadQuery, c.consistency), route.DBSStatus.String(), req, res)
+		cancel()
+
+		if err != nil {
+			c.log("refresh committed index failed ", err.Error())
+			return nil
+		}
+
+		c.cache.observe(res.CommittedIndex)
+	}
+
+	return c.cache.get(key)
+}
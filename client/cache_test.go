@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"database/sql"
+	"testing"
+
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQueryCacheLRU(t *testing.T) {
+	Convey("test query cache eviction and invalidation", t, func() {
+		qc := newQueryCache(2)
+		r1, r2, r3 := &wt.Response{}, &wt.Response{}, &wt.Response{}
+
+		qc.put("q1", 1, r1)
+		qc.put("q2", 1, r2)
+		So(qc.get("q1"), ShouldEqual, r1)
+
+		// q2 is the least recently used
+		qc.put("q3", 1, r3)
+		So(qc.get("q2"), ShouldBeNil)
+		So(qc.get("q1"), ShouldEqual, r1)
+		So(qc.get("q3"), ShouldEqual, r3)
+
+		// database advanced
+		qc.observe(2)
+		So(qc.get("q1"), ShouldBeNil)
+		So(qc.get("q3"), ShouldBeNil)
+
+		// result produced before known index is not cached
+		qc.put("q1", 1, r1)
+		So(qc.get("q1"), ShouldBeNil)
+		qc.put("q1", 2, r1)
+		So(qc.get("q1"), ShouldEqual, r1)
+	})
+}
+
+func TestQueryCache(t *testing.T) {
+	Convey("test query result cache", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db, dbNoRefresh, dbWriter *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db?cache_size=10&cache_refresh=1ns&consistency=eventual")
+		So(err, ShouldBeNil)
+		defer db.Close()
+		dbNoRefresh, err = sql.Open("covenantsql", "covenantsql://db?cache_size=10&cache_refresh=1h&consistency=eventual")
+		So(err, ShouldBeNil)
+		defer dbNoRefresh.Close()
+		dbWriter, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer dbWriter.Close()
+		db.SetMaxOpenConns(1)
+		dbNoRefresh.SetMaxOpenConns(1)
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into test values (1)")
+		So(err, ShouldBeNil)
+
+		count := func(db *sql.DB) (c int) {
+			So(db.QueryRow("select count(1) from test").Scan(&c), ShouldBeNil)
+			return
+		}
+
+		So(count(db), ShouldEqual, 1)
+		So(count(dbNoRefresh), ShouldEqual, 1)
+
+		// write on the same connection invalidates cache
+		_, err = dbNoRefresh.Exec("insert into test values (2)")
+		So(err, ShouldBeNil)
+		So(count(dbNoRefresh), ShouldEqual, 2)
+
+		// write by others is noticed after refresh interval only
+		_, err = dbWriter.Exec("insert into test values (3)")
+		So(err, ShouldBeNil)
+		So(count(db), ShouldEqual, 3)
+		So(count(dbNoRefresh), ShouldEqual, 2)
+	})
+}
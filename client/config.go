@@ -18,12 +18,26 @@ package client
 
 import (
 	"net/url"
+	"strconv"
 	"time"
 )
 
 const (
 	paramKeyDebug          = "debug"
 	paramKeyUpdateInterval = "update_interval"
+	paramKeyQueryTimeout   = "query_timeout"
+	paramKeyDialTimeout    = "dial_timeout"
+	paramKeyMaxRetries     = "max_retries"
+	paramKeyMaxOpenConns   = "max_open_conns"
+	paramKeyMaxIdleConns   = "max_idle_conns"
+	paramKeyFetchSize      = "fetch_size"
+	paramKeyCacheSize      = "cache_size"
+	paramKeyCacheRefresh   = "cache_refresh"
+	paramKeyBlobChunkSize  = "blob_chunk_size"
+	paramKeyAsOfHeight     = "as_of_height"
+	paramKeyAsOfTime       = "as_of_time"
+	paramKeyConsistency    = "consistency"
+	paramKeyBPEndpoints    = "bp"
 )
 
 var (
@@ -38,16 +52,60 @@ type Config struct {
 	Debug               bool
 	PeersUpdateInterval time.Duration
 
-	// additional configs should be filled
-	// such as read/write/exec timeout
-	// currently no timeout is supported.
+	// QueryTimeout limits a single query request to the database, zero means no timeout.
+	QueryTimeout time.Duration
+	// DialTimeout limits block producer requests such as peers discovery, zero means no timeout.
+	DialTimeout time.Duration
+	// MaxRetries defines the max retry count of a failed query request.
+	MaxRetries int
+
+	// MaxOpenConns and MaxIdleConns defines the connection pool sizing applied by Open,
+	// zero leaves the database/sql defaults untouched.
+	MaxOpenConns int
+	MaxIdleConns int
+
+	// FetchSize defines the row count fetched in each round trip of a read query, remaining rows
+	// are kept in a server side cursor until Rows.Next requires them, zero fetches all rows at once.
+	FetchSize int
+
+	// CacheSize defines the max count of read query results cached by each connection, cached
+	// results are invalidated once the database commits new writes, zero disables the cache. Only
+	// eventual consistent reads are served from the cache.
+	CacheSize int
+	// CacheRefreshInterval defines how often the committed index of database is checked to
+	// validate cached results, results may be stale within this interval.
+	CacheRefreshInterval time.Duration
+
+	// BlobChunkSize defines the size threshold of blob values, larger blobs in write queries are
+	// stored as chunks of this size in a side table and reassembled on read, zero disables chunking.
+	BlobChunkSize int
+
+	// AsOfHeight and AsOfTime make read queries served on the historical state of database when
+	// the block at AsOfHeight is produced or at AsOfTime, AsOfHeight takes precedence and zero
+	// values mean the latest state. Connections with historical state specified are read-only,
+	// the state can also be specified per query with WithAsOfHeight and WithAsOfTime.
+	AsOfHeight int32
+	AsOfTime   time.Time
+
+	// Consistency defines the default consistency level of read queries, strong consistent reads
+	// are served by leader while eventual consistent reads are balanced to all peers. Reads are
+	// strong consistent unless eventual consistency is opted in, the level can also be specified
+	// per query with WithConsistency.
+	Consistency Consistency
+
+	// BPEndpoints defines the block producers requests are sent to, endpoints are health checked
+	// and the fastest one is used, requests fail over to the next endpoint on timeouts or
+	// connection failures. Empty list leaves block producer discovered from config file.
+	BPEndpoints []BPEndpoint
 }
 
 // NewConfig creates a new config with default value.
 func NewConfig() *Config {
 	return &Config{
-		Debug:               false,
-		PeersUpdateInterval: DefaultPeersUpdateInterval,
+		Debug:                false,
+		PeersUpdateInterval:  DefaultPeersUpdateInterval,
+		CacheRefreshInterval: DefaultCacheRefreshInterval,
+		BlobChunkSize:        DefaultBlobChunkSize,
 	}
 }
 
@@ -70,6 +128,58 @@ func (cfg *Config) FormatDSN() string {
 		newQuery.Set(paramKeyUpdateInterval, cfg.PeersUpdateInterval.String())
 	}
 
+	if cfg.QueryTimeout != 0 {
+		newQuery.Set(paramKeyQueryTimeout, cfg.QueryTimeout.String())
+	}
+
+	if cfg.DialTimeout != 0 {
+		newQuery.Set(paramKeyDialTimeout, cfg.DialTimeout.String())
+	}
+
+	if cfg.MaxRetries != 0 {
+		newQuery.Set(paramKeyMaxRetries, strconv.Itoa(cfg.MaxRetries))
+	}
+
+	if cfg.MaxOpenConns != 0 {
+		newQuery.Set(paramKeyMaxOpenConns, strconv.Itoa(cfg.MaxOpenConns))
+	}
+
+	if cfg.MaxIdleConns != 0 {
+		newQuery.Set(paramKeyMaxIdleConns, strconv.Itoa(cfg.MaxIdleConns))
+	}
+
+	if cfg.FetchSize != 0 {
+		newQuery.Set(paramKeyFetchSize, strconv.Itoa(cfg.FetchSize))
+	}
+
+	if cfg.CacheSize != 0 {
+		newQuery.Set(paramKeyCacheSize, strconv.Itoa(cfg.CacheSize))
+	}
+
+	if cfg.CacheRefreshInterval != DefaultCacheRefreshInterval {
+		newQuery.Set(paramKeyCacheRefresh, cfg.CacheRefreshInterval.String())
+	}
+
+	if cfg.BlobChunkSize != DefaultBlobChunkSize {
+		newQuery.Set(paramKeyBlobChunkSize, strconv.Itoa(cfg.BlobChunkSize))
+	}
+
+	if cfg.AsOfHeight != 0 {
+		newQuery.Set(paramKeyAsOfHeight, strconv.Itoa(int(cfg.AsOfHeight)))
+	}
+
+	if !cfg.AsOfTime.IsZero() {
+		newQuery.Set(paramKeyAsOfTime, cfg.AsOfTime.Format(time.RFC3339Nano))
+	}
+
+	if cfg.Consistency != ConsistencyStrong {
+		newQuery.Set(paramKeyConsistency, cfg.Consistency.String())
+	}
+
+	if len(cfg.BPEndpoints) > 0 {
+		newQuery.Set(paramKeyBPEndpoints, formatBPEndpoints(cfg.BPEndpoints))
+	}
+
 	u.RawQuery = newQuery.Encode()
 
 	return u.String()
@@ -92,10 +202,103 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 	}
 	if updateInterval := urlQuery.Get(paramKeyUpdateInterval); updateInterval != "" {
 		// parse update interval
-		if cfg.PeersUpdateInterval, err = time.ParseDuration(updateInterval); err != nil {
+		if cfg.PeersUpdateInterval, err = parseDuration(updateInterval); err != nil {
+			return
+		}
+		if cfg.PeersUpdateInterval == 0 {
+			err = ErrInvalidParameter
+			return
+		}
+	}
+	if queryTimeout := urlQuery.Get(paramKeyQueryTimeout); queryTimeout != "" {
+		if cfg.QueryTimeout, err = parseDuration(queryTimeout); err != nil {
+			return
+		}
+	}
+	if dialTimeout := urlQuery.Get(paramKeyDialTimeout); dialTimeout != "" {
+		if cfg.DialTimeout, err = parseDuration(dialTimeout); err != nil {
+			return
+		}
+	}
+	if maxRetries := urlQuery.Get(paramKeyMaxRetries); maxRetries != "" {
+		if cfg.MaxRetries, err = parseCount(maxRetries); err != nil {
+			return
+		}
+	}
+	if maxOpenConns := urlQuery.Get(paramKeyMaxOpenConns); maxOpenConns != "" {
+		if cfg.MaxOpenConns, err = parseCount(maxOpenConns); err != nil {
+			return
+		}
+	}
+	if maxIdleConns := urlQuery.Get(paramKeyMaxIdleConns); maxIdleConns != "" {
+		if cfg.MaxIdleConns, err = parseCount(maxIdleConns); err != nil {
+			return
+		}
+	}
+	if fetchSize := urlQuery.Get(paramKeyFetchSize); fetchSize != "" {
+		if cfg.FetchSize, err = parseCount(fetchSize); err != nil {
+			return
+		}
+	}
+	if cacheSize := urlQuery.Get(paramKeyCacheSize); cacheSize != "" {
+		if cfg.CacheSize, err = parseCount(cacheSize); err != nil {
+			return
+		}
+	}
+	if cacheRefresh := urlQuery.Get(paramKeyCacheRefresh); cacheRefresh != "" {
+		if cfg.CacheRefreshInterval, err = parseDuration(cacheRefresh); err != nil {
+			return
+		}
+	}
+	if blobChunkSize := urlQuery.Get(paramKeyBlobChunkSize); blobChunkSize != "" {
+		if cfg.BlobChunkSize, err = parseCount(blobChunkSize); err != nil {
+			return
+		}
+	}
+	if asOfHeight := urlQuery.Get(paramKeyAsOfHeight); asOfHeight != "" {
+		var height int
+		if height, err = parseCount(asOfHeight); err != nil {
+			return
+		}
+		cfg.AsOfHeight = int32(height)
+	}
+	if asOfTime := urlQuery.Get(paramKeyAsOfTime); asOfTime != "" {
+		if cfg.AsOfTime, err = time.Parse(time.RFC3339Nano, asOfTime); err != nil {
+			return
+		}
+	}
+	if consistency := urlQuery.Get(paramKeyConsistency); consistency != "" {
+		if cfg.Consistency, err = parseConsistency(consistency); err != nil {
+			return
+		}
+	}
+	if endpoints := urlQuery.Get(paramKeyBPEndpoints); endpoints != "" {
+		if cfg.BPEndpoints, err = parseBPEndpoints(endpoints); err != nil {
 			return
 		}
 	}
 
 	return
 }
+
+// parseDuration parses a non-negative duration parameter.
+func parseDuration(s string) (d time.Duration, err error) {
+	if d, err = time.ParseDuration(s); err != nil {
+		return
+	}
+	if d < 0 {
+		err = ErrInvalidParameter
+	}
+	return
+}
+
+// parseCount parses a non-negative integer parameter.
+func parseCount(s string) (n int, err error) {
+	if n, err = strconv.Atoi(s); err != nil {
+		return
+	}
+	if n < 0 {
+		err = ErrInvalidParameter
+	}
+	return
+}
@@ -47,5 +47,85 @@ func TestConfig(t *testing.T) {
 		cfg.Debug = true
 		cfg.PeersUpdateInterval = DefaultPeersUpdateInterval
 		So(cfg.FormatDSN(), ShouldEqual, "covenantsql://db?debug=true")
+
+		// test timeout, retry, pool, fetch and cache parameters
+		cfg, err = ParseDSN("covenantsql://db?query_timeout=3s&dial_timeout=500ms&max_retries=2" +
+			"&max_open_conns=10&max_idle_conns=5&fetch_size=100&cache_size=20&cache_refresh=5s&blob_chunk_size=2048")
+		So(err, ShouldBeNil)
+		So(cfg.QueryTimeout, ShouldEqual, 3*time.Second)
+		So(cfg.DialTimeout, ShouldEqual, 500*time.Millisecond)
+		So(cfg.MaxRetries, ShouldEqual, 2)
+		So(cfg.MaxOpenConns, ShouldEqual, 10)
+		So(cfg.MaxIdleConns, ShouldEqual, 5)
+		So(cfg.FetchSize, ShouldEqual, 100)
+		So(cfg.CacheSize, ShouldEqual, 20)
+		So(cfg.CacheRefreshInterval, ShouldEqual, 5*time.Second)
+		So(cfg.BlobChunkSize, ShouldEqual, 2048)
+
+		var cfg2 *Config
+		cfg2, err = ParseDSN(cfg.FormatDSN())
+		So(err, ShouldBeNil)
+		So(cfg2, ShouldResemble, cfg)
+
+		// test historical state parameters
+		cfg, err = ParseDSN("covenantsql://db?as_of_height=10&as_of_time=2018-08-01T10:00:00.5Z")
+		So(err, ShouldBeNil)
+		So(cfg.AsOfHeight, ShouldEqual, 10)
+		So(cfg.AsOfTime.Equal(time.Date(2018, 8, 1, 10, 0, 0, 5e8, time.UTC)), ShouldBeTrue)
+		cfg2, err = ParseDSN(cfg.FormatDSN())
+		So(err, ShouldBeNil)
+		So(cfg2, ShouldResemble, cfg)
+
+		// test consistency parameter
+		cfg, err = ParseDSN("covenantsql://db")
+		So(err, ShouldBeNil)
+		So(cfg.Consistency, ShouldEqual, ConsistencyStrong)
+		cfg, err = ParseDSN("covenantsql://db?consistency=eventual")
+		So(err, ShouldBeNil)
+		So(cfg.Consistency, ShouldEqual, ConsistencyEventual)
+		cfg2, err = ParseDSN(cfg.FormatDSN())
+		So(err, ShouldBeNil)
+		So(cfg2, ShouldResemble, cfg)
+
+		// test block producer endpoints parameter
+		nodeA := "00000000000000000000000000000000000000000000000000000000000000aa"
+		nodeB := "00000000000000000000000000000000000000000000000000000000000000bb"
+		cfg, err = ParseDSN("covenantsql://db?bp=" + nodeA + "@127.0.0.1:2120," + nodeB + "@[::1]:2121")
+		So(err, ShouldBeNil)
+		So(cfg.BPEndpoints, ShouldResemble, []BPEndpoint{
+			{NodeID: proto.NodeID(nodeA), Addr: "127.0.0.1:2120"},
+			{NodeID: proto.NodeID(nodeB), Addr: "[::1]:2121"},
+		})
+		cfg2, err = ParseDSN(cfg.FormatDSN())
+		So(err, ShouldBeNil)
+		So(cfg2, ShouldResemble, cfg)
+
+		// invalid parameters
+		_, err = ParseDSN("covenantsql://db?query_timeout=-1s")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?dial_timeout=abc")
+		So(err, ShouldNotBeNil)
+		_, err = ParseDSN("covenantsql://db?max_retries=-1")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?max_open_conns=x")
+		So(err, ShouldNotBeNil)
+		_, err = ParseDSN("covenantsql://db?fetch_size=-10")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?cache_size=-1")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?update_interval=0s")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?as_of_height=-1")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?as_of_time=yesterday")
+		So(err, ShouldNotBeNil)
+		_, err = ParseDSN("covenantsql://db?consistency=weak")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?bp=127.0.0.1:2120")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?bp=xyz@127.0.0.1:2120")
+		So(err, ShouldEqual, ErrInvalidParameter)
+		_, err = ParseDSN("covenantsql://db?bp=" + nodeA + "@localhost")
+		So(err, ShouldEqual, ErrInvalidParameter)
 	})
 }
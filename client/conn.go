@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"math/rand"
+	netrpc "net/rpc"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -37,6 +38,12 @@ import (
 	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 )
 
+const (
+	// MaxLeaderChangeRetries defines the max times of peers refreshing and query resending
+	// on leader change.
+	MaxLeaderChangeRetries = 3
+)
+
 var (
 	connectionID uint64
 	seqNo        uint64
@@ -54,7 +61,29 @@ type conn struct {
 	privKey   *asymmetric.PrivateKey
 	pubKey    *asymmetric.PublicKey
 
+	queryTimeout time.Duration
+	dialTimeout  time.Duration
+	maxRetries   int
+	fetchSize    int
+
+	// cache holds read query results, nil if cache is disabled.
+	cache        *queryCache
+	cacheRefresh time.Duration
+
+	blobChunkSize  int
+	blobTableReady bool
+
+	// asOf defines the default historical state of read queries.
+	asOf asOf
+	// consistency defines the default consistency level of read queries.
+	consistency Consistency
+
+	// peersHealth records the latest health score reported by each peer.
+	peersHealth     map[proto.NodeID]uint32
+	peersHealthLock sync.Mutex
+
 	inTransaction bool
+	txSpan        Span
 	closed        int32
 	closeCh       chan struct{}
 }
@@ -64,6 +93,10 @@ func newConn(cfg *Config) (c *conn, err error) {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	if err = bpEndpoints.add(cfg.BPEndpoints, cfg.DialTimeout); err != nil {
+		return
+	}
+
 	// init connectionID to random id
 	atomic.CompareAndSwapUint64(&connectionID, 0, randSource.Uint64())
 
@@ -92,6 +125,21 @@ func newConn(cfg *Config) (c *conn, err error) {
 		pubKey:  pubKey,
 		queries: make([]wt.Query, 0),
 		closeCh: make(chan struct{}),
+
+		queryTimeout: cfg.QueryTimeout,
+		dialTimeout:  cfg.DialTimeout,
+		maxRetries:   cfg.MaxRetries,
+		fetchSize:    cfg.FetchSize,
+		cacheRefresh: cfg.CacheRefreshInterval,
+
+		blobChunkSize: cfg.BlobChunkSize,
+		asOf:          asOf{height: cfg.AsOfHeight, time: cfg.AsOfTime},
+		consistency:   cfg.Consistency,
+		peersHealth:   make(map[proto.NodeID]uint32),
+	}
+
+	if cfg.CacheSize > 0 {
+		c.cache = newQueryCache(cfg.CacheSize)
 	}
 
 	c.log("new conn database ", c.dbID)
@@ -168,6 +216,7 @@ func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 	// TODO(xq262144): make use of the ctx argument
 	c.inTransaction = true
 	c.queries = c.queries[:0]
+	c.txSpan = startSpan(ctx, SpanTx, c.dbID)
 
 	return c, nil
 }
@@ -189,14 +238,28 @@ func (c *conn) ExecContext(ctx context.Context, query string, args []driver.Name
 		return
 	}
 
-	// TODO(xq262144): make use of the ctx argument
-	sq := convertQuery(query, args)
-	if _, err = c.addQuery(wt.WriteQuery, sq); err != nil {
+	// multiple statements are applied atomically in a single request
+	var queries []wt.Query
+	if queries, err = convertStatements(query, args); err != nil {
 		return
 	}
-
-	result = driver.ResultNoRows
-
+	if wt.IsExplainQueries(queries) {
+		// explain is served on leader like read query, plan rows are discarded by exec
+		ctx = WithConsistency(ctx, ConsistencyStrong)
+		if _, _, err = c.addQuery(ctx, wt.ReadQuery, queries); err == nil {
+			result = driver.ResultNoRows
+		}
+		return
+	}
+	// chunks are written before the query, even if the query is in transaction
+	for i := range queries {
+		if err = c.chunkBlobArgs(ctx, &queries[i]); err != nil {
+			return
+		}
+	}
+	if _, result, err = c.addQuery(ctx, wt.WriteQuery, queries); err == nil {
+		collectStatementResults(ctx, result)
+	}
 	return
 }
 
@@ -207,9 +270,26 @@ func (c *conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 		return
 	}
 
-	// TODO(xq262144): make use of the ctx argument
-	sq := convertQuery(query, args)
-	return c.addQuery(wt.ReadQuery, sq)
+	var sq *wt.Query
+	if sq, err = convertQuery(query, args); err != nil {
+		return
+	}
+	if sq.IsExplain() {
+		// query plan is explained by leader, where the latest schema and statistics exist
+		ctx = WithConsistency(ctx, ConsistencyStrong)
+	}
+	if rows, _, err = c.addQuery(ctx, wt.ReadQuery, []wt.Query{*sq}); err != nil {
+		return
+	}
+	c.decodeBlobs(rows)
+	return
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.CheckNamedValue method.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) (err error) {
+	// named values are accepted and rewritten to positional parameters on sending
+	nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	return
 }
 
 // Commit implements the driver.Tx.Commit method.
@@ -223,13 +303,15 @@ func (c *conn) Commit() (err error) {
 	}
 
 	defer func() {
+		c.txSpan.Finish(err)
 		c.queries = c.queries[:0]
 		c.inTransaction = false
 	}()
 
 	if len(c.queries) > 0 {
 		// send query
-		if _, err = c.sendQuery(wt.WriteQuery, c.queries); err != nil {
+		c.txSpan.SetTag(SpanTagStatementDigest, statementDigest(c.queries))
+		if _, _, err = c.sendQuery(context.Background(), wt.WriteQuery, c.queries, c.txSpan); err != nil {
 			return
 		}
 	}
@@ -248,6 +330,7 @@ func (c *conn) Rollback() error {
 	}
 
 	defer func() {
+		c.txSpan.Finish(nil)
 		c.queries = c.queries[:0]
 		c.inTransaction = false
 	}()
@@ -259,7 +342,23 @@ func (c *conn) Rollback() error {
 	return nil
 }
 
-func (c *conn) addQuery(queryType wt.QueryType, query *wt.Query) (rows driver.Rows, err error) {
+func (c *conn) addQuery(ctx context.Context, queryType wt.QueryType, queries []wt.Query) (
+	rows driver.Rows, result driver.Result, err error) {
+	operation := SpanExec
+	if queryType == wt.ReadQuery {
+		operation = SpanQuery
+	}
+	span := startSpan(ctx, operation, c.dbID)
+	span.SetTag(SpanTagStatementDigest, statementDigest(queries))
+	defer func() {
+		span.Finish(err)
+	}()
+
+	if queryType == wt.WriteQuery && c.asOfFromContext(ctx).isHistorical() {
+		err = ErrHistoricalWrite
+		return
+	}
+
 	if c.inTransaction {
 		// check query type, enqueue query
 		if queryType == wt.ReadQuery {
@@ -268,17 +367,33 @@ func (c *conn) addQuery(queryType wt.QueryType, query *wt.Query) (rows driver.Ro
 			return
 		}
 
-		// append queries
-		c.queries = append(c.queries, *query)
+		// append queries, exec result is not available until the transaction is committed
+		c.queries = append(c.queries, queries...)
+		result = driver.ResultNoRows
 		return
 	}
 
-	return c.sendQuery(queryType, []wt.Query{*query})
+	return c.sendQuery(ctx, queryType, queries, span)
 }
 
-func (c *conn) sendQuery(queryType wt.QueryType, queries []wt.Query) (rows driver.Rows, err error) {
-	c.peersLock.RLock()
-	defer c.peersLock.RUnlock()
+func (c *conn) sendQuery(ctx context.Context, queryType wt.QueryType, queries []wt.Query, span Span) (
+	rows driver.Rows, result driver.Result, err error) {
+	// historical and strong consistent results are not cached
+	historical := c.asOfFromContext(ctx)
+	consistency := c.consistencyFromContext(ctx)
+	useCache := c.cache != nil && !historical.isHistorical() && consistency != ConsistencyStrong
+
+	var key string
+	if queryType == wt.ReadQuery && useCache {
+		if key, err = cacheKey(queries); err != nil {
+			return
+		}
+		if cached := c.cachedResponse(ctx, key); cached != nil {
+			span.SetTag(SpanTagCacheHit, true)
+			rows = newRows(cached)
+			return
+		}
+	}
 
 	// build request
 	seqNo := atomic.AddUint64(&seqNo, 1)
@@ -299,13 +414,54 @@ func (c *conn) sendQuery(queryType wt.QueryType, queries []wt.Query) (rows drive
 		},
 	}
 
+	if queryType == wt.ReadQuery {
+		req.Header.FetchSize = uint64(c.fetchSize)
+		req.Header.AsOfHeight = historical.height
+		req.Header.AsOfTime = historical.time
+	}
+
 	if err = req.Sign(c.privKey); err != nil {
 		return
 	}
 
+	// trace context is carried in envelope, which is not covered by signature
+	injectSpan(span, req)
+
+	var target proto.NodeID
 	var response wt.Response
-	if err = rpc.NewCaller().CallNode(c.peers.Leader.ID, route.DBSQuery.String(), req, &response); err != nil {
-		if strings.Contains(err.Error(), "invalid request sequence") {
+
+	for i := 0; ; i++ {
+		target = c.pickTarget(queryType, consistency)
+		span.SetTag(SpanTagNodeID, string(target))
+
+		if err = c.callNode(ctx, target, route.DBSQuery, req, &response); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			if queryType == wt.ReadQuery {
+				// abort the query still running on the node
+				go c.cancelQuery(target, req.Header.ConnectionID, req.Header.SeqNo)
+			}
+			return
+		}
+		if i >= MaxLeaderChangeRetries || !isLeaderChangeError(err) {
+			break
+		}
+
+		// the same signed request is sent again after peers refreshing, the request sequence
+		// guarantees the write query is not applied twice if the previous one succeeded.
+		c.log("peer ", target, " unavailable, refresh peers and retry ", err.Error())
+
+		if perr := c.getPeers(); perr != nil {
+			c.log("refresh peers failed ", perr.Error())
+			return
+		}
+
+		stats.recordRetry()
+	}
+
+	if err != nil {
+		if isSequenceError(err) {
 			// request sequence failure, try again
 			atomic.StoreUint64(&connectionID, randSource.Uint64())
 			req.Header.ConnectionID = atomic.LoadUint64(&connectionID)
@@ -316,7 +472,8 @@ func (c *conn) sendQuery(queryType wt.QueryType, queries []wt.Query) (rows drive
 			}
 
 			// send request again
-			if err = rpc.NewCaller().CallNode(c.peers.Leader.ID, route.DBSQuery.String(), req, &response); err != nil {
+			stats.recordRetry()
+			if err = c.callNode(ctx, target, route.DBSQuery, req, &response); err != nil {
 				return
 			}
 		} else {
@@ -329,6 +486,16 @@ func (c *conn) sendQuery(queryType wt.QueryType, queries []wt.Query) (rows drive
 		return
 	}
 
+	c.recordHealth(response.Header.NodeID, response.Header.HealthScore)
+	stats.recordBytes(requestSize(req), response.Msgsize())
+
+	if useCache {
+		c.cache.observe(response.Header.CommittedIndex)
+		if queryType == wt.ReadQuery && response.Header.CursorID == 0 {
+			c.cache.put(key, response.Header.CommittedIndex, &response)
+		}
+	}
+
 	// build ack
 	ack := &wt.Ack{
 		Header: wt.SignedAckHeader{
@@ -348,16 +515,169 @@ func (c *conn) sendQuery(queryType wt.QueryType, queries []wt.Query) (rows drive
 	var ackRes wt.AckResponse
 
 	// send ack back
-	if err = rpc.NewCaller().CallNode(c.peers.Leader.ID, route.DBSAck.String(), ack, &ackRes); err != nil {
+	if err = rpc.NewCaller().CallNode(target, route.DBSAck.String(), ack, &ackRes); err != nil {
 		log.Warningf("ack query failed: %v", err)
 		err = nil
 	}
 
-	rows = newRows(&response)
+	r := newRows(&response)
+	if response.Header.CursorID != 0 {
+		// remaining rows are fetched lazily from the responding node
+		r.conn = c
+		r.nodeID = target
+		r.cursorID = response.Header.CursorID
+		r.requestHash = response.Header.Request.HeaderHash
+		r.signee = response.Header.Signee
+	}
+	rows = r
+	if queryType == wt.WriteQuery {
+		result = newExecResult(&response)
+	}
+
+	return
+}
+
+// fetchRows fetches next batch of rows from server side cursor of the node.
+func (c *conn) fetchRows(nodeID proto.NodeID, cursorID uint64) (res *wt.FetchResp, err error) {
+	req := &wt.FetchReq{
+		DatabaseID: c.dbID,
+		CursorID:   cursorID,
+		Count:      uint64(c.fetchSize),
+	}
+
+	// fetch is not idempotent, no retry is applied
+	ctx, cancel := withTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+
+	start := time.Now()
+	stats.recordRequest(false)
+	if err = rpc.NewCaller().CallNodeWithContext(ctx, nodeID, route.DBSFetch.String(), req, &res); err != nil {
+		return
+	}
+	stats.recordLatency(nodeID, time.Since(start))
+	stats.recordBytes(0, (&wt.ResponsePayload{Rows: res.Rows}).Msgsize())
 
 	return
 }
 
+// cancelQuery aborts the running query of the node, failures are ignored as the query may be finished.
+func (c *conn) cancelQuery(nodeID proto.NodeID, connID uint64, seqNo uint64) {
+	req := &wt.CancelReq{
+		DatabaseID:   c.dbID,
+		ConnectionID: connID,
+		SeqNo:        seqNo,
+	}
+	var res wt.CancelResp
+
+	ctx, cancel := withTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+
+	if err := rpc.NewCaller().CallNodeWithContext(ctx, nodeID, route.DBSCancel.String(), req, &res); err != nil {
+		c.log("cancel query failed ", err.Error())
+	}
+}
+
+// closeCursor releases server side cursor of the node.
+func (c *conn) closeCursor(nodeID proto.NodeID, cursorID uint64) (err error) {
+	req := &wt.CloseCursorReq{
+		DatabaseID: c.dbID,
+		CursorID:   cursorID,
+	}
+	var res wt.CloseCursorResp
+
+	ctx, cancel := withTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+
+	return rpc.NewCaller().CallNodeWithContext(ctx, nodeID, route.DBSCloseCursor.String(), req, &res)
+}
+
+// callNode sends request to the database peer with query timeout and retries applied.
+func (c *conn) callNode(ctx context.Context, nodeID proto.NodeID,
+	method route.RemoteFunc, req interface{}, res interface{}) (err error) {
+	var lastErr error
+
+	for i := 0; ; i++ {
+		callCtx, cancel := withTimeout(ctx, c.queryTimeout)
+		start := time.Now()
+		stats.recordRequest(i > 0)
+		err = rpc.NewCaller().CallNodeWithContext(callCtx, nodeID, method.String(), req, res)
+		cancel()
+
+		if err == nil {
+			stats.recordLatency(nodeID, time.Since(start))
+		}
+
+		if i > 0 && err != nil && isSequenceError(err) {
+			// the retried request shares the same sequence with the original one,
+			// the original request may already be processed, report the original failure
+			err = lastErr
+			return
+		}
+
+		if err == nil || i >= c.maxRetries || ctx.Err() != nil || isSequenceError(err) {
+			return
+		}
+
+		lastErr = err
+		c.log("call peer failed, retry ", i+1, " ", err.Error())
+	}
+}
+
+// pickTarget returns the peer to send query, write queries and strong consistent read queries
+// are sent to leader, other read queries are balanced by peers health.
+func (c *conn) pickTarget(queryType wt.QueryType, consistency Consistency) proto.NodeID {
+	c.peersLock.RLock()
+	defer c.peersLock.RUnlock()
+
+	if queryType == wt.WriteQuery || consistency == ConsistencyStrong {
+		return c.peers.Leader.ID
+	}
+
+	return c.pickReadPeer()
+}
+
+// pickReadPeer randomly picks a peer with probability weighted by its recent health score,
+// peers never responded are treated as fully healthy.
+func (c *conn) pickReadPeer() proto.NodeID {
+	c.peersHealthLock.Lock()
+	defer c.peersHealthLock.Unlock()
+
+	var total uint64
+	weights := make([]uint64, len(c.peers.Servers))
+	for i, s := range c.peers.Servers {
+		score, ok := c.peersHealth[s.ID]
+		if !ok {
+			score = wt.MaxHealthScore
+		}
+		weights[i] = uint64(score)
+		total += weights[i]
+	}
+
+	if total == 0 {
+		return c.peers.Leader.ID
+	}
+
+	r := uint64(rand.Int63n(int64(total)))
+	for i, w := range weights {
+		if r < w {
+			return c.peers.Servers[i].ID
+		}
+		r -= w
+	}
+
+	return c.peers.Leader.ID
+}
+
+func (c *conn) recordHealth(nodeID proto.NodeID, score uint32) {
+	c.peersHealthLock.Lock()
+	c.peersHealth[nodeID] = score
+	c.peersHealthLock.Unlock()
+
+	if h := getHooks(); h.OnPeerHealth != nil {
+		h.OnPeerHealth(c.dbID, nodeID, score)
+	}
+}
+
 func (c *conn) getPeers() (err error) {
 	c.peersLock.Lock()
 	defer c.peersLock.Unlock()
@@ -370,8 +690,11 @@ func (c *conn) getPeers() (err error) {
 		return
 	}
 
+	ctx, cancel := withTimeout(context.Background(), c.dialTimeout)
+	defer cancel()
+
 	res := new(bp.GetDatabaseResponse)
-	if err = requestBP(route.BPDBGetDatabase, req, res); err != nil {
+	if err = requestBPWithContext(ctx, route.BPDBGetDatabase, req, res); err != nil {
 		return
 	}
 
@@ -380,7 +703,12 @@ func (c *conn) getPeers() (err error) {
 		return
 	}
 
-	c.peers = res.Header.InstanceMeta.Peers
+	newPeers := res.Header.InstanceMeta.Peers
+	if c.peers != nil && c.peers.Leader != nil && newPeers != nil && newPeers.Leader != nil &&
+		c.peers.Leader.ID != newPeers.Leader.ID {
+		stats.recordLeaderSwitch()
+	}
+	c.peers = newPeers
 
 	return
 }
@@ -389,7 +717,46 @@ func getLocalTime() time.Time {
 	return time.Now().UTC()
 }
 
-func convertQuery(query string, args []driver.NamedValue) (sq *wt.Query) {
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// isLeaderChangeError reports if the error is caused by sending query to a non-leader node or
+// failing to reach the node, which indicates the peers list is outdated.
+func isLeaderChangeError(err error) bool {
+	if serverErr, ok := err.(netrpc.ServerError); ok {
+		// node address resolving failure is also reported by block producer as server error
+		return strings.Contains(string(serverErr), kayak.ErrNotLeader.Error()) ||
+			strings.Contains(string(serverErr), "from DHT failed")
+	}
+
+	return err != context.Canceled && err != context.DeadlineExceeded
+}
+
+func isSequenceError(err error) bool {
+	return strings.Contains(err.Error(), "invalid request sequence")
+}
+
+// isRejectedError reports whether err is a rejection of write request by database peers, which
+// proves the queries of the request are not applied. Leader change and sequence errors are not
+// considered rejections, since an earlier attempt of the same request may have been applied.
+func isRejectedError(err error) bool {
+	if _, ok := err.(netrpc.ServerError); ok {
+		return !isLeaderChangeError(err) && !isSequenceError(err)
+	}
+
+	return false
+}
+
+func convertQuery(query string, args []driver.NamedValue) (sq *wt.Query, err error) {
+	// rewrite named parameters to positional parameters
+	if query, args, err = bindNamedParams(query, args); err != nil {
+		return
+	}
+
 	// rebuild args to named args
 	sq = &wt.Query{
 		Pattern: query,
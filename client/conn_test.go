@@ -17,10 +17,16 @@
 package client
 
 import (
+	"context"
 	"database/sql"
+	netrpc "net/rpc"
 	"testing"
+	"time"
 
+	"github.com/CovenantSQL/CovenantSQL/kayak"
+	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -250,3 +256,286 @@ func TestTransaction(t *testing.T) {
 		So(err, ShouldNotBeNil)
 	})
 }
+
+func TestOpen(t *testing.T) {
+	Convey("test open with dsn options", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = Open("covenantsql://db?query_timeout=10s&max_retries=1&max_open_conns=2&max_idle_conns=1")
+		So(err, ShouldBeNil)
+		So(db, ShouldNotBeNil)
+		So(db.Stats().MaxOpenConnections, ShouldEqual, 2)
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into test values (1)")
+		So(err, ShouldBeNil)
+
+		var result int
+		err = db.QueryRow("select count(1) from test").Scan(&result)
+		So(err, ShouldBeNil)
+		So(result, ShouldEqual, 1)
+
+		db.Close()
+
+		_, err = Open("covenantsql://db?max_open_conns=-1")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestPeerHealthHook(t *testing.T) {
+	Convey("test peer health reporting", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var reported []uint32
+		SetHooks(&Hooks{
+			OnPeerHealth: func(dbID proto.DatabaseID, nodeID proto.NodeID, score uint32) {
+				So(dbID, ShouldEqual, proto.DatabaseID("db"))
+				reported = append(reported, score)
+			},
+		})
+		defer SetHooks(nil)
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		var result int
+		err = db.QueryRow("select count(1) from test").Scan(&result)
+		So(err, ShouldBeNil)
+
+		So(len(reported), ShouldEqual, 2)
+		for _, score := range reported {
+			So(score, ShouldBeGreaterThan, 0)
+			So(score, ShouldBeLessThanOrEqualTo, wt.MaxHealthScore)
+		}
+	})
+}
+
+func TestFetchSize(t *testing.T) {
+	Convey("test lazy rows fetching with fetch size", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db?fetch_size=2")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		for i := 0; i < 5; i++ {
+			_, err = db.Exec("insert into test values (?)", i)
+			So(err, ShouldBeNil)
+		}
+
+		// read all rows in batches
+		var rows *sql.Rows
+		rows, err = db.Query("select test from test order by test")
+		So(err, ShouldBeNil)
+
+		var result []int
+		for rows.Next() {
+			var v int
+			err = rows.Scan(&v)
+			So(err, ShouldBeNil)
+			result = append(result, v)
+		}
+		So(rows.Err(), ShouldBeNil)
+		So(rows.Close(), ShouldBeNil)
+		So(result, ShouldResemble, []int{0, 1, 2, 3, 4})
+
+		// close before all rows are fetched
+		rows, err = db.Query("select test from test order by test")
+		So(err, ShouldBeNil)
+		So(rows.Next(), ShouldBeTrue)
+		So(rows.Close(), ShouldBeNil)
+
+		// result smaller than fetch size
+		var count int
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 5)
+	})
+}
+
+func TestExecResult(t *testing.T) {
+	Convey("test exec result of write queries", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (id integer primary key, test int)")
+		So(err, ShouldBeNil)
+
+		var res sql.Result
+		var lastInsertID, affected int64
+		res, err = db.Exec("insert into test (test) values (1), (2), (3)")
+		So(err, ShouldBeNil)
+		lastInsertID, err = res.LastInsertId()
+		So(err, ShouldBeNil)
+		So(lastInsertID, ShouldEqual, 3)
+		affected, err = res.RowsAffected()
+		So(err, ShouldBeNil)
+		So(affected, ShouldEqual, 3)
+
+		res, err = db.Exec("update test set test = test + 1 where test > ?", 1)
+		So(err, ShouldBeNil)
+		affected, err = res.RowsAffected()
+		So(err, ShouldBeNil)
+		So(affected, ShouldEqual, 2)
+
+		// result is not available in transaction
+		var tx *sql.Tx
+		tx, err = db.Begin()
+		So(err, ShouldBeNil)
+		res, err = tx.Exec("delete from test")
+		So(err, ShouldBeNil)
+		_, err = res.RowsAffected()
+		So(err, ShouldNotBeNil)
+		So(tx.Commit(), ShouldBeNil)
+	})
+}
+
+func TestQueryCancel(t *testing.T) {
+	Convey("test cancel running query with context", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var result int
+		err = db.QueryRowContext(ctx,
+			"with recursive c(x) as (select 1 union all select x + 1 from c) select count(1) from c").Scan(&result)
+		So(err, ShouldNotBeNil)
+
+		// connection is still usable after cancellation
+		err = db.QueryRow("select 1").Scan(&result)
+		So(err, ShouldBeNil)
+		So(result, ShouldEqual, 1)
+	})
+}
+
+func TestLeaderChangeRetry(t *testing.T) {
+	Convey("test retry on leader change", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		So(isLeaderChangeError(netrpc.ServerError(kayak.ErrNotLeader.Error())), ShouldBeTrue)
+		So(isLeaderChangeError(netrpc.ServerError("invalid request sequence applied")), ShouldBeFalse)
+		So(isLeaderChangeError(netrpc.ErrShutdown), ShouldBeTrue)
+		So(isLeaderChangeError(context.Canceled), ShouldBeFalse)
+		So(isRejectedError(netrpc.ServerError("near \"THIS\": syntax error")), ShouldBeTrue)
+		So(isRejectedError(netrpc.ServerError(kayak.ErrNotLeader.Error())), ShouldBeFalse)
+		So(isRejectedError(netrpc.ServerError("invalid request sequence applied")), ShouldBeFalse)
+		So(isRejectedError(netrpc.ErrShutdown), ShouldBeFalse)
+
+		var cfg *Config
+		cfg, err = ParseDSN("covenantsql://db")
+		So(err, ShouldBeNil)
+
+		var c *conn
+		c, err = newConn(cfg)
+		So(err, ShouldBeNil)
+		defer c.Close()
+
+		// make peers outdated with an unreachable leader
+		c.peersLock.Lock()
+		peers := c.peers.Clone()
+		c.peers = &peers
+		c.peers.Leader = &kayak.Server{
+			Role: proto.Leader,
+			ID:   proto.NodeID("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		}
+		c.peersLock.Unlock()
+
+		var sq *wt.Query
+		sq, err = convertQuery("create table test (test int)", nil)
+		So(err, ShouldBeNil)
+		before := Stats()
+		_, _, err = c.addQuery(context.Background(), wt.WriteQuery, []wt.Query{*sq})
+		So(err, ShouldBeNil)
+		after := Stats()
+		So(after.LeaderSwitches, ShouldBeGreaterThan, before.LeaderSwitches)
+		So(after.Retries, ShouldBeGreaterThan, before.Retries)
+
+		c.peersLock.RLock()
+		So(c.peers.Leader.ID, ShouldNotEqual,
+			proto.NodeID("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+		c.peersLock.RUnlock()
+	})
+}
+
+func TestExplain(t *testing.T) {
+	Convey("test explain queries", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (id integer primary key, test int)")
+		So(err, ShouldBeNil)
+
+		var rows *sql.Rows
+		rows, err = db.Query("explain query plan select * from test where id = ?", 1)
+		So(err, ShouldBeNil)
+		var columns []string
+		columns, err = rows.Columns()
+		So(err, ShouldBeNil)
+		So(columns, ShouldContain, "detail")
+		var count int
+		for rows.Next() {
+			count++
+		}
+		So(rows.Err(), ShouldBeNil)
+		So(count, ShouldBeGreaterThan, 0)
+		rows.Close()
+
+		// explain of write statement is not applied
+		_, err = db.Exec("explain insert into test (test) values (1)")
+		So(err, ShouldBeNil)
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 0)
+	})
+}
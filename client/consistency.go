@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+)
+
+// Consistency defines the consistency level of read queries.
+type Consistency int
+
+const (
+	// ConsistencyStrong forces read queries served by the leader peer of the database, cached
+	// results are bypassed. It is the default consistency level.
+	ConsistencyStrong Consistency = iota
+	// ConsistencyEventual allows read queries served by any peer of the database, peers are
+	// balanced by their health, results may lag behind the latest writes on followers.
+	ConsistencyEventual
+)
+
+// String implements fmt.Stringer for Consistency.
+func (c Consistency) String() string {
+	switch c {
+	case ConsistencyStrong:
+		return "strong"
+	case ConsistencyEventual:
+		return "eventual"
+	default:
+		return "unknown"
+	}
+}
+
+func parseConsistency(s string) (c Consistency, err error) {
+	switch s {
+	case ConsistencyStrong.String():
+		c = ConsistencyStrong
+	case ConsistencyEventual.String():
+		c = ConsistencyEventual
+	default:
+		err = ErrInvalidParameter
+	}
+	return
+}
+
+type consistencyContextKey struct{}
+
+// WithConsistency returns a context which makes read queries issued with it served at
+// consistency level c regardless of the connection default.
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	return context.WithValue(ctx, consistencyContextKey{}, c)
+}
+
+// consistencyFromContext returns the consistency level specified by context, the connection
+// default is used if the context does not specify one.
+func (c *conn) consistencyFromContext(ctx context.Context) Consistency {
+	if level, ok := ctx.Value(consistencyContextKey{}).(Consistency); ok {
+		return level
+	}
+	return c.consistency
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/kayak"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConsistency(t *testing.T) {
+	Convey("test read consistency level of queries", t, func() {
+		leader := &kayak.Server{ID: proto.NodeID("leader")}
+		follower := &kayak.Server{ID: proto.NodeID("follower")}
+		c := &conn{
+			peers: &kayak.Peers{
+				Leader:  leader,
+				Servers: []*kayak.Server{leader, follower},
+			},
+			// leader is unhealthy, eventual consistent reads are served by follower
+			peersHealth: map[proto.NodeID]uint32{
+				leader.ID: 0,
+			},
+		}
+
+		So(c.consistencyFromContext(context.Background()), ShouldEqual, ConsistencyStrong)
+		ctx := WithConsistency(context.Background(), ConsistencyEventual)
+		So(c.consistencyFromContext(ctx), ShouldEqual, ConsistencyEventual)
+
+		for i := 0; i < 10; i++ {
+			So(c.pickTarget(wt.ReadQuery, ConsistencyEventual), ShouldEqual, follower.ID)
+			So(c.pickTarget(wt.ReadQuery, ConsistencyStrong), ShouldEqual, leader.ID)
+			So(c.pickTarget(wt.WriteQuery, ConsistencyEventual), ShouldEqual, leader.ID)
+		}
+
+		c.consistency = ConsistencyEventual
+		ctx = WithConsistency(context.Background(), ConsistencyStrong)
+		So(c.consistencyFromContext(context.Background()), ShouldEqual, ConsistencyEventual)
+		So(c.consistencyFromContext(ctx), ShouldEqual, ConsistencyStrong)
+	})
+	Convey("test strong consistent reads on database", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db?cache_size=10&consistency=eventual")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into test values (1)")
+		So(err, ShouldBeNil)
+
+		var count int
+		ctx := WithConsistency(context.Background(), ConsistencyStrong)
+		err = db.QueryRowContext(ctx, "select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+	})
+}
@@ -17,23 +17,30 @@
 package client
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"path/filepath"
+	"time"
 
 	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
 	"github.com/CovenantSQL/CovenantSQL/conf"
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/kayak"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/rpc"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
 	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 )
 
 const (
 	// PubKeyStorePath defines public cache store.
 	PubKeyStorePath = "./public.keystore"
+
+	// StatusProbeTimeout defines the timeout of probing serving status of each database peer.
+	StatusProbeTimeout = 5 * time.Second
 )
 
 func init() {
@@ -54,13 +61,67 @@ func (d *covenantSQLDriver) Open(dsn string) (conn driver.Conn, err error) {
 	return newConn(cfg)
 }
 
+// OpenConnector implements driver.DriverContext.OpenConnector method.
+func (d *covenantSQLDriver) OpenConnector(dsn string) (c driver.Connector, err error) {
+	var cfg *Config
+	if cfg, err = ParseDSN(dsn); err != nil {
+		return
+	}
+
+	return &connector{cfg: cfg, driver: d}, nil
+}
+
+// connector implements driver.Connector interface.
+type connector struct {
+	cfg    *Config
+	driver *covenantSQLDriver
+}
+
+// Connect implements driver.Connector.Connect method.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return newConn(c.cfg)
+}
+
+// Driver implements driver.Connector.Driver method.
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Open returns a database handle with connection pool sizing options of the DSN applied.
+func Open(dsn string) (db *sql.DB, err error) {
+	var cfg *Config
+	if cfg, err = ParseDSN(dsn); err != nil {
+		return
+	}
+
+	db = sql.OpenDB(&connector{cfg: cfg, driver: new(covenantSQLDriver)})
+
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+
+	return
+}
+
 // ResourceMeta defines new database resources requirement descriptions.
 type ResourceMeta wt.ResourceMeta
 
+// MatchConstraints defines the miners allowed to serve a database, see ResourceMeta.Constraints.
+type MatchConstraints = wt.MatchConstraints
+
 // Init defines init process for client.
 func Init(configFile string, masterKey []byte) (err error) {
+	return InitProfile(configFile, "", masterKey)
+}
+
+// InitProfile defines init process for client with the named profile of config, the profile
+// selected by config is used if profile is empty.
+func InitProfile(configFile string, profile string, masterKey []byte) (err error) {
 	// load config
-	if conf.GConf, err = conf.LoadConfig(configFile); err != nil {
+	if conf.GConf, err = conf.LoadConfigProfile(configFile, profile); err != nil {
 		return
 	}
 	pubKeyFilePath := filepath.Join(conf.GConf.WorkingRoot, PubKeyStorePath)
@@ -78,6 +139,11 @@ func Init(configFile string, masterKey []byte) (err error) {
 
 // Create send create database operation to block producer.
 func Create(meta ResourceMeta) (dsn string, err error) {
+	if meta.ConsistencyLevel < 0 || meta.ConsistencyLevel > 1 {
+		err = ErrInvalidResourceMeta
+		return
+	}
+
 	req := new(bp.CreateDatabaseRequest)
 	req.Header.ResourceMeta = wt.ResourceMeta(meta)
 	if req.Header.Signee, err = kms.GetLocalPublicKey(); err != nil {
@@ -106,12 +172,29 @@ func Create(meta ResourceMeta) (dsn string, err error) {
 	return
 }
 
+// PriceEstimate defines the estimated cost of database resource requirements.
+type PriceEstimate = bp.PriceEstimate
+
+// EstimatePrice queries block producer for the cost of database resource requirements meta.
+func EstimatePrice(meta ResourceMeta) (est *PriceEstimate, err error) {
+	req := &bp.EstimatePriceRequest{ResourceMeta: wt.ResourceMeta(meta)}
+	res := new(bp.EstimatePriceResponse)
+	if err = requestBP(route.BPDBEstimatePrice, req, res); err != nil {
+		return
+	}
+	est = &res.Estimate
+	return
+}
+
 // Drop send drop database operation to block producer.
 func Drop(dsn string) (err error) {
 	var cfg *Config
 	if cfg, err = ParseDSN(dsn); err != nil {
 		return
 	}
+	if err = bpEndpoints.add(cfg.BPEndpoints, cfg.DialTimeout); err != nil {
+		return
+	}
 
 	req := new(bp.DropDatabaseRequest)
 	req.Header.DatabaseID = proto.DatabaseID(cfg.DatabaseID)
@@ -131,13 +214,181 @@ func Drop(dsn string) (err error) {
 	return
 }
 
+// NodeStatus defines serving status of a database peer.
+type NodeStatus struct {
+	NodeID      proto.NodeID
+	Ready       bool
+	HealthScore uint32
+	// CommittedIndex defines the last committed log index of the peer.
+	CommittedIndex uint64
+	Err            error
+}
+
+// DatabaseStatus defines provisioning status of a database.
+type DatabaseStatus struct {
+	DatabaseID   proto.DatabaseID
+	ResourceMeta ResourceMeta
+	Peers        *kayak.Peers
+	Nodes        []NodeStatus
+	ReadyNodes   int
+}
+
+// Ready returns if all peers of the database are serving.
+func (s *DatabaseStatus) Ready() bool {
+	return len(s.Nodes) > 0 && s.ReadyNodes == len(s.Nodes)
+}
+
+// GetStatus returns the provisioning progress of database, peers allocated by block producer are
+// probed one by one to check if the database is served.
+func GetStatus(dbID proto.DatabaseID) (status *DatabaseStatus, err error) {
+	req := new(bp.GetDatabaseRequest)
+	req.Header.DatabaseID = dbID
+	if req.Header.Signee, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if err = req.Sign(privateKey); err != nil {
+		return
+	}
+	res := new(bp.GetDatabaseResponse)
+	if err = requestBP(route.BPDBGetDatabase, req, res); err != nil {
+		return
+	}
+	if err = res.Verify(); err != nil {
+		return
+	}
+
+	status = &DatabaseStatus{
+		DatabaseID:   dbID,
+		ResourceMeta: ResourceMeta(res.Header.InstanceMeta.ResourceMeta),
+		Peers:        res.Header.InstanceMeta.Peers,
+	}
+
+	if status.Peers == nil {
+		return
+	}
+
+	for _, s := range status.Peers.Servers {
+		nodeStatus := NodeStatus{
+			NodeID: s.ID,
+		}
+
+		probeReq := &wt.StatusReq{
+			DatabaseID: dbID,
+		}
+		probeRes := new(wt.StatusResp)
+
+		ctx, cancel := context.WithTimeout(context.Background(), StatusProbeTimeout)
+		nodeStatus.Err = rpc.NewCaller().CallNodeWithContext(ctx, s.ID, route.DBSStatus.String(), probeReq, probeRes)
+		cancel()
+
+		if nodeStatus.Err == nil {
+			nodeStatus.Ready = true
+			nodeStatus.HealthScore = probeRes.HealthScore
+			nodeStatus.CommittedIndex = probeRes.CommittedIndex
+			status.ReadyNodes++
+		}
+
+		status.Nodes = append(status.Nodes, nodeStatus)
+	}
+
+	return
+}
+
+// DatabaseInfo defines the block producer recorded profile of a database.
+type DatabaseInfo struct {
+	DatabaseID   proto.DatabaseID
+	Owner        proto.AccountAddress
+	ResourceMeta ResourceMeta
+	// Shared indicates that the database is not owned by but shared with the account.
+	Shared bool
+	// Nodes defines the count of peers serving the database.
+	Nodes int
+	// Space defines the storage space reserved for the database in bytes.
+	Space uint64
+	// Deposit defines the remaining deposit of the database.
+	Deposit uint64
+}
+
+// ListDatabases returns the databases owned by or shared with the local account.
+func ListDatabases() (databases []DatabaseInfo, err error) {
+	req := new(bp.ListDatabasesRequest)
+	if req.Header.Signee, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if err = req.Sign(privateKey); err != nil {
+		return
+	}
+	res := new(bp.ListDatabasesResponse)
+	if err = requestBP(route.BPDBListDatabases, req, res); err != nil {
+		return
+	}
+	if err = res.Verify(); err != nil {
+		return
+	}
+
+	databases = make([]DatabaseInfo, 0, len(res.Header.Databases))
+	for _, p := range res.Header.Databases {
+		info := DatabaseInfo{
+			DatabaseID:   p.InstanceMeta.DatabaseID,
+			Owner:        p.InstanceMeta.Owner,
+			ResourceMeta: ResourceMeta(p.InstanceMeta.ResourceMeta),
+			Shared:       p.Shared,
+			Space:        p.InstanceMeta.ResourceMeta.Space,
+			Deposit:      p.Deposit,
+		}
+		if p.InstanceMeta.Peers != nil {
+			info.Nodes = len(p.InstanceMeta.Peers.Servers)
+		}
+		databases = append(databases, info)
+	}
+
+	return
+}
+
 func requestBP(method route.RemoteFunc, request interface{}, response interface{}) (err error) {
+	return requestBPWithContext(context.Background(), method, request, response)
+}
+
+func requestBPWithContext(ctx context.Context, method route.RemoteFunc, request interface{}, response interface{}) (err error) {
+	defer func() {
+		if isTxThrottledError(err) {
+			log.Warningf("request block producer %s throttled: %v", method, err)
+			err = ErrTxThrottled
+		}
+	}()
+
+	if endpoints, timeout := bpEndpoints.candidates(); len(endpoints) > 0 {
+		for _, e := range endpoints {
+			reqCtx, cancel := withTimeout(ctx, timeout)
+			err = rpc.NewCaller().CallNodeWithContext(reqCtx, e.NodeID, method.String(), request, response)
+			cancel()
+			if err == nil {
+				bpEndpoints.promote(e.NodeID)
+				return
+			}
+			if !isBPFailoverError(err) || ctx.Err() != nil {
+				return
+			}
+			log.Warningf("request block producer %s failed, fail over to next endpoint: %v", e, err)
+			bpEndpoints.demote(e.NodeID)
+		}
+		return
+	}
+
 	var bpNodeID proto.NodeID
 	if bpNodeID, err = rpc.GetCurrentBP(); err != nil {
 		return
 	}
 
-	return rpc.NewCaller().CallNode(bpNodeID, method.String(), request, response)
+	return rpc.NewCaller().CallNodeWithContext(ctx, bpNodeID, method.String(), request, response)
 }
 
 func registerNode() (err error) {
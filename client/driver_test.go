@@ -20,6 +20,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -53,6 +55,86 @@ func TestCreate(t *testing.T) {
 		dsn, err = Create(ResourceMeta{})
 		So(err, ShouldBeNil)
 		So(dsn, ShouldEqual, "covenantsql://db")
+
+		dsn, err = Create(ResourceMeta{
+			Node:             1,
+			TargetRegion:     "us-west",
+			ConsistencyLevel: 1,
+		})
+		So(err, ShouldBeNil)
+		So(dsn, ShouldEqual, "covenantsql://db")
+
+		_, err = Create(ResourceMeta{ConsistencyLevel: 1.5})
+		So(err, ShouldEqual, ErrInvalidResourceMeta)
+	})
+}
+
+func TestEstimatePrice(t *testing.T) {
+	Convey("test estimate price", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var est *PriceEstimate
+		est, err = EstimatePrice(ResourceMeta{Node: 2})
+		So(err, ShouldBeNil)
+		So(est.GasPerHour, ShouldEqual, 2)
+		So(est.CostPerMonth, ShouldEqual, 2*bp.HoursPerMonth)
+
+		_, err = EstimatePrice(ResourceMeta{})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestGetStatus(t *testing.T) {
+	Convey("test get status", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var status *DatabaseStatus
+		status, err = GetStatus(proto.DatabaseID("db"))
+		So(err, ShouldBeNil)
+		So(status.DatabaseID, ShouldEqual, proto.DatabaseID("db"))
+		So(status.Nodes, ShouldHaveLength, 1)
+		So(status.ReadyNodes, ShouldEqual, 1)
+		So(status.Ready(), ShouldBeTrue)
+		So(status.Nodes[0].HealthScore, ShouldBeGreaterThan, 0)
+
+		// database not served by miner
+		status, err = GetStatus(proto.DatabaseID("db_not_served"))
+		So(err, ShouldBeNil)
+		So(status.Ready(), ShouldBeFalse)
+		So(status.Nodes[0].Err, ShouldNotBeNil)
+	})
+}
+
+func TestListDatabases(t *testing.T) {
+	Convey("test list databases", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var (
+			owner     proto.AccountAddress
+			databases []DatabaseInfo
+		)
+		owner, err = localAccountAddress()
+		So(err, ShouldBeNil)
+		databases, err = ListDatabases()
+		So(err, ShouldBeNil)
+		So(databases, ShouldHaveLength, 1)
+		So(databases[0].DatabaseID, ShouldEqual, proto.DatabaseID("db"))
+		So(databases[0].Owner, ShouldEqual, owner)
+		So(databases[0].Shared, ShouldBeFalse)
+		So(databases[0].Nodes, ShouldEqual, 1)
+		So(databases[0].Deposit, ShouldEqual, 100)
 	})
 }
 
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/sqlchain/storage"
+)
+
+// DumpFormat defines the output format of database snapshot.
+type DumpFormat int
+
+const (
+	// DumpSQL outputs snapshot as sql statements like the .dump command of sqlite shell.
+	DumpSQL DumpFormat = iota
+	// DumpSQLite outputs snapshot as a sqlite database file.
+	DumpSQLite
+)
+
+var (
+	// DumpBatchSize defines the row count fetched in each round trip of dumping a table.
+	DumpBatchSize = 1000
+)
+
+// DumpOptions defines the snapshot to dump.
+type DumpOptions struct {
+	Format DumpFormat
+	// Height defines the block height of the snapshot, zero means the state when dump starts.
+	Height int32
+}
+
+// Dump writes the current state of database to w as sql statements.
+func Dump(dbID proto.DatabaseID, w io.Writer) (err error) {
+	return DumpContext(context.Background(), dbID, w, DumpOptions{})
+}
+
+// DumpContext writes a consistent snapshot of database to w. The snapshot is read from database
+// peers as historical state, so writes committed during dumping are not included.
+func DumpContext(ctx context.Context, dbID proto.DatabaseID, w io.Writer, opts DumpOptions) (err error) {
+	cfg := NewConfig()
+	cfg.DatabaseID = string(dbID)
+	if opts.Height > 0 {
+		cfg.AsOfHeight = opts.Height
+	} else {
+		cfg.AsOfTime = time.Now()
+	}
+
+	db := sql.OpenDB(&connector{cfg: cfg, driver: new(covenantSQLDriver)})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	switch opts.Format {
+	case DumpSQL:
+		if _, err = io.WriteString(w, "BEGIN TRANSACTION;\n"); err != nil {
+			return
+		}
+		if err = dump(ctx, db, &sqlDumpSink{w: w}); err != nil {
+			return
+		}
+		_, err = io.WriteString(w, "COMMIT;\n")
+		return
+	case DumpSQLite:
+		var sink *sqliteDumpSink
+		if sink, err = newSQLiteDumpSink(); err != nil {
+			return
+		}
+		defer sink.cleanup()
+		if err = dump(ctx, db, sink); err != nil {
+			return
+		}
+		return sink.writeTo(w)
+	default:
+		return ErrInvalidParameter
+	}
+}
+
+// dumpSink receives statements rebuilding the snapshot.
+type dumpSink interface {
+	exec(stmt string) error
+}
+
+// sqlDumpSink writes statements to w.
+type sqlDumpSink struct {
+	w io.Writer
+}
+
+func (s *sqlDumpSink) exec(stmt string) (err error) {
+	_, err = io.WriteString(s.w, stmt+";\n")
+	return
+}
+
+// sqliteDumpSink rebuilds the snapshot in a temporary sqlite file, statements are executed in
+// batches of DumpBatchSize.
+type sqliteDumpSink struct {
+	dir     string
+	file    string
+	st      *storage.Storage
+	pending []storage.Query
+}
+
+func newSQLiteDumpSink() (s *sqliteDumpSink, err error) {
+	s = new(sqliteDumpSink)
+	if s.dir, err = ioutil.TempDir("", "covenantsql_dump_"); err != nil {
+		return
+	}
+	s.file = filepath.Join(s.dir, "dump.db")
+	if s.st, err = storage.New(s.file); err != nil {
+		os.RemoveAll(s.dir)
+	}
+	return
+}
+
+func (s *sqliteDumpSink) exec(stmt string) (err error) {
+	s.pending = append(s.pending, storage.Query{Pattern: stmt})
+	if len(s.pending) >= DumpBatchSize {
+		err = s.flush()
+	}
+	return
+}
+
+func (s *sqliteDumpSink) flush() (err error) {
+	if len(s.pending) == 0 {
+		return
+	}
+	_, err = s.st.Exec(context.Background(), s.pending)
+	s.pending = s.pending[:0]
+	return
+}
+
+func (s *sqliteDumpSink) writeTo(w io.Writer) (err error) {
+	if err = s.flush(); err != nil {
+		return
+	}
+	if err = s.st.Close(); err != nil {
+		return
+	}
+	s.st = nil
+
+	var f *os.File
+	if f, err = os.Open(s.file); err != nil {
+		return
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return
+}
+
+func (s *sqliteDumpSink) cleanup() {
+	if s.st != nil {
+		s.st.Close()
+	}
+	os.RemoveAll(s.dir)
+}
+
+// dump reads schema and rows of snapshot, tables are created before other schema objects
+// such as indexes and triggers.
+func dump(ctx context.Context, db *sql.DB, sink dumpSink) (err error) {
+	var rows *sql.Rows
+	if rows, err = db.QueryContext(ctx, `SELECT "type", "name", "sql" FROM sqlite_master `+
+		`WHERE "sql" IS NOT NULL AND "name" NOT LIKE 'sqlite_%' `+
+		`ORDER BY CASE "type" WHEN 'table' THEN 0 ELSE 1 END`); err != nil {
+		return
+	}
+
+	var tables, others []string
+	var schemas = make(map[string]string)
+	for rows.Next() {
+		var objType, name, schema string
+		if err = rows.Scan(&objType, &name, &schema); err != nil {
+			rows.Close()
+			return
+		}
+		schemas[name] = schema
+		if objType == "table" {
+			tables = append(tables, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return
+	}
+	rows.Close()
+
+	for _, t := range tables {
+		if err = sink.exec(schemas[t]); err != nil {
+			return
+		}
+		if err = dumpTable(ctx, db, t, sink); err != nil {
+			return
+		}
+	}
+	for _, o := range others {
+		if err = sink.exec(schemas[o]); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func dumpTable(ctx context.Context, db *sql.DB, table string, sink dumpSink) (err error) {
+	quoted := quoteDumpIdent(table)
+
+	for offset := 0; ; offset += DumpBatchSize {
+		var rows *sql.Rows
+		if rows, err = db.QueryContext(ctx, fmt.Sprintf(
+			"SELECT * FROM %s LIMIT %d OFFSET %d", quoted, DumpBatchSize, offset)); err != nil {
+			return
+		}
+
+		var columns []string
+		if columns, err = rows.Columns(); err != nil {
+			rows.Close()
+			return
+		}
+
+		// text values are returned as bytes, declared types tell blobs apart
+		var columnTypes []*sql.ColumnType
+		if columnTypes, err = rows.ColumnTypes(); err != nil {
+			rows.Close()
+			return
+		}
+		isBlob := make([]bool, len(columnTypes))
+		for i, ct := range columnTypes {
+			isBlob[i] = strings.Contains(strings.ToUpper(ct.DatabaseTypeName()), "BLOB")
+		}
+
+		var count int
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		for rows.Next() {
+			if err = rows.Scan(dest...); err != nil {
+				rows.Close()
+				return
+			}
+			if err = sink.exec(buildDumpInsert(quoted, values, isBlob)); err != nil {
+				rows.Close()
+				return
+			}
+			count++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil || count < DumpBatchSize {
+			return
+		}
+	}
+}
+
+func buildDumpInsert(quotedTable string, values []interface{}, isBlob []bool) string {
+	var buf bytes.Buffer
+
+	buf.WriteString("INSERT INTO ")
+	buf.WriteString(quotedTable)
+	buf.WriteString(" VALUES(")
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(formatDumpValue(v, isBlob[i]))
+	}
+	buf.WriteByte(')')
+
+	return buf.String()
+}
+
+func formatDumpValue(v interface{}, isBlob bool) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		if !isBlob && utf8.Valid(x) {
+			return quoteDumpString(string(x))
+		}
+		return "X'" + hex.EncodeToString(x) + "'"
+	case string:
+		return quoteDumpString(x)
+	case time.Time:
+		return quoteDumpString(x.Format("2006-01-02 15:04:05.999999999-07:00"))
+	default:
+		return quoteDumpString(fmt.Sprint(x))
+	}
+}
+
+func quoteDumpString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func quoteDumpIdent(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
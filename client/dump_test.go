@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/sqlchain/storage"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDump(t *testing.T) {
+	Convey("test dump database snapshot", t, func() {
+		var stopTestService func()
+		var tempDir string
+		var err error
+		stopTestService, tempDir, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		origBatchSize := DumpBatchSize
+		DumpBatchSize = 2
+		defer func() { DumpBatchSize = origBatchSize }()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec(`create table "test" (id integer primary key, name text, score real, data blob)`)
+		So(err, ShouldBeNil)
+		_, err = db.Exec(`create index "test_name" on "test" (name)`)
+		So(err, ShouldBeNil)
+		_, err = db.Exec(`insert into "test" values (1, 'it''s', 1.5, x'0102'), (2, null, 2, null), (3, 'c', 3, null)`)
+		So(err, ShouldBeNil)
+
+		var buf bytes.Buffer
+		err = Dump(proto.DatabaseID("db"), &buf)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "BEGIN TRANSACTION;\n"+
+			`CREATE TABLE "test" (id integer primary key, name text, score real, data blob);`+"\n"+
+			`INSERT INTO "test" VALUES(1,'it''s',1.5,X'0102');`+"\n"+
+			`INSERT INTO "test" VALUES(2,NULL,2,NULL);`+"\n"+
+			`INSERT INTO "test" VALUES(3,'c',3,NULL);`+"\n"+
+			`CREATE INDEX "test_name" on "test" (name);`+"\n"+
+			"COMMIT;\n")
+
+		// dump as sqlite file
+		buf.Reset()
+		err = DumpContext(context.Background(), proto.DatabaseID("db"), &buf, DumpOptions{Format: DumpSQLite})
+		So(err, ShouldBeNil)
+		dumpFile := filepath.Join(tempDir, "dump.db")
+		err = ioutil.WriteFile(dumpFile, buf.Bytes(), 0600)
+		So(err, ShouldBeNil)
+		defer os.Remove(dumpFile)
+
+		var st *storage.Storage
+		st, err = storage.New(dumpFile)
+		So(err, ShouldBeNil)
+		defer st.Close()
+		_, _, data, err := st.Query(context.Background(), []storage.Query{
+			{Pattern: `select count(1), sum(score) from "test"`},
+		})
+		So(err, ShouldBeNil)
+		So(data, ShouldHaveLength, 1)
+		So(data[0][0], ShouldEqual, 3)
+		So(data[0][1], ShouldEqual, 6.5)
+
+		// block not produced yet
+		err = DumpContext(context.Background(), proto.DatabaseID("db"), &buf, DumpOptions{Height: 1 << 30})
+		So(err, ShouldNotBeNil)
+
+		err = DumpContext(context.Background(), proto.DatabaseID("db"), &buf, DumpOptions{Format: DumpFormat(-1)})
+		So(err, ShouldEqual, ErrInvalidParameter)
+	})
+}
@@ -20,5 +20,26 @@ import "errors"
 
 // Various errors the driver might returns.
 var (
-	ErrQueryInTransaction = errors.New("only write is supported during transaction")
+	ErrQueryInTransaction     = errors.New("only write is supported during transaction")
+	ErrInvalidParameter       = errors.New("invalid dsn parameter")
+	ErrInvalidTableName       = errors.New("invalid table name")
+	ErrKeyNotFound            = errors.New("key not found")
+	ErrInvalidColumnName      = errors.New("invalid column name")
+	ErrColumnCountInvalid     = errors.New("row values count mismatches columns count")
+	ErrInvalidResourceMeta    = errors.New("invalid database resource requirements")
+	ErrMixedParameters        = errors.New("named and positional parameters can not be mixed")
+	ErrNamedParamNotFound     = errors.New("named parameter not found in arguments")
+	ErrAsyncWriterClosed      = errors.New("async writer is closed")
+	ErrBlobChunkNotFound      = errors.New("blob chunk not found")
+	ErrBlobChunkCorrupted     = errors.New("blob chunk corrupted")
+	ErrParamCountMismatch     = errors.New("placeholders count mismatches arguments count")
+	ErrUnsupportedConn        = errors.New("connection is not a covenantsql connection")
+	ErrHistoricalWrite        = errors.New("write is not supported on historical state")
+	ErrPermissionNotConfirmed = errors.New("permission update is not confirmed by block producer")
+	ErrTxNotFound             = errors.New("transaction not found")
+	ErrTxFailed               = errors.New("transaction failed")
+	ErrInvalidTxReceipts      = errors.New("invalid transaction receipts")
+	ErrInvalidCheckpoint      = errors.New("invalid checkpoint")
+	ErrTxThrottled            = errors.New("transaction submission throttled by block producer")
+	ErrInvalidFetchResponse   = errors.New("fetched rows do not belong to query cursor")
 )
@@ -0,0 +1,147 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gormdialect implements a GORM dialector for CovenantSQL.
+//
+// CovenantSQL databases speak the SQLite dialect, but some database/sql behaviors differ from a
+// local SQLite file: writes in a transaction are queued until commit so their results are not
+// available before commit, and eventual consistent reads may be served by followers lagging behind
+// the leader. The dialector keeps the default strong consistent reads so that rows are reloaded
+// right after they are written, and runs write callbacks out of implicit transactions so that
+// the last insert id is available to fill the primary key, which emulates the RETURNING clause.
+//
+// The package requires Go 1.18 or later, the same as GORM v2.
+package gormdialect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// Name defines the dialect name, which is also the registered database/sql driver name.
+	Name = "covenantsql"
+)
+
+// Various errors translated from database errors, the errors defined by GORM are reused if any.
+var (
+	ErrDuplicatedKey           = gorm.ErrDuplicatedKey
+	ErrForeignKeyViolated      = gorm.ErrForeignKeyViolated
+	ErrNotNullViolated         = errors.New("violates not null constraint")
+	ErrCheckConstraintViolated = errors.New("violates check constraint")
+	ErrTableNotFound           = errors.New("table not found")
+)
+
+// Various errors of unsupported schema alterations.
+var (
+	ErrModifyColumnUnsupported     = errors.New("modifying column is not supported")
+	ErrModifyIndexUnsupported      = errors.New("modifying index is not supported")
+	ErrModifyConstraintUnsupported = errors.New("modifying constraint is not supported")
+)
+
+var (
+	errorTranslations = []struct {
+		pattern string
+		err     error
+	}{
+		{"UNIQUE constraint failed", ErrDuplicatedKey},
+		{"PRIMARY KEY must be unique", ErrDuplicatedKey},
+		{"FOREIGN KEY constraint failed", ErrForeignKeyViolated},
+		{"NOT NULL constraint failed", ErrNotNullViolated},
+		{"CHECK constraint failed", ErrCheckConstraintViolated},
+		{"no such table", ErrTableNotFound},
+	}
+)
+
+// TranslateError translates constraint violation errors reported by database to the errors
+// defined in this package, other errors are returned as is.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	for _, t := range errorTranslations {
+		if strings.Contains(msg, t.pattern) {
+			return t.err
+		}
+	}
+	return err
+}
+
+// PrepareDSN validates and normalizes the DSN. Read queries are strong consistent unless eventual
+// consistency is opted in explicitly, rows reloaded after writes are always up to date then.
+func PrepareDSN(dsn string) (prepared string, err error) {
+	var cfg *client.Config
+	if cfg, err = client.ParseDSN(dsn); err != nil {
+		return
+	}
+	prepared = cfg.FormatDSN()
+	return
+}
+
+func quote(key string) string {
+	return `"` + strings.Replace(key, `"`, `""`, -1) + `"`
+}
+
+// dataTypeOf returns the column type of field, an empty string is returned if the type is not
+// supported.
+func dataTypeOf(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "bool"
+	case schema.Int, schema.Uint:
+		if field.AutoIncrement {
+			return "integer primary key autoincrement"
+		}
+		if field.Size > 32 {
+			return "bigint"
+		}
+		return "integer"
+	case schema.Float:
+		return "real"
+	case schema.String:
+		if field.Size > 0 && field.Size < 65532 {
+			return fmt.Sprintf("varchar(%d)", field.Size)
+		}
+		return "text"
+	case schema.Time:
+		return "datetime"
+	case schema.Bytes:
+		return "blob"
+	}
+	return string(field.DataType)
+}
+
+// limitAndOffsetSQL builds the limit clause, SQLite requires a limit clause for offset.
+func limitAndOffsetSQL(limit *int, offset int) (sql string) {
+	if limit != nil && *limit >= 0 {
+		sql = fmt.Sprintf("LIMIT %d", *limit)
+	} else if offset > 0 {
+		sql = "LIMIT -1"
+	}
+	if offset > 0 {
+		sql += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return
+}
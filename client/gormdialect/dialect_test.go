@@ -0,0 +1,76 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gormdialect
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	. "github.com/smartystreets/goconvey/convey"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestDialect(t *testing.T) {
+	Convey("test error translation", t, func() {
+		So(TranslateError(nil), ShouldBeNil)
+		So(TranslateError(errors.New("UNIQUE constraint failed: test.id")), ShouldEqual, gorm.ErrDuplicatedKey)
+		So(TranslateError(errors.New("FOREIGN KEY constraint failed")), ShouldEqual, gorm.ErrForeignKeyViolated)
+		So(TranslateError(errors.New("NOT NULL constraint failed: test.name")), ShouldEqual, ErrNotNullViolated)
+		So(TranslateError(errors.New("no such table: test")), ShouldEqual, ErrTableNotFound)
+		err := errors.New("unknown")
+		So(TranslateError(err), ShouldEqual, err)
+	})
+	Convey("test dsn preparation", t, func() {
+		dsn, err := PrepareDSN("covenantsql://db")
+		So(err, ShouldBeNil)
+		cfg, err := client.ParseDSN(dsn)
+		So(err, ShouldBeNil)
+		So(cfg.DatabaseID, ShouldEqual, "db")
+		So(cfg.Consistency, ShouldEqual, client.ConsistencyStrong)
+		dsn, err = PrepareDSN("covenantsql://db?consistency=eventual")
+		So(err, ShouldBeNil)
+		cfg, err = client.ParseDSN(dsn)
+		So(err, ShouldBeNil)
+		So(cfg.Consistency, ShouldEqual, client.ConsistencyEventual)
+		_, err = PrepareDSN("covenantsql://db?consistency=weak")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("test column types and clauses", t, func() {
+		So(quote(`a"b`), ShouldEqual, `"a""b"`)
+		So(dataTypeOf(&schema.Field{DataType: schema.Bool}), ShouldEqual, "bool")
+		So(dataTypeOf(&schema.Field{DataType: schema.Int, Size: 32}), ShouldEqual, "integer")
+		So(dataTypeOf(&schema.Field{DataType: schema.Int, Size: 64}), ShouldEqual, "bigint")
+		So(dataTypeOf(&schema.Field{DataType: schema.Uint, Size: 64, AutoIncrement: true}),
+			ShouldEqual, "integer primary key autoincrement")
+		So(dataTypeOf(&schema.Field{DataType: schema.Float}), ShouldEqual, "real")
+		So(dataTypeOf(&schema.Field{DataType: schema.String, Size: 255}), ShouldEqual, "varchar(255)")
+		So(dataTypeOf(&schema.Field{DataType: schema.String}), ShouldEqual, "text")
+		So(dataTypeOf(&schema.Field{DataType: schema.Time}), ShouldEqual, "datetime")
+		So(dataTypeOf(&schema.Field{DataType: schema.Bytes}), ShouldEqual, "blob")
+		So(dataTypeOf(&schema.Field{DataType: "json"}), ShouldEqual, "json")
+		limit := 10
+		So(limitAndOffsetSQL(nil, 0), ShouldBeEmpty)
+		So(limitAndOffsetSQL(&limit, 0), ShouldEqual, "LIMIT 10")
+		So(limitAndOffsetSQL(&limit, 5), ShouldEqual, "LIMIT 10 OFFSET 5")
+		So(limitAndOffsetSQL(nil, 5), ShouldEqual, "LIMIT -1 OFFSET 5")
+	})
+}
@@ -0,0 +1,148 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gormdialect
+
+import (
+	"database/sql"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	_ gorm.Dialector       = (*Dialector)(nil)
+	_ gorm.ErrorTranslator = (*Dialector)(nil)
+)
+
+// Config defines the connection config of dialector.
+type Config struct {
+	DSN  string        // DSN of CovenantSQL database, ignored if Conn is set
+	Conn gorm.ConnPool // existing connection pool to use instead of opening DSN
+}
+
+// Dialector implements the gorm.Dialector interface.
+type Dialector struct {
+	*Config
+}
+
+// Open returns the dialector of the CovenantSQL database specified by DSN. Read queries are strong
+// consistent unless specified by DSN.
+func Open(dsn string) gorm.Dialector {
+	return &Dialector{Config: &Config{DSN: dsn}}
+}
+
+// New returns the dialector of config.
+func New(config Config) gorm.Dialector {
+	return &Dialector{Config: &config}
+}
+
+// Name implements gorm.Dialector.Name.
+func (Dialector) Name() string {
+	return Name
+}
+
+// Initialize implements gorm.Dialector.Initialize. The create, update and delete callbacks are not
+// wrapped in implicit transactions, since results of writes in a CovenantSQL transaction are not
+// available until commit.
+func (d Dialector) Initialize(db *gorm.DB) (err error) {
+	db.SkipDefaultTransaction = true
+
+	// RETURNING is not supported, the primary keys are filled by the last insert id instead, which
+	// is the id of the last inserted row for batch inserts
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{
+		LastInsertIDReversed: true,
+	})
+
+	if d.Conn != nil {
+		db.ConnPool = d.Conn
+	} else {
+		var dsn string
+		if dsn, err = PrepareDSN(d.DSN); err != nil {
+			return
+		}
+		if db.ConnPool, err = sql.Open(Name, dsn); err != nil {
+			return
+		}
+	}
+
+	db.ClauseBuilders["LIMIT"] = buildLimit
+	return
+}
+
+// Migrator implements gorm.Dialector.Migrator.
+func (d Dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return Migrator{
+		Migrator: migrator.Migrator{
+			Config: migrator.Config{
+				DB:                          db,
+				Dialector:                   d,
+				CreateIndexAfterCreateTable: true,
+			},
+		},
+	}
+}
+
+// DataTypeOf implements gorm.Dialector.DataTypeOf.
+func (Dialector) DataTypeOf(field *schema.Field) string {
+	return dataTypeOf(field)
+}
+
+// DefaultValueOf implements gorm.Dialector.DefaultValueOf, the DEFAULT keyword is not supported
+// in VALUES clause by the SQLite dialect.
+func (Dialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "NULL"}
+}
+
+// BindVarTo implements gorm.Dialector.BindVarTo.
+func (Dialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+
+// QuoteTo implements gorm.Dialector.QuoteTo.
+func (Dialector) QuoteTo(writer clause.Writer, str string) {
+	for i, part := range strings.Split(str, ".") {
+		if i > 0 {
+			writer.WriteByte('.')
+		}
+		writer.WriteString(quote(part))
+	}
+}
+
+// Explain implements gorm.Dialector.Explain.
+func (Dialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+// Translate implements gorm.ErrorTranslator.Translate, it takes effect if the TranslateError
+// option of GORM is enabled.
+func (Dialector) Translate(err error) error {
+	return TranslateError(err)
+}
+
+func buildLimit(c clause.Clause, builder clause.Builder) {
+	if limit, ok := c.Expression.(clause.Limit); ok {
+		builder.WriteString(limitAndOffsetSQL(limit.Limit, limit.Offset))
+	}
+}
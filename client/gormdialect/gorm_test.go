@@ -0,0 +1,97 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gormdialect
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	_ "github.com/CovenantSQL/go-sqlite3-encrypt"
+	. "github.com/smartystreets/goconvey/convey"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testUser struct {
+	ID   uint64
+	Name string `gorm:"size:64;uniqueIndex"`
+	Age  int    `gorm:"index"`
+}
+
+func TestGORM(t *testing.T) {
+	Convey("test gorm with dialector", t, func() {
+		fl, err := ioutil.TempFile("", "gormdialect-")
+		So(err, ShouldBeNil)
+		fl.Close()
+		defer os.Remove(fl.Name())
+		sqlDB, err := sql.Open("sqlite3", fl.Name())
+		So(err, ShouldBeNil)
+		defer sqlDB.Close()
+
+		db, err := gorm.Open(New(Config{Conn: sqlDB}), &gorm.Config{
+			TranslateError: true,
+			Logger:         logger.Discard,
+		})
+		So(err, ShouldBeNil)
+		So(db.Dialector.Name(), ShouldEqual, Name)
+
+		// migrations are idempotent
+		m := db.Migrator()
+		So(m.HasTable(&testUser{}), ShouldBeFalse)
+		So(m.AutoMigrate(&testUser{}), ShouldBeNil)
+		So(m.AutoMigrate(&testUser{}), ShouldBeNil)
+		So(m.HasTable(&testUser{}), ShouldBeTrue)
+		So(m.HasColumn(&testUser{}, "Name"), ShouldBeTrue)
+		So(m.HasColumn(&testUser{}, "email"), ShouldBeFalse)
+		So(m.HasIndex(&testUser{}, "idx_test_users_name"), ShouldBeTrue)
+		So(m.HasIndex(&testUser{}, "Age"), ShouldBeTrue)
+		tables, err := m.GetTables()
+		So(err, ShouldBeNil)
+		So(tables, ShouldContain, "test_users")
+
+		// primary keys are filled by last insert id
+		u := testUser{Name: "a", Age: 10}
+		So(db.Create(&u).Error, ShouldBeNil)
+		So(u.ID, ShouldEqual, 1)
+		users := []testUser{{Name: "b", Age: 20}, {Name: "c", Age: 30}}
+		So(db.Create(&users).Error, ShouldBeNil)
+		So(users[0].ID, ShouldEqual, 2)
+		So(users[1].ID, ShouldEqual, 3)
+
+		// errors are translated
+		So(db.Create(&testUser{Name: "a"}).Error, ShouldEqual, gorm.ErrDuplicatedKey)
+		So(db.Table("not_exists").Find(&users).Error, ShouldEqual, ErrTableNotFound)
+
+		// offset without limit
+		users = nil
+		So(db.Order("id").Offset(1).Find(&users).Error, ShouldBeNil)
+		So(users, ShouldHaveLength, 2)
+		So(users[0].Name, ShouldEqual, "b")
+
+		// unsupported alterations
+		So(m.AlterColumn(&testUser{}, "Age"), ShouldEqual, ErrModifyColumnUnsupported)
+		So(m.DropColumn(&testUser{}, "Age"), ShouldEqual, ErrModifyColumnUnsupported)
+		So(m.RenameIndex(&testUser{}, "Age", "age"), ShouldEqual, ErrModifyIndexUnsupported)
+		So(m.DropIndex(&testUser{}, "Age"), ShouldBeNil)
+		So(m.HasIndex(&testUser{}, "Age"), ShouldBeFalse)
+	})
+}
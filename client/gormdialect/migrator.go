@@ -0,0 +1,193 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gormdialect
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	_ gorm.Migrator = Migrator{}
+)
+
+// Migrator implements the gorm.Migrator interface, schema is inspected through the sqlite_master
+// table instead of information_schema, and the table alterations not supported by the SQLite
+// dialect are rejected.
+type Migrator struct {
+	migrator.Migrator
+}
+
+// CurrentDatabase implements gorm.Migrator.CurrentDatabase.
+func (m Migrator) CurrentDatabase() string {
+	return "main"
+}
+
+// GetTables implements gorm.Migrator.GetTables.
+func (m Migrator) GetTables() (tables []string, err error) {
+	err = m.DB.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").
+		Scan(&tables).Error
+	return
+}
+
+// HasTable implements gorm.Migrator.HasTable.
+func (m Migrator) HasTable(value interface{}) bool {
+	var n int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+			stmt.Table).Row().Scan(&n)
+	})
+	return n > 0
+}
+
+// HasColumn implements gorm.Migrator.HasColumn.
+func (m Migrator) HasColumn(value interface{}, name string) bool {
+	var n int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			name = field.DBName
+		}
+		return m.DB.Raw("SELECT count(*) FROM pragma_table_info(?) WHERE name = ?",
+			stmt.Table, name).Row().Scan(&n)
+	})
+	return n > 0
+}
+
+// AlterColumn implements gorm.Migrator.AlterColumn, column alteration is not supported by the
+// SQLite dialect.
+func (m Migrator) AlterColumn(value interface{}, name string) error {
+	return ErrModifyColumnUnsupported
+}
+
+// DropColumn implements gorm.Migrator.DropColumn, column removal is not supported by the SQLite
+// dialect.
+func (m Migrator) DropColumn(value interface{}, name string) error {
+	return ErrModifyColumnUnsupported
+}
+
+// RenameColumn implements gorm.Migrator.RenameColumn, column renaming is not supported by the
+// SQLite dialect.
+func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error {
+	return ErrModifyColumnUnsupported
+}
+
+// HasConstraint implements gorm.Migrator.HasConstraint, constraints are looked up in the table
+// definition.
+func (m Migrator) HasConstraint(value interface{}, name string) bool {
+	var n int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		constraint, chk, table := m.GuessConstraintAndTable(stmt, name)
+		if constraint != nil {
+			name = constraint.Name
+		} else if chk != nil {
+			name = chk.Name
+		}
+		return m.DB.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ? AND sql LIKE ?",
+			table, "%CONSTRAINT "+quote(name)+" %").Row().Scan(&n)
+	})
+	return n > 0
+}
+
+// CreateConstraint implements gorm.Migrator.CreateConstraint, constraints can only be defined
+// with the table by the SQLite dialect.
+func (m Migrator) CreateConstraint(value interface{}, name string) error {
+	return ErrModifyConstraintUnsupported
+}
+
+// DropConstraint implements gorm.Migrator.DropConstraint, constraints can only be dropped with the
+// table by the SQLite dialect.
+func (m Migrator) DropConstraint(value interface{}, name string) error {
+	return ErrModifyConstraintUnsupported
+}
+
+// BuildIndexOptions builds the indexed columns, index prefix lengths are not supported by the
+// SQLite dialect.
+func (m Migrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement) (results []interface{}) {
+	for _, opt := range opts {
+		str := stmt.Quote(opt.DBName)
+		if opt.Expression != "" {
+			str = opt.Expression
+		}
+		if opt.Collate != "" {
+			str += " COLLATE " + opt.Collate
+		}
+		if opt.Sort != "" {
+			str += " " + opt.Sort
+		}
+		results = append(results, clause.Expr{SQL: str})
+	}
+	return
+}
+
+// CreateIndex implements gorm.Migrator.CreateIndex, index types and comments are not supported by
+// the SQLite dialect, the where condition of index creates a partial index.
+func (m Migrator) CreateIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		idx := stmt.Schema.LookIndex(name)
+		if idx == nil {
+			return fmt.Errorf("failed to create index with name %s", name)
+		}
+
+		createIndexSQL := "CREATE "
+		if idx.Class != "" {
+			createIndexSQL += idx.Class + " "
+		}
+		createIndexSQL += "INDEX ? ON ??"
+		if idx.Where != "" {
+			createIndexSQL += " WHERE " + idx.Where
+		}
+
+		return m.DB.Exec(createIndexSQL, clause.Column{Name: idx.Name}, m.CurrentTable(stmt),
+			m.BuildIndexOptions(idx.Fields, stmt)).Error
+	})
+}
+
+// HasIndex implements gorm.Migrator.HasIndex.
+func (m Migrator) HasIndex(value interface{}, name string) bool {
+	var n int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if idx := stmt.Schema.LookIndex(name); idx != nil {
+			name = idx.Name
+		}
+		return m.DB.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?",
+			stmt.Table, name).Row().Scan(&n)
+	})
+	return n > 0
+}
+
+// DropIndex implements gorm.Migrator.DropIndex.
+func (m Migrator) DropIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if idx := stmt.Schema.LookIndex(name); idx != nil {
+			name = idx.Name
+		}
+		return m.DB.Exec("DROP INDEX ?", clause.Column{Name: name}).Error
+	})
+}
+
+// RenameIndex implements gorm.Migrator.RenameIndex, index renaming is not supported by the SQLite
+// dialect.
+func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error {
+	return ErrModifyIndexUnsupported
+}
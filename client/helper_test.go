@@ -28,6 +28,8 @@ import (
 	"time"
 
 	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
 	"github.com/CovenantSQL/CovenantSQL/conf"
 	"github.com/CovenantSQL/CovenantSQL/consistent"
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
@@ -47,6 +49,7 @@ import (
 
 var (
 	rootHash = hash.Hash{}
+	stubMCC  *stubMCCService
 )
 
 // fake BPDB service
@@ -105,6 +108,47 @@ func (s *stubBPDBService) GetNodeDatabases(req *wt.InitService, resp *wt.InitSer
 	return
 }
 
+func (s *stubBPDBService) ListDatabases(req *bp.ListDatabasesRequest, resp *bp.ListDatabasesResponse) (err error) {
+	var instance wt.ServiceInstance
+	if instance, err = s.getInstanceMeta(proto.DatabaseID("db")); err != nil {
+		return
+	}
+	var enc []byte
+	if enc, err = req.Header.Signee.MarshalHash(); err != nil {
+		return
+	}
+	instance.Owner = proto.AccountAddress(hash.THashH(enc))
+	resp.Header.Databases = []bp.DatabaseProfile{
+		{
+			InstanceMeta: instance,
+			Deposit:      100,
+		},
+	}
+	if resp.Header.Signee, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	err = resp.Sign(privateKey)
+	return
+}
+
+func (s *stubBPDBService) EstimatePrice(req *bp.EstimatePriceRequest, resp *bp.EstimatePriceResponse) (err error) {
+	if req.ResourceMeta.Node == 0 {
+		return bp.ErrInvalidResourceMeta
+	}
+	resp.Estimate = bp.PriceEstimate{
+		CurrentGasPrice: 1,
+		GasPrice:        1,
+		GasPerHour:      uint64(req.ResourceMeta.Node),
+		CostPerHour:     uint64(req.ResourceMeta.Node),
+		CostPerMonth:    uint64(req.ResourceMeta.Node) * bp.HoursPerMonth,
+	}
+	return
+}
+
 func (s *stubBPDBService) getInstanceMeta(dbID proto.DatabaseID) (instance wt.ServiceInstance, err error) {
 	var pubKey *asymmetric.PublicKey
 	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
@@ -146,6 +190,271 @@ func (s *stubBPDBService) getInstanceMeta(dbID proto.DatabaseID) (instance wt.Se
 	return
 }
 
+// fake main chain service
+type stubMCCService struct {
+	sync.Mutex
+	nonces   map[proto.AccountAddress]pi.AccountNonce
+	profiles map[proto.DatabaseID]*pt.SQLChainProfile
+	balances map[proto.AccountAddress]uint64
+	txStates map[hash.Hash]pi.TransactionState
+	usages   map[proto.DatabaseID]*bp.DatabaseUsage
+	events   map[proto.AccountAddress][]*bp.AccountEvent
+}
+
+func newStubMCCService() *stubMCCService {
+	return &stubMCCService{
+		nonces:   make(map[proto.AccountAddress]pi.AccountNonce),
+		profiles: make(map[proto.DatabaseID]*pt.SQLChainProfile),
+		balances: make(map[proto.AccountAddress]uint64),
+		txStates: make(map[hash.Hash]pi.TransactionState),
+		usages:   make(map[proto.DatabaseID]*bp.DatabaseUsage),
+		events:   make(map[proto.AccountAddress][]*bp.AccountEvent),
+	}
+}
+
+func (s *stubMCCService) NextAccountNonce(req *bp.NextAccountNonceReq, resp *bp.NextAccountNonceResp) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	resp.Addr = req.Addr
+	resp.Nonce = s.nonces[req.Addr]
+	return
+}
+
+func (s *stubMCCService) UpdatePermission(req *bp.UpdatePermissionReq, resp *bp.UpdatePermissionResp) (err error) {
+	if err = req.Tx.Verify(); err != nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if req.Tx.Nonce != s.nonces[req.Tx.Sender] {
+		return bp.ErrInvalidAccountNonce
+	}
+	profile, ok := s.profiles[req.Tx.DatabaseID]
+	if !ok {
+		profile = &pt.SQLChainProfile{
+			ID:    req.Tx.DatabaseID,
+			Owner: req.Tx.Sender,
+			Users: []*pt.SQLChainUser{{Address: req.Tx.Sender, Permission: pt.Admin}},
+		}
+		s.profiles[req.Tx.DatabaseID] = profile
+	}
+	users := make([]*pt.SQLChainUser, 0, len(profile.Users)+1)
+	for _, u := range profile.Users {
+		if u.Address != req.Tx.User {
+			users = append(users, u)
+		}
+	}
+	if !req.Tx.Revoke {
+		users = append(users, &pt.SQLChainUser{Address: req.Tx.User, Permission: req.Tx.Permission})
+	}
+	profile.Users = users
+	s.nonces[req.Tx.Sender]++
+	return
+}
+
+func (s *stubMCCService) QueryAccountBalance(
+	req *bp.QueryAccountBalanceReq, resp *bp.QueryAccountBalanceResp) (err error,
+) {
+	s.Lock()
+	defer s.Unlock()
+	balance, ok := s.balances[req.Addr]
+	if !ok {
+		return bp.ErrAccountNotFound
+	}
+	resp.Addr = req.Addr
+	resp.StableCoinBalance = balance
+	return
+}
+
+func (s *stubMCCService) Transfer(req *bp.TransferReq, resp *bp.TransferResp) (err error) {
+	if err = req.Tx.Verify(); err != nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if req.Tx.Nonce != s.nonces[req.Tx.Sender] {
+		return bp.ErrInvalidAccountNonce
+	}
+	if s.balances[req.Tx.Sender] < req.Tx.Amount {
+		return bp.ErrInsufficientBalance
+	}
+	s.balances[req.Tx.Sender] -= req.Tx.Amount
+	s.balances[req.Tx.Receiver] += req.Tx.Amount
+	s.nonces[req.Tx.Sender]++
+	s.txStates[req.Tx.GetHash()] = pi.TransactionStatePending
+	events := s.events[req.Tx.Receiver]
+	s.events[req.Tx.Receiver] = append(events, &bp.AccountEvent{
+		Seq:          uint64(len(events) + 1),
+		Type:         bp.AccountEventTransferIn,
+		Tx:           req.Tx.GetHash(),
+		Counterparty: req.Tx.Sender,
+		Amount:       req.Tx.Amount,
+	})
+	return
+}
+
+func (s *stubMCCService) SimulateTx(req *bp.SimulateTxReq, resp *bp.SimulateTxResp) (err error) {
+	tx, ok := req.Tx.(*pt.Transfer)
+	if !ok {
+		return bp.ErrUnknownTransactionType
+	}
+	if err = tx.Verify(); err != nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if tx.Nonce != s.nonces[tx.Sender] {
+		resp.Error = bp.ErrInvalidAccountNonce.Error()
+		return
+	}
+	if s.balances[tx.Sender] < tx.Amount {
+		resp.Error = bp.ErrInsufficientBalance.Error()
+		return
+	}
+	resp.Ok = true
+	resp.Fee = tx.Fee
+	resp.Accounts = []pt.Account{
+		{Address: tx.Sender, StableCoinBalance: s.balances[tx.Sender] - tx.Amount},
+		{Address: tx.Receiver, StableCoinBalance: s.balances[tx.Receiver] + tx.Amount},
+	}
+	return
+}
+
+func (s *stubMCCService) QueryAccountProof(
+	req *bp.QueryAccountProofReq, resp *bp.QueryAccountProofResp) (err error,
+) {
+	s.Lock()
+	balance, ok := s.balances[req.Addr]
+	s.Unlock()
+	if !ok {
+		return bp.ErrAccountNotFound
+	}
+	// the account is the only leaf of the meta state tree
+	resp.Account = pt.Account{Address: req.Addr, StableCoinBalance: balance}
+	resp.Proof = &pt.StateProof{}
+	if resp.Proof.Header.MetaRoot, err = pt.AccountLeaf(&resp.Account); err != nil {
+		return
+	}
+	var (
+		priv *asymmetric.PrivateKey
+		enc  []byte
+	)
+	if priv, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if enc, err = resp.Proof.Header.Header.MarshalHash(); err != nil {
+		return
+	}
+	resp.Proof.Header.BlockHash = hash.THashH(enc)
+	resp.Proof.Header.Signee = priv.PubKey()
+	resp.Proof.Header.Signature, err = priv.Sign(resp.Proof.Header.BlockHash[:])
+	return
+}
+
+func (s *stubMCCService) QueryTxState(req *bp.QueryTxStateReq, resp *bp.QueryTxStateResp) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	resp.Hash = req.Hash
+	resp.State = s.txStates[req.Hash]
+	// pending transactions are confirmed by the next query
+	if resp.State == pi.TransactionStatePending {
+		s.txStates[req.Hash] = pi.TransactionStateConfirmed
+	} else if resp.State == pi.TransactionStateConfirmed {
+		resp.Height = 1
+	} else if resp.State == pi.TransactionStateFailed {
+		resp.Reason = bp.ErrInvalidAccountNonce.Error()
+	}
+	return
+}
+
+func (s *stubMCCService) QueryTxReceipts(req *bp.QueryTxReceiptsReq, resp *bp.QueryTxReceiptsResp) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, h := range req.Hashes {
+		r := bp.TxReceipt{Hash: h, State: s.txStates[h]}
+		if r.State == pi.TransactionStateConfirmed {
+			r.Height = 1
+		} else if r.State == pi.TransactionStateFailed {
+			r.Reason = bp.ErrInvalidAccountNonce.Error()
+		}
+		resp.Receipts = append(resp.Receipts, r)
+	}
+	return
+}
+
+func (s *stubMCCService) QueryAccountEvents(
+	req *bp.QueryAccountEventsReq, resp *bp.QueryAccountEventsResp) (err error,
+) {
+	s.Lock()
+	defer s.Unlock()
+	for _, e := range s.events[req.Addr] {
+		if e.Seq > req.Since {
+			resp.Events = append(resp.Events, e)
+		}
+	}
+	return
+}
+
+func (s *stubMCCService) QueryCheckpoint(req *bp.QueryCheckpointReq, resp *bp.QueryCheckpointResp) (err error) {
+	var priv *asymmetric.PrivateKey
+	if priv, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	// checkpoints are signed by the block producer of the test service
+	height := req.Height
+	if height == 0 {
+		height = 16
+	}
+	if resp.Checkpoint, err = pt.NewCheckpoint(&pt.CheckpointHeader{Height: height}); err != nil {
+		return
+	}
+	_, err = resp.Checkpoint.Sign(priv)
+	return
+}
+
+func (s *stubMCCService) QueryFeeEstimate(
+	req *bp.QueryFeeEstimateReq, resp *bp.QueryFeeEstimateResp) (err error,
+) {
+	s.Lock()
+	defer s.Unlock()
+	resp.Estimate = bp.FeeEstimate{
+		Pending:       len(s.txStates),
+		Capacity:      1,
+		MinFeeRate:    uint64(len(s.txStates)),
+		MedianFeeRate: uint64(len(s.txStates)),
+	}
+	return
+}
+
+func (s *stubMCCService) QuerySQLChainProfile(
+	req *bp.QuerySQLChainProfileReq, resp *bp.QuerySQLChainProfileResp) (err error,
+) {
+	s.Lock()
+	defer s.Unlock()
+	profile, ok := s.profiles[req.DBID]
+	if !ok {
+		return bp.ErrDatabaseNotFound
+	}
+	resp.Profile = *profile
+	return
+}
+
+func (s *stubMCCService) QueryDatabaseUsage(
+	req *bp.QueryDatabaseUsageReq, resp *bp.QueryDatabaseUsageResp) (err error,
+) {
+	s.Lock()
+	defer s.Unlock()
+	usage, ok := s.usages[req.DBID]
+	if !ok {
+		return bp.ErrDatabaseNotFound
+	}
+	resp.Usage = *usage
+	return
+}
+
 func startTestService() (stopTestService func(), tempDir string, err error) {
 	var server *rpc.Server
 	var cleanup func()
@@ -270,6 +579,13 @@ func initNode() (cleanupFunc func(), tempDir string, server *rpc.Server, err err
 		return
 	}
 
+	// register main chain service
+	stubMCC = newStubMCCService()
+	localNonces.reset()
+	if err = server.RegisterService(bp.MainChainRPCName, stubMCC); err != nil {
+		return
+	}
+
 	// init private key
 	masterKey := []byte("")
 	if err = server.InitRPCServer(conf.GConf.ListenAddr, privateKeyPath, masterKey); err != nil {
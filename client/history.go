@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+type asOfContextKey struct{}
+
+// asOf defines the historical state a read query is served on, zero values mean the latest state.
+type asOf struct {
+	height int32
+	time   time.Time
+}
+
+func (a asOf) isHistorical() bool {
+	return a.height > 0 || !a.time.IsZero()
+}
+
+// WithAsOfHeight returns a context which makes read queries issued with it served on the state of
+// database when the block at height is produced, the state is reconstructed by database peers from
+// writes committed by the block and its ancestors.
+func WithAsOfHeight(ctx context.Context, height int32) context.Context {
+	return context.WithValue(ctx, asOfContextKey{}, asOf{height: height})
+}
+
+// WithAsOfTime returns a context which makes read queries issued with it served on the state of
+// database at t, the state is reconstructed by database peers from writes issued before t.
+func WithAsOfTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, asOfContextKey{}, asOf{time: t})
+}
+
+// asOfFromContext returns the historical state specified by context, the connection default is
+// used if the context does not specify one.
+func (c *conn) asOfFromContext(ctx context.Context) asOf {
+	if a, ok := ctx.Value(asOfContextKey{}).(asOf); ok {
+		return a
+	}
+	return c.asOf
+}
+
+// GetHeadHeight returns the height of the latest block of database, the state at the height is
+// consistent and can be read with WithAsOfHeight.
+func GetHeadHeight(ctx context.Context, dbID proto.DatabaseID) (height int32, err error) {
+	cfg := NewConfig()
+	cfg.DatabaseID = string(dbID)
+
+	var c *conn
+	if c, err = newConn(cfg); err != nil {
+		return
+	}
+	defer c.Close()
+
+	req := &wt.StatusReq{
+		DatabaseID: c.dbID,
+	}
+	res := new(wt.StatusResp)
+	if err = c.callNode(ctx, c.pickTarget(wt.ReadQuery, c.consistency), route.DBSStatus, req, res); err != nil {
+		return
+	}
+	height = res.Height
+	return
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistoricalQuery(t *testing.T) {
+	Convey("test historical queries", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db?cache_size=10")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into test values (1)")
+		So(err, ShouldBeNil)
+		asOf := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		_, err = db.Exec("insert into test values (2)")
+		So(err, ShouldBeNil)
+
+		var count int
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 2)
+
+		// query with context option, cached latest result is not used
+		ctx := WithAsOfTime(context.Background(), asOf)
+		err = db.QueryRowContext(ctx, "select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 2)
+
+		// historical state is read-only
+		_, err = db.ExecContext(ctx, "insert into test values (3)")
+		So(err, ShouldEqual, ErrHistoricalWrite)
+
+		var height int32
+		height, err = GetHeadHeight(context.Background(), proto.DatabaseID("db"))
+		So(err, ShouldBeNil)
+		So(height, ShouldBeGreaterThanOrEqualTo, -1)
+
+		// block not produced yet
+		err = db.QueryRowContext(WithAsOfHeight(context.Background(), 1<<30),
+			"select count(1) from test").Scan(&count)
+		So(err, ShouldNotBeNil)
+
+		// query with dsn parameter
+		var dbAsOf *sql.DB
+		dbAsOf, err = sql.Open("covenantsql", "covenantsql://db?as_of_time="+
+			url.QueryEscape(asOf.UTC().Format(time.RFC3339Nano)))
+		So(err, ShouldBeNil)
+		defer dbAsOf.Close()
+
+		err = dbAsOf.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 1)
+		_, err = dbAsOf.Exec("insert into test values (3)")
+		So(err, ShouldEqual, ErrHistoricalWrite)
+	})
+}
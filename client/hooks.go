@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// Hooks defines the instrumentation callbacks of the driver, nil callbacks are ignored.
+type Hooks struct {
+	// OnPeerHealth is called with the health score reported by a database peer in query response.
+	OnPeerHealth func(dbID proto.DatabaseID, nodeID proto.NodeID, score uint32)
+
+	// StartSpan is called on each query, exec and transaction to create a tracing span,
+	// operation is one of SpanQuery, SpanExec and SpanTx.
+	StartSpan func(ctx context.Context, operation string) Span
+}
+
+// Span defines a tracing span created by Hooks.StartSpan, it is implemented by users
+// to adapt to tracers such as OpenTracing or OpenTelemetry.
+type Span interface {
+	// SetTag sets attribute of the span.
+	SetTag(key string, value interface{})
+	// Inject writes the span context to carrier, the carrier is sent to the database
+	// peer in request envelope for server side continuation.
+	Inject(carrier map[string]string)
+	// Finish ends the span with the error of the operation.
+	Finish(err error)
+}
+
+var (
+	hooks atomic.Value
+)
+
+func init() {
+	hooks.Store(&Hooks{})
+}
+
+// SetHooks replaces the instrumentation callbacks of the driver, nil resets to no callbacks.
+func SetHooks(h *Hooks) {
+	if h == nil {
+		h = &Hooks{}
+	}
+	hooks.Store(h)
+}
+
+func getHooks() *Hooks {
+	return hooks.Load().(*Hooks)
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+const (
+	// DefaultKVTable defines the default managed table name of key-value store.
+	DefaultKVTable = "__kv_store"
+)
+
+var (
+	identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// KVPair defines a single key-value pair returned by KV.Scan.
+type KVPair struct {
+	Key   string
+	Value []byte
+}
+
+// KV defines a key-value convenience layer stored in a managed table of a database.
+type KV struct {
+	db    *sql.DB
+	table string
+}
+
+// NewKV returns a key-value store using table of the database, the table is created if not exists.
+func NewKV(db *sql.DB, table string) (kv *KV, err error) {
+	if table == "" {
+		table = DefaultKVTable
+	}
+	if !identifierRegex.MatchString(table) {
+		err = ErrInvalidTableName
+		return
+	}
+
+	if _, err = db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS "%s" ("k" TEXT PRIMARY KEY NOT NULL, "v" BLOB)`, table)); err != nil {
+		return
+	}
+
+	kv = &KV{
+		db:    db,
+		table: table,
+	}
+
+	return
+}
+
+// Get returns value of the key, ErrKeyNotFound is returned if the key does not exist.
+func (kv *KV) Get(key string) (value []byte, err error) {
+	row := kv.db.QueryRow(fmt.Sprintf(`SELECT "v" FROM "%s" WHERE "k" = ? LIMIT 1`, kv.table), key)
+	if err = row.Scan(&value); err == sql.ErrNoRows {
+		err = ErrKeyNotFound
+	}
+	return
+}
+
+// Put sets value of the key, existing value is overwritten.
+func (kv *KV) Put(key string, value []byte) (err error) {
+	_, err = kv.db.Exec(fmt.Sprintf(`INSERT OR REPLACE INTO "%s" ("k", "v") VALUES (?, ?)`, kv.table), key, value)
+	return
+}
+
+// Delete removes the key, deleting a non-existent key is not an error.
+func (kv *KV) Delete(key string) (err error) {
+	_, err = kv.db.Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE "k" = ?`, kv.table), key)
+	return
+}
+
+// Scan returns at most limit pairs in key order in range [start, end),
+// empty end means no upper bound and non-positive limit means no limit.
+func (kv *KV) Scan(start string, end string, limit int) (pairs []KVPair, err error) {
+	q := fmt.Sprintf(`SELECT "k", "v" FROM "%s" WHERE "k" >= ?`, kv.table)
+	args := []interface{}{start}
+
+	if end != "" {
+		q += ` AND "k" < ?`
+		args = append(args, end)
+	}
+
+	q += ` ORDER BY "k"`
+
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	var rows *sql.Rows
+	if rows, err = kv.db.Query(q, args...); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	pairs = make([]KVPair, 0)
+
+	for rows.Next() {
+		var p KVPair
+		if err = rows.Scan(&p.Key, &p.Value); err != nil {
+			return
+		}
+		pairs = append(pairs, p)
+	}
+
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"database/sql"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKV(t *testing.T) {
+	Convey("test key-value store", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		var kv *KV
+		_, err = NewKV(db, "invalid table")
+		So(err, ShouldEqual, ErrInvalidTableName)
+		kv, err = NewKV(db, "")
+		So(err, ShouldBeNil)
+		So(kv, ShouldNotBeNil)
+
+		// create again should not fail
+		_, err = NewKV(db, DefaultKVTable)
+		So(err, ShouldBeNil)
+
+		_, err = kv.Get("a")
+		So(err, ShouldEqual, ErrKeyNotFound)
+
+		So(kv.Put("a", []byte("1")), ShouldBeNil)
+		So(kv.Put("b", []byte("2")), ShouldBeNil)
+		So(kv.Put("c", []byte("3")), ShouldBeNil)
+		So(kv.Put("a", []byte("4")), ShouldBeNil)
+
+		var value []byte
+		value, err = kv.Get("a")
+		So(err, ShouldBeNil)
+		So(value, ShouldResemble, []byte("4"))
+
+		var pairs []KVPair
+		pairs, err = kv.Scan("", "", 0)
+		So(err, ShouldBeNil)
+		So(pairs, ShouldResemble, []KVPair{
+			{Key: "a", Value: []byte("4")},
+			{Key: "b", Value: []byte("2")},
+			{Key: "c", Value: []byte("3")},
+		})
+
+		pairs, err = kv.Scan("b", "c", 0)
+		So(err, ShouldBeNil)
+		So(pairs, ShouldResemble, []KVPair{{Key: "b", Value: []byte("2")}})
+
+		pairs, err = kv.Scan("a", "", 2)
+		So(err, ShouldBeNil)
+		So(len(pairs), ShouldEqual, 2)
+
+		So(kv.Delete("a"), ShouldBeNil)
+		So(kv.Delete("not_exists"), ShouldBeNil)
+		_, err = kv.Get("a")
+		So(err, ShouldEqual, ErrKeyNotFound)
+	})
+}
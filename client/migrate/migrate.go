@@ -0,0 +1,298 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package migrate implements a golang-migrate database driver for CovenantSQL.
+//
+// Migration versions are recorded in a schema migrations table. Write queries of a database
+// are serialized by its leader, so a sentinel row inserted with fixed primary key acts as the
+// advisory lock of migration runners.
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+const (
+	// DefaultMigrationsTable defines the default table recording migration version.
+	DefaultMigrationsTable = "schema_migrations"
+	// DefaultLockTimeout defines the default expiration of an unreleased lock, a lock held
+	// longer than it is considered left by a crashed runner and is taken over.
+	DefaultLockTimeout = 15 * time.Minute
+
+	// NilVersion defines the version of database without any migration applied.
+	NilVersion = -1
+
+	lockTableSuffix = "_lock"
+	lockRowID       = 1
+)
+
+// Various errors of migrate driver.
+var (
+	ErrNilConfig           = errors.New("no config")
+	ErrInvalidTableName    = errors.New("invalid migrations table name")
+	ErrLocked              = errors.New("database is locked by another migration")
+	ErrNotLocked           = errors.New("database is not locked")
+	ErrEmptyMigration      = errors.New("empty migration")
+	ErrInvalidVersionState = errors.New("invalid migration version state")
+)
+
+var (
+	identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Config defines the migrate driver config.
+type Config struct {
+	MigrationsTable string
+	LockTimeout     time.Duration
+}
+
+// Driver implements the golang-migrate database.Driver interface.
+type Driver struct {
+	db       *sql.DB
+	config   *Config
+	owner    string
+	isLocked bool
+	lock     sync.Mutex
+}
+
+// WithInstance returns a migrate driver using an opened CovenantSQL database.
+func WithInstance(db *sql.DB, config *Config) (d *Driver, err error) {
+	if config == nil {
+		err = ErrNilConfig
+		return
+	}
+	if config.MigrationsTable == "" {
+		config.MigrationsTable = DefaultMigrationsTable
+	}
+	if !identifierRegex.MatchString(config.MigrationsTable) {
+		err = ErrInvalidTableName
+		return
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = DefaultLockTimeout
+	}
+
+	d = &Driver{
+		db:     db,
+		config: config,
+		owner:  hash.THashH([]byte(fmt.Sprintf("%p-%d", db, time.Now().UnixNano()))).String(),
+	}
+
+	if err = d.ensureTables(); err != nil {
+		d = nil
+	}
+
+	return
+}
+
+func (d *Driver) lockTable() string {
+	return d.config.MigrationsTable + lockTableSuffix
+}
+
+func (d *Driver) ensureTables() (err error) {
+	if _, err = d.db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS "%s" ("version" INTEGER NOT NULL PRIMARY KEY, "dirty" BOOLEAN NOT NULL)`,
+		d.config.MigrationsTable)); err != nil {
+		return
+	}
+	_, err = d.db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS "%s" ("id" INTEGER NOT NULL PRIMARY KEY, "owner" TEXT NOT NULL, "locked_at" INTEGER NOT NULL)`,
+		d.lockTable()))
+	return
+}
+
+// Close implements database.Driver.Close.
+func (d *Driver) Close() error {
+	return d.db.Close()
+}
+
+// Lock implements database.Driver.Lock, the lock is acquired by inserting the sentinel row,
+// which fails if the row is held by another runner.
+func (d *Driver) Lock() (err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.isLocked {
+		return ErrLocked
+	}
+
+	now := time.Now()
+
+	var tx *sql.Tx
+	if tx, err = d.db.Begin(); err != nil {
+		return
+	}
+	// take over expired lock
+	if _, err = tx.Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE "id" = ? AND "locked_at" < ?`, d.lockTable()),
+		lockRowID, now.Add(-d.config.LockTimeout).UnixNano()); err != nil {
+		tx.Rollback()
+		return
+	}
+	if _, err = tx.Exec(fmt.Sprintf(`INSERT INTO "%s" ("id", "owner", "locked_at") VALUES (?, ?, ?)`, d.lockTable()),
+		lockRowID, d.owner, now.UnixNano()); err != nil {
+		tx.Rollback()
+		return d.checkLockConflict(err)
+	}
+	if err = tx.Commit(); err != nil {
+		// queries in transaction are executed on commit by CovenantSQL
+		return d.checkLockConflict(err)
+	}
+
+	d.isLocked = true
+	return
+}
+
+func (d *Driver) checkLockConflict(lockErr error) (err error) {
+	var owner string
+	row := d.db.QueryRow(fmt.Sprintf(`SELECT "owner" FROM "%s" WHERE "id" = ?`, d.lockTable()), lockRowID)
+	if row.Scan(&owner) == nil && owner != d.owner {
+		return ErrLocked
+	}
+	return lockErr
+}
+
+// Unlock implements database.Driver.Unlock.
+func (d *Driver) Unlock() (err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.isLocked {
+		return ErrNotLocked
+	}
+
+	if _, err = d.db.Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE "id" = ? AND "owner" = ?`, d.lockTable()),
+		lockRowID, d.owner); err != nil {
+		return
+	}
+
+	d.isLocked = false
+	return
+}
+
+// Run implements database.Driver.Run, statements of the migration are applied in a single write request.
+func (d *Driver) Run(migration io.Reader) (err error) {
+	var body []byte
+	if body, err = ioutil.ReadAll(migration); err != nil {
+		return
+	}
+
+	query := strings.TrimSpace(string(body))
+	if query == "" {
+		return ErrEmptyMigration
+	}
+
+	if _, err = d.db.Exec(query); err != nil {
+		err = fmt.Errorf("migration failed: %v", err)
+	}
+	return
+}
+
+// SetVersion implements database.Driver.SetVersion.
+func (d *Driver) SetVersion(version int, dirty bool) (err error) {
+	var tx *sql.Tx
+	if tx, err = d.db.Begin(); err != nil {
+		return
+	}
+	if _, err = tx.Exec(fmt.Sprintf(`DELETE FROM "%s"`, d.config.MigrationsTable)); err != nil {
+		tx.Rollback()
+		return
+	}
+	if version >= 0 {
+		if _, err = tx.Exec(fmt.Sprintf(`INSERT INTO "%s" ("version", "dirty") VALUES (?, ?)`,
+			d.config.MigrationsTable), version, dirty); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// Version implements database.Driver.Version, NilVersion is returned if no migration is applied.
+func (d *Driver) Version() (version int, dirty bool, err error) {
+	var rows *sql.Rows
+	if rows, err = d.db.Query(fmt.Sprintf(`SELECT "version", "dirty" FROM "%s" LIMIT 2`,
+		d.config.MigrationsTable)); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	version = NilVersion
+	for i := 0; rows.Next(); i++ {
+		if i > 0 {
+			err = ErrInvalidVersionState
+			return
+		}
+		if err = rows.Scan(&version, &dirty); err != nil {
+			return
+		}
+	}
+
+	err = rows.Err()
+	return
+}
+
+// Drop implements database.Driver.Drop, all tables except the migration tables are dropped
+// and the version is reset.
+func (d *Driver) Drop() (err error) {
+	var rows *sql.Rows
+	if rows, err = d.db.Query(`SELECT "name" FROM "sqlite_master" WHERE "type" = "table"`); err != nil {
+		return
+	}
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return
+		}
+		if strings.HasPrefix(name, "sqlite_") ||
+			name == d.config.MigrationsTable || name == d.lockTable() {
+			continue
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	var tx *sql.Tx
+	if tx, err = d.db.Begin(); err != nil {
+		return
+	}
+	if _, err = tx.Exec(fmt.Sprintf(`DELETE FROM "%s"`, d.config.MigrationsTable)); err != nil {
+		tx.Rollback()
+		return
+	}
+	for _, t := range tables {
+		if _, err = tx.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, t)); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+	return tx.Commit()
+}
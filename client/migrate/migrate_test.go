@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/CovenantSQL/go-sqlite3-encrypt"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func openTestDB() (db *sql.DB, cleanup func(), err error) {
+	var fl *os.File
+	if fl, err = ioutil.TempFile("", "migrate-"); err != nil {
+		return
+	}
+	fl.Close()
+
+	if db, err = sql.Open("sqlite3", fl.Name()); err != nil {
+		os.Remove(fl.Name())
+		return
+	}
+
+	cleanup = func() {
+		db.Close()
+		os.Remove(fl.Name())
+	}
+	return
+}
+
+func TestDriver(t *testing.T) {
+	Convey("test migrate driver", t, func() {
+		db, cleanup, err := openTestDB()
+		So(err, ShouldBeNil)
+		defer cleanup()
+
+		_, err = WithInstance(db, nil)
+		So(err, ShouldEqual, ErrNilConfig)
+		_, err = WithInstance(db, &Config{MigrationsTable: "bad table"})
+		So(err, ShouldEqual, ErrInvalidTableName)
+
+		d, err := WithInstance(db, &Config{})
+		So(err, ShouldBeNil)
+
+		version, dirty, err := d.Version()
+		So(err, ShouldBeNil)
+		So(version, ShouldEqual, NilVersion)
+		So(dirty, ShouldBeFalse)
+
+		Convey("lock is exclusive between runners", func() {
+			other, err := WithInstance(db, &Config{})
+			So(err, ShouldBeNil)
+
+			So(d.Unlock(), ShouldEqual, ErrNotLocked)
+			So(d.Lock(), ShouldBeNil)
+			So(d.Lock(), ShouldEqual, ErrLocked)
+			So(other.Lock(), ShouldEqual, ErrLocked)
+			So(d.Unlock(), ShouldBeNil)
+			So(other.Lock(), ShouldBeNil)
+			So(other.Unlock(), ShouldBeNil)
+		})
+
+		Convey("expired lock is taken over", func() {
+			other, err := WithInstance(db, &Config{LockTimeout: time.Millisecond})
+			So(err, ShouldBeNil)
+
+			So(d.Lock(), ShouldBeNil)
+			time.Sleep(10 * time.Millisecond)
+			So(other.Lock(), ShouldBeNil)
+			So(other.Unlock(), ShouldBeNil)
+		})
+
+		Convey("run migrations and set version", func() {
+			So(d.Run(strings.NewReader("")), ShouldEqual, ErrEmptyMigration)
+			So(d.Run(strings.NewReader(
+				"CREATE TABLE test (id INTEGER PRIMARY KEY);\nINSERT INTO test VALUES (1);")), ShouldBeNil)
+			So(d.Run(strings.NewReader("THIS IS NOT A SQL")), ShouldNotBeNil)
+
+			So(d.SetVersion(1, true), ShouldBeNil)
+			So(d.SetVersion(1, false), ShouldBeNil)
+			version, dirty, err := d.Version()
+			So(err, ShouldBeNil)
+			So(version, ShouldEqual, 1)
+			So(dirty, ShouldBeFalse)
+
+			var count int
+			So(db.QueryRow("SELECT COUNT(1) FROM test").Scan(&count), ShouldBeNil)
+			So(count, ShouldEqual, 1)
+
+			So(d.Drop(), ShouldBeNil)
+			version, _, err = d.Version()
+			So(err, ShouldBeNil)
+			So(version, ShouldEqual, NilVersion)
+			_, err = db.Exec("SELECT * FROM test")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/golang-migrate/migrate/database"
+
+	// register covenantsql sql driver
+	_ "github.com/CovenantSQL/CovenantSQL/client"
+)
+
+var (
+	_ database.Driver = (*Driver)(nil)
+
+	// sqlDriverName defines the sql driver opening the dsn passed to golang-migrate.
+	sqlDriverName = "covenantsql"
+)
+
+func init() {
+	database.Register("covenantsql", &Driver{})
+}
+
+// Open implements database.Driver.Open, migrate driver options are passed as
+// x-migrations-table and x-lock-timeout parameters of the covenantsql dsn.
+func (d *Driver) Open(dsn string) (driver database.Driver, err error) {
+	var u *url.URL
+	if u, err = url.Parse(dsn); err != nil {
+		return
+	}
+
+	config := &Config{}
+	q := u.Query()
+	config.MigrationsTable = q.Get("x-migrations-table")
+	if timeout := q.Get("x-lock-timeout"); timeout != "" {
+		if config.LockTimeout, err = time.ParseDuration(timeout); err != nil {
+			return
+		}
+	}
+	q.Del("x-migrations-table")
+	q.Del("x-lock-timeout")
+	u.RawQuery = q.Encode()
+
+	var db *sql.DB
+	if db, err = sql.Open(sqlDriverName, u.String()); err != nil {
+		return
+	}
+
+	var md *Driver
+	if md, err = WithInstance(db, config); err != nil {
+		db.Close()
+		return
+	}
+
+	driver = md
+	return
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOpen(t *testing.T) {
+	Convey("test open migrate driver by dsn", t, func() {
+		fl, err := ioutil.TempFile("", "migrate-")
+		So(err, ShouldBeNil)
+		fl.Close()
+		defer os.Remove(fl.Name())
+
+		// open test database by sqlite3 driver instead of covenantsql
+		origDriverName := sqlDriverName
+		sqlDriverName = "sqlite3"
+		defer func() { sqlDriverName = origDriverName }()
+
+		_, err = (&Driver{}).Open("file:" + fl.Name() + "?x-lock-timeout=forever")
+		So(err, ShouldNotBeNil)
+		_, err = (&Driver{}).Open("file:" + fl.Name() + "?x-migrations-table=bad-table")
+		So(err, ShouldEqual, ErrInvalidTableName)
+
+		driver, err := (&Driver{}).Open(
+			"file:" + fl.Name() + "?x-migrations-table=custom_migrations&x-lock-timeout=1m")
+		So(err, ShouldBeNil)
+		defer driver.Close()
+
+		d, ok := driver.(*Driver)
+		So(ok, ShouldBeTrue)
+		So(d.config.MigrationsTable, ShouldEqual, "custom_migrations")
+		So(d.config.LockTimeout, ShouldEqual, time.Minute)
+
+		var name string
+		So(d.db.QueryRow(
+			"SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'custom_migrations'",
+		).Scan(&name), ShouldBeNil)
+		So(name, ShouldEqual, "custom_migrations")
+
+		version, dirty, err := driver.Version()
+		So(err, ShouldBeNil)
+		So(version, ShouldEqual, NilVersion)
+		So(dirty, ShouldBeFalse)
+	})
+}
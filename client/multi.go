@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+var (
+	createTriggerRegex = regexp.MustCompile(`(?is)^CREATE\s+(TEMP\s+|TEMPORARY\s+)?TRIGGER\b`)
+	triggerEndRegex    = regexp.MustCompile(`(?is)\bEND$`)
+)
+
+// splitStatements splits query to statements by semicolons outside of literals, comments and
+// trigger bodies, placeholder count of each statement is returned. Empty statements are dropped.
+func splitStatements(query string) (stmts []string, params []int) {
+	var start, count int
+
+	appendStmt := func(end int) {
+		if stmt := strings.TrimSpace(query[start:end]); stmt != "" {
+			stmts = append(stmts, stmt)
+			params = append(params, count)
+		}
+		start = end + 1
+		count = 0
+	}
+
+	for i := 0; i < len(query); {
+		if end := skipLiteral(query, i); end > i {
+			i = end
+			continue
+		}
+
+		switch query[i] {
+		case '?':
+			count++
+		case ';':
+			// statements in trigger body are terminated by semicolons too
+			stmt := strings.TrimSpace(query[start:i])
+			if !createTriggerRegex.MatchString(stmt) || triggerEndRegex.MatchString(stmt) {
+				appendStmt(i)
+			}
+		}
+		i++
+	}
+
+	appendStmt(len(query))
+	return
+}
+
+// convertStatements converts semicolon separated statements to queries, arguments are assigned
+// to statements by placeholders order.
+func convertStatements(query string, args []driver.NamedValue) (queries []wt.Query, err error) {
+	// rewrite named parameters of all statements first
+	if query, args, err = bindNamedParams(query, args); err != nil {
+		return
+	}
+
+	stmts, params := splitStatements(query)
+	if len(stmts) <= 1 {
+		// single statement is sent as is
+		var sq *wt.Query
+		if sq, err = convertQuery(query, args); err != nil {
+			return
+		}
+		queries = []wt.Query{*sq}
+		return
+	}
+
+	total := 0
+	for _, p := range params {
+		total += p
+	}
+	if total != len(args) {
+		err = ErrParamCountMismatch
+		return
+	}
+
+	queries = make([]wt.Query, len(stmts))
+	offset := 0
+
+	for i, stmt := range stmts {
+		stmtArgs := make([]driver.NamedValue, params[i])
+		for j := range stmtArgs {
+			stmtArgs[j] = driver.NamedValue{
+				Ordinal: j + 1,
+				Value:   args[offset+j].Value,
+			}
+		}
+		offset += params[i]
+
+		var sq *wt.Query
+		if sq, err = convertQuery(stmt, stmtArgs); err != nil {
+			return
+		}
+		queries[i] = *sq
+	}
+
+	return
+}
+
+// statementResultsContextKey is the context key of the statement results collected by ExecMulti.
+type statementResultsContextKey struct{}
+
+// collectStatementResults appends the result of each statement in result to the collector of
+// ExecMulti in ctx if any.
+func collectStatementResults(ctx context.Context, result driver.Result) {
+	collected, ok := ctx.Value(statementResultsContextKey{}).(*[]sql.Result)
+	if !ok {
+		return
+	}
+	if er, ok := result.(*execResult); ok {
+		for _, r := range er.statements {
+			*collected = append(*collected, r)
+		}
+	}
+}
+
+// ExecMulti executes semicolon separated statements atomically in a single write request and
+// returns the result of each statement.
+func ExecMulti(ctx context.Context, db *sql.DB, query string, args ...interface{}) (results []sql.Result, err error) {
+	if _, ok := db.Driver().(*covenantSQLDriver); !ok {
+		err = ErrUnsupportedConn
+		return
+	}
+	// the statements are sent by the driver in a single request, which collects the results
+	ctx = context.WithValue(ctx, statementResultsContextKey{}, &results)
+	if _, err = db.ExecContext(ctx, query, args...); err != nil {
+		results = nil
+	}
+	return
+}
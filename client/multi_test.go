@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitStatements(t *testing.T) {
+	Convey("test splitting statements", t, func() {
+		stmts, params := splitStatements("select 1")
+		So(stmts, ShouldResemble, []string{"select 1"})
+		So(params, ShouldResemble, []int{0})
+
+		stmts, params = splitStatements(" ; insert into t values (?, ';'); -- comment; ?\n" +
+			"update t set a = ? /* ; ? */ where b = \"?;\";;")
+		So(stmts, ShouldResemble, []string{
+			"insert into t values (?, ';')",
+			"-- comment; ?\nupdate t set a = ? /* ; ? */ where b = \"?;\"",
+		})
+		So(params, ShouldResemble, []int{1, 1})
+
+		stmts, _ = splitStatements("CREATE TRIGGER tr AFTER INSERT ON t BEGIN " +
+			"INSERT INTO log VALUES (1); DELETE FROM log WHERE id < 0; END; insert into t values (1)")
+		So(stmts, ShouldResemble, []string{
+			"CREATE TRIGGER tr AFTER INSERT ON t BEGIN " +
+				"INSERT INTO log VALUES (1); DELETE FROM log WHERE id < 0; END",
+			"insert into t values (1)",
+		})
+
+		stmts, _ = splitStatements("  ;  ")
+		So(stmts, ShouldBeEmpty)
+	})
+
+	Convey("test converting statements", t, func() {
+		args := []driver.NamedValue{
+			{Ordinal: 1, Value: int64(1)},
+			{Ordinal: 2, Value: int64(2)},
+			{Ordinal: 3, Value: int64(3)},
+		}
+
+		queries, err := convertStatements("insert into t values (?); insert into t values (?, ?)", args)
+		So(err, ShouldBeNil)
+		So(queries, ShouldHaveLength, 2)
+		So(queries[0].Args, ShouldHaveLength, 1)
+		So(queries[0].Args[0].Value, ShouldEqual, 1)
+		So(queries[1].Args, ShouldHaveLength, 2)
+		So(queries[1].Args[1].Value, ShouldEqual, 3)
+
+		_, err = convertStatements("insert into t values (?); insert into t values (?)", args)
+		So(err, ShouldEqual, ErrParamCountMismatch)
+
+		queries, err = convertStatements("insert into t values (:a); insert into t values (:a, :b)",
+			[]driver.NamedValue{
+				{Name: "a", Value: int64(1)},
+				{Name: "b", Value: int64(2)},
+			})
+		So(err, ShouldBeNil)
+		So(queries, ShouldHaveLength, 2)
+		So(queries[1].Pattern, ShouldEqual, "insert into t values (?, ?)")
+		So(queries[1].Args[0].Value, ShouldEqual, 1)
+		So(queries[1].Args[1].Value, ShouldEqual, 2)
+	})
+}
+
+func TestExecMulti(t *testing.T) {
+	Convey("test multiple statements exec", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		var results []sql.Result
+		results, err = ExecMulti(context.Background(), db,
+			"create table test (id integer primary key, v int);"+
+				"insert into test (v) values (?), (?);"+
+				"update test set v = v + 1 where v > ?", 1, 2, 1)
+		So(err, ShouldBeNil)
+		So(results, ShouldHaveLength, 3)
+
+		var id, affected int64
+		id, err = results[1].LastInsertId()
+		So(err, ShouldBeNil)
+		So(id, ShouldEqual, 2)
+		affected, err = results[1].RowsAffected()
+		So(err, ShouldBeNil)
+		So(affected, ShouldEqual, 2)
+		affected, err = results[2].RowsAffected()
+		So(err, ShouldBeNil)
+		So(affected, ShouldEqual, 1)
+
+		// aggregated result of plain exec
+		var res sql.Result
+		res, err = db.Exec("insert into test (v) values (10); insert into test (v) values (11)")
+		So(err, ShouldBeNil)
+		affected, err = res.RowsAffected()
+		So(err, ShouldBeNil)
+		So(affected, ShouldEqual, 2)
+
+		// statements are applied atomically
+		_, err = db.Exec("insert into test (v) values (20); THIS IS NOT A SQL")
+		So(err, ShouldNotBeNil)
+
+		var count int
+		err = db.QueryRow("select count(1) from test").Scan(&count)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 4)
+	})
+}
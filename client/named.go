@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"database/sql/driver"
+)
+
+// bindNamedParams rewrites ":name", "@name" and "$name" placeholders of query to positional
+// placeholders and reorders named arguments by their occurrences, query and args are returned
+// untouched if no named argument is supplied.
+func bindNamedParams(query string, args []driver.NamedValue) (bound string, boundArgs []driver.NamedValue, err error) {
+	named := make(map[string]driver.NamedValue)
+	for _, arg := range args {
+		if arg.Name != "" {
+			named[arg.Name] = arg
+		}
+	}
+
+	if len(named) == 0 {
+		return query, args, nil
+	}
+	if len(named) != len(args) {
+		err = ErrMixedParameters
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(query))
+	boundArgs = make([]driver.NamedValue, 0, len(args))
+
+	for i := 0; i < len(query); {
+		if end := skipLiteral(query, i); end > i {
+			buf.WriteString(query[i:end])
+			i = end
+			continue
+		}
+
+		ch := query[i]
+
+		switch {
+		case ch == '?':
+			err = ErrMixedParameters
+			return
+		case (ch == ':' || ch == '@' || ch == '$') && i+1 < len(query) && isIdentifierStart(query[i+1]):
+			end := i + 2
+			for end < len(query) && isIdentifierPart(query[end]) {
+				end++
+			}
+
+			arg, ok := named[query[i+1:end]]
+			if !ok {
+				err = ErrNamedParamNotFound
+				return
+			}
+
+			boundArgs = append(boundArgs, driver.NamedValue{
+				Ordinal: len(boundArgs) + 1,
+				Value:   arg.Value,
+			})
+			buf.WriteByte('?')
+			i = end
+		default:
+			buf.WriteByte(ch)
+			i++
+		}
+	}
+
+	bound = buf.String()
+	return
+}
+
+// skipLiteral returns the end of quoted string, quoted identifier or comment starting at i,
+// i is returned if there is none.
+func skipLiteral(query string, i int) (end int) {
+	ch := query[i]
+
+	switch {
+	case ch == '\'' || ch == '"' || ch == '`':
+		// quoted string or identifier, quote char is escaped by doubling it
+		end = i + 1
+		for end < len(query) {
+			if query[end] == ch {
+				if end+1 < len(query) && query[end+1] == ch {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		return minInt(end+1, len(query))
+	case ch == '[':
+		// bracket quoted identifier
+		end = i + 1
+		for end < len(query) && query[end] != ']' {
+			end++
+		}
+		return minInt(end+1, len(query))
+	case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+		// line comment
+		end = i + 2
+		for end < len(query) && query[end] != '\n' {
+			end++
+		}
+		return
+	case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+		// block comment
+		end = i + 2
+		for end+1 < len(query) && !(query[end] == '*' && query[end+1] == '/') {
+			end++
+		}
+		return minInt(end+2, len(query))
+	default:
+		return i
+	}
+}
+
+func isIdentifierStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentifierPart(ch byte) bool {
+	return isIdentifierStart(ch) || (ch >= '0' && ch <= '9')
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
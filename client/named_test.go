@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBindNamedParams(t *testing.T) {
+	Convey("test named parameters rewriting", t, func() {
+		var query string
+		var args []driver.NamedValue
+		var err error
+
+		// positional parameters are untouched
+		positional := []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}
+		query, args, err = bindNamedParams("select ?", positional)
+		So(err, ShouldBeNil)
+		So(query, ShouldEqual, "select ?")
+		So(args, ShouldResemble, positional)
+
+		// all placeholder styles with repeated name
+		query, args, err = bindNamedParams(
+			"select * from t where a = :a and b = @b and c = $c or a = :a",
+			[]driver.NamedValue{
+				{Name: "c", Ordinal: 1, Value: "c"},
+				{Name: "b", Ordinal: 2, Value: "b"},
+				{Name: "a", Ordinal: 3, Value: "a"},
+			})
+		So(err, ShouldBeNil)
+		So(query, ShouldEqual, "select * from t where a = ? and b = ? and c = ? or a = ?")
+		So(args, ShouldResemble, []driver.NamedValue{
+			{Ordinal: 1, Value: "a"},
+			{Ordinal: 2, Value: "b"},
+			{Ordinal: 3, Value: "c"},
+			{Ordinal: 4, Value: "a"},
+		})
+
+		// placeholders in literals and comments are kept
+		query, args, err = bindNamedParams(
+			"select ':a', \"@a\", `$a`, [:a], 'it''s :a' -- :a\n, /* :a */ :a",
+			[]driver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}})
+		So(err, ShouldBeNil)
+		So(query, ShouldEqual, "select ':a', \"@a\", `$a`, [:a], 'it''s :a' -- :a\n, /* :a */ ?")
+		So(args, ShouldHaveLength, 1)
+
+		// missing parameter
+		_, _, err = bindNamedParams("select :a, :b", []driver.NamedValue{{Name: "a", Ordinal: 1}})
+		So(err, ShouldEqual, ErrNamedParamNotFound)
+
+		// mixed parameters
+		_, _, err = bindNamedParams("select :a, ?", []driver.NamedValue{{Name: "a", Ordinal: 1}})
+		So(err, ShouldEqual, ErrMixedParameters)
+		_, _, err = bindNamedParams("select :a, :b", []driver.NamedValue{
+			{Name: "a", Ordinal: 1},
+			{Ordinal: 2},
+		})
+		So(err, ShouldEqual, ErrMixedParameters)
+	})
+
+	Convey("test named parameters in queries", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (k text, v int)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into test values (:k, @v)", sql.Named("v", 1), sql.Named("k", "a"))
+		So(err, ShouldBeNil)
+
+		var v int
+		err = db.QueryRow("select v from test where k = $k", sql.Named("k", "a")).Scan(&v)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"sync"
+
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+)
+
+// nonceSequence allocates the nonces of transactions sent by an account locally, so several
+// transactions could be in flight concurrently without waiting for the previous ones to be
+// applied. Block producer queues the transactions arrived ahead of their nonces in a small window
+// until the transactions before them arrive.
+type nonceSequence struct {
+	sync.Mutex
+	addr  proto.AccountAddress
+	next  AccountNonce
+	valid bool
+}
+
+// localNonces is the nonce sequence of the local account.
+var localNonces nonceSequence
+
+// allocate returns the next nonce of account addr, the sequence is synchronized with block
+// producer on first use or after reset.
+func (s *nonceSequence) allocate(addr proto.AccountAddress) (nonce AccountNonce, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.valid || s.addr != addr {
+		req := &bp.NextAccountNonceReq{Addr: addr}
+		resp := new(bp.NextAccountNonceResp)
+		if err = requestBP(route.MCCNextAccountNonce, req, resp); err != nil {
+			return
+		}
+		s.addr, s.next, s.valid = addr, resp.Nonce, true
+	}
+	nonce = s.next
+	s.next++
+	return
+}
+
+// reset discards the allocated nonces, which should be called once a transaction with an
+// allocated nonce is rejected, so the gap is filled by the next allocation.
+func (s *nonceSequence) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.valid = false
+}
+
+// AllocateNonce returns a nonce for the next transaction sent by the local account without
+// waiting for the pending transactions to be applied. Unlike GetNextNonce, the nonces allocated
+// concurrently are distinct.
+func AllocateNonce() (nonce AccountNonce, err error) {
+	var addr proto.AccountAddress
+	if addr, err = localAccountAddress(); err != nil {
+		return
+	}
+	return localNonces.allocate(addr)
+}
+
+// ResetNonce discards the nonces allocated by AllocateNonce, it should be called if a transaction
+// with an allocated nonce is rejected by block producer.
+func ResetNonce() {
+	localNonces.reset()
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAllocateNonce(t *testing.T) {
+	Convey("test nonce allocation", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		addr, err := localAccountAddress()
+		So(err, ShouldBeNil)
+		stubMCC.Lock()
+		stubMCC.nonces[addr] = 5
+		stubMCC.Unlock()
+
+		nonce, err := AllocateNonce()
+		So(err, ShouldBeNil)
+		So(nonce, ShouldEqual, 5)
+		nonce, err = AllocateNonce()
+		So(err, ShouldBeNil)
+		So(nonce, ShouldEqual, 6)
+
+		// allocated nonces are discarded after reset
+		ResetNonce()
+		nonce, err = AllocateNonce()
+		So(err, ShouldBeNil)
+		So(nonce, ShouldEqual, 5)
+	})
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"time"
+
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+)
+
+const (
+	// PermissionConfirmInterval defines the interval of checking whether a permission update is
+	// confirmed by block producer.
+	PermissionConfirmInterval = time.Second
+
+	// PermissionConfirmTimeout defines the max duration of waiting for a permission update to be
+	// confirmed by block producer.
+	PermissionConfirmTimeout = 5 * time.Minute
+)
+
+// Permission defines the permission of a database user.
+type Permission pt.UserPermission
+
+const (
+	// PermissionAdmin allows the user to read, write and manage permissions of the database.
+	PermissionAdmin = Permission(pt.Admin)
+	// PermissionWrite allows the user to read and write the database.
+	PermissionWrite = Permission(pt.ReadWrite)
+	// PermissionRead allows the user to read the database.
+	PermissionRead = Permission(pt.Read)
+)
+
+// DatabaseUser defines a user and its permission of database.
+type DatabaseUser = pt.SQLChainUser
+
+// GrantPermission grants perm on database to the account of user public key, the local account
+// must be an admin of the database. It returns after the grant is confirmed by block producer.
+func GrantPermission(dbID proto.DatabaseID, user *asymmetric.PublicKey, perm Permission) (err error) {
+	if user == nil {
+		return ErrInvalidParameter
+	}
+	var userAddr proto.AccountAddress
+	if userAddr, err = accountAddress(user); err != nil {
+		return
+	}
+	return updatePermission(dbID, userAddr, perm, false, 0)
+}
+
+// RevokePermission revokes all permissions on database from the account of user public key, the
+// local account must be an admin of the database. It returns after the revocation is confirmed by
+// block producer.
+func RevokePermission(dbID proto.DatabaseID, user *asymmetric.PublicKey) (err error) {
+	if user == nil {
+		return ErrInvalidParameter
+	}
+	var userAddr proto.AccountAddress
+	if userAddr, err = accountAddress(user); err != nil {
+		return
+	}
+	return updatePermission(dbID, userAddr, 0, true, 0)
+}
+
+// GrantAccountPermission is like GrantPermission but grants perm to the account address user.
+func GrantAccountPermission(dbID proto.DatabaseID, user proto.AccountAddress, perm Permission) error {
+	return updatePermission(dbID, user, perm, false, 0)
+}
+
+// RevokeAccountPermission is like RevokePermission but revokes from the account address user.
+func RevokeAccountPermission(dbID proto.DatabaseID, user proto.AccountAddress) error {
+	return updatePermission(dbID, user, 0, true, 0)
+}
+
+// RevokeAccountPermissionWithFee is like RevokeAccountPermission but pays fee in covenant coins
+// to block producer, so the revocation is packed before pending transactions with lower fee rates.
+func RevokeAccountPermissionWithFee(dbID proto.DatabaseID, user proto.AccountAddress, fee uint64) error {
+	return updatePermission(dbID, user, 0, true, fee)
+}
+
+// GetDatabaseUsers returns the confirmed users and permissions of database.
+func GetDatabaseUsers(dbID proto.DatabaseID) (users []*DatabaseUser, err error) {
+	req := &bp.QuerySQLChainProfileReq{DBID: dbID}
+	resp := new(bp.QuerySQLChainProfileResp)
+	if err = requestBP(route.MCCQuerySQLChainProfile, req, resp); err != nil {
+		return
+	}
+	users = resp.Profile.Users
+	return
+}
+
+func updatePermission(dbID proto.DatabaseID, userAddr proto.AccountAddress, perm Permission,
+	revoke bool, fee uint64) (err error) {
+	var (
+		privateKey *asymmetric.PrivateKey
+		sender     proto.AccountAddress
+	)
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if sender, err = accountAddress(privateKey.PubKey()); err != nil {
+		return
+	}
+
+	var nonce AccountNonce
+	if nonce, err = localNonces.allocate(sender); err != nil {
+		return
+	}
+
+	tx := &pt.UpdatePermission{
+		UpdatePermissionHeader: pt.UpdatePermissionHeader{
+			Sender:     sender,
+			Nonce:      nonce,
+			DatabaseID: dbID,
+			User:       userAddr,
+			Permission: pt.UserPermission(perm),
+			Revoke:     revoke,
+			Fee:        fee,
+		},
+		ChainID: chainID(),
+	}
+	if err = tx.Sign(privateKey); err != nil {
+		localNonces.reset()
+		return
+	}
+	if err = requestBP(route.MCCUpdatePermission, &bp.UpdatePermissionReq{Tx: tx}, new(bp.UpdatePermissionResp)); err != nil {
+		localNonces.reset()
+		return
+	}
+
+	return waitPermission(dbID, userAddr, pt.UserPermission(perm), revoke)
+}
+
+// waitPermission polls the confirmed database profile until the permission update takes effect.
+func waitPermission(
+	dbID proto.DatabaseID, user proto.AccountAddress, perm pt.UserPermission, revoke bool) (err error,
+) {
+	deadline := time.Now().Add(PermissionConfirmTimeout)
+
+	for {
+		req := &bp.QuerySQLChainProfileReq{DBID: dbID}
+		resp := new(bp.QuerySQLChainProfileResp)
+		if err = requestBP(route.MCCQuerySQLChainProfile, req, resp); err == nil {
+			var found bool
+			for _, u := range resp.Profile.Users {
+				if u.Address == user {
+					found = true
+					if !revoke && u.Permission == perm {
+						return
+					}
+				}
+			}
+			if revoke && !found {
+				return
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return ErrPermissionNotConfirmed
+		}
+		time.Sleep(PermissionConfirmInterval)
+	}
+}
+
+func accountAddress(pubKey *asymmetric.PublicKey) (addr proto.AccountAddress, err error) {
+	var enc []byte
+	if enc, err = pubKey.MarshalHash(); err != nil {
+		return
+	}
+	addr = proto.AccountAddress(hash.THashH(enc))
+	return
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"testing"
+
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPermission(t *testing.T) {
+	Convey("test grant and revoke database permissions", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var (
+			dbID     = proto.DatabaseID("db")
+			userKey  *asymmetric.PublicKey
+			userAddr proto.AccountAddress
+			getPerm  = func() (perm pt.UserPermission, found bool) {
+				resp := new(bp.QuerySQLChainProfileResp)
+				err = requestBP(route.MCCQuerySQLChainProfile, &bp.QuerySQLChainProfileReq{DBID: dbID}, resp)
+				So(err, ShouldBeNil)
+				for _, u := range resp.Profile.Users {
+					if u.Address == userAddr {
+						return u.Permission, true
+					}
+				}
+				return
+			}
+		)
+		_, userKey, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		userAddr, err = accountAddress(userKey)
+		So(err, ShouldBeNil)
+
+		err = GrantPermission(dbID, userKey, PermissionRead)
+		So(err, ShouldBeNil)
+		perm, found := getPerm()
+		So(found, ShouldBeTrue)
+		So(perm, ShouldEqual, pt.Read)
+
+		err = GrantPermission(dbID, userKey, PermissionWrite)
+		So(err, ShouldBeNil)
+		perm, found = getPerm()
+		So(found, ShouldBeTrue)
+		So(perm, ShouldEqual, pt.ReadWrite)
+
+		err = RevokePermission(dbID, userKey)
+		So(err, ShouldBeNil)
+		_, found = getPerm()
+		So(found, ShouldBeFalse)
+
+		err = GrantPermission(dbID, userKey, Permission(pt.NumberOfUserPermission))
+		So(err, ShouldNotBeNil)
+		err = GrantPermission(dbID, nil, PermissionRead)
+		So(err, ShouldEqual, ErrInvalidParameter)
+
+		err = GrantAccountPermission(dbID, userAddr, PermissionWrite)
+		So(err, ShouldBeNil)
+		users, err := GetDatabaseUsers(dbID)
+		So(err, ShouldBeNil)
+		found = false
+		for _, u := range users {
+			if u.Address == userAddr {
+				found = true
+				So(u.Permission, ShouldEqual, pt.ReadWrite)
+			}
+		}
+		So(found, ShouldBeTrue)
+
+		err = RevokeAccountPermission(dbID, userAddr)
+		So(err, ShouldBeNil)
+		_, found = getPerm()
+		So(found, ShouldBeFalse)
+	})
+}
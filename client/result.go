@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+
+// execResult implements driver.Result using exec result computed by the leader.
+type execResult struct {
+	lastInsertID int64
+	affectedRows int64
+
+	// statements defines the result of each statement in request
+	statements []*execResult
+}
+
+func newExecResult(res *wt.Response) (r *execResult) {
+	r = &execResult{
+		lastInsertID: res.Header.LastInsertID,
+		affectedRows: res.Header.AffectedRows,
+	}
+
+	for _, row := range res.Payload.Rows {
+		if len(row.Values) != len(wt.WriteResultColumns) {
+			continue
+		}
+		r.statements = append(r.statements, &execResult{
+			lastInsertID: toInt64(row.Values[0]),
+			affectedRows: toInt64(row.Values[1]),
+		})
+	}
+
+	return
+}
+
+func toInt64(v interface{}) int64 {
+	switch i := v.(type) {
+	case int64:
+		return i
+	case uint64:
+		return int64(i)
+	case int:
+		return int64(i)
+	case int32:
+		return int64(i)
+	case uint32:
+		return int64(i)
+	case int8:
+		return int64(i)
+	case uint8:
+		return int64(i)
+	case int16:
+		return int64(i)
+	case uint16:
+		return int64(i)
+	default:
+		return 0
+	}
+}
+
+// LastInsertId implements driver.Result.LastInsertId method.
+func (r *execResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// RowsAffected implements driver.Result.RowsAffected method.
+func (r *execResult) RowsAffected() (int64, error) {
+	return r.affectedRows, nil
+}
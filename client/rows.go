@@ -19,15 +19,43 @@ package client
 import (
 	"database/sql/driver"
 	"io"
+	"reflect"
 	"strings"
+	"time"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
 	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
 )
 
+var (
+	scanTypeInt64     = reflect.TypeOf(int64(0))
+	scanTypeFloat64   = reflect.TypeOf(float64(0))
+	scanTypeString    = reflect.TypeOf("")
+	scanTypeBytes     = reflect.TypeOf([]byte{})
+	scanTypeBool      = reflect.TypeOf(false)
+	scanTypeTime      = reflect.TypeOf(time.Time{})
+	scanTypeInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
 type rows struct {
 	columns []string
 	types   []string
 	data    []wt.ResponseRow
+
+	// server side cursor holding remaining rows, zero cursorID means all rows are received
+	conn     *conn
+	nodeID   proto.NodeID
+	cursorID uint64
+
+	// fetched batches must be signed by the query responder in sequence of the cursor
+	requestHash hash.Hash
+	signee      *asymmetric.PublicKey
+	seq         uint64
+
+	// blobConn loads chunked blobs referenced in rows, nil if chunked blobs are not decoded
+	blobConn *conn
 }
 
 func newRows(res *wt.Response) *rows {
@@ -44,18 +72,44 @@ func (r *rows) Columns() []string {
 }
 
 // Close implements driver.Rows.Close method.
-func (r *rows) Close() error {
+func (r *rows) Close() (err error) {
 	r.data = nil
-	return nil
+
+	if r.cursorID != 0 {
+		err = r.conn.closeCursor(r.nodeID, r.cursorID)
+		r.cursorID = 0
+	}
+
+	return
 }
 
 // Next implements driver.Rows.Next method.
-func (r *rows) Next(dest []driver.Value) error {
-	if len(r.data) == 0 {
-		return io.EOF
+func (r *rows) Next(dest []driver.Value) (err error) {
+	for len(r.data) == 0 {
+		if r.cursorID == 0 {
+			return io.EOF
+		}
+
+		var res *wt.FetchResp
+		if res, err = r.conn.fetchRows(r.nodeID, r.cursorID); err != nil {
+			return
+		}
+		if err = r.verifyBatch(res); err != nil {
+			return
+		}
+		r.data = res.Rows
+		if res.Header.EOF {
+			// cursor is closed by server on eof
+			r.cursorID = 0
+		}
 	}
 
 	for i, d := range r.data[0].Values {
+		if b, ok := d.([]byte); ok && r.blobConn != nil && isBlobRef(b) {
+			if d, err = r.blobConn.loadBlob(b); err != nil {
+				return
+			}
+		}
 		dest[i] = d
 	}
 
@@ -65,7 +119,60 @@ func (r *rows) Next(dest []driver.Value) error {
 	return nil
 }
 
+// verifyBatch checks the fetched batch is signed by the node responding the query and follows the
+// previous batch of the cursor.
+func (r *rows) verifyBatch(res *wt.FetchResp) (err error) {
+	if err = res.Verify(); err != nil {
+		return
+	}
+
+	h := &res.Header
+	if !h.Signee.IsEqual(r.signee) || !h.RequestHash.IsEqual(&r.requestHash) ||
+		h.CursorID != r.cursorID || h.Seq != r.seq+1 {
+		return ErrInvalidFetchResponse
+	}
+
+	r.seq = h.Seq
+	return
+}
+
 // ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName.ColumnTypeDatabaseTypeName method.
 func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
 	return strings.ToUpper(r.types[index])
 }
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable.ColumnTypeNullable method.
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	// sqlite does not report nullability of result columns, any column may contain NULL
+	return true, true
+}
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType.ColumnTypeScanType method.
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if index >= len(r.types) {
+		return scanTypeInterface
+	}
+
+	// follows the column affinity rules of sqlite with extra date/time and boolean types
+	declType := strings.ToUpper(r.types[index])
+
+	switch {
+	case declType == "":
+		// expression columns have no declared type
+		return scanTypeInterface
+	case strings.Contains(declType, "INT"):
+		return scanTypeInt64
+	case strings.Contains(declType, "CHAR"), strings.Contains(declType, "CLOB"), strings.Contains(declType, "TEXT"):
+		return scanTypeString
+	case strings.Contains(declType, "BLOB"):
+		return scanTypeBytes
+	case strings.Contains(declType, "REAL"), strings.Contains(declType, "FLOA"), strings.Contains(declType, "DOUB"):
+		return scanTypeFloat64
+	case strings.Contains(declType, "DATE"), strings.Contains(declType, "TIME"):
+		return scanTypeTime
+	case strings.Contains(declType, "BOOL"):
+		return scanTypeBool
+	default:
+		return scanTypeInterface
+	}
+}
@@ -15,3 +15,120 @@
  */
 
 package client
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRowsColumnTypes(t *testing.T) {
+	Convey("test column types of rows", t, func() {
+		r := &rows{
+			columns: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"},
+			types:   []string{"integer", "varchar(255)", "blob", "double", "datetime", "boolean", "", "numeric", "bigint"},
+		}
+
+		expected := []reflect.Type{
+			reflect.TypeOf(int64(0)),
+			reflect.TypeOf(""),
+			reflect.TypeOf([]byte{}),
+			reflect.TypeOf(float64(0)),
+			reflect.TypeOf(time.Time{}),
+			reflect.TypeOf(false),
+			reflect.TypeOf((*interface{})(nil)).Elem(),
+			reflect.TypeOf((*interface{})(nil)).Elem(),
+			reflect.TypeOf(int64(0)),
+		}
+
+		for i := range r.columns {
+			So(r.ColumnTypeScanType(i), ShouldEqual, expected[i])
+			nullable, ok := r.ColumnTypeNullable(i)
+			So(nullable, ShouldBeTrue)
+			So(ok, ShouldBeTrue)
+		}
+
+		So(r.ColumnTypeDatabaseTypeName(1), ShouldEqual, "VARCHAR(255)")
+	})
+
+	Convey("test column types of query", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (id integer, name text)")
+		So(err, ShouldBeNil)
+
+		var rows *sql.Rows
+		rows, err = db.Query("select id, name from test")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+
+		var types []*sql.ColumnType
+		types, err = rows.ColumnTypes()
+		So(err, ShouldBeNil)
+		So(types, ShouldHaveLength, 2)
+		So(types[0].DatabaseTypeName(), ShouldEqual, "INTEGER")
+		So(types[0].ScanType(), ShouldEqual, reflect.TypeOf(int64(0)))
+		So(types[1].ScanType(), ShouldEqual, reflect.TypeOf(""))
+		nullable, ok := types[1].Nullable()
+		So(ok, ShouldBeTrue)
+		So(nullable, ShouldBeTrue)
+	})
+}
+
+func TestRowsVerifyBatch(t *testing.T) {
+	Convey("test verify fetched batches of cursor", t, func() {
+		privKey, pubKey, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		otherKey, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+
+		r := &rows{
+			cursorID:    1,
+			requestHash: hash.Hash{0x1},
+			signee:      pubKey,
+		}
+		buildBatch := func(seq uint64, signer *asymmetric.PrivateKey) *wt.FetchResp {
+			res := &wt.FetchResp{
+				Header: wt.SignedFetchRespHeader{
+					FetchRespHeader: wt.FetchRespHeader{
+						RequestHash: hash.Hash{0x1},
+						CursorID:    1,
+						Seq:         seq,
+					},
+					Signee: signer.PubKey(),
+				},
+				Rows: []wt.ResponseRow{{Values: []interface{}{int64(seq)}}},
+			}
+			So(res.Sign(signer), ShouldBeNil)
+			return res
+		}
+
+		So(r.verifyBatch(buildBatch(1, privKey)), ShouldBeNil)
+		So(r.verifyBatch(buildBatch(2, privKey)), ShouldBeNil)
+
+		// replayed batch
+		So(r.verifyBatch(buildBatch(2, privKey)), ShouldEqual, ErrInvalidFetchResponse)
+		// batch signed by other node
+		So(r.verifyBatch(buildBatch(3, otherKey)), ShouldEqual, ErrInvalidFetchResponse)
+		// tampered rows
+		res := buildBatch(3, privKey)
+		res.Rows[0].Values[0] = int64(4)
+		So(r.verifyBatch(res), ShouldEqual, wt.ErrHashVerification)
+		So(r.verifyBatch(buildBatch(3, privKey)), ShouldBeNil)
+	})
+}
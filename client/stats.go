@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+	hsp "github.com/CovenantSQL/HashStablePack/marshalhash"
+	"github.com/beorn7/perks/quantile"
+)
+
+// LatencyStats defines request latency statistics of a database peer.
+type LatencyStats struct {
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// DriverStats defines the statistics of all connections of the driver since process start.
+type DriverStats struct {
+	// Requests defines the count of requests sent to database peers, including retries.
+	Requests uint64
+	// Retries defines the count of requests resent after failures or leader changes.
+	Retries uint64
+	// LeaderSwitches defines the count of leader changes noticed on peers refreshing.
+	LeaderSwitches uint64
+	// BytesSent and BytesReceived define the estimated encoded size of queries and results.
+	BytesSent     uint64
+	BytesReceived uint64
+	// NodeLatencies defines latency statistics of successful requests to each peer.
+	NodeLatencies map[proto.NodeID]LatencyStats
+}
+
+type driverStats struct {
+	requests       uint64
+	retries        uint64
+	leaderSwitches uint64
+	bytesSent      uint64
+	bytesReceived  uint64
+
+	latencyLock sync.Mutex
+	latencies   map[proto.NodeID]*quantile.Stream
+}
+
+var (
+	stats = &driverStats{
+		latencies: make(map[proto.NodeID]*quantile.Stream),
+	}
+	latencyTargets = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+)
+
+// Stats returns a snapshot of the driver statistics.
+func Stats() (s DriverStats) {
+	s.Requests = atomic.LoadUint64(&stats.requests)
+	s.Retries = atomic.LoadUint64(&stats.retries)
+	s.LeaderSwitches = atomic.LoadUint64(&stats.leaderSwitches)
+	s.BytesSent = atomic.LoadUint64(&stats.bytesSent)
+	s.BytesReceived = atomic.LoadUint64(&stats.bytesReceived)
+
+	stats.latencyLock.Lock()
+	defer stats.latencyLock.Unlock()
+
+	s.NodeLatencies = make(map[proto.NodeID]LatencyStats, len(stats.latencies))
+	for nodeID, q := range stats.latencies {
+		s.NodeLatencies[nodeID] = LatencyStats{
+			Count: uint64(q.Count()),
+			P50:   time.Duration(q.Query(0.5)),
+			P90:   time.Duration(q.Query(0.9)),
+			P99:   time.Duration(q.Query(0.99)),
+		}
+	}
+
+	return
+}
+
+func (s *driverStats) recordRequest(retry bool) {
+	atomic.AddUint64(&s.requests, 1)
+	if retry {
+		atomic.AddUint64(&s.retries, 1)
+	}
+}
+
+func (s *driverStats) recordRetry() {
+	atomic.AddUint64(&s.retries, 1)
+}
+
+func (s *driverStats) recordLeaderSwitch() {
+	atomic.AddUint64(&s.leaderSwitches, 1)
+}
+
+func (s *driverStats) recordBytes(sent int, received int) {
+	atomic.AddUint64(&s.bytesSent, uint64(sent))
+	atomic.AddUint64(&s.bytesReceived, uint64(received))
+}
+
+func (s *driverStats) recordLatency(nodeID proto.NodeID, d time.Duration) {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+
+	q, ok := s.latencies[nodeID]
+	if !ok {
+		q = quantile.NewTargeted(latencyTargets)
+		s.latencies[nodeID] = q
+	}
+	q.Insert(float64(d))
+}
+
+// requestSize returns the estimated encoded size of query request.
+func requestSize(req *wt.Request) (size int) {
+	size = req.Header.Msgsize()
+	for _, q := range req.Payload.Queries {
+		size += hsp.StringPrefixSize + len(q.Pattern)
+		for _, a := range q.Args {
+			size += hsp.StringPrefixSize + len(a.Name) + hsp.GuessSize(a.Value)
+		}
+	}
+	return
+}
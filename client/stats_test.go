@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"database/sql"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStats(t *testing.T) {
+	Convey("test driver statistics", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		before := Stats()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		for i := 0; i < 10; i++ {
+			var count int
+			err = db.QueryRow("select count(1) from test").Scan(&count)
+			So(err, ShouldBeNil)
+		}
+
+		after := Stats()
+		So(after.Requests-before.Requests, ShouldBeGreaterThanOrEqualTo, 11)
+		So(after.BytesSent, ShouldBeGreaterThan, before.BytesSent)
+		So(after.BytesReceived, ShouldBeGreaterThan, before.BytesReceived)
+		So(after.NodeLatencies, ShouldNotBeEmpty)
+
+		for _, l := range after.NodeLatencies {
+			So(l.Count, ShouldBeGreaterThan, 0)
+			So(l.P50, ShouldBeGreaterThan, 0)
+			So(l.P99, ShouldBeGreaterThanOrEqualTo, l.P50)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	// SubscribePollInterval defines the interval of polling row changes from database peers.
+	SubscribePollInterval = time.Second
+	// SubscribeBufferSize defines the buffered events of a subscription channel.
+	SubscribeBufferSize = 1024
+)
+
+const (
+	// ChangeInsert defines a row inserted.
+	ChangeInsert = wt.ChangeInsert
+	// ChangeUpdate defines a row updated.
+	ChangeUpdate = wt.ChangeUpdate
+	// ChangeDelete defines a row deleted.
+	ChangeDelete = wt.ChangeDelete
+)
+
+// ChangeEvent defines a committed row change of subscribed database.
+type ChangeEvent struct {
+	Index uint64 // log index of the write query applied the change
+	Op    wt.ChangeOp
+	Table string
+	RowID int64 // rowid of the row, equals to the INTEGER PRIMARY KEY if any
+
+	// Lost reports that changes before the event are no longer retained by database peers and
+	// missed by the subscription, subscriber should reload the table, Op and RowID are not set.
+	Lost bool
+}
+
+// Subscribe returns a channel of row changes committed to table of database after subscribing,
+// changes of all tables are subscribed if table is empty. The channel is closed after cancel
+// is called.
+func Subscribe(dbID proto.DatabaseID, table string) (events <-chan ChangeEvent, cancel func(), err error) {
+	return SubscribeContext(context.Background(), dbID, table)
+}
+
+// SubscribeContext is like Subscribe but the subscription is also cancelled with ctx.
+func SubscribeContext(ctx context.Context, dbID proto.DatabaseID, table string) (
+	events <-chan ChangeEvent, cancel func(), err error) {
+	cfg := NewConfig()
+	cfg.DatabaseID = string(dbID)
+
+	var c *conn
+	if c, err = newConn(cfg); err != nil {
+		return
+	}
+
+	// changes are polled since current committed index
+	req := &wt.StatusReq{
+		DatabaseID: c.dbID,
+	}
+	res := new(wt.StatusResp)
+
+	if err = c.callNode(ctx, c.pickTarget(wt.ReadQuery, c.consistency), route.DBSStatus, req, res); err != nil {
+		c.Close()
+		return
+	}
+
+	ctx, cancel = context.WithCancel(ctx)
+	ch := make(chan ChangeEvent, SubscribeBufferSize)
+	go c.pollChanges(ctx, table, res.CommittedIndex, ch)
+
+	events = ch
+	return
+}
+
+// pollChanges polls row changes from database peers until ctx is done, the connection is closed on exit.
+func (c *conn) pollChanges(ctx context.Context, table string, since uint64, ch chan<- ChangeEvent) {
+	defer close(ch)
+	defer c.Close()
+
+	ticker := time.NewTicker(SubscribePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		req := &wt.ChangesReq{
+			DatabaseID: c.dbID,
+			Table:      table,
+			SinceIndex: since,
+		}
+		res := new(wt.ChangesResp)
+
+		if err := c.callNode(ctx, c.pickTarget(wt.ReadQuery, c.consistency), route.DBSChanges, req, res); err != nil {
+			c.log("poll changes failed ", err.Error())
+
+			if isLeaderChangeError(err) {
+				c.getPeers()
+			}
+			continue
+		}
+
+		if res.Truncated {
+			if !sendChange(ctx, ch, ChangeEvent{Index: res.LastIndex, Table: table, Lost: true}) {
+				return
+			}
+		}
+
+		for _, e := range res.Events {
+			if !sendChange(ctx, ch, ChangeEvent{Index: e.Index, Op: e.Op, Table: e.Table, RowID: e.RowID}) {
+				return
+			}
+		}
+
+		// peer lagging behind returns a smaller committed index
+		if res.LastIndex > since {
+			since = res.LastIndex
+		}
+	}
+}
+
+func sendChange(ctx context.Context, ch chan<- ChangeEvent, e ChangeEvent) bool {
+	select {
+	case ch <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
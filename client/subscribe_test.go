@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubscribe(t *testing.T) {
+	Convey("test row change subscription", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (id integer primary key, v text)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("create table other (id integer primary key)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into test values (1, 'a')")
+		So(err, ShouldBeNil)
+
+		// changes committed before subscribing are not reported
+		events, cancel, err := Subscribe(proto.DatabaseID("db"), "test")
+		So(err, ShouldBeNil)
+
+		_, err = db.Exec("insert into test values (2, 'b')")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("insert into other values (3)")
+		So(err, ShouldBeNil)
+		_, err = db.Exec("update test set v = 'c' where id = 1; delete from test where id = 2")
+		So(err, ShouldBeNil)
+
+		var received []ChangeEvent
+		timeout := time.After(10 * time.Second)
+		for len(received) < 3 {
+			select {
+			case e := <-events:
+				received = append(received, e)
+			case <-timeout:
+				So("events not received", ShouldBeEmpty)
+			}
+		}
+
+		So(received[0].Op, ShouldEqual, ChangeInsert)
+		So(received[0].RowID, ShouldEqual, 2)
+		So(received[0].Table, ShouldEqual, "test")
+		So(received[1].Op, ShouldEqual, ChangeUpdate)
+		So(received[1].RowID, ShouldEqual, 1)
+		So(received[2].Op, ShouldEqual, ChangeDelete)
+		So(received[2].RowID, ShouldEqual, 2)
+		So(received[1].Index, ShouldEqual, received[2].Index)
+		So(received[0].Index, ShouldBeLessThan, received[1].Index)
+
+		// channel is closed on cancel
+		cancel()
+		select {
+		case _, ok := <-events:
+			So(ok, ShouldBeFalse)
+		case <-time.After(5 * time.Second):
+			So("channel not closed", ShouldBeEmpty)
+		}
+
+		// subscription is cancelled with context
+		ctx, ctxCancel := context.WithCancel(context.Background())
+		events, cancel, err = SubscribeContext(ctx, proto.DatabaseID("db"), "")
+		So(err, ShouldBeNil)
+		defer cancel()
+		ctxCancel()
+		select {
+		case _, ok := <-events:
+			So(ok, ShouldBeFalse)
+		case <-time.After(5 * time.Second):
+			So("channel not closed", ShouldBeEmpty)
+		}
+
+		// subscribe non-existent database
+		_, _, err = Subscribe(proto.DatabaseID("db_not_exists"), "")
+		So(err, ShouldNotBeNil)
+	})
+}
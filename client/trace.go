@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"strings"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	// SpanQuery defines the operation name of read query span.
+	SpanQuery = "covenantsql.query"
+	// SpanExec defines the operation name of write query span.
+	SpanExec = "covenantsql.exec"
+	// SpanTx defines the operation name of transaction span.
+	SpanTx = "covenantsql.tx"
+
+	// SpanTagDatabaseID defines the span tag of database id.
+	SpanTagDatabaseID = "db.instance"
+	// SpanTagStatementDigest defines the span tag of digest of query statements.
+	SpanTagStatementDigest = "db.statement.digest"
+	// SpanTagNodeID defines the span tag of the remote miner node serving the query.
+	SpanTagNodeID = "peer.node"
+	// SpanTagCacheHit defines the span tag set if query result is served by client cache.
+	SpanTagCacheHit = "cache.hit"
+)
+
+// noopSpan is used if no StartSpan hook is set.
+type noopSpan struct{}
+
+func (noopSpan) SetTag(string, interface{}) {}
+func (noopSpan) Inject(map[string]string)   {}
+func (noopSpan) Finish(error)               {}
+
+func startSpan(ctx context.Context, operation string, dbID proto.DatabaseID) (span Span) {
+	h := getHooks()
+	if h.StartSpan == nil {
+		return noopSpan{}
+	}
+	if span = h.StartSpan(ctx, operation); span == nil {
+		return noopSpan{}
+	}
+	span.SetTag(SpanTagDatabaseID, string(dbID))
+	return
+}
+
+// statementDigest returns digest of query patterns, arguments are not included.
+func statementDigest(queries []wt.Query) string {
+	patterns := make([]string, len(queries))
+	for i, q := range queries {
+		patterns[i] = q.Pattern
+	}
+	return hash.THashH([]byte(strings.Join(patterns, ";"))).String()
+}
+
+// injectSpan writes span context to request envelope.
+func injectSpan(span Span, req *wt.Request) {
+	carrier := make(map[string]string)
+	span.Inject(carrier)
+	if len(carrier) > 0 {
+		req.SetTraceContext(carrier)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testSpan struct {
+	operation string
+	tags      map[string]interface{}
+	finished  bool
+	err       error
+}
+
+func (s *testSpan) SetTag(key string, value interface{}) {
+	s.tags[key] = value
+}
+
+func (s *testSpan) Inject(carrier map[string]string) {
+	carrier["trace-id"] = s.operation
+}
+
+func (s *testSpan) Finish(err error) {
+	s.finished = true
+	s.err = err
+}
+
+func TestTracingHook(t *testing.T) {
+	Convey("test tracing spans", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var lock sync.Mutex
+		var spans []*testSpan
+		SetHooks(&Hooks{
+			StartSpan: func(ctx context.Context, operation string) Span {
+				lock.Lock()
+				defer lock.Unlock()
+				s := &testSpan{
+					operation: operation,
+					tags:      make(map[string]interface{}),
+				}
+				spans = append(spans, s)
+				return s
+			},
+		})
+		defer SetHooks(nil)
+
+		var db *sql.DB
+		db, err = sql.Open("covenantsql", "covenantsql://db")
+		So(err, ShouldBeNil)
+		defer db.Close()
+
+		_, err = db.Exec("create table test (test int)")
+		So(err, ShouldBeNil)
+		var result int
+		err = db.QueryRow("select count(1) from test").Scan(&result)
+		So(err, ShouldBeNil)
+
+		var tx *sql.Tx
+		tx, err = db.Begin()
+		So(err, ShouldBeNil)
+		_, err = tx.Exec("insert into test values (1)")
+		So(err, ShouldBeNil)
+		So(tx.Commit(), ShouldBeNil)
+
+		_, err = db.Exec("THIS IS NOT A SQL!!!")
+		So(err, ShouldNotBeNil)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		So(spans, ShouldHaveLength, 5)
+		So(spans[0].operation, ShouldEqual, SpanExec)
+		So(spans[1].operation, ShouldEqual, SpanQuery)
+		So(spans[2].operation, ShouldEqual, SpanTx)
+		So(spans[3].operation, ShouldEqual, SpanExec)
+		So(spans[4].operation, ShouldEqual, SpanExec)
+
+		for _, s := range spans {
+			So(s.finished, ShouldBeTrue)
+			So(s.tags[SpanTagDatabaseID], ShouldEqual, "db")
+			So(s.tags[SpanTagStatementDigest], ShouldNotBeEmpty)
+		}
+
+		// queued query in transaction is sent by the transaction span
+		So(spans[0].tags, ShouldContainKey, SpanTagNodeID)
+		So(spans[1].tags, ShouldContainKey, SpanTagNodeID)
+		So(spans[2].tags, ShouldContainKey, SpanTagNodeID)
+		So(spans[3].tags, ShouldNotContainKey, SpanTagNodeID)
+		So(spans[2].err, ShouldBeNil)
+		So(spans[4].err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+)
+
+// MinerIncome defines the tokens distributed to a miner by billings of a database.
+type MinerIncome = bp.MinerIncome
+
+// DatabaseUsage defines the accumulated usage of a database billed by block producer.
+type DatabaseUsage = bp.DatabaseUsage
+
+// GetUsage returns the billing usage of database dbID, including the acked query counts, the
+// incomes distributed to miners and the remaining deposit of the database.
+func GetUsage(dbID proto.DatabaseID) (usage *DatabaseUsage, err error) {
+	req := &bp.QueryDatabaseUsageReq{DBID: dbID}
+	resp := new(bp.QueryDatabaseUsageResp)
+	if err = requestBP(route.MCCQueryDatabaseUsage, req, resp); err != nil {
+		return
+	}
+	usage = &resp.Usage
+	return
+}
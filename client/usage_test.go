@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetUsage(t *testing.T) {
+	Convey("test get database usage", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var (
+			miner proto.AccountAddress
+			usage *DatabaseUsage
+		)
+		miner, err = localAccountAddress()
+		So(err, ShouldBeNil)
+
+		_, err = GetUsage(proto.DatabaseID("db"))
+		So(err, ShouldNotBeNil)
+
+		stubMCC.Lock()
+		stubMCC.usages[proto.DatabaseID("db")] = &DatabaseUsage{
+			DatabaseID: proto.DatabaseID("db"),
+			Billings:   2,
+			ReadCount:  10,
+			WriteCount: 3,
+			GasAmount:  13,
+			Incomes:    []MinerIncome{{Address: miner, Fee: 13, Reward: 13}},
+			Deposit:    87,
+		}
+		stubMCC.Unlock()
+
+		usage, err = GetUsage(proto.DatabaseID("db"))
+		So(err, ShouldBeNil)
+		So(usage.ReadCount, ShouldEqual, 10)
+		So(usage.WriteCount, ShouldEqual, 3)
+		So(usage.Deposit, ShouldEqual, 87)
+		So(usage.Incomes, ShouldHaveLength, 1)
+		So(usage.Incomes[0].Address, ShouldEqual, miner)
+	})
+}
@@ -0,0 +1,474 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"time"
+
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/conf"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+)
+
+const (
+	// TxConfirmInterval defines the interval of checking whether a transaction is confirmed by
+	// block producer.
+	TxConfirmInterval = time.Second
+	// AccountEventsWait defines the time an account events request waits for new events.
+	AccountEventsWait = 20 * time.Second
+)
+
+// TxState defines the state of a block producer transaction.
+type TxState = pi.TransactionState
+
+const (
+	// TxStateNotFound defines the state of an unknown or rejected transaction.
+	TxStateNotFound = pi.TransactionStateNotFound
+	// TxStatePending defines the state of an accepted transaction which is not packed in any block.
+	TxStatePending = pi.TransactionStatePending
+	// TxStateConfirmed defines the state of a transaction packed in a produced block.
+	TxStateConfirmed = pi.TransactionStateConfirmed
+	// TxStateFailed defines the state of a recently submitted transaction which failed to apply.
+	TxStateFailed = pi.TransactionStateFailed
+)
+
+// AccountNonce defines the nonce of transactions sent by an account.
+type AccountNonce = pi.AccountNonce
+
+// TokenType defines the type of tokens held by accounts.
+type TokenType = pt.TokenType
+
+const (
+	// TokenStableCoin defines the stable coin paid for database usage.
+	TokenStableCoin = pt.StableCoin
+	// TokenCovenantCoin defines the covenant coin paid as fees.
+	TokenCovenantCoin = pt.CovenantCoin
+)
+
+// Balance defines the token balances of an account.
+type Balance struct {
+	StableCoin   uint64
+	CovenantCoin uint64
+}
+
+// FeeEstimate defines the fee rates of pending transactions, a fee rate is the fee in covenant
+// coins per kilobyte of serialized transaction.
+type FeeEstimate = bp.FeeEstimate
+
+// TxReceipt defines the receipt of a block producer transaction.
+type TxReceipt struct {
+	Hash  hash.Hash
+	State TxState
+	// Height is the height of block packing the transaction if it's confirmed.
+	Height uint32
+	// Reason is the error of the transaction if it failed.
+	Reason string
+}
+
+// TxSimulation defines the result of dry-running a block producer transaction.
+type TxSimulation struct {
+	// Fee is the fee in covenant coins charged to the sender once the transaction is applied.
+	Fee uint64
+	// Accounts are the would-be states of the accounts touched by the transaction.
+	Accounts []pt.Account
+	// Reason is the error of the transaction if it fails to apply.
+	Reason string
+}
+
+// AccountEvent defines an event touching an account, such as incoming transfer.
+type AccountEvent = bp.AccountEvent
+
+// AccountEventType defines the type of account events.
+type AccountEventType = bp.AccountEventType
+
+const (
+	// AccountEventTransferIn defines the event of tokens transferred to the account.
+	AccountEventTransferIn = bp.AccountEventTransferIn
+	// AccountEventDatabaseCreated defines the event of a database created for the account.
+	AccountEventDatabaseCreated = bp.AccountEventDatabaseCreated
+	// AccountEventPermissionChanged defines the event of database permission of the account
+	// changed.
+	AccountEventPermissionChanged = bp.AccountEventPermissionChanged
+)
+
+// GetBalance returns the token balances of the local account.
+func GetBalance() (balance Balance, err error) {
+	var addr proto.AccountAddress
+	if addr, err = localAccountAddress(); err != nil {
+		return
+	}
+	return GetAccountBalance(addr)
+}
+
+// GetAccountBalance returns the token balances of account addr, changes of transactions which are
+// not confirmed yet are included.
+func GetAccountBalance(addr proto.AccountAddress) (balance Balance, err error) {
+	req := &bp.QueryAccountBalanceReq{Addr: addr}
+	resp := new(bp.QueryAccountBalanceResp)
+	if err = requestBP(route.MCCQueryAccountBalance, req, resp); err != nil {
+		return
+	}
+	balance.StableCoin = resp.StableCoinBalance
+	balance.CovenantCoin = resp.CovenantCoinBalance
+	return
+}
+
+// GetVerifiedAccountBalance is like GetAccountBalance but returns the balances committed by the
+// head block, which are verified against the meta root of the returned block header. Compare the
+// header with the ones of other block producers to avoid trusting a single block producer.
+func GetVerifiedAccountBalance(addr proto.AccountAddress) (
+	balance Balance, header *pt.SignedHeader, err error,
+) {
+	req := &bp.QueryAccountProofReq{Addr: addr}
+	resp := new(bp.QueryAccountProofResp)
+	if err = requestBP(route.MCCQueryAccountProof, req, resp); err != nil {
+		return
+	}
+	if resp.Proof == nil || resp.Account.Address != addr {
+		err = pt.ErrStateProofVerification
+		return
+	}
+	if err = resp.Proof.VerifyAccount(&resp.Account); err != nil {
+		return
+	}
+	balance.StableCoin = resp.Account.StableCoinBalance
+	balance.CovenantCoin = resp.Account.CovenantCoinBalance
+	header = &resp.Proof.Header
+	return
+}
+
+// TransferTokens transfers amount of stable coins from the local account to account to, the hash
+// of the transfer transaction is returned once it is accepted by block producer. Use
+// WaitTxConfirmation to wait for the transaction to be confirmed.
+func TransferTokens(to proto.AccountAddress, amount uint64) (txHash hash.Hash, err error) {
+	return TransferTokensOfType(to, amount, TokenStableCoin)
+}
+
+// TransferTokensOfType is like TransferTokens but transfers tokens of type token.
+func TransferTokensOfType(to proto.AccountAddress, amount uint64, token TokenType) (txHash hash.Hash, err error) {
+	return TransferTokensWithFee(to, amount, token, 0)
+}
+
+// TransferTokensWithFee is like TransferTokensOfType but pays fee in covenant coins to block
+// producer, transactions with higher fee rates are packed earlier. Use GetFeeEstimate to get the
+// current fee rates.
+func TransferTokensWithFee(
+	to proto.AccountAddress, amount uint64, token TokenType, fee uint64) (txHash hash.Hash, err error,
+) {
+	var (
+		privateKey *asymmetric.PrivateKey
+		sender     proto.AccountAddress
+	)
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if sender, err = accountAddress(privateKey.PubKey()); err != nil {
+		return
+	}
+
+	var nonce AccountNonce
+	if nonce, err = localNonces.allocate(sender); err != nil {
+		return
+	}
+
+	var tx *pt.Transfer
+	if tx, err = newTransfer(privateKey, sender, to, amount, token, fee, nonce); err != nil {
+		localNonces.reset()
+		return
+	}
+	if err = requestBP(route.MCCTransfer, &bp.TransferReq{Tx: tx}, new(bp.TransferResp)); err != nil {
+		localNonces.reset()
+		return
+	}
+
+	txHash = tx.GetHash()
+	return
+}
+
+// SimulateTransfer is like TransferTokensWithFee but dry-runs the transfer with the next nonce
+// of the local account instead of submitting it, see SimulateTx.
+func SimulateTransfer(
+	to proto.AccountAddress, amount uint64, token TokenType, fee uint64) (sim *TxSimulation, err error,
+) {
+	var (
+		privateKey *asymmetric.PrivateKey
+		sender     proto.AccountAddress
+		nonce      AccountNonce
+		tx         *pt.Transfer
+	)
+	if privateKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if sender, err = accountAddress(privateKey.PubKey()); err != nil {
+		return
+	}
+	if nonce, err = GetNextNonce(); err != nil {
+		return
+	}
+	if tx, err = newTransfer(privateKey, sender, to, amount, token, fee, nonce); err != nil {
+		return
+	}
+	return SimulateTx(tx)
+}
+
+// newTransfer returns the signed transfer transaction.
+func newTransfer(
+	privateKey *asymmetric.PrivateKey, sender, to proto.AccountAddress,
+	amount uint64, token TokenType, fee uint64, nonce AccountNonce) (tx *pt.Transfer, err error,
+) {
+	tx = &pt.Transfer{
+		TransferHeader: pt.TransferHeader{
+			Sender:    sender,
+			Receiver:  to,
+			Nonce:     nonce,
+			Amount:    amount,
+			TokenType: token,
+			Fee:       fee,
+		},
+		ChainID: chainID(),
+	}
+	err = tx.Sign(privateKey)
+	return
+}
+
+// SimulateTx dry-runs the signed transaction tx against the current state of block producer
+// without committing it. If tx fails to apply, the error is ErrTxFailed and the Reason of the
+// simulation tells why.
+func SimulateTx(tx pi.Transaction) (sim *TxSimulation, err error) {
+	req := &bp.SimulateTxReq{Tx: tx}
+	resp := new(bp.SimulateTxResp)
+	if err = requestBP(route.MCCSimulateTx, req, resp); err != nil {
+		return
+	}
+	sim = &TxSimulation{
+		Fee:      resp.Fee,
+		Accounts: resp.Accounts,
+		Reason:   resp.Error,
+	}
+	if !resp.Ok {
+		err = ErrTxFailed
+	}
+	return
+}
+
+// GetFeeEstimate returns the fee rates required by pending transactions of block producer.
+func GetFeeEstimate() (estimate FeeEstimate, err error) {
+	req := new(bp.QueryFeeEstimateReq)
+	resp := new(bp.QueryFeeEstimateResp)
+	if err = requestBP(route.MCCQueryFeeEstimate, req, resp); err != nil {
+		return
+	}
+	estimate = resp.Estimate
+	return
+}
+
+// GetTxReceipt returns the receipt of block producer transaction with hash txHash.
+func GetTxReceipt(txHash hash.Hash) (receipt *TxReceipt, err error) {
+	req := &bp.QueryTxStateReq{Hash: txHash}
+	resp := new(bp.QueryTxStateResp)
+	if err = requestBP(route.MCCQueryTxState, req, resp); err != nil {
+		return
+	}
+	receipt = &TxReceipt{
+		Hash:   txHash,
+		State:  resp.State,
+		Height: resp.Height,
+		Reason: resp.Reason,
+	}
+	return
+}
+
+// GetTxReceipts is like GetTxReceipt but returns the receipts of transactions with hashes
+// txHashes in order with a single request.
+func GetTxReceipts(txHashes []hash.Hash) (receipts []*TxReceipt, err error) {
+	req := &bp.QueryTxReceiptsReq{Hashes: txHashes}
+	resp := new(bp.QueryTxReceiptsResp)
+	if err = requestBP(route.MCCQueryTxReceipts, req, resp); err != nil {
+		return
+	}
+	if len(resp.Receipts) != len(txHashes) {
+		err = ErrInvalidTxReceipts
+		return
+	}
+	receipts = make([]*TxReceipt, len(resp.Receipts))
+	for i, r := range resp.Receipts {
+		receipts[i] = &TxReceipt{
+			Hash:   r.Hash,
+			State:  r.State,
+			Height: r.Height,
+			Reason: r.Reason,
+		}
+	}
+	return
+}
+
+// WaitTxConfirmation polls the state of transaction with hash txHash until it is confirmed, the
+// transaction is rejected or ctx is done. The receipt of a failed transaction is returned with
+// ErrTxFailed, its Reason tells why.
+func WaitTxConfirmation(ctx context.Context, txHash hash.Hash) (receipt *TxReceipt, err error) {
+	for {
+		if receipt, err = GetTxReceipt(txHash); err != nil {
+			return
+		}
+		switch receipt.State {
+		case TxStateConfirmed:
+			return
+		case TxStateNotFound:
+			err = ErrTxNotFound
+			return
+		case TxStateFailed:
+			err = ErrTxFailed
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(TxConfirmInterval):
+		}
+	}
+}
+
+// GetAccountEvents returns the events of account addr after sequence since, the request waits
+// at most wait for new events if there is none yet.
+func GetAccountEvents(ctx context.Context, addr proto.AccountAddress, since uint64, wait time.Duration) (
+	events []*AccountEvent, err error,
+) {
+	// wait shorter than the request timeout of block producer endpoints
+	if _, timeout := bpEndpoints.candidates(); timeout > 0 && wait >= timeout {
+		wait = timeout / 2
+	}
+	req := &bp.QueryAccountEventsReq{Addr: addr, Since: since, Wait: wait}
+	resp := new(bp.QueryAccountEventsResp)
+	if err = requestBPWithContext(ctx, route.MCCQueryAccountEvents, req, resp); err != nil {
+		return
+	}
+	events = resp.Events
+	return
+}
+
+// SubscribeAccountEvents calls handler with the events of account addr after sequence since in
+// order, until ctx is done or any request or handler fails. Resubscribe with the Seq of the last
+// handled event to continue.
+func SubscribeAccountEvents(
+	ctx context.Context, addr proto.AccountAddress, since uint64, handler func(*AccountEvent) error,
+) (err error) {
+	for {
+		var events []*AccountEvent
+		if events, err = GetAccountEvents(ctx, addr, since, AccountEventsWait); err != nil {
+			return
+		}
+		for _, e := range events {
+			if err = handler(e); err != nil {
+				return
+			}
+			since = e.Seq
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+	}
+}
+
+// Checkpoint defines a block finalized by a quorum of block producers.
+type Checkpoint = pt.Checkpoint
+
+// GetCheckpoint returns the checkpoint finalized at height, or the latest one if height is 0. The
+// checkpoint is verified to be signed by a quorum of the block producers in the configuration.
+func GetCheckpoint(height uint32) (cp *Checkpoint, err error) {
+	req := &bp.QueryCheckpointReq{Height: height}
+	resp := new(bp.QueryCheckpointResp)
+	if err = requestBP(route.MCCQueryCheckpoint, req, resp); err != nil {
+		return
+	}
+	if resp.Checkpoint == nil || (height != 0 && resp.Checkpoint.Height != height) {
+		err = ErrInvalidCheckpoint
+		return
+	}
+	signees := bpPublicKeys()
+	if err = resp.Checkpoint.Verify(signees, pt.CheckpointQuorum(len(signees))); err != nil {
+		return
+	}
+	cp = resp.Checkpoint
+	return
+}
+
+// bpPublicKeys returns the public keys of the known block producers in the configuration.
+func bpPublicKeys() (keys []*asymmetric.PublicKey) {
+	if conf.GConf == nil {
+		return
+	}
+	add := func(k *asymmetric.PublicKey) {
+		if k == nil {
+			return
+		}
+		for _, v := range keys {
+			if v.IsEqual(k) {
+				return
+			}
+		}
+		keys = append(keys, k)
+	}
+	if conf.GConf.BP != nil {
+		add(conf.GConf.BP.PublicKey)
+	}
+	for _, n := range conf.GConf.KnownNodes {
+		if n.Role == proto.Leader || n.Role == proto.Follower {
+			add(n.PublicKey)
+		}
+	}
+	return
+}
+
+// GetNextNonce returns the nonce of next transaction sent by the local account.
+func GetNextNonce() (nonce AccountNonce, err error) {
+	var addr proto.AccountAddress
+	if addr, err = localAccountAddress(); err != nil {
+		return
+	}
+	req := &bp.NextAccountNonceReq{Addr: addr}
+	resp := new(bp.NextAccountNonceResp)
+	if err = requestBP(route.MCCNextAccountNonce, req, resp); err != nil {
+		return
+	}
+	nonce = resp.Nonce
+	return
+}
+
+func localAccountAddress() (addr proto.AccountAddress, err error) {
+	var pubKey *asymmetric.PublicKey
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	return accountAddress(pubKey)
+}
+
+// chainID returns the chain id which transactions are signed for.
+func chainID() pi.ChainID {
+	if conf.GConf == nil {
+		return ""
+	}
+	return pi.ChainID(conf.GConf.ChainID)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWallet(t *testing.T) {
+	Convey("test wallet operations", t, func() {
+		var stopTestService func()
+		var err error
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var (
+			sender, receiver proto.AccountAddress
+			receiverKey      *asymmetric.PublicKey
+			balance          Balance
+			txHash           hash.Hash
+			receipt          *TxReceipt
+		)
+		sender, err = localAccountAddress()
+		So(err, ShouldBeNil)
+		_, receiverKey, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		receiver, err = accountAddress(receiverKey)
+		So(err, ShouldBeNil)
+
+		_, err = GetBalance()
+		So(err, ShouldNotBeNil)
+
+		stubMCC.Lock()
+		stubMCC.balances[sender] = 100
+		stubMCC.Unlock()
+
+		balance, err = GetBalance()
+		So(err, ShouldBeNil)
+		So(balance.StableCoin, ShouldEqual, 100)
+
+		txHash, err = TransferTokens(receiver, 30)
+		So(err, ShouldBeNil)
+		receipt, err = GetTxReceipt(txHash)
+		So(err, ShouldBeNil)
+		So(receipt.State, ShouldEqual, TxStatePending)
+		receipt, err = WaitTxConfirmation(context.Background(), txHash)
+		So(err, ShouldBeNil)
+		So(receipt.State, ShouldEqual, TxStateConfirmed)
+		So(receipt.Height, ShouldEqual, 1)
+		receipts, err := GetTxReceipts([]hash.Hash{txHash, {}})
+		So(err, ShouldBeNil)
+		So(receipts, ShouldHaveLength, 2)
+		So(receipts[0].State, ShouldEqual, TxStateConfirmed)
+		So(receipts[0].Height, ShouldEqual, 1)
+		So(receipts[1].State, ShouldEqual, TxStateNotFound)
+
+		balance, err = GetBalance()
+		So(err, ShouldBeNil)
+		So(balance.StableCoin, ShouldEqual, 70)
+		balance, err = GetAccountBalance(receiver)
+		So(err, ShouldBeNil)
+		So(balance.StableCoin, ShouldEqual, 30)
+		balance, _, err = GetVerifiedAccountBalance(receiver)
+		So(err, ShouldBeNil)
+		So(balance.StableCoin, ShouldEqual, 30)
+
+		events, err := GetAccountEvents(context.Background(), receiver, 0, 0)
+		So(err, ShouldBeNil)
+		So(events, ShouldHaveLength, 1)
+		So(events[0].Type, ShouldEqual, AccountEventTransferIn)
+		So(events[0].Counterparty, ShouldEqual, sender)
+		So(events[0].Amount, ShouldEqual, 30)
+		errStop := errors.New("stop")
+		err = SubscribeAccountEvents(context.Background(), receiver, 0, func(e *AccountEvent) error {
+			So(e.Tx, ShouldEqual, txHash)
+			return errStop
+		})
+		So(err, ShouldEqual, errStop)
+
+		_, err = TransferTokens(receiver, 1000)
+		So(err, ShouldNotBeNil)
+		_, err = TransferTokensOfType(receiver, 1, TokenType(100))
+		So(err, ShouldNotBeNil)
+
+		nonce, err := GetNextNonce()
+		So(err, ShouldBeNil)
+		So(nonce, ShouldEqual, 1)
+
+		estimate, err := GetFeeEstimate()
+		So(err, ShouldBeNil)
+		So(estimate.Pending, ShouldEqual, 1)
+		So(estimate.MinFeeRate, ShouldEqual, 1)
+		sim, err := SimulateTransfer(receiver, 10, TokenStableCoin, 1)
+		So(err, ShouldBeNil)
+		So(sim.Fee, ShouldEqual, 1)
+		So(sim.Accounts, ShouldHaveLength, 2)
+		So(sim.Accounts[1].Address, ShouldResemble, receiver)
+		sim, err = SimulateTransfer(receiver, 1<<40, TokenStableCoin, 1)
+		So(err, ShouldEqual, ErrTxFailed)
+		So(sim.Reason, ShouldEqual, bp.ErrInsufficientBalance.Error())
+		nonce, err = GetNextNonce()
+		So(err, ShouldBeNil)
+		So(nonce, ShouldEqual, 1)
+		txHash, err = TransferTokensWithFee(receiver, 10, TokenStableCoin, 1)
+		So(err, ShouldBeNil)
+		So(txHash, ShouldNotResemble, hash.Hash{})
+
+		checkpoint, err := GetCheckpoint(0)
+		So(err, ShouldBeNil)
+		So(checkpoint.Height, ShouldEqual, 16)
+		checkpoint, err = GetCheckpoint(32)
+		So(err, ShouldBeNil)
+		So(checkpoint.Height, ShouldEqual, 32)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		_, err = WaitTxConfirmation(ctx, hash.Hash{})
+		So(err, ShouldEqual, ErrTxNotFound)
+
+		failedHash := hash.Hash{0x1}
+		stubMCC.Lock()
+		stubMCC.txStates[failedHash] = TxStateFailed
+		stubMCC.Unlock()
+		receipt, err = WaitTxConfirmation(ctx, failedHash)
+		So(err, ShouldEqual, ErrTxFailed)
+		So(receipt.Reason, ShouldNotBeEmpty)
+	})
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/conf"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hd"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	mine "github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	accountDifficulty  int
+	accountForce       bool
+	accountImportKey   string
+	accountKeyPassword string
+	accountMnemonic    bool
+	accountPassphrase  string
+	accountIndex       uint
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name: "account",
+		usage: "account [-difficulty N] [-force] [-key FILE [-key-password PASSWORD]] " +
+			"[-mnemonic] [-passphrase PASSPHRASE] [-index N] new|show|import|recover",
+		desc: "generate, show, import or recover from mnemonic the account key pair and node id of config",
+		setup: func(fs *flag.FlagSet) {
+			fs.IntVar(&accountDifficulty, "difficulty", 0,
+				"difficulty of mined node id (default MinNodeIDDifficulty of config)")
+			fs.BoolVar(&accountForce, "force", false, "overwrite the existing private key")
+			fs.StringVar(&accountImportKey, "key", "", "private key file to import")
+			fs.StringVar(&accountKeyPassword, "key-password", "",
+				"master key password of the imported private key (default -password)")
+			fs.BoolVar(&accountMnemonic, "mnemonic", false,
+				"derive the new key from a generated mnemonic seed phrase, which recovers the key")
+			fs.StringVar(&accountPassphrase, "passphrase", "", "optional passphrase of mnemonic")
+			fs.UintVar(&accountIndex, "index", 0, "account index of key derived from mnemonic")
+		},
+		run:    runAccount,
+		noInit: true,
+	})
+}
+
+func runAccount(args []string) (err error) {
+	if len(args) != 1 {
+		return flag.ErrHelp
+	}
+
+	// keys are written to the profile in use, so the config without profile applied is kept
+	var raw, cfg *conf.Config
+	if raw, err = conf.LoadConfig(configFile); err != nil {
+		return
+	}
+	if cfg, err = raw.WithProfile(profile); err != nil {
+		return
+	}
+	keyFile := filepath.Join(cfg.WorkingRoot, cfg.PrivateKeyFile)
+
+	switch args[0] {
+	case "new":
+		var privateKey *asymmetric.PrivateKey
+		if accountMnemonic {
+			var mnemonic string
+			if mnemonic, err = kms.NewMnemonic(); err != nil {
+				return
+			}
+			if privateKey, err = kms.DeriveAccountKey(
+				mnemonic, accountPassphrase, uint32(accountIndex)); err != nil {
+				return
+			}
+			fmt.Printf("Mnemonic:    %v\n", mnemonic)
+			fmt.Println("Write down the mnemonic and keep it safe, it recovers all your keys by account recover.")
+		} else if privateKey, _, err = asymmetric.GenSecp256k1KeyPair(); err != nil {
+			return
+		}
+		return setupAccount(raw, cfg, keyFile, privateKey)
+	case "recover":
+		var mnemonic string
+		if err = newWizard(os.Stdin, os.Stdout).ask("Mnemonic", "", func(s string) error {
+			mnemonic = s
+			_, err := hd.MnemonicToEntropy(s)
+			return err
+		}); err != nil {
+			return
+		}
+		var privateKey *asymmetric.PrivateKey
+		if privateKey, err = kms.DeriveAccountKey(
+			mnemonic, accountPassphrase, uint32(accountIndex)); err != nil {
+			return
+		}
+		return setupAccount(raw, cfg, keyFile, privateKey)
+	case "import":
+		if accountImportKey == "" {
+			return flag.ErrHelp
+		}
+		keyPassword := accountKeyPassword
+		if keyPassword == "" {
+			keyPassword = password
+		}
+		var privateKey *asymmetric.PrivateKey
+		if privateKey, err = kms.LoadPrivateKey(accountImportKey, []byte(keyPassword)); err != nil {
+			return
+		}
+		return setupAccount(raw, cfg, keyFile, privateKey)
+	case "show":
+		return showAccount(cfg, keyFile)
+	default:
+		return flag.ErrHelp
+	}
+}
+
+// setupAccount saves the private key to key file of config, mines node id of the key and
+// registers the node id to config.
+func setupAccount(raw, cfg *conf.Config, keyFile string, privateKey *asymmetric.PrivateKey) (err error) {
+	if _, err = os.Stat(keyFile); err == nil {
+		if !accountForce {
+			return fmt.Errorf("private key %v already exists, use -force to overwrite", keyFile)
+		}
+		// private key is saved as read-only
+		if err = os.Remove(keyFile); err != nil {
+			return
+		}
+	} else if !os.IsNotExist(err) {
+		return
+	}
+
+	difficulty := accountDifficulty
+	if difficulty <= 0 {
+		difficulty = cfg.MinNodeIDDifficulty
+	}
+	log.Infof("mining node id with difficulty %d", difficulty)
+	publicKey := privateKey.PubKey()
+	nonce := mineNodeID(publicKey, difficulty)
+
+	node := &proto.Node{
+		ID:        proto.NodeID(nonce.Hash.String()),
+		Role:      proto.Client,
+		PublicKey: publicKey,
+		Nonce:     nonce.Nonce,
+	}
+
+	if err = os.MkdirAll(filepath.Dir(keyFile), 0755); err != nil {
+		return
+	}
+	if err = kms.SavePrivateKey(keyFile, privateKey, []byte(password)); err != nil {
+		return
+	}
+	if err = updateAccountConfig(configFile, raw, cfg, node); err != nil {
+		return
+	}
+
+	log.Infof("private key saved to %v", keyFile)
+	return printAccount(node, nonce.Difficulty)
+}
+
+func showAccount(cfg *conf.Config, keyFile string) (err error) {
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = kms.LoadPrivateKey(keyFile, []byte(password)); err != nil {
+		return
+	}
+	node := &proto.Node{ID: cfg.ThisNodeID, PublicKey: privateKey.PubKey()}
+	for _, n := range cfg.KnownNodes {
+		if n.ID == cfg.ThisNodeID {
+			node.Nonce = n.Nonce
+			break
+		}
+	}
+
+	rawID := node.ID.ToRawNodeID()
+	if rawID == nil || !kms.IsIDPubNonceValid(rawID, &node.Nonce, node.PublicKey) {
+		log.Warningf("node id %v does not match the private key, run account new or import again",
+			node.ID)
+	}
+	return printAccount(node, node.ID.Difficulty())
+}
+
+func printAccount(node *proto.Node, difficulty int) (err error) {
+	var enc []byte
+	if enc, err = node.PublicKey.MarshalHash(); err != nil {
+		return
+	}
+	fmt.Printf("Node ID:     %v\n", node.ID)
+	fmt.Printf("Public key:  %v\n", hex.EncodeToString(node.PublicKey.Serialize()))
+	fmt.Printf("Nonce:       %v\n", node.Nonce)
+	fmt.Printf("Difficulty:  %v\n", difficulty)
+	addr := hash.THashH(enc)
+	fmt.Printf("Address:     %v\n", addr.String())
+	fmt.Printf("Checksummed: %v\n", utils.Addr2Str(proto.AccountAddress(addr), utils.MainNet))
+	return
+}
+
+// mineNodeID computes nonce of public key which makes node id reach the difficulty, the
+// computation is split to all cpus.
+func mineNodeID(publicKey *asymmetric.PublicKey, difficulty int) (nonce mine.NonceInfo) {
+	cpuCount := runtime.NumCPU()
+	quit := make(chan struct{})
+	// buffered for the stopped miners to report their best nonce without blocking
+	nonceCh := make(chan mine.NonceInfo, cpuCount)
+
+	rand.Seed(time.Now().UnixNano())
+	step := math.MaxUint64 / uint64(cpuCount)
+	for i := 0; i < cpuCount; i++ {
+		go func(i int) {
+			miner := mine.NewCPUMiner(quit)
+			block := mine.MiningBlock{
+				Data:      publicKey.Serialize(),
+				NonceChan: nonceCh,
+			}
+			start := mine.Uint256{D: step*uint64(i) + uint64(rand.Uint32())}
+			miner.ComputeBlockNonce(block, start, difficulty)
+		}(i)
+	}
+
+	nonce = <-nonceCh
+	close(quit)
+	return
+}
+
+// updateAccountConfig sets ThisNodeID of config file to the node and replaces the known node
+// entry of the previous node id, the profile in use is updated if any.
+func updateAccountConfig(path string, raw, cfg *conf.Config, node *proto.Node) (err error) {
+	nodes := make([]proto.Node, 0, len(cfg.KnownNodes)+1)
+	for _, n := range cfg.KnownNodes {
+		if n.ID != cfg.ThisNodeID && n.ID != node.ID {
+			nodes = append(nodes, n)
+		}
+	}
+	nodes = append(nodes, *node)
+
+	if p := raw.Profiles[cfg.Profile]; p != nil {
+		p.ThisNodeID, p.KnownNodes = node.ID, nodes
+	} else {
+		raw.ThisNodeID, raw.KnownNodes = node.ID, nodes
+	}
+	return writeConfig(path, raw)
+}
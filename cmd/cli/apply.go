@@ -0,0 +1,537 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// applyMigrationsTable records migrations already applied by the declarative spec.
+	applyMigrationsTable = "__cql_migrations"
+)
+
+var (
+	identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// applySpec defines the declarative database spec used by -apply.
+//
+// Example:
+//
+//	database:
+//	  id: ""          # empty id creates a new database with following requirements
+//	  node: 2
+//	schema:
+//	  - name: users
+//	    sql: CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)
+//	migrations:
+//	  - id: "0001_add_email"
+//	    sql:
+//	      - ALTER TABLE users ADD COLUMN email TEXT
+//	permissions:
+//	  - user: ACCOUNT_ADDRESS  # hex or checksummed account address
+//	    permission: write
+//
+// Tables of schema are created if they don't exist, and apply fails if a table definition
+// differs from spec after the pending migrations are applied, so changes of table definitions
+// should be made by migrations along with the schema. Permissions of listed users are granted or
+// updated, users not listed are kept untouched.
+type applySpec struct {
+	Database struct {
+		ID            string `yaml:"id"`
+		Node          uint16 `yaml:"node"`
+		Space         uint64 `yaml:"space"`
+		Memory        uint64 `yaml:"memory"`
+		LoadAvgPerCPU uint64 `yaml:"load_avg_per_cpu"`
+		EncryptionKey string `yaml:"encryption_key"`
+	} `yaml:"database"`
+	Schema      []applyTable      `yaml:"schema"`
+	Migrations  []applyMigration  `yaml:"migrations"`
+	Permissions []applyPermission `yaml:"permissions"`
+
+	// following sections are not supported by block producer and miners yet,
+	// they are parsed to give a clear error instead of being silently ignored.
+	Jobs     []interface{} `yaml:"jobs"`
+	Webhooks []interface{} `yaml:"webhooks"`
+}
+
+type applyTable struct {
+	Name string `yaml:"name"`
+	SQL  string `yaml:"sql"`
+}
+
+type applyMigration struct {
+	ID  string   `yaml:"id"`
+	SQL []string `yaml:"sql"`
+}
+
+type applyPermission struct {
+	User       string `yaml:"user"`
+	Permission string `yaml:"permission"`
+}
+
+// applyGrant defines a permission granted to or updated for a user.
+type applyGrant struct {
+	name string
+	user proto.AccountAddress
+	perm client.Permission
+}
+
+func loadApplySpec(fileName string) (spec *applySpec, err error) {
+	var content []byte
+	if content, err = ioutil.ReadFile(fileName); err != nil {
+		return
+	}
+
+	spec = new(applySpec)
+	if err = yaml.UnmarshalStrict(content, spec); err != nil {
+		err = fmt.Errorf("parse spec failed: %v", err)
+		return
+	}
+
+	err = spec.validate()
+	return
+}
+
+func (s *applySpec) validate() (err error) {
+	var unsupported []string
+	if len(s.Jobs) > 0 {
+		unsupported = append(unsupported, "jobs")
+	}
+	if len(s.Webhooks) > 0 {
+		unsupported = append(unsupported, "webhooks")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("spec sections not supported yet: %s", strings.Join(unsupported, ", "))
+	}
+
+	if s.Database.ID == "" && s.Database.Node == 0 {
+		return errors.New("database id or node count is required")
+	}
+
+	tables := make(map[string]bool)
+	for _, t := range s.Schema {
+		if !identifierRegex.MatchString(t.Name) {
+			return fmt.Errorf("invalid table name: %#v", t.Name)
+		}
+		if tables[strings.ToLower(t.Name)] {
+			return fmt.Errorf("duplicate table: %s", t.Name)
+		}
+		if strings.TrimSpace(t.SQL) == "" {
+			return fmt.Errorf("empty sql of table: %s", t.Name)
+		}
+		tables[strings.ToLower(t.Name)] = true
+	}
+
+	migrations := make(map[string]bool)
+	for _, m := range s.Migrations {
+		if m.ID == "" {
+			return errors.New("empty migration id")
+		}
+		if migrations[m.ID] {
+			return fmt.Errorf("duplicate migration: %s", m.ID)
+		}
+		if len(m.SQL) == 0 {
+			return fmt.Errorf("empty sql of migration: %s", m.ID)
+		}
+		migrations[m.ID] = true
+	}
+
+	users := make(map[proto.AccountAddress]bool)
+	for _, p := range s.Permissions {
+		var user proto.AccountAddress
+		if user, err = parseAccountAddress(p.User); err != nil {
+			return fmt.Errorf("invalid permission user %#v: %v", p.User, err)
+		}
+		if _, err = parsePermission(p.Permission); err != nil {
+			return fmt.Errorf("invalid permission of user %s: %v", p.User, err)
+		}
+		if users[user] {
+			return fmt.Errorf("duplicate permission user: %s", p.User)
+		}
+		users[user] = true
+	}
+
+	return
+}
+
+// applyPlan defines the actions converging live database to spec.
+type applyPlan struct {
+	createDB   bool
+	tables     []applyTable
+	migrations []applyMigration
+	grants     []applyGrant
+
+	// drifted tables exist with different definitions, they should be altered by migrations
+	drifted []string
+	// unmanaged tables exist in database but not in spec, they are kept untouched
+	unmanaged []string
+	// unmanagedUsers have permissions on database but are not in spec, they are kept untouched
+	unmanagedUsers []string
+}
+
+func (p *applyPlan) empty() bool {
+	return !p.createDB && len(p.tables) == 0 && len(p.migrations) == 0 && len(p.grants) == 0
+}
+
+// checkDrift returns error if some tables differ from spec and no pending migrations could
+// alter them.
+func (p *applyPlan) checkDrift() error {
+	if len(p.drifted) == 0 || len(p.migrations) > 0 {
+		return nil
+	}
+	return fmt.Errorf("tables differ from spec: %s, alter them by migrations and update the schema",
+		strings.Join(p.drifted, ", "))
+}
+
+func (p *applyPlan) print() {
+	if p.createDB {
+		log.Info("+ create database")
+	}
+	for _, t := range p.tables {
+		log.Infof("+ create table %s", t.Name)
+	}
+	for _, m := range p.migrations {
+		log.Infof("+ apply migration %s", m.ID)
+	}
+	for _, g := range p.grants {
+		log.Infof("+ grant %s to %s", permissionString(pt.UserPermission(g.perm)), g.name)
+	}
+	for _, name := range p.drifted {
+		log.Warningf("! table %s differs from spec, use migration to alter it", name)
+	}
+	for _, name := range p.unmanaged {
+		log.Warningf("? table %s is not managed by spec", name)
+	}
+	for _, name := range p.unmanagedUsers {
+		log.Warningf("? user %s is not managed by spec", name)
+	}
+	if p.empty() {
+		log.Info("database is up to date")
+	}
+}
+
+func normalizeSQL(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimRight(s, "; \t\r\n")
+	return whitespaceRegex.ReplaceAllString(s, " ")
+}
+
+// diffSchema compares spec tables with live table definitions keyed by lower cased table name.
+func diffSchema(spec []applyTable, live map[string]string) (create []applyTable, drifted []string, unmanaged []string) {
+	managed := make(map[string]bool)
+
+	for _, t := range spec {
+		name := strings.ToLower(t.Name)
+		managed[name] = true
+
+		liveSQL, exists := live[name]
+		if !exists {
+			create = append(create, t)
+		} else if normalizeSQL(liveSQL) != normalizeSQL(t.SQL) {
+			drifted = append(drifted, t.Name)
+		}
+	}
+
+	for name := range live {
+		if !managed[name] && !strings.HasPrefix(name, "sqlite_") && !strings.HasPrefix(name, "__") {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+	sort.Strings(unmanaged)
+
+	return
+}
+
+func diffMigrations(spec []applyMigration, applied map[string]bool) (pending []applyMigration) {
+	for _, m := range spec {
+		if !applied[m.ID] {
+			pending = append(pending, m)
+		}
+	}
+	return
+}
+
+// diffPermissions compares spec permissions with live database users, users are granted if they
+// don't have the permission of spec.
+func diffPermissions(spec []applyPermission, live []*client.DatabaseUser) (
+	grants []applyGrant, unmanaged []string, err error,
+) {
+	current := make(map[proto.AccountAddress]pt.UserPermission)
+	for _, u := range live {
+		current[u.Address] = u.Permission
+	}
+
+	managed := make(map[proto.AccountAddress]bool)
+	for _, p := range spec {
+		var g = applyGrant{name: p.User}
+		if g.user, err = parseAccountAddress(p.User); err != nil {
+			return
+		}
+		if g.perm, err = parsePermission(p.Permission); err != nil {
+			return
+		}
+		managed[g.user] = true
+		if perm, exists := current[g.user]; !exists || perm != pt.UserPermission(g.perm) {
+			grants = append(grants, g)
+		}
+	}
+
+	for _, u := range live {
+		if !managed[u.Address] {
+			unmanaged = append(unmanaged, hash.Hash(u.Address).String())
+		}
+	}
+	sort.Strings(unmanaged)
+
+	return
+}
+
+func loadLiveTables(db *sql.DB) (live map[string]string, err error) {
+	var rows *sql.Rows
+	if rows, err = db.Query(`SELECT name, sql FROM sqlite_master WHERE type = "table"`); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	live = make(map[string]string)
+	for rows.Next() {
+		var name string
+		var tableSQL sql.NullString
+		if err = rows.Scan(&name, &tableSQL); err != nil {
+			return
+		}
+		live[strings.ToLower(name)] = tableSQL.String
+	}
+
+	err = rows.Err()
+	return
+}
+
+func loadAppliedMigrations(db *sql.DB, live map[string]string) (applied map[string]bool, err error) {
+	applied = make(map[string]bool)
+
+	if _, exists := live[applyMigrationsTable]; !exists {
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = db.Query(fmt.Sprintf(`SELECT id FROM "%s"`, applyMigrationsTable)); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return
+		}
+		applied[id] = true
+	}
+
+	err = rows.Err()
+	return
+}
+
+// planApply diffs spec against the live database and its users, db is nil if the database is not
+// created yet.
+func planApply(db *sql.DB, users []*client.DatabaseUser, spec *applySpec) (plan *applyPlan, err error) {
+	plan = new(applyPlan)
+
+	if plan.grants, plan.unmanagedUsers, err = diffPermissions(spec.Permissions, users); err != nil {
+		return
+	}
+
+	if db == nil {
+		// database not exists yet, everything is to be created
+		plan.createDB = true
+		plan.tables = spec.Schema
+		plan.migrations = spec.Migrations
+		return
+	}
+
+	var live map[string]string
+	if live, err = loadLiveTables(db); err != nil {
+		return
+	}
+
+	plan.tables, plan.drifted, plan.unmanaged = diffSchema(spec.Schema, live)
+
+	var applied map[string]bool
+	if applied, err = loadAppliedMigrations(db, live); err != nil {
+		return
+	}
+
+	plan.migrations = diffMigrations(spec.Migrations, applied)
+
+	return
+}
+
+func executePlan(db *sql.DB, dbID proto.DatabaseID, spec *applySpec, plan *applyPlan) (err error) {
+	for _, t := range plan.tables {
+		if _, err = db.Exec(t.SQL); err != nil {
+			return fmt.Errorf("create table %s failed: %v", t.Name, err)
+		}
+		log.Infof("created table %s", t.Name)
+	}
+
+	if err = executeMigrations(db, plan.migrations); err != nil {
+		return
+	}
+
+	// the drifted tables should be altered by the migrations
+	if len(plan.drifted) > 0 {
+		var live map[string]string
+		if live, err = loadLiveTables(db); err != nil {
+			return
+		}
+		recheck := &applyPlan{}
+		_, recheck.drifted, _ = diffSchema(spec.Schema, live)
+		if err = recheck.checkDrift(); err != nil {
+			return
+		}
+	}
+
+	for _, g := range plan.grants {
+		log.Infof("granting %s to %s, waiting for confirmation",
+			permissionString(pt.UserPermission(g.perm)), g.name)
+		if err = client.GrantAccountPermission(dbID, g.user, g.perm); err != nil {
+			return fmt.Errorf("grant permission to %s failed: %v", g.name, err)
+		}
+		log.Infof("granted %s to %s", permissionString(pt.UserPermission(g.perm)), g.name)
+	}
+
+	return
+}
+
+func executeMigrations(db *sql.DB, migrations []applyMigration) (err error) {
+	if len(migrations) == 0 {
+		return
+	}
+
+	if _, err = db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS "%s" (id TEXT PRIMARY KEY NOT NULL, applied_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		applyMigrationsTable)); err != nil {
+		return
+	}
+
+	for _, m := range migrations {
+		// migration statements and its record are sent in a single transaction
+		var tx *sql.Tx
+		if tx, err = db.Begin(); err != nil {
+			return
+		}
+		for _, q := range m.SQL {
+			if _, err = tx.Exec(q); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("apply migration %s failed: %v", m.ID, err)
+			}
+		}
+		if _, err = tx.Exec(fmt.Sprintf(`INSERT INTO "%s" (id) VALUES (?)`, applyMigrationsTable), m.ID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("apply migration %s failed: %v", m.ID, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("apply migration %s failed: %v", m.ID, err)
+		}
+		log.Infof("applied migration %s", m.ID)
+	}
+
+	return
+}
+
+// runApply converges the database described by spec file, only the plan is printed if planOnly is set.
+func runApply(fileName string, planOnly bool) (err error) {
+	var spec *applySpec
+	if spec, err = loadApplySpec(fileName); err != nil {
+		return
+	}
+
+	var (
+		db    *sql.DB
+		dbID  = proto.DatabaseID(spec.Database.ID)
+		users []*client.DatabaseUser
+	)
+	if dbID != "" {
+		cfg := client.NewConfig()
+		cfg.DatabaseID = spec.Database.ID
+		if db, err = sql.Open("covenantsql", cfg.FormatDSN()); err != nil {
+			return
+		}
+		defer db.Close()
+		if users, err = client.GetDatabaseUsers(dbID); err != nil {
+			return
+		}
+	}
+
+	var plan *applyPlan
+	if plan, err = planApply(db, users, spec); err != nil {
+		return
+	}
+
+	plan.print()
+
+	if err = plan.checkDrift(); err != nil {
+		return
+	}
+	if planOnly || plan.empty() {
+		return
+	}
+
+	if plan.createDB {
+		var dsn string
+		if dsn, err = client.Create(client.ResourceMeta{
+			Node:          spec.Database.Node,
+			Space:         spec.Database.Space,
+			Memory:        spec.Database.Memory,
+			LoadAvgPerCPU: spec.Database.LoadAvgPerCPU,
+			EncryptionKey: spec.Database.EncryptionKey,
+		}); err != nil {
+			return
+		}
+
+		var cfg *client.Config
+		if cfg, err = client.ParseDSN(dsn); err != nil {
+			return
+		}
+
+		log.Infof("created database %s, set it as database id in spec for further applies", cfg.DatabaseID)
+		dbID = proto.DatabaseID(cfg.DatabaseID)
+
+		if db, err = sql.Open("covenantsql", dsn); err != nil {
+			return
+		}
+		defer db.Close()
+	}
+
+	return executePlan(db, dbID, spec, plan)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestApplyPlan(t *testing.T) {
+	Convey("test diffing spec permissions with database users", t, func() {
+		var (
+			alice = proto.AccountAddress{0x1}
+			bob   = proto.AccountAddress{0x2}
+			carol = proto.AccountAddress{0x3}
+			live  = []*client.DatabaseUser{
+				{Address: alice, Permission: pt.Admin},
+				{Address: bob, Permission: pt.Read},
+				{Address: carol, Permission: pt.Read},
+			}
+		)
+		grants, unmanaged, err := diffPermissions([]applyPermission{
+			{User: hash.Hash(alice).String(), Permission: "admin"},
+			{User: hash.Hash(bob).String(), Permission: "read,write"},
+		}, live)
+		So(err, ShouldBeNil)
+		So(grants, ShouldHaveLength, 1)
+		So(grants[0].user, ShouldResemble, bob)
+		So(grants[0].perm, ShouldEqual, client.PermissionWrite)
+		So(unmanaged, ShouldResemble, []string{hash.Hash(carol).String()})
+
+		grants, unmanaged, err = diffPermissions([]applyPermission{
+			{User: hash.Hash(alice).String(), Permission: "read"},
+		}, nil)
+		So(err, ShouldBeNil)
+		So(grants, ShouldHaveLength, 1)
+		So(unmanaged, ShouldBeEmpty)
+
+		_, _, err = diffPermissions([]applyPermission{{User: "invalid", Permission: "read"}}, live)
+		So(err, ShouldNotBeNil)
+		_, _, err = diffPermissions([]applyPermission{{User: hash.Hash(alice).String(), Permission: "owner"}}, live)
+		So(err, ShouldNotBeNil)
+	})
+	Convey("test validating permissions of spec", t, func() {
+		spec := &applySpec{}
+		spec.Database.ID = "db"
+		spec.Permissions = []applyPermission{
+			{User: hash.Hash(proto.AccountAddress{0x1}).String(), Permission: "read"},
+		}
+		So(spec.validate(), ShouldBeNil)
+		spec.Permissions = append(spec.Permissions, spec.Permissions[0])
+		So(spec.validate(), ShouldNotBeNil)
+	})
+	Convey("test failing on drifted tables", t, func() {
+		spec := []applyTable{
+			{Name: "users", SQL: "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"},
+		}
+		create, drifted, _ := diffSchema(spec, map[string]string{
+			"users": "CREATE TABLE users (id INTEGER PRIMARY KEY,\n  name TEXT);",
+		})
+		So(create, ShouldBeEmpty)
+		So(drifted, ShouldBeEmpty)
+
+		_, drifted, _ = diffSchema(spec, map[string]string{
+			"users": "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)",
+		})
+		So(drifted, ShouldResemble, []string{"users"})
+		plan := &applyPlan{drifted: drifted}
+		So(plan.checkDrift(), ShouldNotBeNil)
+		// pending migrations may alter the drifted tables
+		plan.migrations = []applyMigration{{ID: "0001", SQL: []string{"ALTER TABLE users DROP COLUMN email"}}}
+		So(plan.checkDrift(), ShouldBeNil)
+	})
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/CovenantSQL/CovenantSQL/utils/objstore"
+)
+
+var (
+	backupDatabase string
+	backupDest     string
+	backupFormat   string
+	backupSchedule string
+	backupKeep     int
+
+	errMissingDest = errors.New("backup destination is not specified")
+)
+
+// backupTimeFormat defines the time format in names of backups.
+const backupTimeFormat = "20060102T150405Z"
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "backup",
+		usage: "backup -database ID -dest file://DIR|s3://BUCKET/PREFIX [-schedule CRON] [-keep N]",
+		desc:  "back up consistent snapshots of database once or on schedule, keeping the latest N",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&backupDatabase, "database", "", "database id or dsn to back up")
+			fs.StringVar(&backupDest, "dest", "", "destination of backups, file://DIR or "+
+				"s3://BUCKET/PREFIX[?region=REGION&endpoint=URL] with credentials in AWS_* env")
+			fs.StringVar(&backupFormat, "format", "sqlite", "format of backups, sql or sqlite")
+			fs.StringVar(&backupSchedule, "schedule", "", "cron expression like \"0 */6 * * *\" or "+
+				"@daily to run as daemon, back up once if not set")
+			fs.IntVar(&backupKeep, "keep", 0, "number of latest backups to keep, 0 to keep all")
+		},
+		run: runBackup,
+	})
+}
+
+func runBackup(_ []string) (err error) {
+	var cfg *client.Config
+	if cfg, err = databaseDSN(backupDatabase); err != nil {
+		return
+	}
+	dbID := proto.DatabaseID(cfg.DatabaseID)
+
+	opts := client.DumpOptions{}
+	var ext string
+	switch backupFormat {
+	case "sql":
+		opts.Format, ext = client.DumpSQL, ".sql"
+	case "sqlite":
+		opts.Format, ext = client.DumpSQLite, ".db"
+	default:
+		return client.ErrInvalidParameter
+	}
+	if backupKeep < 0 {
+		return client.ErrInvalidParameter
+	}
+
+	if backupDest == "" {
+		return errMissingDest
+	}
+	var store objstore.Store
+	if store, err = objstore.Open(backupDest); err != nil {
+		return
+	}
+
+	if backupSchedule == "" {
+		return backupOnce(dbID, store, opts, ext)
+	}
+
+	var sched *cronSchedule
+	if sched, err = parseCron(backupSchedule); err != nil {
+		return
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	for {
+		next := sched.next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never runs", backupSchedule)
+		}
+		log.Infof("next backup of %v at %v", dbID, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			log.Info("backup daemon stopped")
+			return nil
+		case <-timer.C:
+		}
+
+		// failure of a single round is retried on next schedule
+		if err = backupOnce(dbID, store, opts, ext); err != nil {
+			log.WithError(err).Errorf("backup %v failed", dbID)
+		}
+	}
+}
+
+// backupOnce takes snapshot of database at the head block and uploads it to store as
+// DBID-HEIGHT-TIME.EXT, the oldest backups exceeding backupKeep are removed afterwards.
+func backupOnce(dbID proto.DatabaseID, store objstore.Store, opts client.DumpOptions, ext string) (err error) {
+	ctx := context.Background()
+	if opts.Height, err = client.GetHeadHeight(ctx, dbID); err != nil {
+		return
+	}
+	if opts.Height < 0 {
+		// no block produced yet, read the state when dump starts
+		opts.Height = 0
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile("", "covenantsql_backup_"); err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	if err = client.DumpContext(ctx, dbID, bw, opts); err != nil {
+		return
+	}
+	if err = bw.Flush(); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = ioutil.ReadFile(f.Name()); err != nil {
+		return
+	}
+
+	prefix := string(dbID) + "-"
+	name := fmt.Sprintf("%s%010d-%s%s", prefix, opts.Height, time.Now().UTC().Format(backupTimeFormat), ext)
+	if err = store.Put(name, data); err != nil {
+		return
+	}
+	log.Infof("backup %v at height %d saved as %v", dbID, opts.Height, name)
+
+	if backupKeep == 0 {
+		return
+	}
+	var names []string
+	if names, err = store.List(prefix); err != nil {
+		return
+	}
+	// names are ordered by height and time
+	sort.Strings(names)
+	for len(names) > backupKeep {
+		if err = store.Remove(names[0]); err != nil {
+			return
+		}
+		log.Infof("expired backup %v removed", names[0])
+		names = names[1:]
+	}
+	return
+}
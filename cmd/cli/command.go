@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// subCommands holds the subcommands of cql, which are invoked as
+// "cql [global flags] NAME [flags]" after client is initialized.
+var subCommands = make(map[string]*subCommand)
+
+// subCommand defines a cql subcommand, flags are registered to the flag set by setup and
+// parsed before run is called with the remaining arguments.
+type subCommand struct {
+	name  string
+	usage string
+	desc  string
+	setup func(fs *flag.FlagSet)
+	run   func(args []string) error
+	// noInit skips client initialization for subcommands working on local config and keys.
+	noInit bool
+}
+
+func registerSubCommand(c *subCommand) {
+	subCommands[c.name] = c
+}
+
+// needInit reports whether client should be initialized before running args.
+func needInit(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	c, ok := subCommands[args[0]]
+	return !ok || !c.noInit
+}
+
+// runSubCommand runs the subcommand named by args[0], reporting false if it is not a subcommand.
+func runSubCommand(args []string) (ok bool, err error) {
+	if len(args) == 0 {
+		return
+	}
+	var c *subCommand
+	if c, ok = subCommands[args[0]]; !ok {
+		return
+	}
+
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: cql [global flags] %s\n\n%s\n\n", c.usage, c.desc)
+		fs.PrintDefaults()
+	}
+	if c.setup != nil {
+		c.setup(fs)
+	}
+	if err = fs.Parse(args[1:]); err != nil {
+		return
+	}
+
+	err = c.run(fs.Args())
+	return
+}
+
+func printSubCommands() {
+	names := make([]string, 0, len(subCommands))
+	for name := range subCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "\nSubcommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, subCommands[name].desc)
+	}
+}
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [subcommand [flags]]\n\n", os.Args[0])
+		flag.PrintDefaults()
+		printSubCommands()
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/xo/usql/drivers"
+)
+
+const (
+	// schemaCacheTTL defines how long the fetched schema is used for completion before refreshing.
+	schemaCacheTTL = 30 * time.Second
+)
+
+// sqlKeywords defines the keywords offered by completion, sorted for lookup.
+var sqlKeywords = []string{
+	"ABORT", "ADD", "ALL", "ALTER", "AND", "AS", "ASC", "AUTOINCREMENT", "BEGIN", "BETWEEN",
+	"BY", "CASE", "CHECK", "COLUMN", "COMMIT", "CONSTRAINT", "CREATE", "CROSS", "DEFAULT",
+	"DELETE", "DESC", "DESCRIBE", "DISTINCT", "DROP", "ELSE", "END", "EXISTS", "EXPLAIN",
+	"FOREIGN", "FROM", "GROUP", "HAVING", "IF", "IN", "INDEX", "INNER", "INSERT", "INTEGER",
+	"INTO", "IS", "JOIN", "KEY", "LEFT", "LIKE", "LIMIT", "NOT", "NULL", "OFFSET", "ON", "OR",
+	"ORDER", "OUTER", "PRIMARY", "REAL", "REFERENCES", "REPLACE", "ROLLBACK", "SELECT", "SET",
+	"SHOW", "TABLE", "TABLES", "TEXT", "THEN", "TRANSACTION", "UNION", "UNIQUE", "UPDATE",
+	"USING", "VALUES", "VIEW", "WHEN", "WHERE", "WITH",
+}
+
+// schemaCache holds table and column names of the connected database for completion.
+type schemaCache struct {
+	sync.Mutex
+	tables  map[string][]string
+	fetched time.Time
+}
+
+func newSchemaCache() *schemaCache {
+	return &schemaCache{
+		tables: make(map[string][]string),
+	}
+}
+
+// get returns the cached schema, which is fetched again if it is expired.
+func (c *schemaCache) get(db drivers.DB) map[string][]string {
+	c.Lock()
+	defer c.Unlock()
+
+	if time.Since(c.fetched) < schemaCacheTTL {
+		return c.tables
+	}
+
+	if tables, err := fetchSchema(db); err == nil {
+		c.tables = tables
+		c.fetched = time.Now()
+	}
+
+	return c.tables
+}
+
+// invalidate makes the schema fetched again on next completion.
+func (c *schemaCache) invalidate() {
+	c.Lock()
+	defer c.Unlock()
+	c.fetched = time.Time{}
+}
+
+func fetchSchema(db drivers.DB) (tables map[string][]string, err error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table';`)
+	if err != nil {
+		return
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	tables = make(map[string][]string, len(names))
+	for _, name := range names {
+		if !identifierRegex.MatchString(name) {
+			continue
+		}
+		if tables[name], err = fetchColumns(db, name); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func fetchColumns(db drivers.DB, table string) (columns []string, err error) {
+	rows, err := db.Query(`PRAGMA table_info("` + table + `");`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	// cid, name, type, notnull, dflt_value, pk
+	for rows.Next() {
+		var name string
+		var cid, typ, notNull, defaultValue, pk interface{}
+		if err = rows.Scan(&cid, &name, &typ, &notNull, &defaultValue, &pk); err != nil {
+			return
+		}
+		columns = append(columns, name)
+	}
+
+	return
+}
+
+// shellCompleter implements readline.AutoCompleter, completing shell commands, sql keywords,
+// table names and column names of the connected database.
+type shellCompleter struct {
+	s *shell
+}
+
+// Do implements readline.AutoCompleter.Do.
+func (c *shellCompleter) Do(line []rune, pos int) (newLine [][]rune, length int) {
+	start := pos
+	for start > 0 && isCompletionRune(line[start-1]) {
+		start--
+	}
+	word := string(line[start:pos])
+	length = len(line[start:pos])
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(word, `\`):
+		for name := range shellCommands {
+			candidates = append(candidates, `\`+name)
+		}
+	case strings.Contains(word, "."):
+		// columns of the qualified table
+		i := strings.LastIndex(word, ".")
+		table := word[:i]
+		for _, column := range c.tables()[table] {
+			candidates = append(candidates, table+"."+column)
+		}
+	default:
+		candidates = append(candidates, sqlKeywords...)
+		for table, columns := range c.tables() {
+			candidates = append(candidates, table)
+			candidates = append(candidates, columns...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	lowerWord := strings.ToLower(word)
+	for _, candidate := range candidates {
+		if seen[candidate] || len(candidate) <= len(word) ||
+			!strings.HasPrefix(strings.ToLower(candidate), lowerWord) {
+			continue
+		}
+		seen[candidate] = true
+
+		// keywords follow the case typed by user
+		if isKeyword(candidate) && word != "" && word == lowerWord {
+			candidate = strings.ToLower(candidate)
+		}
+		newLine = append(newLine, []rune(candidate[len(word):]))
+	}
+
+	sort.Slice(newLine, func(i, j int) bool {
+		return string(newLine[i]) < string(newLine[j])
+	})
+
+	return
+}
+
+func (c *shellCompleter) tables() map[string][]string {
+	if c.s.h == nil || c.s.h.URL() == nil {
+		return nil
+	}
+	return c.s.schema.get(c.s.h.DB())
+}
+
+func isCompletionRune(r rune) bool {
+	return r == '_' || r == '.' || r == '\\' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isKeyword(s string) bool {
+	i := sort.SearchStrings(sqlKeywords, s)
+	return i < len(sqlKeywords) && sqlKeywords[i] == s
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	createInteractive bool
+
+	errCreateAborted = errors.New("create database aborted")
+
+	sizeUnits = []struct {
+		suffix string
+		size   uint64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "create",
+		usage: "create [-interactive] [META]",
+		desc:  "create database, META is instance requirement json or simply a node count",
+		setup: func(fs *flag.FlagSet) {
+			fs.BoolVar(&createInteractive, "interactive", false,
+				"walk through resource requirements and confirm the estimated cost before creating")
+		},
+		run: runCreate,
+	})
+}
+
+// parseResourceMeta parses instance requirement json or simply a node count.
+func parseResourceMeta(s string) (meta client.ResourceMeta, err error) {
+	if err = json.Unmarshal([]byte(s), &meta); err == nil {
+		return
+	}
+	// not a instance json, try if it is a number describing node count
+	var nodeCnt uint64
+	if nodeCnt, err = strconv.ParseUint(s, 10, 16); err != nil {
+		err = fmt.Errorf("%v is not a valid instance description", s)
+		return
+	}
+	meta = client.ResourceMeta{Node: uint16(nodeCnt)}
+	return
+}
+
+// parseSize parses size with optional unit suffix like 10GB.
+func parseSize(s string) (size uint64, err error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	unit := uint64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	var v float64
+	if v, err = strconv.ParseFloat(s, 64); err != nil || v < 0 {
+		err = fmt.Errorf("invalid size %v", s)
+		return
+	}
+	size = uint64(v * float64(unit))
+	return
+}
+
+func runCreate(args []string) (err error) {
+	var meta client.ResourceMeta
+	if createInteractive {
+		if len(args) != 0 {
+			return flag.ErrHelp
+		}
+		var ok bool
+		w := newWizard(os.Stdin, os.Stdout)
+		if meta, ok, err = w.run(); err != nil {
+			return
+		} else if !ok {
+			return errCreateAborted
+		}
+	} else {
+		if len(args) != 1 {
+			return flag.ErrHelp
+		}
+		if meta, err = parseResourceMeta(args[0]); err != nil {
+			return
+		}
+	}
+
+	var dsn string
+	if dsn, err = client.Create(meta); err != nil {
+		return
+	}
+
+	log.Infof("the newly created database is: %v", dsn)
+	return
+}
+
+// wizard asks resource requirements of database interactively.
+type wizard struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func newWizard(r io.Reader, w io.Writer) *wizard {
+	return &wizard{r: bufio.NewReader(r), w: w}
+}
+
+// ask prompts question and parses the answer by parse until it succeeds, the default value
+// is used for empty answer.
+func (z *wizard) ask(question, def string, parse func(string) error) (err error) {
+	for {
+		fmt.Fprintf(z.w, "%s [%s]: ", question, def)
+		var line string
+		if line, err = z.r.ReadString('\n'); err != nil && (err != io.EOF || line == "") {
+			return
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			line = def
+		}
+		if err = parse(line); err == nil {
+			return
+		}
+		fmt.Fprintf(z.w, "invalid answer: %v\n", err)
+	}
+}
+
+func (z *wizard) run() (meta client.ResourceMeta, ok bool, err error) {
+	if err = z.ask("Node count", "1", func(s string) (err error) {
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, 16); err == nil && n == 0 {
+			err = errors.New("node count should be positive")
+		}
+		meta.Node = uint16(n)
+		return
+	}); err != nil {
+		return
+	}
+	if err = z.ask("Storage space per node", "1GB", func(s string) (err error) {
+		meta.Space, err = parseSize(s)
+		return
+	}); err != nil {
+		return
+	}
+	if err = z.ask("Memory per node", "256MB", func(s string) (err error) {
+		meta.Memory, err = parseSize(s)
+		return
+	}); err != nil {
+		return
+	}
+	if err = z.ask("Consistency (strong, eventual, or fraction of peers in (0, 1])", "strong",
+		func(s string) (err error) {
+			switch strings.ToLower(s) {
+			case "strong":
+				meta.UseEventualConsistency, meta.ConsistencyLevel = false, 1
+			case "eventual":
+				meta.UseEventualConsistency, meta.ConsistencyLevel = true, 0
+			default:
+				var level float64
+				if level, err = strconv.ParseFloat(s, 64); err != nil || level <= 0 || level > 1 {
+					return errors.New("consistency level should be in (0, 1]")
+				}
+				meta.UseEventualConsistency, meta.ConsistencyLevel = false, level
+			}
+			return
+		}); err != nil {
+		return
+	}
+	if err = z.ask("Target gas price, 0 for current price", "0", func(s string) (err error) {
+		meta.GasPrice, err = strconv.ParseUint(s, 10, 64)
+		return
+	}); err != nil {
+		return
+	}
+	if err = z.ask("Allowed miner regions, comma separated or * for any", "*", func(s string) (err error) {
+		meta.Constraints.Regions = nil
+		if s == "*" {
+			return
+		}
+		for _, region := range strings.Split(s, ",") {
+			if region = strings.TrimSpace(region); region != "" {
+				meta.Constraints.Regions = append(meta.Constraints.Regions, region)
+			}
+		}
+		return
+	}); err != nil {
+		return
+	}
+
+	var est *client.PriceEstimate
+	if est, err = client.EstimatePrice(meta); err != nil {
+		return
+	}
+	fmt.Fprintln(z.w)
+	fmt.Fprintf(z.w, "Nodes:              %d\n", meta.Node)
+	fmt.Fprintf(z.w, "Space per node:     %d bytes\n", meta.Space)
+	fmt.Fprintf(z.w, "Memory per node:    %d bytes\n", meta.Memory)
+	if len(meta.Constraints.Regions) > 0 {
+		fmt.Fprintf(z.w, "Miner regions:      %s\n", strings.Join(meta.Constraints.Regions, ", "))
+	}
+	fmt.Fprintf(z.w, "Gas price:          %d (current %d)\n", est.GasPrice, est.CurrentGasPrice)
+	fmt.Fprintf(z.w, "Gas per hour:       %d\n", est.GasPerHour)
+	fmt.Fprintf(z.w, "Estimated cost:     %d per hour, %d per month\n", est.CostPerHour, est.CostPerMonth)
+	fmt.Fprintln(z.w)
+
+	err = z.ask("Create database", "y/N", func(s string) error {
+		switch strings.ToLower(s) {
+		case "y", "yes":
+			ok = true
+		case "n", "no", "y/n":
+			ok = false
+		default:
+			return errors.New("answer y or n")
+		}
+		return nil
+	})
+	return
+}
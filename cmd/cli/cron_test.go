@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseCron(t *testing.T) {
+	Convey("test parsing cron fields", t, func() {
+		for _, c := range []struct {
+			field    string
+			min, max int
+			values   []int
+		}{
+			{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}},
+			{"?", 1, 3, []int{1, 2, 3}},
+			{"3", 0, 59, []int{3}},
+			{"1,3,5", 0, 59, []int{1, 3, 5}},
+			{"1-4", 0, 59, []int{1, 2, 3, 4}},
+			{"*/15", 0, 59, []int{0, 15, 30, 45}},
+			{"10-20/5", 0, 59, []int{10, 15, 20}},
+			{"50/4", 0, 59, []int{50, 54, 58}},
+			{"1-2,10-30/10,59", 0, 59, []int{1, 2, 10, 20, 30, 59}},
+			{"7", 0, 7, []int{7}},
+		} {
+			bits, err := parseCronField(c.field, c.min, c.max)
+			So(err, ShouldBeNil)
+			var expect uint64
+			for _, v := range c.values {
+				expect |= 1 << uint(v)
+			}
+			So(bits, ShouldEqual, expect)
+		}
+	})
+	Convey("test parsing invalid cron fields", t, func() {
+		for _, field := range []string{
+			"", "a", "60", "-1", "0-60", "5-1", "1-", "-5", "1-2-3", "*/0", "*/-1", "*/a", "1,,2",
+		} {
+			_, err := parseCronField(field, 0, 59)
+			So(err, ShouldNotBeNil)
+		}
+		_, err := parseCronField("0", 1, 31)
+		So(err, ShouldNotBeNil)
+	})
+	Convey("test parsing cron expressions", t, func() {
+		sched, err := parseCron("*/30 9-17 * * 1-5")
+		So(err, ShouldBeNil)
+		So(sched.minute, ShouldEqual, uint64(1|1<<30))
+		So(sched.hour, ShouldEqual, uint64(1<<18-1<<9))
+		So(sched.dom, ShouldEqual, uint64(1<<32-2))
+		So(sched.month, ShouldEqual, uint64(1<<13-2))
+		So(sched.dow, ShouldEqual, uint64(1<<6-2))
+		So(sched.domAny, ShouldBeTrue)
+		So(sched.dowAny, ShouldBeFalse)
+
+		// sunday is both 0 and 7
+		sched, err = parseCron("0 0 1 * 7")
+		So(err, ShouldBeNil)
+		So(sched.dow, ShouldEqual, uint64(1|1<<7))
+		So(sched.domAny, ShouldBeFalse)
+
+		for d, expr := range cronDescriptors {
+			sched, err = parseCron(" " + d + " ")
+			So(err, ShouldBeNil)
+			expect, err := parseCron(expr)
+			So(err, ShouldBeNil)
+			So(sched, ShouldResemble, expect)
+		}
+		_, err = parseCron("@DAILY")
+		So(err, ShouldBeNil)
+
+		for _, expr := range []string{"", "* * * *", "* * * * * *", "@every 5m", "60 * * * *",
+			"* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8"} {
+			_, err = parseCron(expr)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestCronNext(t *testing.T) {
+	Convey("test next time of cron schedules", t, func() {
+		at := func(s string) time.Time {
+			t, err := time.Parse("2006-01-02 15:04:05", s)
+			So(err, ShouldBeNil)
+			return t
+		}
+		for _, c := range []struct {
+			expr   string
+			from   string
+			expect string
+		}{
+			{"* * * * *", "2018-10-01 10:00:00", "2018-10-01 10:01:00"},
+			{"* * * * *", "2018-10-01 10:00:59", "2018-10-01 10:01:00"},
+			{"*/15 * * * *", "2018-10-01 10:15:00", "2018-10-01 10:30:00"},
+			{"0 * * * *", "2018-10-01 23:30:00", "2018-10-02 00:00:00"},
+			{"30 2 * * *", "2018-10-01 02:30:00", "2018-10-02 02:30:00"},
+			{"@monthly", "2018-12-15 00:00:00", "2019-01-01 00:00:00"},
+			{"@yearly", "2018-01-01 00:00:00", "2019-01-01 00:00:00"},
+			// 2018-10-01 is monday
+			{"0 9 * * 1-5", "2018-10-05 09:00:00", "2018-10-08 09:00:00"},
+			{"@weekly", "2018-10-01 00:00:00", "2018-10-07 00:00:00"},
+			{"0 0 * * 7", "2018-10-01 00:00:00", "2018-10-07 00:00:00"},
+			// day of month or day of week
+			{"0 0 15 * 1", "2018-10-01 00:00:00", "2018-10-08 00:00:00"},
+			{"0 0 3 * 1", "2018-10-01 00:00:00", "2018-10-03 00:00:00"},
+			{"0 0 31 * *", "2018-10-31 00:00:00", "2018-12-31 00:00:00"},
+			{"0 0 29 2 *", "2018-03-01 00:00:00", "2020-02-29 00:00:00"},
+		} {
+			sched, err := parseCron(c.expr)
+			So(err, ShouldBeNil)
+			So(sched.next(at(c.from)), ShouldResemble, at(c.expect))
+		}
+
+		sched, err := parseCron("0 0 30 2 *")
+		So(err, ShouldBeNil)
+		So(sched.next(at("2018-10-01 00:00:00")).IsZero(), ShouldBeTrue)
+	})
+}
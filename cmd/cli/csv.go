@@ -0,0 +1,371 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+)
+
+const (
+	// csvImportBatchRows defines the max rows inserted by a single statement of csv import.
+	csvImportBatchRows = 500
+
+	csvQuoteMinimal = "minimal"
+	csvQuoteAll     = "all"
+	csvQuoteNone    = "none"
+)
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "import",
+		usage: `\import [OPTIONS] FILE TABLE`,
+		desc:  "import csv file into table in a single transaction",
+		run:   importCommand,
+	})
+	registerShellCommand(&shellCommand{
+		name:  "export",
+		usage: `\export [OPTIONS] QUERY > FILE`,
+		desc:  "export query result to csv file",
+		run:   exportCommand,
+	})
+}
+
+// csvOptions defines the csv dialect shared by import and export.
+type csvOptions struct {
+	delimiter string
+	quote     string
+	header    bool
+	null      string
+}
+
+func newCSVFlagSet(name string, s *shell, opts *csvOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(s.Stderr())
+	fs.StringVar(&opts.delimiter, "delimiter", ",", `field delimiter, \t for tab`)
+	fs.StringVar(&opts.quote, "quote", csvQuoteMinimal, "quoting of fields: minimal, all or none")
+	fs.BoolVar(&opts.header, "header", true, "first line is column names")
+	fs.StringVar(&opts.null, "null", "", "string representing NULL")
+	return fs
+}
+
+func (o *csvOptions) comma() (r rune, err error) {
+	d := o.delimiter
+	if d == `\t` {
+		d = "\t"
+	}
+	if utf8.RuneCountInString(d) != 1 {
+		err = errInvalidShellArgs
+		return
+	}
+	r, _ = utf8.DecodeRuneInString(d)
+	switch o.quote {
+	case csvQuoteMinimal, csvQuoteAll, csvQuoteNone:
+	default:
+		err = errInvalidShellArgs
+	}
+	return
+}
+
+// csvRecordReader reads records of the csv dialect.
+type csvRecordReader struct {
+	opts  *csvOptions
+	comma rune
+	csv   *csv.Reader
+	lines *bufio.Scanner
+}
+
+func newCSVRecordReader(r io.Reader, opts *csvOptions, comma rune) *csvRecordReader {
+	if opts.quote == csvQuoteNone {
+		return &csvRecordReader{opts: opts, comma: comma, lines: bufio.NewScanner(r)}
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	return &csvRecordReader{opts: opts, comma: comma, csv: cr}
+}
+
+func (r *csvRecordReader) read() (record []string, err error) {
+	if r.csv != nil {
+		return r.csv.Read()
+	}
+	if !r.lines.Scan() {
+		if err = r.lines.Err(); err == nil {
+			err = io.EOF
+		}
+		return
+	}
+	return strings.Split(r.lines.Text(), string(r.comma)), nil
+}
+
+func writeCSVRecord(w io.Writer, record []string, opts *csvOptions, comma rune) (err error) {
+	var buf bytes.Buffer
+	for i, field := range record {
+		if i > 0 {
+			buf.WriteRune(comma)
+		}
+		// a single empty field is quoted, or the line is skipped as empty by csv reader
+		quote := opts.quote == csvQuoteAll || (opts.quote == csvQuoteMinimal &&
+			(strings.ContainsAny(field, string(comma)+"\"\r\n") || len(record) == 1 && field == ""))
+		if !quote {
+			buf.WriteString(field)
+			continue
+		}
+		buf.WriteByte('"')
+		buf.WriteString(strings.Replace(field, `"`, `""`, -1))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+func importCommand(s *shell, args []string, _ string) (err error) {
+	opts := new(csvOptions)
+	fs := newCSVFlagSet("import", s, opts)
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 2 {
+		return errInvalidShellArgs
+	}
+	fileName, table := fs.Arg(0), strings.TrimSuffix(fs.Arg(1), ";")
+	if !identifierRegex.MatchString(table) {
+		return errInvalidShellArgs
+	}
+	var comma rune
+	if comma, err = opts.comma(); err != nil {
+		return
+	}
+	if s.h.URL() == nil {
+		return errNotConnected
+	}
+
+	var f *os.File
+	if f, err = os.Open(fileName); err != nil {
+		return
+	}
+	defer f.Close()
+	r := newCSVRecordReader(f, opts, comma)
+
+	var columns []string
+	if opts.header {
+		if columns, err = r.read(); err != nil {
+			return
+		}
+		for _, c := range columns {
+			if !identifierRegex.MatchString(c) {
+				return fmt.Errorf("invalid column name %#v", c)
+			}
+		}
+	} else if columns, err = fetchColumns(s.h.DB(), table); err != nil {
+		return
+	}
+	if len(columns) == 0 || len(columns) > client.MaxBulkInsertVariables {
+		return fmt.Errorf("invalid columns of table %s", table)
+	}
+
+	// insert in the running transaction of shell, or start a new one
+	var tx *sql.Tx
+	var exec func(string, ...interface{}) (sql.Result, error)
+	if db, ok := s.h.DB().(*sql.DB); ok {
+		if tx, err = db.Begin(); err != nil {
+			return
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			}
+		}()
+		exec = tx.Exec
+	} else {
+		exec = s.h.DB().Exec
+	}
+
+	batchRows := client.MaxBulkInsertVariables / len(columns)
+	if batchRows > csvImportBatchRows {
+		batchRows = csvImportBatchRows
+	}
+
+	var count int
+	var rows [][]interface{}
+	for {
+		var record []string
+		if record, err = r.read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return
+		}
+		if len(record) != len(columns) {
+			return fmt.Errorf("line %d: %v", count+1, client.ErrColumnCountInvalid)
+		}
+
+		row := make([]interface{}, len(record))
+		for i, field := range record {
+			if field != opts.null {
+				row[i] = field
+			}
+		}
+		rows = append(rows, row)
+		count++
+
+		if len(rows) >= batchRows {
+			if err = execInsert(exec, table, columns, rows); err != nil {
+				return
+			}
+			rows = rows[:0]
+		}
+	}
+	if err = execInsert(exec, table, columns, rows); err != nil {
+		return
+	}
+
+	if tx != nil {
+		if err = tx.Commit(); err != nil {
+			return
+		}
+	}
+
+	fmt.Fprintf(s.Stdout(), "IMPORT %d", count)
+	fmt.Fprintln(s.Stdout())
+	return
+}
+
+func execInsert(exec func(string, ...interface{}) (sql.Result, error),
+	table string, columns []string, rows [][]interface{}) (err error) {
+	if len(rows) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `INSERT INTO "%s" ("%s") VALUES `, table, strings.Join(columns, `", "`))
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	args := make([]interface{}, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(placeholders)
+		args = append(args, row...)
+	}
+
+	_, err = exec(buf.String(), args...)
+	return
+}
+
+func exportCommand(s *shell, args []string, raw string) (err error) {
+	opts := new(csvOptions)
+	fs := newCSVFlagSet("export", s, opts)
+	var rest string
+	if rest, err = parseFlags(fs, args, raw); err != nil {
+		return
+	}
+	i := strings.LastIndex(rest, ">")
+	if i < 0 {
+		return errInvalidShellArgs
+	}
+	query := strings.TrimSpace(rest[:i])
+	fileName := strings.TrimSpace(rest[i+1:])
+	if query == "" || fileName == "" {
+		return errInvalidShellArgs
+	}
+	var comma rune
+	if comma, err = opts.comma(); err != nil {
+		return
+	}
+	if s.h.URL() == nil {
+		return errNotConnected
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.h.DB().Query(query); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var columns []string
+	if columns, err = rows.Columns(); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.Create(fileName); err != nil {
+		return
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	if opts.header {
+		if err = writeCSVRecord(w, columns, opts, comma); err != nil {
+			return
+		}
+	}
+
+	var count int
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return
+		}
+		for i, v := range values {
+			record[i] = formatCSVValue(v, opts.null)
+		}
+		if err = writeCSVRecord(w, record, opts, comma); err != nil {
+			return
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	fmt.Fprintf(s.Stdout(), "EXPORT %d", count)
+	fmt.Fprintln(s.Stdout())
+	return
+}
+
+func formatCSVValue(v interface{}, null string) string {
+	switch x := v.(type) {
+	case nil:
+		return null
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(x)
+	}
+}
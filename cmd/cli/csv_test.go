@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func csvRoundTrip(records [][]string, opts *csvOptions) (file string, decoded [][]string) {
+	comma, err := opts.comma()
+	So(err, ShouldBeNil)
+	var buf bytes.Buffer
+	for _, record := range records {
+		So(writeCSVRecord(&buf, record, opts, comma), ShouldBeNil)
+	}
+	file = buf.String()
+	r := newCSVRecordReader(&buf, opts, comma)
+	for {
+		record, err := r.read()
+		if err == io.EOF {
+			break
+		}
+		So(err, ShouldBeNil)
+		decoded = append(decoded, record)
+	}
+	return
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	records := [][]string{
+		{"id", "name", "note"},
+		{"1", "plain", ""},
+		{"2", "with,comma", `with "quotes"`},
+		{"3", "multi\nline", "carriage\rreturn"},
+		{"4", "\ttab", " spaces "},
+		{"5", `"`, `""`},
+		{"6", "unicode 中文", "semi;colon"},
+	}
+	Convey("test csv records round trip", t, func() {
+		for _, c := range []struct {
+			delimiter string
+			quote     string
+		}{
+			{",", csvQuoteMinimal},
+			{",", csvQuoteAll},
+			{`\t`, csvQuoteMinimal},
+			{";", csvQuoteAll},
+			{"|", csvQuoteMinimal},
+		} {
+			_, decoded := csvRoundTrip(records, &csvOptions{delimiter: c.delimiter, quote: c.quote})
+			So(decoded, ShouldResemble, records)
+		}
+	})
+	Convey("test csv records round trip without quoting", t, func() {
+		plain := [][]string{{"id", "name"}, {"1", "a \"b\" c"}, {"2", ""}, {"", ""}}
+		file, decoded := csvRoundTrip(plain, &csvOptions{delimiter: `\t`, quote: csvQuoteNone})
+		So(file, ShouldEqual, "id\tname\n1\ta \"b\" c\n2\t\n\t\n")
+		So(decoded, ShouldResemble, plain)
+	})
+	Convey("test csv quoting", t, func() {
+		file, _ := csvRoundTrip([][]string{{"a", "b c", "d,e"}}, &csvOptions{delimiter: ",", quote: csvQuoteMinimal})
+		So(file, ShouldEqual, "a,b c,\"d,e\"\n")
+		file, _ = csvRoundTrip([][]string{{"a", ""}}, &csvOptions{delimiter: ",", quote: csvQuoteAll})
+		So(file, ShouldEqual, "\"a\",\"\"\n")
+	})
+	Convey("test csv single empty field round trip", t, func() {
+		single := [][]string{{"v"}, {""}, {"x"}, {""}}
+		for _, quote := range []string{csvQuoteMinimal, csvQuoteAll, csvQuoteNone} {
+			_, decoded := csvRoundTrip(single, &csvOptions{delimiter: ",", quote: quote})
+			So(decoded, ShouldResemble, single)
+		}
+	})
+	Convey("test invalid csv options", t, func() {
+		for _, opts := range []*csvOptions{
+			{delimiter: "", quote: csvQuoteMinimal},
+			{delimiter: ",,", quote: csvQuoteMinimal},
+			{delimiter: ",", quote: "some"},
+		} {
+			_, err := opts.comma()
+			So(err, ShouldEqual, errInvalidShellArgs)
+		}
+	})
+	Convey("test formatting csv values", t, func() {
+		ts := time.Date(2018, 10, 1, 8, 30, 0, 500, time.UTC)
+		for _, c := range []struct {
+			value  interface{}
+			expect string
+		}{
+			{nil, `\N`},
+			{[]byte("bytes"), "bytes"},
+			{"string", "string"},
+			{int64(-1), "-1"},
+			{1.5, "1.5"},
+			{true, "true"},
+			{ts, "2018-10-01T08:30:00.0000005Z"},
+		} {
+			So(formatCSVValue(c.value, `\N`), ShouldEqual, c.expect)
+		}
+		// NULL is read back as the null string
+		_, decoded := csvRoundTrip([][]string{{formatCSVValue(nil, `\N`), "N"}},
+			&csvOptions{delimiter: ",", quote: csvQuoteMinimal})
+		So(decoded, ShouldResemble, [][]string{{`\N`, "N"}})
+	})
+}
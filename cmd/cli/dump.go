@@ -0,0 +1,344 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	dumpDatabase string
+	dumpOutput   string
+	dumpFormat   string
+	dumpHeight   int
+
+	restoreDatabase  string
+	restoreInput     string
+	restoreNode      uint
+	restoreBatchSize int
+
+	errMissingDatabase = errors.New("database is not specified")
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "dump",
+		usage: "dump -database ID [-o FILE] [-height N] [-format sql|sqlite]",
+		desc:  "dump a consistent snapshot of database as sql statements or sqlite file",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&dumpDatabase, "database", "", "database id or dsn to dump")
+			fs.StringVar(&dumpOutput, "o", "", "output file, default to stdout")
+			fs.StringVar(&dumpFormat, "format", "sql", "output format, sql or sqlite")
+			fs.IntVar(&dumpHeight, "height", 0, "dump the state at block height, default to current state")
+		},
+		run: runDump,
+	})
+	registerSubCommand(&subCommand{
+		name:  "restore",
+		usage: "restore (-database ID | -node N) [-i FILE] [-batch N]",
+		desc:  "restore sql dump to an existing database or a newly created one",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&restoreDatabase, "database", "", "database id or dsn to restore to")
+			fs.StringVar(&restoreInput, "i", "", "input sql dump file, default to stdin")
+			fs.UintVar(&restoreNode, "node", 0, "create a new database of node count to restore to")
+			fs.IntVar(&restoreBatchSize, "batch", 500, "statements executed in each transaction")
+		},
+		run: runRestore,
+	})
+}
+
+// databaseDSN accepts both database id and dsn.
+func databaseDSN(database string) (cfg *client.Config, err error) {
+	if database == "" {
+		err = errMissingDatabase
+		return
+	}
+	if strings.HasPrefix(database, "covenantsql://") {
+		return client.ParseDSN(database)
+	}
+	cfg = client.NewConfig()
+	cfg.DatabaseID = database
+	return
+}
+
+func runDump(_ []string) (err error) {
+	var cfg *client.Config
+	if cfg, err = databaseDSN(dumpDatabase); err != nil {
+		return
+	}
+
+	opts := client.DumpOptions{Height: int32(dumpHeight)}
+	switch dumpFormat {
+	case "sql":
+		opts.Format = client.DumpSQL
+	case "sqlite":
+		opts.Format = client.DumpSQLite
+	default:
+		return client.ErrInvalidParameter
+	}
+
+	var w io.Writer = os.Stdout
+	if dumpOutput != "" {
+		var f *os.File
+		if f, err = os.Create(dumpOutput); err != nil {
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if err = client.DumpContext(context.Background(), proto.DatabaseID(cfg.DatabaseID), bw, opts); err != nil {
+		return
+	}
+	if err = bw.Flush(); err != nil {
+		return
+	}
+
+	log.Infof("dump database %v success", cfg.DatabaseID)
+	return
+}
+
+func runRestore(_ []string) (err error) {
+	var dsn string
+	if restoreDatabase == "" && restoreNode > 0 {
+		if dsn, err = client.Create(client.ResourceMeta{Node: uint16(restoreNode)}); err != nil {
+			return
+		}
+		log.Infof("the newly created database is: %v", dsn)
+	} else {
+		var cfg *client.Config
+		if cfg, err = databaseDSN(restoreDatabase); err != nil {
+			return
+		}
+		dsn = cfg.FormatDSN()
+	}
+	if restoreBatchSize <= 0 {
+		return client.ErrInvalidParameter
+	}
+
+	var r io.Reader = os.Stdin
+	if restoreInput != "" {
+		var f *os.File
+		if f, err = os.Open(restoreInput); err != nil {
+			return
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var db *sql.DB
+	if db, err = sql.Open("covenantsql", dsn); err != nil {
+		return
+	}
+	defer db.Close()
+
+	var count int
+	var batch []string
+	flush := func() (err error) {
+		if len(batch) == 0 {
+			return
+		}
+		var tx *sql.Tx
+		if tx, err = db.Begin(); err != nil {
+			return
+		}
+		for _, q := range batch {
+			if _, err = tx.Exec(q); err != nil {
+				tx.Rollback()
+				return
+			}
+		}
+		if err = tx.Commit(); err != nil {
+			return
+		}
+		count += len(batch)
+		batch = batch[:0]
+		return
+	}
+
+	err = restoreStatements(r, func(q string) (err error) {
+		if batch = append(batch, q); len(batch) >= restoreBatchSize {
+			err = flush()
+		}
+		return
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		log.Errorf("restore stopped after %d statements", count)
+		return
+	}
+
+	log.Infof("restore %d statements to %v success", count, dsn)
+	return
+}
+
+// restoreStatements reads statements of sql dump, transactions of dump are skipped since they
+// are replaced by batches.
+func restoreStatements(r io.Reader, f func(q string) error) error {
+	return splitStatements(r, func(q string) error {
+		switch strings.ToUpper(q) {
+		case "BEGIN", "BEGIN TRANSACTION", "COMMIT", "END", "END TRANSACTION":
+			return nil
+		}
+		return f(q)
+	})
+}
+
+func isWordRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// splitStatements reads sql statements separated by semicolons, semicolons in quoted strings,
+// identifiers and comments are skipped. Semicolons in the BEGIN...END body of CREATE TRIGGER
+// statements are skipped too, CASE...END expressions are tracked as nested blocks of body.
+func splitStatements(r io.Reader, f func(q string) error) (err error) {
+	br := bufio.NewReader(r)
+	var buf strings.Builder
+	var quote rune
+	var lineComment, blockComment bool
+	var prev rune
+
+	// leading words of statement and the BEGIN...END nesting depth of trigger body
+	var word strings.Builder
+	var words []string
+	var depth int
+
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToUpper(word.String())
+		word.Reset()
+		if len(words) < 3 {
+			words = append(words, w)
+		}
+		if !isCreateTrigger(words) {
+			return
+		}
+		switch w {
+		case "BEGIN", "CASE":
+			depth++
+		case "END":
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+
+	emit := func() error {
+		q := strings.TrimSpace(buf.String())
+		buf.Reset()
+		words = words[:0]
+		depth = 0
+		if q == "" {
+			return nil
+		}
+		return f(q)
+	}
+
+	for {
+		var c rune
+		if c, _, err = br.ReadRune(); err == io.EOF {
+			break
+		} else if err != nil {
+			return
+		}
+
+		if !lineComment && !blockComment && quote == 0 {
+			if isWordRune(c) {
+				word.WriteRune(c)
+			} else {
+				endWord()
+			}
+		}
+
+		switch {
+		case lineComment:
+			if c == '\n' {
+				lineComment = false
+			}
+			prev = c
+			continue
+		case blockComment:
+			if prev == '*' && c == '/' {
+				blockComment = false
+				c = 0
+			}
+			prev = c
+			continue
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '-' && prev == '-':
+			lineComment = true
+			s := buf.String()
+			buf.Reset()
+			buf.WriteString(s[:len(s)-1])
+			prev = 0
+			continue
+		case c == '*' && prev == '/':
+			blockComment = true
+			s := buf.String()
+			buf.Reset()
+			buf.WriteString(s[:len(s)-1])
+			prev = 0
+			continue
+		case c == ';' && depth == 0:
+			if err = emit(); err != nil {
+				return
+			}
+			prev = c
+			continue
+		}
+
+		buf.WriteRune(c)
+		prev = c
+	}
+
+	return emit()
+}
+
+// isCreateTrigger returns whether the leading words are of a CREATE TRIGGER statement.
+func isCreateTrigger(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+		return len(words) > 2 && words[2] == "TRIGGER"
+	}
+	return words[1] == "TRIGGER"
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/sqlchain/storage"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func collectStatements(dump string) (stmts []string, err error) {
+	err = restoreStatements(strings.NewReader(dump), func(q string) error {
+		stmts = append(stmts, q)
+		return nil
+	})
+	return
+}
+
+func TestSplitStatements(t *testing.T) {
+	Convey("test splitting statements of sql dump", t, func() {
+		cases := []struct {
+			dump  string
+			stmts []string
+		}{
+			{"select 1; select 2", []string{"select 1", "select 2"}},
+			{"select ';' -- a;b\n; select \"a;b\" /* ; */;", []string{"select ';'", "select \"a;b\""}},
+			{"BEGIN TRANSACTION;\ncreate table t (a);\nCOMMIT;\n", []string{"create table t (a)"}},
+			{"begin; insert into t values (1); end;", []string{"insert into t values (1)"}},
+			{
+				"CREATE TRIGGER tr AFTER INSERT ON t BEGIN insert into l values (1); update l set a = 2; END;\n" +
+					"select 1;",
+				[]string{
+					"CREATE TRIGGER tr AFTER INSERT ON t BEGIN insert into l values (1); update l set a = 2; END",
+					"select 1",
+				},
+			},
+			{
+				"create temp trigger tr after insert on t begin\n" +
+					"  select case when new.a > 0 then 1 else 0 end; insert into \"end\" values (1);\n" +
+					"end; select 1;",
+				[]string{
+					"create temp trigger tr after insert on t begin\n" +
+						"  select case when new.a > 0 then 1 else 0 end; insert into \"end\" values (1);\n" +
+						"end",
+					"select 1",
+				},
+			},
+		}
+		for _, c := range cases {
+			stmts, err := collectStatements(c.dump)
+			So(err, ShouldBeNil)
+			So(stmts, ShouldResemble, c.stmts)
+		}
+	})
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	Convey("test restoring sql dump with triggers", t, func() {
+		dir, err := ioutil.TempDir("", "covenantsql_cli_dump_")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		src, err := storage.New(filepath.Join(dir, "src.db"))
+		So(err, ShouldBeNil)
+		defer src.Close()
+		_, err = src.Exec(context.Background(), []storage.Query{
+			{Pattern: `create table "test" (id integer primary key, name text)`},
+			{Pattern: `create table "log" (id integer, action text)`},
+			{Pattern: `create trigger "test_insert" after insert on "test" begin ` +
+				`insert into "log" values (new.id, 'insert; ' || case when new.name is null then 'null' else new.name end); ` +
+				`update "log" set action = upper(action) where id = new.id; end`},
+			{Pattern: `insert into "test" values (1, 'a')`},
+		})
+		So(err, ShouldBeNil)
+
+		// build the dump as sql statements in the same layout of client.Dump
+		var dump strings.Builder
+		dump.WriteString("BEGIN TRANSACTION;\n")
+		_, _, schemas, err := src.Query(context.Background(), []storage.Query{
+			{Pattern: `select "sql" from sqlite_master where "sql" is not null ` +
+				`order by case "type" when 'table' then 0 else 1 end, "name"`},
+		})
+		So(err, ShouldBeNil)
+		So(schemas, ShouldHaveLength, 3)
+		for _, s := range schemas {
+			dump.WriteString(fmt.Sprintf("%s;\n", s[0]))
+		}
+		dump.WriteString("COMMIT;\n")
+
+		stmts, err := collectStatements(dump.String())
+		So(err, ShouldBeNil)
+		So(stmts, ShouldHaveLength, 3)
+
+		dst, err := storage.New(filepath.Join(dir, "dst.db"))
+		So(err, ShouldBeNil)
+		defer dst.Close()
+		for _, q := range stmts {
+			_, err = dst.Exec(context.Background(), []storage.Query{{Pattern: q}})
+			So(err, ShouldBeNil)
+		}
+
+		// the restored trigger is identical and fires on insert
+		_, _, restored, err := dst.Query(context.Background(), []storage.Query{
+			{Pattern: `select "sql" from sqlite_master where "type" = 'trigger'`},
+		})
+		So(err, ShouldBeNil)
+		So(restored, ShouldHaveLength, 1)
+		So(restored[0][0], ShouldResemble, schemas[2][0])
+		_, err = dst.Exec(context.Background(), []storage.Query{
+			{Pattern: `insert into "test" values (2, 'b')`},
+		})
+		So(err, ShouldBeNil)
+		_, _, logs, err := dst.Query(context.Background(), []storage.Query{
+			{Pattern: `select id, action from "log"`},
+		})
+		So(err, ShouldBeNil)
+		So(logs, ShouldHaveLength, 1)
+		So(logs[0][0], ShouldEqual, 2)
+		So(logs[0][1], ShouldResemble, []byte("INSERT; B"))
+	})
+}
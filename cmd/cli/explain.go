@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+)
+
+// explainPlanRegex matches a single line EXPLAIN QUERY PLAN statement, which is rendered as tree.
+var explainPlanRegex = regexp.MustCompile(`(?is)^\s*explain\s+query\s+plan\s+.*;\s*$`)
+
+// planNode defines a step of query plan.
+type planNode struct {
+	id       int64
+	detail   string
+	children []*planNode
+}
+
+// explainPlan runs the EXPLAIN QUERY PLAN statement and prints the plan as tree like sqlite shell.
+func explainPlan(s *shell, qstr string) (err error) {
+	if s.h.URL() == nil {
+		return errNotConnected
+	}
+
+	var root *planNode
+	if root, err = queryPlan(s.h.DB(), strings.TrimSuffix(strings.TrimSpace(qstr), ";")); err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("QUERY PLAN\n")
+	renderPlan(&buf, root.children, "")
+	_, err = s.Stdout().Write(buf.Bytes())
+	return
+}
+
+func queryPlan(db drivers.DB, qstr string) (root *planNode, err error) {
+	rows, err := db.Query(qstr)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var columns []string
+	if columns, err = rows.Columns(); err != nil {
+		return
+	}
+	if len(columns) != 4 {
+		err = fmt.Errorf("unexpected query plan columns %v", columns)
+		return
+	}
+	// sqlite 3.24 reports (id, parent, notused, detail), earlier versions report
+	// (selectid, order, from, detail) which is rendered flat
+	nested := columns[0] == "id" && columns[1] == "parent"
+
+	root = &planNode{}
+	nodes := map[int64]*planNode{0: root}
+	for rows.Next() {
+		var id, parent, notUsed int64
+		var detail string
+		if err = rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return
+		}
+		n := &planNode{id: id, detail: detail}
+		p := root
+		if nested {
+			if pn, ok := nodes[parent]; ok {
+				p = pn
+			}
+			nodes[id] = n
+		}
+		p.children = append(p.children, n)
+	}
+	err = rows.Err()
+	return
+}
+
+func renderPlan(w io.Writer, nodes []*planNode, indent string) {
+	for i, n := range nodes {
+		branch, next := "|--", "|  "
+		if i == len(nodes)-1 {
+			branch, next = "`--", "   "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, n.detail)
+		renderPlan(w, n.children, indent+next)
+	}
+}
@@ -0,0 +1,380 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/stmt"
+)
+
+const (
+	outputFormatTable    = "table"
+	outputFormatJSON     = "json"
+	outputFormatTSV      = "tsv"
+	outputFormatVertical = "vertical"
+
+	// tsvNull represents NULL in tsv output, same as the text format of COPY.
+	tsvNull = `\N`
+)
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "format",
+		usage: `\format [table|json|tsv|vertical]`,
+		desc:  "show or set output format of query results",
+		run:   formatCommand,
+	})
+}
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case outputFormatTable, outputFormatJSON, outputFormatTSV, outputFormatVertical:
+		return true
+	default:
+		return false
+	}
+}
+
+func formatCommand(s *shell, args []string, _ string) (err error) {
+	switch len(args) {
+	case 0:
+		fmt.Fprintf(s.Stdout(), "output format is %s", s.format)
+		fmt.Fprintln(s.Stdout())
+	case 1:
+		format := strings.ToLower(strings.TrimSuffix(args[0], ";"))
+		if !validOutputFormat(format) {
+			return errInvalidShellArgs
+		}
+		s.format = format
+	default:
+		return errInvalidShellArgs
+	}
+	return
+}
+
+// formatQuery consumes lines of queries when output format is not table, lines are buffered
+// until the statement is terminated by semicolon and then executed by execFormatted. Query
+// terminated by \G instead of semicolon is always displayed vertically.
+func (s *shell) formatQuery(line string) bool {
+	if s.h == nil || s.h.URL() == nil {
+		return false
+	}
+
+	trimmed := strings.TrimSpace(line)
+	if strings.HasSuffix(trimmed, `\G`) && (trimmed == `\G` || !strings.HasPrefix(trimmed, `\`)) {
+		// take over the lines buffered by usql handler
+		if buf := s.h.Buf(); buf.Len != 0 {
+			s.pending = append(s.pending, buf.String())
+			buf.Reset(nil)
+		}
+		s.pending = append(s.pending, strings.TrimSuffix(trimmed, `\G`))
+		s.flushAs(outputFormatVertical)
+		return true
+	}
+
+	if s.format == outputFormatTable {
+		return false
+	}
+
+	if len(s.pending) == 0 {
+		if strings.HasPrefix(trimmed, `\`) {
+			return false
+		}
+		_, _, isQuery, err := drivers.Process(s.h.URL(), stmt.FindPrefix(line), line)
+		if err != nil || !isQuery {
+			return false
+		}
+	}
+
+	s.pending = append(s.pending, line)
+	if strings.HasSuffix(trimmed, ";") {
+		s.flush()
+	}
+	return true
+}
+
+// flush executes the buffered query, it's called at the end of input to run the statement
+// which is not terminated by semicolon.
+func (s *shell) flush() {
+	s.flushAs(s.format)
+}
+
+func (s *shell) flushAs(format string) {
+	if len(s.pending) == 0 {
+		return
+	}
+	qstr := strings.Join(s.pending, "\n")
+	s.pending = nil
+	if strings.TrimSpace(qstr) == "" {
+		return
+	}
+
+	if err := s.execFormatted(qstr, format); err != nil {
+		fmt.Fprintf(s.Stderr(), "error: %v", err)
+		fmt.Fprintln(s.Stderr())
+	}
+}
+
+func (s *shell) execFormatted(qstr string, format string) (err error) {
+	if s.h.URL() == nil {
+		return errNotConnected
+	}
+
+	qstr = strings.TrimSuffix(strings.TrimSpace(qstr), ";")
+	if _, qstr, _, err = drivers.Process(s.h.URL(), stmt.FindPrefix(qstr), qstr); err != nil {
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.h.DB().Query(qstr); err != nil {
+		return
+	}
+	defer rows.Close()
+	s.last = qstr
+
+	w := bufio.NewWriter(s.Stdout())
+	switch format {
+	case outputFormatJSON:
+		err = encodeJSON(w, rows)
+	case outputFormatTSV:
+		err = encodeTSV(w, rows)
+	case outputFormatVertical:
+		err = encodeVertical(w, rows)
+	default:
+		err = errInvalidShellArgs
+	}
+	if err != nil {
+		return
+	}
+	return w.Flush()
+}
+
+// scanColumns reads the column names and reports whether each column is declared as blob.
+func scanColumns(rows *sql.Rows) (columns []string, isBlob []bool, err error) {
+	if columns, err = rows.Columns(); err != nil {
+		return
+	}
+	var columnTypes []*sql.ColumnType
+	if columnTypes, err = rows.ColumnTypes(); err != nil {
+		return
+	}
+	isBlob = make([]bool, len(columns))
+	for i, ct := range columnTypes {
+		isBlob[i] = strings.Contains(strings.ToUpper(ct.DatabaseTypeName()), "BLOB")
+	}
+	return
+}
+
+// eachRow scans rows into values and calls fn for each row.
+func eachRow(rows *sql.Rows, count int, fn func(values []interface{}) error) (err error) {
+	values := make([]interface{}, count)
+	dest := make([]interface{}, count)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return
+		}
+		if err = fn(values); err != nil {
+			return
+		}
+	}
+	return rows.Err()
+}
+
+// encodeJSON writes the rows as json array of objects, NULL is written as null and blob is
+// written as tagged object {"$blob": "BASE64"}.
+func encodeJSON(w io.Writer, rows *sql.Rows) (err error) {
+	var columns []string
+	var isBlob []bool
+	if columns, isBlob, err = scanColumns(rows); err != nil {
+		return
+	}
+
+	keys := make([][]byte, len(columns))
+	for i, c := range columns {
+		if keys[i], err = json.Marshal(c); err != nil {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	var count int
+	buf.WriteString("[")
+	err = eachRow(rows, len(columns), func(values []interface{}) (err error) {
+		if count > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for i, v := range values {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.Write(keys[i])
+			buf.WriteString(": ")
+			if err = writeJSONValue(&buf, v, isBlob[i]); err != nil {
+				return
+			}
+		}
+		buf.WriteString("}")
+		count++
+		_, err = w.Write(buf.Bytes())
+		buf.Reset()
+		return
+	})
+	if err != nil {
+		return
+	}
+	if count > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}, isBlob bool) (err error) {
+	var data []byte
+	switch x := v.(type) {
+	case nil:
+		buf.WriteString("null")
+		return
+	case []byte:
+		if isBlob || !utf8.Valid(x) {
+			buf.WriteString(`{"$blob": "`)
+			buf.WriteString(base64.StdEncoding.EncodeToString(x))
+			buf.WriteString(`"}`)
+			return
+		}
+		data, err = json.Marshal(string(x))
+	case time.Time:
+		data, err = json.Marshal(x.Format(time.RFC3339Nano))
+	default:
+		data, err = json.Marshal(x)
+	}
+	if err != nil {
+		return
+	}
+	buf.Write(data)
+	return
+}
+
+// encodeTSV writes the rows as tab separated values with a header line, NULL is written as \N,
+// blob is written as \x prefixed hex string, and backslash, tab and newline are escaped.
+func encodeTSV(w io.Writer, rows *sql.Rows) (err error) {
+	var columns []string
+	var isBlob []bool
+	if columns, isBlob, err = scanColumns(rows); err != nil {
+		return
+	}
+
+	record := make([]string, len(columns))
+	for i, c := range columns {
+		record[i] = escapeTSV(c)
+	}
+	if _, err = io.WriteString(w, strings.Join(record, "\t")+"\n"); err != nil {
+		return
+	}
+
+	return eachRow(rows, len(columns), func(values []interface{}) (err error) {
+		for i, v := range values {
+			record[i] = formatTSVValue(v, isBlob[i])
+		}
+		_, err = io.WriteString(w, strings.Join(record, "\t")+"\n")
+		return
+	})
+}
+
+func formatTSVValue(v interface{}, isBlob bool) string {
+	switch x := v.(type) {
+	case nil:
+		return tsvNull
+	case []byte:
+		if isBlob || !utf8.Valid(x) {
+			return `\x` + hex.EncodeToString(x)
+		}
+		return escapeTSV(string(x))
+	case string:
+		return escapeTSV(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	default:
+		return escapeTSV(fmt.Sprint(x))
+	}
+}
+
+// encodeVertical writes each row as a block of "column: value" lines, which is readable for
+// results wider than terminal.
+func encodeVertical(w io.Writer, rows *sql.Rows) (err error) {
+	var columns []string
+	if columns, _, err = scanColumns(rows); err != nil {
+		return
+	}
+
+	var width int
+	for _, c := range columns {
+		if n := utf8.RuneCountInString(c); n > width {
+			width = n
+		}
+	}
+
+	var count int
+	var buf bytes.Buffer
+	err = eachRow(rows, len(columns), func(values []interface{}) (err error) {
+		count++
+		fmt.Fprintf(&buf, "%s %d. row %s\n", verticalSeparator, count, verticalSeparator)
+		for i, v := range values {
+			buf.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(columns[i])))
+			buf.WriteString(columns[i])
+			buf.WriteString(": ")
+			buf.WriteString(formatCSVValue(v, "NULL"))
+			buf.WriteString("\n")
+		}
+		_, err = w.Write(buf.Bytes())
+		buf.Reset()
+		return
+	})
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "(%d rows)\n", count)
+	return
+}
+
+var verticalSeparator = strings.Repeat("*", 27)
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func escapeTSV(s string) string {
+	return tsvEscaper.Replace(s)
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	pt "github.com/CovenantSQL/CovenantSQL/blockproducer/types"
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	aclDatabase string
+	aclUser     string
+	aclPerm     string
+
+	errMissingUser = errors.New("user address is required")
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "grant",
+		usage: "grant -database ID -to ADDRESS -perm read[,write|,admin]",
+		desc:  "grant permission on database to account and wait for confirmation",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&aclDatabase, "database", "", "database id or dsn to grant permission on")
+			fs.StringVar(&aclUser, "to", "", "account address to grant permission to")
+			fs.StringVar(&aclPerm, "perm", "read", "permission to grant, combination of read, write and admin")
+		},
+		run: runGrant,
+	})
+	registerSubCommand(&subCommand{
+		name:  "revoke",
+		usage: "revoke -database ID -from ADDRESS",
+		desc:  "revoke all permissions on database from account and wait for confirmation",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&aclDatabase, "database", "", "database id or dsn to revoke permission on")
+			fs.StringVar(&aclUser, "from", "", "account address to revoke permission from")
+		},
+		run: runRevoke,
+	})
+	registerSubCommand(&subCommand{
+		name:  "acl",
+		usage: "acl -database ID",
+		desc:  "list users and permissions of database",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&aclDatabase, "database", "", "database id or dsn to list permissions of")
+		},
+		run: runACL,
+	})
+}
+
+// parsePermission parses comma separated permissions, the highest one is returned as write
+// implies read and admin implies both.
+func parsePermission(s string) (perm client.Permission, err error) {
+	var level int
+	for _, p := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(p)) {
+		case "read":
+			if level < 1 {
+				perm, level = client.PermissionRead, 1
+			}
+		case "write":
+			if level < 2 {
+				perm, level = client.PermissionWrite, 2
+			}
+		case "admin":
+			perm, level = client.PermissionAdmin, 3
+		default:
+			err = fmt.Errorf("invalid permission %v", p)
+			return
+		}
+	}
+	return
+}
+
+func permissionString(perm pt.UserPermission) string {
+	switch perm {
+	case pt.Admin:
+		return "admin,write,read"
+	case pt.ReadWrite:
+		return "write,read"
+	case pt.Read:
+		return "read"
+	default:
+		return "unknown"
+	}
+}
+
+func parseAccountAddress(s string) (addr proto.AccountAddress, err error) {
+	if s == "" {
+		err = errMissingUser
+		return
+	}
+	// the checksummed address is accepted as well as the hex one
+	if addr, _, err = utils.Str2Addr(s); err == nil {
+		return
+	}
+	var h *hash.Hash
+	if h, err = hash.NewHashFromStr(s); err != nil {
+		return
+	}
+	addr = proto.AccountAddress(*h)
+	return
+}
+
+// aclArgs parses the database and user address of acl subcommands.
+func aclArgs() (dbID proto.DatabaseID, user proto.AccountAddress, err error) {
+	var cfg *client.Config
+	if cfg, err = databaseDSN(aclDatabase); err != nil {
+		return
+	}
+	dbID = proto.DatabaseID(cfg.DatabaseID)
+	user, err = parseAccountAddress(aclUser)
+	return
+}
+
+func runGrant(_ []string) (err error) {
+	var (
+		dbID proto.DatabaseID
+		user proto.AccountAddress
+		perm client.Permission
+	)
+	if dbID, user, err = aclArgs(); err != nil {
+		return
+	}
+	if perm, err = parsePermission(aclPerm); err != nil {
+		return
+	}
+
+	log.Infof("granting %s on %s to %s, waiting for confirmation", permissionString(pt.UserPermission(perm)),
+		dbID, aclUser)
+	if err = client.GrantAccountPermission(dbID, user, perm); err != nil {
+		return
+	}
+	return printACL(dbID)
+}
+
+func runRevoke(_ []string) (err error) {
+	var (
+		dbID proto.DatabaseID
+		user proto.AccountAddress
+	)
+	if dbID, user, err = aclArgs(); err != nil {
+		return
+	}
+
+	log.Infof("revoking permissions on %s from %s, waiting for confirmation", dbID, aclUser)
+	if err = client.RevokeAccountPermission(dbID, user); err != nil {
+		return
+	}
+	return printACL(dbID)
+}
+
+func runACL(_ []string) (err error) {
+	var cfg *client.Config
+	if cfg, err = databaseDSN(aclDatabase); err != nil {
+		return
+	}
+	return printACL(proto.DatabaseID(cfg.DatabaseID))
+}
+
+func printACL(dbID proto.DatabaseID) (err error) {
+	var users []*client.DatabaseUser
+	if users, err = client.GetDatabaseUsers(dbID); err != nil {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Address\tPermission")
+	for _, u := range users {
+		fmt.Fprintf(w, "%s\t%s\n", hash.Hash(u.Address).String(), permissionString(u.Permission))
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+	fmt.Printf("(%d users)\n", len(users))
+	return
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultHistoryCount defines the entries listed by \history without -n.
+const defaultHistoryCount = 20
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "history",
+		usage: `\history [-n COUNT] [PATTERN] | -exec INDEX`,
+		desc:  "list history matching PATTERN, or execute history entry INDEX again",
+		run:   historyCommand,
+	})
+}
+
+// readHistory reads entries of history file, the empty lines are skipped like readline does.
+func readHistory(path string) (entries []string, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	err = sc.Err()
+	return
+}
+
+func historyCommand(s *shell, args []string, raw string) (err error) {
+	var count, index int
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(s.Stderr())
+	fs.IntVar(&count, "n", defaultHistoryCount, "number of entries to list, 0 for all")
+	fs.IntVar(&index, "exec", 0, "index of history entry to execute")
+
+	var pattern string
+	if pattern, err = parseFlags(fs, args, raw); err != nil {
+		return
+	}
+	if s.historyFile == "" {
+		return fmt.Errorf("history is not available")
+	}
+
+	var entries []string
+	if entries, err = readHistory(s.historyFile); err != nil {
+		return
+	}
+
+	if index != 0 {
+		if pattern != "" || index < 0 || index > len(entries) {
+			return errInvalidShellArgs
+		}
+		// replay the entry as if it's typed again
+		line := entries[index-1]
+		fmt.Fprintln(s.Stdout(), line)
+		s.replay = append(s.replay, line)
+		return
+	}
+	if count < 0 {
+		return errInvalidShellArgs
+	}
+
+	pattern = strings.ToLower(pattern)
+	var matched []int
+	for i, e := range entries {
+		if strings.Contains(strings.ToLower(e), pattern) {
+			matched = append(matched, i)
+		}
+	}
+	if count > 0 && len(matched) > count {
+		matched = matched[len(matched)-count:]
+	}
+
+	w := s.Stdout()
+	for _, i := range matched {
+		fmt.Fprintf(w, "%5d  %s", i+1, entries[i])
+		fmt.Fprintln(w)
+	}
+
+	return
+}
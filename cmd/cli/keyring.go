@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"syscall"
+
+	"github.com/CovenantSQL/CovenantSQL/conf"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// keyringService is the service name of master key passphrases saved in OS keyring, the
+// passphrases are saved per private key file.
+const keyringService = "covenantsql"
+
+var (
+	// errKeyringNotFound indicates there is no passphrase saved for the private key.
+	errKeyringNotFound = errors.New("passphrase not found in keyring")
+	// errKeyringNotSupported indicates OS keyring is not available on this platform.
+	errKeyringNotSupported = errors.New("keyring is not supported on this platform")
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:   "keyring",
+		usage:  "keyring save|delete",
+		desc:   "save master key passphrase of private key to OS keyring, or delete it",
+		run:    runKeyring,
+		noInit: true,
+	})
+}
+
+// keyringAccount returns the absolute path of private key of config, which is the account of
+// passphrase saved in keyring.
+func keyringAccount() (account string, err error) {
+	var cfg *conf.Config
+	if cfg, err = conf.LoadConfigProfile(configFile, profile); err != nil {
+		return
+	}
+	return filepath.Abs(filepath.Join(cfg.WorkingRoot, cfg.PrivateKeyFile))
+}
+
+// loadKeyringPassword reads master key passphrase of private key from OS keyring.
+func loadKeyringPassword() (passphrase string, err error) {
+	var account string
+	if account, err = keyringAccount(); err != nil {
+		return
+	}
+	return keyringGet(keyringService, account)
+}
+
+func runKeyring(args []string) (err error) {
+	if len(args) != 1 {
+		return flag.ErrHelp
+	}
+
+	var account string
+	if account, err = keyringAccount(); err != nil {
+		return
+	}
+
+	switch args[0] {
+	case "save":
+		passphrase := password
+		if passphrase == "" {
+			fmt.Print("Type in Master key to save: ")
+			var input []byte
+			input, err = terminal.ReadPassword(int(syscall.Stdin))
+			fmt.Println("")
+			if err != nil {
+				return
+			}
+			passphrase = string(input)
+		}
+		// make sure the passphrase is correct before saving
+		if _, err = kms.LoadPrivateKey(account, []byte(passphrase)); err != nil {
+			return
+		}
+		if err = keyringSet(keyringService, account, passphrase); err != nil {
+			return
+		}
+		fmt.Printf("passphrase of %s saved to keyring\n", account)
+	case "delete":
+		if err = keyringDelete(keyringService, account); err != nil {
+			return
+		}
+		fmt.Printf("passphrase of %s deleted from keyring\n", account)
+	default:
+		return flag.ErrHelp
+	}
+	return
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// errSecItemNotFound is the exit status of security command if the item is not found.
+const errSecItemNotFound = 44
+
+func isItemNotFound(err error) bool {
+	if e, ok := err.(*exec.ExitError); ok {
+		if ws, ok := e.Sys().(syscall.WaitStatus); ok {
+			return ws.ExitStatus() == errSecItemNotFound
+		}
+	}
+	return false
+}
+
+// keyringGet reads the generic password from macOS Keychain.
+func keyringGet(service, account string) (secret string, err error) {
+	var out []byte
+	if out, err = exec.Command("security", "find-generic-password",
+		"-s", service, "-a", account, "-w").Output(); err != nil {
+		if isItemNotFound(err) {
+			err = errKeyringNotFound
+		}
+		return
+	}
+	secret = strings.TrimSuffix(string(out), "\n")
+	return
+}
+
+// quoteSecurityArg quotes the argument for the interactive mode of security command.
+func quoteSecurityArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// keyringSet saves the generic password to macOS Keychain, the existing one is updated. The
+// command is fed to the interactive mode of security through stdin with the password hex
+// encoded, so the password never appears in the command line of any process.
+func keyringSet(service, account, secret string) (err error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		quoteSecurityArg(service), quoteSecurityArg(account), hex.EncodeToString([]byte(secret))))
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return
+	}
+	// the interactive mode exits successfully even if the command fails
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		err = errors.New(msg)
+	}
+	return
+}
+
+// keyringDelete deletes the generic password from macOS Keychain.
+func keyringDelete(service, account string) (err error) {
+	if err = exec.Command("security", "delete-generic-password",
+		"-s", service, "-a", account).Run(); err != nil {
+		if isItemNotFound(err) {
+			err = errKeyringNotFound
+		}
+	}
+	return
+}
@@ -0,0 +1,61 @@
+// +build !darwin,!windows
+
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// keyringGet reads the secret from Secret Service by secret-tool of libsecret.
+func keyringGet(service, account string) (secret string, err error) {
+	if _, err = exec.LookPath("secret-tool"); err != nil {
+		err = errKeyringNotSupported
+		return
+	}
+	var out []byte
+	if out, err = exec.Command("secret-tool", "lookup",
+		"service", service, "account", account).Output(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// secret-tool exits with 1 if nothing is found
+			err = errKeyringNotFound
+		}
+		return
+	}
+	secret = strings.TrimSuffix(string(out), "\n")
+	return
+}
+
+// keyringSet saves the secret to Secret Service, the secret is passed through stdin.
+func keyringSet(service, account, secret string) (err error) {
+	if _, err = exec.LookPath("secret-tool"); err != nil {
+		return errKeyringNotSupported
+	}
+	cmd := exec.Command("secret-tool", "store", "--label", service+" "+account,
+		"service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	return cmd.Run()
+}
+
+// keyringDelete deletes the secret from Secret Service.
+func keyringDelete(service, account string) (err error) {
+	if _, err = exec.LookPath("secret-tool"); err != nil {
+		return errKeyringNotSupported
+	}
+	return exec.Command("secret-tool", "clear", "service", service, "account", account).Run()
+}
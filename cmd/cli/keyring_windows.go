@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure of Windows Credential Manager.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func credentialTarget(service, account string) (*uint16, error) {
+	return windows.UTF16PtrFromString(service + ":" + account)
+}
+
+// keyringGet reads the generic credential from Windows Credential Manager.
+func keyringGet(service, account string) (secret string, err error) {
+	var target *uint16
+	if target, err = credentialTarget(service, account); err != nil {
+		return
+	}
+	var cred *credential
+	r, _, e := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0,
+		uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if e == windows.ERROR_NOT_FOUND {
+			err = errKeyringNotFound
+		} else {
+			err = e
+		}
+		return
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+	secret = string(blob)
+	return
+}
+
+// keyringSet saves the generic credential to Windows Credential Manager.
+func keyringSet(service, account, secret string) (err error) {
+	cred := &credential{
+		Type:               credTypeGeneric,
+		CredentialBlobSize: uint32(len(secret)),
+		Persist:            credPersistLocalMachine,
+	}
+	if cred.TargetName, err = credentialTarget(service, account); err != nil {
+		return
+	}
+	if cred.UserName, err = windows.UTF16PtrFromString(account); err != nil {
+		return
+	}
+	blob := []byte(secret)
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, e := procCredWriteW.Call(uintptr(unsafe.Pointer(cred)), 0); r == 0 {
+		err = e
+	}
+	return
+}
+
+// keyringDelete deletes the generic credential from Windows Credential Manager.
+func keyringDelete(service, account string) (err error) {
+	var target *uint16
+	if target, err = credentialTarget(service, account); err != nil {
+		return
+	}
+	if r, _, e := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		if e == windows.ERROR_NOT_FOUND {
+			err = errKeyringNotFound
+		} else {
+			err = e
+		}
+	}
+	return
+}
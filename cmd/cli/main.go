@@ -19,7 +19,6 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -48,14 +47,25 @@ var (
 	outFile           string
 	noRC              bool
 	configFile        string
+	profile           string
 	password          string
+	useKeyring        bool
 	singleTransaction bool
 	variables         varsFlag
+	outputFormat      string
+	usePager          bool
+	historyFile       string
+	onError           string
+	reportFile        string
 
 	// DML variables
 	createDB string // as a instance meta json string or simply a node count
 	dropDB   string // database id to drop
 
+	// declarative provisioning variables
+	applyFile string // database spec file to converge
+	planOnly  bool   // only print the plan of apply
+
 	// regex for special sql statement
 	descTableRegex       = regexp.MustCompile("(?i)^desc(?:ribe)?\\s+(\\w+)\\s*;?\\s*$")
 	showCreateTableRegex = regexp.MustCompile("(?i)^show\\s+create\\s+table\\s+(\\w+)\\s*;\\s*?$")
@@ -144,7 +154,11 @@ func init() {
 			}
 			return s, nil
 		},
-		RowsAffected: func(sql.Result) (int64, error) {
+		RowsAffected: func(res sql.Result) (int64, error) {
+			// affected rows is not available for queries in transaction
+			if n, err := res.RowsAffected(); err == nil {
+				return n, nil
+			}
 			return 0, nil
 		},
 		Open: func(url *dburl.URL) (func(string, string) (*sql.DB, error), error) {
@@ -205,16 +219,28 @@ func init() {
 	flag.StringVar(&dsn, "dsn", "", "database url")
 	flag.StringVar(&command, "command", "", "run only single command (SQL or usql internal command) and exit")
 	flag.StringVar(&fileName, "file", "", "execute commands from file and exit")
+	flag.StringVar(&fileName, "f", "", "shorthand of -file")
+	flag.StringVar(&onError, "on-error", onErrorStop, "behavior on statement failure of -file: stop or continue")
+	flag.StringVar(&reportFile, "report", "", "write json lines result of each statement of -file to report file")
 	flag.BoolVar(&noRC, "no-rc", false, "do not read start up file")
 	flag.StringVar(&outFile, "out", "", "output file")
 	flag.StringVar(&configFile, "config", "config.yaml", "config file for covenantsql")
+	flag.StringVar(&profile, "profile", "", "profile of config to use (default the profile selected by config)")
 	flag.StringVar(&password, "password", "", "master key password for covenantsql")
+	flag.BoolVar(&useKeyring, "keyring", false, "read master key password from OS keyring if -password is not set")
 	flag.BoolVar(&singleTransaction, "single-transaction", false, "execute as a single transaction (if non-interactive)")
 	flag.Var(&variables, "variable", "set variable")
+	flag.StringVar(&historyFile, "history", "", "history file of interactive shell (default ~/.covenantsql_history)")
+	flag.StringVar(&outputFormat, "format", outputFormatTable, "output format of query results: table, json, tsv or vertical")
+	flag.BoolVar(&usePager, "pager", true, "page results longer or wider than terminal through $PAGER in interactive shell")
 
 	// DML flags
 	flag.StringVar(&createDB, "create", "", "create database, argument can be instance requirement json or simply a node count requirement")
 	flag.StringVar(&dropDB, "drop", "", "drop database, argument should be a database id (without covenantsql:// scheme is acceptable)")
+
+	// declarative provisioning flags
+	flag.StringVar(&applyFile, "apply", "", "converge database to the yaml spec file, creating database, tables, applying migrations and granting permissions")
+	flag.BoolVar(&planOnly, "plan", false, "print the plan of -apply without changing anything")
 }
 
 func main() {
@@ -222,10 +248,39 @@ func main() {
 
 	var err error
 
+	if useKeyring && password == "" {
+		if password, err = loadKeyringPassword(); err == errKeyringNotFound {
+			log.Warning("master key password not found in keyring, save it by keyring subcommand")
+		} else if err != nil {
+			log.Warningf("read master key password from keyring failed: %v", err)
+		}
+	}
+
 	// init covenantsql driver
-	if err = client.Init(configFile, []byte(password)); err != nil {
-		log.Errorf("init covenantsql client failed: %v", err)
-		os.Exit(-1)
+	if needInit(flag.Args()) {
+		if err = client.InitProfile(configFile, profile, []byte(password)); err != nil {
+			log.Errorf("init covenantsql client failed: %v", err)
+			os.Exit(-1)
+			return
+		}
+	}
+
+	if ok, err := runSubCommand(flag.Args()); ok {
+		if err != nil {
+			log.Errorf("%v failed: %v", flag.Arg(0), err)
+			os.Exit(-1)
+		}
+		return
+	}
+
+	if applyFile != "" {
+		// converge database to spec
+		if err = runApply(applyFile, planOnly); err != nil {
+			log.Errorf("apply %v failed: %v", applyFile, err)
+			os.Exit(-1)
+			return
+		}
+
 		return
 	}
 
@@ -252,19 +307,11 @@ func main() {
 	if createDB != "" {
 		// create database
 		// parse instance requirement
-		var meta client.ResourceMeta
-
-		if err := json.Unmarshal([]byte(createDB), &meta); err != nil {
-			// not a instance json, try if it is a number describing node count
-			nodeCnt, err := strconv.ParseUint(createDB, 10, 16)
-			if err != nil {
-				// still failing
-				log.Errorf("create database failed: %v is not a valid instance description", createDB)
-				os.Exit(-1)
-				return
-			}
-
-			meta = client.ResourceMeta{Node: uint16(nodeCnt)}
+		meta, err := parseResourceMeta(createDB)
+		if err != nil {
+			log.Errorf("create database failed: %v", err)
+			os.Exit(-1)
+			return
 		}
 
 		dsn, err := client.Create(meta)
@@ -303,6 +350,10 @@ func main() {
 }
 
 func run(u *user.User) (err error) {
+	if !validOutputFormat(outputFormat) {
+		return fmt.Errorf("invalid output format %v", outputFormat)
+	}
+
 	// get working directory
 	wd, err := os.Getwd()
 	if err != nil {
@@ -319,15 +370,21 @@ func run(u *user.User) (err error) {
 	}
 
 	// create input/output
+	if historyFile == "" {
+		historyFile = env.HistoryFile(u)
+	}
 	interactive := command != "" || fileName != ""
-	l, err := rline.New(interactive, outFile, env.HistoryFile(u))
+	l, err := rline.New(interactive, outFile, historyFile)
 	if err != nil {
 		return err
 	}
 	defer l.Close()
 
-	// create handler
-	h := handler.New(l, u, wd, true)
+	// create handler, covenantsql shell commands are handled before usql
+	s := newShell(l)
+	s.historyFile = historyFile
+	h := handler.New(s, u, wd, true)
+	s.h = h
 
 	// open dsn
 	if err = h.Open(dsn); err != nil {
@@ -351,7 +408,10 @@ func run(u *user.User) (err error) {
 		}
 	}
 
-	if command != "" {
+	if command != "" && s.dispatch(command) {
+		// covenantsql shell command
+		s.flush()
+	} else if command != "" {
 		// one liner command
 		h.SetSingleLineMode(true)
 		h.Reset([]rune(command))
@@ -362,11 +422,16 @@ func run(u *user.User) (err error) {
 		}
 	} else if fileName != "" {
 		// file
-		if err = h.Include(fileName, false); err != nil {
+		var code int
+		if code, err = runScript(s, fileName, onError, reportFile); err != nil {
 			log.Errorf("run file failed: %v", err)
 			os.Exit(-1)
 			return
 		}
+		if code != scriptCodeOK {
+			os.Exit(code)
+			return
+		}
 	} else {
 		// interactive
 		if err = h.Run(); err != nil {
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// defaultPager is used if $PAGER is not set, -S chops long lines to scroll wide tables
+// horizontally instead of wrapping them.
+const defaultPager = "less -S"
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "pager",
+		usage: `\pager [on|off]`,
+		desc:  "show or toggle paging of results longer or wider than terminal",
+		run:   pagerCommand,
+	})
+}
+
+// pagedOutput buffers output of a statement when enabled, the buffered output is written
+// through pager by page if it doesn't fit the terminal.
+type pagedOutput struct {
+	out     io.Writer
+	buf     bytes.Buffer
+	enabled bool
+}
+
+func newPagedOutput(out io.Writer, enabled bool) *pagedOutput {
+	// paging only makes sense on terminal
+	if f, ok := out.(*os.File); !ok || !terminal.IsTerminal(int(f.Fd())) {
+		enabled = false
+	}
+	return &pagedOutput{out: out, enabled: enabled}
+}
+
+// Write implements io.Writer.Write.
+func (p *pagedOutput) Write(data []byte) (int, error) {
+	if !p.enabled {
+		return p.out.Write(data)
+	}
+	return p.buf.Write(data)
+}
+
+// page writes the buffered output to terminal directly or through pager.
+func (p *pagedOutput) page() (err error) {
+	if p.buf.Len() == 0 {
+		return
+	}
+	defer p.buf.Reset()
+
+	if p.fits() {
+		_, err = p.out.Write(p.buf.Bytes())
+		return
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+
+	// interrupt is handled by pager
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = bytes.NewReader(p.buf.Bytes())
+	cmd.Stdout = p.out
+	cmd.Stderr = os.Stderr
+	if cmd.Run() != nil {
+		// pager is not available, write directly
+		_, err = p.out.Write(p.buf.Bytes())
+	}
+	return
+}
+
+// fits reports whether the buffered output fits the terminal.
+func (p *pagedOutput) fits() bool {
+	f, ok := p.out.(*os.File)
+	if !ok {
+		return true
+	}
+	width, height, err := terminal.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return true
+	}
+
+	lines := strings.Split(strings.TrimSuffix(p.buf.String(), "\n"), "\n")
+	// leave a line for prompt
+	if len(lines) >= height {
+		return false
+	}
+	for _, l := range lines {
+		if utf8.RuneCountInString(l) > width {
+			return false
+		}
+	}
+	return true
+}
+
+func pagerCommand(s *shell, args []string, _ string) (err error) {
+	switch len(args) {
+	case 0:
+		state := "off"
+		if s.out.enabled {
+			state = "on"
+		}
+		fmt.Fprintf(s.Stdout(), "pager is %s", state)
+		fmt.Fprintln(s.Stdout())
+	case 1:
+		switch strings.ToLower(strings.TrimSuffix(args[0], ";")) {
+		case "on":
+			s.out.enabled = true
+		case "off":
+			s.out.enabled = false
+			err = s.out.page()
+		default:
+			return errInvalidShellArgs
+		}
+	default:
+		return errInvalidShellArgs
+	}
+	return
+}
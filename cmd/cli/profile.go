@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/CovenantSQL/CovenantSQL/conf"
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:   "profile",
+		usage:  "profile [list | use NAME]",
+		desc:   "list profiles of config or select the profile used by default",
+		run:    runProfile,
+		noInit: true,
+	})
+}
+
+// writeConfig saves config to path, the file mode of existing config is kept.
+func writeConfig(path string, cfg *conf.Config) (err error) {
+	var fi os.FileInfo
+	if fi, err = os.Stat(path); err != nil {
+		return
+	}
+	var content []byte
+	if content, err = yaml.Marshal(cfg); err != nil {
+		return
+	}
+	return ioutil.WriteFile(path, content, fi.Mode())
+}
+
+func runProfile(args []string) (err error) {
+	var cfg *conf.Config
+	if cfg, err = conf.LoadConfig(configFile); err != nil {
+		return
+	}
+
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "list"):
+		current := cfg.Profile
+		if profile != "" {
+			current = profile
+		}
+		names := make([]string, 0, len(cfg.Profiles))
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			mark := " "
+			if name == current {
+				mark = "*"
+			}
+			fmt.Printf("%s %s\n", mark, name)
+		}
+		return
+	case len(args) == 2 && args[0] == "use":
+		if _, err = cfg.WithProfile(args[1]); err != nil {
+			return
+		}
+		cfg.Profile = args[1]
+		if err = writeConfig(configFile, cfg); err != nil {
+			return
+		}
+		fmt.Printf("using profile %s\n", args[1])
+		return
+	default:
+		return flag.ErrHelp
+	}
+}
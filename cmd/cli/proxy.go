@@ -0,0 +1,300 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/gorilla/mux"
+)
+
+const proxyTimeout = time.Minute
+
+var (
+	proxyListen   string
+	proxyDatabase string
+	proxyToken    string
+	proxyCertFile string
+	proxyKeyFile  string
+
+	errMissingQuery  = errors.New("query is not specified")
+	errUnauthorized  = errors.New("unauthorized")
+	errProxyInsecure = errors.New("listening on non-loopback address requires -token and tls")
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "proxy",
+		usage: "proxy [-listen ADDR] [-database ID] [-token TOKEN] [-tls-cert FILE -tls-key FILE]",
+		desc:  "serve databases on local http endpoint of adapter protocol, requests are signed with local key",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&proxyListen, "listen", "127.0.0.1:4661", "address to listen on")
+			fs.StringVar(&proxyDatabase, "database", "", "default database id or dsn of requests without database")
+			fs.StringVar(&proxyToken, "token", "", "require requests to carry header \"Authorization: Bearer TOKEN\"")
+			fs.StringVar(&proxyCertFile, "tls-cert", "", "certificate file to serve https")
+			fs.StringVar(&proxyKeyFile, "tls-key", "", "private key file of tls certificate")
+		},
+		run: runProxy,
+	})
+}
+
+// proxyRequest defines the request of query and exec api.
+type proxyRequest struct {
+	Database string        `json:"database"`
+	Query    string        `json:"query"`
+	Args     []interface{} `json:"args"`
+}
+
+// proxy serves query and exec requests with database connections opened by the local key.
+type proxy struct {
+	sync.Mutex
+	dbs   map[string]*sql.DB
+	token string
+}
+
+func runProxy(_ []string) (err error) {
+	var host string
+	if host, _, err = net.SplitHostPort(proxyListen); err != nil {
+		return
+	}
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && host != "localhost" {
+		// the key holder's databases would be exposed to network otherwise
+		if proxyToken == "" || proxyCertFile == "" {
+			return errProxyInsecure
+		}
+	}
+	if proxyDatabase != "" {
+		if _, err = databaseDSN(proxyDatabase); err != nil {
+			return
+		}
+	}
+
+	p := &proxy{dbs: make(map[string]*sql.DB), token: proxyToken}
+	defer p.close()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		sendResponse(http.StatusOK, true, nil, nil, rw)
+	}).Methods("GET")
+	v1Router := router.PathPrefix("/v1").Subrouter()
+	v1Router.HandleFunc("/query", p.auth(p.query)).Methods("POST")
+	v1Router.HandleFunc("/exec", p.auth(p.exec)).Methods("POST")
+
+	server := &http.Server{
+		Addr:         proxyListen,
+		WriteTimeout: proxyTimeout,
+		ReadTimeout:  proxyTimeout,
+		IdleTimeout:  proxyTimeout,
+		Handler:      router,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if proxyCertFile != "" {
+			errCh <- server.ListenAndServeTLS(proxyCertFile, proxyKeyFile)
+		} else {
+			errCh <- server.ListenAndServe()
+		}
+	}()
+	log.Infof("proxy is listening on %v", proxyListen)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err = <-errCh:
+		return
+	case <-stop:
+	}
+	log.Info("proxy stopped")
+	return server.Shutdown(context.Background())
+}
+
+func (p *proxy) auth(h http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if p.token != "" && subtle.ConstantTimeCompare(
+			[]byte(r.Header.Get("Authorization")), []byte("Bearer "+p.token)) != 1 {
+			sendResponse(http.StatusUnauthorized, false, errUnauthorized, nil, rw)
+			return
+		}
+		h(rw, r)
+	}
+}
+
+// db returns the connection pool of database, pools are opened on first use and kept until
+// proxy stops.
+func (p *proxy) db(database string) (db *sql.DB, err error) {
+	if database == "" {
+		database = proxyDatabase
+	}
+	var cfg *client.Config
+	if cfg, err = databaseDSN(database); err != nil {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	dsn := cfg.FormatDSN()
+	if db = p.dbs[dsn]; db != nil {
+		return
+	}
+	if db, err = sql.Open("covenantsql", dsn); err != nil {
+		return
+	}
+	p.dbs[dsn] = db
+	return
+}
+
+func (p *proxy) close() {
+	p.Lock()
+	defer p.Unlock()
+	for _, db := range p.dbs {
+		db.Close()
+	}
+}
+
+// parseProxyRequest reads request from json body or form values, args are only supported in json.
+func parseProxyRequest(r *http.Request) (req *proxyRequest, err error) {
+	req = new(proxyRequest)
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/json" {
+		if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+			return
+		}
+	} else {
+		req.Database, req.Query = r.FormValue("database"), r.FormValue("query")
+	}
+	if req.Query == "" {
+		err = errMissingQuery
+	}
+	return
+}
+
+func (p *proxy) query(rw http.ResponseWriter, r *http.Request) {
+	req, err := parseProxyRequest(r)
+	if err != nil {
+		sendResponse(http.StatusBadRequest, false, err, nil, rw)
+		return
+	}
+	db, err := p.db(req.Database)
+	if err != nil {
+		sendResponse(http.StatusBadRequest, false, err, nil, rw)
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), req.Query, req.Args...)
+	if err != nil {
+		sendResponse(http.StatusInternalServerError, false, err, nil, rw)
+		return
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		sendResponse(http.StatusInternalServerError, false, err, nil, rw)
+		return
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		sendResponse(http.StatusInternalServerError, false, err, nil, rw)
+		return
+	}
+	types := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		types[i] = ct.DatabaseTypeName()
+	}
+
+	result := make([][]interface{}, 0)
+	if err = eachRow(rows, len(columns), func(values []interface{}) error {
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			// text is returned as string, binary is base64 encoded by json
+			if b, ok := v.([]byte); ok && utf8.Valid(b) {
+				v = string(b)
+			}
+			row[i] = v
+		}
+		result = append(result, row)
+		return nil
+	}); err != nil {
+		sendResponse(http.StatusInternalServerError, false, err, nil, rw)
+		return
+	}
+
+	sendResponse(http.StatusOK, true, nil, map[string]interface{}{
+		"columns": columns,
+		"types":   types,
+		"rows":    result,
+	}, rw)
+}
+
+func (p *proxy) exec(rw http.ResponseWriter, r *http.Request) {
+	req, err := parseProxyRequest(r)
+	if err != nil {
+		sendResponse(http.StatusBadRequest, false, err, nil, rw)
+		return
+	}
+	db, err := p.db(req.Database)
+	if err != nil {
+		sendResponse(http.StatusBadRequest, false, err, nil, rw)
+		return
+	}
+
+	res, err := db.ExecContext(r.Context(), req.Query, req.Args...)
+	if err != nil {
+		sendResponse(http.StatusInternalServerError, false, err, nil, rw)
+		return
+	}
+	lastInsertID, _ := res.LastInsertId()
+	affectedRows, _ := res.RowsAffected()
+
+	sendResponse(http.StatusOK, true, nil, map[string]interface{}{
+		"last_insert_id": lastInsertID,
+		"affected_rows":  affectedRows,
+	}, rw)
+}
+
+func sendResponse(code int, success bool, msg interface{}, data interface{}, rw http.ResponseWriter) {
+	msgStr := "ok"
+	if msg != nil {
+		msgStr = fmt.Sprint(msg)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(map[string]interface{}{
+		"status":  msgStr,
+		"success": success,
+		"data":    data,
+	})
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/stmt"
+)
+
+const (
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
+
+	// exit codes of statements in script report, also used as exit code of cql.
+	scriptCodeOK      = 0
+	scriptCodeFailed  = 1
+	scriptCodeSkipped = 2
+)
+
+// statementResult defines the machine-readable result of a script statement.
+type statementResult struct {
+	Index     int    `json:"index"`
+	Line      int    `json:"line"`
+	Statement string `json:"statement"`
+	Code      int    `json:"code"`
+	Error     string `json:"error,omitempty"`
+}
+
+// scriptRunner executes statements of script file one by one, results of statements are
+// written to report as json lines.
+type scriptRunner struct {
+	s       *shell
+	stop    bool
+	report  *json.Encoder
+	results []*statementResult
+	lineNo  int
+	failed  bool
+	vars    varSubstituter
+}
+
+// runScript executes the script file, the transaction started by -single-transaction is rolled
+// back if any statement fails. The returned code is non-zero if any statement fails.
+func runScript(s *shell, path string, onError string, reportFile string) (code int, err error) {
+	if onError != onErrorStop && onError != onErrorContinue {
+		err = fmt.Errorf("invalid on-error mode %v", onError)
+		return
+	}
+
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	r := &scriptRunner{s: s, stop: onError == onErrorStop}
+	if reportFile != "" {
+		var rf *os.File
+		if rf, err = os.Create(reportFile); err != nil {
+			return
+		}
+		defer rf.Close()
+		r.report = json.NewEncoder(rf)
+	}
+
+	sc := bufio.NewScanner(f)
+	buf := stmt.New(func() ([]rune, error) {
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		r.lineNo++
+		return []rune(r.vars.substitute(sc.Text(), env.All())), nil
+	}, stmt.AllowMultilineComments(true))
+
+	if err = r.run(buf); err != nil {
+		return
+	}
+
+	if r.failed {
+		code = scriptCodeFailed
+		if singleTransaction {
+			err = s.h.Rollback()
+		}
+	}
+	return
+}
+
+func (r *scriptRunner) run(buf *stmt.Stmt) error {
+	var startLine int
+	for {
+		cmd, params, err := buf.Next()
+		if err == io.EOF {
+			// execute the last statement without semicolon
+			if buf.Len != 0 {
+				r.exec(startLine, buf.Prefix, buf.String())
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if startLine == 0 {
+			startLine = r.lineNo
+		}
+
+		var res metacmd.Result
+		if cmd = strings.TrimPrefix(cmd, `\`); cmd != "" {
+			if r.failed && r.stop {
+				r.record(startLine, `\`+cmd, nil)
+				continue
+			}
+			if res, err = r.command(cmd, params); err != nil {
+				fmt.Fprintf(r.s.Stderr(), "error: line %d: %v", r.lineNo, err)
+				fmt.Fprintln(r.s.Stderr())
+			}
+			r.record(r.lineNo, `\`+cmd, err)
+			if res.Quit {
+				return nil
+			}
+		}
+
+		if buf.Ready() || (res.Exec != metacmd.ExecNone && buf.Len != 0) {
+			prefix, qstr := buf.Prefix, buf.String()
+			buf.Reset(nil)
+			r.exec(startLine, prefix, qstr)
+			startLine = 0
+		} else if buf.Len == 0 {
+			startLine = 0
+		}
+	}
+}
+
+// command runs the covenantsql shell command or usql meta command.
+func (r *scriptRunner) command(cmd string, params []string) (res metacmd.Result, err error) {
+	if c, ok := shellCommands[cmd]; ok {
+		res.Processed = len(params)
+		err = c.run(r.s, params, strings.Join(params, " "))
+		return
+	}
+
+	var runner metacmd.Runner
+	if runner, err = metacmd.Decode(cmd, params); err != nil {
+		return
+	}
+	return runner.Run(r.s.h)
+}
+
+func (r *scriptRunner) exec(line int, prefix, qstr string) {
+	if strings.TrimSpace(qstr) == "" || strings.TrimSpace(qstr) == ";" {
+		return
+	}
+
+	if r.failed && r.stop {
+		r.record(line, qstr, nil)
+		return
+	}
+
+	if r.s.h.URL() == nil {
+		r.record(line, qstr, errNotConnected)
+		return
+	}
+
+	var err error
+	_, _, isQuery, _ := drivers.Process(r.s.h.URL(), prefix, qstr)
+	if isQuery && r.s.format != outputFormatTable {
+		err = r.s.execFormatted(qstr, r.s.format)
+	} else {
+		err = r.s.h.Execute(r.s.Stdout(), metacmd.Result{Exec: metacmd.ExecOnly}, prefix, qstr, false)
+	}
+	if err != nil {
+		fmt.Fprintf(r.s.Stderr(), "error: line %d: %v", line, err)
+		fmt.Fprintln(r.s.Stderr())
+	}
+	r.record(line, qstr, err)
+}
+
+// record reports result of the statement, statement is treated as skipped if it's not executed
+// after a failure in stop mode.
+func (r *scriptRunner) record(line int, qstr string, err error) {
+	res := &statementResult{
+		Index:     len(r.results) + 1,
+		Line:      line,
+		Statement: qstr,
+	}
+	switch {
+	case err != nil:
+		res.Code = scriptCodeFailed
+		res.Error = err.Error()
+		r.failed = true
+	case r.failed && r.stop:
+		res.Code = scriptCodeSkipped
+	default:
+		res.Code = scriptCodeOK
+	}
+	r.results = append(r.results, res)
+
+	if r.report != nil {
+		r.report.Encode(res)
+	}
+}
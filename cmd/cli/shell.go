@@ -0,0 +1,238 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/handler"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/rline"
+	"github.com/xo/usql/text"
+)
+
+var (
+	// errInvalidShellArgs indicates the shell command is called with wrong arguments.
+	errInvalidShellArgs = errors.New("invalid arguments")
+
+	// errNotConnected indicates the shell command requires a connected database.
+	errNotConnected = text.ErrNotConnected
+
+	// schemaChangeRegex matches statements changing schema of database.
+	schemaChangeRegex = regexp.MustCompile(`(?i)^\s*(create|alter|drop)\s`)
+
+	// shellCommands holds the covenantsql specific backslash commands, which are handled
+	// by shell before the line reaches usql handler.
+	shellCommands = make(map[string]*shellCommand)
+)
+
+// shellCommand defines a backslash command of the interactive shell, run is called with the
+// arguments split by spaces and the raw argument text.
+type shellCommand struct {
+	name  string
+	usage string
+	desc  string
+	run   func(s *shell, args []string, raw string) error
+}
+
+func registerShellCommand(c *shellCommand) {
+	shellCommands[c.name] = c
+}
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "d",
+		usage: `\d [TABLE]`,
+		desc:  "list tables, or describe columns and indexes of TABLE",
+		run:   describeCommand,
+	})
+	registerShellCommand(&shellCommand{
+		name:  "help",
+		usage: `\help`,
+		desc:  "show help on covenantsql shell commands",
+		run:   helpCommand,
+	})
+}
+
+// shell wraps the usql input/output, lines of shell commands are executed and consumed,
+// other lines are passed through to usql handler.
+type shell struct {
+	rline.IO
+	h       *handler.Handler
+	out     *pagedOutput
+	schema  *schemaCache
+	format  string
+	pending []string
+	last    string // last query executed by shell instead of usql handler
+	vars    varSubstituter
+
+	// historyFile is the readline history file, replay holds lines of history to execute again.
+	historyFile string
+	replay      []string
+}
+
+func newShell(l rline.IO) (s *shell) {
+	s = &shell{
+		IO:     l,
+		out:    newPagedOutput(l.Stdout(), usePager && l.Interactive()),
+		schema: newSchemaCache(),
+		format: outputFormat,
+	}
+
+	if r, ok := l.(*rline.Rline); ok && l.Interactive() {
+		// replace the default tab completer of readline
+		cfg := *r.Inst.Config
+		cfg.AutoComplete = &shellCompleter{s: s}
+		r.Inst.SetConfig(&cfg)
+	}
+
+	return
+}
+
+// Stdout implements rline.IO.Stdout, output is paged if it doesn't fit the terminal.
+func (s *shell) Stdout() io.Writer {
+	return s.out
+}
+
+// Next implements rline.IO.Next.
+func (s *shell) Next() (line []rune, err error) {
+	for {
+		// output of the previous statement is complete
+		if err = s.out.page(); err != nil {
+			return
+		}
+		if len(s.replay) > 0 {
+			line, s.replay = []rune(s.replay[0]), s.replay[1:]
+		} else if line, err = s.IO.Next(); err != nil {
+			s.flush()
+			s.out.page()
+			return
+		}
+		raw := string(line)
+		line = []rune(s.vars.substitute(raw, env.All()))
+		if !s.dispatch(string(line)) {
+			s.last = ""
+			if schemaChangeRegex.MatchString(string(line)) {
+				s.schema.invalidate()
+			}
+			return
+		}
+		if s.Interactive() {
+			s.Save(raw)
+		}
+	}
+}
+
+// dispatch runs the line if it is a shell command and reports whether the line is consumed.
+func (s *shell) dispatch(line string) bool {
+	if s.formatQuery(line) {
+		return true
+	}
+
+	if explainPlanRegex.MatchString(line) {
+		if err := explainPlan(s, line); err != nil {
+			fmt.Fprintf(s.Stderr(), "error: %v", err)
+			fmt.Fprintln(s.Stderr())
+		}
+		return true
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], `\`) {
+		return false
+	}
+
+	c, ok := shellCommands[strings.TrimPrefix(fields[0], `\`)]
+	if !ok {
+		return false
+	}
+
+	raw := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+	if err := c.run(s, fields[1:], raw); err != nil {
+		fmt.Fprintf(s.Stderr(), "error: %v", err)
+		fmt.Fprintln(s.Stderr())
+	}
+
+	return true
+}
+
+// parseFlags parses leading flags of shell command arguments, the raw text after flags is
+// returned to keep the spacing of statements in arguments.
+func parseFlags(fs *flag.FlagSet, args []string, raw string) (rest string, err error) {
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	rest = raw
+	for _, arg := range args[:len(args)-fs.NArg()] {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, arg))
+	}
+
+	return
+}
+
+// query executes the query and prints the result like queries typed in shell.
+func (s *shell) query(qstr string) error {
+	if s.h.URL() == nil {
+		return errNotConnected
+	}
+	return s.h.Execute(s.Stdout(), metacmd.Result{Exec: metacmd.ExecOnly}, "SELECT", qstr, false)
+}
+
+func describeCommand(s *shell, args []string, _ string) (err error) {
+	switch len(args) {
+	case 0:
+		return s.query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name;`)
+	case 1:
+		table := strings.TrimSuffix(args[0], ";")
+		if !identifierRegex.MatchString(table) {
+			return errInvalidShellArgs
+		}
+		if err = s.query(`PRAGMA table_info("` + table + `");`); err != nil {
+			return
+		}
+		return s.query(`SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = '` +
+			table + `' ORDER BY name;`)
+	default:
+		return errInvalidShellArgs
+	}
+}
+
+func helpCommand(s *shell, _ []string, _ string) (err error) {
+	names := make([]string, 0, len(shellCommands))
+	for name := range shellCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := s.Stdout()
+	fmt.Fprintln(w, "CovenantSQL")
+	for _, name := range names {
+		c := shellCommands[name]
+		fmt.Fprintf(w, "  %-40s %s", c.usage, c.desc)
+		fmt.Fprintln(w)
+	}
+
+	return
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	transferTo     string
+	transferAmount uint64
+	transferToken  string
+	transferFee    uint64
+	transferYes    bool
+
+	errMissingAmount = errors.New("amount should be positive")
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "transfer",
+		usage: "transfer [-yes] -to ADDRESS -amount N [-token stable|covenant] [-fee N]",
+		desc:  "transfer tokens to account and wait until the transaction is packed in block",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&transferTo, "to", "", "account address to transfer tokens to")
+			fs.Uint64Var(&transferAmount, "amount", 0, "amount of tokens to transfer")
+			fs.StringVar(&transferToken, "token", "stable", "type of tokens to transfer, stable or covenant")
+			fs.Uint64Var(&transferFee, "fee", 0, "fee in covenant coins paid to block producer")
+			fs.BoolVar(&transferYes, "yes", false, "transfer without confirmation")
+		},
+		run: runTransfer,
+	})
+}
+
+func parseTokenType(s string) (token client.TokenType, err error) {
+	switch strings.ToLower(s) {
+	case "stable", "stablecoin":
+		token = client.TokenStableCoin
+	case "covenant", "covenantcoin":
+		token = client.TokenCovenantCoin
+	default:
+		err = fmt.Errorf("invalid token type %v", s)
+	}
+	return
+}
+
+func runTransfer(_ []string) (err error) {
+	var (
+		to    proto.AccountAddress
+		token client.TokenType
+	)
+	if to, err = parseAccountAddress(transferTo); err != nil {
+		return
+	}
+	if transferAmount == 0 {
+		return errMissingAmount
+	}
+	if token, err = parseTokenType(transferToken); err != nil {
+		return
+	}
+
+	pubKey, err := kms.GetLocalPublicKey()
+	if err != nil {
+		return
+	}
+	enc, err := pubKey.MarshalHash()
+	if err != nil {
+		return
+	}
+	nonce, err := client.GetNextNonce()
+	if err != nil {
+		return
+	}
+
+	// dry-run the transfer so that an invalid one fails before confirmation
+	var sim *client.TxSimulation
+	if sim, err = client.SimulateTransfer(to, transferAmount, token, transferFee); err != nil {
+		if err == client.ErrTxFailed {
+			err = fmt.Errorf("%v: %s", err, sim.Reason)
+		}
+		return
+	}
+
+	fmt.Printf("From:    %v\n", hash.THashH(enc).String())
+	fmt.Printf("To:      %v\n", hash.Hash(to).String())
+	fmt.Printf("Amount:  %d %v\n", transferAmount, token)
+	fmt.Printf("Fee:     %d %v\n", sim.Fee, client.TokenCovenantCoin)
+	fmt.Printf("Nonce:   %d\n", nonce)
+	fmt.Println()
+
+	if !transferYes {
+		var ok bool
+		if err = newWizard(os.Stdin, os.Stdout).ask("Transfer", "y/N", func(s string) error {
+			switch strings.ToLower(s) {
+			case "y", "yes":
+				ok = true
+			case "n", "no", "y/n":
+				ok = false
+			default:
+				return errors.New("answer y or n")
+			}
+			return nil
+		}); err != nil {
+			return
+		}
+		if !ok {
+			log.Info("transfer cancelled")
+			return
+		}
+	}
+
+	var txHash hash.Hash
+	if txHash, err = client.TransferTokensWithFee(to, transferAmount, token, transferFee); err != nil {
+		return
+	}
+	fmt.Printf("Transaction: %v\n", txHash.String())
+	log.Info("waiting for the transaction to be packed in block")
+
+	var receipt *client.TxReceipt
+	if receipt, err = client.WaitTxConfirmation(context.Background(), txHash); err != nil {
+		if err == client.ErrTxFailed {
+			err = fmt.Errorf("%v: %s", err, receipt.Reason)
+		}
+		return
+	}
+	fmt.Printf("Block height: %d\n", receipt.Height)
+	return
+}
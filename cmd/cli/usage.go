@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var (
+	usageDatabase string
+	usageJSON     bool
+)
+
+func init() {
+	registerSubCommand(&subCommand{
+		name:  "usage",
+		usage: "usage -database ID [-json]",
+		desc:  "show billed query counts, storage, miner incomes and remaining deposit of database",
+		setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&usageDatabase, "database", "", "database id or dsn to inspect")
+			fs.BoolVar(&usageJSON, "json", false, "print usage as json")
+		},
+		run: runUsage,
+	})
+}
+
+// databaseUsage defines the usage report of cql usage.
+type databaseUsage struct {
+	DatabaseID string
+	Billings   uint32
+	LowHeight  int32
+	HighHeight int32
+	ReadCount  uint64
+	WriteCount uint64
+	GasAmount  uint64
+	// StorageBytes defines the storage consumed by database, -1 if it's not available.
+	StorageBytes  int64
+	ReservedSpace uint64
+	Deposit       uint64
+	Incomes       []minerIncome
+}
+
+type minerIncome struct {
+	Address string
+	Fee     uint64
+	Reward  uint64
+}
+
+func runUsage(_ []string) (err error) {
+	var cfg *client.Config
+	if cfg, err = databaseDSN(usageDatabase); err != nil {
+		return
+	}
+	dbID := proto.DatabaseID(cfg.DatabaseID)
+
+	var usage *client.DatabaseUsage
+	if usage, err = client.GetUsage(dbID); err != nil {
+		return
+	}
+	u := &databaseUsage{
+		DatabaseID: cfg.DatabaseID,
+		Billings:   usage.Billings,
+		LowHeight:  usage.LowHeight,
+		HighHeight: usage.HighHeight,
+		ReadCount:  usage.ReadCount,
+		WriteCount: usage.WriteCount,
+		GasAmount:  usage.GasAmount,
+		Deposit:    usage.Deposit,
+		Incomes:    make([]minerIncome, 0, len(usage.Incomes)),
+	}
+	for _, v := range usage.Incomes {
+		u.Incomes = append(u.Incomes, minerIncome{
+			Address: hash.Hash(v.Address).String(),
+			Fee:     v.Fee,
+			Reward:  v.Reward,
+		})
+	}
+	if status, err := client.GetStatus(dbID); err == nil {
+		u.ReservedSpace = status.ResourceMeta.Space
+	}
+	if u.StorageBytes, err = storageBytes(cfg.FormatDSN()); err != nil {
+		log.Warningf("query storage of database %v failed: %v", dbID, err)
+		u.StorageBytes, err = -1, nil
+	}
+
+	if usageJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(u)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Database:\t%s\n", u.DatabaseID)
+	fmt.Fprintf(w, "Billings:\t%d (sqlchain height %d - %d)\n", u.Billings, u.LowHeight, u.HighHeight)
+	fmt.Fprintf(w, "Read queries:\t%d\n", u.ReadCount)
+	fmt.Fprintf(w, "Write queries:\t%d\n", u.WriteCount)
+	fmt.Fprintf(w, "Gas consumed:\t%d\n", u.GasAmount)
+	if u.StorageBytes >= 0 {
+		fmt.Fprintf(w, "Storage used:\t%d bytes\n", u.StorageBytes)
+	} else {
+		fmt.Fprintf(w, "Storage used:\tunknown\n")
+	}
+	fmt.Fprintf(w, "Storage reserved:\t%d bytes\n", u.ReservedSpace)
+	fmt.Fprintf(w, "Remaining deposit:\t%d\n", u.Deposit)
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	if len(u.Incomes) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "Miner\tFee\tReward")
+		for _, v := range u.Incomes {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", v.Address, v.Fee, v.Reward)
+		}
+		err = w.Flush()
+	}
+	return
+}
+
+// storageBytes returns the size of database file served by miners.
+func storageBytes(dsn string) (size int64, err error) {
+	var db *sql.DB
+	if db, err = sql.Open("covenantsql", dsn); err != nil {
+		return
+	}
+	defer db.Close()
+
+	var pageCount, pageSize int64
+	if err = db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return
+	}
+	if err = db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return
+	}
+	size = pageCount * pageSize
+	return
+}
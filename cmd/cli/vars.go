@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/xo/usql/env"
+)
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "set",
+		usage: `\set [NAME [VALUE]]`,
+		desc:  "set variable NAME substituted by :NAME, :'NAME' or :\"NAME\", or list all variables",
+		run:   setCommand,
+	})
+	registerShellCommand(&shellCommand{
+		name:  "unset",
+		usage: `\unset NAME`,
+		desc:  "unset variable NAME",
+		run:   unsetCommand,
+	})
+}
+
+// quoteLiteral quotes s as sql string literal, single quotes in s are doubled and NUL characters
+// are concatenated as char(0), as sqlite stops parsing statement at NUL.
+func quoteLiteral(s string) string {
+	s = "'" + strings.Replace(s, "'", "''", -1) + "'"
+	return strings.Replace(s, "\x00", "'||char(0)||'", -1)
+}
+
+// quoteIdentifier quotes s as sql identifier, double quotes in s are doubled.
+func quoteIdentifier(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// unquoteLiteral reverses quoteLiteral, s is returned as is if it's not quoted.
+func unquoteLiteral(s string) string {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return s
+	}
+	return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+}
+
+func setCommand(s *shell, args []string, raw string) (err error) {
+	if len(args) == 0 {
+		vars := env.All()
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := s.Stdout()
+		for _, name := range names {
+			fmt.Fprintf(w, "%s = %s", name, quoteLiteral(vars[name]))
+			fmt.Fprintln(w)
+		}
+		return
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(raw, args[0]))
+	return env.Set(args[0], unquoteLiteral(value))
+}
+
+func unsetCommand(s *shell, args []string, _ string) (err error) {
+	if len(args) != 1 {
+		return errInvalidShellArgs
+	}
+	return env.Unset(args[0])
+}
+
+// varSubstituter replaces :NAME, :'NAME' and :"NAME" in lines of statements with the value of
+// variable NAME as is, quoted as string literal and quoted as identifier respectively. Variables
+// in string literals, quoted identifiers and comments are left untouched, so are the undefined
+// ones. The quoting state is carried across lines of multi-line statements.
+type varSubstituter struct {
+	quote   rune
+	comment bool
+}
+
+func (v *varSubstituter) substitute(line string, vars map[string]string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), `\`) {
+		return line
+	}
+
+	r := []rune(line)
+	var buf bytes.Buffer
+	for i := 0; i < len(r); i++ {
+		c, next := r[i], rune(0)
+		if i+1 < len(r) {
+			next = r[i+1]
+		}
+
+		switch {
+		case v.comment:
+			if c == '*' && next == '/' {
+				v.comment = false
+				buf.WriteRune(c)
+				c = next
+				i++
+			}
+		case v.quote != 0:
+			if c == v.quote {
+				v.quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			v.quote = c
+		case c == '-' && next == '-':
+			buf.WriteString(string(r[i:]))
+			return buf.String()
+		case c == '/' && next == '*':
+			v.comment = true
+			buf.WriteRune(c)
+			c = next
+			i++
+		case c == ':' && next == ':':
+			// type cast
+			buf.WriteRune(c)
+			c = next
+			i++
+		case c == ':':
+			if name, quote, end := readVarRef(r, i); end > i {
+				if value, ok := vars[name]; ok {
+					switch quote {
+					case '\'':
+						value = quoteLiteral(value)
+					case '"':
+						value = quoteIdentifier(value)
+					}
+					buf.WriteString(value)
+					i = end - 1
+					continue
+				}
+			}
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
+
+// readVarRef reads the variable reference starting at r[i], end is i if there is no reference.
+func readVarRef(r []rune, i int) (name string, quote rune, end int) {
+	start := i + 1
+	if start < len(r) && (r[start] == '\'' || r[start] == '"') {
+		quote = r[start]
+		start++
+	}
+	end = start
+	for end < len(r) && (r[end] == '_' || unicode.IsLetter(r[end]) || unicode.IsNumber(r[end])) {
+		end++
+	}
+	if end == start {
+		return "", 0, i
+	}
+	name = string(r[start:end])
+	if quote != 0 {
+		if end >= len(r) || r[end] != quote {
+			return "", 0, i
+		}
+		end++
+	}
+	return
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuote(t *testing.T) {
+	Convey("test quoting string literals and identifiers", t, func() {
+		for _, c := range []struct {
+			value      string
+			literal    string
+			identifier string
+		}{
+			{"", `''`, `""`},
+			{"abc", `'abc'`, `"abc"`},
+			{"it's", `'it''s'`, `"it's"`},
+			{`a "b"`, `'a "b"'`, `"a ""b"""`},
+			{`'; drop table t; --`, `'''; drop table t; --'`, `"'; drop table t; --"`},
+			{`a\'b`, `'a\''b'`, `"a\'b"`},
+			{`c:\dir\`, `'c:\dir\'`, `"c:\dir\"`},
+			{"a\x00b", `'a'||char(0)||'b'`, "\"a\x00b\""},
+			{"\x00'", `''||char(0)||''''`, "\"\x00'\""},
+		} {
+			So(quoteLiteral(c.value), ShouldEqual, c.literal)
+			So(quoteIdentifier(c.value), ShouldEqual, c.identifier)
+			if c.value != "" && c.literal == "'"+c.value+"'" {
+				So(unquoteLiteral(c.literal), ShouldEqual, c.value)
+			}
+		}
+		So(unquoteLiteral(`'it''s'`), ShouldEqual, "it's")
+		So(unquoteLiteral(`abc`), ShouldEqual, "abc")
+		So(unquoteLiteral(`'`), ShouldEqual, "'")
+	})
+}
+
+func TestVarSubstituter(t *testing.T) {
+	vars := map[string]string{
+		"id":    "1",
+		"name":  "it's",
+		"table": `my "table"`,
+		"path":  `c:\dir\`,
+		"nul":   "a\x00b",
+		"x_1":   "2",
+	}
+	Convey("test substituting variables in a line", t, func() {
+		for _, c := range []struct {
+			line   string
+			expect string
+		}{
+			{`SELECT :id`, `SELECT 1`},
+			{`SELECT :'name'`, `SELECT 'it''s'`},
+			{`SELECT * FROM :"table"`, `SELECT * FROM "my ""table"""`},
+			{`SELECT :'path'`, `SELECT 'c:\dir\'`},
+			{`SELECT :"path"`, `SELECT "c:\dir\"`},
+			{`SELECT :'nul'`, `SELECT 'a'||char(0)||'b'`},
+			{`SELECT :x_1+:id;`, `SELECT 2+1;`},
+			// unknown variables are left untouched
+			{`SELECT :unknown, :'unknown', :"unknown"`, `SELECT :unknown, :'unknown', :"unknown"`},
+			// unterminated or empty references
+			{`SELECT :'name`, `SELECT :'name`},
+			{`SELECT :"name' :id`, `SELECT :"name' :id`},
+			{`SELECT :, :'', :id`, `SELECT :, :'', 1`},
+			// quoted strings, identifiers, comments and casts
+			{`SELECT ':id', ":id", ` + "`:id`", `SELECT ':id', ":id", ` + "`:id`"},
+			{`SELECT 'it''s :id', :id`, `SELECT 'it''s :id', 1`},
+			{`SELECT 'a\' , :id`, `SELECT 'a\' , 1`},
+			{`SELECT :id -- :id`, `SELECT 1 -- :id`},
+			{`SELECT /* :id */ :id`, `SELECT /* :id */ 1`},
+			{`SELECT a::id`, `SELECT a::id`},
+			// shell commands
+			{`\set id :id`, `\set id :id`},
+		} {
+			var v varSubstituter
+			So(v.substitute(c.line, vars), ShouldEqual, c.expect)
+		}
+	})
+	Convey("test substituting variables in multi-line statement", t, func() {
+		var v varSubstituter
+		for _, c := range []struct {
+			line   string
+			expect string
+		}{
+			{`SELECT ':id`, `SELECT ':id`},
+			{`:name', :id /* :id`, `:name', 1 /* :id`},
+			{`:id */ :'name'`, `:id */ 'it''s'`},
+		} {
+			So(v.substitute(c.line, vars), ShouldEqual, c.expect)
+		}
+		So(v.quote, ShouldEqual, 0)
+		So(v.comment, ShouldBeFalse)
+	})
+}
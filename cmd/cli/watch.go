@@ -0,0 +1,202 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/stmt"
+)
+
+const (
+	// defaultWatchInterval defines the interval of \watch without argument.
+	defaultWatchInterval = 2 * time.Second
+
+	ansiClearScreen = "\x1b[H\x1b[2J"
+	ansiHighlight   = "\x1b[7m"
+	ansiReset       = "\x1b[0m"
+)
+
+func init() {
+	registerShellCommand(&shellCommand{
+		name:  "watch",
+		usage: `\watch [SECONDS]`,
+		desc:  "execute last query every SECONDS and highlight changes until interrupted",
+		run:   watchCommand,
+	})
+}
+
+// watchResult holds the formatted cells of a watched query.
+type watchResult struct {
+	columns []string
+	rows    [][]string
+}
+
+func (r *watchResult) cell(row, col int) (string, bool) {
+	if r == nil || row >= len(r.rows) || col >= len(r.rows[row]) {
+		return "", false
+	}
+	return r.rows[row][col], true
+}
+
+func watchCommand(s *shell, args []string, _ string) (err error) {
+	interval := defaultWatchInterval
+	switch len(args) {
+	case 0:
+	case 1:
+		var secs float64
+		if secs, err = strconv.ParseFloat(strings.TrimSuffix(args[0], ";"), 64); err != nil || secs <= 0 {
+			return errInvalidShellArgs
+		}
+		interval = time.Duration(secs * float64(time.Second))
+	default:
+		return errInvalidShellArgs
+	}
+
+	if s.h.URL() == nil {
+		return errNotConnected
+	}
+	qstr := s.last
+	if qstr == "" {
+		qstr = s.h.Last()
+	}
+	if qstr = strings.TrimSuffix(strings.TrimSpace(qstr), ";"); qstr == "" {
+		return fmt.Errorf("no query to watch")
+	}
+	var isQuery bool
+	if _, qstr, isQuery, err = drivers.Process(s.h.URL(), stmt.FindPrefix(qstr), qstr); err != nil {
+		return
+	}
+	if !isQuery {
+		return fmt.Errorf("only query could be watched")
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev *watchResult
+	for {
+		var cur *watchResult
+		if cur, err = watchQuery(s.h.DB(), qstr); err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if s.Interactive() {
+			buf.WriteString(ansiClearScreen)
+		}
+		fmt.Fprintf(&buf, "Every %v: %s    %s\n\n", interval, qstr, time.Now().Format(time.RFC1123))
+		renderWatch(&buf, cur, prev, s.Interactive())
+		// bypass the pager to refresh the screen in place
+		if _, err = s.IO.Stdout().Write(buf.Bytes()); err != nil {
+			return
+		}
+		prev = cur
+
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func watchQuery(db drivers.DB, qstr string) (res *watchResult, err error) {
+	var rows *sql.Rows
+	if rows, err = db.Query(qstr); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	res = &watchResult{}
+	if res.columns, err = rows.Columns(); err != nil {
+		return
+	}
+	err = eachRow(rows, len(res.columns), func(values []interface{}) error {
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = formatCSVValue(v, "NULL")
+		}
+		res.rows = append(res.rows, row)
+		return nil
+	})
+	return
+}
+
+// renderWatch renders result as aligned table, cells different from previous result are
+// highlighted if highlight is enabled.
+func renderWatch(buf *bytes.Buffer, cur, prev *watchResult, highlight bool) {
+	widths := make([]int, len(cur.columns))
+	for i, c := range cur.columns {
+		widths[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range cur.rows {
+		for i, v := range row {
+			if n := utf8.RuneCountInString(v); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	pad := func(v string, width int) string {
+		return v + strings.Repeat(" ", width-utf8.RuneCountInString(v))
+	}
+
+	for i, c := range cur.columns {
+		if i > 0 {
+			buf.WriteString(" | ")
+		}
+		buf.WriteString(pad(c, widths[i]))
+	}
+	buf.WriteString("\n")
+	for i, w := range widths {
+		if i > 0 {
+			buf.WriteString("-+-")
+		}
+		buf.WriteString(strings.Repeat("-", w))
+	}
+	buf.WriteString("\n")
+
+	for r, row := range cur.rows {
+		for i, v := range row {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+			old, ok := prev.cell(r, i)
+			if highlight && prev != nil && (!ok || old != v) {
+				buf.WriteString(ansiHighlight + pad(v, widths[i]) + ansiReset)
+			} else {
+				buf.WriteString(pad(v, widths[i]))
+			}
+		}
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(buf, "(%d rows)\n", len(cur.rows))
+}
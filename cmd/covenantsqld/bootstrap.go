@@ -25,6 +25,7 @@ import (
 	"time"
 
 	bp "github.com/CovenantSQL/CovenantSQL/blockproducer"
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/blockproducer/types"
 	"github.com/CovenantSQL/CovenantSQL/conf"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
@@ -163,11 +164,27 @@ func runNode(nodeID proto.NodeID, listenAddr string) (err error) {
 		2*time.Second,
 		100*time.Millisecond,
 	)
+	for _, v := range conf.GConf.BP.Authorities {
+		chainConfig.Authorities = append(chainConfig.Authorities, proto.AccountAddress(v))
+	}
+	chainConfig.AuthorityThreshold = conf.GConf.BP.AuthorityThreshold
+	chainConfig.MempoolTTL = conf.GConf.BP.MempoolTTL
+	chainConfig.AccountTxRate = conf.GConf.BP.AccountTxRate
+	chainConfig.AccountTxBurst = conf.GConf.BP.AccountTxBurst
+	chainConfig.IPTxRate = conf.GConf.BP.IPTxRate
+	chainConfig.IPTxBurst = conf.GConf.BP.IPTxBurst
+	chainConfig.ChainID = pi.ChainID(conf.GConf.ChainID)
+	chainConfig.LegacyTxDeadline = conf.GConf.BP.LegacyTxDeadline
+	if !archival && !conf.GConf.BP.Archival {
+		chainConfig.KeepBlocks = conf.GConf.BP.KeepBlocks
+	}
 	chain, err := bp.NewChain(chainConfig)
 	if err != nil {
 		log.Errorf("init chain failed: %v", err)
 		return
 	}
+	dbService.Chain = chain
+	chain.OnMinerDeregistered(dbService.ReprovisionNode)
 	chain.Start()
 	defer chain.Stop()
 
@@ -55,6 +55,7 @@ var (
 	showVersion bool
 	configFile  string
 	genKeyPair  bool
+	archival    bool
 
 	clientMode      bool
 	clientOperation string
@@ -67,6 +68,7 @@ func init() {
 	flag.BoolVar(&noLogo, "nologo", false, "Do not print logo")
 	flag.BoolVar(&showVersion, "version", false, "Show version information and exit")
 	flag.StringVar(&configFile, "config", "./config.yaml", "Config file path")
+	flag.BoolVar(&archival, "archival", false, "Keep the full main chain history, KeepBlocks of config is ignored")
 
 	flag.StringVar(&cpuProfile, "cpu-profile", "", "Path to file for CPU profiling information")
 	flag.StringVar(&memProfile, "mem-profile", "", "Path to file for memory profiling information")
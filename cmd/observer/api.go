@@ -18,11 +18,14 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
@@ -35,6 +38,10 @@ import (
 
 var (
 	apiTimeout = time.Second * 10
+
+	// defaultListLimit and maxListLimit defines the page size of listing apis.
+	defaultListLimit = 20
+	maxListLimit     = 100
 )
 
 func sendResponse(code int, success bool, msg interface{}, data interface{}, rw http.ResponseWriter) {
@@ -76,26 +83,9 @@ func (a *explorerAPI) GetAck(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	// format ack to json response
-	sendResponse(200, true, "", map[string]interface{}{
-		"ack": map[string]interface{}{
-			"request": map[string]interface{}{
-				"hash":      ack.Response.Request.HeaderHash.String(),
-				"timestamp": a.formatTime(ack.Response.Request.Timestamp),
-				"node":      ack.Response.Request.NodeID,
-				"type":      ack.Response.Request.QueryType.String(),
-				"count":     ack.Response.Request.BatchCount,
-			},
-			"response": map[string]interface{}{
-				"hash":         ack.Response.HeaderHash.String(),
-				"timestamp":    a.formatTime(ack.Response.Timestamp),
-				"node":         ack.Response.NodeID,
-				"log_position": ack.Response.LogOffset,
-			},
-			"hash":      ack.HeaderHash.String(),
-			"timestamp": a.formatTime(ack.AckHeader.Timestamp),
-			"node":      ack.AckHeader.NodeID,
-		},
-	}, rw)
+	res := a.formatAck(ack)
+	res["ack"].(map[string]interface{})["orphaned"] = a.service.isOrphaned(dbID, &ack.HeaderHash)
+	sendResponse(200, true, "", res, rw)
 }
 
 func (a *explorerAPI) GetRequest(rw http.ResponseWriter, r *http.Request) {
@@ -173,7 +163,9 @@ func (a *explorerAPI) GetBlock(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendResponse(200, true, "", a.formatBlock(height, block), rw)
+	res := a.formatBlock(height, block)
+	res["block"].(map[string]interface{})["orphaned"] = a.service.isOrphaned(dbID, block.BlockHash())
+	sendResponse(200, true, "", res, rw)
 }
 
 func (a *explorerAPI) GetBlockByHeight(rw http.ResponseWriter, r *http.Request) {
@@ -205,7 +197,9 @@ func (a *explorerAPI) GetBlockByHeight(rw http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	sendResponse(200, true, "", a.formatBlock(height, block), rw)
+	res := a.formatBlock(height, block)
+	res["block"].(map[string]interface{})["orphaned"] = a.service.isOrphaned(dbID, block.BlockHash())
+	sendResponse(200, true, "", res, rw)
 }
 
 func (a *explorerAPI) getHighestBlock(rw http.ResponseWriter, r *http.Request) {
@@ -226,7 +220,634 @@ func (a *explorerAPI) getHighestBlock(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendResponse(200, true, "", a.formatBlock(height, block), rw)
+	res := a.formatBlock(height, block)
+	res["block"].(map[string]interface{})["orphaned"] = a.service.isOrphaned(dbID, block.BlockHash())
+	sendResponse(200, true, "", res, rw)
+}
+
+// ListBlocks lists block headers of database page by page, see getListOptions for parameters.
+func (a *explorerAPI) ListBlocks(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	opts, err := a.getListOptions(r)
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	heights, blocks, next, err := a.service.listBlocks(dbID, opts)
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	result := make([]interface{}, 0, len(blocks))
+	for i, b := range blocks {
+		result = append(result, a.formatBlockHeader(heights[i], b)["block"])
+	}
+	sendResponse(200, true, "", map[string]interface{}{
+		"blocks": result,
+		"next":   a.formatCursor(next),
+	}, rw)
+}
+
+// ListAcks lists acked queries of database page by page, see getListOptions for parameters.
+func (a *explorerAPI) ListAcks(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	opts, err := a.getListOptions(r)
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	acks, next, err := a.service.listAcks(dbID, opts)
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	sendResponse(200, true, "", a.formatAckList(acks, next), rw)
+}
+
+// SearchQueries lists acked queries matching exactly one of table, digest or requester, write
+// queries are indexed by tables and statement digests while all queries are indexed by requester
+// address. Other parameters are same as ListAcks.
+func (a *explorerAPI) SearchQueries(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	opts, err := a.getListOptions(r)
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	var bucket []byte
+	var term string
+	q := r.URL.Query()
+	for _, f := range []struct {
+		name   string
+		bucket []byte
+	}{
+		{"table", queryTableBucket},
+		{"digest", queryDigestBucket},
+		{"requester", queryRequesterBucket},
+	} {
+		if v := q.Get(f.name); v != "" {
+			if bucket != nil {
+				bucket = nil
+				break
+			}
+			bucket, term = f.bucket, v
+		}
+	}
+	if bucket == nil {
+		sendResponse(400, false, "exactly one of table, digest or requester is required", nil, rw)
+		return
+	}
+	if q.Get("table") != "" {
+		// table names are indexed in lower case
+		term = strings.ToLower(term)
+	}
+
+	acks, next, err := a.service.searchAcks(dbID, bucket, term, opts)
+	if err == ErrNotFound {
+		acks, next, err = nil, nil, nil
+	}
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	sendResponse(200, true, "", a.formatAckList(acks, next), rw)
+}
+
+// Export streams blocks or acked queries of database as csv or parquet file, data is "blocks" or
+// "queries" and format is "csv" by default or "parquet". The first column of each row is the
+// cursor to resume exporting after the row, and limit restricts the number of rows.
+func (a *explorerAPI) Export(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	q := r.URL.Query()
+	data := q.Get("data")
+	if data == "" {
+		data = exportBlocks
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+	var cursor []byte
+	if c := q.Get("cursor"); c != "" {
+		if cursor, err = hex.DecodeString(c); err != nil {
+			sendResponse(400, false, "invalid cursor", nil, rw)
+			return
+		}
+	}
+	var limit int
+	if l := q.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			sendResponse(400, false, "invalid limit", nil, rw)
+			return
+		}
+	}
+
+	// rows are streamed in both formats, the response header is sent before the first write
+	var started bool
+	out := &lazyWriter{w: rw, init: func() {
+		started = true
+		if format == exportFormatParquet {
+			rw.Header().Set("Content-Type", "application/octet-stream")
+			rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.parquet"`, dbID, data))
+		} else {
+			rw.Header().Set("Content-Type", "text/csv")
+		}
+		rw.WriteHeader(http.StatusOK)
+	}}
+	w, err := newExportWriter(out, data, format, true)
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	_, _, err = a.service.export(dbID, data, cursor, limit, w)
+	if err == nil {
+		err = w.close()
+	}
+	if err != nil {
+		if !started {
+			if err == ErrNotFound {
+				sendResponse(404, false, err, nil, rw)
+			} else {
+				sendResponse(500, false, err, nil, rw)
+			}
+		} else {
+			// part of response is written
+			log.Warningf("export %v of database %v failed: %v", data, dbID, err)
+		}
+	}
+}
+
+// lazyWriter calls init before the first write.
+type lazyWriter struct {
+	w    io.Writer
+	init func()
+}
+
+func (l *lazyWriter) Write(p []byte) (int, error) {
+	if l.init != nil {
+		l.init()
+		l.init = nil
+	}
+	return l.w.Write(p)
+}
+
+// ListReorgs lists chain reorganizations of database, see getListOptions for parameters.
+func (a *explorerAPI) ListReorgs(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	opts, err := a.getListOptions(r)
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	reorgs, next, err := a.service.listReorgs(dbID, opts)
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	result := make([]interface{}, 0, len(reorgs))
+	for _, reorg := range reorgs {
+		result = append(result, a.formatReorg(reorg)["reorg"])
+	}
+	sendResponse(200, true, "", map[string]interface{}{
+		"reorgs": result,
+		"next":   a.formatCursor(next),
+	}, rw)
+}
+
+// CheckConsistency cross checks blocks of all miners of database and the observed blocks, the
+// from and to parameters specify the height range, which defaults to the latest 10 blocks.
+func (a *explorerAPI) CheckConsistency(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	q := r.URL.Query()
+	var from, to int32
+	if rawTo := q.Get("to"); rawTo != "" {
+		var h int64
+		if h, err = strconv.ParseInt(rawTo, 10, 32); err != nil || h < 0 {
+			sendResponse(400, false, "invalid to height", nil, rw)
+			return
+		}
+		to = int32(h)
+	} else if to, _, err = a.service.getHighestBlock(dbID); err == ErrNotFound {
+		sendResponse(400, false, err, nil, rw)
+		return
+	} else if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+	if from = to - defaultCheckBlocks + 1; from < 0 {
+		from = 0
+	}
+	if rawFrom := q.Get("from"); rawFrom != "" {
+		var h int64
+		if h, err = strconv.ParseInt(rawFrom, 10, 32); err != nil || h < 0 {
+			sendResponse(400, false, "invalid from height", nil, rw)
+			return
+		}
+		from = int32(h)
+	}
+	if from > to || to-from >= maxCheckBlocks {
+		sendResponse(400, false, fmt.Sprintf("height range should contain 1 to %d blocks", maxCheckBlocks), nil, rw)
+		return
+	}
+
+	report, err := a.service.checkConsistency(dbID, from, to)
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	sendResponse(200, true, "", a.formatConsistencyReport(report), rw)
+}
+
+// ListConsistencyReports lists saved consistency reports of database, see getListOptions for
+// parameters.
+func (a *explorerAPI) ListConsistencyReports(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	opts, err := a.getListOptions(r)
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	reports, next, err := a.service.listConsistencyReports(dbID, opts)
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	result := make([]interface{}, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, a.formatConsistencyReport(report)["report"])
+	}
+	sendResponse(200, true, "", map[string]interface{}{
+		"reports": result,
+		"next":    a.formatCursor(next),
+	}, rw)
+}
+
+// ListSubscriptions lists subscribed databases with the sync status.
+func (a *explorerAPI) ListSubscriptions(rw http.ResponseWriter, r *http.Request) {
+	subscriptions, err := a.service.listSubscriptions()
+	if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	result := make([]interface{}, 0, len(subscriptions))
+	for _, st := range subscriptions {
+		result = append(result, a.formatSubscription(st))
+	}
+	sendResponse(200, true, "", map[string]interface{}{
+		"subscriptions": result,
+	}, rw)
+}
+
+// SubscribeDatabase subscribes the database at runtime, the from parameter resets the subscription
+// to "oldest" or "newest" block, existing subscription is kept if from is not specified.
+func (a *explorerAPI) SubscribeDatabase(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	switch from {
+	case "", "oldest", "newest":
+	default:
+		sendResponse(400, false, "from should be oldest or newest", nil, rw)
+		return
+	}
+
+	if err = a.service.subscribe(dbID, from); err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	sendResponse(200, true, "", nil, rw)
+}
+
+// UnsubscribeDatabase cancels the subscription of database, the observed data is kept.
+func (a *explorerAPI) UnsubscribeDatabase(rw http.ResponseWriter, r *http.Request) {
+	dbID, err := a.getDBID(mux.Vars(r))
+	if err != nil {
+		sendResponse(400, false, err, nil, rw)
+		return
+	}
+
+	if err = a.service.unsubscribe(dbID); err == ErrNotFound {
+		sendResponse(404, false, err, nil, rw)
+		return
+	} else if err != nil {
+		sendResponse(500, false, err, nil, rw)
+		return
+	}
+
+	sendResponse(200, true, "", nil, rw)
+}
+
+// getListOptions parses the listing parameters: cursor is the "next" value of previous page,
+// limit is the page size which is 20 by default and 100 at most, since and until specify the time
+// range in RFC 3339 or unix milliseconds like the timestamps in results, order is "desc" for
+// newest first by default or "asc", and type filters acked queries by Read or Write.
+func (a *explorerAPI) getListOptions(r *http.Request) (opts *listOptions, err error) {
+	q := r.URL.Query()
+	opts = &listOptions{limit: defaultListLimit, desc: true}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		if opts.cursor, err = hex.DecodeString(cursor); err != nil {
+			err = errors.New("invalid cursor")
+			return
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		if opts.limit, err = strconv.Atoi(limit); err != nil || opts.limit <= 0 || opts.limit > maxListLimit {
+			err = fmt.Errorf("limit should be in [1, %d]", maxListLimit)
+			return
+		}
+	}
+	if opts.since, err = a.parseTime(q.Get("since")); err != nil {
+		return
+	}
+	if opts.until, err = a.parseTime(q.Get("until")); err != nil {
+		return
+	}
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.desc = false
+	default:
+		err = errors.New("order should be asc or desc")
+		return
+	}
+	switch t := q.Get("type"); t {
+	case "":
+	case wt.ReadQuery.String(), wt.WriteQuery.String():
+		qt := wt.ReadQuery
+		if t == wt.WriteQuery.String() {
+			qt = wt.WriteQuery
+		}
+		opts.queryType = &qt
+	default:
+		err = fmt.Errorf("invalid query type %v", t)
+	}
+	return
+}
+
+func (a *explorerAPI) parseTime(s string) (t time.Time, err error) {
+	if s == "" {
+		return
+	}
+	if ms, perr := strconv.ParseFloat(s, 64); perr == nil {
+		t = time.Unix(0, int64(ms*1e6))
+		return
+	}
+	if t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+		err = fmt.Errorf("invalid time %v", s)
+	}
+	return
+}
+
+func (a *explorerAPI) formatCursor(cursor []byte) string {
+	return hex.EncodeToString(cursor)
+}
+
+// Subscribe upgrades the request to websocket and pushes events of databases specified by the
+// "db" query parameters, events of all databases are pushed if none is specified. Each message
+// is a json object with "type" of block, ack or request, "database" and the entity formatted as
+// the corresponding rest api, blocks are pushed with headers only. The connection is closed if
+// the client falls behind.
+func (a *explorerAPI) Subscribe(rw http.ResponseWriter, r *http.Request) {
+	var dbs []proto.DatabaseID
+	for _, db := range r.URL.Query()["db"] {
+		dbs = append(dbs, proto.DatabaseID(db))
+	}
+
+	conn, err := upgradeWebSocket(rw, r)
+	if err == errNotWebSocket {
+		sendResponse(400, false, err, nil, rw)
+		return
+	} else if err != nil {
+		log.WithError(err).Warning("upgrade websocket failed")
+		return
+	}
+
+	l := a.service.events.listen(dbs)
+	defer a.service.events.unlisten(l)
+	go conn.serve()
+	defer conn.close()
+
+	for {
+		select {
+		case <-conn.closed:
+			return
+		case e, ok := <-l.ch:
+			if !ok {
+				if l.dropped {
+					log.Warning("websocket subscriber falls behind, connection closed")
+				}
+				return
+			}
+			if err = conn.writeJSON(a.formatEvent(e)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (a *explorerAPI) formatEvent(e *observerEvent) (msg map[string]interface{}) {
+	switch e.Type {
+	case eventBlock:
+		msg = a.formatBlockHeader(e.Height, e.Block)
+	case eventAck:
+		msg = a.formatAck(e.Ack)
+	case eventRequest:
+		msg = a.formatRequestSummary(e.Request, e.Offset)
+	case eventReorg:
+		msg = a.formatReorg(e.Reorg)
+	default:
+		msg = make(map[string]interface{})
+	}
+	msg["type"] = e.Type
+	msg["database"] = e.DatabaseID
+	return
+}
+
+func (a *explorerAPI) formatReorg(r *reorgRecord) map[string]interface{} {
+	orphaned := make([]string, 0, len(r.Orphaned))
+	for _, h := range r.Orphaned {
+		orphaned = append(orphaned, h.String())
+	}
+	affected := make([]string, 0, len(r.AffectedQueries))
+	for _, h := range r.AffectedQueries {
+		affected = append(affected, h.String())
+	}
+
+	return map[string]interface{}{
+		"reorg": map[string]interface{}{
+			"timestamp":        a.formatTime(r.Time),
+			"fork_height":      r.ForkHeight,
+			"old_head":         r.OldHead.String(),
+			"old_height":       r.OldHeight,
+			"new_head":         r.NewHead.String(),
+			"new_height":       r.NewHeight,
+			"orphaned_blocks":  orphaned,
+			"affected_queries": affected,
+		},
+	}
+}
+
+func (a *explorerAPI) formatConsistencyReport(r *consistencyReport) map[string]interface{} {
+	divergences := make([]interface{}, 0, len(r.Divergences))
+	for _, d := range r.Divergences {
+		divergences = append(divergences, map[string]interface{}{
+			"height":   d.Height,
+			"source":   d.Source,
+			"kind":     d.Kind,
+			"expected": d.Expected,
+			"actual":   d.Actual,
+		})
+	}
+
+	return map[string]interface{}{
+		"report": map[string]interface{}{
+			"timestamp":   a.formatTime(r.Time),
+			"from":        r.From,
+			"to":          r.To,
+			"sources":     r.Sources,
+			"consistent":  len(r.Divergences) == 0,
+			"divergences": divergences,
+		},
+	}
+}
+
+func (a *explorerAPI) formatBlockHeader(height int32, b *ct.Block) map[string]interface{} {
+	return map[string]interface{}{
+		"block": map[string]interface{}{
+			"height":       height,
+			"hash":         b.BlockHash().String(),
+			"genesis_hash": b.GenesisHash().String(),
+			"parent_hash":  b.ParentHash().String(),
+			"timestamp":    a.formatTime(b.Timestamp()),
+			"version":      b.SignedHeader.Version,
+			"producer":     b.Producer(),
+			"count":        len(b.Queries),
+		},
+	}
+}
+
+func (a *explorerAPI) formatRequestSummary(req *wt.Request, offset uint64) map[string]interface{} {
+	patterns := make([]string, 0, len(req.Payload.Queries))
+	for _, q := range req.Payload.Queries {
+		patterns = append(patterns, q.Pattern)
+	}
+
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"hash":         req.Header.HeaderHash.String(),
+			"timestamp":    a.formatTime(req.Header.Timestamp),
+			"node":         req.Header.NodeID,
+			"type":         req.Header.QueryType.String(),
+			"count":        req.Header.BatchCount,
+			"log_position": offset,
+			"patterns":     patterns,
+		},
+	}
+}
+
+func (a *explorerAPI) formatSubscription(st *subscriptionStatus) map[string]interface{} {
+	var blockTime interface{}
+	if !st.BlockTime.IsZero() {
+		blockTime = a.formatTime(st.BlockTime)
+	}
+	return map[string]interface{}{
+		"database":        st.DatabaseID,
+		"position":        st.Position,
+		"height":          st.Height,
+		"timestamp":       blockTime,
+		"expected_height": st.ExpectedHeight,
+		"lag":             st.Lag(),
+	}
+}
+
+func (a *explorerAPI) formatAckList(acks []*wt.SignedAckHeader, next []byte) map[string]interface{} {
+	result := make([]interface{}, 0, len(acks))
+	for _, ack := range acks {
+		result = append(result, a.formatAck(ack)["ack"])
+	}
+	return map[string]interface{}{
+		"acks": result,
+		"next": a.formatCursor(next),
+	}
+}
+
+func (a *explorerAPI) formatAck(ack *wt.SignedAckHeader) map[string]interface{} {
+	requester, _ := ackRequester(ack)
+	return map[string]interface{}{
+		"ack": map[string]interface{}{
+			"request": map[string]interface{}{
+				"hash":      ack.Response.Request.HeaderHash.String(),
+				"timestamp": a.formatTime(ack.Response.Request.Timestamp),
+				"node":      ack.Response.Request.NodeID,
+				"type":      ack.Response.Request.QueryType.String(),
+				"count":     ack.Response.Request.BatchCount,
+				"requester": requester,
+			},
+			"response": map[string]interface{}{
+				"hash":         ack.Response.HeaderHash.String(),
+				"timestamp":    a.formatTime(ack.Response.Timestamp),
+				"node":         ack.Response.NodeID,
+				"log_position": ack.Response.LogOffset,
+			},
+			"hash":      ack.HeaderHash.String(),
+			"timestamp": a.formatTime(ack.AckHeader.Timestamp),
+			"node":      ack.AckHeader.NodeID,
+		},
+	}
 }
 
 func (a *explorerAPI) formatBlock(height int32, b *ct.Block) map[string]interface{} {
@@ -266,6 +887,8 @@ func (a *explorerAPI) formatRequest(req *wt.Request) map[string]interface{} {
 		queries = append(queries, map[string]interface{}{
 			"pattern": q.Pattern,
 			"args":    args,
+			"digest":  queryDigest(q.Pattern),
+			"tables":  queryTables(q.Pattern),
 		})
 	}
 
@@ -317,6 +940,17 @@ func startAPI(service *Service, listenAddr string) (server *http.Server, err err
 	v1Router.HandleFunc("/block/{db}/{hash}", api.GetBlock).Methods("GET")
 	v1Router.HandleFunc("/height/{db}/{height:[0-9]+}", api.GetBlockByHeight).Methods("GET")
 	v1Router.HandleFunc("/head/{db}", api.getHighestBlock).Methods("GET")
+	v1Router.HandleFunc("/blocks/{db}", api.ListBlocks).Methods("GET")
+	v1Router.HandleFunc("/acks/{db}", api.ListAcks).Methods("GET")
+	v1Router.HandleFunc("/queries/{db}", api.SearchQueries).Methods("GET")
+	v1Router.HandleFunc("/reorgs/{db}", api.ListReorgs).Methods("GET")
+	v1Router.HandleFunc("/export/{db}", api.Export).Methods("GET")
+	v1Router.HandleFunc("/consistency/{db}", api.ListConsistencyReports).Methods("GET")
+	v1Router.HandleFunc("/consistency/{db}", api.CheckConsistency).Methods("POST")
+	v1Router.HandleFunc("/subscriptions", api.ListSubscriptions).Methods("GET")
+	v1Router.HandleFunc("/subscriptions/{db}", api.SubscribeDatabase).Methods("POST", "PUT")
+	v1Router.HandleFunc("/subscriptions/{db}", api.UnsubscribeDatabase).Methods("DELETE")
+	v1Router.HandleFunc("/subscribe", api.Subscribe).Methods("GET")
 
 	server = &http.Server{
 		Addr:         listenAddr,
@@ -0,0 +1,300 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	"github.com/CovenantSQL/CovenantSQL/sqlchain"
+	ct "github.com/CovenantSQL/CovenantSQL/sqlchain/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/coreos/bbolt"
+)
+
+const (
+	// observerSource names the blocks observed locally in consistency reports.
+	observerSource = "observer"
+
+	// kinds of divergences
+	divergenceUnavailable = "unavailable" // failed to fetch block from the miner
+	divergenceMissing     = "missing"     // block exists on other replicas only
+	divergenceInvalid     = "invalid"     // block fails verification
+	divergenceHash        = "hash"        // block hash differs from the majority with same queries
+	divergenceQueries     = "queries"     // block hash and queries differ from the majority
+
+	// defaultCheckBlocks and maxCheckBlocks defines the blocks checked at once.
+	defaultCheckBlocks = 10
+	maxCheckBlocks     = 100
+)
+
+// divergence defines a block of a replica not matching the majority of replicas.
+type divergence struct {
+	Height   int32
+	Source   string // node id of miner or observerSource
+	Kind     string
+	Expected string // majority block hash
+	Actual   string // block hash or error of the source
+}
+
+// consistencyReport defines the result of cross checking blocks of replicas in [From, To].
+type consistencyReport struct {
+	DatabaseID  proto.DatabaseID
+	From        int32
+	To          int32
+	Sources     []string
+	Time        time.Time
+	Divergences []*divergence
+}
+
+// checkConsistency fetches blocks in height range from all miners of database, and compares
+// them with each other and the locally observed blocks, the report is saved for listing.
+func (s *Service) checkConsistency(dbID proto.DatabaseID, from, to int32) (report *consistencyReport, err error) {
+	instance, err := s.getUpstream(dbID)
+	if err != nil {
+		return
+	}
+
+	report = &consistencyReport{
+		DatabaseID: dbID,
+		From:       from,
+		To:         to,
+		Time:       time.Now(),
+	}
+	var nodes []proto.NodeID
+	if instance.Peers != nil {
+		for _, server := range instance.Peers.Servers {
+			if server != nil {
+				nodes = append(nodes, server.ID)
+				report.Sources = append(report.Sources, string(server.ID))
+			}
+		}
+	}
+	report.Sources = append(report.Sources, observerSource)
+
+	for h := from; h <= to; h++ {
+		blocks := make(map[string]*ct.Block)
+		for _, node := range nodes {
+			req := &sqlchain.MuxFetchBlockReq{}
+			resp := &sqlchain.MuxFetchBlockResp{}
+			req.DatabaseID = dbID
+			req.Height = h
+			if err = s.caller.CallNode(node, route.SQLCFetchBlock.String(), req, resp); err != nil {
+				report.Divergences = append(report.Divergences, &divergence{
+					Height: h,
+					Source: string(node),
+					Kind:   divergenceUnavailable,
+					Actual: err.Error(),
+				})
+				err = nil
+				continue
+			}
+			blocks[string(node)] = resp.Block
+		}
+		var local *ct.Block
+		if local, err = s.getBlockByHeight(dbID, h); err == nil {
+			blocks[observerSource] = local
+		} else if err != ErrNotFound {
+			return
+		}
+		err = nil
+
+		report.Divergences = append(report.Divergences, compareBlocks(h, blocks)...)
+	}
+
+	if err = s.db.Update(func(tx *bolt.Tx) (err error) {
+		cb, err := tx.Bucket(consistencyBucket).CreateBucketIfNotExists([]byte(dbID))
+		if err != nil {
+			return
+		}
+		reportBytes, err := utils.EncodeMsgPack(report)
+		if err != nil {
+			return
+		}
+		return cb.Put(timeToBytes(report.Time), reportBytes.Bytes())
+	}); err != nil {
+		return
+	}
+
+	if len(report.Divergences) > 0 {
+		log.Warningf("found %d divergences of database %v in blocks [%d, %d]",
+			len(report.Divergences), dbID, from, to)
+	}
+	return
+}
+
+// compareBlocks compares blocks of sources at the same height, the block hash held by most
+// sources is expected, the locally observed block wins a tie.
+func compareBlocks(height int32, blocks map[string]*ct.Block) (result []*divergence) {
+	sources := make([]string, 0, len(blocks))
+	for source := range blocks {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	counts := make(map[hash.Hash]int)
+	byHash := make(map[hash.Hash]*ct.Block)
+	for _, source := range sources {
+		b := blocks[source]
+		if b == nil {
+			continue
+		}
+		if err := b.Verify(); err != nil {
+			result = append(result, &divergence{
+				Height: height,
+				Source: source,
+				Kind:   divergenceInvalid,
+				Actual: err.Error(),
+			})
+			delete(blocks, source)
+			continue
+		}
+		h := *b.BlockHash()
+		counts[h]++
+		if _, ok := byHash[h]; !ok || source == observerSource {
+			byHash[h] = b
+		}
+	}
+	if len(counts) == 0 {
+		// not produced yet
+		return
+	}
+
+	var expected hash.Hash
+	var max int
+	for h, n := range counts {
+		local := blocks[observerSource]
+		if n > max || (n == max && local != nil && local.BlockHash().IsEqual(&h)) {
+			expected, max = h, n
+		}
+	}
+	expectedQueries := make(map[hash.Hash]bool)
+	for _, q := range byHash[expected].Queries {
+		if q != nil {
+			expectedQueries[*q] = true
+		}
+	}
+
+	for _, source := range sources {
+		b, ok := blocks[source]
+		if !ok {
+			// invalid block
+			continue
+		}
+		d := &divergence{
+			Height:   height,
+			Source:   source,
+			Expected: expected.String(),
+		}
+		switch {
+		case b == nil:
+			d.Kind = divergenceMissing
+		case b.BlockHash().IsEqual(&expected):
+			// queries are covered by the verified merkle root
+			continue
+		case sameQueries(b, expectedQueries):
+			d.Kind = divergenceHash
+			d.Actual = b.BlockHash().String()
+		default:
+			d.Kind = divergenceQueries
+			d.Actual = b.BlockHash().String()
+		}
+		result = append(result, d)
+	}
+	return
+}
+
+func sameQueries(b *ct.Block, expected map[hash.Hash]bool) bool {
+	seen := make(map[hash.Hash]bool)
+	for _, q := range b.Queries {
+		if q == nil || !expected[*q] {
+			return false
+		}
+		seen[*q] = true
+	}
+	return len(seen) == len(expected)
+}
+
+// startConsistencyCheck checks the latest blocks of subscribed databases periodically until the
+// service stops.
+func (s *Service) startConsistencyCheck(interval time.Duration, blocks int) {
+	if interval <= 0 || blocks <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+
+			subscriptions, err := s.listSubscriptions()
+			if err != nil {
+				log.Warningf("list subscriptions failed: %v", err)
+				continue
+			}
+			for _, st := range subscriptions {
+				if st.Height < 0 {
+					continue
+				}
+				from := st.Height - int32(blocks) + 1
+				if from < 0 {
+					from = 0
+				}
+				if _, err = s.checkConsistency(st.DatabaseID, from, st.Height); err != nil {
+					log.Warningf("check consistency of database %v failed: %v", st.DatabaseID, err)
+				}
+			}
+		}
+	}()
+}
+
+// listConsistencyReports lists saved consistency reports of database by time.
+func (s *Service) listConsistencyReports(dbID proto.DatabaseID, opts *listOptions) (
+	reports []*consistencyReport, next []byte, err error) {
+	var low, high []byte
+	if !opts.since.IsZero() {
+		low = timeToBytes(opts.since)
+	}
+	if !opts.until.IsZero() {
+		high = timeToBytes(opts.until.Add(time.Nanosecond))
+	}
+	err = s.db.View(func(tx *bolt.Tx) (err error) {
+		bucket := tx.Bucket(consistencyBucket).Bucket([]byte(dbID))
+		if bucket == nil {
+			return
+		}
+		next, err = scanBucket(bucket, opts, low, high, func(k, v []byte) (accept bool, stop bool, err error) {
+			var r *consistencyReport
+			if err = utils.DecodeMsgPack(v, &r); err != nil {
+				return
+			}
+			reports = append(reports, r)
+			accept = true
+			return
+		})
+		return
+	})
+	return
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	ct "github.com/CovenantSQL/CovenantSQL/sqlchain/types"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	eventBlock   = "block"
+	eventAck     = "ack"
+	eventRequest = "request"
+	eventReorg   = "reorg"
+
+	// eventBufferSize defines the events buffered for each listener, events are dropped for
+	// listeners falling behind.
+	eventBufferSize = 256
+)
+
+// observerEvent defines a newly observed block, acked query, write request or reorg of database.
+type observerEvent struct {
+	Type       string
+	DatabaseID proto.DatabaseID
+
+	Height  int32
+	Block   *ct.Block
+	Ack     *wt.SignedAckHeader
+	Request *wt.Request
+	Offset  uint64
+	Reorg   *reorgRecord
+}
+
+// eventListener receives events of databases, all databases are listened if dbs is empty.
+type eventListener struct {
+	dbs     map[proto.DatabaseID]bool
+	ch      chan *observerEvent
+	dropped bool
+}
+
+// eventHub dispatches observed events to listeners.
+type eventHub struct {
+	lock      sync.Mutex
+	listeners map[*eventListener]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		listeners: make(map[*eventListener]struct{}),
+	}
+}
+
+func (h *eventHub) listen(dbs []proto.DatabaseID) (l *eventListener) {
+	l = &eventListener{
+		dbs: make(map[proto.DatabaseID]bool),
+		ch:  make(chan *observerEvent, eventBufferSize),
+	}
+	for _, dbID := range dbs {
+		l.dbs[dbID] = true
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.listeners[l] = struct{}{}
+	return
+}
+
+func (h *eventHub) unlisten(l *eventListener) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.listeners[l]; ok {
+		delete(h.listeners, l)
+		close(l.ch)
+	}
+}
+
+// publish sends event to listeners without blocking, the listener is closed if its buffer is
+// full so that the client knows events are lost.
+func (h *eventHub) publish(e *observerEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for l := range h.listeners {
+		if len(l.dbs) > 0 && !l.dbs[e.DatabaseID] {
+			continue
+		}
+		select {
+		case l.ch <- e:
+		default:
+			l.dropped = true
+			delete(h.listeners, l)
+			close(l.ch)
+		}
+	}
+}
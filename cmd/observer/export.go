@@ -0,0 +1,310 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	ct "github.com/CovenantSQL/CovenantSQL/sqlchain/types"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	exportBlocks  = "blocks"
+	exportQueries = "queries"
+
+	exportFormatCSV     = "csv"
+	exportFormatParquet = "parquet"
+
+	// exportPageSize defines the entries read from observer database at once.
+	exportPageSize = 500
+)
+
+// exportWriter writes exported rows, the first column of each row is the cursor to resume
+// exporting after the row.
+type exportWriter interface {
+	writeRow(values []interface{}) error
+	close() error
+}
+
+// exportSchema returns the columns of exported data.
+func exportSchema(data string) (names []string, types []parquetType, err error) {
+	switch data {
+	case exportBlocks:
+		names = []string{"cursor", "height", "hash", "parent_hash", "producer", "timestamp", "query_count"}
+		types = []parquetType{parquetByteArray, parquetInt64, parquetByteArray, parquetByteArray,
+			parquetByteArray, parquetInt64, parquetInt64}
+	case exportQueries:
+		names = []string{"cursor", "hash", "timestamp", "type", "request_hash", "request_timestamp",
+			"requester", "node", "log_position", "patterns"}
+		types = []parquetType{parquetByteArray, parquetByteArray, parquetInt64, parquetByteArray,
+			parquetByteArray, parquetInt64, parquetByteArray, parquetByteArray, parquetInt64,
+			parquetByteArray}
+	default:
+		err = fmt.Errorf("invalid export data %v, should be blocks or queries", data)
+	}
+	return
+}
+
+func newExportWriter(w io.Writer, data string, format string, header bool) (ew exportWriter, err error) {
+	names, types, err := exportSchema(data)
+	if err != nil {
+		return
+	}
+	switch format {
+	case exportFormatCSV:
+		ew = &csvExportWriter{w: csv.NewWriter(w), header: names, writeHeader: header}
+	case exportFormatParquet:
+		ew = newParquetWriter(w, names, types)
+	default:
+		err = fmt.Errorf("invalid export format %v, should be csv or parquet", format)
+	}
+	return
+}
+
+// csvExportWriter writes rows as csv, the header is written before the first row.
+type csvExportWriter struct {
+	w           *csv.Writer
+	header      []string
+	writeHeader bool
+}
+
+func (c *csvExportWriter) writeRow(values []interface{}) (err error) {
+	if c.writeHeader {
+		c.writeHeader = false
+		if err = c.w.Write(c.header); err != nil {
+			return
+		}
+	}
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = fmt.Sprint(v)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvExportWriter) close() error {
+	if c.writeHeader {
+		c.writeHeader = false
+		c.w.Write(c.header)
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// export writes blocks or acked queries of database after cursor in ascending order, at most
+// limit rows are written if limit is positive. The returned count is the number of rows written
+// and next is the cursor of the last row.
+func (s *Service) export(dbID proto.DatabaseID, data string, cursor []byte, limit int, w exportWriter) (
+	next []byte, count int, err error) {
+	next = cursor
+	for limit <= 0 || count < limit {
+		opts := &listOptions{cursor: next, limit: exportPageSize}
+		if limit > 0 && limit-count < opts.limit {
+			opts.limit = limit - count
+		}
+
+		var more []byte
+		switch data {
+		case exportBlocks:
+			var heights []int32
+			var blocks []*ct.Block
+			if heights, blocks, more, err = s.listBlocks(dbID, opts); err != nil {
+				return
+			}
+			for i, b := range blocks {
+				key := append(heightToBytes(heights[i]), b.BlockHash().CloneBytes()...)
+				if err = w.writeRow(s.exportBlock(key, heights[i], b)); err != nil {
+					return
+				}
+				next = key
+				count++
+			}
+		case exportQueries:
+			var acks []*wt.SignedAckHeader
+			if acks, more, err = s.listAcks(dbID, opts); err != nil {
+				return
+			}
+			for _, ack := range acks {
+				var row []interface{}
+				if row, err = s.exportAck(dbID, ack); err != nil {
+					return
+				}
+				if err = w.writeRow(row); err != nil {
+					return
+				}
+				next = ackTimeKey(ack)
+				count++
+			}
+		default:
+			_, _, err = exportSchema(data)
+			return
+		}
+		if more == nil {
+			break
+		}
+	}
+	return
+}
+
+func (s *Service) exportBlock(key []byte, height int32, b *ct.Block) []interface{} {
+	return []interface{}{
+		hex.EncodeToString(key),
+		int64(height),
+		b.BlockHash().String(),
+		b.ParentHash().String(),
+		string(b.Producer()),
+		b.Timestamp().UnixNano() / 1e6,
+		int64(len(b.Queries)),
+	}
+}
+
+func (s *Service) exportAck(dbID proto.DatabaseID, ack *wt.SignedAckHeader) (row []interface{}, err error) {
+	requester, err := ackRequester(ack)
+	if err != nil {
+		return
+	}
+
+	// patterns of write queries as json array
+	var patterns string
+	if ack.Response.Request.QueryType == wt.WriteQuery {
+		var req *wt.Request
+		if req, err = s.getRequestByOffset(dbID, ack.Response.LogOffset); err == nil {
+			list := make([]string, 0, len(req.Payload.Queries))
+			for _, q := range req.Payload.Queries {
+				list = append(list, q.Pattern)
+			}
+			var enc []byte
+			if enc, err = json.Marshal(list); err != nil {
+				return
+			}
+			patterns = string(enc)
+		} else if err != ErrNotFound {
+			return
+		}
+		err = nil
+	}
+
+	row = []interface{}{
+		hex.EncodeToString(ackTimeKey(ack)),
+		ack.HeaderHash.String(),
+		ack.AckHeader.Timestamp.UnixNano() / 1e6,
+		ack.Response.Request.QueryType.String(),
+		ack.Response.Request.HeaderHash.String(),
+		ack.Response.Request.Timestamp.UnixNano() / 1e6,
+		requester,
+		string(ack.Response.Request.NodeID),
+		int64(ack.Response.LogOffset),
+		patterns,
+	}
+	return
+}
+
+// lastCSVCursor returns the cursor of the last row of previously exported csv file, so the
+// export could be resumed by appending to the file.
+func lastCSVCursor(path string) (cursor []byte, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	defer f.Close()
+
+	var last string
+	r := csv.NewReader(bufio.NewReader(f))
+	r.FieldsPerRecord = -1
+	for {
+		var record []string
+		if record, err = r.Read(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		if len(record) > 0 {
+			last = record[0]
+		}
+	}
+	if last == "" || last == "cursor" {
+		return
+	}
+	return hex.DecodeString(last)
+}
+
+// runExport exports data of database from the local observer database, the observer should be
+// stopped. Exporting to existing csv file is resumed from the last row of the file.
+func runExport(dbID proto.DatabaseID, data string, format string, output string, cursorHex string) (err error) {
+	if output == "" {
+		return fmt.Errorf("export output file is required")
+	}
+
+	var cursor []byte
+	if cursorHex != "" {
+		if cursor, err = hex.DecodeString(cursorHex); err != nil {
+			return fmt.Errorf("invalid export cursor: %v", err)
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	header := true
+	if format == exportFormatCSV && cursor == nil {
+		var last []byte
+		if last, err = lastCSVCursor(output); err != nil {
+			return
+		}
+		if last != nil {
+			cursor, header = last, false
+			flags = os.O_WRONLY | os.O_APPEND
+		}
+	}
+
+	service, err := NewService()
+	if err != nil {
+		return
+	}
+	defer service.db.Close()
+
+	f, err := os.OpenFile(output, flags, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	w, err := newExportWriter(f, data, format, header)
+	if err != nil {
+		return
+	}
+	next, count, err := service.export(dbID, data, cursor, 0, w)
+	if err != nil {
+		return
+	}
+	if err = w.close(); err != nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d rows to %s, resume with -export-cursor %x\n", count, output, next)
+	return
+}
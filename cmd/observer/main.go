@@ -30,6 +30,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/rpc"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/CovenantSQL/CovenantSQL/utils/objstore"
 )
 
 var (
@@ -44,6 +45,23 @@ var (
 	dbID          string
 	listenAddr    string
 	resetPosition string
+
+	// retention
+	retentionDays   int
+	retentionBlocks int
+	pruneInterval   time.Duration
+	archiveDest     string
+
+	// consistency check
+	checkInterval time.Duration
+	checkBlocks   int
+
+	// export
+	exportDB     string
+	exportData   string
+	exportFormat string
+	exportOutput string
+	exportCursor string
 )
 
 func init() {
@@ -51,6 +69,18 @@ func init() {
 	flag.StringVar(&dbID, "database", "", "database to listen for observation")
 	flag.StringVar(&resetPosition, "reset", "", "reset subscribe position")
 	flag.StringVar(&listenAddr, "listen", "127.0.0.1:4663", "listen address for http explorer api")
+	flag.IntVar(&retentionDays, "retention-days", 0, "days of data to keep for each database, 0 to keep all")
+	flag.IntVar(&retentionBlocks, "retention-blocks", 0, "latest blocks to keep for each database, 0 to keep all")
+	flag.DurationVar(&pruneInterval, "prune-interval", time.Hour, "interval of pruning data out of retention")
+	flag.StringVar(&archiveDest, "archive", "", "archive pruned data to file://DIR or "+
+		"s3://BUCKET/PREFIX[?region=REGION&endpoint=URL] with credentials in AWS_* env")
+	flag.DurationVar(&checkInterval, "check-interval", 0, "interval of cross checking blocks of miners, 0 to disable")
+	flag.IntVar(&checkBlocks, "check-blocks", defaultCheckBlocks, "latest blocks to cross check for each database")
+	flag.StringVar(&exportDB, "export", "", "export data of database from local observer database and exit")
+	flag.StringVar(&exportData, "export-data", exportBlocks, "data to export, blocks or queries")
+	flag.StringVar(&exportFormat, "export-format", exportFormatCSV, "export file format, csv or parquet")
+	flag.StringVar(&exportOutput, "export-output", "", "export output file, existing csv file is appended")
+	flag.StringVar(&exportCursor, "export-cursor", "", "resume exporting after the cursor")
 }
 
 func main() {
@@ -65,6 +95,13 @@ func main() {
 		log.Fatalf("load config from %s failed: %s", configFile, err)
 	}
 
+	if exportDB != "" {
+		if err = runExport(proto.DatabaseID(exportDB), exportData, exportFormat, exportOutput, exportCursor); err != nil {
+			log.Fatalf("export failed: %v", err)
+		}
+		return
+	}
+
 	kms.InitBP()
 
 	// start rpc
@@ -79,6 +116,24 @@ func main() {
 		log.Fatalf("start observation failed: %v", err)
 	}
 
+	// start pruning
+	if pruneInterval <= 0 {
+		log.Fatalf("invalid prune interval %v", pruneInterval)
+	}
+	policy := &retentionPolicy{days: retentionDays, blocks: retentionBlocks, interval: pruneInterval}
+	if archiveDest != "" {
+		if policy.archive, err = objstore.Open(archiveDest); err != nil {
+			log.Fatalf("open archive failed: %v", err)
+		}
+	}
+	service.startPruning(policy)
+
+	// start consistency check
+	if checkBlocks <= 0 || checkBlocks > maxCheckBlocks {
+		log.Fatalf("check blocks should be in [1, %d]", maxCheckBlocks)
+	}
+	service.startConsistencyCheck(checkInterval, checkBlocks)
+
 	// start explorer api
 	httpServer, err := startAPI(service, listenAddr)
 	if err != nil {
@@ -19,11 +19,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
+	"encoding/binary"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -203,6 +209,67 @@ func getJSON(pattern string, args ...interface{}) (result *jsonq.JsonQuery, err
 	return
 }
 
+func dialWebSocket(path string) (conn net.Conn, r *bufio.Reader, err error) {
+	if conn, err = net.Dial("tcp", "localhost:4663"); err != nil {
+		return
+	}
+	fmt.Fprintf(conn, "GET /v1/%s HTTP/1.1\r\nHost: localhost:4663\r\nUpgrade: websocket\r\n"+
+		"Connection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n", path)
+
+	r = bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		conn.Close()
+		err = fmt.Errorf("websocket handshake failed: %v", resp.Status)
+	}
+	return
+}
+
+// readWebSocketJSON reads the next text message, control frames from server are skipped.
+func readWebSocketJSON(r *bufio.Reader) (result *jsonq.JsonQuery, err error) {
+	for {
+		var header [2]byte
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err = io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err = io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		payload := make([]byte, length)
+		if _, err = io.ReadFull(r, payload); err != nil {
+			return
+		}
+		if header[0]&0x0f != 0x1 {
+			continue
+		}
+
+		var res map[string]interface{}
+		if err = json.Unmarshal(payload, &res); err != nil {
+			return
+		}
+		result = jsonq.NewQuery(res)
+		return
+	}
+}
+
 func ensureSuccess(v interface{}, err error) interface{} {
 	if err != nil {
 		debug.PrintStack()
@@ -308,9 +375,31 @@ func TestFullProcess(t *testing.T) {
 			observerCmd.Wait()
 		}()
 
+		// subscribe new blocks and queries of the database
+		err = utils.WaitForPorts(context.Background(), "127.0.0.1", []int{4663}, time.Millisecond*200)
+		So(err, ShouldBeNil)
+		wsConn, wsReader, err := dialWebSocket("subscribe?db=" + dbID)
+		So(err, ShouldBeNil)
+		defer wsConn.Close()
+
 		// wait for the observer to collect blocks, two periods is enough
 		time.Sleep(blockProducePeriod * 2)
 
+		// test pushed events, blocks are pushed with headers only
+		wsConn.SetReadDeadline(time.Now().Add(blockProducePeriod))
+		var pushedBlock bool
+		for !pushedBlock {
+			event, err := readWebSocketJSON(wsReader)
+			So(err, ShouldBeNil)
+			So(ensureSuccess(event.String("database")), ShouldEqual, dbID)
+			So(ensureSuccess(event.String("type")), ShouldBeIn, []string{"block", "ack", "request"})
+			if eventType, _ := event.String("type"); eventType == "block" {
+				So(ensureSuccess(event.String("block", "hash")), ShouldNotBeEmpty)
+				So(ensureSuccess(event.Int("block", "count")), ShouldBeGreaterThanOrEqualTo, 0)
+				pushedBlock = true
+			}
+		}
+
 		// test get genesis block by height
 		res, err := getJSON("height/%v/0", dbID)
 		So(err, ShouldBeNil)
@@ -389,6 +478,110 @@ func TestFullProcess(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(ensureSuccess(res.String("request", "queries", "0", "pattern")), ShouldContainSubstring, "CREATE TABLE")
 
+		// test list blocks page by page in ascending order
+		res, err = getJSON("blocks/%v?order=asc&limit=1", dbID)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("blocks")), ShouldHaveLength, 1)
+		So(ensureSuccess(res.String("blocks", "0", "hash")), ShouldEqual, genesisHash)
+		cursor := ensureSuccess(res.String("next")).(string)
+		So(cursor, ShouldNotBeEmpty)
+		res, err = getJSON("blocks/%v?order=asc&limit=1&cursor=%v", dbID, cursor)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.String("blocks", "0", "hash")), ShouldEqual, blockHash)
+
+		// test list write queries
+		res, err = getJSON("acks/%v?type=%v", dbID, wt.WriteQuery.String())
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("acks")), ShouldNotBeEmpty)
+		So(ensureSuccess(res.String("acks", "0", "request", "type")), ShouldEqual, wt.WriteQuery.String())
+
+		// test time range filter
+		res, err = getJSON("acks/%v?until=%v", dbID, time.Unix(0, 0).Format(time.RFC3339))
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("acks")), ShouldBeEmpty)
+
+		// test query index
+		res, err = getJSON("queries/%v?table=TEST_RAW", dbID)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("acks")), ShouldNotBeEmpty)
+		So(ensureSuccess(res.String("acks", "0", "request", "type")), ShouldEqual, wt.WriteQuery.String())
+		requester := ensureSuccess(res.String("acks", "0", "request", "requester")).(string)
+		So(requester, ShouldNotBeEmpty)
+
+		res, err = getJSON("queries/%v?requester=%v", dbID, requester)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("acks")), ShouldNotBeEmpty)
+
+		res, err = getJSON("queries/%v?table=not_exists", dbID)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("acks")), ShouldBeEmpty)
+
+		_, err = getJSON("queries/%v?table=test&digest=x", dbID)
+		So(err, ShouldNotBeNil)
+
+		// test reorg tracking, no fork is expected in the test network
+		res, err = getJSON("reorgs/%v", dbID)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("reorgs")), ShouldBeEmpty)
+
+		res, err = getJSON("head/%v", dbID)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.Bool("block", "orphaned")), ShouldBeFalse)
+
+		// test consistency check, all miners are expected to agree
+		resp, err := http.Post("http://localhost:4663/v1/consistency/"+dbID, "", nil)
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+		res, err = getJSON("consistency/%v", dbID)
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("reports")), ShouldHaveLength, 1)
+		So(ensureSuccess(res.Bool("reports", "0", "consistent")), ShouldBeTrue)
+
+		// test export
+		resp, err = http.Get("http://localhost:4663/v1/export/" + dbID + "?data=queries&limit=2")
+		So(err, ShouldBeNil)
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		resp.Body.Close()
+		So(err, ShouldBeNil)
+		So(records, ShouldHaveLength, 3)
+		So(records[0][0], ShouldEqual, "cursor")
+
+		resp, err = http.Get("http://localhost:4663/v1/export/" + dbID + "?data=queries&cursor=" + records[2][0])
+		So(err, ShouldBeNil)
+		resumed, err := csv.NewReader(resp.Body).ReadAll()
+		resp.Body.Close()
+		So(err, ShouldBeNil)
+		So(len(resumed), ShouldBeGreaterThan, 1)
+		So(resumed[1][0], ShouldBeGreaterThan, records[2][0])
+
+		resp, err = http.Get("http://localhost:4663/v1/export/" + dbID + "?format=parquet")
+		So(err, ShouldBeNil)
+		parquetFile, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(err, ShouldBeNil)
+		So(string(parquetFile[:4]), ShouldEqual, "PAR1")
+		So(string(parquetFile[len(parquetFile)-4:]), ShouldEqual, "PAR1")
+
+		// test subscription management
+		res, err = getJSON("subscriptions")
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.String("subscriptions", "0", "database")), ShouldEqual, dbID)
+		So(ensureSuccess(res.Int("subscriptions", "0", "height")), ShouldBeGreaterThan, 0)
+		So(ensureSuccess(res.Int("subscriptions", "0", "lag")), ShouldBeGreaterThanOrEqualTo, 0)
+
+		req, err := http.NewRequest(http.MethodDelete, "http://localhost:4663/v1/subscriptions/"+dbID, nil)
+		So(err, ShouldBeNil)
+		resp, err = http.DefaultClient.Do(req)
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+		res, err = getJSON("subscriptions")
+		So(err, ShouldBeNil)
+		So(ensureSuccess(res.ArrayOfObjects("subscriptions")), ShouldBeEmpty)
+
 		err = client.Drop(dsn)
 		So(err, ShouldBeNil)
 	})
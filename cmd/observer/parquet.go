@@ -0,0 +1,327 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// parquetType defines the physical types of parquet columns.
+type parquetType int32
+
+const (
+	parquetInt64     parquetType = 2
+	parquetByteArray parquetType = 6
+
+	parquetMagic = "PAR1"
+
+	// parquetRowGroupRows defines the max rows of a row group, which bounds the rows buffered.
+	parquetRowGroupRows = 10000
+
+	// enum values of parquet format
+	parquetPageData         = 0
+	parquetEncodingPlain    = 0
+	parquetEncodingRLE      = 3
+	parquetRepetitionNeeded = 0
+	parquetConvertedUTF8    = 0
+	parquetCodecNone        = 0
+)
+
+type parquetColumn struct {
+	name string
+	typ  parquetType
+	data bytes.Buffer
+}
+
+// parquetColumnChunk defines the location of a column chunk written in file.
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+}
+
+// parquetRowGroup defines a row group written in file.
+type parquetRowGroup struct {
+	rows   int64
+	size   int64
+	chunks []parquetColumnChunk
+}
+
+// parquetWriter writes a parquet file of required columns, values are PLAIN encoded without
+// compression. Rows are buffered in memory until groupRows rows are collected, which are then
+// written as a row group, so the memory used is bounded regardless of the rows exported. The
+// footer describing all row groups is written on close.
+type parquetWriter struct {
+	w         io.Writer
+	columns   []*parquetColumn
+	groupRows int64
+
+	offset int64 // bytes written to w
+	rows   int64 // rows buffered in columns
+	total  int64
+	groups []parquetRowGroup
+}
+
+func newParquetWriter(w io.Writer, names []string, types []parquetType) *parquetWriter {
+	p := &parquetWriter{w: w, groupRows: parquetRowGroupRows}
+	for i, name := range names {
+		p.columns = append(p.columns, &parquetColumn{name: name, typ: types[i]})
+	}
+	return p
+}
+
+func (p *parquetWriter) write(b []byte) (err error) {
+	var n int
+	n, err = p.w.Write(b)
+	p.offset += int64(n)
+	return
+}
+
+// writeRow appends a row, values should be int64 or string according to the column types.
+func (p *parquetWriter) writeRow(values []interface{}) (err error) {
+	if len(values) != len(p.columns) {
+		return fmt.Errorf("expect %d values, got %d", len(p.columns), len(values))
+	}
+	for i, c := range p.columns {
+		switch c.typ {
+		case parquetInt64:
+			if _, ok := values[i].(int64); !ok {
+				return fmt.Errorf("invalid value %v of int64 column %s", values[i], c.name)
+			}
+		case parquetByteArray:
+			if _, ok := values[i].(string); !ok {
+				return fmt.Errorf("invalid value %v of byte array column %s", values[i], c.name)
+			}
+		}
+	}
+	for i, c := range p.columns {
+		switch v := values[i].(type) {
+		case int64:
+			binary.Write(&c.data, binary.LittleEndian, v)
+		case string:
+			binary.Write(&c.data, binary.LittleEndian, uint32(len(v)))
+			c.data.WriteString(v)
+		}
+	}
+	if p.rows++; p.rows >= p.groupRows {
+		err = p.flushRowGroup()
+	}
+	return
+}
+
+// flushRowGroup writes the buffered rows as a row group, each column chunk is a single data page.
+func (p *parquetWriter) flushRowGroup() (err error) {
+	if p.offset == 0 {
+		if err = p.write([]byte(parquetMagic)); err != nil {
+			return
+		}
+	}
+
+	group := parquetRowGroup{rows: p.rows, chunks: make([]parquetColumnChunk, len(p.columns))}
+	for i, c := range p.columns {
+		var t thriftWriter
+		t.i32(1, parquetPageData)
+		t.i32(2, int32(c.data.Len()))
+		t.i32(3, int32(c.data.Len()))
+		t.structBegin(5)
+		t.i32(1, int32(p.rows))
+		t.i32(2, parquetEncodingPlain)
+		t.i32(3, parquetEncodingRLE)
+		t.i32(4, parquetEncodingRLE)
+		t.structEnd()
+		t.stop()
+
+		group.chunks[i] = parquetColumnChunk{
+			offset: p.offset,
+			size:   int64(t.buf.Len() + c.data.Len()),
+		}
+		group.size += group.chunks[i].size
+		if err = p.write(t.buf.Bytes()); err != nil {
+			return
+		}
+		if err = p.write(c.data.Bytes()); err != nil {
+			return
+		}
+		c.data.Reset()
+	}
+
+	p.groups = append(p.groups, group)
+	p.total += p.rows
+	p.rows = 0
+	return
+}
+
+// close writes the buffered rows and the footer.
+func (p *parquetWriter) close() (err error) {
+	if p.rows > 0 {
+		if err = p.flushRowGroup(); err != nil {
+			return
+		}
+	}
+	if p.offset == 0 {
+		if err = p.write([]byte(parquetMagic)); err != nil {
+			return
+		}
+	}
+
+	var t thriftWriter
+	t.i32(1, 1)
+	t.listBegin(2, thriftStruct, len(p.columns)+1)
+	t.elemBegin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.structEnd()
+	for _, c := range p.columns {
+		t.elemBegin()
+		t.i32(1, int32(c.typ))
+		t.i32(3, parquetRepetitionNeeded)
+		t.binary(4, c.name)
+		if c.typ == parquetByteArray {
+			t.i32(6, parquetConvertedUTF8)
+		}
+		t.structEnd()
+	}
+	t.i64(3, p.total)
+	t.listBegin(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.elemBegin()
+		t.listBegin(1, thriftStruct, len(p.columns))
+		for i, c := range p.columns {
+			t.elemBegin()
+			t.i64(2, g.chunks[i].offset)
+			t.structBegin(3)
+			t.i32(1, int32(c.typ))
+			t.listBegin(2, thriftI32, 2)
+			t.elemI32(parquetEncodingPlain)
+			t.elemI32(parquetEncodingRLE)
+			t.listBegin(3, thriftBinary, 1)
+			t.elemBinary(c.name)
+			t.i32(4, parquetCodecNone)
+			t.i64(5, g.rows)
+			t.i64(6, g.chunks[i].size)
+			t.i64(7, g.chunks[i].size)
+			t.i64(9, g.chunks[i].offset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.structEnd()
+	}
+	t.binary(6, "covenantobserver")
+	t.stop()
+
+	var footer bytes.Buffer
+	footer.Write(t.buf.Bytes())
+	binary.Write(&footer, binary.LittleEndian, uint32(t.buf.Len()))
+	footer.WriteString(parquetMagic)
+	return p.write(footer.Bytes())
+}
+
+// thrift compact protocol types used by parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in thrift compact protocol, fields of each struct should be
+// written in increasing order of field id.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	lastID int16
+	stack  []int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+// varint writes zigzag encoded integer.
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.elemBinary(v)
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.lastID = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+func (t *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.uvarint(uint64(size))
+	}
+}
+
+// elemBegin starts a struct element of list, it's ended by structEnd.
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) elemI32(v int32) {
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) elemBinary(v string) {
+	t.uvarint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// thriftReader decodes structs in thrift compact protocol as maps of field id to values.
+type thriftReader struct {
+	r *bytes.Reader
+}
+
+func (t *thriftReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (t *thriftReader) varint() int64 {
+	v := t.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (t *thriftReader) byte() byte {
+	b, err := t.r.ReadByte()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (t *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return t.varint()
+	case thriftBinary:
+		b := make([]byte, t.uvarint())
+		if _, err := io.ReadFull(t.r, b); err != nil {
+			panic(err)
+		}
+		return string(b)
+	case thriftList:
+		h := t.byte()
+		size := uint64(h >> 4)
+		if size == 15 {
+			size = t.uvarint()
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = t.value(h & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return t.structValue()
+	default:
+		panic(fmt.Sprintf("unexpected thrift type %d", typ))
+	}
+}
+
+func (t *thriftReader) structValue() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		h := t.byte()
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(t.varint())
+		}
+		fields[id] = t.value(h & 0x0f)
+	}
+}
+
+// readParquetFooter returns the file metadata of parquet file.
+func readParquetFooter(file []byte) map[int16]interface{} {
+	So(len(file), ShouldBeGreaterThanOrEqualTo, 12)
+	So(string(file[:4]), ShouldEqual, parquetMagic)
+	So(string(file[len(file)-4:]), ShouldEqual, parquetMagic)
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := file[len(file)-8-size : len(file)-8]
+	return (&thriftReader{r: bytes.NewReader(footer)}).structValue()
+}
+
+// readParquetPage returns the values of the data page at offset.
+func readParquetPage(file []byte, offset int64, typ parquetType) (values []interface{}) {
+	r := bytes.NewReader(file[offset:])
+	header := (&thriftReader{r: r}).structValue()
+	So(header[1], ShouldEqual, parquetPageData)
+	dataHeader := header[5].(map[int16]interface{})
+	So(dataHeader[2], ShouldEqual, parquetEncodingPlain)
+	data := make([]byte, header[2].(int64))
+	_, err := io.ReadFull(r, data)
+	So(err, ShouldBeNil)
+	for i := int64(0); i < dataHeader[1].(int64); i++ {
+		switch typ {
+		case parquetInt64:
+			values = append(values, int64(binary.LittleEndian.Uint64(data)))
+			data = data[8:]
+		case parquetByteArray:
+			n := binary.LittleEndian.Uint32(data)
+			values = append(values, string(data[4:4+n]))
+			data = data[4+n:]
+		}
+	}
+	So(data, ShouldBeEmpty)
+	return
+}
+
+func TestParquetWriter(t *testing.T) {
+	Convey("test writing parquet file in row groups", t, func() {
+		var buf bytes.Buffer
+		p := newParquetWriter(&buf, []string{"id", "name"}, []parquetType{parquetInt64, parquetByteArray})
+		p.groupRows = 3
+
+		var rows [][]interface{}
+		for i := 0; i < 7; i++ {
+			rows = append(rows, []interface{}{int64(i), fmt.Sprintf("row-%d", i)})
+			So(p.writeRow(rows[i]), ShouldBeNil)
+			// row groups are written once they are full
+			So(p.rows, ShouldEqual, (i+1)%3)
+		}
+		So(buf.Len(), ShouldBeGreaterThan, len(parquetMagic))
+		So(p.writeRow([]interface{}{int64(1)}), ShouldNotBeNil)
+		So(p.writeRow([]interface{}{"1", "a"}), ShouldNotBeNil)
+		So(p.writeRow([]interface{}{int64(1), int64(1)}), ShouldNotBeNil)
+		So(p.close(), ShouldBeNil)
+
+		meta := readParquetFooter(buf.Bytes())
+		So(meta[1], ShouldEqual, 1)
+		So(meta[2], ShouldHaveLength, 3)
+		So(meta[3], ShouldEqual, 7)
+		groups := meta[4].([]interface{})
+		So(groups, ShouldHaveLength, 3)
+
+		var decoded [][]interface{}
+		for _, g := range groups {
+			group := g.(map[int16]interface{})
+			chunks := group[1].([]interface{})
+			So(chunks, ShouldHaveLength, 2)
+			var columns [][]interface{}
+			var size int64
+			for i, c := range chunks {
+				chunk := c.(map[int16]interface{})
+				colMeta := chunk[3].(map[int16]interface{})
+				So(colMeta[3], ShouldResemble, []interface{}{p.columns[i].name})
+				So(colMeta[5], ShouldEqual, group[3])
+				So(colMeta[9], ShouldEqual, chunk[2])
+				size += colMeta[6].(int64)
+				values := readParquetPage(buf.Bytes(), colMeta[9].(int64), p.columns[i].typ)
+				So(values, ShouldHaveLength, group[3])
+				columns = append(columns, values)
+			}
+			So(group[2], ShouldEqual, size)
+			for i := range columns[0] {
+				decoded = append(decoded, []interface{}{columns[0][i], columns[1][i]})
+			}
+		}
+		So(decoded, ShouldResemble, rows)
+	})
+	Convey("test writing empty parquet file", t, func() {
+		var buf bytes.Buffer
+		p := newParquetWriter(&buf, []string{"id"}, []parquetType{parquetInt64})
+		So(p.close(), ShouldBeNil)
+		meta := readParquetFooter(buf.Bytes())
+		So(meta[3], ShouldEqual, 0)
+		So(meta[4], ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	ct "github.com/CovenantSQL/CovenantSQL/sqlchain/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/CovenantSQL/CovenantSQL/utils/objstore"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+	"github.com/coreos/bbolt"
+)
+
+// pruneBatchSize defines the blocks and acks pruned in a transaction.
+const pruneBatchSize = 1000
+
+// retentionPolicy defines the observed data kept for each database, data older than days or
+// beyond the latest blocks is pruned, and archived to object store if archive is set.
+type retentionPolicy struct {
+	days     int
+	blocks   int
+	interval time.Duration
+	archive  objstore.Store
+}
+
+func (p *retentionPolicy) enabled() bool {
+	return p != nil && (p.days > 0 || p.blocks > 0)
+}
+
+// archiveBatch defines the pruned data archived as an object encoded with msgpack, so it could
+// be loaded for offline analysis.
+type archiveBatch struct {
+	DatabaseID proto.DatabaseID
+	Heights    []int32
+	Blocks     []*ct.Block
+	Acks       []*wt.SignedAckHeader
+	Offsets    []uint64
+	Requests   []*wt.Request
+}
+
+func (a *archiveBatch) empty() bool {
+	return len(a.Blocks) == 0 && len(a.Acks) == 0
+}
+
+// startPruning prunes the observer database periodically until the service stops.
+func (s *Service) startPruning(p *retentionPolicy) {
+	if !p.enabled() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.prune(p); err != nil {
+				log.Warningf("prune observer database failed: %v", err)
+			}
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// prune removes data out of retention of all observed databases.
+func (s *Service) prune(p *retentionPolicy) (err error) {
+	var dbs []proto.DatabaseID
+	seen := make(map[string]bool)
+	if err = s.db.View(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{blockBucket, ackBucket} {
+			tx.Bucket(name).ForEach(func(k, v []byte) error {
+				if v == nil && !seen[string(k)] {
+					seen[string(k)] = true
+					dbs = append(dbs, proto.DatabaseID(k))
+				}
+				return nil
+			})
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	for _, dbID := range dbs {
+		if err = s.pruneDatabase(dbID, p); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *Service) pruneDatabase(dbID proto.DatabaseID, p *retentionPolicy) (err error) {
+	// blocks with height not above cutoffHeight or earlier than cutoffTime are pruned, and acks
+	// earlier than the cutoff time or the oldest block kept are pruned
+	cutoffHeight := int32(-1)
+	var cutoffTime time.Time
+	if p.days > 0 {
+		cutoffTime = time.Now().Add(-time.Duration(p.days) * 24 * time.Hour)
+	}
+	ackCutoff := cutoffTime
+	if p.blocks > 0 {
+		var headHeight int32
+		if headHeight, _, err = s.getHighestBlock(dbID); err == nil {
+			cutoffHeight = headHeight - int32(p.blocks)
+			var oldest *ct.Block
+			if oldest, err = s.getBlockByHeight(dbID, cutoffHeight+1); err == nil &&
+				oldest.Timestamp().After(ackCutoff) {
+				ackCutoff = oldest.Timestamp()
+			}
+		}
+		if err == ErrNotFound {
+			err = nil
+		} else if err != nil {
+			return
+		}
+	}
+
+	var total archiveBatch
+	for {
+		batch := &archiveBatch{DatabaseID: dbID}
+		if err = s.db.View(func(tx *bolt.Tx) error {
+			return collectPruned(tx, batch, cutoffHeight, cutoffTime, ackCutoff)
+		}); err != nil || batch.empty() {
+			break
+		}
+
+		if p.archive != nil {
+			var data *bytes.Buffer
+			if data, err = utils.EncodeMsgPack(batch); err != nil {
+				return
+			}
+			// names are ordered by time
+			name := fmt.Sprintf("%s-%020d.msgpack", dbID, time.Now().UnixNano())
+			if err = p.archive.Put(name, data.Bytes()); err != nil {
+				// keep the data until it's archived
+				return
+			}
+		}
+
+		if err = s.db.Update(func(tx *bolt.Tx) error {
+			return deletePruned(tx, batch, ackCutoff)
+		}); err != nil {
+			return
+		}
+
+		total.Blocks = append(total.Blocks, batch.Blocks...)
+		total.Acks = append(total.Acks, batch.Acks...)
+		if len(batch.Blocks) < pruneBatchSize && len(batch.Acks) < pruneBatchSize {
+			break
+		}
+	}
+
+	if !total.empty() {
+		log.Infof("pruned %d blocks and %d acks of database %v", len(total.Blocks), len(total.Acks), dbID)
+	}
+	return
+}
+
+// collectPruned collects at most pruneBatchSize blocks and acks out of retention, the genesis
+// block is always kept.
+func collectPruned(tx *bolt.Tx, batch *archiveBatch, cutoffHeight int32, cutoffTime, ackCutoff time.Time) (
+	err error) {
+	dbID := []byte(batch.DatabaseID)
+
+	if bb := tx.Bucket(blockBucket).Bucket(dbID); bb != nil {
+		c := bb.Cursor()
+		for k, v := c.First(); k != nil && len(batch.Blocks) < pruneBatchSize; k, v = c.Next() {
+			h := bytesToHeight(k[:4])
+			if h == 0 || v == nil {
+				continue
+			}
+			var b *ct.Block
+			if err = utils.DecodeMsgPack(v, &b); err != nil {
+				return
+			}
+			if h > cutoffHeight && (cutoffTime.IsZero() || !b.Timestamp().Before(cutoffTime)) {
+				break
+			}
+			batch.Heights = append(batch.Heights, h)
+			batch.Blocks = append(batch.Blocks, b)
+		}
+	}
+
+	ib := tx.Bucket(ackTimeBucket).Bucket(dbID)
+	ab := tx.Bucket(ackBucket).Bucket(dbID)
+	if ackCutoff.IsZero() || ib == nil || ab == nil {
+		return
+	}
+	rb := tx.Bucket(requestBucket).Bucket(dbID)
+	cutoff := timeToBytes(ackCutoff)
+	c := ib.Cursor()
+	for k, _ := c.First(); k != nil && len(batch.Acks) < pruneBatchSize; k, _ = c.Next() {
+		if bytes.Compare(k[:8], cutoff) >= 0 {
+			break
+		}
+		ackBytes := ab.Get(k[8:])
+		if ackBytes == nil {
+			continue
+		}
+		var ack *wt.SignedAckHeader
+		if err = utils.DecodeMsgPack(ackBytes, &ack); err != nil {
+			return
+		}
+		batch.Acks = append(batch.Acks, ack)
+
+		if ack.Response.Request.QueryType != wt.WriteQuery || rb == nil {
+			continue
+		}
+		prefix := offsetToBytes(ack.Response.LogOffset)
+		if rk, rv := rb.Cursor().Seek(prefix); rk != nil && bytes.HasPrefix(rk, prefix) {
+			var request *wt.Request
+			if err = utils.DecodeMsgPack(rv, &request); err != nil {
+				return
+			}
+			batch.Offsets = append(batch.Offsets, ack.Response.LogOffset)
+			batch.Requests = append(batch.Requests, request)
+		}
+	}
+	return
+}
+
+// deletePruned removes the collected data and the related indexes, reorg records and consistency
+// reports earlier than the ack cutoff time are also removed.
+func deletePruned(tx *bolt.Tx, batch *archiveBatch, ackCutoff time.Time) (err error) {
+	dbID := []byte(batch.DatabaseID)
+	del := func(bucket []byte, key []byte) error {
+		if b := tx.Bucket(bucket).Bucket(dbID); b != nil {
+			return b.Delete(key)
+		}
+		return nil
+	}
+
+	for i, b := range batch.Blocks {
+		if err = del(blockBucket, append(heightToBytes(batch.Heights[i]), b.BlockHash()[:]...)); err != nil {
+			return
+		}
+		if err = del(blockHeightBucket, b.BlockHash()[:]); err != nil {
+			return
+		}
+		if err = del(orphanBucket, b.BlockHash()[:]); err != nil {
+			return
+		}
+	}
+
+	requests := make(map[uint64]*wt.Request)
+	for i, offset := range batch.Offsets {
+		requests[offset] = batch.Requests[i]
+	}
+	for _, ack := range batch.Acks {
+		var request *wt.Request
+		if ack.Response.Request.QueryType == wt.WriteQuery {
+			request = requests[ack.Response.LogOffset]
+		}
+		if err = unindexQuery(tx, batch.DatabaseID, ack, request); err != nil {
+			return
+		}
+		if err = del(ackTimeBucket, ackTimeKey(ack)); err != nil {
+			return
+		}
+		if err = del(ackBucket, ack.HeaderHash[:]); err != nil {
+			return
+		}
+		if err = del(orphanBucket, ack.HeaderHash[:]); err != nil {
+			return
+		}
+		if request != nil {
+			reqHash := request.Header.HeaderHash[:]
+			if err = del(requestBucket, append(offsetToBytes(ack.Response.LogOffset), reqHash...)); err != nil {
+				return
+			}
+			if err = del(logOffsetBucket, reqHash); err != nil {
+				return
+			}
+		}
+	}
+
+	if ackCutoff.IsZero() {
+		return
+	}
+	cutoff := timeToBytes(ackCutoff)
+	for _, name := range [][]byte{reorgBucket, consistencyBucket} {
+		b := tx.Bucket(name).Bucket(dbID)
+		if b == nil {
+			continue
+		}
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], cutoff) < 0; k, _ = c.First() {
+			if err = c.Delete(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
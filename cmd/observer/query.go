@@ -0,0 +1,226 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlIdentifier sqlTokenKind = iota
+	sqlKeywordOrName
+	sqlLiteral
+	sqlParam
+	sqlPunct
+)
+
+type sqlToken struct {
+	kind  sqlTokenKind
+	value string
+}
+
+// tokenizeSQL splits statement into tokens, comments are dropped and quoted identifiers are
+// unquoted. It's only meant for indexing statements, not validating them.
+func tokenizeSQL(s string) (tokens []sqlToken) {
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		next := rune(0)
+		if i+1 < len(r) {
+			next = r[i+1]
+		}
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '-' && next == '-':
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case c == '/' && next == '*':
+			i += 2
+			for i < len(r) && !(r[i] == '*' && i+1 < len(r) && r[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case c == '\'':
+			// string literal, quotes are escaped by doubling
+			j := i + 1
+			for ; j < len(r); j++ {
+				if r[j] == '\'' {
+					if j+1 < len(r) && r[j+1] == '\'' {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlLiteral, value: "?"})
+			i = j + 1
+		case c == '"' || c == '`' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < len(r) && r[j] != end {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlIdentifier, value: string(r[i+1 : min(j, len(r))])})
+			i = j + 1
+		case c == '?' || ((c == ':' || c == '@' || c == '$') && isIdentRune(next)):
+			j := i + 1
+			for j < len(r) && isIdentRune(r[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlParam, value: "?"})
+			i = j
+		case unicode.IsDigit(c) || (c == '.' && unicode.IsDigit(next)):
+			j := i + 1
+			for j < len(r) && (isIdentRune(r[j]) || r[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlLiteral, value: "?"})
+			i = j
+		case isIdentRune(c):
+			j := i + 1
+			for j < len(r) && (isIdentRune(r[j]) || r[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlKeywordOrName, value: strings.ToLower(string(r[i:j]))})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{kind: sqlPunct, value: string(c)})
+			i++
+		}
+	}
+	return
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// normalizeQuery returns the statement with literals and parameters replaced by ?, keywords
+// lower cased and spaces collapsed, statements differing only in values are normalized equally.
+func normalizeQuery(pattern string) string {
+	tokens := tokenizeSQL(pattern)
+	for len(tokens) > 0 && tokens[len(tokens)-1].value == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	parts := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind == sqlIdentifier {
+			parts = append(parts, `"`+t.value+`"`)
+		} else {
+			parts = append(parts, t.value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// queryDigest returns the digest of normalized statement.
+func queryDigest(pattern string) string {
+	return hash.THashH([]byte(normalizeQuery(pattern))).String()
+}
+
+// queryTables returns the lower cased names of tables referenced by statement, such as tables
+// after FROM, JOIN, INTO, UPDATE and TABLE, and the table of CREATE INDEX.
+func queryTables(pattern string) (tables []string) {
+	tokens := tokenizeSQL(pattern)
+	seen := make(map[string]bool)
+	add := func(i int) int {
+		// skip IF [NOT] EXISTS
+		for i < len(tokens) && tokens[i].kind == sqlKeywordOrName &&
+			(tokens[i].value == "if" || tokens[i].value == "not" || tokens[i].value == "exists") {
+			i++
+		}
+		if i >= len(tokens) || (tokens[i].kind != sqlKeywordOrName && tokens[i].kind != sqlIdentifier) {
+			return i
+		}
+		name := strings.ToLower(tokens[i].value)
+		// schema qualified name
+		if i+2 < len(tokens) && tokens[i+1].value == "." {
+			i += 2
+			name = strings.ToLower(tokens[i].value)
+		}
+		if name != "select" && !isClauseKeyword(name) && !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+		return i + 1
+	}
+
+	createIndex := false
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind != sqlKeywordOrName {
+			continue
+		}
+		switch t.value {
+		case "index":
+			createIndex = i > 0 && (tokens[i-1].value == "create" || tokens[i-1].value == "unique")
+		case "on":
+			if createIndex {
+				createIndex = false
+				add(i + 1)
+			}
+		case "from":
+			// comma separated tables
+			for j := add(i + 1); j < len(tokens); {
+				// skip alias
+				if j < len(tokens) && tokens[j].value == "as" {
+					j++
+				}
+				if j < len(tokens) && (tokens[j].kind == sqlKeywordOrName || tokens[j].kind == sqlIdentifier) &&
+					!isClauseKeyword(tokens[j].value) {
+					j++
+				}
+				if j >= len(tokens) || tokens[j].value != "," {
+					break
+				}
+				j = add(j + 1)
+			}
+		case "join", "into", "update", "table":
+			add(i + 1)
+		}
+	}
+	return
+}
+
+// isClauseKeyword reports whether s starts a clause after table names, so it's not an alias.
+func isClauseKeyword(s string) bool {
+	switch s {
+	case "where", "join", "inner", "left", "right", "cross", "natural", "outer", "on", "using",
+		"group", "order", "limit", "having", "union", "except", "intersect", "set", "values":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,289 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	ct "github.com/CovenantSQL/CovenantSQL/sqlchain/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/coreos/bbolt"
+)
+
+// reorgRecord defines a switch of the observed chain head to another branch.
+type reorgRecord struct {
+	Time       time.Time
+	ForkHeight int32 // height of the common ancestor
+	OldHead    hash.Hash
+	OldHeight  int32
+	NewHead    hash.Hash
+	NewHeight  int32
+	// Orphaned lists blocks of the abandoned branch, and AffectedQueries lists acks included in
+	// orphaned blocks only.
+	Orphaned        []hash.Hash
+	AffectedQueries []hash.Hash
+}
+
+func reorgKey(r *reorgRecord) []byte {
+	return append(timeToBytes(r.Time), r.NewHead[:]...)
+}
+
+// getBlockInTx returns the height and block of hash, b is nil if block is not observed.
+func getBlockInTx(tx *bolt.Tx, dbID proto.DatabaseID, h []byte) (height int32, b *ct.Block, err error) {
+	hb := tx.Bucket(blockHeightBucket).Bucket([]byte(dbID))
+	bb := tx.Bucket(blockBucket).Bucket([]byte(dbID))
+	if hb == nil || bb == nil {
+		return
+	}
+	rawHeight := hb.Get(h)
+	if rawHeight == nil {
+		return
+	}
+	height = bytesToHeight(rawHeight)
+	blockBytes := bb.Get(append(heightToBytes(height), h...))
+	if blockBytes == nil {
+		return
+	}
+	err = utils.DecodeMsgPack(blockBytes, &b)
+	return
+}
+
+// trackHead updates the chain head of database with a newly observed block, which should be
+// called before the block is stored. Blocks not on the head chain are marked as orphaned, and
+// the reorg is returned if the head switches to another branch.
+func trackHead(tx *bolt.Tx, dbID proto.DatabaseID, height int32, b *ct.Block) (reorg *reorgRecord, err error) {
+	blockHash := b.BlockHash()
+	var known *ct.Block
+	if _, known, err = getBlockInTx(tx, dbID, blockHash[:]); err != nil || known != nil {
+		// ignore blocks received again, such as the genesis block on each subscribing
+		return
+	}
+
+	hb := tx.Bucket(headBucket)
+	newHead := append(heightToBytes(height), blockHash[:]...)
+	head := hb.Get([]byte(dbID))
+	if head == nil {
+		err = hb.Put([]byte(dbID), newHead)
+		return
+	}
+	headHeight, headHash := bytesToHeight(head[:4]), append([]byte{}, head[4:]...)
+
+	parent := b.ParentHash()
+	var parentBlock *ct.Block
+	if _, parentBlock, err = getBlockInTx(tx, dbID, parent[:]); err != nil {
+		return
+	}
+
+	switch {
+	case bytes.Equal(parent[:], headHash) || parentBlock == nil:
+		// extends the head, or the parent is not observed so the fork is unknown
+		if height > headHeight {
+			err = hb.Put([]byte(dbID), newHead)
+		}
+		return
+	case height <= headHeight:
+		// block of a side branch, its queries may be included by the head chain
+		var ob *bolt.Bucket
+		if ob, err = tx.Bucket(orphanBucket).CreateBucketIfNotExists([]byte(dbID)); err != nil {
+			return
+		}
+		err = ob.Put(blockHash[:], newHead)
+		return
+	}
+
+	// switch to the branch of block, walk back both branches to the common ancestor
+	reorg = &reorgRecord{
+		Time:      time.Now(),
+		OldHeight: headHeight,
+		NewHeight: height,
+	}
+	copy(reorg.OldHead[:], headHash)
+	reorg.NewHead = *blockHash
+	rKey := reorgKey(reorg)
+
+	oldHash, oldHeight := headHash, headHeight
+	newHash := parent[:]
+	adopted := []*ct.Block{b}
+	var newHeight int32
+	if newHeight, _, err = getBlockInTx(tx, dbID, newHash); err != nil {
+		return
+	}
+	for !bytes.Equal(oldHash, newHash) {
+		var ob *ct.Block
+		if oldHeight >= newHeight {
+			if _, ob, err = getBlockInTx(tx, dbID, oldHash); err != nil {
+				return
+			}
+			if ob == nil {
+				break
+			}
+			if err = markOrphaned(tx, dbID, ob, rKey); err != nil {
+				return
+			}
+			reorg.Orphaned = append(reorg.Orphaned, *ob.BlockHash())
+			reorg.AffectedQueries = append(reorg.AffectedQueries, blockQueries(ob)...)
+			oldHash = ob.ParentHash()[:]
+			if oldHeight, _, err = getBlockInTx(tx, dbID, oldHash); err != nil {
+				return
+			}
+		} else {
+			var nb *ct.Block
+			if _, nb, err = getBlockInTx(tx, dbID, newHash); err != nil {
+				return
+			}
+			if nb == nil {
+				break
+			}
+			adopted = append(adopted, nb)
+			newHash = nb.ParentHash()[:]
+			if newHeight, _, err = getBlockInTx(tx, dbID, newHash); err != nil {
+				return
+			}
+		}
+	}
+	reorg.ForkHeight = oldHeight
+
+	// blocks of new branch may be orphaned by an earlier reorg, and queries could be included in
+	// both branches
+	included := make(map[hash.Hash]bool)
+	for _, nb := range adopted {
+		if err = unmarkOrphaned(tx, dbID, nb); err != nil {
+			return
+		}
+		for _, q := range blockQueries(nb) {
+			included[q] = true
+		}
+	}
+	affected := reorg.AffectedQueries[:0]
+	for _, q := range reorg.AffectedQueries {
+		if !included[q] {
+			affected = append(affected, q)
+		}
+	}
+	reorg.AffectedQueries = affected
+
+	if err = hb.Put([]byte(dbID), newHead); err != nil {
+		return
+	}
+
+	rb, err := tx.Bucket(reorgBucket).CreateBucketIfNotExists([]byte(dbID))
+	if err != nil {
+		return
+	}
+	reorgBytes, err := utils.EncodeMsgPack(reorg)
+	if err != nil {
+		return
+	}
+	if err = rb.Put(rKey, reorgBytes.Bytes()); err != nil {
+		return
+	}
+
+	log.Warningf("database %v reorganized at height %d: %v -> %v, orphaned %d blocks",
+		dbID, reorg.ForkHeight, reorg.OldHead.String(), reorg.NewHead.String(), len(reorg.Orphaned))
+	return
+}
+
+func blockQueries(b *ct.Block) (queries []hash.Hash) {
+	for _, q := range b.Queries {
+		if q != nil {
+			queries = append(queries, *q)
+		}
+	}
+	return
+}
+
+// markOrphaned marks block and its queries as orphaned, the value is the key of reorg record, or
+// the key of block itself for blocks of side branch.
+func markOrphaned(tx *bolt.Tx, dbID proto.DatabaseID, b *ct.Block, value []byte) (err error) {
+	ob, err := tx.Bucket(orphanBucket).CreateBucketIfNotExists([]byte(dbID))
+	if err != nil {
+		return
+	}
+	if err = ob.Put(b.BlockHash()[:], value); err != nil {
+		return
+	}
+	for _, q := range b.Queries {
+		if q != nil {
+			if err = ob.Put(q[:], value); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func unmarkOrphaned(tx *bolt.Tx, dbID proto.DatabaseID, b *ct.Block) (err error) {
+	ob := tx.Bucket(orphanBucket).Bucket([]byte(dbID))
+	if ob == nil {
+		return
+	}
+	if err = ob.Delete(b.BlockHash()[:]); err != nil {
+		return
+	}
+	for _, q := range b.Queries {
+		if q != nil {
+			if err = ob.Delete(q[:]); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// isOrphaned reports whether the block or ack of hash is not on the head chain of database.
+func (s *Service) isOrphaned(dbID proto.DatabaseID, h *hash.Hash) (orphaned bool) {
+	s.db.View(func(tx *bolt.Tx) error {
+		if ob := tx.Bucket(orphanBucket).Bucket([]byte(dbID)); ob != nil {
+			orphaned = ob.Get(h[:]) != nil
+		}
+		return nil
+	})
+	return
+}
+
+// listReorgs lists reorgs of database by time.
+func (s *Service) listReorgs(dbID proto.DatabaseID, opts *listOptions) (
+	reorgs []*reorgRecord, next []byte, err error) {
+	var low, high []byte
+	if !opts.since.IsZero() {
+		low = timeToBytes(opts.since)
+	}
+	if !opts.until.IsZero() {
+		high = timeToBytes(opts.until.Add(time.Nanosecond))
+	}
+	err = s.db.View(func(tx *bolt.Tx) (err error) {
+		bucket := tx.Bucket(reorgBucket).Bucket([]byte(dbID))
+		if bucket == nil {
+			return
+		}
+		next, err = scanBucket(bucket, opts, low, high, func(k, v []byte) (accept bool, stop bool, err error) {
+			var r *reorgRecord
+			if err = utils.DecodeMsgPack(v, &r); err != nil {
+				return
+			}
+			reorgs = append(reorgs, r)
+			accept = true
+			return
+		})
+		return
+	})
+	return
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -62,6 +63,14 @@ const (
   |    |	    |---> [hash] => ack
   |    |         \--> [hash] => ack
   |    |
+  |  [ack_time]-->[`dbID`]
+  |    |             |---> [timestamp+hash] => query type
+  |    |              \--> [timestamp+hash] => query type
+  |    |
+  |  [query_table|query_digest|query_requester]-->[`dbID`]
+  |    |             |---> [term+0+timestamp+hash] => query type
+  |    |              \--> [term+0+timestamp+hash] => query type
+  |    |
   |  [request]-->[`dbID`]
   |    |            |---> [offset+hash] => request
   |    |             \--> [offset+hash] => request
@@ -70,8 +79,20 @@ const (
   |                 |---> [hash] => offset
   |                  \--> [hash] => offset
   |
+  |  [head]
+  |    \---> [`dbID`] => height+hash of head block
+  |
+  |  [reorg]-->[`dbID`]
+  |    |          \---> [timestamp+new head hash] => reorg record
+  |    |
+  |  [orphan]-->[`dbID`]
+  |    |           \---> [block or ack hash] => reorg key or block key
+  |    |
+  |  [consistency]-->[`dbID`]
+  |    |                \---> [timestamp] => consistency report
+  |    |
    \-> [subscription]
-             \---> [`dbID`] => height
+             \---> [`dbID`] => height to resume subscription from
 */
 
 var (
@@ -83,8 +104,19 @@ var (
 	// bolt db buckets
 	blockBucket        = []byte("block")
 	ackBucket          = []byte("ack")
+	ackTimeBucket      = []byte("ack_time")
 	requestBucket      = []byte("request")
 	subscriptionBucket = []byte("subscription")
+	headBucket         = []byte("head")
+	reorgBucket        = []byte("reorg")
+	orphanBucket       = []byte("orphan")
+	consistencyBucket  = []byte("consistency")
+
+	// query index buckets, write queries are indexed by tables and statement digests, and all
+	// queries are indexed by requester address
+	queryTableBucket     = []byte("query_table")
+	queryDigestBucket    = []byte("query_digest")
+	queryRequesterBucket = []byte("query_requester")
 
 	blockHeightBucket = []byte("height")
 	logOffsetBucket   = []byte("offset")
@@ -101,7 +133,9 @@ type Service struct {
 
 	db      *bolt.DB
 	caller  *rpc.Caller
+	events  *eventHub
 	stopped int32
+	stopCh  chan struct{}
 }
 
 // NewService creates new observer service and load previous subscription from the meta database.
@@ -136,7 +170,23 @@ func NewService() (service *Service, err error) {
 		if _, err = tx.CreateBucketIfNotExists(blockHeightBucket); err != nil {
 			return
 		}
-		_, err = tx.CreateBucketIfNotExists(logOffsetBucket)
+		if _, err = tx.CreateBucketIfNotExists(logOffsetBucket); err != nil {
+			return
+		}
+		for _, name := range [][]byte{headBucket, reorgBucket, orphanBucket, consistencyBucket} {
+			if _, err = tx.CreateBucketIfNotExists(name); err != nil {
+				return
+			}
+		}
+		if tx.Bucket(ackTimeBucket) == nil {
+			// index acks stored by previous versions
+			if err = buildAckTimeIndex(tx); err != nil {
+				return
+			}
+		}
+		if tx.Bucket(queryTableBucket) == nil {
+			return buildQueryIndex(tx)
+		}
 		return
 	}); err != nil {
 		return
@@ -147,6 +197,8 @@ func NewService() (service *Service, err error) {
 		subscription: make(map[proto.DatabaseID]int32),
 		db:           db,
 		caller:       rpc.NewCaller(),
+		events:       newEventHub(),
+		stopCh:       make(chan struct{}),
 	}
 
 	// load previous subscriptions
@@ -164,6 +216,161 @@ func NewService() (service *Service, err error) {
 	return
 }
 
+func buildAckTimeIndex(tx *bolt.Tx) (err error) {
+	tb, err := tx.CreateBucket(ackTimeBucket)
+	if err != nil {
+		return
+	}
+	return tx.Bucket(ackBucket).ForEach(func(rawDBID, _ []byte) (err error) {
+		ab := tx.Bucket(ackBucket).Bucket(rawDBID)
+		if ab == nil {
+			return
+		}
+		ib, err := tb.CreateBucketIfNotExists(rawDBID)
+		if err != nil {
+			return
+		}
+		return ab.ForEach(func(_, ackBytes []byte) (err error) {
+			var ack *wt.SignedAckHeader
+			if err = utils.DecodeMsgPack(ackBytes, &ack); err != nil {
+				return
+			}
+			return ib.Put(ackTimeKey(ack), []byte{byte(ack.Response.Request.QueryType)})
+		})
+	})
+}
+
+func buildQueryIndex(tx *bolt.Tx) (err error) {
+	for _, name := range [][]byte{queryTableBucket, queryDigestBucket, queryRequesterBucket} {
+		if _, err = tx.CreateBucketIfNotExists(name); err != nil {
+			return
+		}
+	}
+	return tx.Bucket(ackBucket).ForEach(func(rawDBID, _ []byte) (err error) {
+		ab := tx.Bucket(ackBucket).Bucket(rawDBID)
+		if ab == nil {
+			return
+		}
+		rb := tx.Bucket(requestBucket).Bucket(rawDBID)
+		return ab.ForEach(func(_, ackBytes []byte) (err error) {
+			var ack *wt.SignedAckHeader
+			if err = utils.DecodeMsgPack(ackBytes, &ack); err != nil {
+				return
+			}
+			var request *wt.Request
+			if ack.Response.Request.QueryType == wt.WriteQuery && rb != nil {
+				prefix := offsetToBytes(ack.Response.LogOffset)
+				if k, v := rb.Cursor().Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) {
+					if err = utils.DecodeMsgPack(v, &request); err != nil {
+						return
+					}
+				}
+			}
+			return indexQuery(tx, proto.DatabaseID(rawDBID), ack, request)
+		})
+	})
+}
+
+// queryIndexTerm defines an entry of query indexes.
+type queryIndexTerm struct {
+	bucket []byte
+	term   string
+}
+
+// queryIndexTerms returns the index entries of acked query, request is the original write query
+// or nil.
+func queryIndexTerms(ack *wt.SignedAckHeader, request *wt.Request) (terms []queryIndexTerm, err error) {
+	var requester string
+	if requester, err = ackRequester(ack); err != nil {
+		return
+	}
+	if requester != "" {
+		terms = append(terms, queryIndexTerm{queryRequesterBucket, requester})
+	}
+
+	if request == nil {
+		return
+	}
+	tables := make(map[string]bool)
+	digests := make(map[string]bool)
+	for _, q := range request.Payload.Queries {
+		for _, t := range queryTables(q.Pattern) {
+			if !tables[t] {
+				tables[t] = true
+				terms = append(terms, queryIndexTerm{queryTableBucket, t})
+			}
+		}
+		if d := queryDigest(q.Pattern); !digests[d] {
+			digests[d] = true
+			terms = append(terms, queryIndexTerm{queryDigestBucket, d})
+		}
+	}
+	return
+}
+
+// indexQuery adds acked query to query indexes, request is the original write query or nil.
+func indexQuery(tx *bolt.Tx, dbID proto.DatabaseID, ack *wt.SignedAckHeader, request *wt.Request) (err error) {
+	terms, err := queryIndexTerms(ack, request)
+	if err != nil {
+		return
+	}
+	value := []byte{byte(ack.Response.Request.QueryType)}
+	for _, t := range terms {
+		var b *bolt.Bucket
+		if b, err = tx.Bucket(t.bucket).CreateBucketIfNotExists([]byte(dbID)); err != nil {
+			return
+		}
+		if err = b.Put(queryIndexKey(t.term, ack), value); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// unindexQuery removes acked query from query indexes.
+func unindexQuery(tx *bolt.Tx, dbID proto.DatabaseID, ack *wt.SignedAckHeader, request *wt.Request) (err error) {
+	terms, err := queryIndexTerms(ack, request)
+	if err != nil {
+		return
+	}
+	for _, t := range terms {
+		if b := tx.Bucket(t.bucket).Bucket([]byte(dbID)); b != nil {
+			if err = b.Delete(queryIndexKey(t.term, ack)); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// ackRequester returns the account address of query requester, or empty string if unknown.
+func ackRequester(ack *wt.SignedAckHeader) (addr string, err error) {
+	signee := ack.Response.Request.Signee
+	if signee == nil {
+		return
+	}
+	var enc []byte
+	if enc, err = signee.MarshalHash(); err != nil {
+		return
+	}
+	addr = hash.THashH(enc).String()
+	return
+}
+
+func queryIndexKey(term string, ack *wt.SignedAckHeader) (key []byte) {
+	key = append([]byte(term), 0)
+	return append(key, ackTimeKey(ack)...)
+}
+
+func ackTimeKey(ack *wt.SignedAckHeader) (key []byte) {
+	key = timeToBytes(ack.AckHeader.Timestamp)
+	return append(key, ack.HeaderHash.CloneBytes()...)
+}
+
+func timeToBytes(t time.Time) []byte {
+	return offsetToBytes(uint64(t.UnixNano()))
+}
+
 func offsetToBytes(offset uint64) (data []byte) {
 	data = make([]byte, 8)
 	binary.BigEndian.PutUint64(data, offset)
@@ -217,6 +424,17 @@ func (s *Service) subscribe(dbID proto.DatabaseID, resetSubscribePosition string
 		}
 	}
 
+	if shouldStartSubscribe {
+		// persist the subscription, it's resumed on restart even if the start request fails
+		h := s.subscription[dbID]
+		if err = s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(subscriptionBucket).Put([]byte(dbID), heightToBytes(h))
+		}); err != nil {
+			s.lock.Unlock()
+			return
+		}
+	}
+
 	s.lock.Unlock()
 
 	if shouldStartSubscribe {
@@ -226,6 +444,110 @@ func (s *Service) subscribe(dbID proto.DatabaseID, resetSubscribePosition string
 	return
 }
 
+// unsubscribe cancels the subscription of database, the observed data is kept.
+func (s *Service) unsubscribe(dbID proto.DatabaseID) (err error) {
+	if atomic.LoadInt32(&s.stopped) == 1 {
+		return ErrStopped
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exists := s.subscription[dbID]; !exists {
+		return ErrNotFound
+	}
+
+	if err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionBucket).Delete([]byte(dbID))
+	}); err != nil {
+		return
+	}
+	delete(s.subscription, dbID)
+
+	log.Infof("stop subscribing transactions from database %v", dbID)
+	req := &sqlchain.MuxCancelSubscriptionReq{}
+	resp := &sqlchain.MuxCancelSubscriptionResp{}
+	req.DatabaseID = dbID
+	if err = s.minerRequest(dbID, route.SQLCCancelSubscription.String(), req, resp); err != nil {
+		// the upstream stops pushing to unknown subscriber on its own
+		log.Warningf("cancel subscription for database %v failed: %v", dbID, err)
+		err = nil
+	}
+	return
+}
+
+// subscriptionStatus defines the sync status of a subscribed database.
+type subscriptionStatus struct {
+	DatabaseID proto.DatabaseID
+	// Position is the height to resume subscription from.
+	Position int32
+	// Height is the highest block height observed, -1 if no block is observed.
+	Height    int32
+	BlockTime time.Time
+	// ExpectedHeight is the height of chain head estimated by time, -1 if the chain is unknown.
+	ExpectedHeight int32
+}
+
+// Lag returns the count of blocks not observed yet, -1 if unknown.
+func (st *subscriptionStatus) Lag() int32 {
+	if st.ExpectedHeight < 0 {
+		return -1
+	}
+	if lag := st.ExpectedHeight - st.Height; lag > 0 {
+		return lag
+	}
+	return 0
+}
+
+// listSubscriptions returns the sync status of subscribed databases.
+func (s *Service) listSubscriptions() (result []*subscriptionStatus, err error) {
+	s.lock.Lock()
+	result = make([]*subscriptionStatus, 0, len(s.subscription))
+	for dbID, pos := range s.subscription {
+		result = append(result, &subscriptionStatus{
+			DatabaseID:     dbID,
+			Position:       pos,
+			Height:         -1,
+			ExpectedHeight: -1,
+		})
+	}
+	s.lock.Unlock()
+
+	// the persisted position is moved forward by incoming blocks
+	if err = s.db.View(func(tx *bolt.Tx) error {
+		sb := tx.Bucket(subscriptionBucket)
+		for _, st := range result {
+			if pos := sb.Get([]byte(st.DatabaseID)); pos != nil {
+				st.Position = bytesToHeight(pos)
+			}
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DatabaseID < result[j].DatabaseID
+	})
+
+	for _, st := range result {
+		var b *ct.Block
+		if st.Height, b, err = s.getHighestBlock(st.DatabaseID); err == nil {
+			st.BlockTime = b.Timestamp()
+		} else if err == ErrNotFound {
+			st.Height, err = -1, nil
+		} else {
+			return
+		}
+		// only use the cached instance to avoid querying block producer for each listing
+		if iInstance, exists := s.upstreamServers.Load(st.DatabaseID); exists {
+			genesis := iInstance.(*wt.ServiceInstance).GenesisBlock
+			st.ExpectedHeight = int32(time.Since(genesis.Timestamp()) / blockProducePeriod)
+		}
+	}
+	return
+}
+
 // AdviseNewBlock handles block replication request from the remote database chain service.
 func (s *Service) AdviseNewBlock(req *sqlchain.MuxAdviseNewBlockReq, resp *sqlchain.MuxAdviseNewBlockResp) (err error) {
 	if atomic.LoadInt32(&s.stopped) == 1 {
@@ -263,7 +585,7 @@ func (s *Service) start() (err error) {
 	}
 
 	s.lock.Lock()
-	dbs := make([]proto.DatabaseID, len(s.subscription))
+	dbs := make([]proto.DatabaseID, 0, len(s.subscription))
 	for dbID := range s.subscription {
 		dbs = append(dbs, dbID)
 	}
@@ -326,6 +648,7 @@ func (s *Service) addAckedQuery(dbID proto.DatabaseID, ack *wt.SignedAckHeader)
 	}
 
 	// fetch original query
+	var request *wt.Request
 	if ack.Response.Request.QueryType == wt.WriteQuery {
 		req := &wt.GetRequestReq{}
 		resp := &wt.GetRequestResp{}
@@ -365,10 +688,18 @@ func (s *Service) addAckedQuery(dbID proto.DatabaseID, ack *wt.SignedAckHeader)
 		}); err != nil {
 			return
 		}
+
+		request = resp.Request
+		s.events.publish(&observerEvent{
+			Type:       eventRequest,
+			DatabaseID: dbID,
+			Request:    resp.Request,
+			Offset:     req.LogOffset,
+		})
 	}
 
 	// store ack
-	return s.db.Update(func(tx *bolt.Tx) (err error) {
+	if err = s.db.Update(func(tx *bolt.Tx) (err error) {
 		ab, err := tx.Bucket(ackBucket).CreateBucketIfNotExists([]byte(dbID))
 		if err != nil {
 			return
@@ -377,9 +708,27 @@ func (s *Service) addAckedQuery(dbID proto.DatabaseID, ack *wt.SignedAckHeader)
 		if err != nil {
 			return
 		}
-		err = ab.Put(ack.HeaderHash.CloneBytes(), ackBytes.Bytes())
+		if err = ab.Put(ack.HeaderHash.CloneBytes(), ackBytes.Bytes()); err != nil {
+			return
+		}
+		ib, err := tx.Bucket(ackTimeBucket).CreateBucketIfNotExists([]byte(dbID))
+		if err != nil {
+			return
+		}
+		if err = ib.Put(ackTimeKey(ack), []byte{byte(ack.Response.Request.QueryType)}); err != nil {
+			return
+		}
+		return indexQuery(tx, dbID, ack, request)
+	}); err != nil {
 		return
+	}
+
+	s.events.publish(&observerEvent{
+		Type:       eventAck,
+		DatabaseID: dbID,
+		Ack:        ack,
 	})
+	return
 }
 
 func (s *Service) addBlock(dbID proto.DatabaseID, b *ct.Block) (err error) {
@@ -390,7 +739,11 @@ func (s *Service) addBlock(dbID proto.DatabaseID, b *ct.Block) (err error) {
 	key := heightToBytes(h)
 	key = append(key, b.BlockHash().CloneBytes()...)
 
-	return s.db.Update(func(tx *bolt.Tx) (err error) {
+	var reorg *reorgRecord
+	if err = s.db.Update(func(tx *bolt.Tx) (err error) {
+		if reorg, err = trackHead(tx, dbID, h, b); err != nil {
+			return
+		}
 		bb, err := tx.Bucket(blockBucket).CreateBucketIfNotExists([]byte(dbID))
 		if err != nil {
 			return
@@ -406,9 +759,35 @@ func (s *Service) addBlock(dbID proto.DatabaseID, b *ct.Block) (err error) {
 		if err != nil {
 			return
 		}
-		err = hb.Put(b.BlockHash()[:], heightToBytes(h))
+		if err = hb.Put(b.BlockHash()[:], heightToBytes(h)); err != nil {
+			return
+		}
+		// move the resume position of subscription forward, the genesis block stored on
+		// subscribing does not change the position
+		sb := tx.Bucket(subscriptionBucket)
+		if pos := sb.Get([]byte(dbID)); pos != nil && h > 0 && bytesToHeight(pos) < h {
+			err = sb.Put([]byte(dbID), heightToBytes(h))
+		}
 		return
+	}); err != nil {
+		return
+	}
+
+	s.events.publish(&observerEvent{
+		Type:       eventBlock,
+		DatabaseID: dbID,
+		Height:     h,
+		Block:      b,
 	})
+	if reorg != nil {
+		s.events.publish(&observerEvent{
+			Type:       eventReorg,
+			DatabaseID: dbID,
+			Height:     h,
+			Reorg:      reorg,
+		})
+	}
+	return
 }
 
 func (s *Service) stop() (err error) {
@@ -421,6 +800,7 @@ func (s *Service) stop() (err error) {
 	defer s.lock.Unlock()
 
 	atomic.StoreInt32(&s.stopped, 1)
+	close(s.stopCh)
 
 	// send cancel subscription to all databases
 	log.Infof("stop subscribing all databases")
@@ -578,6 +958,14 @@ func (s *Service) getHighestBlock(dbID proto.DatabaseID) (height int32, b *ct.Bl
 			return ErrNotFound
 		}
 
+		// prefer the tracked head, the highest block may be on a side branch
+		if head := tx.Bucket(headBucket).Get([]byte(dbID)); head != nil {
+			if blockData := bucket.Get(head); blockData != nil {
+				height = bytesToHeight(head[:4])
+				return utils.DecodeMsgPack(blockData, &b)
+			}
+		}
+
 		cur := bucket.Cursor()
 		if last, blockData := cur.Last(); last != nil {
 			// decode bytes
@@ -656,3 +1044,181 @@ func (s *Service) getBlock(dbID proto.DatabaseID, h *hash.Hash) (height int32, b
 
 	return
 }
+
+// listOptions defines the range, order and page size of listing.
+type listOptions struct {
+	cursor []byte // key of the last entry of previous page
+	limit  int
+	since  time.Time
+	until  time.Time
+	desc   bool
+
+	// queryType filters listed acks, all acks are listed if it's nil.
+	queryType *wt.QueryType
+}
+
+func (o *listOptions) inRange(t time.Time) (in bool, stop bool) {
+	if !o.since.IsZero() && t.Before(o.since) {
+		return false, o.desc
+	}
+	if !o.until.IsZero() && t.After(o.until) {
+		return false, !o.desc
+	}
+	return true, false
+}
+
+// scanBucket iterates entries of bucket with keys in [low, high) in the order of opts starting
+// after the cursor, fn is called with each entry until it stops or limit entries are accepted.
+// The key of last accepted entry is returned as the cursor of next page if limit is reached.
+func scanBucket(b *bolt.Bucket, opts *listOptions, low, high []byte,
+	fn func(k, v []byte) (accept bool, stop bool, err error)) (next []byte, err error) {
+	c := b.Cursor()
+
+	var k, v []byte
+	switch {
+	case !opts.desc && opts.cursor != nil:
+		if k, v = c.Seek(opts.cursor); bytes.Equal(k, opts.cursor) {
+			k, v = c.Next()
+		}
+	case !opts.desc && low != nil:
+		k, v = c.Seek(low)
+	case !opts.desc:
+		k, v = c.First()
+	default:
+		upper := opts.cursor
+		if upper == nil {
+			upper = high
+		}
+		if upper == nil {
+			k, v = c.Last()
+			break
+		}
+		if k, v = c.Seek(upper); k == nil {
+			k, v = c.Last()
+		}
+		for k != nil && bytes.Compare(k, upper) >= 0 {
+			k, v = c.Prev()
+		}
+	}
+
+	var count int
+	for ; k != nil; k, v = advanceCursor(c, opts.desc) {
+		if !opts.desc && high != nil && bytes.Compare(k, high) >= 0 {
+			return
+		}
+		if opts.desc && low != nil && bytes.Compare(k, low) < 0 {
+			return
+		}
+		if v == nil {
+			// nested bucket
+			continue
+		}
+
+		var accept, stop bool
+		if accept, stop, err = fn(k, v); err != nil || stop {
+			return
+		}
+		if accept {
+			if count++; count == opts.limit {
+				next = append([]byte{}, k...)
+				return
+			}
+		}
+	}
+	return
+}
+
+func advanceCursor(c *bolt.Cursor, desc bool) (k, v []byte) {
+	if desc {
+		return c.Prev()
+	}
+	return c.Next()
+}
+
+func (s *Service) listBlocks(dbID proto.DatabaseID, opts *listOptions) (
+	heights []int32, blocks []*ct.Block, next []byte, err error) {
+	err = s.db.View(func(tx *bolt.Tx) (err error) {
+		bucket := tx.Bucket(blockBucket).Bucket([]byte(dbID))
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		next, err = scanBucket(bucket, opts, nil, nil, func(k, v []byte) (accept bool, stop bool, err error) {
+			var b *ct.Block
+			if err = utils.DecodeMsgPack(v, &b); err != nil {
+				return
+			}
+			if accept, stop = opts.inRange(b.Timestamp()); accept {
+				heights = append(heights, bytesToHeight(k[:4]))
+				blocks = append(blocks, b)
+			}
+			return
+		})
+		return
+	})
+	return
+}
+
+func (s *Service) listAcks(dbID proto.DatabaseID, opts *listOptions) (
+	acks []*wt.SignedAckHeader, next []byte, err error) {
+	var low, high []byte
+	if !opts.since.IsZero() {
+		low = timeToBytes(opts.since)
+	}
+	if !opts.until.IsZero() {
+		high = timeToBytes(opts.until.Add(time.Nanosecond))
+	}
+	return s.scanAcks(dbID, ackTimeBucket, opts, low, high)
+}
+
+// searchAcks lists acked queries matching term of query index bucket.
+func (s *Service) searchAcks(dbID proto.DatabaseID, bucket []byte, term string, opts *listOptions) (
+	acks []*wt.SignedAckHeader, next []byte, err error) {
+	prefix := append([]byte(term), 0)
+	low := append(append([]byte{}, prefix...), timeToBytes(opts.since)...)
+	var high []byte
+	if opts.until.IsZero() {
+		high = append([]byte(term), 1)
+	} else {
+		high = append(append([]byte{}, prefix...), timeToBytes(opts.until.Add(time.Nanosecond))...)
+	}
+	if opts.since.IsZero() {
+		low = prefix
+	}
+	return s.scanAcks(dbID, bucket, opts, low, high)
+}
+
+// scanAcks lists acked queries of index bucket, keys of the index end with ack hash and values
+// are query types.
+func (s *Service) scanAcks(dbID proto.DatabaseID, index []byte, opts *listOptions, low, high []byte) (
+	acks []*wt.SignedAckHeader, next []byte, err error) {
+	err = s.db.View(func(tx *bolt.Tx) (err error) {
+		bucket := tx.Bucket(index).Bucket([]byte(dbID))
+		ab := tx.Bucket(ackBucket).Bucket([]byte(dbID))
+		if bucket == nil || ab == nil {
+			return ErrNotFound
+		}
+
+		next, err = scanBucket(bucket, opts, low, high, func(k, v []byte) (accept bool, stop bool, err error) {
+			if opts.queryType != nil && (len(v) == 0 || wt.QueryType(v[0]) != *opts.queryType) {
+				return
+			}
+			if len(k) < hash.HashSize {
+				return
+			}
+			ackBytes := ab.Get(k[len(k)-hash.HashSize:])
+			if ackBytes == nil {
+				return
+			}
+			var ack *wt.SignedAckHeader
+			if err = utils.DecodeMsgPack(ackBytes, &ack); err != nil {
+				return
+			}
+			acks = append(acks, ack)
+			accept = true
+			return
+		})
+		return
+	})
+	return
+}
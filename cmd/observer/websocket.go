@@ -0,0 +1,254 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// websocketGUID is the magic string of websocket handshake defined in RFC 6455.
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xa
+
+	// wsMaxFrameSize limits the frames sent by clients, clients only send control frames.
+	wsMaxFrameSize = 64 * 1024
+	wsWriteTimeout = 10 * time.Second
+	wsPingInterval = 30 * time.Second
+)
+
+var (
+	errNotWebSocket     = errors.New("not a websocket handshake")
+	errWebSocketFrame   = errors.New("invalid websocket frame")
+	errWebSocketClosed  = errors.New("websocket closed")
+	errHijackNotAllowed = errors.New("connection does not support hijacking")
+)
+
+// wsConn is a minimal server side websocket connection which sends text messages and answers
+// control frames from clients.
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	writeLock sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeWebSocket completes the websocket handshake and takes over the connection.
+func upgradeWebSocket(rw http.ResponseWriter, r *http.Request) (c *wsConn, err error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		err = errNotWebSocket
+		return
+	}
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		err = errHijackNotAllowed
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	// clear deadlines set by http server for the request
+	conn.SetDeadline(time.Time{})
+
+	c = &wsConn{conn: conn, r: buf.Reader, closed: make(chan struct{})}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: "+websocketAccept(key)+"\r\n\r\n"); err != nil {
+		conn.Close()
+		c = nil
+	}
+	return
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) (err error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	select {
+	case <-c.closed:
+		return errWebSocketClosed
+	default:
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op // final frame
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err = c.conn.Write(header); err != nil {
+		return
+	}
+	_, err = c.conn.Write(payload)
+	return
+}
+
+// writeJSON sends v as a json text message.
+func (c *wsConn) writeJSON(v interface{}) (err error) {
+	var data []byte
+	if data, err = json.Marshal(v); err != nil {
+		return
+	}
+	return c.writeFrame(wsOpText, data)
+}
+
+// readFrame reads a frame from client, client frames are always masked.
+func (c *wsConn) readFrame() (op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.r, header[:]); err != nil {
+		return
+	}
+	op = header[0] & 0x0f
+	if header[1]&0x80 == 0 {
+		err = errWebSocketFrame
+		return
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxFrameSize {
+		err = errWebSocketFrame
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// serve answers ping and close frames from client and pings client periodically until the
+// connection is closed, messages from client are discarded.
+func (c *wsConn) serve() {
+	defer c.close()
+
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.closed:
+				return
+			case <-ticker.C:
+				if err := c.writeFrame(wsOpPing, nil); err != nil {
+					c.close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		op, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch op {
+		case wsOpClose:
+			c.writeFrame(wsOpClose, payload)
+			return
+		case wsOpPing:
+			if err = c.writeFrame(wsOpPong, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		c.writeLock.Lock()
+		defer c.writeLock.Unlock()
+		close(c.closed)
+		c.conn.Close()
+	})
+}
@@ -17,6 +17,7 @@
 package conf
 
 import (
+	"fmt"
 	"io/ioutil"
 	"time"
 
@@ -73,6 +74,26 @@ type BPInfo struct {
 	ChainFileName string `yaml:"ChainFileName"`
 	// BPGenesisInfo is the genesis block filed
 	BPGenesis BPGenesisInfo `yaml:"BPGenesisInfo"`
+	// Authorities are the accounts voting for chain parameter updates
+	Authorities []hash.Hash `yaml:"Authorities,omitempty"`
+	// AuthorityThreshold is the votes required by an update, the majority of authorities if not set
+	AuthorityThreshold int `yaml:"AuthorityThreshold,omitempty"`
+	// KeepBlocks is the recent blocks kept by a pruned node, full history is kept if not set
+	KeepBlocks uint32 `yaml:"KeepBlocks,omitempty"`
+	// Archival keeps the full history regardless of KeepBlocks
+	Archival bool `yaml:"Archival,omitempty"`
+	// MempoolTTL is how long a pending transaction is kept in the persisted memory pool
+	MempoolTTL time.Duration `yaml:"MempoolTTL,omitempty"`
+	// AccountTxRate is the transactions submitted per second by an account, not limited if not set
+	AccountTxRate float64 `yaml:"AccountTxRate,omitempty"`
+	// AccountTxBurst is the transactions submitted in a burst by an account
+	AccountTxBurst int `yaml:"AccountTxBurst,omitempty"`
+	// IPTxRate is the transactions submitted per second from an IP address, not limited if not set
+	IPTxRate float64 `yaml:"IPTxRate,omitempty"`
+	// IPTxBurst is the transactions submitted in a burst from an IP address
+	IPTxBurst int `yaml:"IPTxBurst,omitempty"`
+	// LegacyTxDeadline is the height from which transactions signed without chain id are rejected
+	LegacyTxDeadline uint32 `yaml:"LegacyTxDeadline,omitempty"`
 }
 
 // MinerDatabaseFixture config.
@@ -120,11 +141,30 @@ type Config struct {
 
 	DNSSeed DNSSeed `yaml:"DNSSeed"`
 
+	// ChainID identifies the network which transactions are signed for.
+	ChainID string `yaml:"ChainID,omitempty"`
+
 	BP    *BPInfo    `yaml:"BlockProducer"`
 	Miner *MinerInfo `yaml:"Miner,omitempty"`
 
 	KnownNodes  []proto.Node `yaml:"KnownNodes"`
 	SeedBPNodes []proto.Node `yaml:"-"`
+
+	// Profile is the name of profile in use, empty for no profile.
+	Profile  string              `yaml:"Profile,omitempty"`
+	Profiles map[string]*Profile `yaml:"Profiles,omitempty"`
+}
+
+// Profile defines a named environment like testnet or mainnet in config, the non-empty fields
+// override the corresponding fields of config.
+type Profile struct {
+	WorkingRoot     string       `yaml:"WorkingRoot,omitempty"`
+	PubKeyStoreFile string       `yaml:"PubKeyStoreFile,omitempty"`
+	PrivateKeyFile  string       `yaml:"PrivateKeyFile,omitempty"`
+	ThisNodeID      proto.NodeID `yaml:"ThisNodeID,omitempty"`
+	ChainID         string       `yaml:"ChainID,omitempty"`
+	BP              *BPInfo      `yaml:"BlockProducer,omitempty"`
+	KnownNodes      []proto.Node `yaml:"KnownNodes,omitempty"`
 }
 
 // GConf is the global config pointer
@@ -146,3 +186,54 @@ func LoadConfig(configPath string) (config *Config, err error) {
 
 	return
 }
+
+// LoadConfigProfile loads config from configPath and applies the named profile, the
+// profile selected by config is used if profile is empty.
+func LoadConfigProfile(configPath string, profile string) (config *Config, err error) {
+	if config, err = LoadConfig(configPath); err != nil {
+		return
+	}
+	return config.WithProfile(profile)
+}
+
+// WithProfile returns a copy of config overridden by the named profile, the profile selected
+// by config is used if name is empty and config itself is returned if no profile is selected.
+func (c *Config) WithProfile(name string) (config *Config, err error) {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return c, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok || p == nil {
+		err = fmt.Errorf("profile %v not found", name)
+		return
+	}
+
+	copied := *c
+	config = &copied
+	config.Profile = name
+	if p.WorkingRoot != "" {
+		config.WorkingRoot = p.WorkingRoot
+	}
+	if p.PubKeyStoreFile != "" {
+		config.PubKeyStoreFile = p.PubKeyStoreFile
+	}
+	if p.PrivateKeyFile != "" {
+		config.PrivateKeyFile = p.PrivateKeyFile
+	}
+	if p.ThisNodeID != "" {
+		config.ThisNodeID = p.ThisNodeID
+	}
+	if p.ChainID != "" {
+		config.ChainID = p.ChainID
+	}
+	if p.BP != nil {
+		config.BP = p.BP
+	}
+	if len(p.KnownNodes) != 0 {
+		config.KnownNodes = p.KnownNodes
+	}
+	return
+}
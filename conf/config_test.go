@@ -149,3 +149,41 @@ func TestConf(t *testing.T) {
 		So(err, ShouldNotBeNil)
 	})
 }
+
+func TestConfigProfile(t *testing.T) {
+	Convey("WithProfile", t, func() {
+		defer os.Remove(testFile)
+		ioutil.WriteFile(testFile, []byte(`
+WorkingRoot: "./base"
+PrivateKeyFile: "private.key"
+ThisNodeID: "00000f3b43288fe99831eb533ab77ec455d13e11fc38ec35a42d4edd17aa320d"
+Profile: testnet
+Profiles:
+  testnet:
+    WorkingRoot: "./testnet"
+    ThisNodeID: "000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade"
+  local:
+    PrivateKeyFile: "local.key"
+`), 0600)
+
+		config, err := LoadConfigProfile(testFile, "")
+		So(err, ShouldBeNil)
+		So(config.Profile, ShouldEqual, "testnet")
+		So(config.WorkingRoot, ShouldEqual, "./testnet")
+		So(config.PrivateKeyFile, ShouldEqual, "private.key")
+		So(config.ThisNodeID, ShouldEqual, "000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade")
+
+		config, err = LoadConfigProfile(testFile, "local")
+		So(err, ShouldBeNil)
+		So(config.Profile, ShouldEqual, "local")
+		So(config.WorkingRoot, ShouldEqual, "./base")
+		So(config.PrivateKeyFile, ShouldEqual, "local.key")
+
+		raw, err := LoadConfig(testFile)
+		So(err, ShouldBeNil)
+		So(raw.WorkingRoot, ShouldEqual, "./base")
+
+		_, err = LoadConfigProfile(testFile, "mainnet")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -112,9 +112,10 @@ func (h *Hash) Difficulty() (difficulty int) {
 	return HashSize * 8
 }
 
-// MarshalYAML implements the yaml.Marshaler interface.
+// MarshalYAML implements the yaml.Marshaler interface, the hash is encoded in byte order
+// which is consistent with UnmarshalYAML.
 func (hash Hash) MarshalYAML() (interface{}, error) {
-	return hash.String(), nil
+	return hex.EncodeToString(hash[:]), nil
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"encoding/hex"
 	"testing"
+
+	"gopkg.in/yaml.v2"
 )
 
 // mainNetGenesisHash is the hash of the first block in the block chain for the
@@ -264,3 +266,18 @@ func TestHash_Difficulty(t *testing.T) {
 		t.Errorf("Difficulty test new(Hash) expect 256 got %d", newDifficulty)
 	}
 }
+
+func TestHash_YAML(t *testing.T) {
+	h := THashH([]byte("Space Cowboy"))
+	enc, err := yaml.Marshal(h)
+	if err != nil {
+		t.Fatalf("marshal hash failed: %v", err)
+	}
+	var dec Hash
+	if err = yaml.Unmarshal(enc, &dec); err != nil {
+		t.Fatalf("unmarshal hash failed: %v", err)
+	}
+	if !dec.IsEqual(&h) {
+		t.Errorf("YAML round trip expect %s got %s", h.String(), dec.String())
+	}
+}
@@ -0,0 +1,24 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package hd implements the hierarchical deterministic keys defined in BIP32 and the mnemonic
+// seed phrase defined in BIP39, so that all the node and wallet keys of a user can be recovered
+// from a single seed phrase.
+//
+// The keys are derived by the BIP44 path m/44'/CoinType'/index'/0/0, see AccountPath. In
+// CovenantSQL the key of a node is also its wallet account, so node and wallet keys are simply
+// keys of different indexes.
+package hd
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	ec "github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart uint32 = 0x80000000
+	// CoinType is the BIP44 coin type of CovenantSQL keys, which is the ASCII code of "CQL".
+	CoinType uint32 = 0x43514c
+
+	// MinSeedLen defines the minimum bytes of master key seed.
+	MinSeedLen = 16
+	// MaxSeedLen defines the maximum bytes of master key seed.
+	MaxSeedLen = 64
+)
+
+var (
+	// ErrInvalidSeed indicates that the seed length is out of range or the seed produces an
+	// invalid master key.
+	ErrInvalidSeed = errors.New("invalid seed")
+	// ErrInvalidChild indicates that the child key of an index is invalid, which happens with a
+	// probability lower than 1 in 2^127 and the next index should be used.
+	ErrInvalidChild = errors.New("invalid child key")
+	// ErrHardenedFromPublic indicates that a hardened child key is derived from a public key.
+	ErrHardenedFromPublic = errors.New("cannot derive hardened key from public key")
+	// ErrNotPrivate indicates that the private key is requested from a public extended key.
+	ErrNotPrivate = errors.New("not a private extended key")
+	// ErrInvalidPath indicates that the derivation path is malformed.
+	ErrInvalidPath = errors.New("invalid derivation path")
+
+	masterKeySalt = []byte("Bitcoin seed")
+)
+
+// ExtendedKey defines a private or public key with the chain code to derive its child keys.
+type ExtendedKey struct {
+	// key is the 32 bytes private key or the 33 bytes compressed public key.
+	key       []byte
+	chainCode []byte
+	depth     uint8
+	childNum  uint32
+	isPrivate bool
+}
+
+// NewMaster returns the master key of seed.
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinSeedLen || len(seed) > MaxSeedLen {
+		return nil, ErrInvalidSeed
+	}
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	if k := new(big.Int).SetBytes(sum[:32]); k.Sign() == 0 || k.Cmp(ec.S256().N) >= 0 {
+		return nil, ErrInvalidSeed
+	}
+	return &ExtendedKey{key: sum[:32], chainCode: sum[32:], isPrivate: true}, nil
+}
+
+// IsPrivate reports whether k is a private extended key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth returns the depth of k from master key.
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ChildNum returns the index of k derived from its parent.
+func (k *ExtendedKey) ChildNum() uint32 {
+	return k.childNum
+}
+
+// PrivateKey returns the private key of k.
+func (k *ExtendedKey) PrivateKey() (*asymmetric.PrivateKey, error) {
+	if !k.isPrivate {
+		return nil, ErrNotPrivate
+	}
+	priv, _ := asymmetric.PrivKeyFromBytes(k.key)
+	return priv, nil
+}
+
+// PublicKey returns the public key of k.
+func (k *ExtendedKey) PublicKey() (*asymmetric.PublicKey, error) {
+	return asymmetric.ParsePubKey(k.pubKeyBytes())
+}
+
+// Neuter returns the public extended key of k, which derives the public keys of the
+// non-hardened children only.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	return &ExtendedKey{
+		key:       k.pubKeyBytes(),
+		chainCode: k.chainCode,
+		depth:     k.depth,
+		childNum:  k.childNum,
+	}
+}
+
+func (k *ExtendedKey) pubKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	x, y := ec.S256().ScalarBaseMult(k.key)
+	return (&ec.PublicKey{Curve: ec.S256(), X: x, Y: y}).SerializeCompressed()
+}
+
+// Child returns the child key of index i, i >= HardenedKeyStart derives a hardened child which
+// is only derivable from a private key.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	var (
+		hardened = i >= HardenedKeyStart
+		data     = make([]byte, 0, 37)
+	)
+	if hardened {
+		if !k.isPrivate {
+			return nil, ErrHardenedFromPublic
+		}
+		data = append(append(data, 0), k.key...)
+	} else {
+		data = append(data, k.pubKeyBytes()...)
+	}
+	data = data[:len(data)+4]
+	binary.BigEndian.PutUint32(data[len(data)-4:], i)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	var (
+		sum   = mac.Sum(nil)
+		curve = ec.S256()
+		il    = new(big.Int).SetBytes(sum[:32])
+		child = &ExtendedKey{
+			chainCode: sum[32:],
+			depth:     k.depth + 1,
+			childNum:  i,
+			isPrivate: k.isPrivate,
+		}
+	)
+	if il.Cmp(curve.N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+	if k.isPrivate {
+		il.Add(il, new(big.Int).SetBytes(k.key))
+		il.Mod(il, curve.N)
+		if il.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+		child.key = make([]byte, 32)
+		b := il.Bytes()
+		copy(child.key[32-len(b):], b)
+		return child, nil
+	}
+
+	pub, err := ec.ParsePubKey(k.key, curve)
+	if err != nil {
+		return nil, err
+	}
+	x, y := curve.ScalarBaseMult(sum[:32])
+	if x, y = curve.Add(x, y, pub.X, pub.Y); x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+	child.key = (&ec.PublicKey{Curve: curve, X: x, Y: y}).SerializeCompressed()
+	return child, nil
+}
+
+// Derive returns the descendant key of path like "m/44'/0'/0'/0/0", which is relative to k.
+func (k *ExtendedKey) Derive(path string) (child *ExtendedKey, err error) {
+	var indexes []uint32
+	if indexes, err = ParsePath(path); err != nil {
+		return
+	}
+	child = k
+	for _, i := range indexes {
+		if child, err = child.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// ParsePath parses the child indexes of path, the hardened indexes are marked by a ' or H
+// suffix.
+func ParsePath(path string) (indexes []uint32, err error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, ErrInvalidPath
+	}
+	indexes = make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "H") {
+			p, offset = p[:len(p)-1], HardenedKeyStart
+		}
+		i, perr := strconv.ParseUint(p, 10, 32)
+		if perr != nil || uint32(i) >= HardenedKeyStart {
+			return nil, ErrInvalidPath
+		}
+		indexes = append(indexes, uint32(i)+offset)
+	}
+	return
+}
+
+// AccountPath returns the BIP44 derivation path of the account key of index.
+func AccountPath(index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/0/0", CoinType, index)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExtendedKey(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	Convey("Test vector 1 of BIP32", t, func() {
+		vectors := []struct {
+			path string
+			priv string
+			pub  string
+		}{
+			{
+				path: "m",
+				priv: "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+				pub:  "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2",
+			},
+			{
+				path: "m/0'",
+				priv: "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+				pub:  "035a784662a4a20a65bf6aab9ae98a6c068a81c52e4b032c0fb5400c706cfccc56",
+			},
+			{
+				path: "m/0H/1",
+				priv: "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+				pub:  "03501e454bf00751f24b1b489aa925215d66af2234e3891c3b21a52bedb3cd711c",
+			},
+			{
+				path: "m/0'/1/2'/2/1000000000",
+				priv: "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8",
+				pub:  "022a471424da5e657499d1ff51cb43c47481a03b1e77f951fe64cec9f5a48f7011",
+			},
+		}
+		master, err := NewMaster(seed)
+		So(err, ShouldBeNil)
+		for _, v := range vectors {
+			key, err := master.Derive(v.path)
+			So(err, ShouldBeNil)
+			So(key.IsPrivate(), ShouldBeTrue)
+			priv, err := key.PrivateKey()
+			So(err, ShouldBeNil)
+			So(hex.EncodeToString(priv.Serialize()), ShouldEqual, v.priv)
+			pub, err := key.PublicKey()
+			So(err, ShouldBeNil)
+			So(hex.EncodeToString(pub.Serialize()), ShouldEqual, v.pub)
+		}
+	})
+	Convey("Derive public child from public key", t, func() {
+		master, err := NewMaster(seed)
+		So(err, ShouldBeNil)
+		parent, err := master.Derive("m/0'")
+		So(err, ShouldBeNil)
+		neutered := parent.Neuter()
+		So(neutered.IsPrivate(), ShouldBeFalse)
+		So(neutered.Depth(), ShouldEqual, 1)
+		child, err := neutered.Child(1)
+		So(err, ShouldBeNil)
+		So(child.ChildNum(), ShouldEqual, 1)
+		pub, err := child.PublicKey()
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(pub.Serialize()), ShouldEqual,
+			"03501e454bf00751f24b1b489aa925215d66af2234e3891c3b21a52bedb3cd711c")
+		_, err = child.PrivateKey()
+		So(err, ShouldEqual, ErrNotPrivate)
+		_, err = neutered.Child(HardenedKeyStart)
+		So(err, ShouldEqual, ErrHardenedFromPublic)
+	})
+	Convey("Invalid seed and path", t, func() {
+		_, err := NewMaster(seed[:8])
+		So(err, ShouldEqual, ErrInvalidSeed)
+		for _, p := range []string{"", "0/1", "m/", "m/a", "m/2147483648", "m/-1"} {
+			_, err = ParsePath(p)
+			So(err, ShouldEqual, ErrInvalidPath)
+		}
+		indexes, err := ParsePath(AccountPath(1))
+		So(err, ShouldBeNil)
+		So(indexes, ShouldResemble, []uint32{
+			HardenedKeyStart + 44, HardenedKeyStart + CoinType, HardenedKeyStart + 1, 0, 0,
+		})
+	})
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultEntropyBits defines the entropy bits of a new mnemonic, which makes 12 words.
+	DefaultEntropyBits = 128
+	// MinEntropyBits defines the minimum entropy bits of mnemonic.
+	MinEntropyBits = 128
+	// MaxEntropyBits defines the maximum entropy bits of mnemonic.
+	MaxEntropyBits = 256
+
+	wordCount      = 2048
+	wordBits       = 11
+	seedIterations = 2048
+	seedLen        = 64
+)
+
+var (
+	// ErrInvalidEntropy indicates that the entropy length is not a multiple of 32 bits between
+	// MinEntropyBits and MaxEntropyBits.
+	ErrInvalidEntropy = errors.New("invalid entropy length")
+	// ErrInvalidMnemonic indicates that the mnemonic has an invalid word count or unknown words.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	// ErrMnemonicChecksum indicates that the mnemonic checksum doesn't match.
+	ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch")
+	// ErrNonASCIIPassphrase indicates that the passphrase contains non-ASCII characters, which
+	// requires NFKD normalization that is not supported.
+	ErrNonASCIIPassphrase = errors.New("passphrase contains non-ASCII characters")
+
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, wordCount)
+		for i, w := range englishWords {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+// NewEntropy returns random entropy of bits to generate mnemonic.
+func NewEntropy(bits int) (entropy []byte, err error) {
+	if bits < MinEntropyBits || bits > MaxEntropyBits || bits%32 != 0 {
+		return nil, ErrInvalidEntropy
+	}
+	entropy = make([]byte, bits/8)
+	_, err = rand.Read(entropy)
+	return
+}
+
+// NewMnemonic returns the mnemonic of entropy, the first entropy bits/32 bits of its sha256 hash
+// is appended to entropy as checksum, and each 11 bits are encoded as a word.
+func NewMnemonic(entropy []byte) (mnemonic string, err error) {
+	bits := len(entropy) * 8
+	if bits < MinEntropyBits || bits > MaxEntropyBits || bits%32 != 0 {
+		return "", ErrInvalidEntropy
+	}
+	var (
+		checksum = sha256.Sum256(entropy)
+		data     = append(append([]byte{}, entropy...), checksum[0])
+		words    = make([]string, (bits+bits/32)/wordBits)
+	)
+	for i := range words {
+		var idx int
+		for j := 0; j < wordBits; j++ {
+			idx = idx<<1 | bit(data, i*wordBits+j)
+		}
+		words[i] = englishWords[idx]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy returns the entropy of mnemonic and verifies its checksum.
+func MnemonicToEntropy(mnemonic string) (entropy []byte, err error) {
+	if !isASCII(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	var (
+		words = strings.Fields(mnemonic)
+		total = len(words) * wordBits
+		bits  = total * 32 / 33
+	)
+	if total%33 != 0 || bits < MinEntropyBits || bits > MaxEntropyBits {
+		return nil, ErrInvalidMnemonic
+	}
+	data := make([]byte, (total+7)/8)
+	for i, w := range words {
+		idx, ok := wordIndex[strings.ToLower(w)]
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		for j := 0; j < wordBits; j++ {
+			if idx&(1<<uint(wordBits-1-j)) != 0 {
+				pos := i*wordBits + j
+				data[pos/8] |= 0x80 >> uint(pos%8)
+			}
+		}
+	}
+	entropy = data[:bits/8]
+	checksum := sha256.Sum256(entropy)
+	for i := bits; i < total; i++ {
+		if bit(data, i) != bit(checksum[:], i-bits) {
+			return nil, ErrMnemonicChecksum
+		}
+	}
+	return
+}
+
+// IsMnemonicValid reports whether mnemonic has valid words and checksum.
+func IsMnemonicValid(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// NewSeed returns the 64 bytes seed of mnemonic protected by an optional passphrase, which is
+// derived by PBKDF2-HMAC-SHA512 with 2048 iterations. BIP-39 requires NFKD normalization of
+// the mnemonic and passphrase, which is a no-op for ASCII, so non-ASCII passphrase is rejected
+// instead of producing a seed incompatible with other wallets.
+func NewSeed(mnemonic, passphrase string) (seed []byte, err error) {
+	if _, err = MnemonicToEntropy(mnemonic); err != nil {
+		return
+	}
+	if !isASCII(passphrase) {
+		return nil, ErrNonASCIIPassphrase
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2([]byte(normalized), []byte("mnemonic"+passphrase), seedIterations, seedLen), nil
+}
+
+// isASCII reports whether s contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// bit returns the i-th bit of data in big-endian order.
+func bit(data []byte, i int) int {
+	return int(data[i/8]>>uint(7-i%8)) & 1
+}
+
+// pbkdf2 derives a key of keyLen bytes from password and salt with HMAC-SHA512 as defined in
+// RFC 2898.
+func pbkdf2(password, salt []byte, iter, keyLen int) (key []byte) {
+	var (
+		prf   = hmac.New(sha512.New, password)
+		block = make([]byte, 4)
+		u     []byte
+	)
+	for i := uint32(1); len(key) < keyLen; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(block, i)
+		prf.Write(block)
+		u = prf.Sum(u[:0])
+		t := append([]byte{}, u...)
+		for n := 1; n < iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hd
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMnemonic(t *testing.T) {
+	Convey("Test vectors of BIP39", t, func() {
+		vectors := []struct {
+			entropy  string
+			mnemonic string
+			seed     string
+		}{
+			{
+				entropy:  "00000000000000000000000000000000",
+				mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+				seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+			},
+			{
+				entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+				mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+				seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+			},
+			{
+				entropy:  "9e885d952ad362caeb4efe34a8e91bd2",
+				mnemonic: "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+				seed:     "274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+			},
+			{
+				entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+				mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+				seed:     "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+			},
+		}
+		for _, v := range vectors {
+			entropy, err := hex.DecodeString(v.entropy)
+			So(err, ShouldBeNil)
+			mnemonic, err := NewMnemonic(entropy)
+			So(err, ShouldBeNil)
+			So(mnemonic, ShouldEqual, v.mnemonic)
+			decoded, err := MnemonicToEntropy(mnemonic)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, entropy)
+			seed, err := NewSeed(mnemonic, "TREZOR")
+			So(err, ShouldBeNil)
+			So(hex.EncodeToString(seed), ShouldEqual, v.seed)
+		}
+	})
+	Convey("Generate random mnemonic", t, func() {
+		for _, bits := range []int{128, 160, 192, 224, 256} {
+			entropy, err := NewEntropy(bits)
+			So(err, ShouldBeNil)
+			mnemonic, err := NewMnemonic(entropy)
+			So(err, ShouldBeNil)
+			So(len(strings.Fields(mnemonic)), ShouldEqual, bits*33/32/11)
+			So(IsMnemonicValid(mnemonic), ShouldBeTrue)
+		}
+		_, err := NewEntropy(64)
+		So(err, ShouldEqual, ErrInvalidEntropy)
+		_, err = NewMnemonic(make([]byte, 17))
+		So(err, ShouldEqual, ErrInvalidEntropy)
+	})
+	Convey("Mnemonic is normalized", t, func() {
+		seed, err := NewSeed(" Legal winner thank year wave sausage\tworth useful legal winner thank yellow\n", "TREZOR")
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(seed), ShouldStartWith, "2e8905819b8723fe")
+	})
+	Convey("Invalid mnemonic", t, func() {
+		_, err := MnemonicToEntropy("abandon abandon abandon")
+		So(err, ShouldEqual, ErrInvalidMnemonic)
+		_, err = MnemonicToEntropy(strings.Repeat("abandon ", 11) + "covenant")
+		So(err, ShouldEqual, ErrInvalidMnemonic)
+		_, err = MnemonicToEntropy(strings.Repeat("abandon ", 12))
+		So(err, ShouldEqual, ErrMnemonicChecksum)
+		// KELVIN SIGN is lowered to "k", which must not match "kit"
+		_, err = MnemonicToEntropy("\u212ait " + strings.Repeat("abandon ", 11))
+		So(err, ShouldEqual, ErrInvalidMnemonic)
+		_, err = NewSeed(strings.Repeat("zoo ", 12), "")
+		So(err, ShouldEqual, ErrMnemonicChecksum)
+	})
+	Convey("Non-ASCII passphrase is rejected", t, func() {
+		mnemonic := strings.Repeat("abandon ", 11) + "about"
+		for _, passphrase := range []string{"caf\u00e9", "cafe\u0301", "\uff34\uff32\uff25\uff3a\uff2f\uff32"} {
+			_, err := NewSeed(mnemonic, passphrase)
+			So(err, ShouldEqual, ErrNonASCIIPassphrase)
+		}
+		_, err := NewSeed(mnemonic, "TREZOR")
+		So(err, ShouldBeNil)
+	})
+}
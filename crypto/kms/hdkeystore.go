@@ -0,0 +1,52 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kms
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hd"
+)
+
+// NewMnemonic generates a mnemonic seed phrase of 12 words, which should be written down by
+// user to recover the keys derived from it.
+func NewMnemonic() (mnemonic string, err error) {
+	var entropy []byte
+	if entropy, err = hd.NewEntropy(hd.DefaultEntropyBits); err != nil {
+		return
+	}
+	return hd.NewMnemonic(entropy)
+}
+
+// DeriveAccountKey derives the private key of account index from mnemonic and an optional
+// passphrase, the key of a node or wallet is recovered by the same mnemonic and index.
+func DeriveAccountKey(mnemonic, passphrase string, index uint32) (key *asymmetric.PrivateKey, err error) {
+	var (
+		seed   []byte
+		master *hd.ExtendedKey
+		child  *hd.ExtendedKey
+	)
+	if seed, err = hd.NewSeed(mnemonic, passphrase); err != nil {
+		return
+	}
+	if master, err = hd.NewMaster(seed); err != nil {
+		return
+	}
+	if child, err = master.Derive(hd.AccountPath(index)); err != nil {
+		return
+	}
+	return child.PrivateKey()
+}
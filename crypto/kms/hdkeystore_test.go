@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kms
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeriveAccountKey(t *testing.T) {
+	Convey("derive account keys from mnemonic", t, func() {
+		mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+		key, err := DeriveAccountKey(mnemonic, "", 0)
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(key.Serialize()), ShouldEqual,
+			"7184737ccd4106bdf0a9943545800aa430cb1627db59873cd31dc6cf12d8ae25")
+		key, err = DeriveAccountKey(mnemonic, "", 1)
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(key.Serialize()), ShouldEqual,
+			"5d2e87febbcee14d0ed1ea9ba4e5fc4aefcb64811d7925e6aee27855ca53eb22")
+		key, err = DeriveAccountKey(mnemonic, "TREZOR", 0)
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(key.Serialize()), ShouldEqual,
+			"b7a812d7293f61e3fbb4b331a0ac89fe26ed806458475287e121ec8278b275c2")
+		_, err = DeriveAccountKey("abandon abandon", "", 0)
+		So(err, ShouldEqual, hd.ErrInvalidMnemonic)
+	})
+	Convey("recover key from new mnemonic", t, func() {
+		mnemonic, err := NewMnemonic()
+		So(err, ShouldBeNil)
+		So(hd.IsMnemonicValid(mnemonic), ShouldBeTrue)
+		key1, err := DeriveAccountKey(mnemonic, "", 0)
+		So(err, ShouldBeNil)
+		key2, err := DeriveAccountKey(mnemonic, "", 0)
+		So(err, ShouldBeNil)
+		So(key1.Serialize(), ShouldResemble, key2.Serialize())
+	})
+}
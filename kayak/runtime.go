@@ -145,6 +145,15 @@ func (r *Runtime) GetLog(offset uint64) (data []byte, err error) {
 	return
 }
 
+// LastCommittedIndex returns the index of last log committed by runner.
+func (r *Runtime) LastCommittedIndex() (index uint64, err error) {
+	if index, err = r.logStore.GetUint64(keyCommittedIndex); err == ErrKeyNotFound {
+		// nothing committed yet
+		err = nil
+	}
+	return
+}
+
 // UpdatePeers defines common peers update logic.
 func (r *Runtime) UpdatePeers(peers *Peers) error {
 	// Verify peers
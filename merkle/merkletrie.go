@@ -76,6 +76,44 @@ func (merkle *Merkle) GetRoot() *hash.Hash {
 	return merkle.tree[len(merkle.tree)-1]
 }
 
+// GetProof returns the sibling hashes on the path from the item at index to the root, from the
+// bottom up. A missing right sibling is filled with the node itself, as it is merged in the tree.
+func (merkle *Merkle) GetProof(index uint64) (proof []*hash.Hash, ok bool) {
+	var (
+		size   = (uint64(len(merkle.tree)) + 1) / 2
+		offset uint64
+	)
+	if index >= size || merkle.tree[index] == nil {
+		return
+	}
+	for ; size > 1; size /= 2 {
+		sibling := merkle.tree[offset+(index^1)]
+		if sibling == nil {
+			sibling = merkle.tree[offset+index]
+		}
+		proof = append(proof, sibling)
+		offset += size
+		index /= 2
+	}
+	ok = true
+	return
+}
+
+// VerifyProof verifies that item is at index of the merkle tree with root by proof returned by
+// GetProof.
+func VerifyProof(item *hash.Hash, index uint64, proof []*hash.Hash, root *hash.Hash) bool {
+	h := item
+	for _, sibling := range proof {
+		if index&1 == 0 {
+			h = MergeTwoHash(h, sibling)
+		} else {
+			h = MergeTwoHash(sibling, h)
+		}
+		index /= 2
+	}
+	return index == 0 && h.IsEqual(root)
+}
+
 // MergeTwoHash computes the hash of the concatenate of two hash
 func MergeTwoHash(l *hash.Hash, r *hash.Hash) *hash.Hash {
 	result := hash.THashH(append(append([]byte{}, (*l)[:]...), (*r)[:]...))
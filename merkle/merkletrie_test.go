@@ -46,6 +46,29 @@ func TestNewMerkle(t *testing.T) {
 	})
 }
 
+func TestMerkleProof(t *testing.T) {
+	Convey("Proofs of all items should be verified by the root", t, func() {
+		for _, n := range []int{1, 2, 3, 5, 8} {
+			items := make([]*hash.Hash, n)
+			for i := range items {
+				items[i] = &hash.Hash{}
+				rand.Read(items[i][:])
+			}
+			merkle := NewMerkle(items)
+			root := merkle.GetRoot()
+			for i := range items {
+				proof, ok := merkle.GetProof(uint64(i))
+				So(ok, ShouldBeTrue)
+				So(VerifyProof(items[i], uint64(i), proof, root), ShouldBeTrue)
+				So(VerifyProof(items[i], uint64(i)+1<<uint(len(proof)), proof, root), ShouldBeFalse)
+				So(VerifyProof(&hash.Hash{}, uint64(i), proof, root), ShouldBeFalse)
+			}
+			_, ok := merkle.GetProof(uint64(n))
+			So(ok, ShouldBeFalse)
+		}
+	})
+}
+
 func mergeHash(h0 *hash.Hash, h1 *hash.Hash) *hash.Hash {
 	h := hash.THashH(append(h0[:], h1[:]...))
 	return &h
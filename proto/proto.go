@@ -30,11 +30,15 @@ type EnvelopeAPI interface {
 	GetTTL() time.Duration
 	GetExpire() time.Duration
 	GetNodeID() *RawNodeID
+	GetTraceContext() map[string]string
+	GetRemoteAddr() string
 
 	SetVersion(string)
 	SetTTL(time.Duration)
 	SetExpire(time.Duration)
 	SetNodeID(*RawNodeID)
+	SetTraceContext(map[string]string)
+	SetRemoteAddr(string)
 }
 
 // Envelope is the protocol header
@@ -43,6 +47,14 @@ type Envelope struct {
 	TTL     time.Duration
 	Expire  time.Duration
 	NodeID  *RawNodeID
+
+	// TraceContext carries the tracing span context of caller for server side continuation,
+	// it is not included in hash.
+	TraceContext map[string]string
+
+	// RemoteAddr is the network address of caller set by server on receiving the request, it is
+	// not included in hash.
+	RemoteAddr string
 }
 
 // PingReq is Ping RPC request
@@ -120,6 +132,16 @@ func (e *Envelope) GetNodeID() *RawNodeID {
 	return e.NodeID
 }
 
+// GetTraceContext implements EnvelopeAPI.GetTraceContext
+func (e *Envelope) GetTraceContext() map[string]string {
+	return e.TraceContext
+}
+
+// GetRemoteAddr implements EnvelopeAPI.GetRemoteAddr
+func (e *Envelope) GetRemoteAddr() string {
+	return e.RemoteAddr
+}
+
 // SetVersion implements EnvelopeAPI.SetVersion
 func (e *Envelope) SetVersion(ver string) {
 	e.Version = ver
@@ -140,5 +162,15 @@ func (e *Envelope) SetNodeID(nodeID *RawNodeID) {
 	e.NodeID = nodeID
 }
 
+// SetTraceContext implements EnvelopeAPI.SetTraceContext
+func (e *Envelope) SetTraceContext(ctx map[string]string) {
+	e.TraceContext = ctx
+}
+
+// SetRemoteAddr implements EnvelopeAPI.SetRemoteAddr
+func (e *Envelope) SetRemoteAddr(addr string) {
+	e.RemoteAddr = addr
+}
+
 // DatabaseID is database name, will be generated from UUID
 type DatabaseID string
@@ -109,6 +109,86 @@ const (
 	OBSAdviseAckedQuery
 	// OBSAdviseNewBlock is used by sqlchain to push new block to observers
 	OBSAdviseNewBlock
+	// DBSFetch is used by client to fetch rows from server side cursor
+	DBSFetch
+	// DBSCloseCursor is used by client to close server side cursor
+	DBSCloseCursor
+	// DBSCancel is used by client to cancel running query
+	DBSCancel
+	// DBSStatus is used by client to query database serving status of miner
+	DBSStatus
+	// DBSChanges is used by client to poll committed row changes of database
+	DBSChanges
+	// MCCNextAccountNonce is used by block producer main chain to allocate next nonce for transactions
+	MCCNextAccountNonce
+	// MCCUpdatePermission is used by block producer main chain to update database user permission
+	MCCUpdatePermission
+	// MCCQuerySQLChainProfile is used by block producer main chain to query confirmed database profile
+	MCCQuerySQLChainProfile
+	// MCCQueryAccountBalance is used by block producer main chain to query account balance
+	MCCQueryAccountBalance
+	// MCCTransfer is used by block producer main chain to transfer tokens between accounts
+	MCCTransfer
+	// MCCQueryTxState is used by block producer main chain to query transaction state
+	MCCQueryTxState
+	// BPDBListDatabases is used by client to list databases owned by or shared with the account
+	BPDBListDatabases
+	// MCCQueryDatabaseUsage is used by block producer main chain to query billing usage of database
+	MCCQueryDatabaseUsage
+	// BPDBEstimatePrice is used by client to estimate cost of database resource requirements
+	BPDBEstimatePrice
+	// MCCRegisterMiner is used by block producer main chain to register or deregister miner
+	MCCRegisterMiner
+	// MCCQueryMiners is used by block producer main chain to query registered miners
+	MCCQueryMiners
+	// MCCChallengeBilling is used by block producer main chain to challenge billing
+	MCCChallengeBilling
+	// MCCProveBilling is used by block producer main chain to prove challenged billing
+	MCCProveBilling
+	// MCCQueryBilling is used by block producer main chain to query billing dispute state
+	MCCQueryBilling
+	// MCCQueryFeeEstimate is used by block producer main chain to estimate transaction fee rates
+	MCCQueryFeeEstimate
+	// MCCCreateMultiSig is used by block producer main chain to create multi-signature account
+	MCCCreateMultiSig
+	// MCCProposeMultiSig is used by block producer main chain to propose multi-signature action
+	MCCProposeMultiSig
+	// MCCApproveMultiSig is used by block producer main chain to approve multi-signature action
+	MCCApproveMultiSig
+	// MCCQueryMultiSig is used by block producer main chain to query multi-signature account
+	MCCQueryMultiSig
+	// MCCSubmitMinerEvidence is used by block producer main chain to report miner misbehavior
+	MCCSubmitMinerEvidence
+	// MCCChallengeService is used by block producer main chain to challenge miner service
+	MCCChallengeService
+	// MCCProveService is used by block producer main chain to answer miner service challenge
+	MCCProveService
+	// MCCUpdateParams is used by block producer main chain to vote for chain parameters update
+	MCCUpdateParams
+	// MCCQueryParams is used by block producer main chain to query chain parameters
+	MCCQueryParams
+	// MCCQueryChainMode is used by block producer main chain to query the block history kept
+	MCCQueryChainMode
+	// MCCQueryAccountProof is used by block producer main chain to query account with state proof
+	MCCQueryAccountProof
+	// MCCQueryDatabaseProof is used by block producer main chain to query database with state proof
+	MCCQueryDatabaseProof
+	// MCCQueryTxReceipts is used by block producer main chain to query transaction states in batch
+	MCCQueryTxReceipts
+	// MCCQueryAccountEvents is used by block producer main chain to query or wait for account events
+	MCCQueryAccountEvents
+	// MCCQueryCheckpoint is used by block producer main chain to query finalized checkpoint
+	MCCQueryCheckpoint
+	// MCCCreateVesting is used by block producer main chain to lock tokens with release schedule
+	MCCCreateVesting
+	// MCCReleaseVesting is used by block producer main chain to release vested tokens
+	MCCReleaseVesting
+	// MCCRevokeVesting is used by block producer main chain to revoke revocable vesting
+	MCCRevokeVesting
+	// MCCQueryVesting is used by block producer main chain to query vesting
+	MCCQueryVesting
+	// MCCSimulateTx is used by block producer main chain to dry-run transaction
+	MCCSimulateTx
 )
 
 // String returns the RemoteFunc string
@@ -162,6 +242,86 @@ func (s RemoteFunc) String() string {
 		return "OBS.AdviseAckedQuery"
 	case OBSAdviseNewBlock:
 		return "OBS.AdviseNewBlock"
+	case DBSFetch:
+		return "DBS.Fetch"
+	case DBSCloseCursor:
+		return "DBS.CloseCursor"
+	case DBSCancel:
+		return "DBS.Cancel"
+	case DBSStatus:
+		return "DBS.Status"
+	case DBSChanges:
+		return "DBS.Changes"
+	case MCCNextAccountNonce:
+		return "MCC.NextAccountNonce"
+	case MCCUpdatePermission:
+		return "MCC.UpdatePermission"
+	case MCCQuerySQLChainProfile:
+		return "MCC.QuerySQLChainProfile"
+	case MCCQueryAccountBalance:
+		return "MCC.QueryAccountBalance"
+	case MCCTransfer:
+		return "MCC.Transfer"
+	case MCCQueryTxState:
+		return "MCC.QueryTxState"
+	case BPDBListDatabases:
+		return "BPDB.ListDatabases"
+	case MCCQueryDatabaseUsage:
+		return "MCC.QueryDatabaseUsage"
+	case BPDBEstimatePrice:
+		return "BPDB.EstimatePrice"
+	case MCCRegisterMiner:
+		return "MCC.RegisterMiner"
+	case MCCQueryMiners:
+		return "MCC.QueryMiners"
+	case MCCChallengeBilling:
+		return "MCC.ChallengeBilling"
+	case MCCProveBilling:
+		return "MCC.ProveBilling"
+	case MCCQueryBilling:
+		return "MCC.QueryBilling"
+	case MCCQueryFeeEstimate:
+		return "MCC.QueryFeeEstimate"
+	case MCCCreateMultiSig:
+		return "MCC.CreateMultiSig"
+	case MCCProposeMultiSig:
+		return "MCC.ProposeMultiSig"
+	case MCCApproveMultiSig:
+		return "MCC.ApproveMultiSig"
+	case MCCQueryMultiSig:
+		return "MCC.QueryMultiSig"
+	case MCCSubmitMinerEvidence:
+		return "MCC.SubmitMinerEvidence"
+	case MCCChallengeService:
+		return "MCC.ChallengeService"
+	case MCCProveService:
+		return "MCC.ProveService"
+	case MCCUpdateParams:
+		return "MCC.UpdateParams"
+	case MCCQueryParams:
+		return "MCC.QueryParams"
+	case MCCQueryChainMode:
+		return "MCC.QueryChainMode"
+	case MCCQueryAccountProof:
+		return "MCC.QueryAccountProof"
+	case MCCQueryDatabaseProof:
+		return "MCC.QueryDatabaseProof"
+	case MCCQueryTxReceipts:
+		return "MCC.QueryTxReceipts"
+	case MCCQueryAccountEvents:
+		return "MCC.QueryAccountEvents"
+	case MCCQueryCheckpoint:
+		return "MCC.QueryCheckpoint"
+	case MCCCreateVesting:
+		return "MCC.CreateVesting"
+	case MCCReleaseVesting:
+		return "MCC.ReleaseVesting"
+	case MCCRevokeVesting:
+		return "MCC.RevokeVesting"
+	case MCCQueryVesting:
+		return "MCC.QueryVesting"
+	case MCCSimulateTx:
+		return "MCC.SimulateTx"
 	}
 	return "Unknown"
 }
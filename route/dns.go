@@ -176,6 +176,20 @@ func initBPNodeIDs() (bpNodeIDs NodeIDAddressMap) {
 	return resolver.bpNodeIDs
 }
 
+// AddBPNode registers an extra Block Producer node id and addr, used by clients
+// configured with block producer endpoints besides the config file and DNS seed
+func AddBPNode(id *proto.RawNodeID, addr string) (err error) {
+	initResolver()
+	if id == nil {
+		return ErrNilNodeID
+	}
+	resolver.Lock()
+	defer resolver.Unlock()
+	resolver.cache[*id] = addr
+	resolver.bpNodeIDs[*id] = addr
+	return
+}
+
 // GetBPs returns the known BP node id list
 func GetBPs() (BPAddrs []proto.NodeID) {
 	BPAddrs = make([]proto.NodeID, 0, len(resolver.bpNodeIDs))
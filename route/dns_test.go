@@ -65,6 +65,19 @@ func TestResolver(t *testing.T) {
 
 		So(IsBPNodeID(nodeA), ShouldBeFalse)
 
+		err = AddBPNode(nil, "127.0.0.1:2120")
+		So(err, ShouldEqual, ErrNilNodeID)
+
+		nodeB := &proto.RawNodeID{
+			Hash: hash.Hash([32]byte{0xbb, 0xbb}),
+		}
+		err = AddBPNode(nodeB, "127.0.0.1:2120")
+		So(err, ShouldBeNil)
+		So(IsBPNodeID(nodeB), ShouldBeTrue)
+		addr, err = GetNodeAddrCache(nodeB)
+		So(err, ShouldBeNil)
+		So(addr, ShouldEqual, "127.0.0.1:2120")
+
 		BPmap := initBPNodeIDs()
 		log.Debugf("BPmap: %v", BPmap)
 		BPs := GetBPs()
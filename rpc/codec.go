@@ -26,6 +26,8 @@ import (
 type NodeAwareServerCodec struct {
 	rpc.ServerCodec
 	NodeID *proto.RawNodeID
+	// RemoteAddr is the network address of the connection, which overrides the one sent by caller
+	RemoteAddr string
 }
 
 // NewNodeAwareServerCodec returns new NodeAwareServerCodec with normal rpc.ServerCode and proto.RawNodeID
@@ -49,8 +51,9 @@ func (nc *NodeAwareServerCodec) ReadRequestBody(body interface{}) (err error) {
 	}
 
 	if r, ok := body.(proto.EnvelopeAPI); ok {
-		// inject node id to rpc envelope
+		// inject node id and remote address to rpc envelope
 		r.SetNodeID(nc.NodeID)
+		r.SetRemoteAddr(nc.RemoteAddr)
 	}
 
 	return
@@ -157,6 +157,7 @@ sessionLoop:
 				RawToString: true,
 			})
 			nodeAwareCodec := NewNodeAwareServerCodec(msgpackCodec, remoteNodeID)
+			nodeAwareCodec.RemoteAddr = conn.RemoteAddr().String()
 			go s.rpcServer.ServeCodec(nodeAwareCodec)
 		}
 	}
@@ -855,14 +855,16 @@ func (c *Chain) FetchAckedQuery(height int32, header *hash.Hash) (
 		err = c.db.View(func(tx *bolt.Tx) (err error) {
 			for i := height - c.rt.queryTTL; i <= height; i++ {
 				if b := tx.Bucket(metaBucket[:]).Bucket(metaHeightIndexBucket).Bucket(
-					heightToKey(height)); b != nil {
+					heightToKey(i)); b != nil {
 					if v := b.Bucket(metaAckIndexBucket).Get(header[:]); v != nil {
 						dec := &wt.SignedAckHeader{}
 
 						if err = utils.DecodeMsgPack(v, dec); err != nil {
-							ack = dec
-							break
+							return
 						}
+
+						ack = dec
+						break
 					}
 				}
 			}
@@ -878,6 +880,38 @@ func (c *Chain) FetchAckedQuery(height int32, header *hash.Hash) (
 	return
 }
 
+// LastCommittedLogOffset returns the last log offset of write queries committed by the block at
+// height, which is the largest log offset acknowledged in the nearest block containing write
+// queries at or below height. Zero is returned if no write query is committed yet.
+func (c *Chain) LastCommittedLogOffset(height int32) (offset uint64, err error) {
+	for h := height; h >= 0; h-- {
+		var b *ct.Block
+		if b, err = c.FetchBlock(h); err != nil {
+			return
+		}
+		if b == nil {
+			continue
+		}
+
+		for _, q := range b.Queries {
+			var ack *wt.SignedAckHeader
+			if ack, err = c.FetchAckedQuery(h, q); err != nil {
+				return
+			}
+			if resp := ack.SignedResponseHeader(); resp.Request.QueryType == wt.WriteQuery &&
+				resp.LogOffset > offset {
+				offset = resp.LogOffset
+			}
+		}
+
+		if offset > 0 {
+			return
+		}
+	}
+
+	return
+}
+
 // syncAckedQuery uses RPC call to synchronize an acknowledged query from a remote node.
 func (c *Chain) syncAckedQuery(height int32, header *hash.Hash, id proto.NodeID) (
 	ack *wt.SignedAckHeader, err error,
@@ -1043,6 +1077,27 @@ func (c *Chain) UpdatePeers(peers *kayak.Peers) error {
 	return c.rt.updatePeers(peers)
 }
 
+// HeadLag returns the number of turns that the head block of the sql-chain falls behind.
+func (c *Chain) HeadLag() int32 {
+	head := c.rt.getHead()
+	if head == nil {
+		return 0
+	}
+	if lag := c.rt.getNextTurn() - 1 - head.Height; lag > 0 {
+		return lag
+	}
+	return 0
+}
+
+// HeadHeight returns the height of the head block of the sql-chain, -1 if there is no block yet.
+func (c *Chain) HeadHeight() int32 {
+	head := c.rt.getHead()
+	if head == nil {
+		return -1
+	}
+	return head.Height
+}
+
 // getBilling returns a billing request from the blocks within height range [low, high].
 func (c *Chain) getBilling(low, high int32) (req *pt.BillingRequest, err error) {
 	// Height `n` is ensured (or skipped) if `Next Turn` > `n` + 1
@@ -1057,6 +1112,7 @@ func (c *Chain) getBilling(low, high int32) (req *pt.BillingRequest, err error)
 		ack                 *wt.SignedAckHeader
 		lowBlock, highBlock *ct.Block
 		billings            = make(map[proto.AccountAddress]*proto.AddrAndGas)
+		reads, writes       uint64
 	)
 
 	if head := c.rt.getHead(); head != nil {
@@ -1097,6 +1153,12 @@ func (c *Chain) getBilling(low, high int32) (req *pt.BillingRequest, err error)
 				return
 			}
 
+			if ack.SignedRequestHeader().QueryType == wt.WriteQuery {
+				writes += ack.SignedRequestHeader().BatchCount
+			} else {
+				reads += ack.SignedRequestHeader().BatchCount
+			}
+
 			if billing, ok := billings[addr]; ok {
 				billing.GasAmount += c.rt.price[ack.SignedRequestHeader().QueryType] *
 					ack.SignedRequestHeader().BatchCount
@@ -1130,6 +1192,8 @@ func (c *Chain) getBilling(low, high int32) (req *pt.BillingRequest, err error)
 			HighBlock:  *highBlock.BlockHash(),
 			HighHeight: high,
 			GasAmounts: gasAmounts,
+			ReadCount:  reads,
+			WriteCount: writes,
 		},
 	}
 	return
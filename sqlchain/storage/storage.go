@@ -19,6 +19,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
@@ -26,9 +27,12 @@ import (
 
 	"github.com/CovenantSQL/CovenantSQL/twopc"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/CovenantSQL/go-sqlite3-encrypt"
+)
 
-	// Register CovenantSQL/go-sqlite3-encrypt engine.
-	_ "github.com/CovenantSQL/go-sqlite3-encrypt"
+const (
+	// driverName is the name of the sqlite3 driver which records row changes of connections.
+	driverName = "sqlite3-recorder"
 )
 
 var (
@@ -38,8 +42,81 @@ var (
 	}{
 		db: make(map[string]*sql.DB),
 	}
+
+	// recorders holds the change recorder registered on each open connection.
+	recorders = struct {
+		sync.Mutex
+		conn map[*sqlite3.SQLiteConn]*changeRecorder
+	}{
+		conn: make(map[*sqlite3.SQLiteConn]*changeRecorder),
+	}
 )
 
+func init() {
+	sql.Register(driverName, &recorderDriver{
+		SQLiteDriver: sqlite3.SQLiteDriver{
+			ConnectHook: registerRecorder,
+		},
+	})
+}
+
+// registerRecorder registers the update hook of a newly opened connection, the hook is kept until
+// the connection is closed by connection pool.
+func registerRecorder(conn *sqlite3.SQLiteConn) error {
+	r := &changeRecorder{}
+	conn.RegisterUpdateHook(r.record)
+
+	recorders.Lock()
+	defer recorders.Unlock()
+	recorders.conn[conn] = r
+	return nil
+}
+
+// recorderKey is the context key of the change recorder target passed to BeginTx.
+type recorderKey struct{}
+
+// recorderDriver wraps the sqlite3 driver to track the change recorder of connections.
+type recorderDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+// Open implements driver.Driver.Open.
+func (d *recorderDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &recorderConn{SQLiteConn: conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// recorderConn wraps the sqlite3 connection to drop its change recorder on close.
+type recorderConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// BeginTx implements driver.ConnBeginTx.BeginTx, the change recorder of the connection is set to
+// the target carried by ctx if any.
+func (c *recorderConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if target, ok := ctx.Value(recorderKey{}).(**changeRecorder); ok {
+		recorders.Lock()
+		*target = recorders.conn[c.SQLiteConn]
+		recorders.Unlock()
+	}
+
+	return c.SQLiteConn.BeginTx(ctx, opts)
+}
+
+// Close implements driver.Conn.Close.
+func (c *recorderConn) Close() error {
+	recorders.Lock()
+	delete(recorders.conn, c.SQLiteConn)
+	recorders.Unlock()
+
+	return c.SQLiteConn.Close()
+}
+
 // Query represents the single query of sqlite.
 type Query struct {
 	Pattern string
@@ -72,7 +149,7 @@ func openDB(dsn string) (db *sql.DB, err error) {
 
 	if (fn == ":memory:" || mode == "memory") && cache != "shared" {
 		// Return a new DB instance if it's in memory and private.
-		db, err = sql.Open("sqlite3", fdsn)
+		db, err = sql.Open(driverName, fdsn)
 		return
 	}
 
@@ -81,7 +158,7 @@ func openDB(dsn string) (db *sql.DB, err error) {
 	index.Unlock()
 
 	if !ok {
-		db, err = sql.Open("sqlite3", fdsn)
+		db, err = sql.Open(driverName, fdsn)
 
 		if err != nil {
 			return nil, err
@@ -109,11 +186,13 @@ func equalTxID(x, y *TxID) bool {
 // Storage represents a underlying storage implementation based on sqlite3.
 type Storage struct {
 	sync.Mutex
-	dsn     string
-	db      *sql.DB
-	tx      *sql.Tx // Current tx
-	id      TxID
-	queries []Query
+	dsn      string
+	db       *sql.DB
+	conn     *sql.Conn // Connection of current tx
+	tx       *sql.Tx   // Current tx
+	id       TxID
+	queries  []Query
+	recorder *changeRecorder
 }
 
 // New returns a new storage connected by dsn.
@@ -151,9 +230,7 @@ func (s *Storage) Prepare(ctx context.Context, wb twopc.WriteBatch) (err error)
 			"conn = %d, seq = %d, time = %d", s.id.ConnectionID, s.id.SeqNo, s.id.Timestamp)
 	}
 
-	s.tx, err = s.db.BeginTx(ctx, nil)
-
-	if err != nil {
+	if err = s.beginTx(ctx); err != nil {
 		return
 	}
 
@@ -163,12 +240,58 @@ func (s *Storage) Prepare(ctx context.Context, wb twopc.WriteBatch) (err error)
 	return nil
 }
 
+// QueryResult defines the result of a single query in exec log.
+type QueryResult struct {
+	LastInsertID int64
+	RowsAffected int64
+}
+
+// ExecResult defines the result of a committed exec log.
+type ExecResult struct {
+	LastInsertID int64         // last inserted row id of the last query
+	RowsAffected int64         // total affected rows of all queries
+	Queries      []QueryResult // result of each query
+	Changes      []RowChange   // rows changed by all queries
+}
+
+// RowChange defines a row changed by a committed exec log.
+type RowChange struct {
+	Op    int    // one of sqlite3.SQLITE_INSERT, sqlite3.SQLITE_UPDATE and sqlite3.SQLITE_DELETE
+	Table string // name of the changed table
+	RowID int64  // rowid of the changed row, equals to the INTEGER PRIMARY KEY if any
+}
+
+// changeRecorder collects row changes reported by the sqlite update hook of a connection.
+type changeRecorder struct {
+	active  bool
+	changes []RowChange
+}
+
+func (r *changeRecorder) record(op int, db string, table string, rowID int64) {
+	if !r.active {
+		return
+	}
+
+	r.changes = append(r.changes, RowChange{
+		Op:    op,
+		Table: table,
+		RowID: rowID,
+	})
+}
+
 // Commit implements commit method of two-phase commit worker.
 func (s *Storage) Commit(ctx context.Context, wb twopc.WriteBatch) (err error) {
+	_, err = s.CommitWithResult(ctx, wb)
+	return
+}
+
+// CommitWithResult commits the prepared exec log and returns the execution result.
+func (s *Storage) CommitWithResult(ctx context.Context, wb twopc.WriteBatch) (result ExecResult, err error) {
 	el, ok := wb.(*ExecLog)
 
 	if !ok {
-		return errors.New("unexpected WriteBatch type")
+		err = errors.New("unexpected WriteBatch type")
+		return
 	}
 
 	s.Lock()
@@ -184,28 +307,42 @@ func (s *Storage) Commit(ctx context.Context, wb twopc.WriteBatch) (err error) {
 					args[i] = v
 				}
 
-				_, err = s.tx.ExecContext(ctx, q.Pattern, args...)
+				var res sql.Result
+				res, err = s.tx.ExecContext(ctx, q.Pattern, args...)
 
 				if err != nil {
 					log.Debugf("commit query failed: %v", err)
 					s.tx.Rollback()
-					s.tx = nil
-					s.queries = nil
+					s.endTx()
 					return
 				}
+
+				// sqlite driver never fails on fetching these values
+				var qr QueryResult
+				qr.LastInsertID, _ = res.LastInsertId()
+				qr.RowsAffected, _ = res.RowsAffected()
+				result.LastInsertID = qr.LastInsertID
+				result.RowsAffected += qr.RowsAffected
+				result.Queries = append(result.Queries, qr)
 			}
 
 			s.tx.Commit()
-			s.tx = nil
-			s.queries = nil
-			return nil
+
+			if s.recorder != nil {
+				result.Changes = s.recorder.changes
+			}
+
+			s.endTx()
+			return
 		}
 
-		return fmt.Errorf("twopc: inconsistent state, currently in tx: "+
+		err = fmt.Errorf("twopc: inconsistent state, currently in tx: "+
 			"conn = %d, seq = %d, time = %d", s.id.ConnectionID, s.id.SeqNo, s.id.Timestamp)
+		return
 	}
 
-	return errors.New("twopc: tx not prepared")
+	err = errors.New("twopc: tx not prepared")
+	return
 }
 
 // Rollback implements rollback method of two-phase commit worker.
@@ -226,13 +363,55 @@ func (s *Storage) Rollback(ctx context.Context, wb twopc.WriteBatch) (err error)
 
 	if s.tx != nil {
 		s.tx.Rollback()
-		s.tx = nil
-		s.queries = nil
+		s.endTx()
 	}
 
 	return nil
 }
 
+// beginTx begins the tx of exec log on a dedicated connection, row changes applied on the
+// connection are recorded until the tx ends.
+func (s *Storage) beginTx(ctx context.Context) (err error) {
+	var conn *sql.Conn
+
+	if conn, err = s.db.Conn(ctx); err != nil {
+		return
+	}
+
+	var recorder *changeRecorder
+
+	if s.tx, err = conn.BeginTx(context.WithValue(ctx, recorderKey{}, &recorder), nil); err != nil {
+		conn.Close()
+		return
+	}
+
+	if recorder != nil {
+		recorder.active = true
+		recorder.changes = nil
+	}
+
+	s.conn = conn
+	s.recorder = recorder
+	return
+}
+
+// endTx releases the connection of current tx.
+func (s *Storage) endTx() {
+	if s.recorder != nil {
+		s.recorder.active = false
+		s.recorder.changes = nil
+		s.recorder = nil
+	}
+
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	s.tx = nil
+	s.queries = nil
+}
+
 // Query implements read-only query feature.
 func (s *Storage) Query(ctx context.Context, queries []Query) (columns []string, types []string,
 	data [][]interface{}, err error) {
@@ -242,17 +421,53 @@ func (s *Storage) Query(ctx context.Context, queries []Query) (columns []string,
 		return
 	}
 
-	var tx *sql.Tx
+	var c *Cursor
+	if c, err = s.QueryCursor(ctx, queries); err != nil {
+		return
+	}
+
+	// free result set
+	defer c.Close()
+
+	columns, types = c.Columns, c.Types
+	data, _, err = c.Fetch(0)
+	return
+}
+
+// Cursor represents the result set of a read-only query, rows are fetched in batches within the
+// same read transaction.
+type Cursor struct {
+	Columns []string
+	Types   []string
+
+	tx      *sql.Tx
+	rows    *sql.Rows
+	scanner *rowScanner
+}
+
+// QueryCursor executes the first query of queries in a read-only transaction and returns the
+// cursor of result set, the cursor should be closed to release the transaction.
+func (s *Storage) QueryCursor(ctx context.Context, queries []Query) (c *Cursor, err error) {
+	if len(queries) == 0 {
+		err = errors.New("no query to execute")
+		return
+	}
+
 	var txOptions = &sql.TxOptions{
 		ReadOnly: true,
 	}
 
-	if tx, err = s.db.BeginTx(ctx, txOptions); err != nil {
+	c = &Cursor{}
+
+	if c.tx, err = s.db.BeginTx(ctx, txOptions); err != nil {
 		return
 	}
 
-	// always rollback on complete
-	defer tx.Rollback()
+	defer func() {
+		if err != nil {
+			c.Close()
+		}
+	}()
 
 	q := queries[0]
 
@@ -263,36 +478,62 @@ func (s *Storage) Query(ctx context.Context, queries []Query) (columns []string,
 		args[i] = v
 	}
 
-	var rows *sql.Rows
-	if rows, err = tx.Query(q.Pattern, args...); err != nil {
+	if c.rows, err = c.tx.QueryContext(ctx, q.Pattern, args...); err != nil {
 		return
 	}
 
-	// free result set
-	defer rows.Close()
-
 	// get rows meta
-	if columns, err = rows.Columns(); err != nil {
+	if c.Columns, err = c.rows.Columns(); err != nil {
 		return
 	}
 
 	// get types meta
-	if types, err = s.transformColumnTypes(rows.ColumnTypes()); err != nil {
+	if c.Types, err = s.transformColumnTypes(c.rows.ColumnTypes()); err != nil {
 		return
 	}
 
-	rs := newRowScanner(len(columns))
+	c.scanner = newRowScanner(len(c.Columns))
 
-	for rows.Next() {
-		err = rows.Scan(rs.ScanArgs()...)
-		if err != nil {
+	return
+}
+
+// Fetch returns at most count rows of the result set, non-positive count fetches all remaining rows.
+// The eof flag reports if the result set is exhausted.
+func (c *Cursor) Fetch(count int) (data [][]interface{}, eof bool, err error) {
+	data = make([][]interface{}, 0)
+
+	if c.rows == nil {
+		eof = true
+		return
+	}
+
+	for count <= 0 || len(data) < count {
+		if !c.rows.Next() {
+			eof = true
+			err = c.rows.Err()
+			return
+		}
+
+		if err = c.rows.Scan(c.scanner.ScanArgs()...); err != nil {
 			return
 		}
 
-		data = append(data, rs.GetRow())
+		data = append(data, c.scanner.GetRow())
 	}
 
-	err = rows.Err()
+	return
+}
+
+// Close frees the result set and rollbacks the read transaction.
+func (c *Cursor) Close() (err error) {
+	if c.rows != nil {
+		c.rows.Close()
+		c.rows = nil
+	}
+	if c.tx != nil {
+		err = c.tx.Rollback()
+		c.tx = nil
+	}
 	return
 }
 
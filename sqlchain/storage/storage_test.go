@@ -24,6 +24,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/CovenantSQL/go-sqlite3-encrypt"
 )
 
 func newQuery(query string, args ...interface{}) (q Query) {
@@ -347,3 +349,231 @@ func TestStorage(t *testing.T) {
 		}
 	}
 }
+
+func TestCursor(t *testing.T) {
+	fl, err := ioutil.TempFile("", "sqlite3-")
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	st, err := New(fmt.Sprintf("file:%s", fl.Name()))
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	queries := []Query{newQuery("CREATE TABLE IF NOT EXISTS `kv` (`key` TEXT PRIMARY KEY, `value` BLOB)")}
+
+	for i := 0; i < 5; i++ {
+		queries = append(queries, newQuery("INSERT INTO `kv` VALUES (?, ?)", fmt.Sprintf("k%d", i), "v"))
+	}
+
+	if _, err = st.Exec(context.Background(), queries); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	if _, err = st.QueryCursor(context.Background(), nil); err == nil {
+		t.Fatal("Unexpected result: returned nil while expecting an error")
+	}
+
+	c, err := st.QueryCursor(context.Background(), []Query{newQuery("SELECT `key` FROM `kv` ORDER BY `key`")})
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.Columns, []string{"key"}) {
+		t.Fatalf("Unexpected columns: %v", c.Columns)
+	}
+
+	var fetched int
+
+	for _, expected := range []struct {
+		count int
+		eof   bool
+	}{{2, false}, {2, false}, {1, true}} {
+		data, eof, err := c.Fetch(2)
+
+		if err != nil {
+			t.Fatalf("Error occurred: %v", err)
+		}
+
+		if len(data) != expected.count || eof != expected.eof {
+			t.Fatalf("Unexpected result: count = %d, eof = %v", len(data), eof)
+		}
+
+		for _, row := range data {
+			if key := string(row[0].([]byte)); key != fmt.Sprintf("k%d", fetched) {
+				t.Fatalf("Unexpected row: %v", key)
+			}
+			fetched++
+		}
+	}
+
+	if err = c.Close(); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	if data, eof, err := c.Fetch(1); err != nil || !eof || len(data) != 0 {
+		t.Fatalf("Unexpected result on closed cursor: %v, %v, %v", data, eof, err)
+	}
+}
+
+func TestCommitWithResult(t *testing.T) {
+	fl, err := ioutil.TempFile("", "sqlite3-")
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	st, err := New(fmt.Sprintf("file:%s", fl.Name()))
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	el1 := &ExecLog{
+		ConnectionID: 1,
+		SeqNo:        1,
+		Timestamp:    time.Now().UnixNano(),
+		Queries: []Query{
+			newQuery("CREATE TABLE IF NOT EXISTS `kv` (`key` TEXT PRIMARY KEY, `value` BLOB)"),
+			newQuery("INSERT INTO `kv` VALUES ('k0', 'v0')"),
+			newQuery("INSERT INTO `kv` VALUES ('k1', 'v1')"),
+		},
+	}
+
+	el2 := &ExecLog{
+		ConnectionID: 1,
+		SeqNo:        2,
+		Timestamp:    time.Now().UnixNano(),
+		Queries: []Query{
+			newQuery("UPDATE `kv` SET `value` = 'v'"),
+		},
+	}
+
+	if err = st.Prepare(context.Background(), el1); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	result, err := st.CommitWithResult(context.Background(), el1)
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+	if result.LastInsertID != 2 || result.RowsAffected != 2 {
+		t.Fatalf("Error exec result: %+v", result)
+	}
+	if len(result.Queries) != 3 || result.Queries[1] != (QueryResult{1, 1}) ||
+		result.Queries[2] != (QueryResult{2, 1}) {
+		t.Fatalf("Error query results: %+v", result.Queries)
+	}
+
+	if err = st.Prepare(context.Background(), el2); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	result, err = st.CommitWithResult(context.Background(), el2)
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+	if result.RowsAffected != 2 {
+		t.Fatalf("Error exec result: %+v", result)
+	}
+}
+
+func TestRowChanges(t *testing.T) {
+	fl, err := ioutil.TempFile("", "sqlite3-")
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	recorders.Lock()
+	registered := len(recorders.conn)
+	recorders.Unlock()
+
+	st, err := New(fmt.Sprintf("file:%s", fl.Name()))
+
+	if err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	commit := func(seq uint64, queries ...string) (result ExecResult) {
+		el := &ExecLog{
+			ConnectionID: 1,
+			SeqNo:        seq,
+			Timestamp:    time.Now().UnixNano(),
+		}
+
+		for _, q := range queries {
+			el.Queries = append(el.Queries, newQuery(q))
+		}
+
+		if err = st.Prepare(context.Background(), el); err != nil {
+			t.Fatalf("Error occurred: %v", err)
+		}
+
+		if result, err = st.CommitWithResult(context.Background(), el); err != nil {
+			t.Fatalf("Error occurred: %v", err)
+		}
+
+		return
+	}
+
+	result := commit(1,
+		"CREATE TABLE `t` (`id` INTEGER PRIMARY KEY, `v` TEXT)",
+		"INSERT INTO `t` VALUES (5, 'a')",
+		"INSERT INTO `t` VALUES (7, 'b')",
+	)
+
+	if !reflect.DeepEqual(result.Changes, []RowChange{
+		{sqlite3.SQLITE_INSERT, "t", 5},
+		{sqlite3.SQLITE_INSERT, "t", 7},
+	}) {
+		t.Fatalf("Error row changes: %+v", result.Changes)
+	}
+
+	result = commit(2, "UPDATE `t` SET `v` = 'c' WHERE `id` = 7", "DELETE FROM `t` WHERE `id` = 5")
+
+	if !reflect.DeepEqual(result.Changes, []RowChange{
+		{sqlite3.SQLITE_UPDATE, "t", 7},
+		{sqlite3.SQLITE_DELETE, "t", 5},
+	}) {
+		t.Fatalf("Error row changes: %+v", result.Changes)
+	}
+
+	// rolled back changes are discarded
+	el := &ExecLog{
+		ConnectionID: 1,
+		SeqNo:        3,
+		Timestamp:    time.Now().UnixNano(),
+		Queries:      []Query{newQuery("INSERT INTO `t` VALUES (9, 'd')")},
+	}
+
+	if err = st.Prepare(context.Background(), el); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	if err = st.Rollback(context.Background(), el); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	if result = commit(4, "SELECT 1"); len(result.Changes) != 0 {
+		t.Fatalf("Error row changes: %+v", result.Changes)
+	}
+
+	// recorders are dropped with the closed connections
+	if err = st.Close(); err != nil {
+		t.Fatalf("Error occurred: %v", err)
+	}
+
+	recorders.Lock()
+	defer recorders.Unlock()
+
+	if len(recorders.conn) != registered {
+		t.Fatalf("Error recorders count: %d, expected: %d", len(recorders.conn), registered)
+	}
+}
@@ -35,8 +35,28 @@ func PubKey2Addr(pubKey *asymmetric.PublicKey, version byte) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	h := hash.THashH(enc[:])
-	return base58.CheckEncode(h[:], version), nil
+	return Addr2Str(proto.AccountAddress(hash.THashH(enc[:])), version), nil
+}
+
+// Addr2Str encodes the account address to a base58 string with version and checksum, the same
+// as the address of public key returned by PubKey2Addr.
+func Addr2Str(addr proto.AccountAddress, version byte) string {
+	return base58.CheckEncode(addr[:], version)
+}
+
+// Str2Addr decodes the account address and its version from the string encoded by Addr2Str,
+// the checksum is verified to prevent typos.
+func Str2Addr(s string) (addr proto.AccountAddress, version byte, err error) {
+	var b []byte
+	if b, version, err = base58.CheckDecode(s); err != nil {
+		return
+	}
+	if len(b) != len(addr) {
+		err = ErrInvalidAddress
+		return
+	}
+	copy(addr[:], b)
+	return
 }
 
 func PubKeyHash(pubKey *asymmetric.PublicKey) (addr proto.AccountAddress, err error) {
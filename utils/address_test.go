@@ -93,3 +93,29 @@ func TestPubKeyHashAndAddressing(t *testing.T) {
 		}
 	})
 }
+
+func TestAddressChecksum(t *testing.T) {
+	Convey("Encode and decode the checksummed address", t, func() {
+		_, pub, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		h, err := PubKeyHash(pub)
+		So(err, ShouldBeNil)
+		for _, version := range []byte{MainNet, TestNet} {
+			s := Addr2Str(h, version)
+			addr, err := PubKey2Addr(pub, version)
+			So(err, ShouldBeNil)
+			So(s, ShouldEqual, addr)
+			decoded, v, err := Str2Addr(s)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, h)
+			So(v, ShouldEqual, version)
+		}
+	})
+	Convey("Reject the mistyped address", t, func() {
+		s := "1EcL9WYyB59jVLSX9kxFdfY53aDoWAKSFRkwwV2cvMMNCWj81J"
+		_, _, err := Str2Addr(s[:10] + "2" + s[11:])
+		So(err, ShouldEqual, base58.ErrChecksum)
+		_, _, err = Str2Addr(base58.CheckEncode([]byte{0x1, 0x2}, MainNet))
+		So(err, ShouldEqual, ErrInvalidAddress)
+	})
+}
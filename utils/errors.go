@@ -5,4 +5,6 @@ import "errors"
 var (
 	// ErrInvalidType defines invalid type.
 	ErrInvalidType = errors.New("invalid type")
+	// ErrInvalidAddress defines invalid account address.
+	ErrInvalidAddress = errors.New("invalid address")
 )
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package objstore provides storage of named objects in local directory or s3 compatible
+// services, which is used for backups and archives.
+package objstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store defines the destination of objects, names are relative to the destination.
+type Store interface {
+	Put(name string, data []byte) error
+	List(prefix string) ([]string, error)
+	Remove(name string) error
+}
+
+// fileStore stores objects as files in local directory.
+type fileStore struct {
+	dir string
+}
+
+func (s *fileStore) Put(name string, data []byte) (err error) {
+	// rename is atomic, half written object is never visible
+	tmp := filepath.Join(s.dir, "."+name+".tmp")
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+func (s *fileStore) List(prefix string) (names []string, err error) {
+	var files []os.FileInfo
+	if files, err = ioutil.ReadDir(s.dir); err != nil {
+		return
+	}
+	for _, f := range files {
+		if !f.IsDir() && !strings.HasPrefix(f.Name(), ".") && strings.HasPrefix(f.Name(), prefix) {
+			names = append(names, f.Name())
+		}
+	}
+	return
+}
+
+func (s *fileStore) Remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// s3Store stores objects of s3 bucket under prefix.
+type s3Store struct {
+	c      *s3Client
+	prefix string
+}
+
+func (s *s3Store) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(s.prefix, "/") + "/" + name
+}
+
+func (s *s3Store) Put(name string, data []byte) error {
+	return s.c.putObject(s.key(name), data)
+}
+
+func (s *s3Store) List(prefix string) (names []string, err error) {
+	var keys []string
+	if keys, err = s.c.listObjects(s.key(prefix)); err != nil {
+		return
+	}
+	for _, k := range keys {
+		names = append(names, strings.TrimPrefix(k, s.key("")))
+	}
+	return
+}
+
+func (s *s3Store) Remove(name string) error {
+	return s.c.deleteObject(s.key(name))
+}
+
+// Open opens the store of dest, which is file://DIR or
+// s3://BUCKET/PREFIX[?region=REGION&endpoint=URL] with credentials in AWS_* environment
+// variables. The directory is created if not exists.
+func Open(dest string) (store Store, err error) {
+	var u *url.URL
+	if u, err = url.Parse(dest); err != nil {
+		return
+	}
+	switch u.Scheme {
+	case "file":
+		dir := u.Path
+		if u.Host != "" {
+			// relative path like file://backups
+			dir = filepath.Join(u.Host, u.Path)
+		}
+		if err = os.MkdirAll(dir, 0700); err != nil {
+			return
+		}
+		store = &fileStore{dir: dir}
+	case "s3":
+		s := new(s3Store)
+		if s.c, s.prefix, err = newS3Client(u); err != nil {
+			return
+		}
+		store = s
+	default:
+		err = fmt.Errorf("unsupported object store %v", dest)
+	}
+	return
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFileStore(t *testing.T) {
+	Convey("test file store", t, func() {
+		dir, err := ioutil.TempDir("", "objstore_test_")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		store, err := Open("file://" + filepath.Join(dir, "sub"))
+		So(err, ShouldBeNil)
+
+		So(store.Put("a-1", []byte("1")), ShouldBeNil)
+		So(store.Put("a-2", []byte("2")), ShouldBeNil)
+		So(store.Put("b-1", []byte("3")), ShouldBeNil)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "a-2"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "2")
+
+		names, err := store.List("a-")
+		So(err, ShouldBeNil)
+		So(names, ShouldResemble, []string{"a-1", "a-2"})
+
+		So(store.Remove("a-1"), ShouldBeNil)
+		names, err = store.List("")
+		So(err, ShouldBeNil)
+		So(names, ShouldResemble, []string{"a-2", "b-1"})
+	})
+}
+
+func TestOpen(t *testing.T) {
+	Convey("test open store", t, func() {
+		_, err := Open("ftp://host/path")
+		So(err, ShouldNotBeNil)
+
+		os.Unsetenv("AWS_ACCESS_KEY_ID")
+		_, err = Open("s3://bucket/prefix")
+		So(err, ShouldEqual, ErrMissingS3Credentials)
+
+		os.Setenv("AWS_ACCESS_KEY_ID", "id")
+		os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+		defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+		store, err := Open("s3://bucket/prefix?region=us-west-2")
+		So(err, ShouldBeNil)
+		s := store.(*s3Store)
+		So(s.c.bucket, ShouldEqual, "bucket")
+		So(s.c.endpoint.String(), ShouldEqual, "https://s3.us-west-2.amazonaws.com")
+		So(s.key("x"), ShouldEqual, "prefix/x")
+	})
+}
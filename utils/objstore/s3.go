@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3TimeFormat    = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+)
+
+// ErrMissingS3Credentials indicates the credentials of s3 are not set in environment.
+var ErrMissingS3Credentials = errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for s3")
+
+// s3Client is a minimal s3 client supporting the basic object operations, requests are
+// signed with AWS signature version 4 and sent in path style, so s3 compatible services such as
+// minio are also supported with the endpoint parameter.
+type s3Client struct {
+	endpoint *url.URL
+	region   string
+	bucket   string
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	client *http.Client
+}
+
+// newS3Client creates client from url s3://BUCKET/PREFIX?region=REGION&endpoint=URL, the
+// credentials are read from environment variables like the aws cli does.
+func newS3Client(u *url.URL) (c *s3Client, prefix string, err error) {
+	c = &s3Client{
+		region:       u.Query().Get("region"),
+		bucket:       u.Host,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 10 * time.Minute},
+	}
+	if c.bucket == "" {
+		err = fmt.Errorf("bucket is not specified in %v", u)
+		return
+	}
+	if c.accessKey == "" || c.secretKey == "" {
+		err = ErrMissingS3Credentials
+		return
+	}
+	if c.region == "" {
+		if c.region = os.Getenv("AWS_REGION"); c.region == "" {
+			c.region = s3DefaultRegion
+		}
+	}
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + c.region + ".amazonaws.com"
+	}
+	if c.endpoint, err = url.Parse(endpoint); err != nil {
+		return
+	}
+	prefix = strings.TrimPrefix(u.Path, "/")
+	return
+}
+
+// s3Escape escapes s as required by signature version 4, slashes are kept if path is true.
+func s3Escape(s string, path bool) string {
+	var buf bytes.Buffer
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', path && b == '/':
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// do sends signed request of object key, the whole body is read to compute payload hash.
+func (c *s3Client) do(method, key string, query url.Values, body []byte) (resp *http.Response, err error) {
+	path := "/" + c.bucket
+	if key != "" {
+		path += "/" + key
+	}
+	escapedPath := s3Escape(path, true)
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, s3Escape(k, false)+"="+s3Escape(query.Get(k), false))
+	}
+	canonicalQuery := strings.Join(params, "&")
+
+	u := *c.endpoint
+	u.Path, u.RawPath, u.RawQuery = path, escapedPath, canonicalQuery
+	var req *http.Request
+	if req, err = http.NewRequest(method, u.String(), bytes.NewReader(body)); err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headerValues := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format(s3TimeFormat),
+	}
+	if c.sessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+		headerValues["x-amz-security-token"] = c.sessionToken
+	}
+	var canonicalHeaders bytes.Buffer
+	for _, h := range signedHeaders {
+		canonicalHeaders.WriteString(h + ":" + headerValues[h] + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapedPath,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{now.Format(s3DateFormat), c.region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+c.secretKey), now.Format(s3DateFormat))
+	signingKey = hmacSHA256(signingKey, c.region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, c.accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+
+	if resp, err = c.client.Do(req); err != nil {
+		return
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		err = fmt.Errorf("s3 %s %s failed: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	return
+}
+
+func (c *s3Client) putObject(key string, body []byte) (err error) {
+	var resp *http.Response
+	if resp, err = c.do(http.MethodPut, key, nil, body); err != nil {
+		return
+	}
+	return resp.Body.Close()
+}
+
+func (c *s3Client) deleteObject(key string) (err error) {
+	var resp *http.Response
+	if resp, err = c.do(http.MethodDelete, key, nil, nil); err != nil {
+		return
+	}
+	return resp.Body.Close()
+}
+
+// listObjects returns keys of objects with prefix in lexical order.
+func (c *s3Client) listObjects(prefix string) (keys []string, err error) {
+	var result struct {
+		Contents []struct {
+			Key string
+		}
+		IsTruncated           bool
+		NextContinuationToken string
+	}
+
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		var resp *http.Response
+		if resp, err = c.do(http.MethodGet, "", query, nil); err != nil {
+			return
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return
+		}
+		for _, o := range result.Contents {
+			keys = append(keys, o.Key)
+		}
+		if !result.IsTruncated {
+			return
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+		result.Contents = nil
+	}
+}
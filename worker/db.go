@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
@@ -40,11 +41,20 @@ const (
 	// StorageFileName defines storage file name of database instance.
 	StorageFileName = "storage.db3"
 
+	// HistoryFileName defines storage file name of the historical state snapshot.
+	HistoryFileName = "history.db3"
+
 	// SQLChainFileName defines sqlchain storage file name.
 	SQLChainFileName = "chain.db"
 
 	// MaxRecordedConnectionSequences defines the max connection slots to anti reply attack.
 	MaxRecordedConnectionSequences = 1000
+
+	// HealthPenaltyPerLagTurn defines the health score penalty of each turn the sqlchain falls behind.
+	HealthPenaltyPerLagTurn = 10
+
+	// HealthPenaltyPerInflightQuery defines the health score penalty of each query in processing.
+	HealthPenaltyPerInflightQuery = 1
 )
 
 // Database defines a single database instance in worker runtime.
@@ -57,6 +67,19 @@ type Database struct {
 	connSeqs       sync.Map
 	connSeqEvictCh chan uint64
 	chain          *sqlchain.Chain
+	inflight       int32
+	cursorLock     sync.Mutex
+	cursors        map[uint64]*cursorEntry
+	lastCursorID   uint64
+	cursorStopCh   chan struct{}
+	runningQueries sync.Map // map[wt.QueryKey]context.CancelFunc
+	pendingResults sync.Map // map[storage.TxID]*storage.ExecResult
+	changesLock    sync.Mutex
+	changes        []wt.ChangeEvent
+	evictedIndex   uint64 // changes of logs till this index are not retained
+	historyLock    sync.Mutex
+	history        *storage.Storage // historical state snapshot
+	historyOffset  uint64           // last log replayed to history
 }
 
 // NewDatabase create a single database instance using config.
@@ -76,6 +99,8 @@ func NewDatabase(cfg *DBConfig, peers *kayak.Peers, genesisBlock *ct.Block) (db
 		cfg:            cfg,
 		dbID:           cfg.DatabaseID,
 		connSeqEvictCh: make(chan uint64, 1),
+		cursors:        make(map[uint64]*cursorEntry),
+		cursorStopCh:   make(chan struct{}),
 	}
 
 	defer func() {
@@ -159,9 +184,17 @@ func NewDatabase(cfg *DBConfig, peers *kayak.Peers, genesisBlock *ct.Block) (db
 		return
 	}
 
+	// changes committed before start are not recorded
+	if db.evictedIndex, err = db.kayakRuntime.LastCommittedIndex(); err != nil {
+		return
+	}
+
 	// init sequence eviction processor
 	go db.evictSequences()
 
+	// init idle cursor eviction processor
+	go db.evictCursors()
+
 	return
 }
 
@@ -180,10 +213,20 @@ func (db *Database) Query(request *wt.Request) (response *wt.Response, err error
 		return
 	}
 
+	atomic.AddInt32(&db.inflight, 1)
+	defer atomic.AddInt32(&db.inflight, -1)
+
 	switch request.Header.QueryType {
 	case wt.ReadQuery:
 		return db.readQuery(request)
 	case wt.WriteQuery:
+		if request.Header.IsHistorical() {
+			return nil, ErrHistoricalWrite
+		}
+		if wt.IsExplainQueries(request.Payload.Queries) {
+			// explain changes nothing, serve it like read query instead of logging it
+			return db.readQuery(request)
+		}
 		return db.writeQuery(request)
 	default:
 		// TODO(xq262144): verbose errors with custom error structure
@@ -216,6 +259,23 @@ func (db *Database) Shutdown() (err error) {
 		}
 	}
 
+	if db.cursorStopCh != nil {
+		// stop idle cursor evictions
+		select {
+		case <-db.cursorStopCh:
+		default:
+			close(db.cursorStopCh)
+		}
+	}
+
+	// release read transactions held by cursors
+	db.closeCursors()
+
+	// remove historical state snapshot
+	db.historyLock.Lock()
+	db.closeHistory()
+	db.historyLock.Unlock()
+
 	if db.storage != nil {
 		// stop storage
 		if err = db.storage.Close(); err != nil {
@@ -274,6 +334,16 @@ func (db *Database) writeQuery(request *wt.Request) (response *wt.Response, err
 		return
 	}
 
+	// register pending result to collect exec result when the log is committed locally
+	txID := storage.TxID{
+		ConnectionID: request.Header.ConnectionID,
+		SeqNo:        request.Header.SeqNo,
+		Timestamp:    request.Header.Timestamp.UnixNano(),
+	}
+	result := new(storage.ExecResult)
+	db.pendingResults.Store(txID, result)
+	defer db.pendingResults.Delete(txID)
+
 	var logOffset uint64
 	logOffset, err = db.kayakRuntime.Apply(buf.Bytes())
 
@@ -281,7 +351,14 @@ func (db *Database) writeQuery(request *wt.Request) (response *wt.Response, err
 		return
 	}
 
-	return db.buildQueryResponse(request, logOffset, []string{}, []string{}, [][]interface{}{})
+	// result of each query is returned in payload
+	data := make([][]interface{}, len(result.Queries))
+	for i, r := range result.Queries {
+		data[i] = []interface{}{r.LastInsertID, r.RowsAffected}
+	}
+
+	return db.buildQueryResponse(request, logOffset, logOffset, 0, *result,
+		wt.WriteResultColumns, wt.WriteResultTypes, data)
 }
 
 func (db *Database) readQuery(request *wt.Request) (response *wt.Response, err error) {
@@ -290,16 +367,98 @@ func (db *Database) readQuery(request *wt.Request) (response *wt.Response, err e
 	var columns, types []string
 	var data [][]interface{}
 
-	columns, types, data, err = db.storage.Query(context.Background(), convertQuery(request.Payload.Queries))
+	if request.Header.IsHistorical() {
+		return db.historicalQuery(request)
+	}
+
+	if request.Header.FetchSize > 0 {
+		return db.readQueryWithCursor(request)
+	}
+
+	var committedIndex uint64
+	if committedIndex, err = db.kayakRuntime.LastCommittedIndex(); err != nil {
+		return
+	}
+
+	// register the running query for cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	key := request.Header.GetQueryKey()
+	db.runningQueries.Store(key, cancel)
+	defer func() {
+		db.runningQueries.Delete(key)
+		cancel()
+	}()
+
+	columns, types, data, err = db.storage.Query(ctx, convertQuery(request.Payload.Queries))
 	if err != nil {
 		return
 	}
 
-	return db.buildQueryResponse(request, 0, columns, types, data)
+	return db.buildQueryResponse(request, 0, committedIndex, 0, storage.ExecResult{}, columns, types, data)
 }
 
-func (db *Database) buildQueryResponse(request *wt.Request, offset uint64,
-	columns []string, types []string, data [][]interface{}) (response *wt.Response, err error) {
+// Cancel aborts the running read query, write queries are replicated by kayak and can not be cancelled.
+func (db *Database) Cancel(key wt.QueryKey) (err error) {
+	rawCancel, ok := db.runningQueries.Load(key)
+	if !ok {
+		return ErrQueryNotRunning
+	}
+
+	rawCancel.(context.CancelFunc)()
+	return
+}
+
+func (db *Database) readQueryWithCursor(request *wt.Request) (response *wt.Response, err error) {
+	var committedIndex uint64
+	if committedIndex, err = db.kayakRuntime.LastCommittedIndex(); err != nil {
+		return
+	}
+
+	// register the running query for cancellation, the query keeps running in cursor until the
+	// cursor is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	key := request.Header.GetQueryKey()
+	db.runningQueries.Store(key, cancel)
+	done := func() {
+		db.runningQueries.Delete(key)
+		cancel()
+	}
+
+	var c *storage.Cursor
+	if c, err = db.storage.QueryCursor(ctx, convertQuery(request.Payload.Queries)); err != nil {
+		done()
+		return
+	}
+
+	var data [][]interface{}
+	var eof bool
+	if data, eof, err = c.Fetch(int(request.Header.FetchSize)); err != nil || eof {
+		c.Close()
+		done()
+		if err != nil {
+			return
+		}
+		return db.buildQueryResponse(request, 0, committedIndex, 0, storage.ExecResult{}, c.Columns, c.Types, data)
+	}
+
+	// keep remaining rows in cursor for further fetches
+	var cursorID uint64
+	if cursorID, err = db.addCursor(&request.Header, c, cancel); err != nil {
+		c.Close()
+		done()
+		return
+	}
+
+	if response, err = db.buildQueryResponse(request, 0, committedIndex, cursorID, storage.ExecResult{},
+		c.Columns, c.Types, data); err != nil {
+		db.removeCursor(cursorID)
+	}
+
+	return
+}
+
+func (db *Database) buildQueryResponse(request *wt.Request, offset uint64, committedIndex uint64, cursorID uint64,
+	result storage.ExecResult, columns []string, types []string, data [][]interface{}) (response *wt.Response, err error) {
 	// build response
 	response = new(wt.Response)
 	response.Header.Request = request.Header
@@ -309,6 +468,11 @@ func (db *Database) buildQueryResponse(request *wt.Request, offset uint64,
 	response.Header.LogOffset = offset
 	response.Header.Timestamp = getLocalTime()
 	response.Header.RowCount = uint64(len(data))
+	response.Header.HealthScore = db.healthScore()
+	response.Header.CursorID = cursorID
+	response.Header.LastInsertID = result.LastInsertID
+	response.Header.AffectedRows = result.RowsAffected
+	response.Header.CommittedIndex = committedIndex
 	if response.Header.Signee, err = getLocalPubKey(); err != nil {
 		return
 	}
@@ -336,6 +500,25 @@ func (db *Database) buildQueryResponse(request *wt.Request, offset uint64,
 	return
 }
 
+// HealthScore returns the health score of the database replica.
+func (db *Database) HealthScore() uint32 {
+	return db.healthScore()
+}
+
+// healthScore returns the health score of the database replica based on sqlchain lag and load.
+func (db *Database) healthScore() uint32 {
+	penalty := int64(db.chain.HeadLag())*HealthPenaltyPerLagTurn +
+		int64(atomic.LoadInt32(&db.inflight))*HealthPenaltyPerInflightQuery
+
+	if penalty >= int64(wt.MaxHealthScore) {
+		return 0
+	} else if penalty < 0 {
+		return wt.MaxHealthScore
+	}
+
+	return wt.MaxHealthScore - uint32(penalty)
+}
+
 func (db *Database) saveResponse(respHeader *wt.SignedResponseHeader) (err error) {
 	return db.chain.VerifyAndPushResponsedQuery(respHeader)
 }
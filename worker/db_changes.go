@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"strings"
+
+	"github.com/CovenantSQL/CovenantSQL/sqlchain/storage"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+	"github.com/CovenantSQL/go-sqlite3-encrypt"
+)
+
+const (
+	// MaxRecordedChanges defines the max row changes retained in a database instance for subscribers.
+	MaxRecordedChanges = 10000
+)
+
+// Changes returns the row changes of table committed after log index since, changes of all
+// tables are returned if table is empty. Truncated is set if part of the changes are evicted.
+func (db *Database) Changes(table string, since uint64) (events []wt.ChangeEvent, lastIndex uint64,
+	truncated bool, err error) {
+	// committed index is read ahead so that changes of logs committing concurrently are not reported partially
+	if lastIndex, err = db.kayakRuntime.LastCommittedIndex(); err != nil {
+		return
+	}
+
+	db.changesLock.Lock()
+	defer db.changesLock.Unlock()
+
+	truncated = since < db.evictedIndex
+	events = make([]wt.ChangeEvent, 0)
+
+	for _, e := range db.changes {
+		if e.Index <= since || e.Index > lastIndex {
+			continue
+		}
+		if table != "" && !strings.EqualFold(e.Table, table) {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	return
+}
+
+func (db *Database) recordChanges(changes []storage.RowChange) {
+	if len(changes) == 0 || db.kayakRuntime == nil {
+		return
+	}
+
+	// storage commit happens before the committed index is updated by kayak runner
+	lastIndex, err := db.kayakRuntime.LastCommittedIndex()
+	if err != nil {
+		log.Warnf("get committed index failed: %v", err)
+		return
+	}
+
+	db.changesLock.Lock()
+	defer db.changesLock.Unlock()
+
+	for _, c := range changes {
+		e := wt.ChangeEvent{
+			Index: lastIndex + 1,
+			Table: c.Table,
+			RowID: c.RowID,
+		}
+
+		switch c.Op {
+		case sqlite3.SQLITE_INSERT:
+			e.Op = wt.ChangeInsert
+		case sqlite3.SQLITE_UPDATE:
+			e.Op = wt.ChangeUpdate
+		case sqlite3.SQLITE_DELETE:
+			e.Op = wt.ChangeDelete
+		default:
+			continue
+		}
+
+		db.changes = append(db.changes, e)
+	}
+
+	if n := len(db.changes) - MaxRecordedChanges; n > 0 {
+		db.evictedIndex = db.changes[n-1].Index
+		db.changes = db.changes[n:]
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/sqlchain/storage"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+const (
+	// MaxOpenCursors defines the max server side cursors opened simultaneously in a database instance.
+	MaxOpenCursors = 64
+
+	// CursorIdleTimeout defines the idle time before an unused cursor is closed automatically.
+	CursorIdleTimeout = time.Minute
+)
+
+type cursorEntry struct {
+	sync.Mutex
+	cursor      *storage.Cursor
+	owner       proto.NodeID
+	requestHash hash.Hash
+	seq         uint64
+	lastActive  time.Time
+
+	// the query keeps running until cursor is closed, it is registered as running query by key
+	key    wt.QueryKey
+	cancel context.CancelFunc
+}
+
+// Fetch returns at most count rows from the cursor owned by node, the cursor is closed on eof.
+// Fetched rows are signed in sequence with the read query creating the cursor, so that the
+// batches can not be forged, reordered or replayed.
+func (db *Database) Fetch(nodeID proto.NodeID, cursorID uint64, count uint64) (resp *wt.FetchResp, err error) {
+	var entry *cursorEntry
+	if entry, err = db.getCursor(nodeID, cursorID); err != nil {
+		return
+	}
+
+	entry.Lock()
+	var (
+		data [][]interface{}
+		eof  bool
+	)
+	data, eof, err = entry.cursor.Fetch(int(count))
+	entry.seq++
+	seq := entry.seq
+	entry.lastActive = time.Now()
+	entry.Unlock()
+
+	if err != nil || eof {
+		db.removeCursor(cursorID)
+	}
+	if err != nil {
+		return
+	}
+
+	resp = new(wt.FetchResp)
+	resp.Header.RequestHash = entry.requestHash
+	if resp.Header.NodeID, err = kms.GetLocalNodeID(); err != nil {
+		return
+	}
+	resp.Header.CursorID = cursorID
+	resp.Header.Seq = seq
+	resp.Header.EOF = eof
+	if resp.Header.Signee, err = getLocalPubKey(); err != nil {
+		return
+	}
+
+	resp.Rows = make([]wt.ResponseRow, len(data))
+	for i, d := range data {
+		resp.Rows[i].Values = d
+	}
+
+	// sign fields
+	var privateKey *asymmetric.PrivateKey
+	if privateKey, err = getLocalPrivateKey(); err != nil {
+		return
+	}
+	err = resp.Sign(privateKey)
+
+	return
+}
+
+// CloseCursor closes the cursor owned by node.
+func (db *Database) CloseCursor(nodeID proto.NodeID, cursorID uint64) (err error) {
+	if _, err = db.getCursor(nodeID, cursorID); err != nil {
+		return
+	}
+
+	db.removeCursor(cursorID)
+	return
+}
+
+func (db *Database) addCursor(header *wt.SignedRequestHeader, c *storage.Cursor, cancel context.CancelFunc) (
+	cursorID uint64, err error) {
+	db.cursorLock.Lock()
+	defer db.cursorLock.Unlock()
+
+	if db.cursors == nil {
+		db.cursors = make(map[uint64]*cursorEntry)
+	}
+
+	if len(db.cursors) >= MaxOpenCursors {
+		err = ErrTooManyCursors
+		return
+	}
+
+	cursorID = atomic.AddUint64(&db.lastCursorID, 1)
+	db.cursors[cursorID] = &cursorEntry{
+		cursor:      c,
+		owner:       header.NodeID,
+		requestHash: header.HeaderHash,
+		lastActive:  time.Now(),
+		key:         header.GetQueryKey(),
+		cancel:      cancel,
+	}
+
+	return
+}
+
+func (db *Database) getCursor(nodeID proto.NodeID, cursorID uint64) (entry *cursorEntry, err error) {
+	db.cursorLock.Lock()
+	defer db.cursorLock.Unlock()
+
+	var ok bool
+	if entry, ok = db.cursors[cursorID]; !ok || entry.owner != nodeID {
+		// do not reveal cursors of other nodes
+		err = ErrCursorNotFound
+	}
+
+	return
+}
+
+func (db *Database) removeCursor(cursorID uint64) {
+	db.cursorLock.Lock()
+	entry, ok := db.cursors[cursorID]
+	delete(db.cursors, cursorID)
+	db.cursorLock.Unlock()
+
+	if ok {
+		db.closeCursorEntry(entry)
+	}
+}
+
+func (db *Database) closeCursors() {
+	db.cursorLock.Lock()
+	cursors := db.cursors
+	db.cursors = nil
+	db.cursorLock.Unlock()
+
+	for _, entry := range cursors {
+		db.closeCursorEntry(entry)
+	}
+}
+
+func (db *Database) closeCursorEntry(entry *cursorEntry) {
+	entry.Lock()
+	entry.cursor.Close()
+	entry.Unlock()
+
+	db.runningQueries.Delete(entry.key)
+	entry.cancel()
+}
+
+func (db *Database) evictCursors() {
+	ticker := time.NewTicker(CursorIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.cursorStopCh:
+			return
+		case now := <-ticker.C:
+			var expired []uint64
+
+			db.cursorLock.Lock()
+			for id, entry := range db.cursors {
+				entry.Lock()
+				if now.Sub(entry.lastActive) > CursorIdleTimeout {
+					expired = append(expired, id)
+				}
+				entry.Unlock()
+			}
+			db.cursorLock.Unlock()
+
+			for _, id := range expired {
+				db.removeCursor(id)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/sqlchain/storage"
+	ct "github.com/CovenantSQL/CovenantSQL/sqlchain/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
+)
+
+// historicalQuery serves read query on the historical state, which is reconstructed by replaying
+// logs committed by the requested block or before the requested time. The snapshot is kept and
+// advanced incrementally, so historical queries are served one at a time.
+func (db *Database) historicalQuery(request *wt.Request) (response *wt.Response, err error) {
+	var committed uint64
+	if request.Header.AsOfHeight > 0 {
+		var block *ct.Block
+		if block, err = db.chain.FetchBlock(request.Header.AsOfHeight); err != nil {
+			return
+		}
+		if block == nil {
+			err = ErrBlockNotFound
+			return
+		}
+		if committed, err = db.chain.LastCommittedLogOffset(request.Header.AsOfHeight); err != nil {
+			return
+		}
+	}
+
+	db.historyLock.Lock()
+	defer db.historyLock.Unlock()
+
+	var offset uint64
+	if request.Header.AsOfHeight > 0 {
+		offset, err = db.seekHistoryOffset(committed)
+	} else {
+		offset, err = db.seekHistoryTime(request.Header.AsOfTime)
+	}
+	if err != nil {
+		return
+	}
+
+	// register the running query for cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	key := request.Header.GetQueryKey()
+	db.runningQueries.Store(key, cancel)
+	defer func() {
+		db.runningQueries.Delete(key)
+		cancel()
+	}()
+
+	var columns, types []string
+	var data [][]interface{}
+	if columns, types, data, err = db.history.Query(ctx, convertQuery(request.Payload.Queries)); err != nil {
+		return
+	}
+
+	return db.buildQueryResponse(request, 0, offset, 0, storage.ExecResult{}, columns, types, data)
+}
+
+// seekHistoryOffset replays logs to the snapshot till the log at offset.
+func (db *Database) seekHistoryOffset(offset uint64) (uint64, error) {
+	return db.seekHistory(func(i uint64, _ *wt.Request) bool {
+		return i > offset
+	})
+}
+
+// seekHistoryTime replays logs to the snapshot till the last log issued before asOf, the replay
+// stops at the first log issued after asOf.
+func (db *Database) seekHistoryTime(asOf time.Time) (uint64, error) {
+	return db.seekHistory(func(_ uint64, req *wt.Request) bool {
+		return req.Header.Timestamp.After(asOf)
+	})
+}
+
+// seekHistory replays logs to the snapshot in commit order till the first log beyond the target,
+// the snapshot is rebuilt if the replayed logs are beyond the target already.
+func (db *Database) seekHistory(beyond func(offset uint64, req *wt.Request) bool) (offset uint64, err error) {
+	if db.history != nil && db.historyOffset > 0 {
+		// rebuild snapshot for earlier state
+		var req *wt.Request
+		if req, err = db.getLogRequest(db.historyOffset); err != nil {
+			return
+		}
+		if beyond(db.historyOffset, req) {
+			db.closeHistory()
+		}
+	}
+
+	if db.history == nil {
+		if err = db.openHistory(); err != nil {
+			return
+		}
+	}
+
+	var lastIndex uint64
+	if lastIndex, err = db.kayakRuntime.LastCommittedIndex(); err != nil {
+		return
+	}
+
+	for i := db.historyOffset + 1; i <= lastIndex; i++ {
+		var req *wt.Request
+		if req, err = db.getLogRequest(i); err != nil {
+			return
+		}
+		if beyond(i, req) {
+			break
+		}
+
+		// failed log is rolled back as a whole on commit, so does replaying
+		if _, execErr := db.history.Exec(context.Background(), convertQuery(req.Payload.Queries)); execErr != nil {
+			log.Debugf("replay log %d failed: %v", i, execErr)
+		}
+
+		db.historyOffset = i
+	}
+
+	offset = db.historyOffset
+	return
+}
+
+func (db *Database) getLogRequest(offset uint64) (req *wt.Request, err error) {
+	var data []byte
+	if data, err = db.kayakRuntime.GetLog(offset); err != nil {
+		return
+	}
+
+	req = new(wt.Request)
+	err = utils.DecodeMsgPack(data, req)
+	return
+}
+
+func (db *Database) openHistory() (err error) {
+	historyFile := filepath.Join(db.cfg.DataDir, HistoryFileName)
+	removeStorageFiles(historyFile)
+
+	historyDSN, err := storage.NewDSN(historyFile)
+	if err != nil {
+		return
+	}
+
+	if db.cfg.EncryptionKey != "" {
+		historyDSN.AddParam("_crypto_key", db.cfg.EncryptionKey)
+	}
+
+	if db.history, err = storage.New(historyDSN.Format()); err != nil {
+		return
+	}
+
+	db.historyOffset = 0
+	return
+}
+
+func (db *Database) closeHistory() {
+	if db.history == nil {
+		return
+	}
+
+	db.history.Close()
+	db.history = nil
+	db.historyOffset = 0
+	removeStorageFiles(filepath.Join(db.cfg.DataDir, HistoryFileName))
+}
+
+func removeStorageFiles(file string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(file + suffix)
+	}
+}
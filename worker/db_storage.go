@@ -46,7 +46,25 @@ func (db *Database) Commit(ctx context.Context, wb twopc.WriteBatch) (err error)
 		return
 	}
 	db.recordSequence(log)
-	return db.storage.Commit(ctx, log)
+
+	var result storage.ExecResult
+	if result, err = db.storage.CommitWithResult(ctx, log); err != nil {
+		return
+	}
+
+	// fill exec result of write query issued on this node
+	txID := storage.TxID{
+		ConnectionID: log.ConnectionID,
+		SeqNo:        log.SeqNo,
+		Timestamp:    log.Timestamp,
+	}
+	if rawResult, ok := db.pendingResults.Load(txID); ok {
+		*rawResult.(*storage.ExecResult) = result
+	}
+
+	db.recordChanges(result.Changes)
+
+	return
 }
 
 // Rollback implements twopc.Worker.Rollback.
@@ -106,7 +106,9 @@ func TestSingleDatabase(t *testing.T) {
 			So(err, ShouldBeNil)
 			err = res.Verify()
 			So(err, ShouldBeNil)
-			So(res.Header.RowCount, ShouldEqual, 0)
+			So(res.Header.RowCount, ShouldEqual, 2)
+			So(res.Payload.Columns, ShouldResemble, wt.WriteResultColumns)
+			So(res.Payload.Rows[1].Values, ShouldResemble, []interface{}{int64(1), int64(1)})
 
 			// test select query
 			var readQuery *wt.Request
@@ -145,7 +147,7 @@ func TestSingleDatabase(t *testing.T) {
 			So(err, ShouldBeNil)
 			err = res.Verify()
 			So(err, ShouldBeNil)
-			So(res.Header.RowCount, ShouldEqual, 0)
+			So(res.Header.RowCount, ShouldEqual, 2)
 
 			// request again with same sequence
 			writeQuery, err = buildQuery(wt.WriteQuery, 1, 1, []string{
@@ -392,7 +394,7 @@ func TestDatabaseRecycle(t *testing.T) {
 		So(err, ShouldBeNil)
 		err = res.Verify()
 		So(err, ShouldBeNil)
-		So(res.Header.RowCount, ShouldEqual, 0)
+		So(res.Header.RowCount, ShouldEqual, 2)
 
 		// test select query
 		var readQuery *wt.Request
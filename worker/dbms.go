@@ -284,6 +284,78 @@ func (dbms *DBMS) Ack(ack *wt.Ack) (err error) {
 	return db.Ack(ack)
 }
 
+// Fetch handles fetching rows from server side cursor of previous read query.
+func (dbms *DBMS) Fetch(nodeID proto.NodeID, req *wt.FetchReq) (resp *wt.FetchResp, err error) {
+	var db *Database
+	var exists bool
+
+	if db, exists = dbms.getMeta(req.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+
+	return db.Fetch(nodeID, req.CursorID, req.Count)
+}
+
+// CloseCursor handles closing server side cursor of previous read query.
+func (dbms *DBMS) CloseCursor(nodeID proto.NodeID, req *wt.CloseCursorReq) (err error) {
+	var db *Database
+	var exists bool
+
+	if db, exists = dbms.getMeta(req.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+
+	return db.CloseCursor(nodeID, req.CursorID)
+}
+
+// Cancel handles cancellation of running query.
+func (dbms *DBMS) Cancel(nodeID proto.NodeID, req *wt.CancelReq) (err error) {
+	var db *Database
+	var exists bool
+
+	if db, exists = dbms.getMeta(req.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+
+	return db.Cancel(wt.QueryKey{
+		NodeID:       nodeID,
+		ConnectionID: req.ConnectionID,
+		SeqNo:        req.SeqNo,
+	})
+}
+
+// Status handles serving status query of database.
+func (dbms *DBMS) Status(dbID proto.DatabaseID) (healthScore uint32, committedIndex uint64, height int32, err error) {
+	var db *Database
+	var exists bool
+
+	if db, exists = dbms.getMeta(dbID); !exists {
+		err = ErrNotExists
+		return
+	}
+
+	healthScore = db.HealthScore()
+	height = db.chain.HeadHeight()
+	committedIndex, err = db.kayakRuntime.LastCommittedIndex()
+	return
+}
+
+// Changes handles polling of committed row changes of database.
+func (dbms *DBMS) Changes(req *wt.ChangesReq) (events []wt.ChangeEvent, lastIndex uint64, truncated bool, err error) {
+	var db *Database
+	var exists bool
+
+	if db, exists = dbms.getMeta(req.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+
+	return db.Changes(req.Table, req.SinceIndex)
+}
+
 // GetRequest handles fetching original request of previous transactions.
 func (dbms *DBMS) GetRequest(dbID proto.DatabaseID, offset uint64) (query *wt.Request, err error) {
 	var db *Database
@@ -17,6 +17,7 @@
 package worker
 
 import (
+	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/rpc"
 	wt "github.com/CovenantSQL/CovenantSQL/worker/types"
@@ -112,3 +113,60 @@ func (rpc *DBMSRPCService) GetRequest(req *wt.GetRequestReq, resp *wt.GetRequest
 	resp.Request, err = rpc.dbms.GetRequest(req.DatabaseID, req.LogOffset)
 	return
 }
+
+// Fetch rpc, called by client to fetch remaining rows of read query from server side cursor.
+func (rpc *DBMSRPCService) Fetch(req *wt.FetchReq, resp *wt.FetchResp) (err error) {
+	if req.Envelope.NodeID == nil {
+		err = ErrInvalidRequest
+		return
+	}
+
+	var r *wt.FetchResp
+	if r, err = rpc.dbms.Fetch(proto.NodeID(req.Envelope.NodeID.String()), req); err != nil {
+		return
+	}
+
+	resp.Header = r.Header
+	resp.Rows = r.Rows
+	return
+}
+
+// CloseCursor rpc, called by client to release server side cursor before all rows are fetched.
+func (rpc *DBMSRPCService) CloseCursor(req *wt.CloseCursorReq, _ *wt.CloseCursorResp) (err error) {
+	if req.Envelope.NodeID == nil {
+		err = ErrInvalidRequest
+		return
+	}
+
+	err = rpc.dbms.CloseCursor(proto.NodeID(req.Envelope.NodeID.String()), req)
+	return
+}
+
+// Cancel rpc, called by client to abort its running query.
+func (rpc *DBMSRPCService) Cancel(req *wt.CancelReq, _ *wt.CancelResp) (err error) {
+	if req.Envelope.NodeID == nil {
+		err = ErrInvalidRequest
+		return
+	}
+
+	err = rpc.dbms.Cancel(proto.NodeID(req.Envelope.NodeID.String()), req)
+	return
+}
+
+// Status rpc, called by client to check if the database is served by this miner.
+func (rpc *DBMSRPCService) Status(req *wt.StatusReq, resp *wt.StatusResp) (err error) {
+	resp.HealthScore, resp.CommittedIndex, resp.Height, err = rpc.dbms.Status(req.DatabaseID)
+	return
+}
+
+// Changes rpc, called by client to poll committed row changes of subscribed database.
+func (rpc *DBMSRPCService) Changes(req *wt.ChangesReq, resp *wt.ChangesResp) (err error) {
+	// verify changes are polled by an identified node, as read queries are required to be
+	if req.Envelope.NodeID == nil {
+		err = ErrInvalidRequest
+		return
+	}
+
+	resp.Events, resp.LastIndex, resp.Truncated, err = rpc.dbms.Changes(req)
+	return
+}
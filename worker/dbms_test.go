@@ -113,8 +113,10 @@ func TestDBMS(t *testing.T) {
 				So(err, ShouldBeNil)
 				err = queryRes.Verify()
 				So(err, ShouldBeNil)
-				So(queryRes.Header.RowCount, ShouldEqual, 0)
+				So(queryRes.Header.RowCount, ShouldEqual, 2)
 				So(queryRes.Header.LogOffset, ShouldEqual, 1)
+				So(queryRes.Header.CommittedIndex, ShouldEqual, 1)
+				So(queryRes.Header.AffectedRows, ShouldEqual, 1)
 
 				var reqGetRequest wt.GetRequestReq
 				var respGetRequest *wt.GetRequestResp
@@ -142,6 +144,7 @@ func TestDBMS(t *testing.T) {
 				So(queryRes.Payload.Rows, ShouldNotBeEmpty)
 				So(queryRes.Payload.Rows[0].Values, ShouldNotBeEmpty)
 				So(queryRes.Payload.Rows[0].Values[0], ShouldEqual, 1)
+				So(queryRes.Header.CommittedIndex, ShouldEqual, 1)
 
 				// sending read ack
 				var ack *wt.Ack
@@ -153,6 +156,294 @@ func TestDBMS(t *testing.T) {
 				So(err, ShouldBeNil)
 			})
 
+			Convey("cursor queries", func() {
+				var writeQuery *wt.Request
+				var queryRes *wt.Response
+				writeQuery, err = buildQueryWithDatabaseID(wt.WriteQuery, 1, 1, dbID, []string{
+					"create table test (test int)",
+					"insert into test values(1)",
+					"insert into test values(2)",
+					"insert into test values(3)",
+				})
+				So(err, ShouldBeNil)
+
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+
+				// read first batch with fetch size
+				var readQuery *wt.Request
+				readQuery, err = buildQueryWithDatabaseID(wt.ReadQuery, 1, 2, dbID, []string{
+					"select * from test order by test",
+				})
+				So(err, ShouldBeNil)
+				readQuery.Header.FetchSize = 2
+				err = readQuery.Sign(privateKey)
+				So(err, ShouldBeNil)
+
+				err = testRequest(route.DBSQuery, readQuery, &queryRes)
+				So(err, ShouldBeNil)
+				err = queryRes.Verify()
+				So(err, ShouldBeNil)
+				So(queryRes.Header.RowCount, ShouldEqual, uint64(2))
+				So(queryRes.Header.CursorID, ShouldNotEqual, 0)
+				So(queryRes.Payload.Columns, ShouldResemble, []string{"test"})
+
+				// fetch remaining rows
+				var fetchReq wt.FetchReq
+				var fetchResp *wt.FetchResp
+				fetchReq.DatabaseID = dbID
+				fetchReq.CursorID = queryRes.Header.CursorID
+				fetchReq.Count = 2
+				err = testRequest(route.DBSFetch, fetchReq, &fetchResp)
+				So(err, ShouldBeNil)
+				err = fetchResp.Verify()
+				So(err, ShouldBeNil)
+				So(fetchResp.Header.RequestHash, ShouldResemble, readQuery.Header.HeaderHash)
+				So(fetchResp.Header.CursorID, ShouldEqual, queryRes.Header.CursorID)
+				So(fetchResp.Header.Seq, ShouldEqual, uint64(1))
+				So(fetchResp.Header.EOF, ShouldBeTrue)
+				So(fetchResp.Rows, ShouldHaveLength, 1)
+				So(fetchResp.Rows[0].Values[0], ShouldEqual, 3)
+
+				// tampered rows are detected
+				fetchResp.Rows[0].Values[0] = int64(4)
+				err = fetchResp.Verify()
+				So(err, ShouldNotBeNil)
+
+				// cursor is closed on eof
+				err = testRequest(route.DBSFetch, fetchReq, &fetchResp)
+				So(err, ShouldNotBeNil)
+
+				// close cursor explicitly
+				readQuery, err = buildQueryWithDatabaseID(wt.ReadQuery, 1, 3, dbID, []string{
+					"select * from test",
+				})
+				So(err, ShouldBeNil)
+				readQuery.Header.FetchSize = 1
+				err = readQuery.Sign(privateKey)
+				So(err, ShouldBeNil)
+
+				err = testRequest(route.DBSQuery, readQuery, &queryRes)
+				So(err, ShouldBeNil)
+				So(queryRes.Header.CursorID, ShouldNotEqual, 0)
+
+				var closeReq wt.CloseCursorReq
+				var closeResp *wt.CloseCursorResp
+				closeReq.DatabaseID = dbID
+				closeReq.CursorID = queryRes.Header.CursorID
+				err = testRequest(route.DBSCloseCursor, closeReq, &closeResp)
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSCloseCursor, closeReq, &closeResp)
+				So(err, ShouldNotBeNil)
+
+				// cancel query running in cursor
+				readQuery, err = buildQueryWithDatabaseID(wt.ReadQuery, 1, 4, dbID, []string{
+					"select * from test",
+				})
+				So(err, ShouldBeNil)
+				readQuery.Header.FetchSize = 1
+				err = readQuery.Sign(privateKey)
+				So(err, ShouldBeNil)
+
+				err = testRequest(route.DBSQuery, readQuery, &queryRes)
+				So(err, ShouldBeNil)
+				So(queryRes.Header.CursorID, ShouldNotEqual, 0)
+
+				var cancelReq wt.CancelReq
+				var cancelResp *wt.CancelResp
+				cancelReq.DatabaseID = dbID
+				cancelReq.ConnectionID = readQuery.Header.ConnectionID
+				cancelReq.SeqNo = readQuery.Header.SeqNo
+				err = testRequest(route.DBSCancel, cancelReq, &cancelResp)
+				So(err, ShouldBeNil)
+
+				fetchReq.CursorID = queryRes.Header.CursorID
+				err = testRequest(route.DBSFetch, fetchReq, &fetchResp)
+				So(err, ShouldNotBeNil)
+				err = testRequest(route.DBSCancel, cancelReq, &cancelResp)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("row changes", func() {
+				var writeQuery *wt.Request
+				var queryRes *wt.Response
+				writeQuery, err = buildQueryWithDatabaseID(wt.WriteQuery, 1, 1, dbID, []string{
+					"create table test (id integer primary key, v text)",
+					"create table other (id integer primary key)",
+					"insert into test values(3, 'a')",
+					"insert into other values(4)",
+				})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+
+				writeQuery, err = buildQueryWithDatabaseID(wt.WriteQuery, 1, 2, dbID, []string{
+					"update test set v = 'b' where id = 3",
+					"delete from test where id = 3",
+				})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+
+				var changesReq wt.ChangesReq
+				var changesResp *wt.ChangesResp
+				changesReq.DatabaseID = dbID
+				changesReq.Table = "test"
+				err = testRequest(route.DBSChanges, changesReq, &changesResp)
+				So(err, ShouldBeNil)
+				So(changesResp.Truncated, ShouldBeFalse)
+				So(changesResp.LastIndex, ShouldEqual, 2)
+				So(changesResp.Events, ShouldResemble, []wt.ChangeEvent{
+					{Index: 1, Op: wt.ChangeInsert, Table: "test", RowID: 3},
+					{Index: 2, Op: wt.ChangeUpdate, Table: "test", RowID: 3},
+					{Index: 2, Op: wt.ChangeDelete, Table: "test", RowID: 3},
+				})
+
+				// all tables after index
+				changesReq.Table = ""
+				changesReq.SinceIndex = 1
+				err = testRequest(route.DBSChanges, changesReq, &changesResp)
+				So(err, ShouldBeNil)
+				So(changesResp.Events, ShouldHaveLength, 2)
+
+				changesReq.SinceIndex = 0
+				err = testRequest(route.DBSChanges, changesReq, &changesResp)
+				So(err, ShouldBeNil)
+				So(changesResp.Events, ShouldHaveLength, 4)
+				So(changesResp.Events[1].Table, ShouldEqual, "other")
+
+				// non-existent database
+				changesReq.DatabaseID = proto.DatabaseID("db_not_exists")
+				err = testRequest(route.DBSChanges, changesReq, &changesResp)
+				So(err, ShouldNotBeNil)
+
+				// anonymous caller
+				changesReq.DatabaseID = dbID
+				err = (&DBMSRPCService{dbms: dbms}).Changes(&changesReq, &wt.ChangesResp{})
+				So(err, ShouldEqual, ErrInvalidRequest)
+			})
+
+			Convey("historical queries", func() {
+				var writeQuery *wt.Request
+				var queryRes *wt.Response
+				writeQuery, err = buildQueryWithDatabaseID(wt.WriteQuery, 1, 1, dbID, []string{
+					"create table test (test int)",
+					"insert into test values(1)",
+				})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+				asOf1 := writeQuery.Header.Timestamp
+
+				time.Sleep(10 * time.Millisecond)
+				writeQuery, err = buildQueryWithDatabaseID(wt.WriteQuery, 1, 2, dbID, []string{
+					"insert into test values(2)",
+				})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+				asOf2 := writeQuery.Header.Timestamp
+
+				readAsOf := func(seqNo uint64, asOf time.Time, height int32) (res *wt.Response, err error) {
+					var readQuery *wt.Request
+					if readQuery, err = buildQueryWithDatabaseID(wt.ReadQuery, 1, seqNo, dbID, []string{
+						"select count(1) from test",
+					}); err != nil {
+						return
+					}
+					readQuery.Header.AsOfTime = asOf
+					readQuery.Header.AsOfHeight = height
+					if err = readQuery.Sign(privateKey); err != nil {
+						return
+					}
+					err = testRequest(route.DBSQuery, readQuery, &res)
+					return
+				}
+
+				// snapshot advanced incrementally
+				queryRes, err = readAsOf(3, asOf2, 0)
+				So(err, ShouldBeNil)
+				So(queryRes.Header.CommittedIndex, ShouldEqual, 2)
+				So(queryRes.Payload.Rows[0].Values[0], ShouldEqual, 2)
+
+				// snapshot rebuilt for earlier state
+				queryRes, err = readAsOf(4, asOf1, 0)
+				So(err, ShouldBeNil)
+				So(queryRes.Header.CommittedIndex, ShouldEqual, 1)
+				So(queryRes.Payload.Rows[0].Values[0], ShouldEqual, 1)
+
+				// state before any write
+				_, err = readAsOf(5, asOf1.Add(-time.Second), 0)
+				So(err, ShouldNotBeNil)
+
+				// block not produced yet
+				_, err = readAsOf(6, time.Time{}, 1<<30)
+				So(err, ShouldNotBeNil)
+
+				// replay stops at the log offset committed by block
+				db, ok := dbms.getMeta(dbID)
+				So(ok, ShouldBeTrue)
+				db.historyLock.Lock()
+				offset, err := db.seekHistoryOffset(2)
+				So(err, ShouldBeNil)
+				So(offset, ShouldEqual, 2)
+				offset, err = db.seekHistoryOffset(1)
+				So(err, ShouldBeNil)
+				So(offset, ShouldEqual, 1)
+				db.historyLock.Unlock()
+
+				// historical state is read-only
+				writeQuery, err = buildQueryWithDatabaseID(wt.WriteQuery, 1, 7, dbID, []string{
+					"insert into test values(3)",
+				})
+				So(err, ShouldBeNil)
+				writeQuery.Header.AsOfTime = asOf1
+				err = writeQuery.Sign(privateKey)
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("cancel query", func() {
+				var readQuery *wt.Request
+				readQuery, err = buildQueryWithDatabaseID(wt.ReadQuery, 1, 1, dbID, []string{
+					"with recursive c(x) as (select 1 union all select x + 1 from c) select count(1) from c",
+				})
+				So(err, ShouldBeNil)
+
+				queryErrCh := make(chan error, 1)
+				go func() {
+					var queryRes *wt.Response
+					queryErrCh <- testRequest(route.DBSQuery, readQuery, &queryRes)
+				}()
+
+				var cancelReq wt.CancelReq
+				var cancelResp *wt.CancelResp
+				cancelReq.DatabaseID = dbID
+				cancelReq.ConnectionID = readQuery.Header.ConnectionID
+				cancelReq.SeqNo = readQuery.Header.SeqNo
+
+				// wait for query to start
+				for i := 0; i < 50; i++ {
+					if err = testRequest(route.DBSCancel, cancelReq, &cancelResp); err == nil {
+						break
+					}
+					time.Sleep(100 * time.Millisecond)
+				}
+				So(err, ShouldBeNil)
+
+				select {
+				case err = <-queryErrCh:
+					So(err, ShouldNotBeNil)
+				case <-time.After(10 * time.Second):
+					So("query not cancelled", ShouldBeEmpty)
+				}
+
+				// cancel finished query
+				err = testRequest(route.DBSCancel, cancelReq, &cancelResp)
+				So(err, ShouldNotBeNil)
+			})
+
 			Convey("query non-existent database", func() {
 				// sending write query
 				var writeQuery *wt.Request
@@ -39,4 +39,19 @@ var (
 
 	// ErrSpaceLimitExceeded defines errors on disk space exceeding limit.
 	ErrSpaceLimitExceeded = errors.New("space limit exceeded")
+
+	// ErrTooManyCursors defines errors on opening cursor exceeding max open cursors limit.
+	ErrTooManyCursors = errors.New("too many open cursors")
+
+	// ErrCursorNotFound defines errors on accessing a closed or non-exists cursor.
+	ErrCursorNotFound = errors.New("cursor not found")
+
+	// ErrQueryNotRunning defines errors on cancelling a finished or non-exists query.
+	ErrQueryNotRunning = errors.New("query not running")
+
+	// ErrHistoricalWrite defines errors on issuing write query on historical state.
+	ErrHistoricalWrite = errors.New("historical state is read-only")
+
+	// ErrBlockNotFound defines errors on querying historical state of a block not produced yet.
+	ErrBlockNotFound = errors.New("block not found")
 )
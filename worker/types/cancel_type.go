@@ -0,0 +1,33 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "github.com/CovenantSQL/CovenantSQL/proto"
+
+// CancelReq defines Cancel RPC request entity, the query is identified by the connection id and
+// sequence no of the original request of the calling node.
+type CancelReq struct {
+	proto.Envelope
+	DatabaseID   proto.DatabaseID
+	ConnectionID uint64
+	SeqNo        uint64
+}
+
+// CancelResp defines Cancel RPC response entity.
+type CancelResp struct {
+	proto.Envelope
+}
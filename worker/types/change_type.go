@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "github.com/CovenantSQL/CovenantSQL/proto"
+
+// ChangeOp defines the operation type of row change.
+type ChangeOp uint8
+
+const (
+	// ChangeInsert defines a row inserted.
+	ChangeInsert ChangeOp = iota
+	// ChangeUpdate defines a row updated.
+	ChangeUpdate
+	// ChangeDelete defines a row deleted.
+	ChangeDelete
+)
+
+// String returns the string representation of change operation.
+func (o ChangeOp) String() string {
+	switch o {
+	case ChangeInsert:
+		return "INSERT"
+	case ChangeUpdate:
+		return "UPDATE"
+	case ChangeDelete:
+		return "DELETE"
+	}
+	return "Unknown"
+}
+
+// ChangeEvent defines a row change applied by committed write query.
+type ChangeEvent struct {
+	Index uint64 // log index of the write query
+	Op    ChangeOp
+	Table string
+	RowID int64 // rowid of the row, equals to the INTEGER PRIMARY KEY if any
+}
+
+// ChangesReq defines Changes RPC request entity.
+type ChangesReq struct {
+	proto.Envelope
+	DatabaseID proto.DatabaseID
+	Table      string // all tables if empty
+	SinceIndex uint64 // changes of logs after this index are returned
+}
+
+// ChangesResp defines Changes RPC response entity.
+type ChangesResp struct {
+	proto.Envelope
+	Events    []ChangeEvent
+	LastIndex uint64 // last committed log index covered by events
+	Truncated bool   // some changes after SinceIndex are no longer retained by the peer
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// FetchReq defines Fetch RPC request entity.
+type FetchReq struct {
+	proto.Envelope
+	DatabaseID proto.DatabaseID
+	CursorID   uint64
+	Count      uint64
+}
+
+// FetchRespHeader defines the header of a batch of rows fetched from server side cursor.
+type FetchRespHeader struct {
+	RequestHash hash.Hash    // header hash of the read query request creating the cursor
+	NodeID      proto.NodeID // response node id
+	CursorID    uint64
+	Seq         uint64    // batch sequence in cursor, the rows of query response are batch 0
+	RowCount    uint64    // row count of batch
+	EOF         bool      // the cursor is closed after this batch
+	DataHash    hash.Hash // hash of rows
+}
+
+// SignedFetchRespHeader defines a signed fetch response header.
+type SignedFetchRespHeader struct {
+	FetchRespHeader
+	HeaderHash hash.Hash
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature
+}
+
+// FetchResp defines Fetch RPC response entity.
+type FetchResp struct {
+	proto.Envelope
+	Header SignedFetchRespHeader
+	Rows   []ResponseRow
+}
+
+// Serialize structure to bytes.
+func (h *FetchRespHeader) Serialize() []byte {
+	if h == nil {
+		return []byte{'\000'}
+	}
+
+	buf := new(bytes.Buffer)
+
+	buf.Write(h.RequestHash[:])
+	binary.Write(buf, binary.LittleEndian, uint64(len(h.NodeID)))
+	buf.WriteString(string(h.NodeID))
+	binary.Write(buf, binary.LittleEndian, h.CursorID)
+	binary.Write(buf, binary.LittleEndian, h.Seq)
+	binary.Write(buf, binary.LittleEndian, h.RowCount)
+	binary.Write(buf, binary.LittleEndian, h.EOF)
+	buf.Write(h.DataHash[:])
+
+	return buf.Bytes()
+}
+
+// Verify checks hash and signature in fetch response header.
+func (sh *SignedFetchRespHeader) Verify() (err error) {
+	// verify hash
+	if err = verifyHash(&sh.FetchRespHeader, &sh.HeaderHash); err != nil {
+		return
+	}
+	// verify signature
+	if sh.Signee == nil || sh.Signature == nil || !sh.Signature.Verify(sh.HeaderHash[:], sh.Signee) {
+		return ErrSignVerification
+	}
+
+	return nil
+}
+
+// Sign the fetch response header.
+func (sh *SignedFetchRespHeader) Sign(signer *asymmetric.PrivateKey) (err error) {
+	// build our hash
+	buildHash(&sh.FetchRespHeader, &sh.HeaderHash)
+
+	// sign
+	sh.Signature, err = signer.Sign(sh.HeaderHash[:])
+
+	return
+}
+
+// payload returns the fetched rows as response payload for hashing, columns are returned by the
+// query response creating the cursor.
+func (r *FetchResp) payload() *ResponsePayload {
+	return &ResponsePayload{Rows: r.Rows}
+}
+
+// Verify checks hash and signature in whole fetch response.
+func (r *FetchResp) Verify() (err error) {
+	// verify data hash in header
+	if err = verifyHash(r.payload(), &r.Header.DataHash); err != nil {
+		return
+	}
+
+	return r.Header.Verify()
+}
+
+// Sign the fetch response.
+func (r *FetchResp) Sign(signer *asymmetric.PrivateKey) (err error) {
+	// set rows count
+	r.Header.RowCount = uint64(len(r.Rows))
+
+	// build hash in header
+	buildHash(r.payload(), &r.Header.DataHash)
+
+	// sign the response
+	return r.Header.Sign(signer)
+}
+
+// CloseCursorReq defines CloseCursor RPC request entity.
+type CloseCursorReq struct {
+	proto.Envelope
+	DatabaseID proto.DatabaseID
+	CursorID   uint64
+}
+
+// CloseCursorResp defines CloseCursor RPC response entity.
+type CloseCursorResp struct {
+	proto.Envelope
+}
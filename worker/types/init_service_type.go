@@ -42,6 +42,59 @@ type ResourceMeta struct {
 	Memory        uint64 // reserved memory in bytes
 	LoadAvgPerCPU uint64 // max loadAvg15 per CPU
 	EncryptionKey string `hspack:"-"` // encryption key for database instance
+
+	// TargetRegion defines the preferred region of allocated nodes, see Constraints for the
+	// required regions.
+	TargetRegion string
+	// UseEventualConsistency defines if writes are acknowledged before replicated to all peers.
+	UseEventualConsistency bool
+	// ConsistencyLevel defines the fraction of peers required to confirm a write, ranges in (0, 1].
+	ConsistencyLevel float64
+	// GasPrice defines the target gas price paid for the database, 0 for the current gas price.
+	GasPrice uint64
+	// Constraints defines the owner requirements of miners allocated to the database.
+	Constraints MatchConstraints
+}
+
+// MatchConstraints defines the miners allowed to serve a database, the constraints are checked
+// against the miner profiles registered on main chain.
+type MatchConstraints struct {
+	Regions       []string       // allowed miner regions, empty for any region
+	MinStake      uint64         // min covenant coin staked by miners
+	ExcludeMiners []proto.NodeID // miners never allocated
+	MaxGasPrice   uint64         // max gas price paid for the database, 0 for no cap
+}
+
+// RequireProfile returns whether the constraints could only be checked with miner profiles.
+func (c *MatchConstraints) RequireProfile() bool {
+	return len(c.Regions) > 0 || c.MinStake > 0 || c.MaxGasPrice > 0
+}
+
+// Excludes returns whether miner nodeID is excluded.
+func (c *MatchConstraints) Excludes(nodeID proto.NodeID) bool {
+	for _, id := range c.ExcludeMiners {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// Accept returns whether a miner of region, stake and gas price satisfies the constraints except
+// the exclusion.
+func (c *MatchConstraints) Accept(region string, stake, gasPrice uint64) bool {
+	if c.MinStake > stake || (c.MaxGasPrice > 0 && gasPrice > c.MaxGasPrice) {
+		return false
+	}
+	if len(c.Regions) == 0 {
+		return true
+	}
+	for _, r := range c.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
 }
 
 // ServiceInstance defines single instance to be initialized.
@@ -50,6 +103,8 @@ type ServiceInstance struct {
 	Peers        *kayak.Peers
 	ResourceMeta ResourceMeta
 	GenesisBlock *ct.Block
+	// Owner defines the account which creates the database.
+	Owner proto.AccountAddress
 }
 
 // InitServiceResponseHeader defines worker service init response header.
@@ -81,6 +136,36 @@ func (m *ResourceMeta) Serialize() []byte {
 	binary.Write(buf, binary.LittleEndian, m.Node)
 	binary.Write(buf, binary.LittleEndian, m.Space)
 	binary.Write(buf, binary.LittleEndian, m.Memory)
+	binary.Write(buf, binary.LittleEndian, uint64(len(m.TargetRegion)))
+	buf.WriteString(m.TargetRegion)
+	binary.Write(buf, binary.LittleEndian, m.UseEventualConsistency)
+	binary.Write(buf, binary.LittleEndian, m.ConsistencyLevel)
+	binary.Write(buf, binary.LittleEndian, m.GasPrice)
+	buf.Write(m.Constraints.Serialize())
+
+	return buf.Bytes()
+}
+
+// Serialize structure to bytes.
+func (c *MatchConstraints) Serialize() []byte {
+	if c == nil {
+		return []byte{'\000'}
+	}
+
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.LittleEndian, uint64(len(c.Regions)))
+	for _, region := range c.Regions {
+		binary.Write(buf, binary.LittleEndian, uint64(len(region)))
+		buf.WriteString(region)
+	}
+	binary.Write(buf, binary.LittleEndian, c.MinStake)
+	binary.Write(buf, binary.LittleEndian, uint64(len(c.ExcludeMiners)))
+	for _, nodeID := range c.ExcludeMiners {
+		binary.Write(buf, binary.LittleEndian, uint64(len(nodeID)))
+		buf.WriteString(string(nodeID))
+	}
+	binary.Write(buf, binary.LittleEndian, c.MaxGasPrice)
 
 	return buf.Bytes()
 }
@@ -102,6 +187,7 @@ func (i *ServiceInstance) Serialize() []byte {
 	} else {
 		buf.Write([]byte{'\000'})
 	}
+	buf.Write(i.Owner[:])
 
 	return buf.Bytes()
 }
@@ -73,24 +73,78 @@ func (z *InitServiceResponseHeader) Msgsize() (s int) {
 }
 
 // MarshalHash marshals for hash
-func (z *ResourceMeta) MarshalHash() (o []byte, err error) {
+func (z *MatchConstraints) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
 	// map header, size 4
 	o = append(o, 0x84, 0x84)
-	o = hsp.AppendUint16(o, z.Node)
+	o = hsp.AppendArrayHeader(o, uint32(len(z.Regions)))
+	for za0001 := range z.Regions {
+		o = hsp.AppendString(o, z.Regions[za0001])
+	}
 	o = append(o, 0x84)
-	o = hsp.AppendUint64(o, z.Space)
+	o = hsp.AppendArrayHeader(o, uint32(len(z.ExcludeMiners)))
+	for za0002 := range z.ExcludeMiners {
+		if oTemp, err := z.ExcludeMiners[za0002].MarshalHash(); err != nil {
+			return nil, err
+		} else {
+			o = hsp.AppendBytes(o, oTemp)
+		}
+	}
 	o = append(o, 0x84)
-	o = hsp.AppendUint64(o, z.Memory)
+	o = hsp.AppendUint64(o, z.MinStake)
 	o = append(o, 0x84)
+	o = hsp.AppendUint64(o, z.MaxGasPrice)
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *MatchConstraints) Msgsize() (s int) {
+	s = 1 + 8 + hsp.ArrayHeaderSize
+	for za0001 := range z.Regions {
+		s += hsp.StringPrefixSize + len(z.Regions[za0001])
+	}
+	s += 14 + hsp.ArrayHeaderSize
+	for za0002 := range z.ExcludeMiners {
+		s += z.ExcludeMiners[za0002].Msgsize()
+	}
+	s += 9 + hsp.Uint64Size + 12 + hsp.Uint64Size
+	return
+}
+
+// MarshalHash marshals for hash
+func (z *ResourceMeta) MarshalHash() (o []byte, err error) {
+	var b []byte
+	o = hsp.Require(b, z.Msgsize())
+	// map header, size 9
+	o = append(o, 0x89, 0x89)
+	if oTemp, err := z.Constraints.MarshalHash(); err != nil {
+		return nil, err
+	} else {
+		o = hsp.AppendBytes(o, oTemp)
+	}
+	o = append(o, 0x89)
+	o = hsp.AppendFloat64(o, z.ConsistencyLevel)
+	o = append(o, 0x89)
+	o = hsp.AppendString(o, z.TargetRegion)
+	o = append(o, 0x89)
+	o = hsp.AppendBool(o, z.UseEventualConsistency)
+	o = append(o, 0x89)
+	o = hsp.AppendUint16(o, z.Node)
+	o = append(o, 0x89)
+	o = hsp.AppendUint64(o, z.Space)
+	o = append(o, 0x89)
+	o = hsp.AppendUint64(o, z.Memory)
+	o = append(o, 0x89)
 	o = hsp.AppendUint64(o, z.LoadAvgPerCPU)
+	o = append(o, 0x89)
+	o = hsp.AppendUint64(o, z.GasPrice)
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *ResourceMeta) Msgsize() (s int) {
-	s = 1 + 5 + hsp.Uint16Size + 6 + hsp.Uint64Size + 7 + hsp.Uint64Size + 14 + hsp.Uint64Size
+	s = 1 + 12 + z.Constraints.Msgsize() + 17 + hsp.Float64Size + 13 + hsp.StringPrefixSize + len(z.TargetRegion) + 23 + hsp.BoolSize + 5 + hsp.Uint16Size + 6 + hsp.Uint64Size + 7 + hsp.Uint64Size + 14 + hsp.Uint64Size + 9 + hsp.Uint64Size
 	return
 }
 
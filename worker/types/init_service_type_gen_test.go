@@ -120,6 +120,43 @@ func BenchmarkAppendMsgInitServiceResponseHeader(b *testing.B) {
 	}
 }
 
+func TestMarshalHashMatchConstraints(t *testing.T) {
+	v := MatchConstraints{}
+	binary.Read(rand.Reader, binary.BigEndian, &v)
+	bts1, err := v.MarshalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bts2, err := v.MarshalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts1, bts2) {
+		t.Fatal("hash not stable")
+	}
+}
+
+func BenchmarkMarshalHashMatchConstraints(b *testing.B) {
+	v := MatchConstraints{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.MarshalHash()
+	}
+}
+
+func BenchmarkAppendMsgMatchConstraints(b *testing.B) {
+	v := MatchConstraints{}
+	bts := make([]byte, 0, v.Msgsize())
+	bts, _ = v.MarshalHash()
+	b.SetBytes(int64(len(bts)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bts, _ = v.MarshalHash()
+	}
+}
+
 func TestMarshalHashResourceMeta(t *testing.T) {
 	v := ResourceMeta{}
 	binary.Read(rand.Reader, binary.BigEndian, &v)
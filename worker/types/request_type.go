@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/binary"
+	"regexp"
 	"time"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
@@ -47,6 +48,24 @@ type Query struct {
 	Args    []sql.NamedArg
 }
 
+var explainRegex = regexp.MustCompile(`(?i)^\s*explain\s`)
+
+// IsExplain returns if the query is an EXPLAIN or EXPLAIN QUERY PLAN statement, which shows how
+// the statement is executed instead of executing it.
+func (q *Query) IsExplain() bool {
+	return explainRegex.MatchString(q.Pattern)
+}
+
+// IsExplainQueries returns if all the queries are EXPLAIN statements.
+func IsExplainQueries(queries []Query) bool {
+	for i := range queries {
+		if !queries[i].IsExplain() {
+			return false
+		}
+	}
+	return len(queries) > 0
+}
+
 func (t QueryType) String() string {
 	switch t {
 	case ReadQuery:
@@ -73,6 +92,21 @@ type RequestHeader struct {
 	Timestamp    time.Time // time in UTC zone
 	BatchCount   uint64    // query count in this request
 	QueriesHash  hash.Hash // hash of query payload
+
+	// FetchSize defines the row count of first batch of a read query, remaining rows are kept in
+	// a server side cursor and fetched on demand, zero means all rows are returned at once.
+	FetchSize uint64
+
+	// AsOfHeight and AsOfTime define the historical state a read query is served on, the state
+	// produced by writes committed by the block at AsOfHeight or issued before AsOfTime is
+	// queried, AsOfHeight takes precedence. Zero values mean the latest state.
+	AsOfHeight int32
+	AsOfTime   time.Time
+}
+
+// IsHistorical returns if the request queries a historical state.
+func (h *RequestHeader) IsHistorical() bool {
+	return h.AsOfHeight > 0 || !h.AsOfTime.IsZero()
 }
 
 // QueryKey defines an unique query key of a request.
@@ -122,6 +156,13 @@ func (h *RequestHeader) Serialize() []byte {
 	binary.Write(buf, binary.LittleEndian, int64(h.Timestamp.UnixNano())) // use nanoseconds unix epoch
 	binary.Write(buf, binary.LittleEndian, h.BatchCount)
 	buf.Write(h.QueriesHash[:])
+	binary.Write(buf, binary.LittleEndian, h.FetchSize)
+	binary.Write(buf, binary.LittleEndian, h.AsOfHeight)
+	if h.AsOfTime.IsZero() {
+		binary.Write(buf, binary.LittleEndian, int64(0))
+	} else {
+		binary.Write(buf, binary.LittleEndian, int64(h.AsOfTime.UnixNano()))
+	}
 
 	return buf.Bytes()
 }
@@ -48,41 +48,47 @@ func (z QueryType) Msgsize() (s int) {
 func (z *RequestHeader) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 8
-	o = append(o, 0x88, 0x88)
+	// map header, size 11
+	o = append(o, 0x8b, 0x8b)
 	o = hsp.AppendInt32(o, int32(z.QueryType))
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
 	if oTemp, err := z.QueriesHash.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
+	o = hsp.AppendInt32(o, z.AsOfHeight)
+	o = append(o, 0x8b)
 	if oTemp, err := z.DatabaseID.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
 	if oTemp, err := z.NodeID.MarshalHash(); err != nil {
 		return nil, err
 	} else {
 		o = hsp.AppendBytes(o, oTemp)
 	}
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
 	o = hsp.AppendTime(o, z.Timestamp)
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
+	o = hsp.AppendTime(o, z.AsOfTime)
+	o = append(o, 0x8b)
 	o = hsp.AppendUint64(o, z.ConnectionID)
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
 	o = hsp.AppendUint64(o, z.SeqNo)
-	o = append(o, 0x88)
+	o = append(o, 0x8b)
 	o = hsp.AppendUint64(o, z.BatchCount)
+	o = append(o, 0x8b)
+	o = hsp.AppendUint64(o, z.FetchSize)
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *RequestHeader) Msgsize() (s int) {
-	s = 1 + 10 + hsp.Int32Size + 12 + z.QueriesHash.Msgsize() + 11 + z.DatabaseID.Msgsize() + 7 + z.NodeID.Msgsize() + 10 + hsp.TimeSize + 13 + hsp.Uint64Size + 6 + hsp.Uint64Size + 11 + hsp.Uint64Size
+	s = 1 + 10 + hsp.Int32Size + 12 + z.QueriesHash.Msgsize() + 11 + hsp.Int32Size + 11 + z.DatabaseID.Msgsize() + 7 + z.NodeID.Msgsize() + 10 + hsp.TimeSize + 9 + hsp.TimeSize + 13 + hsp.Uint64Size + 6 + hsp.Uint64Size + 11 + hsp.Uint64Size + 10 + hsp.Uint64Size
 	return
 }
 
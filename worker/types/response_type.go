@@ -29,6 +29,19 @@ import (
 
 //go:generate hsp
 
+const (
+	// MaxHealthScore defines the health score of a fully healthy replica.
+	MaxHealthScore uint32 = 100
+)
+
+var (
+	// WriteResultColumns defines the payload columns of write query response, each row
+	// contains the result of a query in request.
+	WriteResultColumns = []string{"last_insert_id", "rows_affected"}
+	// WriteResultTypes defines the payload column types of write query response.
+	WriteResultTypes = []string{"INTEGER", "INTEGER"}
+)
+
 // ResponseRow defines single row of query response.
 type ResponseRow struct {
 	Values []interface{}
@@ -49,6 +62,21 @@ type ResponseHeader struct {
 	RowCount  uint64       // response row count of payload
 	LogOffset uint64       // request log offset
 	DataHash  hash.Hash    // hash of query response
+
+	// HealthScore defines health of the responding replica, ranges from 0 to MaxHealthScore.
+	HealthScore uint32
+
+	// CursorID defines the server side cursor holding remaining rows of result, zero if the
+	// result is complete.
+	CursorID uint64
+
+	// LastInsertID and AffectedRows define the exec result of write query computed by the leader.
+	LastInsertID int64
+	AffectedRows int64
+
+	// CommittedIndex defines the last committed log index of the database replica before
+	// the query is processed, the result reflects at least this state of database.
+	CommittedIndex uint64
 }
 
 // SignedResponseHeader defines a signed query response header.
@@ -114,6 +142,11 @@ func (h *ResponseHeader) Serialize() []byte {
 	binary.Write(buf, binary.LittleEndian, h.RowCount)
 	binary.Write(buf, binary.LittleEndian, h.LogOffset)
 	buf.Write(h.DataHash[:])
+	binary.Write(buf, binary.LittleEndian, h.HealthScore)
+	binary.Write(buf, binary.LittleEndian, h.CursorID)
+	binary.Write(buf, binary.LittleEndian, h.LastInsertID)
+	binary.Write(buf, binary.LittleEndian, h.AffectedRows)
+	binary.Write(buf, binary.LittleEndian, h.CommittedIndex)
 
 	return buf.Bytes()
 }
@@ -0,0 +1,34 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "github.com/CovenantSQL/CovenantSQL/proto"
+
+// StatusReq defines Status RPC request entity.
+type StatusReq struct {
+	proto.Envelope
+	DatabaseID proto.DatabaseID
+}
+
+// StatusResp defines Status RPC response entity.
+type StatusResp struct {
+	proto.Envelope
+	HealthScore    uint32
+	CommittedIndex uint64
+	// Height defines the height of the head block of sql-chain served by the miner.
+	Height int32
+}
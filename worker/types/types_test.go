@@ -960,3 +960,16 @@ func TestOther_MarshalHash(t *testing.T) {
 		So(s, ShouldNotBeEmpty)
 	})
 }
+
+func TestQuery_IsExplain(t *testing.T) {
+	Convey("explain queries", t, func() {
+		So((&Query{Pattern: "EXPLAIN SELECT 1"}).IsExplain(), ShouldBeTrue)
+		So((&Query{Pattern: " explain query plan select * from t"}).IsExplain(), ShouldBeTrue)
+		So((&Query{Pattern: "SELECT 'explain '"}).IsExplain(), ShouldBeFalse)
+		So((&Query{Pattern: "explained"}).IsExplain(), ShouldBeFalse)
+
+		So(IsExplainQueries(nil), ShouldBeFalse)
+		So(IsExplainQueries([]Query{{Pattern: "EXPLAIN SELECT 1"}, {Pattern: "EXPLAIN SELECT 2"}}), ShouldBeTrue)
+		So(IsExplainQueries([]Query{{Pattern: "EXPLAIN SELECT 1"}, {Pattern: "DELETE FROM t"}}), ShouldBeFalse)
+	})
+}